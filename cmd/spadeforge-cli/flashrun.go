@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/artifactname"
+	"github.com/mblsha/spadeforge/internal/discovery"
+	"github.com/mblsha/spadeforge/internal/httptransport"
+	loaderclient "github.com/mblsha/spadeforge/internal/spadeloader/client"
+	loaderjob "github.com/mblsha/spadeforge/internal/spadeloader/job"
+)
+
+// defaultLoaderService is the mDNS service spadeloader servers announce.
+const defaultLoaderService = "_spadeloader._tcp"
+
+type loaderFlags struct {
+	serverURL       *string
+	discoverEnabled *bool
+	discoverTimeout *time.Duration
+	discoverService *string
+	token           *string
+	authHeader      *string
+	caFile          *string
+
+	board      *string
+	device     *string
+	cable      *string
+	ftdiIndex  *int
+	designName *string
+	dryRun     *bool
+	mode       *string
+	offset     *string
+	flashPoll  *time.Duration
+}
+
+func addLoaderFlags(fs *flag.FlagSet) *loaderFlags {
+	return &loaderFlags{
+		serverURL:       fs.String("loader-server", defaultString(os.Getenv("SPADELOADER_SERVER"), ""), "spadeloader server base url (if empty, auto-discover)"),
+		discoverEnabled: fs.Bool("loader-discover", true, "auto-discover the spadeloader when --loader-server is not provided"),
+		discoverTimeout: fs.Duration("loader-discover-timeout", 2*time.Second, "spadeloader mDNS auto-discovery timeout"),
+		discoverService: fs.String("loader-discover-service", defaultLoaderService, "mDNS service name used for spadeloader discovery"),
+		token:           fs.String("loader-token", strings.TrimSpace(os.Getenv("SPADELOADER_TOKEN")), "spadeloader auth token"),
+		authHeader:      fs.String("loader-auth-header", defaultString(os.Getenv("SPADELOADER_AUTH_HEADER"), "X-Build-Token"), "spadeloader auth header"),
+		caFile:          fs.String("loader-ca-file", strings.TrimSpace(os.Getenv("SPADELOADER_CA_FILE")), "PEM CA bundle trusted for the spadeloader in addition to system roots"),
+
+		board:      fs.String("board", "", "fpga board to flash (required, example: alchitry_au)"),
+		device:     fs.String("device", "", "programmer to use when several boards are attached: serial:<FTDI serial> or busdev:<bus>:<device>"),
+		cable:      fs.String("cable", "", "openFPGALoader cable overriding the board's default (example: ft2232)"),
+		ftdiIndex:  fs.Int("ftdi-index", -1, "pick among identical cables by probe order (0 is the first)"),
+		designName: fs.String("name", "{project}", "template for the design name shown by the spadeloader, e.g. {project}-{git_short}"),
+		dryRun:     fs.Bool("flash-dry-run", false, "have the spadeloader detect the board without programming it"),
+		mode:       fs.String("flash-mode", "sram", "sram loads the FPGA until power-off; flash writes the board's SPI flash so the design persists"),
+		offset:     fs.String("flash-offset", "", "SPI flash offset for --flash-mode flash, decimal or 0x hex (default 0)"),
+		flashPoll:  fs.Duration("flash-poll", 2*time.Second, "flash status polling interval when the event stream ends early"),
+	}
+}
+
+// check validates the loader flags of fs before anything is built.
+func (f *loaderFlags) check(fs *flag.FlagSet) error {
+	if strings.TrimSpace(*f.board) == "" {
+		return fmt.Errorf("--board is required")
+	}
+	if _, err := f.target(); err != nil {
+		return err
+	}
+	if _, _, err := f.flashMode(); err != nil {
+		return err
+	}
+	if err := artifactname.Validate(*f.designName); err != nil {
+		return fmt.Errorf("--name: %w", err)
+	}
+	if fs.Lookup("wait").Value.String() != "true" {
+		return fmt.Errorf("--wait=false cannot be used with run")
+	}
+	if fs.Lookup("json").Value.String() == "true" {
+		return fmt.Errorf("--json is not supported by run")
+	}
+	if _, err := httptransport.NewClient(httptransport.Options{CAFile: *f.caFile}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (f *loaderFlags) target() (loaderjob.Target, error) {
+	return loaderjob.NewTarget(*f.device, *f.cable, *f.ftdiIndex)
+}
+
+func (f *loaderFlags) flashMode() (loaderjob.Mode, uint32, error) {
+	mode, err := loaderjob.ParseMode(*f.mode)
+	if err != nil {
+		return "", 0, fmt.Errorf("--flash-mode: %w", err)
+	}
+	offset, err := loaderjob.ParseFlashOffset(*f.offset)
+	if err != nil {
+		return "", 0, fmt.Errorf("--flash-offset: %w", err)
+	}
+	if err := loaderjob.ValidateFlashOffset(mode, offset); err != nil {
+		return "", 0, fmt.Errorf("--flash-offset: %w", err)
+	}
+	return mode, offset, nil
+}
+
+func (f *loaderFlags) newClient() (*loaderclient.HTTPClient, error) {
+	resolved, err := resolveServerURL(*f.serverURL, *f.discoverEnabled, *f.discoverTimeout, *f.discoverService, discovery.DefaultDomain)
+	if err != nil {
+		return nil, fmt.Errorf("spadeloader: %w", err)
+	}
+	return &loaderclient.HTTPClient{
+		BaseURL:    resolved,
+		Token:      *f.token,
+		AuthHeader: *f.authHeader,
+		CAFile:     *f.caFile,
+	}, nil
+}
+
+// runRun builds like submit, then flashes the extracted design.bit with a
+// spadeloader and waits for the flash to finish.
+func runRun(args []string) error {
+	var lf *loaderFlags
+	built, err := submitBuild("spadeforge-cli run", args, func(fs *flag.FlagSet) func() error {
+		lf = addLoaderFlags(fs)
+		return func() error { return lf.check(fs) }
+	})
+	if err != nil {
+		return err
+	}
+
+	bitstream := filepath.Join(built.OutputDir, "design.bit")
+	if _, err := os.Stat(bitstream); err != nil {
+		return fmt.Errorf("no design.bit in %s to flash: %w", built.OutputDir, err)
+	}
+	design, err := artifactname.Expand(*lf.designName, built.Record.NameVars())
+	if err != nil {
+		return fmt.Errorf("--name: %w", err)
+	}
+	target, err := lf.target()
+	if err != nil {
+		return err
+	}
+	mode, offset, err := lf.flashMode()
+	if err != nil {
+		return err
+	}
+	c, err := lf.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	flashID, err := c.SubmitFlash(ctx, loaderclient.SubmitRequest{
+		Board:         strings.TrimSpace(*lf.board),
+		DesignName:    design,
+		BitstreamPath: bitstream,
+		DryRun:        *lf.dryRun,
+		Mode:          mode,
+		FlashOffset:   offset,
+		Target:        target,
+	})
+	if err != nil {
+		return fmt.Errorf("submit flash: %w", err)
+	}
+	fmt.Printf("flash submitted: %s (board=%s design=%s)\n", flashID, strings.TrimSpace(*lf.board), design)
+
+	rec, err := waitForFlash(ctx, c, flashID, *lf.flashPoll)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("flash finished: %s (%s)\n", rec.State, rec.Message)
+	if rec.State != loaderjob.StateSucceeded {
+		if rec.FailureKind != "" {
+			fmt.Printf("flash failure: %s: %s\n", rec.FailureKind, rec.FailureSummary)
+			if hint := loaderjob.FailureGuidance(rec.FailureKind); hint != "" {
+				fmt.Printf("hint: %s\n", hint)
+			}
+		}
+		return fmt.Errorf("flash %s failed: %s", flashID, defaultString(rec.Error, rec.Message))
+	}
+	return nil
+}
+
+// waitForFlash streams the loader job's events, printing each progress
+// change, and polls once the stream ends before the job does.
+func waitForFlash(ctx context.Context, c *loaderclient.HTTPClient, jobID string, poll time.Duration) (*loaderjob.Record, error) {
+	var last string
+	observe := func(state loaderjob.State, step string, message string) {
+		if state == "" || state == loaderjob.StateSucceeded || state == loaderjob.StateFailed {
+			return
+		}
+		line := fmt.Sprintf("flash state=%s step=%s message=%s", state, defaultString(step, "-"), message)
+		if line != last {
+			fmt.Println(line)
+			last = line
+		}
+	}
+
+	if err := c.StreamEvents(ctx, jobID, 0, func(ev *loaderjob.Event) {
+		observe(ev.State, ev.Step, ev.Message)
+	}); err != nil {
+		return nil, err
+	}
+	rec, err := c.GetJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Terminal() {
+		return rec, nil
+	}
+	return c.WaitForTerminalWithProgress(ctx, jobID, poll, func(update *loaderjob.Record) {
+		observe(update.State, update.CurrentStep, update.Message)
+	})
+}
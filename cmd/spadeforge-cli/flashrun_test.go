@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	loaderclient "github.com/mblsha/spadeforge/internal/spadeloader/client"
+	loaderjob "github.com/mblsha/spadeforge/internal/spadeloader/job"
+)
+
+func TestRunRun_RequiresBoardBeforeBuilding(t *testing.T) {
+	t.Parallel()
+
+	err := runRun([]string{"--discover=false", "--project", "p", "--top", "top", "--part", "xc7a35t", "--source", "top.sv"})
+	if err == nil || !strings.Contains(err.Error(), "--board is required") {
+		t.Fatalf("runRun() error = %v, want --board is required", err)
+	}
+}
+
+func TestRunRun_RejectsNoWait(t *testing.T) {
+	t.Parallel()
+
+	err := runRun([]string{"--discover=false", "--board", "alchitry_au", "--wait=false"})
+	if err == nil || !strings.Contains(err.Error(), "--wait=false") {
+		t.Fatalf("runRun() error = %v, want --wait=false rejection", err)
+	}
+}
+
+func TestWaitForFlash_StreamEndsEarlyFallsBackToPolling(t *testing.T) {
+	t.Parallel()
+
+	var getCalls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/jobs/f1/events":
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("data: {\"job_id\":\"f1\",\"state\":\"RUNNING\",\"step\":\"flash\",\"message\":\"flashing\"}\n\n"))
+		case "/v1/jobs/f1":
+			state := loaderjob.StateRunning
+			if getCalls.Add(1) >= 2 {
+				state = loaderjob.StateSucceeded
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(&loaderjob.Record{ID: "f1", State: state})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	c := &loaderclient.HTTPClient{BaseURL: ts.URL, Client: ts.Client()}
+	rec, err := waitForFlash(context.Background(), c, "f1", 5*time.Millisecond)
+	if err != nil {
+		t.Fatalf("waitForFlash() error: %v", err)
+	}
+	if rec.State != loaderjob.StateSucceeded {
+		t.Fatalf("state = %s, want %s", rec.State, loaderjob.StateSucceeded)
+	}
+	if getCalls.Load() < 2 {
+		t.Fatalf("expected fallback polling after stream close, getCalls=%d", getCalls.Load())
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+// jsonLine is one line of --json output. Event says what happened:
+// submitted, state, finished, diagnostic, artifacts, status or
+// diagnostics_summary; the other fields are set when they apply.
+type jsonLine struct {
+	Event string    `json:"event"`
+	JobID string    `json:"job_id,omitempty"`
+	State job.State `json:"state,omitempty"`
+	Step  string    `json:"step,omitempty"`
+
+	Message        string `json:"message,omitempty"`
+	Error          string `json:"error,omitempty"`
+	FailureKind    string `json:"failure_kind,omitempty"`
+	FailureSummary string `json:"failure_summary,omitempty"`
+
+	Project       string `json:"project,omitempty"`
+	QueuePosition int    `json:"queue_position,omitempty"`
+	CachedFrom    string `json:"cached_from,omitempty"`
+
+	ElapsedSeconds float64    `json:"elapsed_seconds,omitempty"`
+	HeartbeatAt    *time.Time `json:"heartbeat_at,omitempty"`
+
+	Diagnostic *job.Diagnostic                `json:"diagnostic,omitempty"`
+	Summary    *job.ProjectDiagnosticsSummary `json:"summary,omitempty"`
+
+	ArtifactsDir string   `json:"artifacts_dir,omitempty"`
+	ArtifactZip  string   `json:"artifact_zip,omitempty"`
+	Files        []string `json:"files,omitempty"`
+
+	At time.Time `json:"at"`
+}
+
+// jsonWriter prints JSON lines for --json. A nil writer means human output.
+type jsonWriter struct {
+	enc *json.Encoder
+}
+
+func newJSONWriter(w io.Writer, enabled bool) *jsonWriter {
+	if !enabled {
+		return nil
+	}
+	return &jsonWriter{enc: json.NewEncoder(w)}
+}
+
+func (w *jsonWriter) emit(line jsonLine) {
+	if line.At.IsZero() {
+		line.At = time.Now().UTC()
+	}
+	_ = w.enc.Encode(line)
+}
+
+// emitSubmitted reports the accepted job.
+func (w *jsonWriter) emitSubmitted(resp *job.SubmitResponse) {
+	state := job.StateQueued
+	if resp.CachedFrom != "" {
+		state = job.StateSucceeded
+	}
+	w.emit(jsonLine{
+		Event:         "submitted",
+		JobID:         resp.JobID,
+		State:         state,
+		Project:       resp.Manifest.Project,
+		QueuePosition: resp.QueuePosition,
+		CachedFrom:    resp.CachedFrom,
+	})
+}
+
+// emitFinished reports the job's terminal record.
+func (w *jsonWriter) emitFinished(rec *job.Record) {
+	line := jsonLine{
+		Event:          "finished",
+		JobID:          rec.ID,
+		State:          rec.State,
+		Message:        rec.Message,
+		Error:          rec.Error,
+		FailureKind:    rec.FailureKind,
+		FailureSummary: rec.FailureSummary,
+		Project:        rec.Manifest.Project,
+	}
+	if rec.StartedAt != nil && rec.FinishedAt != nil {
+		line.ElapsedSeconds = rec.FinishedAt.Sub(*rec.StartedAt).Seconds()
+	}
+	w.emit(line)
+}
+
+// emitDiagnostics reports up to limit unsuppressed errors.
+func (w *jsonWriter) emitDiagnostics(jobID string, report *job.DiagnosticsReport, limit int) {
+	if report == nil {
+		return
+	}
+	emitted := 0
+	for i := range report.Diagnostics {
+		d := report.Diagnostics[i]
+		if d.Severity != job.SeverityError || d.Suppressed {
+			continue
+		}
+		w.emit(jsonLine{Event: "diagnostic", JobID: jobID, Diagnostic: &d})
+		if emitted++; emitted >= limit {
+			return
+		}
+	}
+}
+
+// emitArtifacts reports where the artifacts went, listing each extracted
+// file under dir.
+func (w *jsonWriter) emitArtifacts(jobID, dir, zipPath string, artifactZip []byte) {
+	line := jsonLine{Event: "artifacts", JobID: jobID, ArtifactsDir: dir, ArtifactZip: zipPath}
+	if zr, err := zip.NewReader(bytes.NewReader(artifactZip), int64(len(artifactZip))); err == nil {
+		for _, f := range zr.File {
+			if !f.FileInfo().IsDir() {
+				line.Files = append(line.Files, filepath.Join(dir, filepath.FromSlash(f.Name)))
+			}
+		}
+	}
+	w.emit(line)
+}
+
+// emitStatus reports one status line per job and per unknown ID.
+func (w *jsonWriter) emitStatus(resp *job.StatusResponse) {
+	for _, rec := range resp.Jobs {
+		w.emit(jsonLine{
+			Event:          "status",
+			JobID:          rec.ID,
+			State:          rec.State,
+			Step:           rec.CurrentStep,
+			Message:        rec.Message,
+			Error:          rec.Error,
+			FailureKind:    rec.FailureKind,
+			FailureSummary: rec.FailureSummary,
+			Project:        rec.Manifest.Project,
+		})
+	}
+	for _, id := range resp.Missing {
+		w.emit(jsonLine{Event: "status", JobID: id, Error: "not found"})
+	}
+}
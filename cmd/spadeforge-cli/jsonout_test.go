@@ -0,0 +1,110 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+func decodeJSONLines(t *testing.T, buf *bytes.Buffer) []jsonLine {
+	t.Helper()
+	var lines []jsonLine
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var line jsonLine
+		if err := dec.Decode(&line); err != nil {
+			t.Fatalf("decode json line: %v", err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestJSONWriter_NilWhenDisabled(t *testing.T) {
+	if out := newJSONWriter(&bytes.Buffer{}, false); out != nil {
+		t.Fatalf("newJSONWriter(disabled) = %v, want nil", out)
+	}
+}
+
+func TestJSONWriter_ProgressAndFinished(t *testing.T) {
+	var buf bytes.Buffer
+	out := newJSONWriter(&buf, true)
+
+	started := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	finished := started.Add(90 * time.Second)
+	printProgress(out, "j1", job.StateRunning, "synth", 3*time.Second, started.Format(time.RFC3339), "synthesizing")
+	printProgress(out, "j1", job.StateQueued, "-", 0, "-", "")
+	out.emitFinished(&job.Record{
+		ID:             "j1",
+		State:          job.StateFailed,
+		Error:          "vivado exited 1",
+		FailureKind:    "timing",
+		FailureSummary: "WNS -0.2ns",
+		StartedAt:      &started,
+		FinishedAt:     &finished,
+	})
+
+	lines := decodeJSONLines(t, &buf)
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3", len(lines))
+	}
+	if l := lines[0]; l.Event != "state" || l.JobID != "j1" || l.State != job.StateRunning || l.Step != "synth" || l.ElapsedSeconds != 3 || l.HeartbeatAt == nil || !l.HeartbeatAt.Equal(started) {
+		t.Fatalf("unexpected state line: %+v", l)
+	}
+	if l := lines[1]; l.Step != "" || l.HeartbeatAt != nil {
+		t.Fatalf("placeholder step/heartbeat leaked into json: %+v", l)
+	}
+	if l := lines[2]; l.Event != "finished" || l.FailureKind != "timing" || l.FailureSummary != "WNS -0.2ns" || l.ElapsedSeconds != 90 || l.At.IsZero() {
+		t.Fatalf("unexpected finished line: %+v", l)
+	}
+}
+
+func TestJSONWriter_ArtifactsListsExtractedFiles(t *testing.T) {
+	var zipBuf bytes.Buffer
+	zw := zip.NewWriter(&zipBuf)
+	for _, name := range []string{"design.bit", "reports/", "reports/timing.rpt"} {
+		if _, err := zw.Create(name); err != nil {
+			t.Fatalf("zip create: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip close: %v", err)
+	}
+
+	var buf bytes.Buffer
+	newJSONWriter(&buf, true).emitArtifacts("j1", "output/j1", "", zipBuf.Bytes())
+
+	lines := decodeJSONLines(t, &buf)
+	if len(lines) != 1 || lines[0].Event != "artifacts" || lines[0].ArtifactsDir != "output/j1" {
+		t.Fatalf("unexpected artifacts lines: %+v", lines)
+	}
+	want := []string{filepath.Join("output/j1", "design.bit"), filepath.Join("output/j1", "reports", "timing.rpt")}
+	if !reflect.DeepEqual(lines[0].Files, want) {
+		t.Fatalf("files = %v, want %v", lines[0].Files, want)
+	}
+}
+
+func TestJSONWriter_StatusReportsMissingJobs(t *testing.T) {
+	var buf bytes.Buffer
+	newJSONWriter(&buf, true).emitStatus(&job.StatusResponse{
+		Jobs:    []job.Record{{ID: "j1", State: job.StateSucceeded}},
+		Missing: []string{"nope"},
+	})
+
+	lines := decodeJSONLines(t, &buf)
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	if lines[0].JobID != "j1" || lines[0].State != job.StateSucceeded {
+		t.Fatalf("unexpected job line: %+v", lines[0])
+	}
+	if lines[1].JobID != "nope" || lines[1].Error != "not found" {
+		t.Fatalf("unexpected missing line: %+v", lines[1])
+	}
+}
@@ -6,22 +6,40 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"maps"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"text/tabwriter"
 	"time"
 
+	"github.com/mblsha/spadeforge/internal/artifactname"
 	"github.com/mblsha/spadeforge/internal/client"
 	"github.com/mblsha/spadeforge/internal/discovery"
+	"github.com/mblsha/spadeforge/internal/flashimage"
+	"github.com/mblsha/spadeforge/internal/httpretry"
+	"github.com/mblsha/spadeforge/internal/httptransport"
 	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/logx"
+	"github.com/mblsha/spadeforge/internal/manifest"
 )
 
 var discoverFn = discovery.Discover
 
 func main() {
 	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "cancel" {
+		if err := runCancel(args[1:]); err != nil {
+			log.Fatalf("cancel failed: %v", err)
+		}
+		return
+	}
 	if len(args) > 0 && args[0] == "kill" {
 		if err := runKill(args[1:]); err != nil {
 			log.Fatalf("kill failed: %v", err)
@@ -32,116 +50,1050 @@ func main() {
 		if err := runKillAllVivado(args[1:]); err != nil {
 			log.Fatalf("kill-all-vivado failed: %v", err)
 		}
-		return
-	}
-	if len(args) > 0 && args[0] == "submit" {
-		args = args[1:]
+		return
+	}
+	if len(args) > 0 && args[0] == "workdir" {
+		if err := runWorkDir(args[1:]); err != nil {
+			log.Fatalf("workdir failed: %v", err)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "bundle" {
+		if err := runBundle(args[1:]); err != nil {
+			log.Fatalf("bundle failed: %v", err)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "flash-image" {
+		if err := runFlashImage(args[1:]); err != nil {
+			log.Fatalf("flash-image failed: %v", err)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "repro" {
+		if err := runRepro(args[1:]); err != nil {
+			log.Fatalf("repro failed: %v", err)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "log" {
+		if err := runLog(args[1:]); err != nil {
+			log.Fatalf("log failed: %v", err)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "baseline" {
+		if err := runBaseline(args[1:]); err != nil {
+			log.Fatalf("baseline failed: %v", err)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "self-update" {
+		if err := runSelfUpdate(args[1:]); err != nil {
+			log.Fatalf("self-update failed: %v", err)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "pin" {
+		if err := runPin(args[1:]); err != nil {
+			log.Fatalf("pin failed: %v", err)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "energy" {
+		if err := runEnergy(args[1:]); err != nil {
+			log.Fatalf("energy failed: %v", err)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "me" {
+		if err := runMe(args[1:]); err != nil {
+			log.Fatalf("me failed: %v", err)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "loglevel" {
+		if err := runLogLevel(args[1:]); err != nil {
+			log.Fatalf("loglevel failed: %v", err)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "status" {
+		if err := runStatus(args[1:]); err != nil {
+			log.Fatalf("status failed: %v", err)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "runs" {
+		if err := runRuns(args[1:]); err != nil {
+			log.Fatalf("runs failed: %v", err)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "jobs" {
+		if err := runJobs(args[1:]); err != nil {
+			log.Fatalf("jobs failed: %v", err)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "diagnostics-summary" {
+		if err := runDiagnosticsSummary(args[1:]); err != nil {
+			log.Fatalf("diagnostics-summary failed: %v", err)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "report" {
+		if err := runReport(args[1:]); err != nil {
+			log.Fatalf("report failed: %v", err)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "run" {
+		if err := runRun(args[1:]); err != nil {
+			log.Fatalf("run failed: %v", err)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "submit" {
+		args = args[1:]
+	}
+	if err := runSubmit(args); err != nil {
+		log.Fatalf("submit failed: %v", err)
+	}
+}
+
+func runKillAllVivado(args []string) error {
+	fs := flag.NewFlagSet("spadeforge-cli kill-all-vivado", flag.ContinueOnError)
+	sf := addServerFlags(fs)
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	c, err := sf.newClient()
+	if err != nil {
+		return err
+	}
+	result, err := c.KillAllVivado(context.Background())
+	if err != nil {
+		return err
+	}
+	fmt.Println(result)
+	return nil
+}
+
+func runCancel(args []string) error {
+	fs := flag.NewFlagSet("spadeforge-cli cancel", flag.ContinueOnError)
+	sf := addServerFlags(fs)
+	jobID := fs.String("job-id", "", "job ID to cancel (or pass it as the argument)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	id := strings.TrimSpace(*jobID)
+	if id == "" && fs.NArg() == 1 {
+		id = strings.TrimSpace(fs.Arg(0))
+	}
+	if id == "" || fs.NArg() > 1 {
+		return fmt.Errorf("usage: spadeforge-cli cancel <job_id>")
+	}
+
+	c, err := sf.newClient()
+	if err != nil {
+		return err
+	}
+	rec, err := c.CancelJob(context.Background(), id)
+	if err != nil {
+		return err
+	}
+	if rec.Terminal() {
+		fmt.Printf("job %s canceled\n", id)
+	} else {
+		fmt.Printf("job %s is stopping (state=%s)\n", id, rec.State)
+	}
+	return nil
+}
+
+func runPin(args []string) error {
+	fs := flag.NewFlagSet("spadeforge-cli pin", flag.ContinueOnError)
+	sf := addServerFlags(fs)
+	jobID := fs.String("job-id", "", "job ID to pin (or pass it as the argument)")
+	unpin := fs.Bool("unpin", false, "let retention remove the job again")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	id := strings.TrimSpace(*jobID)
+	if id == "" && fs.NArg() == 1 {
+		id = strings.TrimSpace(fs.Arg(0))
+	}
+	if id == "" || fs.NArg() > 1 {
+		return fmt.Errorf("usage: spadeforge-cli pin [--unpin] <job_id>")
+	}
+
+	c, err := sf.newClient()
+	if err != nil {
+		return err
+	}
+	rec, err := c.PinJob(context.Background(), id, !*unpin)
+	if err != nil {
+		return err
+	}
+	if rec.Pinned {
+		fmt.Printf("job %s pinned; retention will keep it\n", rec.ID)
+	} else {
+		fmt.Printf("job %s unpinned\n", rec.ID)
+	}
+	return nil
+}
+
+func runKill(args []string) error {
+	fs := flag.NewFlagSet("spadeforge-cli kill", flag.ContinueOnError)
+	sf := addServerFlags(fs)
+	jobID := fs.String("job-id", "", "job ID to kill (required)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if strings.TrimSpace(*jobID) == "" {
+		return fmt.Errorf("--job-id is required")
+	}
+
+	c, err := sf.newClient()
+	if err != nil {
+		return err
+	}
+	if err := c.KillJob(context.Background(), *jobID); err != nil {
+		return err
+	}
+	fmt.Printf("kill signal sent to job %s\n", *jobID)
+	return nil
+}
+
+func runWorkDir(args []string) error {
+	fs := flag.NewFlagSet("spadeforge-cli workdir", flag.ContinueOnError)
+	sf := addServerFlags(fs)
+	jobID := fs.String("job-id", "", "job ID whose preserved work dir to browse (required)")
+	file := fs.String("file", "", "work dir relative path to download (if empty, list files)")
+	out := fs.String("out", "", "output path for --file (default: base name in current directory)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if strings.TrimSpace(*jobID) == "" {
+		return fmt.Errorf("--job-id is required")
+	}
+
+	c, err := sf.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	if strings.TrimSpace(*file) == "" {
+		entries, err := c.ListWorkDir(ctx, *jobID)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			fmt.Printf("%12d  %s  %s\n", e.Size, e.ModTime.Format(time.RFC3339), e.Path)
+		}
+		return nil
+	}
+
+	target := strings.TrimSpace(*out)
+	if target == "" {
+		target = filepath.Base(filepath.FromSlash(*file))
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := c.DownloadWorkDirFile(ctx, *jobID, *file, f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("%s written to %s\n", *file, target)
+	return nil
+}
+
+func runBundle(args []string) error {
+	fs := flag.NewFlagSet("spadeforge-cli bundle", flag.ContinueOnError)
+	sf := addServerFlags(fs)
+	jobID := fs.String("job-id", "", "job ID whose request bundle to download (required)")
+	out := fs.String("out", "", "output path (default: <job-id>-bundle.zip)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if strings.TrimSpace(*jobID) == "" {
+		return fmt.Errorf("--job-id is required")
+	}
+
+	c, err := sf.newClient()
+	if err != nil {
+		return err
+	}
+	target := strings.TrimSpace(*out)
+	if target == "" {
+		target = *jobID + "-bundle.zip"
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := c.DownloadBundle(context.Background(), *jobID, f); err != nil {
+		f.Close()
+		_ = os.Remove(target)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("bundle written to %s\n", target)
+	return nil
+}
+
+func runFlashImage(args []string) error {
+	fs := flag.NewFlagSet("spadeforge-cli flash-image", flag.ContinueOnError)
+	sf := addServerFlags(fs)
+	jobID := fs.String("job-id", "", "succeeded job ID whose bitstream to convert (required)")
+	format := fs.String("format", "bin", "flash image format: bin or mcs")
+	offset := fs.String("offset", "0", "flash address the .mcs image starts at, decimal or 0x hex")
+	out := fs.String("out", "", "output path (default: <job-id>-design.<format>)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if strings.TrimSpace(*jobID) == "" {
+		return fmt.Errorf("--job-id is required")
+	}
+	imgFormat, err := flashimage.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+	addr, err := strconv.ParseUint(strings.TrimSpace(*offset), 0, 32)
+	if err != nil {
+		return fmt.Errorf("--offset must be a 32-bit address: %w", err)
+	}
+
+	c, err := sf.newClient()
+	if err != nil {
+		return err
+	}
+	target := strings.TrimSpace(*out)
+	if target == "" {
+		target = *jobID + "-design." + imgFormat
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := c.DownloadFlashImage(context.Background(), *jobID, imgFormat, uint32(addr), f); err != nil {
+		f.Close()
+		_ = os.Remove(target)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("flash image written to %s\n", target)
+	return nil
+}
+
+func runLog(args []string) error {
+	fs := flag.NewFlagSet("spadeforge-cli log", flag.ContinueOnError)
+	sf := addServerFlags(fs)
+	jobID := fs.String("job-id", "", "job ID whose log to download (required)")
+	file := fs.String("file", "console.log", "log to download: console.log or vivado.log")
+	gz := fs.Bool("gz", false, "download gzip-compressed (default output <job-id>-<file>.gz)")
+	out := fs.String("out", "", "output path (default: stdout, or <job-id>-<file>.gz with --gz)")
+	grep := fs.String("grep", "", "search the log on the server for this regular expression instead of downloading it")
+	contextLines := fs.Int("context", 3, "lines of context around --grep matches")
+	sinceFlag := fs.String("since", "", "only lines written at or after this RFC3339 time, or this long ago (e.g. 10m)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if strings.TrimSpace(*jobID) == "" {
+		return fmt.Errorf("--job-id is required")
+	}
+	since, err := parseSince(*sinceFlag, time.Now())
+	if err != nil {
+		return err
+	}
+
+	c, err := sf.newClient()
+	if err != nil {
+		return err
+	}
+	if *grep != "" {
+		result, err := c.SearchLog(context.Background(), *jobID, *file, *grep, *contextLines)
+		if err != nil {
+			return err
+		}
+		printLogSearch(os.Stdout, result)
+		return nil
+	}
+	target := strings.TrimSpace(*out)
+	if target == "" && *gz {
+		target = *jobID + "-" + *file + ".gz"
+	}
+	if target == "" {
+		return c.DownloadLogSince(context.Background(), *jobID, *file, false, since, os.Stdout)
+	}
+
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := c.DownloadLogSince(context.Background(), *jobID, *file, *gz, since, f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%s written to %s\n", *file, target)
+	return nil
+}
+
+// parseSince reads --since as an RFC3339 time or a duration before now.
+func parseSince(raw string, now time.Time) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("--since %q must be an RFC3339 time or a duration like 10m", raw)
+	}
+	return now.Add(-d), nil
+}
+
+func runBaseline(args []string) error {
+	fs := flag.NewFlagSet("spadeforge-cli baseline", flag.ContinueOnError)
+	sf := addServerFlags(fs)
+	jobID := fs.String("job-id", "", "succeeded job to mark as its project's baseline")
+	project := fs.String("project", "", "show the project's current baseline")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if (strings.TrimSpace(*jobID) == "") == (strings.TrimSpace(*project) == "") {
+		return fmt.Errorf("exactly one of --job-id or --project is required")
+	}
+
+	c, err := sf.newClient()
+	if err != nil {
+		return err
+	}
+	var rec *job.Record
+	if *jobID != "" {
+		rec, err = c.SetBaseline(context.Background(), *jobID)
+	} else {
+		rec, err = c.GetProjectBaseline(context.Background(), *project)
+	}
+	if err != nil {
+		return err
+	}
+	printBaseline(os.Stdout, rec)
+	return nil
+}
+
+// printBaseline prints the baseline job and its headline metrics.
+func printBaseline(w io.Writer, rec *job.Record) {
+	fmt.Fprintf(w, "baseline for project %s: %s (%s)\n", rec.Manifest.Project, rec.ID, rec.CreatedAt.Local().Format("2006-01-02 15:04"))
+	if rec.Manifest.Git != nil {
+		fmt.Fprintf(w, "  git: %s\n", rec.Manifest.Git.Short())
+	}
+	if m := rec.Metrics; m != nil {
+		if m.WNS != nil && m.WHS != nil {
+			fmt.Fprintf(w, "  timing: WNS=%.3f ns WHS=%.3f ns\n", *m.WNS, *m.WHS)
+		}
+		keys := make([]string, 0, len(m.Utilization))
+		for key := range m.Utilization {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			u := m.Utilization[key]
+			fmt.Fprintf(w, "  %s: %g/%d (%.2f%%)\n", key, u.Used, u.Available, u.Percent)
+		}
+		fmt.Fprintf(w, "  warnings: %d\n", m.Warnings)
+	}
+}
+
+// printBaselineComparison prints the verdict and every changed metric.
+func printBaselineComparison(w io.Writer, cmp *job.BaselineComparison) {
+	fmt.Fprintf(w, "baseline %s: %s\n", cmp.BaselineJobID, cmp.Summary)
+	for _, d := range cmp.Deltas {
+		if d.Delta == 0 {
+			continue
+		}
+		mark := " "
+		if d.Regressed {
+			mark = "!"
+		}
+		fmt.Fprintf(w, "  %s %s: %g -> %g (%+g)\n", mark, d.Metric, d.Baseline, d.Current, d.Delta)
+	}
+}
+
+func runEnergy(args []string) error {
+	fs := flag.NewFlagSet("spadeforge-cli energy", flag.ContinueOnError)
+	sf := addServerFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	c, err := sf.newClient()
+	if err != nil {
+		return err
+	}
+	stats, err := c.GetEnergyStats(context.Background())
+	if err != nil {
+		return err
+	}
+	printEnergyStats(os.Stdout, stats)
+	return nil
+}
+
+// runMe shows the caller's identity and stored submission defaults, or
+// changes the defaults when any default flag is given.
+func runMe(args []string) error {
+	fs := flag.NewFlagSet("spadeforge-cli me", flag.ContinueOnError)
+	sf := addServerFlags(fs)
+	part := fs.String("part", "", "default target FPGA part")
+	toolchain := fs.String("toolchain", "", "default toolchain: vivado or yosys")
+	board := fs.String("board", "", "default board for run")
+	outputDir := fs.String("output-dir", "", "default artifacts directory")
+	outputName := fs.String("output-name", "", "default artifacts directory name template")
+	bitstreamName := fs.String("bitstream-name", "", "default renamed bitstream template")
+	clear := fs.Bool("clear", false, "remove every stored default before applying the flags above")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	c, err := sf.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	me, err := c.GetMe(ctx)
+	if err != nil {
+		return err
+	}
+	fields := map[string]struct {
+		flag *string
+		dst  *string
+	}{
+		"part":           {part, &me.Defaults.Part},
+		"toolchain":      {toolchain, &me.Defaults.Toolchain},
+		"board":          {board, &me.Defaults.Board},
+		"output-dir":     {outputDir, &me.Defaults.OutputDir},
+		"output-name":    {outputName, &me.Defaults.OutputName},
+		"bitstream-name": {bitstreamName, &me.Defaults.BitstreamName},
+	}
+	update := *clear
+	if *clear {
+		me.Defaults = job.ClientDefaults{}
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if field, ok := fields[f.Name]; ok {
+			*field.dst = *field.flag
+			update = true
+		}
+	})
+	if update {
+		if me, err = c.SetDefaults(ctx, me.Defaults); err != nil {
+			return err
+		}
+	}
+	printMe(os.Stdout, me)
+	return nil
+}
+
+func printMe(w io.Writer, me *job.Me) {
+	fmt.Fprintf(w, "client: %s\n", me.Client)
+	fmt.Fprintf(w, "scopes: %s\n", strings.Join(me.Scopes, ", "))
+	if me.ExpiresAt != nil {
+		fmt.Fprintf(w, "expires: %s\n", me.ExpiresAt.Format(time.RFC3339))
+	}
+	d := me.Defaults
+	if d == (job.ClientDefaults{}) {
+		fmt.Fprintln(w, "defaults: none")
+		return
+	}
+	fmt.Fprintln(w, "defaults:")
+	for _, kv := range [][2]string{
+		{"part", d.Part}, {"toolchain", d.Toolchain}, {"board", d.Board},
+		{"output-dir", d.OutputDir}, {"output-name", d.OutputName}, {"bitstream-name", d.BitstreamName},
+	} {
+		if kv[1] != "" {
+			fmt.Fprintf(w, "  %s: %s\n", kv[0], kv[1])
+		}
+	}
+}
+
+// runLogLevel shows the server's log filters, or changes them when --level
+// or --module is given.
+func runLogLevel(args []string) error {
+	fs := flag.NewFlagSet("spadeforge-cli loglevel", flag.ContinueOnError)
+	sf := addServerFlags(fs)
+	level := fs.String("level", "", "global log level: error, warn, info, debug or trace")
+	var modules stringListFlag
+	fs.Var(&modules, "module", "per-module level as module=level, or module=default to clear (repeatable; modules: "+strings.Join(logx.Modules, ", ")+")")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	c, err := sf.newClient()
+	if err != nil {
+		return err
+	}
+	var settings *logx.Settings
+	if strings.TrimSpace(*level) == "" && len(modules) == 0 {
+		settings, err = c.GetLogLevel(context.Background())
+	} else {
+		req := logx.Settings{Level: *level, Modules: map[string]string{}}
+		for _, entry := range modules {
+			module, moduleLevel, ok := strings.Cut(entry, "=")
+			if !ok {
+				return fmt.Errorf("invalid --module %q (want module=level)", entry)
+			}
+			req.Modules[strings.TrimSpace(module)] = strings.TrimSpace(moduleLevel)
+		}
+		settings, err = c.SetLogLevel(context.Background(), req)
+	}
+	if err != nil {
+		return err
+	}
+	printLogLevel(os.Stdout, settings)
+	return nil
+}
+
+func printLogLevel(w io.Writer, settings *logx.Settings) {
+	fmt.Fprintf(w, "level: %s\n", settings.Level)
+	for _, module := range logx.Modules {
+		if level, ok := settings.Modules[module]; ok {
+			fmt.Fprintf(w, "  %s: %s\n", module, level)
+		}
+	}
+}
+
+// printEnergyStats prints per-project energy totals, largest first.
+func printEnergyStats(w io.Writer, stats *job.EnergyStats) {
+	if len(stats.Projects) == 0 {
+		fmt.Fprintln(w, "no builds with energy data")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROJECT\tBUILDS\tBUILD TIME\tENERGY")
+	for _, p := range append(stats.Projects, stats.Total) {
+		name := p.Project
+		if name == "" {
+			name = "total"
+		}
+		wall := time.Duration(p.WallSeconds * float64(time.Second)).Round(time.Second)
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", name, p.Builds, wall, formatEnergy(p.WattHours, p.Cost, ""))
+	}
+	_ = tw.Flush()
+}
+
+func formatEnergy(wattHours, cost float64, source string) string {
+	out := fmt.Sprintf("%.1f Wh", wattHours)
+	if cost > 0 {
+		out += fmt.Sprintf(" (cost %.2f)", cost)
+	}
+	if source != "" {
+		out += " via " + source
+	}
+	return out
+}
+
+func runStatus(args []string) error {
+	fs := flag.NewFlagSet("spadeforge-cli status", flag.ContinueOnError)
+	sf := addServerFlags(fs)
+	var jobIDs stringListFlag
+	fs.Var(&jobIDs, "job-id", "job ID to show (repeatable; positional IDs also accepted)")
+	jsonOut := fs.Bool("json", false, "print one JSON line per job instead of a table")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	jobIDs = append(jobIDs, fs.Args()...)
+	if len(jobIDs) == 0 {
+		return fmt.Errorf("at least one --job-id is required")
+	}
+
+	c, err := sf.newClient()
+	if err != nil {
+		return err
+	}
+	resp, err := c.GetJobs(context.Background(), jobIDs)
+	if err != nil {
+		return err
+	}
+	if out := newJSONWriter(os.Stdout, *jsonOut); out != nil {
+		out.emitStatus(resp)
+	} else {
+		printJobsStatus(os.Stdout, resp)
+	}
+	if len(resp.Missing) > 0 {
+		return fmt.Errorf("%d job(s) not found", len(resp.Missing))
+	}
+	return nil
+}
+
+// printJobsStatus prints one line per job followed by any unknown IDs.
+func printJobsStatus(w io.Writer, resp *job.StatusResponse) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, rec := range resp.Jobs {
+		step := defaultString(rec.CurrentStep, "-")
+		commit := "-"
+		if rec.Manifest.Git != nil {
+			commit = rec.Manifest.Git.Short()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", rec.ID, defaultString(rec.Manifest.Project, "-"), commit, rec.State, step, rec.Message)
 	}
-	if err := runSubmit(args); err != nil {
-		log.Fatalf("submit failed: %v", err)
+	for _, id := range resp.Missing {
+		fmt.Fprintf(tw, "%s\t-\t-\tNOT FOUND\t-\t\n", id)
 	}
+	_ = tw.Flush()
 }
 
-func runKillAllVivado(args []string) error {
-	fs := flag.NewFlagSet("spadeforge-cli kill-all-vivado", flag.ContinueOnError)
-	serverURL := fs.String("server", defaultString(os.Getenv("SPADEFORGE_SERVER"), ""), "builder server base url (if empty, auto-discover)")
-	discoverEnabled := fs.Bool("discover", true, "auto-discover server when --server is not provided")
-	discoverTimeout := fs.Duration("discover-timeout", 2*time.Second, "mDNS auto-discovery timeout")
-	discoverService := fs.String("discover-service", discovery.DefaultServiceName, "mDNS service name used for discovery")
-	discoverDomain := fs.String("discover-domain", discovery.DefaultDomain, "mDNS discovery domain")
-	token := fs.String("token", strings.TrimSpace(os.Getenv("SPADEFORGE_TOKEN")), "auth token")
-	authHeader := fs.String("auth-header", defaultString(os.Getenv("SPADEFORGE_AUTH_HEADER"), "X-Build-Token"), "auth header")
+func runRuns(args []string) error {
+	fs := flag.NewFlagSet("spadeforge-cli runs", flag.ContinueOnError)
+	sf := addServerFlags(fs)
+	jobID := fs.String("job-id", "", "any build job of the run (positional ID also accepted)")
 
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	if *jobID == "" && fs.NArg() > 0 {
+		*jobID = fs.Arg(0)
+	}
+	if strings.TrimSpace(*jobID) == "" {
+		return fmt.Errorf("--job-id is required")
+	}
 
-	resolvedServerURL, err := resolveServerURL(*serverURL, *discoverEnabled, *discoverTimeout, *discoverService, *discoverDomain)
+	c, err := sf.newClient()
 	if err != nil {
 		return err
 	}
-
-	c := &client.HTTPClient{BaseURL: resolvedServerURL, Token: *token, AuthHeader: *authHeader}
-	result, err := c.KillAllVivado(context.Background())
+	run, err := c.GetRun(context.Background(), *jobID)
 	if err != nil {
 		return err
 	}
-	fmt.Println(result)
+	printRun(os.Stdout, run)
 	return nil
 }
 
-func runKill(args []string) error {
-	fs := flag.NewFlagSet("spadeforge-cli kill", flag.ContinueOnError)
-	serverURL := fs.String("server", defaultString(os.Getenv("SPADEFORGE_SERVER"), ""), "builder server base url (if empty, auto-discover)")
-	discoverEnabled := fs.Bool("discover", true, "auto-discover server when --server is not provided")
-	discoverTimeout := fs.Duration("discover-timeout", 2*time.Second, "mDNS auto-discovery timeout")
-	discoverService := fs.String("discover-service", discovery.DefaultServiceName, "mDNS service name used for discovery")
-	discoverDomain := fs.String("discover-domain", discovery.DefaultDomain, "mDNS discovery domain")
-	token := fs.String("token", strings.TrimSpace(os.Getenv("SPADEFORGE_TOKEN")), "auth token")
-	authHeader := fs.String("auth-header", defaultString(os.Getenv("SPADEFORGE_AUTH_HEADER"), "X-Build-Token"), "auth header")
-	jobID := fs.String("job-id", "", "job ID to kill (required)")
+// printRun prints the run's roll-up followed by one line per build and
+// flash.
+func printRun(w io.Writer, run *job.Run) {
+	fmt.Fprintf(w, "run %s: %s (stage %s)\n", run.ID, run.State, run.Stage)
+	if run.Error != "" {
+		fmt.Fprintf(w, "  error: %s\n", run.Error)
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, b := range run.Builds {
+		fmt.Fprintf(tw, "  build\t%s\t%s\t%s\t%s\n", b.JobID, defaultString(b.Project, "-"), b.State, defaultString(b.FailureKind, "-"))
+	}
+	for _, f := range run.Flashes {
+		state := defaultString(string(f.State), "UNKNOWN")
+		if f.Step != "" {
+			state += " (" + f.Step + ")"
+		}
+		test := "-"
+		if f.Test != nil {
+			test = "test passed"
+			if !f.Test.Passed {
+				test = "test failed: " + f.Test.Summary
+			}
+		}
+		fmt.Fprintf(tw, "  flash\t%s\t%s/%s\t%s\t%s\n", defaultString(f.JobID, "-"), f.Loader, defaultString(f.Board, "-"), state, test)
+		if f.Error != "" {
+			fmt.Fprintf(tw, "  \t\terror: %s\t\t\n", f.Error)
+		}
+	}
+	_ = tw.Flush()
+}
+
+func runJobs(args []string) error {
+	fs := flag.NewFlagSet("spadeforge-cli jobs", flag.ContinueOnError)
+	sf := addServerFlags(fs)
+	var states stringListFlag
+	fs.Var(&states, "state", "only show jobs in this state (repeatable, e.g. FAILED)")
+	limit := fs.Int("limit", 0, "number of jobs to show (default: server default)")
+	offset := fs.Int("offset", 0, "skip this many of the newest matching jobs")
+	follow := fs.Bool("follow", false, "print the queued and running jobs, then stream every job's events until interrupted")
 
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
-	if strings.TrimSpace(*jobID) == "" {
-		return fmt.Errorf("--job-id is required")
+	opts := client.ListJobsOptions{Limit: *limit, Offset: *offset}
+	for _, raw := range states {
+		state, err := job.ParseState(raw)
+		if err != nil {
+			return err
+		}
+		opts.States = append(opts.States, state)
 	}
 
-	resolvedServerURL, err := resolveServerURL(*serverURL, *discoverEnabled, *discoverTimeout, *discoverService, *discoverDomain)
+	c, err := sf.newClient()
+	if err != nil {
+		return err
+	}
+	if *follow {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		err := c.StreamAllEvents(ctx, 0, opts.States, func(ev *job.Event) { printJobEvent(os.Stdout, ev) })
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+	list, err := c.ListJobs(context.Background(), opts)
 	if err != nil {
 		return err
 	}
+	printJobsStatus(os.Stdout, &job.StatusResponse{Jobs: list.Items})
+	if shown := list.Offset + len(list.Items); shown < list.Total {
+		fmt.Printf("%d of %d jobs shown; next page: --offset %d\n", len(list.Items), list.Total, shown)
+	}
+	return nil
+}
 
-	c := &client.HTTPClient{BaseURL: resolvedServerURL, Token: *token, AuthHeader: *authHeader}
-	if err := c.KillJob(context.Background(), *jobID); err != nil {
+// printJobEvent prints one line of a jobs --follow stream.
+func printJobEvent(w io.Writer, ev *job.Event) {
+	fmt.Fprintf(w, "%s %s %s %s %s step=%s %s\n", ev.At.Local().Format(time.TimeOnly), ev.JobID, defaultString(ev.Project, "-"), ev.Type, ev.State, defaultString(ev.Step, "-"), ev.Message)
+}
+
+func runDiagnosticsSummary(args []string) error {
+	fs := flag.NewFlagSet("spadeforge-cli diagnostics-summary", flag.ContinueOnError)
+	sf := addServerFlags(fs)
+	project := fs.String("project", "", "project name (required)")
+	limit := fs.Int("limit", 0, "number of recent builds to include (default: server default)")
+	jsonOut := fs.Bool("json", false, "print the summary as one JSON line")
+
+	if err := fs.Parse(args); err != nil {
 		return err
 	}
-	fmt.Printf("kill signal sent to job %s\n", *jobID)
+	if strings.TrimSpace(*project) == "" {
+		return fmt.Errorf("--project is required")
+	}
+
+	c, err := sf.newClient()
+	if err != nil {
+		return err
+	}
+	summary, err := c.GetProjectDiagnosticsSummary(context.Background(), *project, *limit)
+	if err != nil {
+		return err
+	}
+	if out := newJSONWriter(os.Stdout, *jsonOut); out != nil {
+		out.emit(jsonLine{Event: "diagnostics_summary", Project: summary.Project, Summary: summary})
+		return nil
+	}
+	printDiagnosticsSummary(os.Stdout, summary)
 	return nil
 }
 
+// printDiagnosticsSummary prints one line per build (counts and +new/-resolved
+// against the previous build) followed by the recurring diagnostics.
+func printDiagnosticsSummary(w io.Writer, s *job.ProjectDiagnosticsSummary) {
+	fmt.Fprintf(w, "project %s, last %d builds:\n", s.Project, len(s.Builds))
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, b := range s.Builds {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\terrors=%d\twarnings=%d\t+%d/-%d\n",
+			b.CreatedAt.Local().Format("2006-01-02 15:04"), b.JobID, defaultString(b.Commit, "-"), b.State, b.ErrorCount, b.WarningCount, len(b.New), len(b.Resolved))
+	}
+	_ = tw.Flush()
+
+	fmt.Fprintln(w, "diagnostics:")
+	tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, g := range s.Groups {
+		status := "resolved"
+		if g.InLatest {
+			status = "active"
+		}
+		where := ""
+		if g.File != "" {
+			where = " (" + g.File + ")"
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%d/%d builds\t[%s] %s%s\n", status, g.Severity, g.Builds, len(s.Builds), g.Code, g.Message, where)
+	}
+	_ = tw.Flush()
+}
+
+// printLogSearch renders search results like grep -n with context: "file:N:"
+// for matches, "file-N-" for context and "--" between separate groups.
+func printLogSearch(w io.Writer, result *job.LogSearchResult) {
+	matched := make(map[int]bool, len(result.Matches))
+	for _, m := range result.Matches {
+		matched[m.Line] = true
+	}
+	last := 0
+	emit := func(l job.LogLine) {
+		if l.Line <= last {
+			return
+		}
+		if last > 0 && l.Line > last+1 {
+			fmt.Fprintln(w, "--")
+		}
+		sep := "-"
+		if matched[l.Line] {
+			sep = ":"
+		}
+		fmt.Fprintf(w, "%s%s%d%s%s\n", result.File, sep, l.Line, sep, l.Text)
+		last = l.Line
+	}
+	for _, m := range result.Matches {
+		for _, l := range m.Before {
+			emit(l)
+		}
+		emit(m.LogLine)
+		for _, l := range m.After {
+			emit(l)
+		}
+	}
+	if result.Truncated {
+		fmt.Fprintf(w, "(stopped after %d matches)\n", len(result.Matches))
+	}
+}
+
 func runSubmit(args []string) error {
-	fs := flag.NewFlagSet("spadeforge-cli", flag.ContinueOnError)
+	_, err := submitBuild("spadeforge-cli", args, nil)
+	return err
+}
+
+// builtJob is a finished build and where its artifacts were extracted.
+type builtJob struct {
+	Record    *job.Record
+	OutputDir string
+}
+
+// submitBuild runs the submit command: bundle, submit, wait and extract.
+// extraFlags, when set, registers more flags and returns a check that runs
+// after parsing, before anything is built. The result is nil when --wait is
+// off.
+func submitBuild(name string, args []string, extraFlags func(fs *flag.FlagSet) func() error) (*builtJob, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
 	fs.Usage = usage
 
 	var sources stringListFlag
 	var constraints stringListFlag
+	var flashTo stringListFlag
+	var webhooks stringListFlag
+	var defines stringListFlag
+	var params stringListFlag
+	var ipCores stringListFlag
+	var blockDesigns stringListFlag
 
-	serverURL := fs.String("server", defaultString(os.Getenv("SPADEFORGE_SERVER"), ""), "builder server base url (if empty, auto-discover)")
-	discoverEnabled := fs.Bool("discover", true, "auto-discover server when --server is not provided")
-	discoverTimeout := fs.Duration("discover-timeout", 2*time.Second, "mDNS auto-discovery timeout")
-	discoverService := fs.String("discover-service", discovery.DefaultServiceName, "mDNS service name used for discovery")
-	discoverDomain := fs.String("discover-domain", discovery.DefaultDomain, "mDNS discovery domain")
-	token := fs.String("token", strings.TrimSpace(os.Getenv("SPADEFORGE_TOKEN")), "auth token")
-	authHeader := fs.String("auth-header", defaultString(os.Getenv("SPADEFORGE_AUTH_HEADER"), "X-Build-Token"), "auth header")
+	sf := addServerFlags(fs)
 	project := fs.String("project", "", "project name (required)")
 	top := fs.String("top", "", "top module name")
 	part := fs.String("part", "", "target FPGA part")
-	outputDir := fs.String("output-dir", "output", "directory where artifacts are extracted (under <output-dir>/<job_id>/)")
+	outputDir := fs.String("output-dir", "output", "directory where artifacts are extracted (under <output-dir>/<output-name>/)")
+	outputName := fs.String("output-name", "{job_id}", "template for the extracted artifacts directory name, e.g. {project}-{git_short}-{date}")
+	toolchain := fs.String("toolchain", "", "server toolchain: vivado or yosys (default: server default)")
+	bitstreamName := fs.String("bitstream-name", "", "template for a renamed copy of design.bit, e.g. {project}-{part}-{git_short}-{date}.bit")
 	outZip := fs.String("out-zip", "", "optional path to save raw downloaded artifacts zip")
 	wait := fs.Bool("wait", true, "poll until job reaches terminal state")
 	poll := fs.Duration("poll", 2*time.Second, "status polling interval")
 	streamEvents := fs.Bool("stream-events", false, "stream server events (SSE) instead of polling")
+	eventsTransport := fs.String("events-transport", "auto", "event stream transport for --stream-events: auto (SSE, falling back to WebSocket), sse or ws")
+	showDurations := fs.Bool("show-durations", true, "print a per-phase durations summary when the job finishes")
 	showDiagnostics := fs.Bool("show-diagnostics", true, "print parsed diagnostics on failures when available")
 	diagnosticLimit := fs.Int("diagnostic-limit", 5, "max diagnostics to print on failure")
 	tailLines := fs.Int("tail-lines", 60, "print this many console tail lines on failure")
 	runSwim := fs.Bool("run-swim", false, "run `swim build` before bundling")
 	swimBin := fs.String("swim-bin", "swim", "swim executable")
+	gitMeta := fs.Bool("git", true, "record the current git commit, branch, dirty flag and origin remote in the manifest")
+	submitter := fs.String("submitter", defaultString(os.Getenv("SPADEFORGE_SUBMITTER"), defaultSubmitter()), "identity used by the server to share the queue fairly between users")
+	maxRetries := fs.Int("max-retries", -1, "retry transient failures up to this many times (default: server policy)")
+	retryOn := fs.String("retry-on", "", "comma-separated failure kinds to retry, e.g. internal,license (default: server policy)")
+	noCache := fs.Bool("no-cache", false, "build even when the server's build cache holds a job with the same manifest and sources")
+	jsonOut := fs.Bool("json", false, "print machine-readable JSON lines (job id, state transitions, failure kind, artifact paths) instead of progress text")
+	plain := fs.Bool("plain", plainDefault(), "print a progress line only when the state or step changes, without heartbeats, for screen readers and CI logs (default: true when SPADEFORGE_PLAIN is set or TERM=dumb)")
+	serverDefaults := fs.Bool("server-defaults", true, "fill --part, --toolchain, --board, --output-dir, --output-name and --bitstream-name, when not given, from the defaults stored on the server (see spadeforge-cli me)")
 
 	fs.Var(&sources, "source", "source file (repeatable)")
 	fs.Var(&constraints, "xdc", "constraint file (repeatable)")
+	fs.Var(&flashTo, "flash-to", "on success, have the server flash the bitstream: <loader>:<board>[:<design name template>] (repeatable; loaders come from the server's SPADEFORGE_LOADERS)")
+	fs.Var(&webhooks, "webhook", "on success, have the server call this webhook from its SPADEFORGE_WEBHOOKS (repeatable)")
+	fs.Var(&defines, "define", "Verilog define as NAME=VALUE, or NAME for a bare define (repeatable)")
+	fs.Var(&params, "param", "top module parameter (generic) override as NAME=VALUE (repeatable)")
+	fs.Var(&ipCores, "ip", "Xilinx IP core .xci/.xcix file, bundled with its directory (repeatable)")
+	fs.Var(&blockDesigns, "block-design", "block design .bd file, bundled with its directory, or a write_bd_tcl script (repeatable)")
+	buildMode := fs.String("build-mode", "", "Vivado flow: non_project (default) or project, which builds through launch_runs (experimental)")
+	boardPart := fs.String("board-part", "", "board part for --build-mode project, e.g. digilentinc.com:arty-a7-35:part0:1.1")
+	xpr := fs.String("xpr", "", "import project name, top, part, sources, IP, block designs, constraints, defines and generics from a Vivado .xpr project; flags given alongside take precedence")
+
+	var check func() error
+	if extraFlags != nil {
+		check = extraFlags(fs)
+	}
 
 	if err := fs.Parse(args); err != nil {
-		return err
+		return nil, err
+	}
+	var imported client.BundleSpec
+	if *xpr != "" {
+		var err error
+		if imported, err = applyXPR(*xpr, fs); err != nil {
+			return nil, err
+		}
+		if len(sources) == 0 {
+			sources = imported.Sources
+		} else {
+			imported.Headers, imported.SourceOptions = nil, nil
+		}
+		if len(constraints) == 0 {
+			constraints = imported.Constraints
+		}
+		if len(ipCores) == 0 {
+			ipCores = imported.IP
+		}
+		if len(blockDesigns) == 0 {
+			blockDesigns = imported.BlockDesigns
+		}
+	}
+	// Defaults are applied before the flags are checked, so a stored part or
+	// board satisfies them; without a server the checks report first.
+	c, clientErr := sf.newClient()
+	if clientErr == nil && *serverDefaults {
+		if err := applyServerDefaults(context.Background(), c, fs); err != nil {
+			return nil, err
+		}
 	}
+	if check != nil {
+		if err := check(); err != nil {
+			return nil, err
+		}
+	}
+	switch *eventsTransport {
+	case "auto", "sse", "ws":
+	default:
+		return nil, fmt.Errorf("--events-transport must be auto, sse or ws, got %q", *eventsTransport)
+	}
+
+	out := newJSONWriter(os.Stdout, *jsonOut)
 
 	if *runSwim {
 		cmd := exec.Command(*swimBin, "build")
 		cmd.Stdout = os.Stdout
+		if out != nil {
+			// Keep stdout to JSON lines only.
+			cmd.Stdout = os.Stderr
+		}
 		cmd.Stderr = os.Stderr
 		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("run swim build: %w", err)
+			return nil, fmt.Errorf("run swim build: %w", err)
 		}
 		if len(sources) == 0 {
 			sources = append(sources, "build/spade.sv")
@@ -149,60 +1101,149 @@ func runSubmit(args []string) error {
 	}
 
 	if strings.TrimSpace(*project) == "" {
-		return fmt.Errorf("--project is required")
+		return nil, fmt.Errorf("--project is required")
 	}
 	if *top == "" || *part == "" {
-		return fmt.Errorf("both --top and --part are required")
+		return nil, fmt.Errorf("both --top and --part are required")
 	}
 	if len(sources) == 0 {
-		return fmt.Errorf("at least one --source is required")
+		return nil, fmt.Errorf("at least one --source is required")
+	}
+	if err := artifactname.Validate(*outputName); err != nil {
+		return nil, fmt.Errorf("--output-name: %w", err)
+	}
+	if strings.TrimSpace(*bitstreamName) != "" {
+		if err := artifactname.Validate(*bitstreamName); err != nil {
+			return nil, fmt.Errorf("--bitstream-name: %w", err)
+		}
 	}
 
-	resolvedServerURL, err := resolveServerURL(*serverURL, *discoverEnabled, *discoverTimeout, *discoverService, *discoverDomain)
-	if err != nil {
-		return err
+	if clientErr != nil {
+		return nil, clientErr
 	}
+	c.Submitter = strings.TrimSpace(*submitter)
+	c.NoCache = *noCache
 
-	bundle, err := client.BuildBundle(client.BundleSpec{
-		Project:     *project,
-		Top:         *top,
-		Part:        *part,
-		Sources:     sources,
-		Constraints: constraints,
-	})
+	spec := client.BundleSpec{
+		Project:       *project,
+		Top:           *top,
+		Part:          *part,
+		Sources:       sources,
+		Constraints:   constraints,
+		Headers:       imported.Headers,
+		SourceOptions: imported.SourceOptions,
+		IP:            ipCores,
+		BlockDesigns:  blockDesigns,
+		BitstreamName: *bitstreamName,
+		Toolchain:     *toolchain,
+		BuildMode:     *buildMode,
+		BoardPart:     *boardPart,
+	}
+	var err error
+	if spec.Defines, err = parseAssignments("--define", defines, false); err != nil {
+		return nil, err
+	}
+	spec.Defines = mergeAssignments(imported.Defines, spec.Defines)
+	if spec.TopParams, err = parseAssignments("--param", params, true); err != nil {
+		return nil, err
+	}
+	spec.TopParams = mergeAssignments(imported.TopParams, spec.TopParams)
+	if strings.TrimSpace(*retryOn) != "" && *maxRetries < 0 {
+		return nil, fmt.Errorf("--retry-on needs --max-retries")
+	}
+	if *maxRetries >= 0 {
+		spec.Retry = &manifest.RetryPolicy{MaxRetries: *maxRetries}
+		for _, kind := range strings.Split(*retryOn, ",") {
+			if kind = strings.TrimSpace(kind); kind != "" {
+				spec.Retry.RetryOn = append(spec.Retry.RetryOn, kind)
+			}
+		}
+	}
+	for _, raw := range flashTo {
+		action, err := parseFlashTo(raw)
+		if err != nil {
+			return nil, err
+		}
+		spec.OnSuccess = append(spec.OnSuccess, manifest.Action{Flash: action})
+	}
+	for _, name := range webhooks {
+		spec.OnSuccess = append(spec.OnSuccess, manifest.Action{Webhook: &manifest.WebhookAction{Name: name}})
+	}
+	ctx := context.Background()
+	if *gitMeta {
+		spec.Git = client.DetectGit(ctx, ".")
+	}
+	bundle, err := client.BuildBundle(spec)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	c := &client.HTTPClient{BaseURL: resolvedServerURL, Token: *token, AuthHeader: *authHeader}
-	ctx := context.Background()
-	jobID, err := c.SubmitBundle(ctx, bundle)
+	submitted, err := c.SubmitResumable(ctx, bundle)
 	if err != nil {
-		return err
+		var submitErr *client.SubmitError
+		if errors.As(err, &submitErr) && len(submitErr.Details) > 0 {
+			printValidationChecklist(os.Stderr, submitErr.Details)
+			return nil, fmt.Errorf("server rejected manifest with %d problem(s)", len(submitErr.Details))
+		}
+		return nil, err
+	}
+	jobID := submitted.JobID
+	if out != nil {
+		out.emitSubmitted(submitted)
+		for _, w := range submitted.Warnings {
+			fmt.Fprintf(os.Stderr, "warning: manifest %s\n", w)
+		}
+	} else {
+		printSubmitResponse(submitted)
+	}
+	for _, warning := range manifestSurprises(spec, submitted.Manifest) {
+		fmt.Fprintf(os.Stderr, "warning: server normalized manifest: %s\n", warning)
 	}
-	fmt.Printf("job submitted: %s\n", jobID)
 	if !*wait {
-		return nil
+		return nil, nil
 	}
 
-	record, err := waitForTerminal(ctx, c, jobID, *poll, *streamEvents)
+	record, err := waitForTerminal(ctx, c, jobID, *poll, *streamEvents, *eventsTransport, out, *plain)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	fmt.Printf("job finished: %s (%s)\n", record.State, record.Message)
-	if record.State == job.StateFailed {
-		if record.FailureKind != "" || record.FailureSummary != "" {
-			fmt.Printf("failure: kind=%s summary=%s\n", record.FailureKind, record.FailureSummary)
-		}
-		if *showDiagnostics {
+	if out != nil {
+		out.emitFinished(record)
+		if record.State == job.StateFailed && *showDiagnostics {
 			if report, err := c.GetDiagnostics(ctx, jobID); err == nil {
-				printDiagnostics(report, *diagnosticLimit)
+				out.emitDiagnostics(jobID, report, *diagnosticLimit)
 			}
 		}
-		if *tailLines > 0 {
-			if tail, err := c.GetLogTail(ctx, jobID, *tailLines); err == nil {
-				if strings.TrimSpace(tail) != "" {
-					fmt.Printf("console tail (%d lines):\n%s", *tailLines, tail)
+	} else {
+		fmt.Printf("job finished: %s (%s)\n", record.State, record.Message)
+	}
+	if record.State == job.StateCanceled {
+		return nil, fmt.Errorf("job %s was canceled", jobID)
+	}
+	if out == nil {
+		if *showDurations {
+			collectTimeline(ctx, c, record).WriteSummary(os.Stdout)
+		}
+		if record.Baseline != nil {
+			printBaselineComparison(os.Stdout, record.Baseline)
+		}
+		if e := record.Energy; e != nil {
+			fmt.Printf("energy: %s\n", formatEnergy(e.WattHours, e.Cost, e.Source))
+		}
+		if record.State == job.StateFailed {
+			if record.FailureKind != "" || record.FailureSummary != "" {
+				fmt.Printf("failure: kind=%s summary=%s\n", record.FailureKind, record.FailureSummary)
+			}
+			if *showDiagnostics {
+				if report, err := c.GetDiagnostics(ctx, jobID); err == nil {
+					printDiagnostics(report, *diagnosticLimit)
+				}
+			}
+			if *tailLines > 0 {
+				if tail, err := c.GetLogTail(ctx, jobID, *tailLines); err == nil {
+					if strings.TrimSpace(tail) != "" {
+						fmt.Printf("console tail (%d lines):\n%s", *tailLines, tail)
+					}
 				}
 			}
 		}
@@ -210,64 +1251,245 @@ func runSubmit(args []string) error {
 
 	var artifactZip bytes.Buffer
 	if err := c.DownloadArtifacts(ctx, jobID, &artifactZip); err != nil {
-		return err
+		return nil, err
 	}
 
 	if strings.TrimSpace(*outZip) != "" {
 		dir := filepath.Dir(*outZip)
 		if dir != "." && dir != "" {
 			if err := os.MkdirAll(dir, 0o755); err != nil {
-				return err
+				return nil, err
 			}
 		}
 		f, err := os.OpenFile(*outZip, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if _, err := f.Write(artifactZip.Bytes()); err != nil {
 			f.Close()
-			return err
+			return nil, err
 		}
 		if err := f.Close(); err != nil {
-			return err
+			return nil, err
+		}
+		if out == nil {
+			fmt.Printf("artifact zip written to %s\n", *outZip)
 		}
-		fmt.Printf("artifact zip written to %s\n", *outZip)
 	}
 
-	finalOutputDir := filepath.Join(*outputDir, jobID)
+	dirName, err := artifactname.Expand(*outputName, record.NameVars())
+	if err != nil {
+		return nil, fmt.Errorf("--output-name: %w", err)
+	}
+	finalOutputDir := filepath.Join(*outputDir, dirName)
 	if err := client.ExtractArtifactZip(artifactZip.Bytes(), finalOutputDir); err != nil {
-		return err
+		return nil, err
+	}
+	if out != nil {
+		out.emitArtifacts(jobID, finalOutputDir, *outZip, artifactZip.Bytes())
+	} else {
+		fmt.Printf("artifacts extracted to %s\n", finalOutputDir)
 	}
-	fmt.Printf("artifacts extracted to %s\n", finalOutputDir)
 
 	if record.State != "SUCCEEDED" {
-		return fmt.Errorf("job failed: %s", record.Error)
+		return nil, fmt.Errorf("job failed: %s", record.Error)
+	}
+	return &builtJob{Record: record, OutputDir: finalOutputDir}, nil
+}
+
+func printValidationChecklist(w io.Writer, details []manifest.FieldError) {
+	fmt.Fprintln(w, "manifest validation failed:")
+	for _, d := range details {
+		path := d.Path
+		if path == "" {
+			path = "/"
+		}
+		fmt.Fprintf(w, "  [ ] %s: %s", path, d.Message)
+		if d.Value != nil {
+			fmt.Fprintf(w, " (got %v)", formatValue(d.Value))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func formatValue(v any) string {
+	if s, ok := v.(string); ok {
+		return strconv.Quote(s)
+	}
+	return fmt.Sprint(v)
+}
+
+func printSubmitResponse(resp *job.SubmitResponse) {
+	fmt.Printf("job submitted: %s\n", resp.JobID)
+	mf := resp.Manifest
+	fmt.Printf("server manifest: project=%s top=%s part=%s sources=%d constraints=%d\n",
+		mf.Project, mf.Top, mf.Part, len(mf.Sources), len(mf.Constraints))
+	if g := mf.Git; g != nil {
+		fmt.Printf("git: %s branch=%s remote=%s\n", g.Short(), defaultString(g.Branch, "-"), defaultString(g.Remote, "-"))
+	}
+	for _, w := range resp.Warnings {
+		fmt.Fprintf(os.Stderr, "warning: manifest %s\n", w)
+	}
+	if resp.CachedFrom != "" {
+		fmt.Printf("cache hit: reusing artifacts of job %s\n", resp.CachedFrom)
+	}
+	if resp.QueuePosition > 0 {
+		fmt.Printf("queue position: %d (%d job(s) ahead)\n", resp.QueuePosition, resp.JobsAhead)
+	}
+}
+
+// manifestSurprises lists differences between what the CLI asked for and the
+// manifest the server will actually build.
+func manifestSurprises(spec client.BundleSpec, mf manifest.Manifest) []string {
+	var out []string
+	check := func(field, want, got string) {
+		if strings.TrimSpace(want) != got {
+			out = append(out, fmt.Sprintf("%s %q became %q", field, want, got))
+		}
+	}
+	check("project", spec.Project, mf.Project)
+	check("top", spec.Top, mf.Top)
+	check("part", spec.Part, mf.Part)
+	if len(spec.Sources) != len(mf.Sources) {
+		out = append(out, fmt.Sprintf("%d source(s) submitted but server lists %d", len(spec.Sources), len(mf.Sources)))
+	}
+	if len(spec.Constraints) != len(mf.Constraints) {
+		out = append(out, fmt.Sprintf("%d constraint file(s) submitted but server lists %d", len(spec.Constraints), len(mf.Constraints)))
+	}
+	return out
+}
+
+// applyServerDefaults sets the flags the user left out from the defaults
+// the server stores for this client. Flags fs lacks, such as --board
+// outside run, are skipped. A server without /v1/me, or a token that may not
+// read it, leaves the flags alone.
+func applyServerDefaults(ctx context.Context, c *client.HTTPClient, fs *flag.FlagSet) error {
+	me, err := c.GetMe(ctx)
+	if errors.Is(err, client.ErrMeUnsupported) {
+		return nil
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: server defaults unavailable: %v\n", err)
+		return nil
+	}
+	given := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	d := me.Defaults
+	for _, kv := range [][2]string{
+		{"part", d.Part}, {"toolchain", d.Toolchain}, {"board", d.Board},
+		{"output-dir", d.OutputDir}, {"output-name", d.OutputName}, {"bitstream-name", d.BitstreamName},
+	} {
+		if kv[1] == "" || given[kv[0]] || fs.Lookup(kv[0]) == nil {
+			continue
+		}
+		if err := fs.Set(kv[0], kv[1]); err != nil {
+			return fmt.Errorf("server default for --%s: %w", kv[0], err)
+		}
 	}
 	return nil
 }
 
-func waitForTerminal(ctx context.Context, c *client.HTTPClient, jobID string, poll time.Duration, stream bool) (*job.Record, error) {
+// applyXPR imports a Vivado project and sets --project, --top and --part
+// from it where they were not given. Files the import cannot bundle are
+// reported, since the build will likely fail without them.
+func applyXPR(path string, fs *flag.FlagSet) (client.BundleSpec, error) {
+	spec, skipped, err := client.ImportXPR(path)
+	if err != nil {
+		return client.BundleSpec{}, fmt.Errorf("--xpr: %w", err)
+	}
+	for _, f := range skipped {
+		fmt.Fprintf(os.Stderr, "warning: %s: skipping %s, only HDL sources, IP, block designs and XDC constraints are imported\n", path, f)
+	}
+	given := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	for _, kv := range [][2]string{{"project", spec.Project}, {"top", spec.Top}, {"part", spec.Part}} {
+		if kv[1] == "" || given[kv[0]] {
+			continue
+		}
+		if err := fs.Set(kv[0], kv[1]); err != nil {
+			return client.BundleSpec{}, err
+		}
+	}
+	return spec, nil
+}
+
+// mergeAssignments returns base with override's entries on top.
+func mergeAssignments(base, override map[string]string) map[string]string {
+	if len(base) == 0 {
+		return override
+	}
+	out := maps.Clone(base)
+	maps.Copy(out, override)
+	return out
+}
+
+// parseAssignments turns repeated NAME=VALUE flags into a map. The server
+// checks names and values; duplicates are caught here since a map would
+// silently keep the last.
+func parseAssignments(flagName string, raw []string, valueRequired bool) (map[string]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]string, len(raw))
+	for _, entry := range raw {
+		name, value, hasValue := strings.Cut(strings.TrimSpace(entry), "=")
+		name = strings.TrimSpace(name)
+		if name == "" || (valueRequired && !hasValue) {
+			return nil, fmt.Errorf("%s must be NAME=VALUE, got %q", flagName, entry)
+		}
+		if _, dup := out[name]; dup {
+			return nil, fmt.Errorf("%s %s given twice", flagName, name)
+		}
+		out[name] = strings.TrimSpace(value)
+	}
+	return out, nil
+}
+
+// parseFlashTo parses a --flash-to value, <loader>:<board>[:<design name>].
+func parseFlashTo(raw string) (*manifest.FlashAction, error) {
+	parts := strings.SplitN(raw, ":", 3)
+	if len(parts) < 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+		return nil, fmt.Errorf("--flash-to %q must be <loader>:<board>[:<design name>]", raw)
+	}
+	action := &manifest.FlashAction{Loader: strings.TrimSpace(parts[0]), Board: strings.TrimSpace(parts[1])}
+	if len(parts) == 3 {
+		action.DesignName = strings.TrimSpace(parts[2])
+	}
+	return action, nil
+}
+
+// waitForTerminal prints each progress change, as state lines to out in
+// --json mode, until the job finishes. In plain mode heartbeats are left
+// out, so only state and step changes print.
+func waitForTerminal(ctx context.Context, c *client.HTTPClient, jobID string, poll time.Duration, stream bool, transport string, out *jsonWriter, plain bool) (*job.Record, error) {
 	if stream {
-		return waitForTerminalViaEvents(ctx, c, jobID, poll)
+		return waitForTerminalViaEvents(ctx, c, jobID, poll, transport, out, plain)
 	}
 
 	var lastState string
 	var lastStep string
 	var lastHeartbeat string
+	timeline := &phaseTimeline{}
 	return c.WaitForTerminalWithProgress(ctx, jobID, poll, func(rec *job.Record) {
 		heartbeat := "-"
-		if rec.HeartbeatAt != nil {
+		if plain {
+			heartbeat = ""
+		} else if rec.HeartbeatAt != nil {
 			heartbeat = rec.HeartbeatAt.UTC().Format(time.RFC3339)
 		}
 		step := rec.CurrentStep
 		if step == "" {
 			step = "-"
 		}
+		if len(timeline.spans) == 0 {
+			timeline.Observe(job.StateQueued, "", rec.CreatedAt)
+		}
+		elapsed, _ := timeline.Observe(rec.State, rec.CurrentStep, rec.UpdatedAt)
 
-		shouldPrint := rec.State != job.StateSucceeded && rec.State != job.StateFailed
+		shouldPrint := !rec.State.Terminal()
 		changed := string(rec.State) != lastState || step != lastStep || heartbeat != lastHeartbeat
 		if shouldPrint && changed {
-			fmt.Printf("state=%s step=%s heartbeat=%s message=%s\n", rec.State, step, heartbeat, rec.Message)
+			printProgress(out, jobID, rec.State, step, elapsed, heartbeat, rec.Message)
 			lastState = string(rec.State)
 			lastStep = step
 			lastHeartbeat = heartbeat
@@ -275,31 +1497,35 @@ func waitForTerminal(ctx context.Context, c *client.HTTPClient, jobID string, po
 	})
 }
 
-func waitForTerminalViaEvents(ctx context.Context, c *client.HTTPClient, jobID string, poll time.Duration) (*job.Record, error) {
+func waitForTerminalViaEvents(ctx context.Context, c *client.HTTPClient, jobID string, poll time.Duration, transport string, out *jsonWriter, plain bool) (*job.Record, error) {
 	var lastState string
 	var lastStep string
 	var lastHeartbeat string
+	timeline := &phaseTimeline{}
 
-	printProgress := func(state job.State, step string, heartbeatAt *time.Time, message string) {
+	observe := func(state job.State, step string, at time.Time, heartbeatAt *time.Time, message string) {
 		heartbeat := "-"
-		if heartbeatAt != nil {
+		if plain {
+			heartbeat = ""
+		} else if heartbeatAt != nil {
 			heartbeat = heartbeatAt.UTC().Format(time.RFC3339)
 		}
+		elapsed, _ := timeline.Observe(state, step, at)
 		if step == "" {
 			step = "-"
 		}
-		shouldPrint := state != job.StateSucceeded && state != job.StateFailed
+		shouldPrint := !state.Terminal()
 		changed := string(state) != lastState || step != lastStep || heartbeat != lastHeartbeat
 		if shouldPrint && changed {
-			fmt.Printf("state=%s step=%s heartbeat=%s message=%s\n", state, step, heartbeat, message)
+			printProgress(out, jobID, state, step, elapsed, heartbeat, message)
 			lastState = string(state)
 			lastStep = step
 			lastHeartbeat = heartbeat
 		}
 	}
 
-	if err := c.StreamEvents(ctx, jobID, 0, func(ev *job.Event) {
-		printProgress(ev.State, ev.Step, ev.HeartbeatAt, ev.Message)
+	if err := streamJobEvents(ctx, c, jobID, transport, func(ev *job.Event) {
+		observe(ev.State, ev.Step, ev.At, ev.HeartbeatAt, ev.Message)
 	}); err != nil {
 		return nil, err
 	}
@@ -313,10 +1539,56 @@ func waitForTerminalViaEvents(ctx context.Context, c *client.HTTPClient, jobID s
 	}
 
 	return c.WaitForTerminalWithProgress(ctx, jobID, poll, func(update *job.Record) {
-		printProgress(update.State, update.CurrentStep, update.HeartbeatAt, update.Message)
+		observe(update.State, update.CurrentStep, update.UpdatedAt, update.HeartbeatAt, update.Message)
 	})
 }
 
+// printProgress prints one progress change; step and heartbeat are "-"
+// when unset, and an empty heartbeat is left out.
+func printProgress(out *jsonWriter, jobID string, state job.State, step string, elapsed time.Duration, heartbeat, message string) {
+	if out == nil {
+		if heartbeat == "" {
+			fmt.Printf("state=%s step=%s elapsed=%s message=%s\n", state, step, formatDuration(elapsed), message)
+			return
+		}
+		fmt.Printf("state=%s step=%s elapsed=%s heartbeat=%s message=%s\n", state, step, formatDuration(elapsed), heartbeat, message)
+		return
+	}
+	line := jsonLine{Event: "state", JobID: jobID, State: state, Message: message, ElapsedSeconds: elapsed.Seconds()}
+	if step != "-" {
+		line.Step = step
+	}
+	if at, err := time.Parse(time.RFC3339, heartbeat); err == nil {
+		line.HeartbeatAt = &at
+	}
+	out.emit(line)
+}
+
+// streamJobEvents follows the job's events over transport. In auto mode a
+// failing SSE stream (say, a proxy that rejects or buffers it) is resumed
+// over WebSocket from the last received event.
+func streamJobEvents(ctx context.Context, c *client.HTTPClient, jobID, transport string, onEvent func(*job.Event)) error {
+	switch transport {
+	case "sse":
+		return c.StreamEvents(ctx, jobID, 0, onEvent)
+	case "ws":
+		return c.StreamEventsWS(ctx, jobID, 0, onEvent)
+	}
+	var last int64
+	track := func(ev *job.Event) {
+		if ev.Seq > last {
+			last = ev.Seq
+		}
+		onEvent(ev)
+	}
+	err := c.StreamEvents(ctx, jobID, 0, track)
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "warning: SSE event stream failed (%v); retrying over WebSocket\n", err)
+	return c.StreamEventsWS(ctx, jobID, last, track)
+}
+
 func printDiagnostics(report *job.DiagnosticsReport, limit int) {
 	if report == nil {
 		return
@@ -331,7 +1603,7 @@ func printDiagnostics(report *job.DiagnosticsReport, limit int) {
 
 	printed := 0
 	for _, d := range report.Diagnostics {
-		if d.Severity != job.SeverityError {
+		if d.Severity != job.SeverityError || d.Suppressed {
 			continue
 		}
 		fmt.Printf("diagnostic[%d]: %s [%s] %s", printed+1, d.Severity, d.Code, d.Message)
@@ -341,6 +1613,12 @@ func printDiagnostics(report *job.DiagnosticsReport, limit int) {
 			fmt.Printf(" (%s)", d.File)
 		}
 		fmt.Println()
+		if d.Hint != nil {
+			fmt.Printf("  hint: %s\n", d.Hint.Explanation)
+			for _, step := range d.Hint.NextSteps {
+				fmt.Printf("    - %s\n", step)
+			}
+		}
 		printed++
 		if printed >= limit {
 			break
@@ -349,6 +1627,9 @@ func printDiagnostics(report *job.DiagnosticsReport, limit int) {
 	if printed == 0 {
 		fmt.Printf("diagnostics: %d entries (no errors)\n", len(report.Diagnostics))
 	}
+	if report.SuppressedCount > 0 {
+		fmt.Printf("diagnostics: %d suppressed by known-issue rules\n", report.SuppressedCount)
+	}
 }
 
 type stringListFlag []string
@@ -368,7 +1649,22 @@ func (s *stringListFlag) Set(v string) error {
 func usage() {
 	_, _ = os.Stderr.WriteString("spadeforge-cli usage:\n")
 	_, _ = os.Stderr.WriteString("  spadeforge-cli --project <name> --top <top> --part <part> --source build/spade.sv [--xdc top.xdc] [--output-dir output] [--server http://host:8080]\n")
+	_, _ = os.Stderr.WriteString("  spadeforge-cli workdir --job-id <id> [--file <path> [--out <path>]]\n")
+	_, _ = os.Stderr.WriteString("  spadeforge-cli cancel <job_id>\n")
+	_, _ = os.Stderr.WriteString("  spadeforge-cli pin [--unpin] <job_id>\n")
+	_, _ = os.Stderr.WriteString("  spadeforge-cli self-update [--check] [--version v1.2.3] [--release-url URL] [--public-key KEY]\n")
+	_, _ = os.Stderr.WriteString("  spadeforge-cli bundle --job-id <id> [--out <path>]\n")
+	_, _ = os.Stderr.WriteString("  spadeforge-cli flash-image --job-id <id> [--format bin|mcs] [--offset 0x0] [--out <path>]\n")
+	_, _ = os.Stderr.WriteString("  spadeforge-cli repro <job_id> [--dir <path>] [--vivado <bin>]\n")
+	_, _ = os.Stderr.WriteString("  spadeforge-cli status --job-id <id> [--job-id <id> ...]\n")
+	_, _ = os.Stderr.WriteString("  spadeforge-cli report --format junit <job_id> [--out <path>|-]\n")
+	_, _ = os.Stderr.WriteString("  spadeforge-cli jobs [--state FAILED ...] [--limit N] [--offset N] [--follow]\n")
+	_, _ = os.Stderr.WriteString("  spadeforge-cli energy\n")
+	_, _ = os.Stderr.WriteString("  spadeforge-cli me [--part <part>] [--toolchain vivado|yosys] [--board <board>] [--output-dir <dir>] [--output-name <template>] [--bitstream-name <template>] [--clear]\n")
+	_, _ = os.Stderr.WriteString("  spadeforge-cli loglevel [--level debug] [--module discovery=trace ...]\n")
+	_, _ = os.Stderr.WriteString("  spadeforge-cli run --board <board> [--loader-server http://host:8080] [--name {project}] <submit flags>\n")
 	_, _ = os.Stderr.WriteString("  spadeforge-cli submit --project <name> --top <top> --part <part> --source build/spade.sv [--xdc top.xdc] [--output-dir output] [--server http://host:8080]\n")
+	_, _ = os.Stderr.WriteString("  spadeforge-cli submit --xpr project.xpr [--top <top>] [--output-dir output] [--server http://host:8080]\n")
 }
 
 func defaultString(v, fallback string) string {
@@ -378,6 +1674,113 @@ func defaultString(v, fallback string) string {
 	return strings.TrimSpace(v)
 }
 
+// defaultSubmitter is user@host, or whichever half is known.
+// plainDefault turns plain progress on for dumb terminals and when
+// SPADEFORGE_PLAIN asks for it.
+func plainDefault() bool {
+	if v := strings.TrimSpace(os.Getenv("SPADEFORGE_PLAIN")); v != "" {
+		return v != "0" && !strings.EqualFold(v, "false")
+	}
+	return os.Getenv("TERM") == "dumb"
+}
+
+func defaultSubmitter() string {
+	name := strings.TrimSpace(os.Getenv("USER"))
+	if name == "" {
+		name = strings.TrimSpace(os.Getenv("USERNAME"))
+	}
+	host, _ := os.Hostname()
+	host = strings.TrimSpace(host)
+	switch {
+	case name != "" && host != "":
+		return name + "@" + host
+	case name != "":
+		return name
+	default:
+		return host
+	}
+}
+
+type serverFlags struct {
+	serverURL       *string
+	discoverEnabled *bool
+	discoverTimeout *time.Duration
+	discoverService *string
+	discoverDomain  *string
+	token           *string
+	authHeader      *string
+	retries         *int
+	caFile          *string
+	certFile        *string
+	keyFile         *string
+	insecure        *bool
+	limitRate       *string
+}
+
+func addServerFlags(fs *flag.FlagSet) *serverFlags {
+	f := &serverFlags{
+		serverURL:       fs.String("server", defaultString(os.Getenv("SPADEFORGE_SERVER"), ""), "builder server base url (if empty, auto-discover)"),
+		discoverEnabled: fs.Bool("discover", true, "auto-discover server when --server is not provided"),
+		discoverTimeout: fs.Duration("discover-timeout", 2*time.Second, "mDNS auto-discovery timeout"),
+		discoverService: fs.String("discover-service", discovery.DefaultServiceName, "mDNS service name used for discovery"),
+		discoverDomain:  fs.String("discover-domain", discovery.DefaultDomain, "mDNS discovery domain"),
+		token:           fs.String("token", strings.TrimSpace(os.Getenv("SPADEFORGE_TOKEN")), "auth token"),
+		authHeader:      fs.String("auth-header", defaultString(os.Getenv("SPADEFORGE_AUTH_HEADER"), "X-Build-Token"), "auth header"),
+		caFile:          fs.String("ca-file", strings.TrimSpace(os.Getenv("SPADEFORGE_CA_FILE")), "PEM CA bundle trusted in addition to system roots (for self-signed servers)"),
+		certFile:        fs.String("cert", strings.TrimSpace(os.Getenv("SPADEFORGE_CLIENT_CERT")), "PEM client certificate, accepted in place of the token by servers with a client CA"),
+		keyFile:         fs.String("key", strings.TrimSpace(os.Getenv("SPADEFORGE_CLIENT_KEY")), "PEM private key for --cert"),
+		insecure:        fs.Bool("insecure-skip-verify", false, "disable TLS certificate verification (unsafe)"),
+		retries:         fs.Int("retries", httpretry.Default().MaxAttempts, "attempts for idempotent requests and downloads on transient network errors (1 disables retries)"),
+		limitRate:       fs.String("limit-rate", strings.TrimSpace(os.Getenv("SPADEFORGE_LIMIT_RATE")), "cap upload and download speed in bytes per second, with optional K, M or G suffix (e.g. 2M)"),
+	}
+	fs.StringVar(f.caFile, "cacert", *f.caFile, "alias for --ca-file")
+	return f
+}
+
+// transportOptions are the TLS settings the flags select.
+func (f *serverFlags) transportOptions() httptransport.Options {
+	return httptransport.Options{CAFile: *f.caFile, InsecureSkipVerify: *f.insecure, CertFile: *f.certFile, KeyFile: *f.keyFile}
+}
+
+func (f *serverFlags) newClient() (*client.HTTPClient, error) {
+	if err := checkTransportFlags(f.transportOptions()); err != nil {
+		return nil, err
+	}
+	limitRate, err := httptransport.ParseRate(*f.limitRate)
+	if err != nil {
+		return nil, fmt.Errorf("--limit-rate: %w", err)
+	}
+	resolvedServerURL, err := resolveServerURL(*f.serverURL, *f.discoverEnabled, *f.discoverTimeout, *f.discoverService, *f.discoverDomain)
+	if err != nil {
+		return nil, err
+	}
+	return &client.HTTPClient{
+		BaseURL:    resolvedServerURL,
+		Token:      *f.token,
+		AuthHeader: *f.authHeader,
+		Retry:      httpretry.Policy{MaxAttempts: *f.retries},
+
+		CAFile:             *f.caFile,
+		InsecureSkipVerify: *f.insecure,
+		CertFile:           *f.certFile,
+		KeyFile:            *f.keyFile,
+		LimitRate:          limitRate,
+	}, nil
+}
+
+// checkTransportFlags validates TLS flags up front so a bad CA file or
+// client certificate fails before any upload, and warns loudly when
+// verification is disabled.
+func checkTransportFlags(opts httptransport.Options) error {
+	if _, err := httptransport.NewClient(opts); err != nil {
+		return err
+	}
+	if opts.InsecureSkipVerify {
+		fmt.Fprintln(os.Stderr, httptransport.InsecureWarning)
+	}
+	return nil
+}
+
 func resolveServerURL(
 	explicit string,
 	discover bool,
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"flag"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -13,6 +16,8 @@ import (
 	"github.com/mblsha/spadeforge/internal/client"
 	"github.com/mblsha/spadeforge/internal/discovery"
 	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/manifest"
+	"github.com/mblsha/spadeforge/internal/websocket"
 )
 
 func TestResolveServerURL_ExplicitWins(t *testing.T) {
@@ -97,7 +102,7 @@ func TestWaitForTerminalViaEvents_StreamEndsEarlyFallsBackToPolling(t *testing.T
 	defer ts.Close()
 
 	c := &client.HTTPClient{BaseURL: ts.URL, Client: ts.Client()}
-	rec, err := waitForTerminalViaEvents(context.Background(), c, "j1", 5*time.Millisecond)
+	rec, err := waitForTerminalViaEvents(context.Background(), c, "j1", 5*time.Millisecond, "auto", nil, false)
 	if err != nil {
 		t.Fatalf("waitForTerminalViaEvents() error: %v", err)
 	}
@@ -134,7 +139,7 @@ func TestWaitForTerminalViaEvents_StopsWhenTerminalAfterStream(t *testing.T) {
 	defer ts.Close()
 
 	c := &client.HTTPClient{BaseURL: ts.URL, Client: ts.Client()}
-	rec, err := waitForTerminalViaEvents(context.Background(), c, "j1", 5*time.Millisecond)
+	rec, err := waitForTerminalViaEvents(context.Background(), c, "j1", 5*time.Millisecond, "auto", nil, false)
 	if err != nil {
 		t.Fatalf("waitForTerminalViaEvents() error: %v", err)
 	}
@@ -145,3 +150,164 @@ func TestWaitForTerminalViaEvents_StopsWhenTerminalAfterStream(t *testing.T) {
 		t.Fatalf("expected single terminal fetch, getCalls=%d", getCalls.Load())
 	}
 }
+
+func TestWaitForTerminalViaEvents_AutoFallsBackToWebSocket(t *testing.T) {
+	t.Parallel()
+
+	var wsSince atomic.Value
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/jobs/j1/events":
+			// A proxy that refuses event streams.
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+		case "/v1/jobs/j1/events/ws":
+			wsSince.Store(r.URL.Query().Get("since"))
+			conn, err := websocket.Accept(w, r)
+			if err != nil {
+				return
+			}
+			defer conn.Close(websocket.CloseNormal, "")
+			_ = conn.WriteText([]byte(`{"seq":2,"job_id":"j1","type":"succeeded","state":"SUCCEEDED","message":"done"}`))
+		case "/v1/jobs/j1":
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(&job.Record{ID: "j1", State: job.StateSucceeded, Message: "done"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	c := &client.HTTPClient{BaseURL: ts.URL, Client: ts.Client()}
+	rec, err := waitForTerminalViaEvents(context.Background(), c, "j1", 5*time.Millisecond, "auto", nil, false)
+	if err != nil {
+		t.Fatalf("waitForTerminalViaEvents() error: %v", err)
+	}
+	if rec.State != job.StateSucceeded {
+		t.Fatalf("state = %s, want %s", rec.State, job.StateSucceeded)
+	}
+	if wsSince.Load() == nil {
+		t.Fatal("expected a WebSocket attempt after the SSE stream failed")
+	}
+
+	if _, err := waitForTerminalViaEvents(context.Background(), c, "j1", 5*time.Millisecond, "sse", nil, false); err == nil {
+		t.Fatal("expected the sse transport to report the failed stream")
+	}
+}
+
+func TestManifestSurprises_ReportsServerNormalization(t *testing.T) {
+	spec := client.BundleSpec{
+		Project:     "demo",
+		Top:         "top",
+		Part:        "xc7a35t",
+		Sources:     []string{"a.sv", "b.sv"},
+		Constraints: []string{"top.xdc"},
+	}
+	same := manifest.Manifest{
+		Project:     "demo",
+		Top:         "top",
+		Part:        "xc7a35t",
+		Sources:     []string{"hdl/a.sv", "hdl/b.sv"},
+		Constraints: []string{"constraints/top.xdc"},
+	}
+	if got := manifestSurprises(spec, same); len(got) != 0 {
+		t.Fatalf("expected no surprises, got %v", got)
+	}
+
+	changed := same
+	changed.Part = "xc7a100t"
+	changed.Sources = []string{"hdl/a.sv"}
+	got := manifestSurprises(spec, changed)
+	if len(got) != 2 {
+		t.Fatalf("expected part and source surprises, got %v", got)
+	}
+}
+
+func TestPrintValidationChecklist(t *testing.T) {
+	var out bytes.Buffer
+	printValidationChecklist(&out, []manifest.FieldError{
+		{Path: "/top", Message: "top is required"},
+		{Path: "/sources/1", Message: "source not found in bundle", Value: "hdl/x.sv"},
+	})
+	want := "manifest validation failed:\n" +
+		"  [ ] /top: top is required\n" +
+		"  [ ] /sources/1: source not found in bundle (got \"hdl/x.sv\")\n"
+	if out.String() != want {
+		t.Fatalf("unexpected checklist:\n%s", out.String())
+	}
+}
+
+func TestPrintLogSearch_MergesOverlappingContext(t *testing.T) {
+	line := func(n int, text string) job.LogLine { return job.LogLine{Line: n, Text: text} }
+	var out bytes.Buffer
+	printLogSearch(&out, &job.LogSearchResult{
+		File: "vivado.log",
+		Matches: []job.LogMatch{
+			{LogLine: line(2, "ERROR a"), Before: []job.LogLine{line(1, "x")}, After: []job.LogLine{line(3, "ERROR b")}},
+			{LogLine: line(3, "ERROR b"), Before: []job.LogLine{line(2, "ERROR a")}, After: []job.LogLine{line(4, "y")}},
+			{LogLine: line(9, "ERROR c"), Before: []job.LogLine{line(8, "z")}},
+		},
+	})
+	want := "vivado.log-1-x\n" +
+		"vivado.log:2:ERROR a\n" +
+		"vivado.log:3:ERROR b\n" +
+		"vivado.log-4-y\n" +
+		"--\n" +
+		"vivado.log-8-z\n" +
+		"vivado.log:9:ERROR c\n"
+	if out.String() != want {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+}
+
+func TestApplyServerDefaults_FillsOnlyUnsetFlags(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/me" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(job.Me{Client: "token:ci", Defaults: job.ClientDefaults{Part: "xc7a35tcsg324-1", Toolchain: "yosys", Board: "arty"}})
+	}))
+	defer srv.Close()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	part := fs.String("part", "", "")
+	toolchain := fs.String("toolchain", "", "")
+	if err := fs.Parse([]string{"--toolchain", "vivado"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyServerDefaults(context.Background(), &client.HTTPClient{BaseURL: srv.URL}, fs); err != nil {
+		t.Fatal(err)
+	}
+	if *part != "xc7a35tcsg324-1" || *toolchain != "vivado" {
+		t.Fatalf("part=%q toolchain=%q, want the stored part and the given toolchain", *part, *toolchain)
+	}
+}
+
+func TestParseAssignments(t *testing.T) {
+	got, err := parseAssignments("--define", []string{"SIM", "CLK_HZ=100000000"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, map[string]string{"SIM": "", "CLK_HZ": "100000000"}) {
+		t.Fatalf("defines = %v", got)
+	}
+	if _, err := parseAssignments("--param", []string{"WIDTH"}, true); err == nil {
+		t.Fatal("expected an error for a parameter without a value")
+	}
+	if _, err := parseAssignments("--param", []string{"WIDTH=8", "WIDTH=16"}, true); err == nil {
+		t.Fatal("expected an error for a repeated name")
+	}
+}
+
+func TestParseFlashTo(t *testing.T) {
+	action, err := parseFlashTo("bench:arty:{project}-{git_short}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if action.Loader != "bench" || action.Board != "arty" || action.DesignName != "{project}-{git_short}" {
+		t.Fatalf("unexpected action: %+v", action)
+	}
+	if _, err := parseFlashTo("bench"); err == nil {
+		t.Fatal("expected an error without a board")
+	}
+}
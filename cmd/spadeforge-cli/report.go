@@ -0,0 +1,225 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr,omitempty"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitTestCase `xml:"testcase"`
+	SystemOut  string          `xml:"system-out,omitempty"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Body    string `xml:",chardata"`
+}
+
+func runReport(args []string) error {
+	fs := flag.NewFlagSet("spadeforge-cli report", flag.ContinueOnError)
+	sf := addServerFlags(fs)
+	jobID := fs.String("job-id", "", "finished job to report (positional ID also accepted)")
+	format := fs.String("format", "junit", "report format: junit")
+	out := fs.String("out", "", "output file, - for stdout (default <job-id>.junit.xml)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *jobID == "" && fs.NArg() > 0 {
+		*jobID = fs.Arg(0)
+	}
+	if strings.TrimSpace(*jobID) == "" {
+		return fmt.Errorf("--job-id is required")
+	}
+	if *format != "junit" {
+		return fmt.Errorf("--format must be junit, got %q", *format)
+	}
+
+	c, err := sf.newClient()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	rec, err := c.GetJob(ctx, *jobID)
+	if err != nil {
+		return err
+	}
+	if !rec.Terminal() {
+		return fmt.Errorf("job %s is still %s", rec.ID, rec.State)
+	}
+	timeline := collectTimeline(ctx, c, rec)
+	// Jobs that failed before the builder ran have no diagnostics.
+	report, _ := c.GetDiagnostics(ctx, rec.ID)
+
+	if *out == "-" {
+		return writeJUnitReport(os.Stdout, rec, timeline, report)
+	}
+	path := cmp.Or(*out, rec.ID+".junit.xml")
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeJUnitReport(f, rec, timeline, report); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("junit report written to %s\n", path)
+	return nil
+}
+
+// writeJUnitReport writes the job as one test suite: a case per phase of
+// the timeline, a "build" case carrying the job outcome, and a failed case
+// per unsuppressed error diagnostic. Warnings go to system-out.
+func writeJUnitReport(w io.Writer, rec *job.Record, timeline *phaseTimeline, report *job.DiagnosticsReport) error {
+	project := cmp.Or(rec.Manifest.Project, rec.ID)
+	suite := junitTestSuite{
+		Name:      project,
+		Time:      junitSeconds(timeline.Elapsed()),
+		Timestamp: rec.CreatedAt.UTC().Format("2006-01-02T15:04:05"),
+		Properties: []junitProperty{
+			{Name: "job_id", Value: rec.ID},
+			{Name: "state", Value: string(rec.State)},
+			{Name: "top", Value: rec.Manifest.Top},
+			{Name: "part", Value: rec.Manifest.Part},
+		},
+	}
+	if g := rec.Manifest.Git; g != nil {
+		suite.Properties = append(suite.Properties, junitProperty{Name: "git_commit", Value: g.Commit})
+	}
+	if rec.FailureKind != "" {
+		suite.Properties = append(suite.Properties, junitProperty{Name: "failure_kind", Value: rec.FailureKind})
+	}
+
+	for _, span := range timeline.Totals() {
+		suite.Cases = append(suite.Cases, junitTestCase{
+			Name:      span.Name,
+			ClassName: project + ".phases",
+			Time:      junitSeconds(span.Duration()),
+		})
+	}
+
+	build := junitTestCase{Name: "build", ClassName: project, Time: junitSeconds(timeline.Elapsed())}
+	switch rec.State {
+	case job.StateFailed:
+		build.Failure = &junitMessage{
+			Message: cmp.Or(rec.FailureSummary, rec.Error, rec.Message),
+			Type:    rec.FailureKind,
+			Body:    rec.Error,
+		}
+	case job.StateCanceled:
+		build.Skipped = &junitMessage{Message: cmp.Or(rec.Message, "canceled")}
+	}
+	suite.Cases = append(suite.Cases, build)
+
+	if report != nil {
+		var warnings strings.Builder
+		for _, d := range report.Diagnostics {
+			if d.Suppressed {
+				continue
+			}
+			switch d.Severity {
+			case job.SeverityError:
+				suite.Cases = append(suite.Cases, junitTestCase{
+					Name:      diagnosticCaseName(d),
+					ClassName: project + ".diagnostics",
+					Time:      junitSeconds(0),
+					Failure:   &junitMessage{Message: d.Message, Type: d.Code, Body: cmp.Or(d.Raw, d.Message)},
+				})
+			case job.SeverityWarning:
+				fmt.Fprintf(&warnings, "WARNING %s\n", diagnosticCaseName(d))
+			}
+		}
+		suite.SystemOut = warnings.String()
+	}
+
+	for _, tc := range suite.Cases {
+		suite.Tests++
+		if tc.Failure != nil {
+			suite.Failures++
+		}
+		if tc.Skipped != nil {
+			suite.Skipped++
+		}
+	}
+	doc := junitTestSuites{
+		Name:     "spadeforge",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// diagnosticCaseName is "[code] message (file:line)" with the parts known.
+func diagnosticCaseName(d job.Diagnostic) string {
+	var b strings.Builder
+	if d.Code != "" {
+		fmt.Fprintf(&b, "[%s] ", d.Code)
+	}
+	b.WriteString(d.Message)
+	switch {
+	case d.File != "" && d.Line > 0:
+		fmt.Fprintf(&b, " (%s:%d)", d.File, d.Line)
+	case d.File != "":
+		fmt.Fprintf(&b, " (%s)", d.File)
+	}
+	return b.String()
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/manifest"
+)
+
+func TestWriteJUnitReport_FailedBuild(t *testing.T) {
+	start := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	timeline := &phaseTimeline{}
+	timeline.Observe(job.StateQueued, "", start)
+	timeline.Observe(job.StateRunning, "synth", start.Add(5*time.Second))
+	timeline.Observe(job.StateRunning, "route", start.Add(65*time.Second))
+	timeline.Observe(job.StateFailed, "", start.Add(95*time.Second))
+
+	rec := &job.Record{
+		ID:             "j1",
+		State:          job.StateFailed,
+		CreatedAt:      start,
+		Manifest:       manifest.Manifest{Project: "blinky", Top: "top", Part: "xc7a35t"},
+		Error:          "vivado exited with status 1",
+		FailureKind:    "timing",
+		FailureSummary: "WNS -0.120ns",
+	}
+	report := &job.DiagnosticsReport{Diagnostics: []job.Diagnostic{
+		{Severity: job.SeverityError, Code: "Timing 38-282", Message: "design does not meet timing", File: "top.sv", Line: 12},
+		{Severity: job.SeverityError, Code: "Synth 8-1", Message: "known noise", Suppressed: true},
+		{Severity: job.SeverityWarning, Code: "Synth 8-327", Message: "inferring latch"},
+	}}
+
+	var buf bytes.Buffer
+	if err := writeJUnitReport(&buf, rec, timeline, report); err != nil {
+		t.Fatalf("writeJUnitReport() error: %v", err)
+	}
+
+	var doc junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("report is not valid xml: %v\n%s", err, buf.String())
+	}
+	if doc.Tests != 5 || doc.Failures != 2 || doc.Time != "95.000" {
+		t.Fatalf("totals tests=%d failures=%d time=%s, want 5/2/95.000", doc.Tests, doc.Failures, doc.Time)
+	}
+	suite := doc.Suites[0]
+	var names []string
+	for _, tc := range suite.Cases {
+		names = append(names, tc.ClassName+"/"+tc.Name+"="+tc.Time)
+	}
+	want := []string{
+		"blinky.phases/queued=5.000",
+		"blinky.phases/synth=60.000",
+		"blinky.phases/route=30.000",
+		"blinky/build=95.000",
+		"blinky.diagnostics/[Timing 38-282] design does not meet timing (top.sv:12)=0.000",
+	}
+	if strings.Join(names, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("cases:\n%s\nwant:\n%s", strings.Join(names, "\n"), strings.Join(want, "\n"))
+	}
+	if f := suite.Cases[3].Failure; f == nil || f.Type != "timing" || f.Message != "WNS -0.120ns" {
+		t.Fatalf("build failure = %+v", f)
+	}
+	if !strings.Contains(suite.SystemOut, "[Synth 8-327] inferring latch") {
+		t.Fatalf("warnings missing from system-out: %q", suite.SystemOut)
+	}
+}
+
+func TestWriteJUnitReport_CanceledBuildIsSkipped(t *testing.T) {
+	rec := &job.Record{ID: "j2", State: job.StateCanceled, Message: "canceled by user"}
+	var buf bytes.Buffer
+	if err := writeJUnitReport(&buf, rec, &phaseTimeline{}, nil); err != nil {
+		t.Fatalf("writeJUnitReport() error: %v", err)
+	}
+	var doc junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("report is not valid xml: %v", err)
+	}
+	if doc.Tests != 1 || doc.Skipped != 1 || doc.Suites[0].Name != "j2" {
+		t.Fatalf("unexpected report: %+v", doc)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/client"
+	"github.com/mblsha/spadeforge/internal/manifest"
+)
+
+// reproPlan is a job's bundle unpacked next to the build.tcl the server
+// would generate for it.
+type reproPlan struct {
+	Dir      string
+	Manifest manifest.Manifest
+	TCLPath  string
+	Command  builder.CommandSpec
+}
+
+func runRepro(args []string) error {
+	fs := flag.NewFlagSet("spadeforge-cli repro", flag.ContinueOnError)
+	sf := addServerFlags(fs)
+	jobID := fs.String("job-id", "", "job ID to reproduce (or pass it as the argument)")
+	dir := fs.String("dir", "", "directory to unpack into (default: <job-id>-repro)")
+	vivadoBin := fs.String("vivado", defaultString(os.Getenv("SPADEFORGE_VIVADO_BIN"), "vivado"), "local Vivado binary used in the printed command")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	id := strings.TrimSpace(*jobID)
+	if id == "" && fs.NArg() == 1 {
+		id = strings.TrimSpace(fs.Arg(0))
+	}
+	if id == "" || fs.NArg() > 1 {
+		return fmt.Errorf("usage: spadeforge-cli repro <job_id> [--dir <path>]")
+	}
+	target := strings.TrimSpace(*dir)
+	if target == "" {
+		target = id + "-repro"
+	}
+
+	c, err := sf.newClient()
+	if err != nil {
+		return err
+	}
+	var bundle bytes.Buffer
+	if err := c.DownloadBundle(context.Background(), id, &bundle); err != nil {
+		return err
+	}
+	plan, err := prepareRepro(bundle.Bytes(), target, *vivadoBin, runtime.GOOS)
+	if err != nil {
+		return err
+	}
+	printReproPlan(os.Stdout, id, plan)
+	return nil
+}
+
+// prepareRepro extracts bundle into dir/src and writes dir/build.tcl with
+// the server's generator, pointing reports and the bitstream at
+// dir/artifacts. dir must not exist yet or be empty.
+func prepareRepro(bundle []byte, dir, vivadoBin, osName string) (*reproPlan, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if entries, err := os.ReadDir(absDir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("%s already exists and is not empty", absDir)
+	}
+
+	srcDir := filepath.Join(absDir, "src")
+	if err := client.ExtractArtifactZip(bundle, srcDir); err != nil {
+		return nil, fmt.Errorf("extract bundle: %w", err)
+	}
+	rawManifest, err := os.ReadFile(filepath.Join(srcDir, "manifest.json"))
+	if err != nil {
+		return nil, fmt.Errorf("read manifest.json: %w", err)
+	}
+	mf, err := manifest.Parse(rawManifest)
+	if err != nil {
+		return nil, err
+	}
+
+	bj := builder.BuildJob{
+		WorkDir:      absDir,
+		SourceDir:    srcDir,
+		ArtifactsDir: filepath.Join(absDir, "artifacts"),
+		Manifest:     mf,
+	}
+	if err := os.MkdirAll(bj.ArtifactsDir, 0o755); err != nil {
+		return nil, fmt.Errorf("create artifacts directory: %w", err)
+	}
+	tclPath := filepath.Join(absDir, "build.tcl")
+	if err := os.WriteFile(tclPath, []byte(builder.GenerateTCL(bj)), 0o644); err != nil {
+		return nil, fmt.Errorf("write build.tcl: %w", err)
+	}
+	return &reproPlan{
+		Dir:      absDir,
+		Manifest: mf,
+		TCLPath:  tclPath,
+		Command:  builder.BuildCommand(osName, vivadoBin, tclPath, absDir),
+	}, nil
+}
+
+func printReproPlan(w io.Writer, jobID string, plan *reproPlan) {
+	fmt.Fprintf(w, "job %s (project=%s top=%s part=%s) unpacked in %s\n", jobID, plan.Manifest.Project, plan.Manifest.Top, plan.Manifest.Part, plan.Dir)
+	fmt.Fprintf(w, "  sources:   %s\n", filepath.Join(plan.Dir, "src"))
+	fmt.Fprintf(w, "  script:    %s\n", plan.TCLPath)
+	fmt.Fprintf(w, "  artifacts: %s\n", filepath.Join(plan.Dir, "artifacts"))
+	fmt.Fprintln(w, "run:")
+	cd := builder.CommandSpec{Name: "cd", Args: []string{plan.Command.Dir}}
+	fmt.Fprintf(w, "  %s && %s\n", cd, plan.Command)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mblsha/spadeforge/internal/client"
+)
+
+func TestPrepareRepro_WritesServerTCL(t *testing.T) {
+	srcDir := t.TempDir()
+	src := filepath.Join(srcDir, "top.sv")
+	if err := os.WriteFile(src, []byte("module top; endmodule\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := client.BuildBundle(client.BundleSpec{Project: "demo", Top: "top", Part: "xc7a35t", Sources: []string{src}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "job 1-repro")
+	plan, err := prepareRepro(bundle, dir, "vivado", "linux")
+	if err != nil {
+		t.Fatalf("prepareRepro: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "src", "hdl", "top.sv")); err != nil {
+		t.Fatalf("expected extracted source: %v", err)
+	}
+	tcl, err := os.ReadFile(plan.TCLPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(tcl), "synth_design -top top -part xc7a35t") {
+		t.Fatalf("unexpected build.tcl:\n%s", tcl)
+	}
+	if !strings.Contains(string(tcl), filepath.ToSlash(filepath.Join(dir, "artifacts", "design.bit"))) {
+		t.Fatalf("expected bitstream under local artifacts dir:\n%s", tcl)
+	}
+
+	var out bytes.Buffer
+	printReproPlan(&out, "job-1", plan)
+	want := "cd '" + dir + "' && vivado -mode batch -source '" + plan.TCLPath + "'"
+	if !strings.Contains(out.String(), want) {
+		t.Fatalf("expected %q in output:\n%s", want, out.String())
+	}
+
+	if _, err := prepareRepro(bundle, dir, "vivado", "linux"); err == nil {
+		t.Fatalf("expected error when the target directory is not empty")
+	}
+}
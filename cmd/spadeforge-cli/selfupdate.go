@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mblsha/spadeforge/internal/buildinfo"
+	"github.com/mblsha/spadeforge/internal/httptransport"
+	"github.com/mblsha/spadeforge/internal/selfupdate"
+)
+
+// runSelfUpdate replaces spadeforge-cli with the release matching the
+// server's version, or the one given with --version.
+func runSelfUpdate(args []string) error {
+	fs := flag.NewFlagSet("spadeforge-cli self-update", flag.ContinueOnError)
+	sf := addServerFlags(fs)
+	version := fs.String("version", "", "release to install (default: the server's version)")
+	releaseURL := fs.String("release-url", strings.TrimSpace(os.Getenv("SPADEFORGE_RELEASE_URL")), "release download directory, with {version} for the version (default: advertised by the server)")
+	publicKey := fs.String("public-key", strings.TrimSpace(os.Getenv("SPADEFORGE_RELEASE_PUBLIC_KEY")), "hex or base64 ed25519 key; requires a valid SHA256SUMS.sig")
+	check := fs.Bool("check", false, "only report whether an update is available")
+	force := fs.Bool("force", false, "reinstall even when already at the version")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	ctx := context.Background()
+	target, url, err := selfupdate.Resolve(*version, *releaseURL, func() (*buildinfo.Info, error) {
+		c, err := sf.newClient()
+		if err != nil {
+			return nil, err
+		}
+		return c.GetVersion(ctx)
+	})
+	if err != nil {
+		return err
+	}
+	current := buildinfo.Get().Version
+	if target == current && !*force {
+		fmt.Printf("spadeforge-cli %s is up to date\n", current)
+		return nil
+	}
+	if *check {
+		fmt.Printf("spadeforge-cli %s can be updated to %s\n", current, target)
+		return nil
+	}
+
+	opts := selfupdate.Options{
+		ReleaseURL: url,
+		Version:    target,
+		Binary:     "spadeforge-cli",
+		Client:     httptransport.ClientOrError(sf.transportOptions()),
+	}
+	if *publicKey != "" {
+		if opts.PublicKey, err = selfupdate.ParsePublicKey(*publicKey); err != nil {
+			return err
+		}
+	}
+	path, err := selfupdate.Update(ctx, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("updated %s: %s -> %s\n", path, current, target)
+	return nil
+}
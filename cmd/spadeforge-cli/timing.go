@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/client"
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+// phaseSpan is a contiguous interval a job spent in one state or build step.
+type phaseSpan struct {
+	Name  string
+	Start time.Time
+	End   time.Time
+}
+
+func (s phaseSpan) Duration() time.Duration {
+	if s.End.Before(s.Start) {
+		return 0
+	}
+	return s.End.Sub(s.Start)
+}
+
+// phaseTimeline folds job events into per-phase wall-clock spans. Phases are
+// the job state while queued and the builder step while running.
+type phaseTimeline struct {
+	spans []phaseSpan
+	done  bool
+}
+
+func phaseName(state job.State, step string) string {
+	if state == job.StateRunning && strings.TrimSpace(step) != "" {
+		return step
+	}
+	return strings.ToLower(string(state))
+}
+
+// Observe records that the job was in the given phase at time at. It returns
+// the elapsed time since the first observation and whether the phase changed.
+func (t *phaseTimeline) Observe(state job.State, step string, at time.Time) (time.Duration, bool) {
+	if t.done || at.IsZero() {
+		return t.Elapsed(), false
+	}
+	if state.Terminal() {
+		t.finish(at)
+		return t.Elapsed(), true
+	}
+
+	name := phaseName(state, step)
+	if n := len(t.spans); n > 0 {
+		last := &t.spans[n-1]
+		if at.After(last.End) {
+			last.End = at
+		}
+		if last.Name == name {
+			return t.Elapsed(), false
+		}
+	}
+	t.spans = append(t.spans, phaseSpan{Name: name, Start: at, End: at})
+	return t.Elapsed(), true
+}
+
+func (t *phaseTimeline) finish(at time.Time) {
+	if n := len(t.spans); n > 0 && at.After(t.spans[n-1].End) {
+		t.spans[n-1].End = at
+	}
+	t.done = true
+}
+
+// Elapsed is the wall-clock time between the first and last observation.
+func (t *phaseTimeline) Elapsed() time.Duration {
+	if len(t.spans) == 0 {
+		return 0
+	}
+	return t.spans[len(t.spans)-1].End.Sub(t.spans[0].Start)
+}
+
+// Totals sums span durations per phase name, preserving first-seen order.
+func (t *phaseTimeline) Totals() []phaseSpan {
+	out := make([]phaseSpan, 0, len(t.spans))
+	index := make(map[string]int, len(t.spans))
+	for _, s := range t.spans {
+		i, ok := index[s.Name]
+		if !ok {
+			index[s.Name] = len(out)
+			out = append(out, phaseSpan{Name: s.Name, Start: s.Start, End: s.Start.Add(s.Duration())})
+			continue
+		}
+		out[i].End = out[i].End.Add(s.Duration())
+	}
+	return out
+}
+
+func (t *phaseTimeline) WriteSummary(w io.Writer) {
+	totals := t.Totals()
+	if len(totals) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "durations:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, s := range totals {
+		fmt.Fprintf(tw, "  %s\t%s\n", s.Name, formatDuration(s.Duration()))
+	}
+	fmt.Fprintf(tw, "  total\t%s\n", formatDuration(t.Elapsed()))
+	_ = tw.Flush()
+}
+
+// timelineFromRecord approximates the timeline from record timestamps when
+// the event history is unavailable.
+func timelineFromRecord(rec *job.Record) *phaseTimeline {
+	t := &phaseTimeline{}
+	if rec == nil {
+		return t
+	}
+	t.Observe(job.StateQueued, "", rec.CreatedAt)
+	if rec.StartedAt != nil {
+		t.Observe(job.StateRunning, "", *rec.StartedAt)
+	}
+	if rec.FinishedAt != nil {
+		t.Observe(rec.State, "", *rec.FinishedAt)
+	}
+	return t
+}
+
+// collectTimeline replays the job's event history to build a timeline,
+// falling back to record timestamps if the stream cannot be read.
+func collectTimeline(ctx context.Context, c *client.HTTPClient, rec *job.Record) *phaseTimeline {
+	t := &phaseTimeline{}
+	err := c.StreamEvents(ctx, rec.ID, 0, func(ev *job.Event) {
+		t.Observe(ev.State, ev.Step, ev.At)
+	})
+	if err != nil || !t.done {
+		return timelineFromRecord(rec)
+	}
+	return t
+}
+
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+func TestPhaseTimeline_TotalsPerPhase(t *testing.T) {
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tl := &phaseTimeline{}
+	tl.Observe(job.StateQueued, "", base)
+	tl.Observe(job.StateRunning, "", base.Add(3*time.Second))
+	tl.Observe(job.StateRunning, "synth", base.Add(4*time.Second))
+	tl.Observe(job.StateRunning, "synth", base.Add(30*time.Second))
+	tl.Observe(job.StateRunning, "route", base.Add(64*time.Second))
+	elapsed, changed := tl.Observe(job.StateSucceeded, "route", base.Add(104*time.Second))
+	if !changed {
+		t.Fatalf("expected terminal observation to report a change")
+	}
+	if elapsed != 104*time.Second {
+		t.Fatalf("unexpected elapsed: %s", elapsed)
+	}
+
+	got := map[string]time.Duration{}
+	for _, s := range tl.Totals() {
+		got[s.Name] = s.Duration()
+	}
+	want := map[string]time.Duration{
+		"queued":  3 * time.Second,
+		"running": time.Second,
+		"synth":   60 * time.Second,
+		"route":   40 * time.Second,
+	}
+	for name, d := range want {
+		if got[name] != d {
+			t.Fatalf("phase %s: got %s want %s (all=%v)", name, got[name], d, got)
+		}
+	}
+
+	// Observations after the terminal state are ignored.
+	tl.Observe(job.StateRunning, "late", base.Add(200*time.Second))
+	if tl.Elapsed() != 104*time.Second {
+		t.Fatalf("timeline changed after terminal: %s", tl.Elapsed())
+	}
+}
+
+func TestPhaseTimeline_WriteSummary(t *testing.T) {
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tl := &phaseTimeline{}
+	tl.Observe(job.StateQueued, "", base)
+	tl.Observe(job.StateRunning, "synth", base.Add(5*time.Second))
+	tl.Observe(job.StateFailed, "", base.Add(65*time.Second))
+
+	var out bytes.Buffer
+	tl.WriteSummary(&out)
+	text := out.String()
+	for _, want := range []string{"durations:", "queued", "5s", "synth", "1m0s", "total", "1m5s"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("summary missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestTimelineFromRecord_UsesTimestamps(t *testing.T) {
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	started := base.Add(2 * time.Second)
+	finished := base.Add(12 * time.Second)
+	tl := timelineFromRecord(&job.Record{
+		State:      job.StateSucceeded,
+		CreatedAt:  base,
+		StartedAt:  &started,
+		FinishedAt: &finished,
+	})
+	totals := tl.Totals()
+	if len(totals) != 2 || totals[0].Duration() != 2*time.Second || totals[1].Duration() != 10*time.Second {
+		t.Fatalf("unexpected totals: %+v", totals)
+	}
+	if tl.Elapsed() != 12*time.Second {
+		t.Fatalf("unexpected elapsed: %s", tl.Elapsed())
+	}
+}
@@ -2,7 +2,11 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log"
 	"net"
 	"net/http"
@@ -12,39 +16,152 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/mblsha/spadeforge/internal/atrest"
 	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/buildinfo"
 	"github.com/mblsha/spadeforge/internal/config"
 	"github.com/mblsha/spadeforge/internal/discovery"
+	"github.com/mblsha/spadeforge/internal/doctor"
+	"github.com/mblsha/spadeforge/internal/httptransport"
+	"github.com/mblsha/spadeforge/internal/logx"
+	"github.com/mblsha/spadeforge/internal/manifest"
 	"github.com/mblsha/spadeforge/internal/queue"
 	"github.com/mblsha/spadeforge/internal/server"
 	"github.com/mblsha/spadeforge/internal/store"
 )
 
 func main() {
-	if len(os.Args) > 1 && os.Args[1] != "server" {
+	mode := "server"
+	var args []string
+	if len(os.Args) > 1 {
+		mode, args = os.Args[1], os.Args[2:]
+	}
+	switch mode {
+	case "server":
+		if err := runServer(args); err != nil {
+			log.Fatalf("server failed: %v", err)
+		}
+	case "tui":
+		if err := runTUI(args); err != nil {
+			log.Fatalf("tui failed: %v", err)
+		}
+	case "doctor":
+		os.Exit(runDoctor(os.Stdout))
+	case "tcl":
+		if err := runTCL(os.Args[2:], os.Stdout); err != nil {
+			log.Fatalf("tcl failed: %v", err)
+		}
+	case "release":
+		if err := runRelease(os.Args[2:], os.Stdout); err != nil {
+			log.Fatalf("release failed: %v", err)
+		}
+	case "version":
+		fmt.Println(buildinfo.Get())
+	default:
 		usage()
 		os.Exit(2)
 	}
-	if err := runServer(); err != nil {
-		log.Fatalf("server failed: %v", err)
+}
+
+// runDoctor prints the server self-checks and returns the process exit code:
+// 0 when nothing failed, 1 otherwise.
+func runDoctor(w io.Writer) int {
+	cfg, err := config.FromEnv()
+	if err != nil {
+		fmt.Fprintf(w, "[FAIL]  config  %v\n        fix: see \"Server config (env)\" in the README\n", err)
+		return 1
 	}
+	report := doctor.Run(context.Background(), doctor.ForgeChecks(cfg, nil))
+	_ = report.WriteText(w)
+	if report.Failed() {
+		return 1
+	}
+	return 0
 }
 
-func runServer() error {
+// tuiEnv are the variables spadeforge tui reads rather than the server
+// config, so --check-config does not report them as unknown.
+var tuiEnv = map[string]bool{"SPADEFORGE_SERVER": true}
+
+// runCheckConfig validates the env config and prints the effective config
+// as JSON with secrets redacted. Unused SPADEFORGE_ variables are reported
+// on errw and fail the check, since they are usually typos.
+func runCheckConfig(w, errw io.Writer, environ []string) error {
+	cfg, unused, err := config.CheckEnv(environ)
+	if err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(cfg.Effective()); err != nil {
+		return err
+	}
+	unknown := 0
+	for _, name := range unused {
+		if tuiEnv[name] {
+			continue
+		}
+		unknown++
+		fmt.Fprintf(errw, "unknown variable %s (no setting reads it; typo?)\n", name)
+	}
+	if unknown > 0 {
+		return fmt.Errorf("%d unknown SPADEFORGE_ variable(s)", unknown)
+	}
+	return nil
+}
+
+func runServer(args []string) error {
+	fs := flag.NewFlagSet("spadeforge server", flag.ContinueOnError)
+	checkConfig := fs.Bool("check-config", false, "validate the env config, print the effective config as JSON with secrets redacted, and exit")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *checkConfig {
+		return runCheckConfig(os.Stdout, os.Stderr, os.Environ())
+	}
+
 	cfg, err := config.FromEnv()
 	if err != nil {
 		return err
 	}
+	if err := logx.Apply(logx.Settings{Level: cfg.LogLevel, Modules: cfg.LogModules}); err != nil {
+		return err
+	}
 
 	var b builder.Builder
-	if strings.EqualFold(strings.TrimSpace(os.Getenv("SPADEFORGE_USE_FAKE_BUILDER")), "1") {
-		b = &builder.FakeBuilder{}
+	if cfg.UseFakeBuilder {
+		b = &builder.FakeBuilder{CrashRate: cfg.ChaosCrashRate}
 		log.Printf("using fake builder")
 	} else {
-		b = builder.NewVivadoBuilder(cfg.VivadoBin, nil)
+		var runner, ossRunner builder.Runner
+		if cfg.ToolchainImage != "" {
+			ossRunner = builder.NewContainerRunner(cfg.ContainerRuntime, cfg.ToolchainImage, cfg.WorkDir(), cfg.ArtifactsDir())
+			log.Printf("running yosys/nextpnr in %s via %s", cfg.ToolchainImage, cfg.ContainerRuntime)
+		}
+		if cfg.RecordDir != "" {
+			runner = builder.NewRecordingRunner(nil, cfg.RecordDir)
+			ossRunner = builder.NewRecordingRunner(ossRunner, cfg.RecordDir)
+			log.Printf("recording tool sessions to %s", cfg.RecordDir)
+		}
+		b = &builder.ToolchainBuilder{
+			Default: cfg.Builder,
+			Builders: map[string]builder.Builder{
+				manifest.ToolchainVivado: builder.NewVivadoBuilder(cfg.VivadoBin, runner),
+				manifest.ToolchainYosys:  builder.NewYosysNextpnrBuilder(cfg.OSSBinDir, ossRunner),
+			},
+		}
+		log.Printf("default builder: %s", cfg.Builder)
 	}
 
+	key, err := atrest.LoadKeyFile(cfg.EncryptionKeyFile)
+	if err != nil {
+		return err
+	}
 	st := store.New(cfg)
+	if key != nil {
+		st.SetKey(key)
+		log.Printf("encrypting stored bundles and bitstreams at rest")
+	}
 	mgr := queue.New(cfg, st, b)
 
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
@@ -54,8 +171,24 @@ func runServer() error {
 		return err
 	}
 
+	if cfg.ChaosErrorRate > 0 || cfg.ChaosSlowRate > 0 || cfg.ChaosDropRate > 0 || cfg.ChaosCrashRate > 0 {
+		log.Printf("CHAOS MODE: injecting faults (errors %.2f, slow %.2f, dropped streams %.2f, build crashes %.2f); not for real use",
+			cfg.ChaosErrorRate, cfg.ChaosSlowRate, cfg.ChaosDropRate, cfg.ChaosCrashRate)
+	}
 	api := server.New(cfg, mgr)
 	httpServer := &http.Server{Addr: cfg.ListenAddr, Handler: api.Handler()}
+	proto := "http"
+	if cfg.TLSCert != "" {
+		tlsCfg, err := httptransport.ServerTLSConfig(cfg.TLSCert, cfg.TLSKey, cfg.TLSClientCA)
+		if err != nil {
+			return err
+		}
+		httpServer.TLSConfig = tlsCfg
+		proto = "https"
+		if cfg.TLSClientCA != "" {
+			log.Printf("accepting client certificates issued by %s in place of the token", cfg.TLSClientCA)
+		}
+	}
 
 	var advertiser *discovery.Advertiser
 	if cfg.DiscoveryEnabled {
@@ -74,7 +207,7 @@ func runServer() error {
 				cfg.DiscoveryService,
 				cfg.DiscoveryDomain,
 				port,
-				[]string{"proto=http", "path=/healthz"},
+				[]string{"proto=" + proto, "path=/healthz"},
 				host,
 			)
 			if err != nil {
@@ -90,7 +223,11 @@ func runServer() error {
 
 	errCh := make(chan error, 1)
 	go func() {
-		log.Printf("spadeforge server listening on %s", cfg.ListenAddr)
+		log.Printf("spadeforge %s server listening on %s://%s", buildinfo.Get(), proto, cfg.ListenAddr)
+		if httpServer.TLSConfig != nil {
+			errCh <- httpServer.ListenAndServeTLS("", "")
+			return
+		}
 		errCh <- httpServer.ListenAndServe()
 	}()
 
@@ -110,7 +247,12 @@ func runServer() error {
 func usage() {
 	_, _ = os.Stderr.WriteString("spadeforge usage:\n")
 	_, _ = os.Stderr.WriteString("  spadeforge\n")
-	_, _ = os.Stderr.WriteString("  spadeforge server\n")
+	_, _ = os.Stderr.WriteString("  spadeforge server [--check-config]\n")
+	_, _ = os.Stderr.WriteString("  spadeforge tui [--server <url>] [--download-dir <dir>] [--no-color]\n")
+	_, _ = os.Stderr.WriteString("  spadeforge doctor\n")
+	_, _ = os.Stderr.WriteString("  spadeforge tcl [--manifest manifest.json] [--source-dir .] [--artifacts-dir artifacts] [--out build.tcl]\n")
+	_, _ = os.Stderr.WriteString("  spadeforge release [--version v1.2.3] [--out dist] [--targets linux/amd64,darwin/arm64,...]\n")
+	_, _ = os.Stderr.WriteString("  spadeforge version\n")
 }
 
 func hostFallback() string {
@@ -134,18 +276,5 @@ func parseListenHostPort(listenAddr string) (string, int, error) {
 }
 
 func isLoopbackListenHost(host string) bool {
-	trimmed := strings.TrimSpace(host)
-	if trimmed == "" {
-		return false
-	}
-	lowered := strings.ToLower(trimmed)
-	lowered = strings.TrimSuffix(lowered, ".")
-	if lowered == "localhost" {
-		return true
-	}
-	if idx := strings.IndexByte(lowered, '%'); idx >= 0 {
-		lowered = lowered[:idx]
-	}
-	ip := net.ParseIP(lowered)
-	return ip != nil && ip.IsLoopback()
+	return discovery.IsLoopbackListenHost(host)
 }
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/buildinfo"
+	"github.com/mblsha/spadeforge/internal/selfupdate"
+)
+
+// releaseBinaries are the commands under cmd/ shipped in every archive.
+var releaseBinaries = []string{"spadeforge", "spadeforge-cli", "spadeloader", "spadeloader-cli"}
+
+const defaultReleaseTargets = "linux/amd64,linux/arm64,darwin/amd64,darwin/arm64,windows/amd64,windows/arm64"
+
+var releaseVersionPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+-]*$`)
+
+type releaseTarget struct {
+	OS   string
+	Arch string
+}
+
+func (t releaseTarget) String() string {
+	return t.OS + "/" + t.Arch
+}
+
+func (t releaseTarget) exe() string {
+	if t.OS == "windows" {
+		return ".exe"
+	}
+	return ""
+}
+
+// release cross-compiles releaseBinaries for each target into OutDir, one
+// archive per target, and writes their SHA256SUMS.
+type release struct {
+	Version string
+	Commit  string
+	Date    time.Time
+	Root    string
+	OutDir  string
+	Targets []releaseTarget
+	// SigningKey, when set, signs SHA256SUMS into SHA256SUMS.sig for
+	// self-update to verify.
+	SigningKey ed25519.PrivateKey
+
+	// goBuild compiles pkg for t into out; tests replace it.
+	goBuild func(ctx context.Context, t releaseTarget, ldflags, pkg, out string) error
+}
+
+// runRelease builds release archives from the repository checkout in the
+// current directory.
+func runRelease(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("spadeforge release", flag.ContinueOnError)
+	version := fs.String("version", "", "version to stamp into the binaries (default: git describe)")
+	out := fs.String("out", "dist", "directory for the archives and SHA256SUMS")
+	targets := fs.String("targets", defaultReleaseTargets, "comma-separated GOOS/GOARCH pairs")
+	signingKey := fs.String("signing-key", "", "file with a hex or base64 ed25519 seed; signs SHA256SUMS as SHA256SUMS.sig")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if _, err := os.Stat("go.mod"); err != nil {
+		return fmt.Errorf("run spadeforge release from the repository root: %w", err)
+	}
+	parsed, err := parseReleaseTargets(*targets)
+	if err != nil {
+		return err
+	}
+
+	var key ed25519.PrivateKey
+	if strings.TrimSpace(*signingKey) != "" {
+		if key, err = selfupdate.LoadPrivateKey(*signingKey); err != nil {
+			return err
+		}
+	}
+
+	ctx := context.Background()
+	r := &release{
+		Version:    strings.TrimSpace(*version),
+		Root:       ".",
+		OutDir:     *out,
+		Targets:    parsed,
+		SigningKey: key,
+		Date:       time.Now().UTC(),
+		goBuild:    goBuildRelease,
+	}
+	if r.Version == "" {
+		r.Version = gitOutput(ctx, "describe", "--tags", "--always", "--dirty")
+	}
+	if r.Version == "" {
+		r.Version = "dev"
+	}
+	r.Commit = gitOutput(ctx, "rev-parse", "HEAD")
+	if raw := gitOutput(ctx, "show", "-s", "--format=%cI", "HEAD"); raw != "" {
+		if t, err := time.Parse(time.RFC3339, raw); err == nil {
+			r.Date = t.UTC()
+		}
+	}
+	return r.Run(ctx, stdout)
+}
+
+func parseReleaseTargets(raw string) ([]releaseTarget, error) {
+	var out []releaseTarget
+	seen := map[releaseTarget]bool{}
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		goos, goarch, ok := strings.Cut(item, "/")
+		if !ok || goos == "" || goarch == "" || strings.Contains(goarch, "/") {
+			return nil, fmt.Errorf("invalid target %q: want GOOS/GOARCH", item)
+		}
+		t := releaseTarget{OS: goos, Arch: goarch}
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no targets")
+	}
+	return out, nil
+}
+
+// Run builds every target and prints one line per archive.
+func (r *release) Run(ctx context.Context, stdout io.Writer) error {
+	if !releaseVersionPattern.MatchString(r.Version) {
+		return fmt.Errorf("invalid version %q", r.Version)
+	}
+	if err := os.MkdirAll(r.OutDir, 0o755); err != nil {
+		return err
+	}
+	ldflags := buildinfo.LDFlags(r.Version, r.Commit, r.Date.Format(time.RFC3339))
+
+	var sums strings.Builder
+	for _, t := range r.Targets {
+		archive, err := r.buildTarget(ctx, t, ldflags)
+		if err != nil {
+			return fmt.Errorf("%s: %w", t, err)
+		}
+		sum, err := sha256File(archive)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&sums, "%s  %s\n", sum, filepath.Base(archive))
+		fmt.Fprintf(stdout, "%s  %s\n", sum, archive)
+	}
+	sumsPath := filepath.Join(r.OutDir, selfupdate.ChecksumsName)
+	if err := os.WriteFile(sumsPath, []byte(sums.String()), 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "wrote %s\n", sumsPath)
+	if r.SigningKey != nil {
+		sigPath := filepath.Join(r.OutDir, selfupdate.SignatureName)
+		if err := os.WriteFile(sigPath, selfupdate.Sign(r.SigningKey, []byte(sums.String())), 0o644); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "wrote %s\n", sigPath)
+	}
+	return nil
+}
+
+// buildTarget compiles the binaries for t into a staging dir and packs
+// them, with the README, into a .tar.gz (or .zip for Windows).
+func (r *release) buildTarget(ctx context.Context, t releaseTarget, ldflags string) (string, error) {
+	archive := filepath.Join(r.OutDir, selfupdate.ArchiveName(r.Version, t.OS, t.Arch))
+	name := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(archive), ".zip"), ".tar.gz")
+	stage, err := os.MkdirTemp(r.OutDir, ".stage-"+name+"-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(stage)
+
+	var files []string
+	for _, bin := range releaseBinaries {
+		file := bin + t.exe()
+		if err := r.goBuild(ctx, t, ldflags, "./cmd/"+bin, filepath.Join(stage, file)); err != nil {
+			return "", fmt.Errorf("build %s: %w", bin, err)
+		}
+		files = append(files, file)
+	}
+	if raw, err := os.ReadFile(filepath.Join(r.Root, "README.md")); err == nil {
+		if err := os.WriteFile(filepath.Join(stage, "README.md"), raw, 0o644); err != nil {
+			return "", err
+		}
+		files = append(files, "README.md")
+	}
+
+	if t.OS == "windows" {
+		return archive, writeZipArchive(archive, stage, name, files, r.Date)
+	}
+	return archive, writeTarArchive(archive, stage, name, files, r.Date)
+}
+
+func goBuildRelease(ctx context.Context, t releaseTarget, ldflags, pkg, out string) error {
+	cmd := exec.CommandContext(ctx, "go", "build", "-trimpath", "-ldflags", ldflags, "-o", out, pkg)
+	cmd.Env = append(os.Environ(), "GOOS="+t.OS, "GOARCH="+t.Arch, "CGO_ENABLED=0")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// gitOutput runs git and returns its trimmed output, or "" when git fails.
+func gitOutput(ctx context.Context, args ...string) string {
+	out, err := exec.CommandContext(ctx, "git", args...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// releaseFileMode makes the binaries executable; the README is not.
+func releaseFileMode(file string) int64 {
+	if file == "README.md" {
+		return 0o644
+	}
+	return 0o755
+}
+
+// writeTarArchive packs files from dir under prefix/ with fixed times so
+// rebuilding a commit gives the same archive.
+func writeTarArchive(path, dir, prefix string, files []string, modTime time.Time) error {
+	var buf bytes.Buffer
+	gz, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(gz)
+	for _, file := range files {
+		raw, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			return err
+		}
+		hdr := &tar.Header{
+			Name:    prefix + "/" + file,
+			Mode:    releaseFileMode(file),
+			Size:    int64(len(raw)),
+			ModTime: modTime,
+			Format:  tar.FormatPAX,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(raw); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+func writeZipArchive(path, dir, prefix string, files []string, modTime time.Time) error {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, file := range files {
+		raw, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			return err
+		}
+		hdr := &zip.FileHeader{Name: prefix + "/" + file, Method: zip.Deflate, Modified: modTime}
+		hdr.SetMode(os.FileMode(releaseFileMode(file)))
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(raw); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
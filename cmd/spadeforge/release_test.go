@@ -0,0 +1,164 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/selfupdate"
+)
+
+func TestRelease_BuildsChecksummedArchivesPerTarget(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "README.md"), []byte("# spadeforge\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	targets, err := parseReleaseTargets("linux/arm64, windows/amd64,linux/arm64")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ldflagsSeen []string
+	r := &release{
+		SigningKey: priv,
+		Version:    "v1.2.3",
+		Commit:     "abc123",
+		Date:       time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Root:       root,
+		OutDir:     filepath.Join(root, "dist"),
+		Targets:    targets,
+		goBuild: func(_ context.Context, tg releaseTarget, ldflags, pkg, out string) error {
+			ldflagsSeen = append(ldflagsSeen, ldflags)
+			return os.WriteFile(out, []byte(tg.String()+" "+pkg), 0o755)
+		},
+	}
+	var stdout bytes.Buffer
+	if err := r.Run(context.Background(), &stdout); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ldflagsSeen) != 8 || !strings.Contains(ldflagsSeen[0], "buildinfo.Version=v1.2.3") || !strings.Contains(ldflagsSeen[0], "buildinfo.Commit=abc123") || !strings.Contains(ldflagsSeen[0], "buildinfo.Date=2026-01-02T03:04:05Z") {
+		t.Fatalf("unexpected ldflags: %q", ldflagsSeen)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(r.OutDir, "SHA256SUMS"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := os.ReadFile(filepath.Join(r.OutDir, selfupdate.SignatureName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := selfupdate.Verify(pub, raw, sig); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected two checksums, got:\n%s", raw)
+	}
+	for _, line := range lines {
+		sum, name, ok := strings.Cut(line, "  ")
+		if !ok {
+			t.Fatalf("bad checksum line %q", line)
+		}
+		got, err := sha256File(filepath.Join(r.OutDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != sum {
+			t.Fatalf("%s: checksum %s, file hashes to %s", name, sum, got)
+		}
+	}
+
+	tarNames := tarEntries(t, filepath.Join(r.OutDir, "spadeforge_v1.2.3_linux_arm64.tar.gz"))
+	want := []string{
+		"spadeforge_v1.2.3_linux_arm64/README.md",
+		"spadeforge_v1.2.3_linux_arm64/spadeforge",
+		"spadeforge_v1.2.3_linux_arm64/spadeforge-cli",
+		"spadeforge_v1.2.3_linux_arm64/spadeloader",
+		"spadeforge_v1.2.3_linux_arm64/spadeloader-cli",
+	}
+	if strings.Join(tarNames, ",") != strings.Join(want, ",") {
+		t.Fatalf("tar entries = %v, want %v", tarNames, want)
+	}
+
+	zr, err := zip.OpenReader(filepath.Join(r.OutDir, "spadeforge_v1.2.3_windows_amd64.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	var zipNames []string
+	for _, f := range zr.File {
+		zipNames = append(zipNames, f.Name)
+	}
+	if !strings.Contains(strings.Join(zipNames, ","), "spadeforge_v1.2.3_windows_amd64/spadeloader-cli.exe") {
+		t.Fatalf("zip entries = %v", zipNames)
+	}
+
+	entries, err := os.ReadDir(r.OutDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".stage-") {
+			t.Fatalf("staging dir %s left behind", e.Name())
+		}
+	}
+}
+
+func TestRelease_RejectsBadInput(t *testing.T) {
+	for _, raw := range []string{"", "linux", "linux/", "linux/arm64/v8"} {
+		if _, err := parseReleaseTargets(raw); err == nil {
+			t.Fatalf("expected %q to be rejected", raw)
+		}
+	}
+	r := &release{Version: "v1 beta", OutDir: t.TempDir()}
+	if err := r.Run(context.Background(), io.Discard); err == nil || !strings.Contains(err.Error(), "invalid version") {
+		t.Fatalf("expected invalid version error, got %v", err)
+	}
+}
+
+func tarEntries(t *testing.T, path string) []string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Name != "" && !strings.HasSuffix(hdr.Name, "README.md") && hdr.Mode&0o111 == 0 {
+			t.Fatalf("%s is not executable", hdr.Name)
+		}
+		names = append(names, hdr.Name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/manifest"
+)
+
+// runTCL writes the Vivado flow script the server would run for a
+// manifest, for local or air-gapped builds.
+func runTCL(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("spadeforge tcl", flag.ContinueOnError)
+	manifestPath := fs.String("manifest", "manifest.json", "path to manifest.json")
+	sourceDir := fs.String("source-dir", ".", "directory the manifest's source paths are relative to")
+	artifactsDir := fs.String("artifacts-dir", "artifacts", "directory for reports and design.bit")
+	out := fs.String("out", "", "output path (default: stdout)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	raw, err := os.ReadFile(*manifestPath)
+	if err != nil {
+		return fmt.Errorf("read manifest: %w", err)
+	}
+	mf, err := manifest.Parse(raw)
+	if err != nil {
+		return err
+	}
+	absSource, err := filepath.Abs(*sourceDir)
+	if err != nil {
+		return err
+	}
+	if err := mf.Validate(absSource); err != nil {
+		return fmt.Errorf("validate manifest: %w", err)
+	}
+	absArtifacts, err := filepath.Abs(*artifactsDir)
+	if err != nil {
+		return err
+	}
+
+	tcl := builder.GenerateTCL(builder.BuildJob{
+		SourceDir:    absSource,
+		ArtifactsDir: absArtifacts,
+		Manifest:     mf,
+	})
+	if strings.TrimSpace(*out) == "" {
+		_, err := io.WriteString(stdout, tcl)
+		return err
+	}
+	return os.WriteFile(*out, []byte(tcl), 0o644)
+}
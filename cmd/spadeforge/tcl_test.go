@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunTCL_MatchesServerFlow(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "hdl"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "hdl", "top.sv"), []byte("module top; endmodule\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	manifestPath := filepath.Join(dir, "manifest.json")
+	if err := os.WriteFile(manifestPath, []byte(`{"project":"demo","top":"top","part":"xc7a35t","sources":["hdl/top.sv"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	err := runTCL([]string{"--manifest", manifestPath, "--source-dir", dir, "--artifacts-dir", filepath.Join(dir, "out")}, &out)
+	if err != nil {
+		t.Fatalf("runTCL: %v", err)
+	}
+	tcl := out.String()
+	for _, want := range []string{
+		"read_verilog -sv {" + filepath.ToSlash(filepath.Join(dir, "hdl", "top.sv")) + "}",
+		"synth_design -top top -part xc7a35t",
+		"write_bitstream -force {" + filepath.ToSlash(filepath.Join(dir, "out", "design.bit")) + "}",
+	} {
+		if !strings.Contains(tcl, want) {
+			t.Fatalf("expected %q in script:\n%s", want, tcl)
+		}
+	}
+
+	err = runTCL([]string{"--manifest", manifestPath, "--source-dir", t.TempDir()}, &out)
+	if err == nil || !strings.Contains(err.Error(), "validate manifest") {
+		t.Fatalf("expected missing-source validation error, got %v", err)
+	}
+}
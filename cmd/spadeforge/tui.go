@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/client"
+	"github.com/mblsha/spadeforge/internal/discovery"
+	forgeui "github.com/mblsha/spadeforge/internal/tui"
+)
+
+// runTUI opens the build queue dashboard against a running server.
+func runTUI(args []string) error {
+	fs := flag.NewFlagSet("spadeforge tui", flag.ContinueOnError)
+	fs.Usage = usage
+
+	serverURL := fs.String("server", strings.TrimSpace(os.Getenv("SPADEFORGE_SERVER")), "builder server base url (if empty, auto-discover)")
+	discoverEnabled := fs.Bool("discover", true, "auto-discover server when --server is not provided")
+	discoverTimeout := fs.Duration("discover-timeout", 2*time.Second, "mDNS auto-discovery timeout")
+	discoverService := fs.String("discover-service", discovery.DefaultServiceName, "mDNS service name used for discovery")
+	discoverDomain := fs.String("discover-domain", discovery.DefaultDomain, "mDNS discovery domain")
+	token := fs.String("token", strings.TrimSpace(os.Getenv("SPADEFORGE_TOKEN")), "auth token")
+	authHeader := fs.String("auth-header", envWithFallback("SPADEFORGE_AUTH_HEADER", "X-Build-Token"), "auth header")
+	limit := fs.Int("limit", 100, "max number of jobs to show")
+	refresh := fs.Duration("refresh", 1500*time.Millisecond, "job list and console refresh interval")
+	tailLines := fs.Int("tail-lines", 200, "console lines fetched for the selected job")
+	downloadDir := fs.String("download-dir", ".", "directory artifact zips are saved to")
+	noColor := fs.Bool("no-color", os.Getenv("NO_COLOR") != "", "render without colours (default: true when NO_COLOR is set)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	resolvedServerURL, err := resolveTUIServerURL(*serverURL, *discoverEnabled, *discoverTimeout, *discoverService, *discoverDomain)
+	if err != nil {
+		return err
+	}
+	c := &client.HTTPClient{
+		BaseURL:    resolvedServerURL,
+		Token:      strings.TrimSpace(*token),
+		AuthHeader: strings.TrimSpace(*authHeader),
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	return forgeui.Run(ctx, forgeui.Options{
+		Client:          c,
+		Limit:           *limit,
+		RefreshInterval: *refresh,
+		TailLines:       *tailLines,
+		DownloadDir:     *downloadDir,
+		NoColor:         *noColor,
+	})
+}
+
+func resolveTUIServerURL(explicit string, discover bool, timeout time.Duration, service, domain string) (string, error) {
+	explicit = strings.TrimSpace(explicit)
+	if explicit != "" {
+		return explicit, nil
+	}
+	if !discover {
+		return "", errors.New("server is required when discovery is disabled; pass --server")
+	}
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	endpoint, err := discovery.Discover(ctx, service, domain)
+	if err != nil {
+		return "", fmt.Errorf("discover server via mDNS: %w", err)
+	}
+	return endpoint.URL, nil
+}
+
+func envWithFallback(key, fallback string) string {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		return v
+	}
+	return fallback
+}
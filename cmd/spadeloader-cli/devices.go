@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/discovery"
+	"github.com/mblsha/spadeforge/internal/httptransport"
+	"github.com/mblsha/spadeforge/internal/spadeloader/client"
+	"github.com/mblsha/spadeforge/internal/spadeloader/job"
+)
+
+// runDevices lists the programmers the spadeloader can see.
+func runDevices(args []string) error {
+	fs := flag.NewFlagSet("spadeloader-cli devices", flag.ContinueOnError)
+	serverURL := fs.String("server", defaultString(os.Getenv("SPADELOADER_SERVER"), ""), "spadeloader server base url (if empty, auto-discover)")
+	discoverEnabled := fs.Bool("discover", true, "auto-discover server when --server is not provided")
+	discoverTimeout := fs.Duration("discover-timeout", 2*time.Second, "mDNS auto-discovery timeout")
+	discoverService := fs.String("discover-service", "_spadeloader._tcp", "mDNS service name used for discovery")
+	token := fs.String("token", strings.TrimSpace(os.Getenv("SPADELOADER_TOKEN")), "auth token")
+	authHeader := fs.String("auth-header", defaultString(os.Getenv("SPADELOADER_AUTH_HEADER"), "X-Build-Token"), "auth header")
+	caFile := fs.String("ca-file", strings.TrimSpace(os.Getenv("SPADELOADER_CA_FILE")), "PEM CA bundle trusted in addition to system roots (for self-signed servers)")
+	fs.StringVar(caFile, "cacert", *caFile, "alias for --ca-file")
+	certFile := fs.String("cert", strings.TrimSpace(os.Getenv("SPADELOADER_CLIENT_CERT")), "PEM client certificate, accepted in place of the token by servers with a client CA")
+	keyFile := fs.String("key", strings.TrimSpace(os.Getenv("SPADELOADER_CLIENT_KEY")), "PEM private key for --cert")
+	insecure := fs.Bool("insecure-skip-verify", false, "disable TLS certificate verification (unsafe)")
+	all := fs.Bool("all", false, "include devices seen earlier but no longer connected")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if _, err := httptransport.NewClient(httptransport.Options{CAFile: *caFile, InsecureSkipVerify: *insecure, CertFile: *certFile, KeyFile: *keyFile}); err != nil {
+		return err
+	}
+	if *insecure {
+		fmt.Fprintln(os.Stderr, httptransport.InsecureWarning)
+	}
+	resolved, err := resolveServerURL(*serverURL, *discoverEnabled, *discoverTimeout, *discoverService, discovery.DefaultDomain)
+	if err != nil {
+		return err
+	}
+	c := &client.HTTPClient{
+		BaseURL:            resolved,
+		Token:              *token,
+		AuthHeader:         *authHeader,
+		CAFile:             *caFile,
+		InsecureSkipVerify: *insecure,
+		CertFile:           *certFile,
+		KeyFile:            *keyFile,
+	}
+	devices, err := c.ListDevices(context.Background())
+	if err != nil {
+		return err
+	}
+	if !*all {
+		devices = connectedDevices(devices)
+	}
+	printDevices(os.Stdout, devices)
+	return nil
+}
+
+func connectedDevices(devices []job.Device) []job.Device {
+	var out []job.Device
+	for _, d := range devices {
+		if d.Connected {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+func printDevices(w io.Writer, devices []job.Device) {
+	if len(devices) == 0 {
+		fmt.Fprintln(w, "no devices found")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DEVICE\tBOARD\tPRODUCT\tCONNECTED\tLAST SEEN")
+	for _, d := range devices {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%t\t%s\n",
+			d.Target().Device, defaultString(d.Board, "-"), defaultString(d.Product, "-"), d.Connected, d.LastSeen.Local().Format(time.DateTime))
+	}
+	_ = tw.Flush()
+}
+
+// pickDevice chooses the device to flash when --board is not given: the
+// connected devices with a known board, narrowed to want when --device is
+// set. Several candidates are offered as a numbered list when interactive,
+// and are an error otherwise.
+func pickDevice(devices []job.Device, want string, in io.Reader, out io.Writer, interactive bool) (job.Device, error) {
+	var candidates []job.Device
+	for _, d := range devices {
+		if !d.Connected || d.Board == "" {
+			continue
+		}
+		if want != "" && want != d.Target().Device && want != d.Key() && want != d.BusDev() {
+			continue
+		}
+		candidates = append(candidates, d)
+	}
+	switch {
+	case len(candidates) == 1:
+		return candidates[0], nil
+	case len(candidates) == 0 && want != "":
+		return job.Device{}, fmt.Errorf("device %s is not connected or has no known board; pass --board", want)
+	case len(candidates) == 0:
+		return job.Device{}, errors.New("no connected device with a known board; pass --board or map serials with SPADELOADER_DEVICE_BOARDS on the server")
+	}
+
+	if !interactive {
+		var names []string
+		for _, d := range candidates {
+			names = append(names, d.Board+" ("+d.Target().Device+")")
+		}
+		return job.Device{}, fmt.Errorf("several boards are connected, pass --board and --device: %s", strings.Join(names, ", "))
+	}
+	for i, d := range candidates {
+		fmt.Fprintf(out, "%d) %s  %s  %s\n", i+1, d.Board, d.Target().Device, d.Product)
+	}
+	fmt.Fprintf(out, "board [1-%d]: ", len(candidates))
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && line == "" {
+		return job.Device{}, fmt.Errorf("read board choice: %w", err)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || n < 1 || n > len(candidates) {
+		return job.Device{}, fmt.Errorf("invalid board choice %q", strings.TrimSpace(line))
+	}
+	return candidates[n-1], nil
+}
+
+// stdinIsTerminal reports whether a board can be picked interactively.
+func stdinIsTerminal() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -5,15 +5,19 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/mblsha/spadeforge/internal/discovery"
+	"github.com/mblsha/spadeforge/internal/httpretry"
+	"github.com/mblsha/spadeforge/internal/httptransport"
 	"github.com/mblsha/spadeforge/internal/spadeloader/client"
 	"github.com/mblsha/spadeforge/internal/spadeloader/job"
 )
@@ -22,6 +26,18 @@ var discoverFn = discovery.Discover
 
 func main() {
 	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "self-update" {
+		if err := runSelfUpdate(args[1:]); err != nil {
+			log.Fatalf("self-update failed: %v", err)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "devices" {
+		if err := runDevices(args[1:]); err != nil {
+			log.Fatalf("devices failed: %v", err)
+		}
+		return
+	}
 	if len(args) > 0 && args[0] == "flash" {
 		args = args[1:]
 	}
@@ -42,14 +58,31 @@ func runFlash(args []string) error {
 
 	token := fs.String("token", strings.TrimSpace(os.Getenv("SPADELOADER_TOKEN")), "auth token")
 	authHeader := fs.String("auth-header", defaultString(os.Getenv("SPADELOADER_AUTH_HEADER"), "X-Build-Token"), "auth header")
+	caFile := fs.String("ca-file", strings.TrimSpace(os.Getenv("SPADELOADER_CA_FILE")), "PEM CA bundle trusted in addition to system roots (for self-signed servers)")
+	fs.StringVar(caFile, "cacert", *caFile, "alias for --ca-file")
+	certFile := fs.String("cert", strings.TrimSpace(os.Getenv("SPADELOADER_CLIENT_CERT")), "PEM client certificate, accepted in place of the token by servers with a client CA")
+	keyFile := fs.String("key", strings.TrimSpace(os.Getenv("SPADELOADER_CLIENT_KEY")), "PEM private key for --cert")
+	insecure := fs.Bool("insecure-skip-verify", false, "disable TLS certificate verification (unsafe)")
+	retries := fs.Int("retries", httpretry.Default().MaxAttempts, "attempts for idempotent requests on transient network errors (1 disables retries)")
+	limitRate := fs.String("limit-rate", strings.TrimSpace(os.Getenv("SPADELOADER_LIMIT_RATE")), "cap upload and download speed in bytes per second, with optional K, M or G suffix (e.g. 2M)")
 
-	board := fs.String("board", "", "fpga board name (example: alchitry_au)")
+	board := fs.String("board", "", "fpga board name (example: alchitry_au); if empty, pick a connected device with a known board; all-tagged:<tag> flashes every board with the tag")
+	boards := fs.String("boards", "", "comma-separated boards to flash with the same bitstream, one job each (example: arty,basys3)")
 	designName := fs.String("name", "", "human-readable design name")
 	bitstream := fs.String("bitstream", "", "bitstream file path (.bit)")
+	device := fs.String("device", "", "programmer to use when several boards are attached: serial:<FTDI serial> or busdev:<bus>:<device>")
+	cable := fs.String("cable", "", "openFPGALoader cable overriding the board's default (example: ft2232)")
+	ftdiIndex := fs.Int("ftdi-index", -1, "pick among identical cables by probe order (0 is the first)")
 
+	mode := fs.String("mode", "sram", "sram loads the FPGA until power-off; flash writes the SPI flash so the design persists")
+	flashOffset := fs.String("flash-offset", "", "SPI flash offset for --mode flash, decimal or 0x hex (default 0)")
+	dryRun := fs.Bool("dry-run", false, "validate and detect the board without programming it")
+	monitor := fs.Bool("monitor", false, "after a successful flash, print the board's UART output until the server's monitor window ends or Ctrl-C (needs --wait)")
+	baud := fs.Int("baud", 0, "UART baud rate for --monitor (default: the server's configured rate for the board)")
 	wait := fs.Bool("wait", true, "poll until flash reaches terminal state")
 	poll := fs.Duration("poll", 2*time.Second, "status polling interval")
 	streamEvents := fs.Bool("stream-events", false, "stream server events (SSE) instead of polling")
+	plain := fs.Bool("plain", plainDefault(), "print a progress line only when the state or step changes, without heartbeats, for screen readers and CI logs (default: true when SPADELOADER_PLAIN is set or TERM=dumb)")
 	showLogOnFail := fs.Bool("show-log-on-fail", true, "print full remote console log on failure")
 	tailLines := fs.Int("tail-lines", 60, "print this many console tail lines on failure")
 
@@ -57,24 +90,114 @@ func runFlash(args []string) error {
 		return err
 	}
 
-	if strings.TrimSpace(*board) == "" || strings.TrimSpace(*designName) == "" || strings.TrimSpace(*bitstream) == "" {
-		return fmt.Errorf("--board, --name, and --bitstream are required")
+	if strings.TrimSpace(*designName) == "" || strings.TrimSpace(*bitstream) == "" {
+		return fmt.Errorf("--name and --bitstream are required")
 	}
 	if strings.ToLower(filepath.Ext(strings.TrimSpace(*bitstream))) != ".bit" {
 		return fmt.Errorf("--bitstream must point to a .bit file")
 	}
+	target, err := job.NewTarget(*device, *cable, *ftdiIndex)
+	if err != nil {
+		return err
+	}
+	flashMode, err := job.ParseMode(*mode)
+	if err != nil {
+		return fmt.Errorf("--mode: %w", err)
+	}
+	offset, err := job.ParseFlashOffset(*flashOffset)
+	if err != nil {
+		return fmt.Errorf("--flash-offset: %w", err)
+	}
+	if err := job.ValidateFlashOffset(flashMode, offset); err != nil {
+		return fmt.Errorf("--flash-offset: %w", err)
+	}
+	if *baud < 0 || (*baud > 0 && !*monitor) {
+		return fmt.Errorf("--baud needs --monitor and a positive rate")
+	}
+	if *monitor && *dryRun {
+		return fmt.Errorf("--monitor cannot be combined with --dry-run")
+	}
+
+	if _, err := httptransport.NewClient(httptransport.Options{CAFile: *caFile, InsecureSkipVerify: *insecure, CertFile: *certFile, KeyFile: *keyFile}); err != nil {
+		return err
+	}
+	if *insecure {
+		fmt.Fprintln(os.Stderr, httptransport.InsecureWarning)
+	}
+	rate, err := httptransport.ParseRate(*limitRate)
+	if err != nil {
+		return fmt.Errorf("--limit-rate: %w", err)
+	}
 
 	resolvedServerURL, err := resolveServerURL(*serverURL, *discoverEnabled, *discoverTimeout, *discoverService, *discoverDomain)
 	if err != nil {
 		return err
 	}
 
-	c := &client.HTTPClient{BaseURL: resolvedServerURL, Token: *token, AuthHeader: *authHeader}
+	c := &client.HTTPClient{
+		BaseURL:    resolvedServerURL,
+		Token:      *token,
+		AuthHeader: *authHeader,
+		Retry:      httpretry.Policy{MaxAttempts: *retries},
+
+		CAFile:             *caFile,
+		InsecureSkipVerify: *insecure,
+		CertFile:           *certFile,
+		KeyFile:            *keyFile,
+		LimitRate:          rate,
+	}
 	ctx := context.Background()
+	batchBoards := splitBoards(*boards)
+	if len(batchBoards) > 0 || strings.HasPrefix(strings.TrimSpace(*board), job.AllTaggedPrefix) {
+		if len(batchBoards) > 0 && strings.TrimSpace(*board) != "" {
+			return fmt.Errorf("use either --board or --boards")
+		}
+		if *monitor {
+			return fmt.Errorf("--monitor follows one board and cannot be combined with a batch")
+		}
+		b, err := c.SubmitFlashBatch(ctx, client.SubmitRequest{
+			Board:         strings.TrimSpace(*board),
+			DesignName:    strings.TrimSpace(*designName),
+			BitstreamPath: strings.TrimSpace(*bitstream),
+			DryRun:        *dryRun,
+			Mode:          flashMode,
+			FlashOffset:   offset,
+			Target:        target,
+		}, batchBoards)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("batch submitted: %s (%d jobs)\n", b.ID, len(b.Jobs))
+		if !*wait {
+			return nil
+		}
+		return waitForBatch(ctx, c, b.ID, *poll, os.Stdout)
+	}
+	if strings.TrimSpace(*board) == "" {
+		devices, err := c.ListDevices(ctx)
+		if err != nil {
+			return fmt.Errorf("--board not given and devices unavailable: %w", err)
+		}
+		picked, err := pickDevice(devices, target.Device, os.Stdin, os.Stdout, stdinIsTerminal())
+		if err != nil {
+			return err
+		}
+		*board = picked.Board
+		if target.Device == "" {
+			target.Device = picked.Target().Device
+		}
+		fmt.Printf("using board %s (%s)\n", picked.Board, target.Device)
+	}
 	jobID, err := c.SubmitFlash(ctx, client.SubmitRequest{
 		Board:         strings.TrimSpace(*board),
 		DesignName:    strings.TrimSpace(*designName),
 		BitstreamPath: strings.TrimSpace(*bitstream),
+		DryRun:        *dryRun,
+		Mode:          flashMode,
+		FlashOffset:   offset,
+		Target:        target,
+		Monitor:       *monitor,
+		MonitorBaud:   *baud,
 	})
 	if err != nil {
 		return err
@@ -84,13 +207,14 @@ func runFlash(args []string) error {
 		return nil
 	}
 
-	record, err := waitForTerminal(ctx, c, jobID, *poll, *streamEvents)
+	record, err := waitForTerminal(ctx, c, jobID, *poll, *streamEvents, *plain)
 	if err != nil {
 		return err
 	}
 	fmt.Printf("job finished: %s (%s)\n", record.State, record.Message)
 
 	if record.State == job.StateFailed {
+		printFailure(os.Stdout, record)
 		if *tailLines > 0 {
 			if tailText, err := c.GetLogTail(ctx, jobID, *tailLines); err == nil {
 				trimmed := strings.TrimSpace(tailText)
@@ -112,12 +236,90 @@ func runFlash(args []string) error {
 	if record.State != job.StateSucceeded {
 		return fmt.Errorf("flash failed: %s", defaultString(record.Error, record.Message))
 	}
+	if *monitor {
+		return followSerial(ctx, c, jobID, os.Stdout)
+	}
+	return nil
+}
+
+// splitBoards parses the --boards list.
+func splitBoards(v string) []string {
+	var out []string
+	for _, b := range strings.Split(v, ",") {
+		if b = strings.TrimSpace(b); b != "" {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// waitForBatch prints each job's state changes until the whole batch has
+// finished, then one line per job, and fails if any job did.
+func waitForBatch(ctx context.Context, c *client.HTTPClient, batchID string, poll time.Duration, w io.Writer) error {
+	last := map[string]string{}
+	b, err := c.WaitForBatch(ctx, batchID, poll, func(b *job.Batch) {
+		for _, rec := range b.Jobs {
+			phase := string(rec.State) + " " + defaultString(rec.CurrentStep, "-")
+			if rec.Terminal() || last[rec.ID] == phase {
+				continue
+			}
+			last[rec.ID] = phase
+			fmt.Fprintf(w, "%s: %s\n", rec.Board, phase)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	for _, rec := range b.Jobs {
+		fmt.Fprintf(w, "%s: %s %s (%s)\n", rec.Board, rec.State, rec.ID, defaultString(rec.Error, rec.Message))
+	}
+	if failed := b.Counts[job.StateFailed]; failed > 0 {
+		return fmt.Errorf("batch %s: %d of %d flashes failed", batchID, failed, len(b.Jobs))
+	}
+	return nil
+}
+
+// followSerial copies the job's UART capture to w until the server's
+// monitor ends or the user interrupts it.
+func followSerial(ctx context.Context, c *client.HTTPClient, jobID string, w io.Writer) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	fmt.Fprintln(os.Stderr, "serial monitor (Ctrl-C to stop):")
+	var monitorErr string
+	err := c.StreamSerial(ctx, jobID, func(chunk job.SerialChunk) {
+		_, _ = io.WriteString(w, chunk.Text)
+		if chunk.Done {
+			monitorErr = chunk.Error
+		}
+	})
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if monitorErr != "" {
+		return fmt.Errorf("serial monitor: %s", monitorErr)
+	}
 	return nil
 }
 
-func waitForTerminal(ctx context.Context, c *client.HTTPClient, jobID string, poll time.Duration, stream bool) (*job.Record, error) {
+// printFailure prints the classified failure and what to try next.
+func printFailure(w io.Writer, rec *job.Record) {
+	if rec.FailureKind == "" {
+		return
+	}
+	fmt.Fprintf(w, "failure: %s: %s\n", rec.FailureKind, rec.FailureSummary)
+	if hint := job.FailureGuidance(rec.FailureKind); hint != "" {
+		fmt.Fprintf(w, "hint: %s\n", hint)
+	}
+}
+
+// waitForTerminal prints each progress change until the job finishes. In
+// plain mode heartbeats are left out, so only state and step changes print.
+func waitForTerminal(ctx context.Context, c *client.HTTPClient, jobID string, poll time.Duration, stream, plain bool) (*job.Record, error) {
 	if stream {
-		return waitForTerminalViaEvents(ctx, c, jobID, poll)
+		return waitForTerminalViaEvents(ctx, c, jobID, poll, plain)
 	}
 
 	var lastState string
@@ -126,7 +328,9 @@ func waitForTerminal(ctx context.Context, c *client.HTTPClient, jobID string, po
 
 	return c.WaitForTerminalWithProgress(ctx, jobID, poll, func(rec *job.Record) {
 		heartbeat := "-"
-		if rec.HeartbeatAt != nil {
+		if plain {
+			heartbeat = ""
+		} else if rec.HeartbeatAt != nil {
 			heartbeat = rec.HeartbeatAt.UTC().Format(time.RFC3339)
 		}
 		step := rec.CurrentStep
@@ -137,7 +341,7 @@ func waitForTerminal(ctx context.Context, c *client.HTTPClient, jobID string, po
 		shouldPrint := rec.State != job.StateSucceeded && rec.State != job.StateFailed
 		changed := string(rec.State) != lastState || step != lastStep || heartbeat != lastHeartbeat
 		if shouldPrint && changed {
-			fmt.Printf("state=%s step=%s heartbeat=%s message=%s\n", rec.State, step, heartbeat, rec.Message)
+			printProgress(rec.State, step, heartbeat, rec.Message)
 			lastState = string(rec.State)
 			lastStep = step
 			lastHeartbeat = heartbeat
@@ -145,14 +349,16 @@ func waitForTerminal(ctx context.Context, c *client.HTTPClient, jobID string, po
 	})
 }
 
-func waitForTerminalViaEvents(ctx context.Context, c *client.HTTPClient, jobID string, poll time.Duration) (*job.Record, error) {
+func waitForTerminalViaEvents(ctx context.Context, c *client.HTTPClient, jobID string, poll time.Duration, plain bool) (*job.Record, error) {
 	var lastState string
 	var lastStep string
 	var lastHeartbeat string
 
-	printProgress := func(state job.State, step string, heartbeatAt *time.Time, message string) {
+	observe := func(state job.State, step string, heartbeatAt *time.Time, message string) {
 		heartbeat := "-"
-		if heartbeatAt != nil {
+		if plain {
+			heartbeat = ""
+		} else if heartbeatAt != nil {
 			heartbeat = heartbeatAt.UTC().Format(time.RFC3339)
 		}
 		if step == "" {
@@ -161,7 +367,7 @@ func waitForTerminalViaEvents(ctx context.Context, c *client.HTTPClient, jobID s
 		shouldPrint := state != job.StateSucceeded && state != job.StateFailed
 		changed := string(state) != lastState || step != lastStep || heartbeat != lastHeartbeat
 		if shouldPrint && changed {
-			fmt.Printf("state=%s step=%s heartbeat=%s message=%s\n", state, step, heartbeat, message)
+			printProgress(state, step, heartbeat, message)
 			lastState = string(state)
 			lastStep = step
 			lastHeartbeat = heartbeat
@@ -169,7 +375,7 @@ func waitForTerminalViaEvents(ctx context.Context, c *client.HTTPClient, jobID s
 	}
 
 	if err := c.StreamEvents(ctx, jobID, 0, func(ev *job.Event) {
-		printProgress(ev.State, ev.Step, ev.HeartbeatAt, ev.Message)
+		observe(ev.State, ev.Step, ev.HeartbeatAt, ev.Message)
 	}); err != nil {
 		return nil, err
 	}
@@ -183,14 +389,36 @@ func waitForTerminalViaEvents(ctx context.Context, c *client.HTTPClient, jobID s
 	}
 
 	return c.WaitForTerminalWithProgress(ctx, jobID, poll, func(update *job.Record) {
-		printProgress(update.State, update.CurrentStep, update.HeartbeatAt, update.Message)
+		observe(update.State, update.CurrentStep, update.HeartbeatAt, update.Message)
 	})
 }
 
+// printProgress prints one progress change; an empty heartbeat is left out.
+func printProgress(state job.State, step, heartbeat, message string) {
+	if heartbeat == "" {
+		fmt.Printf("state=%s step=%s message=%s\n", state, step, message)
+		return
+	}
+	fmt.Printf("state=%s step=%s heartbeat=%s message=%s\n", state, step, heartbeat, message)
+}
+
+// plainDefault turns plain progress on for dumb terminals and when
+// SPADELOADER_PLAIN asks for it.
+func plainDefault() bool {
+	if v := strings.TrimSpace(os.Getenv("SPADELOADER_PLAIN")); v != "" {
+		return v != "0" && !strings.EqualFold(v, "false")
+	}
+	return os.Getenv("TERM") == "dumb"
+}
+
 func usage() {
 	_, _ = os.Stderr.WriteString("spadeloader-cli usage:\n")
 	_, _ = os.Stderr.WriteString("  spadeloader-cli --board <board> --name <design-name> --bitstream design.bit [--server http://host:8080]\n")
 	_, _ = os.Stderr.WriteString("  spadeloader-cli flash --board <board> --name <design-name> --bitstream design.bit [--server http://host:8080]\n")
+	_, _ = os.Stderr.WriteString("  spadeloader-cli flash --name <design-name> --bitstream design.bit   (picks a connected board)\n")
+	_, _ = os.Stderr.WriteString("  spadeloader-cli flash --boards <board>,<board> --name <design-name> --bitstream design.bit\n")
+	_, _ = os.Stderr.WriteString("  spadeloader-cli devices [--all] [--server http://host:8080]\n")
+	_, _ = os.Stderr.WriteString("  spadeloader-cli self-update [--check] [--version v1.2.3] [--release-url URL] [--public-key KEY]\n")
 }
 
 func defaultString(v, fallback string) string {
@@ -119,7 +119,7 @@ func TestRunFlash_MissingRequiredArgsFails(t *testing.T) {
 	if err == nil {
 		t.Fatalf("expected error")
 	}
-	if !strings.Contains(err.Error(), "--board, --name, and --bitstream are required") {
+	if !strings.Contains(err.Error(), "--name and --bitstream are required") {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
@@ -156,7 +156,7 @@ func TestWaitForTerminalViaEvents_StreamEndsEarlyFallsBackToPolling(t *testing.T
 	defer ts.Close()
 
 	c := &client.HTTPClient{BaseURL: ts.URL, Client: ts.Client()}
-	rec, err := waitForTerminalViaEvents(context.Background(), c, "j1", 5*time.Millisecond)
+	rec, err := waitForTerminalViaEvents(context.Background(), c, "j1", 5*time.Millisecond, false)
 	if err != nil {
 		t.Fatalf("waitForTerminalViaEvents() error: %v", err)
 	}
@@ -167,3 +167,42 @@ func TestWaitForTerminalViaEvents_StreamEndsEarlyFallsBackToPolling(t *testing.T
 		t.Fatalf("expected fallback polling after stream close, getCalls=%d", getCalls.Load())
 	}
 }
+
+func TestPickDevice(t *testing.T) {
+	t.Parallel()
+
+	au := job.Device{Bus: 3, Address: 4, Serial: "FT5YJ1BZ", Board: "alchitry_au", Connected: true}
+	arty := job.Device{Bus: 1, Address: 9, Serial: "210319A28CB6", Board: "arty", Connected: true}
+	unknown := job.Device{Bus: 1, Address: 2, Connected: true}
+	unplugged := job.Device{Bus: 2, Address: 1, Serial: "OLD", Board: "icebreaker"}
+
+	got, err := pickDevice([]job.Device{au, unknown, unplugged}, "", nil, nil, false)
+	if err != nil || got.Board != "alchitry_au" {
+		t.Fatalf("single candidate: got %+v err=%v", got, err)
+	}
+
+	devices := []job.Device{au, arty, unknown, unplugged}
+	if _, err := pickDevice(devices, "", nil, nil, false); err == nil || !strings.Contains(err.Error(), "arty (serial:210319A28CB6)") {
+		t.Fatalf("expected non-interactive error listing boards, got %v", err)
+	}
+	got, err = pickDevice(devices, "busdev:1:9", nil, nil, false)
+	if err != nil || got.Board != "arty" {
+		t.Fatalf("--device narrows by location: got %+v err=%v", got, err)
+	}
+
+	var out strings.Builder
+	got, err = pickDevice(devices, "", strings.NewReader("2\n"), &out, true)
+	if err != nil || got.Board != "arty" {
+		t.Fatalf("interactive pick: got %+v err=%v", got, err)
+	}
+	if !strings.Contains(out.String(), "1) alchitry_au") {
+		t.Fatalf("expected numbered list, got %q", out.String())
+	}
+	if _, err := pickDevice(devices, "", strings.NewReader("7\n"), &out, true); err == nil {
+		t.Fatal("expected out-of-range choice to fail")
+	}
+
+	if _, err := pickDevice([]job.Device{unknown, unplugged}, "", nil, nil, true); err == nil || !strings.Contains(err.Error(), "pass --board") {
+		t.Fatalf("expected error without candidates, got %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/buildinfo"
+	"github.com/mblsha/spadeforge/internal/discovery"
+	"github.com/mblsha/spadeforge/internal/httptransport"
+	"github.com/mblsha/spadeforge/internal/selfupdate"
+	"github.com/mblsha/spadeforge/internal/spadeloader/client"
+)
+
+// runSelfUpdate replaces spadeloader-cli with the release matching the
+// spadeloader's version, or the one given with --version.
+func runSelfUpdate(args []string) error {
+	fs := flag.NewFlagSet("spadeloader-cli self-update", flag.ContinueOnError)
+	serverURL := fs.String("server", defaultString(os.Getenv("SPADELOADER_SERVER"), ""), "spadeloader server base url (if empty, auto-discover)")
+	discoverEnabled := fs.Bool("discover", true, "auto-discover server when --server is not provided")
+	discoverTimeout := fs.Duration("discover-timeout", 2*time.Second, "mDNS auto-discovery timeout")
+	discoverService := fs.String("discover-service", "_spadeloader._tcp", "mDNS service name used for discovery")
+	token := fs.String("token", strings.TrimSpace(os.Getenv("SPADELOADER_TOKEN")), "auth token")
+	authHeader := fs.String("auth-header", defaultString(os.Getenv("SPADELOADER_AUTH_HEADER"), "X-Build-Token"), "auth header")
+	caFile := fs.String("ca-file", strings.TrimSpace(os.Getenv("SPADELOADER_CA_FILE")), "PEM CA bundle trusted in addition to system roots (for self-signed servers)")
+	fs.StringVar(caFile, "cacert", *caFile, "alias for --ca-file")
+	certFile := fs.String("cert", strings.TrimSpace(os.Getenv("SPADELOADER_CLIENT_CERT")), "PEM client certificate, accepted in place of the token by servers with a client CA")
+	keyFile := fs.String("key", strings.TrimSpace(os.Getenv("SPADELOADER_CLIENT_KEY")), "PEM private key for --cert")
+	insecure := fs.Bool("insecure-skip-verify", false, "disable TLS certificate verification (unsafe)")
+
+	version := fs.String("version", "", "release to install (default: the spadeloader's version)")
+	releaseURL := fs.String("release-url", strings.TrimSpace(os.Getenv("SPADELOADER_RELEASE_URL")), "release download directory, with {version} for the version (default: advertised by the spadeloader)")
+	publicKey := fs.String("public-key", strings.TrimSpace(os.Getenv("SPADELOADER_RELEASE_PUBLIC_KEY")), "hex or base64 ed25519 key; requires a valid SHA256SUMS.sig")
+	check := fs.Bool("check", false, "only report whether an update is available")
+	force := fs.Bool("force", false, "reinstall even when already at the version")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	httpClient, err := httptransport.NewClient(httptransport.Options{CAFile: *caFile, InsecureSkipVerify: *insecure, CertFile: *certFile, KeyFile: *keyFile})
+	if err != nil {
+		return err
+	}
+	if *insecure {
+		fmt.Fprintln(os.Stderr, httptransport.InsecureWarning)
+	}
+
+	ctx := context.Background()
+	target, url, err := selfupdate.Resolve(*version, *releaseURL, func() (*buildinfo.Info, error) {
+		resolved, err := resolveServerURL(*serverURL, *discoverEnabled, *discoverTimeout, *discoverService, discovery.DefaultDomain)
+		if err != nil {
+			return nil, err
+		}
+		c := &client.HTTPClient{
+			BaseURL:            resolved,
+			Token:              *token,
+			AuthHeader:         *authHeader,
+			CAFile:             *caFile,
+			InsecureSkipVerify: *insecure,
+			CertFile:           *certFile,
+			KeyFile:            *keyFile,
+		}
+		return c.GetVersion(ctx)
+	})
+	if err != nil {
+		return err
+	}
+	current := buildinfo.Get().Version
+	if target == current && !*force {
+		fmt.Printf("spadeloader-cli %s is up to date\n", current)
+		return nil
+	}
+	if *check {
+		fmt.Printf("spadeloader-cli %s can be updated to %s\n", current, target)
+		return nil
+	}
+
+	opts := selfupdate.Options{
+		ReleaseURL: url,
+		Version:    target,
+		Binary:     "spadeloader-cli",
+		Client:     httpClient,
+	}
+	if *publicKey != "" {
+		if opts.PublicKey, err = selfupdate.ParsePublicKey(*publicKey); err != nil {
+			return err
+		}
+	}
+	path, err := selfupdate.Update(ctx, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("updated %s: %s -> %s\n", path, current, target)
+	return nil
+}
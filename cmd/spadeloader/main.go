@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"net/http"
@@ -14,10 +17,15 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/mblsha/spadeforge/internal/atrest"
+	"github.com/mblsha/spadeforge/internal/builder"
 	"github.com/mblsha/spadeforge/internal/discovery"
+	"github.com/mblsha/spadeforge/internal/doctor"
+	"github.com/mblsha/spadeforge/internal/httptransport"
 	"github.com/mblsha/spadeforge/internal/spadeloader/client"
 	loaderconfig "github.com/mblsha/spadeforge/internal/spadeloader/config"
 	"github.com/mblsha/spadeforge/internal/spadeloader/flasher"
+	"github.com/mblsha/spadeforge/internal/spadeloader/hass"
 	"github.com/mblsha/spadeforge/internal/spadeloader/history"
 	"github.com/mblsha/spadeforge/internal/spadeloader/queue"
 	"github.com/mblsha/spadeforge/internal/spadeloader/server"
@@ -40,6 +48,8 @@ func main() {
 		if err := runTUI(args); err != nil {
 			log.Fatalf("tui failed: %v", err)
 		}
+	case "doctor":
+		os.Exit(runDoctor(os.Stdout))
 	}
 }
 
@@ -52,14 +62,55 @@ func parseMode(args []string) (string, []string, error) {
 		return "server", args[1:], nil
 	case "tui":
 		return "tui", args[1:], nil
+	case "doctor":
+		return "doctor", args[1:], nil
 	default:
 		return "", nil, fmt.Errorf("unknown mode %q", args[0])
 	}
 }
 
+// tuiEnv are the variables the built-in TUI reads rather than the server
+// config, so --check-config does not report them as unknown.
+var tuiEnv = map[string]bool{"SPADELOADER_PLAIN": true, "SPADELOADER_SERVER": true}
+
+// runCheckConfig validates the env config and prints the effective config
+// as JSON with tokens redacted. Unknown SPADELOADER_ variables are listed on
+// errw and fail the check.
+func runCheckConfig(w, errw io.Writer, environ []string) error {
+	cfg, unused, err := loaderconfig.CheckEnv(environ)
+	if err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(cfg.Effective()); err != nil {
+		return err
+	}
+	unknown := 0
+	for _, name := range unused {
+		if tuiEnv[name] {
+			continue
+		}
+		unknown++
+		fmt.Fprintf(errw, "unknown variable %s (no setting reads it; typo?)\n", name)
+	}
+	if unknown > 0 {
+		return fmt.Errorf("%d unknown SPADELOADER_ variable(s)", unknown)
+	}
+	return nil
+}
+
 func runServerTUI(args []string) error {
-	if len(args) > 0 {
-		return fmt.Errorf("server mode does not accept flags; use environment variables for server config")
+	fs := flag.NewFlagSet("spadeloader server", flag.ContinueOnError)
+	checkConfig := fs.Bool("check-config", false, "validate the env config, print the effective config as JSON with secrets redacted, and exit")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("server mode only accepts --check-config; use environment variables for server config")
+	}
+	if *checkConfig {
+		return runCheckConfig(os.Stdout, os.Stderr, os.Environ())
 	}
 
 	cfg, err := loaderconfig.FromEnv()
@@ -77,10 +128,23 @@ func runServerTUI(args []string) error {
 			return err
 		}
 		log.Printf("using openFPGALoader binary: %s", resolvedBin)
-		f = flasher.NewOpenFPGALoaderFlasher(resolvedBin)
+		of := flasher.NewOpenFPGALoaderFlasher(resolvedBin)
+		if cfg.RecordDir != "" {
+			of.Runner = builder.NewRecordingRunner(nil, cfg.RecordDir)
+			log.Printf("recording openFPGALoader sessions to %s", cfg.RecordDir)
+		}
+		f = of
 	}
 
+	key, err := atrest.LoadKeyFile(cfg.EncryptionKeyFile)
+	if err != nil {
+		return err
+	}
 	st := store.New(cfg)
+	if key != nil {
+		st.SetKey(key)
+		log.Printf("encrypting stored bitstreams at rest")
+	}
 	historyStore := history.New(cfg.HistoryPath(), cfg.HistoryLimit)
 	mgr := queue.New(cfg, st, f, historyStore)
 
@@ -89,12 +153,33 @@ func runServerTUI(args []string) error {
 	rootCtx, cancelRoot := context.WithCancel(signalCtx)
 	defer cancelRoot()
 
+	if cfg.MQTTURL != "" {
+		bridge, err := hass.New(cfg, mgr)
+		if err != nil {
+			return err
+		}
+		go bridge.Run(rootCtx)
+		log.Printf("home assistant bridge enabled topic_prefix=%s discovery_prefix=%s", cfg.MQTTTopicPrefix, cfg.HassDiscoveryPrefix)
+	}
+
 	if err := mgr.Start(rootCtx); err != nil {
 		return err
 	}
 
 	api := server.New(cfg, mgr)
 	httpServer := &http.Server{Addr: cfg.ListenAddr, Handler: api.Handler()}
+	proto := "http"
+	if cfg.TLSCert != "" {
+		tlsCfg, err := httptransport.ServerTLSConfig(cfg.TLSCert, cfg.TLSKey, cfg.TLSClientCA)
+		if err != nil {
+			return err
+		}
+		httpServer.TLSConfig = tlsCfg
+		proto = "https"
+		if cfg.TLSClientCA != "" {
+			log.Printf("accepting client certificates issued by %s in place of the token", cfg.TLSClientCA)
+		}
+	}
 
 	var advertiser *discovery.Advertiser
 	advertisePrimaryAddr := ""
@@ -119,7 +204,7 @@ func runServerTUI(args []string) error {
 				cfg.DiscoveryService,
 				cfg.DiscoveryDomain,
 				port,
-				[]string{"proto=http", "path=/healthz"},
+				[]string{"proto=" + proto, "path=/healthz"},
 				host,
 			)
 			if err != nil {
@@ -140,7 +225,11 @@ func runServerTUI(args []string) error {
 
 	errCh := make(chan error, 1)
 	go func() {
-		log.Printf("spadeloader server listening on %s", cfg.ListenAddr)
+		log.Printf("spadeloader server listening on %s://%s", proto, cfg.ListenAddr)
+		if httpServer.TLSConfig != nil {
+			errCh <- httpServer.ListenAndServeTLS("", "")
+			return
+		}
 		errCh <- httpServer.ListenAndServe()
 	}()
 
@@ -170,10 +259,19 @@ func runServerTUI(args []string) error {
 		Token:      cfg.Token,
 		AuthHeader: cfg.AuthHeader,
 	}
+	if proto == "https" {
+		// The UI talks to this process over loopback, which the server
+		// certificate rarely names.
+		c.BaseURL = "https" + strings.TrimPrefix(localServerURL, "http")
+		c.InsecureSkipVerify = true
+	}
 	uiErr := loaderui.Run(uiCtx, loaderui.Options{
 		Client:               c,
 		Limit:                cfg.HistoryLimit,
 		AdvertisePrimaryAddr: advertisePrimaryAddr,
+		Kiosk:                cfg.Kiosk,
+		NoColor:              os.Getenv("NO_COLOR") != "",
+		Plain:                plainDefault(),
 	})
 
 	// Ensure worker contexts and in-flight operations are canceled when the UI exits.
@@ -195,8 +293,25 @@ func runServerTUI(args []string) error {
 func usage() {
 	_, _ = os.Stderr.WriteString("spadeloader usage:\n")
 	_, _ = os.Stderr.WriteString("  spadeloader\n")
-	_, _ = os.Stderr.WriteString("  spadeloader server\n")
-	_, _ = os.Stderr.WriteString("  spadeloader tui [--server <url>]\n")
+	_, _ = os.Stderr.WriteString("  spadeloader server [--check-config]\n")
+	_, _ = os.Stderr.WriteString("  spadeloader tui [--server <url>] [--kiosk] [--no-color] [--plain]\n")
+	_, _ = os.Stderr.WriteString("  spadeloader doctor\n")
+}
+
+// runDoctor prints the flashing-host self-checks and returns the process exit
+// code: 0 when nothing failed, 1 otherwise.
+func runDoctor(w io.Writer) int {
+	cfg, err := loaderconfig.FromEnv()
+	if err != nil {
+		fmt.Fprintf(w, "[FAIL]  config  %v\n        fix: see \"Server config (env)\" in the README\n", err)
+		return 1
+	}
+	report := doctor.Run(context.Background(), doctor.LoaderChecks(cfg, nil))
+	_ = report.WriteText(w)
+	if report.Failed() {
+		return 1
+	}
+	return 0
 }
 
 func hostFallback() string {
@@ -243,20 +358,7 @@ func localServerURLForClient(listenAddr string) (string, error) {
 }
 
 func isLoopbackListenHost(host string) bool {
-	trimmed := strings.TrimSpace(host)
-	if trimmed == "" {
-		return false
-	}
-	lowered := strings.ToLower(trimmed)
-	lowered = strings.TrimSuffix(lowered, ".")
-	if lowered == "localhost" {
-		return true
-	}
-	if idx := strings.IndexByte(lowered, '%'); idx >= 0 {
-		lowered = lowered[:idx]
-	}
-	ip := net.ParseIP(lowered)
-	return ip != nil && ip.IsLoopback()
+	return discovery.IsLoopbackListenHost(host)
 }
 
 func resolveOpenFPGALoaderBin(bin string) (string, error) {
@@ -1,6 +1,12 @@
 package main
 
-import "testing"
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
 
 func TestParseMode(t *testing.T) {
 	t.Parallel()
@@ -16,6 +22,7 @@ func TestParseMode(t *testing.T) {
 		{name: "explicit server", args: []string{"server"}, wantMode: "server"},
 		{name: "tui", args: []string{"tui"}, wantMode: "tui"},
 		{name: "tui with args", args: []string{"tui", "--server", "http://127.0.0.1:8080"}, wantMode: "tui", wantRest: []string{"--server", "http://127.0.0.1:8080"}},
+		{name: "doctor", args: []string{"doctor"}, wantMode: "doctor"},
 		{name: "invalid", args: []string{"bad"}, expectErr: true},
 	}
 
@@ -166,3 +173,22 @@ func TestResolveOpenFPGALoaderBin(t *testing.T) {
 		t.Fatalf("expected error for missing binary")
 	}
 }
+
+func TestRunCheckConfigIgnoresTUIVariables(t *testing.T) {
+	t.Parallel()
+
+	base := "SPADELOADER_BASE_DIR=" + t.TempDir()
+	var out, errOut bytes.Buffer
+	if err := runCheckConfig(&out, &errOut, []string{base, "SPADELOADER_PLAIN=1"}); err != nil {
+		t.Fatalf("runCheckConfig() error: %v (%s)", err, errOut.String())
+	}
+	var eff map[string]any
+	if err := json.Unmarshal(out.Bytes(), &eff); err != nil || eff["ListenAddr"] == "" {
+		t.Fatalf("effective config = %s, %v", out.String(), err)
+	}
+
+	errOut.Reset()
+	if err := runCheckConfig(io.Discard, &errOut, []string{base, "SPADELOADER_KIOSKK=1"}); err == nil || !strings.Contains(errOut.String(), "SPADELOADER_KIOSKK") {
+		t.Fatalf("runCheckConfig() = %v, stderr %q; want the typo reported", err, errOut.String())
+	}
+}
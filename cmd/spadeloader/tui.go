@@ -30,6 +30,9 @@ func runTUI(args []string) error {
 	limit := fs.Int("limit", 100, "max number of jobs to show")
 	refresh := fs.Duration("refresh", 1500*time.Millisecond, "job list refresh interval")
 	reflashTimeout := fs.Duration("reflash-timeout", 30*time.Second, "timeout for creating a reflash job")
+	kiosk := fs.Bool("kiosk", false, "show only golden designs with a single flash action")
+	noColor := fs.Bool("no-color", os.Getenv("NO_COLOR") != "", "render without colours (default: true when NO_COLOR is set)")
+	plain := fs.Bool("plain", plainDefault(), "print line-oriented updates and read commands by line instead of drawing the screen (default: true when SPADELOADER_PLAIN is set or TERM=dumb)")
 
 	if err := fs.Parse(args); err != nil {
 		return err
@@ -54,6 +57,9 @@ func runTUI(args []string) error {
 		Limit:           *limit,
 		RefreshInterval: *refresh,
 		ReflashTimeout:  *reflashTimeout,
+		Kiosk:           *kiosk,
+		NoColor:         *noColor,
+		Plain:           *plain,
 	})
 }
 
@@ -78,6 +84,15 @@ func resolveTUIServerURL(explicit string, discover bool, timeout time.Duration,
 	return endpoint.URL, nil
 }
 
+// plainDefault turns plain mode on for dumb terminals and when
+// SPADELOADER_PLAIN asks for it.
+func plainDefault() bool {
+	if v := strings.TrimSpace(os.Getenv("SPADELOADER_PLAIN")); v != "" {
+		return v != "0" && !strings.EqualFold(v, "false")
+	}
+	return os.Getenv("TERM") == "dumb"
+}
+
 func envWithFallback(key, fallback string) string {
 	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
 		return v
@@ -112,6 +112,14 @@ func ExtractZipSecure(zipPath, dest string, limits Limits) ([]string, error) {
 }
 
 func WriteZipFromDir(srcDir string, w io.Writer) error {
+	return WriteZipFromDirFunc(srcDir, w, func(path string) (io.ReadCloser, error) {
+		return os.Open(path)
+	})
+}
+
+// WriteZipFromDirFunc is WriteZipFromDir reading each file through open,
+// e.g. to decrypt files stored encrypted.
+func WriteZipFromDirFunc(srcDir string, w io.Writer, open func(path string) (io.ReadCloser, error)) error {
 	zw := zip.NewWriter(w)
 	defer zw.Close()
 
@@ -149,7 +157,7 @@ func WriteZipFromDir(srcDir string, w io.Writer) error {
 			return err
 		}
 
-		rf, err := os.Open(pathNow)
+		rf, err := open(pathNow)
 		if err != nil {
 			return err
 		}
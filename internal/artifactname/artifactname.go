@@ -0,0 +1,115 @@
+// Package artifactname expands naming templates such as
+// "{project}-{part}-{git_short}-{date}.bit" for job artifacts and output
+// directories.
+package artifactname
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Vars are the values available to a template.
+type Vars struct {
+	JobID     string
+	Project   string
+	Top       string
+	Part      string
+	GitShort  string
+	GitBranch string
+	// Time is the job's creation time; {date} and {time} use it in UTC.
+	Time time.Time
+}
+
+// Placeholders lists the names a template may use inside braces.
+var Placeholders = []string{"job_id", "project", "top", "part", "git_short", "git_branch", "date", "time"}
+
+func (v Vars) lookup(name string) (string, bool) {
+	switch name {
+	case "job_id":
+		return v.JobID, true
+	case "project":
+		return v.Project, true
+	case "top":
+		return v.Top, true
+	case "part":
+		return v.Part, true
+	case "git_short":
+		return fallback(v.GitShort, "nogit"), true
+	case "git_branch":
+		return fallback(v.GitBranch, "nobranch"), true
+	case "date":
+		return v.Time.UTC().Format("20060102"), true
+	case "time":
+		return v.Time.UTC().Format("150405"), true
+	}
+	return "", false
+}
+
+// Validate checks that tmpl only uses known placeholders and yields a single
+// file name.
+func Validate(tmpl string) error {
+	_, err := Expand(tmpl, Vars{JobID: "x", Project: "x", Top: "x", Part: "x"})
+	return err
+}
+
+// Expand substitutes every {name} in tmpl. Substituted values have path
+// separators and spaces replaced with "_", and the result must be a single
+// path component.
+func Expand(tmpl string, v Vars) (string, error) {
+	tmpl = strings.TrimSpace(tmpl)
+	if tmpl == "" {
+		return "", errors.New("name template is empty")
+	}
+	var b strings.Builder
+	rest := tmpl
+	for {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			if strings.IndexByte(rest, '}') >= 0 {
+				return "", fmt.Errorf("name template %q has unmatched }", tmpl)
+			}
+			b.WriteString(rest)
+			break
+		}
+		if strings.IndexByte(rest[:open], '}') >= 0 {
+			return "", fmt.Errorf("name template %q has unmatched }", tmpl)
+		}
+		b.WriteString(rest[:open])
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("name template %q has unmatched {", tmpl)
+		}
+		name := rest[open+1 : open+end]
+		value, ok := v.lookup(name)
+		if !ok {
+			return "", fmt.Errorf("name template %q: unknown placeholder {%s} (known: %s)", tmpl, name, strings.Join(Placeholders, ", "))
+		}
+		b.WriteString(sanitize(value))
+		rest = rest[open+end+1:]
+	}
+
+	out := b.String()
+	if strings.ContainsAny(out, `/\`) || out == "." || out == ".." {
+		return "", fmt.Errorf("name template %q must expand to a single file name, got %q", tmpl, out)
+	}
+	return out, nil
+}
+
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ' ', ':', '\t', '\n':
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(s))
+}
+
+func fallback(v, def string) string {
+	if strings.TrimSpace(v) == "" {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,55 @@
+package artifactname
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpand(t *testing.T) {
+	v := Vars{
+		JobID:    "abc123",
+		Project:  "blinky",
+		Part:     "xc7a35tcsg324-1",
+		GitShort: "0123456789ab-dirty",
+		Time:     time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC),
+	}
+	got, err := Expand("{project}-{part}-{git_short}-{date}.bit", v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "blinky-xc7a35tcsg324-1-0123456789ab-dirty-20260304.bit" {
+		t.Fatalf("unexpected name: %q", got)
+	}
+
+	got, err = Expand("{job_id}_{time}_{git_branch}", Vars{JobID: "j1", Time: v.Time})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "j1_050607_nobranch" {
+		t.Fatalf("unexpected name: %q", got)
+	}
+
+	got, err = Expand("{project}", Vars{Project: "team/blinky board"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "team_blinky_board" {
+		t.Fatalf("expected separators replaced, got %q", got)
+	}
+}
+
+func TestExpand_Errors(t *testing.T) {
+	for tmpl, want := range map[string]string{
+		"":                  "empty",
+		"{nope}.bit":        "unknown placeholder {nope}",
+		"{project.bit":      "unmatched {",
+		"project}.bit":      "unmatched }",
+		"out/{project}.bit": "single file name",
+	} {
+		err := Validate(tmpl)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("Validate(%q) = %v, want error containing %q", tmpl, err, want)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+// Package atrest encrypts stored bundles and bitstreams with AES-256-GCM.
+//
+// A sealed file starts with a magic line and a random nonce prefix,
+// followed by the plaintext in 64 KiB chunks, each sealed separately. A
+// chunk's nonce is the prefix, its big-endian index and a flag marking the
+// last chunk, so chunks cannot be reordered, dropped or truncated away
+// without failing authentication. Files without the magic line are
+// plaintext, which lets stores read jobs written before a key was set.
+package atrest
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const (
+	magic      = "SPADESEAL1\n"
+	prefixSize = 7
+	chunkSize  = 64 << 10
+)
+
+// ErrNoKey is returned when a sealed file is opened without a key.
+var ErrNoKey = errors.New("file is encrypted at rest but no encryption key is configured")
+
+// ErrCorrupt is returned for sealed data that fails authentication.
+var ErrCorrupt = errors.New("encrypted file is corrupt or was sealed with another key")
+
+// Key seals and opens files. A nil *Key leaves new files in plaintext and
+// can still open plaintext files.
+type Key struct {
+	aead cipher.AEAD
+}
+
+// NewKey wraps a 32-byte AES-256 key.
+func NewKey(raw []byte) (*Key, error) {
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(raw))
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{aead: aead}, nil
+}
+
+// LoadKeyFile reads a key file holding 32 raw bytes or 64 hex characters.
+// An empty path returns a nil key.
+func LoadKeyFile(path string) (*Key, error) {
+	if path == "" {
+		return nil, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read encryption key: %w", err)
+	}
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) == 64 {
+		if decoded, err := hex.DecodeString(string(trimmed)); err == nil {
+			raw = decoded
+		}
+	}
+	k, err := NewKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key %s: %w", path, err)
+	}
+	return k, nil
+}
+
+// Enabled reports whether new files are sealed.
+func (k *Key) Enabled() bool {
+	return k != nil
+}
+
+// NewWriter returns a writer that seals everything written to it into dst.
+// Close must be called to write the last chunk; it does not close dst.
+// With a nil key the writer passes data through unchanged.
+func (k *Key) NewWriter(dst io.Writer) (io.WriteCloser, error) {
+	if k == nil {
+		return nopWriteCloser{dst}, nil
+	}
+	prefix := make([]byte, prefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(dst, magic); err != nil {
+		return nil, err
+	}
+	if _, err := dst.Write(prefix); err != nil {
+		return nil, err
+	}
+	return &sealWriter{aead: k.aead, dst: dst, prefix: prefix, buf: make([]byte, 0, chunkSize)}, nil
+}
+
+// Open returns a reader of src's plaintext: sealed data is decrypted and
+// anything else is passed through.
+func (k *Key) Open(src io.Reader) (io.Reader, error) {
+	br := bufio.NewReaderSize(src, chunkSize+64)
+	head, err := br.Peek(len(magic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if string(head) != magic {
+		return br, nil
+	}
+	if k == nil {
+		return nil, ErrNoKey
+	}
+	if _, err := br.Discard(len(magic)); err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, prefixSize)
+	if _, err := io.ReadFull(br, prefix); err != nil {
+		return nil, ErrCorrupt
+	}
+	return &openReader{aead: k.aead, src: br, prefix: prefix}, nil
+}
+
+// OpenFile opens path for reading its plaintext. Plaintext files are
+// returned as the *os.File itself, so callers can still seek them.
+func (k *Key) OpenFile(path string) (io.ReadCloser, error) {
+	sealed, err := IsSealed(path)
+	if err != nil {
+		return nil, err
+	}
+	if !sealed {
+		return os.Open(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := k.Open(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	return readCloser{Reader: r, Closer: f}, nil
+}
+
+// SealFile encrypts path in place. Files that are already sealed, and every
+// file when the key is nil, are left alone.
+func (k *Key) SealFile(path string) error {
+	if k == nil {
+		return nil
+	}
+	sealed, err := IsSealed(path)
+	if err != nil || sealed {
+		return err
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".seal-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	w, err := k.NewWriter(tmp)
+	if err == nil {
+		_, err = io.Copy(w, src)
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err == nil {
+		err = tmp.Chmod(info.Mode().Perm())
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("seal %s: %w", path, err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// IsSealed reports whether path starts with the sealed-file magic line.
+func IsSealed(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	head := make([]byte, len(magic))
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	return string(head[:n]) == magic, nil
+}
+
+func chunkNonce(prefix []byte, index uint32, last bool) []byte {
+	nonce := make([]byte, 0, prefixSize+5)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, index)
+	if last {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
+
+type sealWriter struct {
+	aead   cipher.AEAD
+	dst    io.Writer
+	prefix []byte
+	index  uint32
+	buf    []byte
+	out    []byte
+	closed bool
+}
+
+func (w *sealWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("atrest: write after close")
+	}
+	written := 0
+	for len(p) > 0 {
+		// A full chunk is only flushed once more data arrives, so Close
+		// always has a chunk to mark as last.
+		if len(w.buf) == chunkSize {
+			if err := w.flush(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(w.buf[len(w.buf):chunkSize], p)
+		w.buf = w.buf[:len(w.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+func (w *sealWriter) flush(last bool) error {
+	w.out = w.aead.Seal(w.out[:0], chunkNonce(w.prefix, w.index, last), w.buf, nil)
+	if _, err := w.dst.Write(w.out); err != nil {
+		return err
+	}
+	w.index++
+	w.buf = w.buf[:0]
+	return nil
+}
+
+func (w *sealWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.flush(true)
+}
+
+type openReader struct {
+	aead   cipher.AEAD
+	src    *bufio.Reader
+	prefix []byte
+	index  uint32
+	in     []byte
+	plain  []byte
+	done   bool
+}
+
+func (r *openReader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+func (r *openReader) next() error {
+	if cap(r.in) < chunkSize+r.aead.Overhead() {
+		r.in = make([]byte, chunkSize+r.aead.Overhead())
+	}
+	n, err := io.ReadFull(r.src, r.in[:cap(r.in)])
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return err
+	}
+	_, peekErr := r.src.Peek(1)
+	last := errors.Is(peekErr, io.EOF)
+	plain, openErr := r.aead.Open(r.in[:0:0], chunkNonce(r.prefix, r.index, last), r.in[:n], nil)
+	if openErr != nil {
+		return ErrCorrupt
+	}
+	r.index++
+	r.plain = plain
+	r.done = last
+	return nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
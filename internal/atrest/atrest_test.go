@@ -0,0 +1,151 @@
+package atrest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testKey(t *testing.T) *Key {
+	t.Helper()
+	k, err := NewKey(bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatalf("NewKey() error: %v", err)
+	}
+	return k
+}
+
+func seal(t *testing.T, k *Key, plain []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := k.NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("NewWriter() error: %v", err)
+	}
+	if _, err := w.Write(plain); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func open(k *Key, sealed []byte) ([]byte, error) {
+	r, err := k.Open(bytes.NewReader(sealed))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestRoundTrip_ChunkBoundaries(t *testing.T) {
+	k := testKey(t)
+	for _, size := range []int{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 3*chunkSize + 17} {
+		plain := make([]byte, size)
+		_, _ = rand.Read(plain)
+		sealed := seal(t, k, plain)
+		if size > 64 && bytes.Contains(sealed, plain[:64]) {
+			t.Fatalf("size %d: plaintext visible in sealed output", size)
+		}
+		got, err := open(k, sealed)
+		if err != nil {
+			t.Fatalf("size %d: open error: %v", size, err)
+		}
+		if !bytes.Equal(got, plain) {
+			t.Fatalf("size %d: round trip mismatch", size)
+		}
+	}
+}
+
+func TestOpen_RejectsTamperingAndTruncation(t *testing.T) {
+	k := testKey(t)
+	plain := bytes.Repeat([]byte("bitstream"), chunkSize/4)
+	sealed := seal(t, k, plain)
+
+	flipped := append([]byte(nil), sealed...)
+	flipped[len(flipped)/2] ^= 1
+	if _, err := open(k, flipped); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("flipped byte: err = %v, want ErrCorrupt", err)
+	}
+
+	// Cut right after the first full chunk, which was not sealed as last.
+	cut := len(magic) + prefixSize + chunkSize + k.aead.Overhead()
+	if _, err := open(k, sealed[:cut]); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("truncated: err = %v, want ErrCorrupt", err)
+	}
+
+	other, _ := NewKey(bytes.Repeat([]byte{8}, 32))
+	if _, err := open(other, sealed); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("wrong key: err = %v, want ErrCorrupt", err)
+	}
+}
+
+func TestOpen_PlaintextPassesThroughAndNilKeyRefusesSealed(t *testing.T) {
+	var nilKey *Key
+	got, err := open(nilKey, []byte("PK\x03\x04 plain zip"))
+	if err != nil || string(got) != "PK\x03\x04 plain zip" {
+		t.Fatalf("plaintext = %q, %v", got, err)
+	}
+	if _, err := open(nilKey, seal(t, testKey(t), []byte("secret"))); !errors.Is(err, ErrNoKey) {
+		t.Fatalf("nil key on sealed data: err = %v, want ErrNoKey", err)
+	}
+}
+
+func TestSealFile_InPlaceAndIdempotent(t *testing.T) {
+	k := testKey(t)
+	path := filepath.Join(t.TempDir(), "design.bit")
+	if err := os.WriteFile(path, []byte("fake-bitstream"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.SealFile(path); err != nil {
+		t.Fatalf("SealFile() error: %v", err)
+	}
+	first, _ := os.ReadFile(path)
+	if err := k.SealFile(path); err != nil {
+		t.Fatalf("second SealFile() error: %v", err)
+	}
+	second, _ := os.ReadFile(path)
+	if !bytes.Equal(first, second) {
+		t.Fatalf("sealing twice changed the file")
+	}
+	if sealed, _ := IsSealed(path); !sealed {
+		t.Fatalf("IsSealed() = false after SealFile")
+	}
+
+	r, err := k.OpenFile(path)
+	if err != nil {
+		t.Fatalf("OpenFile() error: %v", err)
+	}
+	defer r.Close()
+	if got, _ := io.ReadAll(r); string(got) != "fake-bitstream" {
+		t.Fatalf("decrypted = %q", got)
+	}
+}
+
+func TestLoadKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	hexPath := filepath.Join(dir, "hex.key")
+	_ = os.WriteFile(hexPath, []byte(strings.Repeat("ab", 32)+"\n"), 0o600)
+	rawPath := filepath.Join(dir, "raw.key")
+	_ = os.WriteFile(rawPath, bytes.Repeat([]byte{1}, 32), 0o600)
+	shortPath := filepath.Join(dir, "short.key")
+	_ = os.WriteFile(shortPath, []byte("too short"), 0o600)
+
+	for _, p := range []string{hexPath, rawPath} {
+		if k, err := LoadKeyFile(p); err != nil || k == nil {
+			t.Fatalf("LoadKeyFile(%s) = %v, %v", p, k, err)
+		}
+	}
+	if _, err := LoadKeyFile(shortPath); err == nil {
+		t.Fatalf("LoadKeyFile(short) succeeded")
+	}
+	if k, err := LoadKeyFile(""); k != nil || err != nil {
+		t.Fatalf("LoadKeyFile(\"\") = %v, %v, want nil, nil", k, err)
+	}
+}
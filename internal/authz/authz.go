@@ -0,0 +1,214 @@
+// Package authz holds the named API tokens both servers accept alongside
+// their shared token. Each token carries the scopes it may use and an
+// optional expiry; servers check the scope per route and record the token
+// name on the jobs it creates.
+package authz
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Scope is a class of routes a token may call.
+type Scope string
+
+const (
+	// ScopeRead covers reading jobs, logs, artifacts and events.
+	ScopeRead Scope = "read"
+	// ScopeSubmit covers submitting and canceling builds.
+	ScopeSubmit Scope = "submit"
+	// ScopeFlash covers flashing bitstreams on a spadeloader.
+	ScopeFlash Scope = "flash"
+	// ScopeAdmin covers everything else and implies every other scope.
+	ScopeAdmin Scope = "admin"
+)
+
+// Scopes lists every scope.
+var Scopes = []Scope{ScopeRead, ScopeSubmit, ScopeFlash, ScopeAdmin}
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Token is a named API token.
+type Token struct {
+	Name   string  `json:"name"`
+	Token  string  `json:"token"`
+	Scopes []Scope `json:"scopes"`
+	// ExpiresAt, when set, is when the token stops working.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Allows reports whether t may use scope.
+func (t Token) Allows(scope Scope) bool {
+	return slices.Contains(t.Scopes, scope) || slices.Contains(t.Scopes, ScopeAdmin)
+}
+
+// Expired reports whether t has expired at now.
+func (t Token) Expired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
+
+// ParseEntries parses "name:token:scope|scope[:expiry]" entries, where the
+// expiry is a date (2026-12-31, the start of that day in UTC) or an RFC 3339
+// time.
+func ParseEntries(entries []string) ([]Token, error) {
+	out := make([]Token, 0, len(entries))
+	for _, entry := range entries {
+		parts := strings.SplitN(entry, ":", 4)
+		if len(parts) < 3 {
+			return nil, fmt.Errorf("token entry %q must be name:token:scopes[:expiry]", redactEntry(entry))
+		}
+		tok := Token{Name: strings.TrimSpace(parts[0]), Token: strings.TrimSpace(parts[1])}
+		for _, s := range strings.Split(parts[2], "|") {
+			if s = strings.TrimSpace(s); s != "" {
+				tok.Scopes = append(tok.Scopes, Scope(strings.ToLower(s)))
+			}
+		}
+		if len(parts) == 4 {
+			expires, err := parseExpiry(parts[3])
+			if err != nil {
+				return nil, fmt.Errorf("token %q: %w", tok.Name, err)
+			}
+			tok.ExpiresAt = &expires
+		}
+		out = append(out, tok)
+	}
+	return out, nil
+}
+
+// LoadFile reads a JSON array of tokens.
+func LoadFile(path string) ([]Token, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read tokens file: %w", err)
+	}
+	var out []Token
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, fmt.Errorf("parse tokens file %s: %w", path, err)
+	}
+	for i := range out {
+		for j, s := range out[i].Scopes {
+			out[i].Scopes[j] = Scope(strings.ToLower(strings.TrimSpace(string(s))))
+		}
+	}
+	return out, nil
+}
+
+// Validate checks that names and secrets are set and unique and that every
+// scope is known. shared is the server's full-access token, which no named
+// token may reuse.
+func Validate(tokens []Token, shared string) error {
+	names := map[string]bool{}
+	secrets := map[string]bool{}
+	for _, t := range tokens {
+		if !namePattern.MatchString(t.Name) {
+			return fmt.Errorf("token name %q must be letters, digits, '.', '_' or '-'", t.Name)
+		}
+		if names[t.Name] {
+			return fmt.Errorf("token name %q is used twice", t.Name)
+		}
+		names[t.Name] = true
+		if strings.TrimSpace(t.Token) == "" {
+			return fmt.Errorf("token %q has an empty secret", t.Name)
+		}
+		if secrets[t.Token] || (shared != "" && t.Token == shared) {
+			return fmt.Errorf("token %q reuses another token's secret", t.Name)
+		}
+		secrets[t.Token] = true
+		if len(t.Scopes) == 0 {
+			return fmt.Errorf("token %q has no scopes", t.Name)
+		}
+		for _, s := range t.Scopes {
+			if !slices.Contains(Scopes, s) {
+				return fmt.Errorf("token %q has unknown scope %q (want read, submit, flash or admin)", t.Name, s)
+			}
+		}
+	}
+	return nil
+}
+
+// Lookup finds the token whose secret is got, comparing every secret in
+// constant time.
+func Lookup(tokens []Token, got string) (Token, bool) {
+	var found Token
+	ok := false
+	for _, t := range tokens {
+		if subtle.ConstantTimeCompare([]byte(got), []byte(t.Token)) == 1 {
+			found, ok = t, true
+		}
+	}
+	return found, ok
+}
+
+// ErrExpired is returned by Check for an expired token.
+var ErrExpired = errors.New("token expired")
+
+// Check returns an error when t may not use scope at now.
+func Check(t Token, scope Scope, now time.Time) error {
+	if t.Expired(now) {
+		return ErrExpired
+	}
+	if !t.Allows(scope) {
+		return fmt.Errorf("token %q lacks the %s scope", t.Name, scope)
+	}
+	return nil
+}
+
+// Redacted returns tokens with their secrets replaced by mask.
+func Redacted(tokens []Token, mask string) []Token {
+	if tokens == nil {
+		return nil
+	}
+	out := make([]Token, len(tokens))
+	for i, t := range tokens {
+		t.Token = mask
+		out[i] = t
+	}
+	return out
+}
+
+type tokenKey struct{}
+
+// WithToken returns ctx carrying the named token a request authenticated
+// with.
+func WithToken(ctx context.Context, t Token) context.Context {
+	return context.WithValue(ctx, tokenKey{}, t)
+}
+
+// FromContext returns the named token stored by WithToken.
+func FromContext(ctx context.Context) (Token, bool) {
+	t, ok := ctx.Value(tokenKey{}).(Token)
+	return t, ok
+}
+
+// Name returns the name of the token stored in ctx, or "" for requests
+// made with the shared token, a client certificate or no auth.
+func Name(ctx context.Context) string {
+	t, _ := FromContext(ctx)
+	return t.Name
+}
+
+func parseExpiry(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expiry %q must be YYYY-MM-DD or RFC 3339", s)
+	}
+	return t, nil
+}
+
+// redactEntry hides the secret of a malformed entry in error messages.
+func redactEntry(entry string) string {
+	name, _, _ := strings.Cut(entry, ":")
+	return name + ":..."
+}
@@ -0,0 +1,109 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseEntries(t *testing.T) {
+	tokens, err := ParseEntries([]string{
+		"ci:s3cret:read|Submit",
+		"dash:d4sh:read:2026-12-31",
+		"ops:0ps:admin:2026-12-31T18:00:00+01:00",
+	})
+	if err != nil {
+		t.Fatalf("ParseEntries() error: %v", err)
+	}
+	if len(tokens) != 3 {
+		t.Fatalf("got %d tokens", len(tokens))
+	}
+	if ci := tokens[0]; ci.Name != "ci" || ci.Token != "s3cret" || !ci.Allows(ScopeSubmit) || ci.Allows(ScopeAdmin) || ci.ExpiresAt != nil {
+		t.Fatalf("ci token = %+v", ci)
+	}
+	if got := tokens[1].ExpiresAt; got == nil || !got.Equal(time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("dash expiry = %v", got)
+	}
+	if got := tokens[2].ExpiresAt; got == nil || !got.Equal(time.Date(2026, 12, 31, 17, 0, 0, 0, time.UTC)) {
+		t.Fatalf("ops expiry = %v", got)
+	}
+	if err := Validate(tokens, "shared"); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+
+	if _, err := ParseEntries([]string{"ci:s3cret"}); err == nil {
+		t.Fatalf("expected error for an entry without scopes")
+	}
+	if _, err := ParseEntries([]string{"ci:s3cret:read:next-week"}); err == nil {
+		t.Fatalf("expected error for a bad expiry")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	ok := Token{Name: "ci", Token: "a", Scopes: []Scope{ScopeRead}}
+	for name, tokens := range map[string][]Token{
+		"unknown scope":  {{Name: "ci", Token: "a", Scopes: []Scope{"deploy"}}},
+		"no scopes":      {{Name: "ci", Token: "a"}},
+		"empty secret":   {{Name: "ci", Scopes: []Scope{ScopeRead}}},
+		"bad name":       {{Name: "c i", Token: "a", Scopes: []Scope{ScopeRead}}},
+		"duplicate name": {ok, {Name: "ci", Token: "b", Scopes: []Scope{ScopeRead}}},
+		"reused secret":  {ok, {Name: "dash", Token: "a", Scopes: []Scope{ScopeRead}}},
+		"shared secret":  {{Name: "ci", Token: "shared", Scopes: []Scope{ScopeRead}}},
+	} {
+		if err := Validate(tokens, "shared"); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Minute)
+	admin := Token{Name: "ops", Scopes: []Scope{ScopeAdmin}}
+	for _, scope := range Scopes {
+		if err := Check(admin, scope, now); err != nil {
+			t.Fatalf("admin token denied %s: %v", scope, err)
+		}
+	}
+	reader := Token{Name: "dash", Scopes: []Scope{ScopeRead}}
+	if err := Check(reader, ScopeSubmit, now); err == nil {
+		t.Fatalf("read token allowed submit")
+	}
+	reader.ExpiresAt = &past
+	if err := Check(reader, ScopeRead, now); !errors.Is(err, ErrExpired) {
+		t.Fatalf("Check(expired) = %v, want ErrExpired", err)
+	}
+}
+
+func TestLoadFileAndLookup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	raw := `[{"name": "ci", "token": "s3cret", "scopes": ["READ", "submit"], "expires_at": "2030-01-01T00:00:00Z"}]`
+	if err := os.WriteFile(path, []byte(raw), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() error: %v", err)
+	}
+	if err := Validate(tokens, ""); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	got, ok := Lookup(tokens, "s3cret")
+	if !ok || got.Name != "ci" || !got.Allows(ScopeRead) {
+		t.Fatalf("Lookup() = %+v, %v", got, ok)
+	}
+	if _, ok := Lookup(tokens, "nope"); ok {
+		t.Fatalf("Lookup() found an unknown secret")
+	}
+
+	ctx := WithToken(context.Background(), got)
+	if Name(ctx) != "ci" || Name(context.Background()) != "" {
+		t.Fatalf("Name() = %q", Name(ctx))
+	}
+	if red := Redacted(tokens, "x"); red[0].Token != "x" || tokens[0].Token != "s3cret" {
+		t.Fatalf("Redacted() changed the input or kept the secret")
+	}
+}
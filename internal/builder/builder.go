@@ -32,3 +32,17 @@ type BuildResult struct {
 type Builder interface {
 	Build(ctx context.Context, job BuildJob) (BuildResult, error)
 }
+
+// ToolReporter is implemented by builders that name the tools whose output
+// ends up in the job logs, so the matching diagnostics parsers are used.
+// Builders without it are assumed to run Vivado.
+type ToolReporter interface {
+	Tools() []string
+}
+
+// OutputReporter is implemented by builders that write logs or outputs of
+// their own into the artifacts dir. Artifact exclude rules never drop
+// them, like console.log and the bitstream.
+type OutputReporter interface {
+	Outputs() []string
+}
@@ -0,0 +1,91 @@
+package builder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// DefaultContainerRuntime runs toolchain images when no runtime is set.
+const DefaultContainerRuntime = "docker"
+
+// ContainerRunner runs every command inside a pinned OCI image instead of
+// on the host, so the server needs only docker or podman installed. Each
+// mount is bind-mounted at the same path inside the container, which keeps
+// the absolute paths in generated scripts valid; the command's Dir is
+// mounted too.
+type ContainerRunner struct {
+	// Runner starts the container runtime; OSRunner when nil.
+	Runner  Runner
+	Runtime string
+	Image   string
+	Mounts  []string
+	// User is passed to --user so files written to the mounts belong to
+	// the server; empty keeps the image default.
+	User string
+
+	seq atomic.Int64
+}
+
+// NewContainerRunner runs commands in image with runtime (docker when
+// empty) as the current user.
+func NewContainerRunner(runtime, image string, mounts ...string) *ContainerRunner {
+	if strings.TrimSpace(runtime) == "" {
+		runtime = DefaultContainerRuntime
+	}
+	r := &ContainerRunner{Runner: OSRunner{}, Runtime: runtime, Image: image, Mounts: mounts}
+	if uid, gid := os.Getuid(), os.Getgid(); uid >= 0 && gid >= 0 {
+		r.User = fmt.Sprintf("%d:%d", uid, gid)
+	}
+	return r
+}
+
+func (r *ContainerRunner) Run(ctx context.Context, spec CommandSpec, stdout, stderr io.Writer) (int, error) {
+	if strings.TrimSpace(r.Image) == "" {
+		return -1, errors.New("container runner has no image")
+	}
+	runner := r.Runner
+	if runner == nil {
+		runner = OSRunner{}
+	}
+	name := fmt.Sprintf("spadeforge-%d-%d", os.Getpid(), r.seq.Add(1))
+	exitCode, err := runner.Run(ctx, r.containerSpec(name, spec), stdout, stderr)
+	if ctx.Err() != nil {
+		// Killing the runtime client does not stop the container itself.
+		rm := CommandSpec{Name: r.Runtime, Args: []string{"rm", "-f", name}}
+		if _, rmErr := runner.Run(context.Background(), rm, io.Discard, io.Discard); rmErr != nil {
+			blog.Warnf("[builder] remove container %s: %v", name, rmErr)
+		}
+	}
+	return exitCode, err
+}
+
+func (r *ContainerRunner) containerSpec(name string, spec CommandSpec) CommandSpec {
+	args := []string{"run", "--rm", "--name", name, "--network", "none"}
+	if r.User != "" {
+		args = append(args, "--user", r.User)
+	}
+	seen := map[string]bool{}
+	for _, dir := range append(append([]string{}, r.Mounts...), spec.Dir) {
+		if dir == "" {
+			continue
+		}
+		dir = filepath.Clean(dir)
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		args = append(args, "-v", dir+":"+dir)
+	}
+	if spec.Dir != "" {
+		args = append(args, "-w", filepath.Clean(spec.Dir))
+	}
+	args = append(args, r.Image, spec.Name)
+	args = append(args, spec.Args...)
+	return CommandSpec{Name: r.Runtime, Args: args}
+}
@@ -0,0 +1,67 @@
+package builder
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type specLog struct {
+	specs []CommandSpec
+	block bool
+}
+
+func (l *specLog) Run(ctx context.Context, spec CommandSpec, stdout, stderr io.Writer) (int, error) {
+	l.specs = append(l.specs, spec)
+	if l.block && len(l.specs) == 1 {
+		<-ctx.Done()
+		return -1, ctx.Err()
+	}
+	return 0, nil
+}
+
+func TestContainerRunner_WrapsCommandInImage(t *testing.T) {
+	log := &specLog{}
+	r := &ContainerRunner{Runner: log, Runtime: "podman", Image: "ghcr.io/example/oss-cad@sha256:abc", Mounts: []string{"/srv/work", "/srv/artifacts"}, User: "1000:1000"}
+
+	if _, err := r.Run(context.Background(), CommandSpec{Name: "yosys", Args: []string{"-q", "synth.ys"}, Dir: "/srv/work/job1"}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	got := log.specs[0]
+	if got.Name != "podman" {
+		t.Fatalf("runtime = %s, want podman", got.Name)
+	}
+	args := strings.Join(got.Args, " ")
+	for _, want := range []string{
+		"run --rm --name spadeforge-",
+		"--network none --user 1000:1000",
+		"-v /srv/work:/srv/work -v /srv/artifacts:/srv/artifacts -v /srv/work/job1:/srv/work/job1 -w /srv/work/job1",
+	} {
+		if !strings.Contains(args, want) {
+			t.Fatalf("expected %q in %q", want, args)
+		}
+	}
+	if tail := got.Args[len(got.Args)-4:]; !reflect.DeepEqual(tail, []string{"ghcr.io/example/oss-cad@sha256:abc", "yosys", "-q", "synth.ys"}) {
+		t.Fatalf("image and command = %q", tail)
+	}
+}
+
+func TestContainerRunner_RemovesContainerOnCancel(t *testing.T) {
+	log := &specLog{block: true}
+	r := &ContainerRunner{Runner: log, Runtime: "docker", Image: "img:1"}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := r.Run(ctx, CommandSpec{Name: "nextpnr-ice40"}, nil, nil); err == nil {
+		t.Fatal("expected the canceled run to fail")
+	}
+	if len(log.specs) != 2 {
+		t.Fatalf("expected run and rm, got %+v", log.specs)
+	}
+	name := log.specs[0].Args[3]
+	if rm := log.specs[1]; rm.Name != "docker" || !reflect.DeepEqual(rm.Args, []string{"rm", "-f", name}) {
+		t.Fatalf("cleanup = %+v, want docker rm -f %s", rm, name)
+	}
+}
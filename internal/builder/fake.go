@@ -2,7 +2,9 @@ package builder
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
 	"strings"
@@ -21,6 +23,35 @@ type FakeBuilder struct {
 	HeartbeatInterval time.Duration
 	ConsoleLog        string
 	VivadoLog         string
+	// WorkFiles are written relative to the job work dir to simulate
+	// intermediate tool outputs.
+	WorkFiles map[string]string
+	// TimingReport and UtilizationReport replace the placeholder
+	// timing.rpt and utilization.rpt contents when set.
+	TimingReport      string
+	UtilizationReport string
+	// DiagnosticTools is what Tools reports, so tests can feed other tools'
+	// output through ConsoleLog; empty means Vivado.
+	DiagnosticTools []string
+	// CrashRate is the chance, between 0 and 1, that a build dies partway
+	// through synth the way a segfaulting tool would: a truncated console
+	// log, no reports and exit code 139.
+	CrashRate float64
+}
+
+func (b *FakeBuilder) Tools() []string {
+	if len(b.DiagnosticTools) == 0 {
+		return []string{"vivado"}
+	}
+	return b.DiagnosticTools
+}
+
+// SetReports swaps the timing and utilization reports used by later builds.
+func (b *FakeBuilder) SetReports(timing, utilization string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.TimingReport = timing
+	b.UtilizationReport = utilization
 }
 
 func (b *FakeBuilder) Build(ctx context.Context, job BuildJob) (BuildResult, error) {
@@ -42,7 +73,15 @@ func (b *FakeBuilder) Build(ctx context.Context, job BuildJob) (BuildResult, err
 
 	b.mu.Lock()
 	b.Calls = append(b.Calls, job)
+	timingReport := b.TimingReport
+	utilizationReport := b.UtilizationReport
 	b.mu.Unlock()
+	if timingReport == "" {
+		timingReport = "timing fake\n"
+	}
+	if utilizationReport == "" {
+		utilizationReport = "util fake\n"
+	}
 
 	if b.BlockCh != nil {
 		report("synth", "fake synth step running")
@@ -64,6 +103,11 @@ func (b *FakeBuilder) Build(ctx context.Context, job BuildJob) (BuildResult, err
 		}
 	}
 unblocked:
+	if b.CrashRate > 0 && rand.Float64() < b.CrashRate {
+		report("synth", "fake synth step running")
+		_ = os.WriteFile(filepath.Join(job.ArtifactsDir, "console.log"), []byte("fake build\nStarting synth_design\n"), 0o644)
+		return BuildResult{ExitCode: 139, Message: "fake builder crashed during synth"}, errors.New("fake builder crashed: signal: segmentation fault")
+	}
 	report("route", "fake route step running")
 
 	failErr, shouldFail := shouldFailBuild(b.FailProjects, job.Manifest.Project)
@@ -84,6 +128,16 @@ unblocked:
 		}
 	}
 
+	for rel, content := range b.WorkFiles {
+		target := filepath.Join(job.WorkDir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return BuildResult{ExitCode: 1}, err
+		}
+		if err := os.WriteFile(target, []byte(content), 0o644); err != nil {
+			return BuildResult{ExitCode: 1}, err
+		}
+	}
+
 	if err := os.WriteFile(filepath.Join(job.ArtifactsDir, "console.log"), []byte(consoleLog), 0o644); err != nil {
 		return BuildResult{ExitCode: 1}, err
 	}
@@ -93,10 +147,10 @@ unblocked:
 	if err := os.WriteFile(filepath.Join(job.ArtifactsDir, "vivado.jou"), []byte("journal fake\n"), 0o644); err != nil {
 		return BuildResult{ExitCode: 1}, err
 	}
-	if err := os.WriteFile(filepath.Join(job.ArtifactsDir, "timing.rpt"), []byte("timing fake\n"), 0o644); err != nil {
+	if err := os.WriteFile(filepath.Join(job.ArtifactsDir, "timing.rpt"), []byte(timingReport), 0o644); err != nil {
 		return BuildResult{ExitCode: 1}, err
 	}
-	if err := os.WriteFile(filepath.Join(job.ArtifactsDir, "utilization.rpt"), []byte("util fake\n"), 0o644); err != nil {
+	if err := os.WriteFile(filepath.Join(job.ArtifactsDir, "utilization.rpt"), []byte(utilizationReport), 0o644); err != nil {
 		return BuildResult{ExitCode: 1}, err
 	}
 
@@ -0,0 +1,205 @@
+package builder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SessionFileSuffix ends every file written by RecordingRunner.
+const SessionFileSuffix = ".session.json"
+
+// Session is one recorded tool run: the command, everything it wrote to
+// stdout and stderr with timing, and how it exited.
+type Session struct {
+	Name       string         `json:"name"`
+	Args       []string       `json:"args,omitempty"`
+	RecordedAt time.Time      `json:"recorded_at"`
+	Chunks     []SessionChunk `json:"chunks"`
+	ExitCode   int            `json:"exit_code"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// SessionChunk is a single write by the tool. Offset is measured from the
+// start of the run.
+type SessionChunk struct {
+	OffsetMS int64  `json:"offset_ms"`
+	Stream   string `json:"stream"`
+	Data     string `json:"data"`
+}
+
+// LoadSession reads a session file written by RecordingRunner.
+func LoadSession(path string) (*Session, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Session
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, fmt.Errorf("decode session %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// Save writes the session as indented JSON.
+func (s *Session) Save(path string) error {
+	raw, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(raw, '\n'), 0o644)
+}
+
+// Output concatenates the recorded chunks of one stream ("stdout" or
+// "stderr"), or of both when stream is empty.
+func (s *Session) Output(stream string) string {
+	var b strings.Builder
+	for _, c := range s.Chunks {
+		if stream == "" || c.Stream == stream {
+			b.WriteString(c.Data)
+		}
+	}
+	return b.String()
+}
+
+// RecordingRunner passes commands to Runner unchanged and saves each run as
+// a Session in Dir, for later use with ReplayRunner.
+type RecordingRunner struct {
+	Runner Runner
+	Dir    string
+
+	seq atomic.Int64
+}
+
+func NewRecordingRunner(runner Runner, dir string) *RecordingRunner {
+	if runner == nil {
+		runner = OSRunner{}
+	}
+	return &RecordingRunner{Runner: runner, Dir: dir}
+}
+
+func (r *RecordingRunner) Run(ctx context.Context, spec CommandSpec, stdout, stderr io.Writer) (int, error) {
+	rec := &sessionRecorder{start: time.Now()}
+	session := &Session{Name: spec.Name, Args: spec.Args, RecordedAt: rec.start.UTC()}
+
+	exitCode, runErr := r.Runner.Run(ctx, spec, rec.writer("stdout", stdout), rec.writer("stderr", stderr))
+
+	session.Chunks = rec.chunks
+	session.ExitCode = exitCode
+	if runErr != nil {
+		session.Error = runErr.Error()
+	}
+	if err := os.MkdirAll(r.Dir, 0o755); err != nil {
+		blog.Warnf("[builder] record session: %v", err)
+		return exitCode, runErr
+	}
+	name := fmt.Sprintf("%s-%03d-%s%s", rec.start.UTC().Format("20060102T150405"), r.seq.Add(1), sessionToolName(spec), SessionFileSuffix)
+	if err := session.Save(filepath.Join(r.Dir, name)); err != nil {
+		blog.Warnf("[builder] record session: %v", err)
+	}
+	return exitCode, runErr
+}
+
+// sessionToolName names the recorded tool, looking through the cmd.exe /C
+// wrapper used on Windows.
+func sessionToolName(spec CommandSpec) string {
+	name := spec.Name
+	if strings.EqualFold(filepath.Base(name), "cmd.exe") && len(spec.Args) > 1 && strings.EqualFold(spec.Args[0], "/C") {
+		name = spec.Args[1]
+	}
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	if name == "" || name == "." {
+		return "tool"
+	}
+	return name
+}
+
+type sessionRecorder struct {
+	mu     sync.Mutex
+	start  time.Time
+	chunks []SessionChunk
+}
+
+func (r *sessionRecorder) writer(stream string, out io.Writer) io.Writer {
+	return writerFunc(func(p []byte) (int, error) {
+		r.mu.Lock()
+		r.chunks = append(r.chunks, SessionChunk{
+			OffsetMS: time.Since(r.start).Milliseconds(),
+			Stream:   stream,
+			Data:     string(p),
+		})
+		r.mu.Unlock()
+		if out == nil {
+			return len(p), nil
+		}
+		return out.Write(p)
+	})
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+// ReplayRunner feeds a recorded Session back instead of running a tool.
+// Speed scales the recorded timing: 1 replays in real time, 10 ten times
+// faster, and 0 writes every chunk immediately.
+type ReplayRunner struct {
+	Session *Session
+	Speed   float64
+
+	// Hook, when set, runs after the output has been replayed and before
+	// the recorded exit is returned. Tests use it to create the files the
+	// real tool would have left behind.
+	Hook func(spec CommandSpec) error
+}
+
+func (r *ReplayRunner) Run(ctx context.Context, spec CommandSpec, stdout, stderr io.Writer) (int, error) {
+	if r.Session == nil {
+		return -1, errors.New("replay runner has no session")
+	}
+	start := time.Now()
+	for _, c := range r.Session.Chunks {
+		if r.Speed > 0 {
+			due := time.Duration(float64(time.Duration(c.OffsetMS)*time.Millisecond) / r.Speed)
+			if wait := due - time.Since(start); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return -1, ctx.Err()
+				case <-timer.C:
+				}
+			}
+		}
+		if ctx.Err() != nil {
+			return -1, ctx.Err()
+		}
+		out := stdout
+		if c.Stream == "stderr" {
+			out = stderr
+		}
+		if out != nil {
+			if _, err := io.WriteString(out, c.Data); err != nil {
+				return -1, err
+			}
+		}
+	}
+	if r.Hook != nil {
+		if err := r.Hook(spec); err != nil {
+			return -1, err
+		}
+	}
+	if r.Session.Error != "" {
+		return r.Session.ExitCode, errors.New(r.Session.Error)
+	}
+	return r.Session.ExitCode, nil
+}
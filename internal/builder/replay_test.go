@@ -0,0 +1,124 @@
+package builder
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/diagnostics"
+)
+
+func TestReplayRunner_VivadoSyntaxErrorSession(t *testing.T) {
+	session, err := LoadSession(filepath.Join("testdata", "vivado_syntax_error.session.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vb := NewVivadoBuilder("vivado", &ReplayRunner{Session: session})
+	vb.OSName = "linux"
+	job := makeBuildJob(t)
+	var steps []string
+	job.Progress = func(u ProgressUpdate) {
+		if u.Step != "" {
+			steps = append(steps, u.Step)
+		}
+	}
+
+	res, err := vb.Build(context.Background(), job)
+	if err == nil || res.ExitCode != 1 || res.Message != "vivado invocation failed" {
+		t.Fatalf("Build() = %+v, %v; want recorded exit 1", res, err)
+	}
+	if strings.Join(steps, ",") != "launch,read_sources" {
+		t.Fatalf("progress steps = %v", steps)
+	}
+
+	console, err := os.ReadFile(filepath.Join(job.ArtifactsDir, "console.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(console) != session.Output("") {
+		t.Fatalf("console.log differs from the recorded output")
+	}
+	report := diagnostics.BuildReport(map[string][]byte{"console.log": console})
+	if report.ErrorCount != 4 {
+		t.Fatalf("ErrorCount = %d, want 4", report.ErrorCount)
+	}
+}
+
+func TestRecordingRunner_RoundTripsThroughReplay(t *testing.T) {
+	dir := t.TempDir()
+	inner := &scriptedRunner{writes: []scriptedWrite{
+		{stream: "stdout", data: "SPADEFORGE_STEP:synth\n"},
+		{stream: "stderr", data: "WARNING: [Synth 8-7129] Port clk in module top is either unconnected or has no load\n"},
+		{stream: "stdout", data: "SPADEFORGE_STEP:route\n"},
+	}}
+	rec := NewRecordingRunner(inner, dir)
+
+	var stdout, stderr bytes.Buffer
+	spec := CommandSpec{Name: "cmd.exe", Args: []string{"/C", `C:\Xilinx\bin\vivado.bat`, "-mode", "batch"}}
+	if code, err := rec.Run(context.Background(), spec, &stdout, &stderr); code != 0 || err != nil {
+		t.Fatalf("Run() = %d, %v", code, err)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, "*-vivado"+SessionFileSuffix))
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("recorded sessions = %v, %v", matches, err)
+	}
+	session, err := LoadSession(matches[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(session.Chunks) != 3 || session.Chunks[1].Stream != "stderr" {
+		t.Fatalf("unexpected chunks: %+v", session.Chunks)
+	}
+
+	var replayOut, replayErr bytes.Buffer
+	if _, err := (&ReplayRunner{Session: session}).Run(context.Background(), spec, &replayOut, &replayErr); err != nil {
+		t.Fatal(err)
+	}
+	if replayOut.String() != stdout.String() || replayErr.String() != stderr.String() {
+		t.Fatalf("replay differs: stdout=%q stderr=%q", replayOut.String(), replayErr.String())
+	}
+}
+
+func TestReplayRunner_HonoursTimingAndCancellation(t *testing.T) {
+	session := &Session{Chunks: []SessionChunk{
+		{OffsetMS: 0, Stream: "stdout", Data: "first\n"},
+		{OffsetMS: 60_000, Stream: "stdout", Data: "second\n"},
+	}}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var out bytes.Buffer
+	_, err := (&ReplayRunner{Session: session, Speed: 1}).Run(ctx, CommandSpec{}, &out, io.Discard)
+	if err == nil || out.String() != "first\n" {
+		t.Fatalf("Run() err=%v out=%q; want cancellation after the first chunk", err, out.String())
+	}
+}
+
+type scriptedWrite struct {
+	stream string
+	data   string
+}
+
+type scriptedRunner struct {
+	writes []scriptedWrite
+}
+
+func (r *scriptedRunner) Run(_ context.Context, _ CommandSpec, stdout, stderr io.Writer) (int, error) {
+	for _, w := range r.writes {
+		out := stdout
+		if w.stream == "stderr" {
+			out = stderr
+		}
+		if _, err := io.WriteString(out, w.data); err != nil {
+			return -1, err
+		}
+	}
+	return 0, nil
+}
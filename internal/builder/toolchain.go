@@ -0,0 +1,37 @@
+package builder
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mblsha/spadeforge/internal/manifest"
+)
+
+// ToolchainBuilder hands each job to the builder for its manifest's
+// toolchain, or to the Default toolchain's builder when the manifest names
+// none.
+type ToolchainBuilder struct {
+	Default  string
+	Builders map[string]Builder
+}
+
+// For returns the builder that runs jobs with this manifest.
+func (b *ToolchainBuilder) For(mf manifest.Manifest) (Builder, error) {
+	name := mf.Toolchain
+	if name == "" {
+		name = b.Default
+	}
+	sel, ok := b.Builders[name]
+	if !ok {
+		return nil, fmt.Errorf("toolchain %q is not available on this server", name)
+	}
+	return sel, nil
+}
+
+func (b *ToolchainBuilder) Build(ctx context.Context, job BuildJob) (BuildResult, error) {
+	sel, err := b.For(job.Manifest)
+	if err != nil {
+		return BuildResult{ExitCode: 1, Message: "unsupported toolchain"}, err
+	}
+	return sel.Build(ctx, job)
+}
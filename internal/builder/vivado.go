@@ -13,6 +13,10 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/mblsha/spadeforge/internal/logtime"
+	"github.com/mblsha/spadeforge/internal/logx"
+	"github.com/mblsha/spadeforge/internal/manifest"
 )
 
 type CommandSpec struct {
@@ -21,6 +25,30 @@ type CommandSpec struct {
 	Dir  string
 }
 
+// String renders the command line for a POSIX shell or cmd.exe, quoting
+// arguments that contain spaces or shell metacharacters.
+func (s CommandSpec) String() string {
+	parts := make([]string, 0, len(s.Args)+1)
+	for _, arg := range append([]string{s.Name}, s.Args...) {
+		parts = append(parts, shellQuote(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+func shellQuote(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+	if !strings.ContainsAny(arg, " \t\n'\"$`&|;<>()*?[]{}!#~") {
+		return arg
+	}
+	// Backslashes mean a Windows path, which cmd.exe wants double-quoted.
+	if strings.HasPrefix(arg, `/`) || !strings.Contains(arg, `\`) {
+		return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return `"` + arg + `"`
+}
+
 type Runner interface {
 	Run(ctx context.Context, spec CommandSpec, stdout, stderr io.Writer) (int, error)
 }
@@ -41,7 +69,10 @@ func (OSRunner) Run(ctx context.Context, spec CommandSpec, stdout, stderr io.Wri
 				fmt.Sprint(cmd.Process.Pid)).Run()
 		}
 	}
+	blog.Debugf("[builder] run %s %s dir=%s", spec.Name, strings.Join(spec.Args, " "), spec.Dir)
+	start := time.Now()
 	err := cmd.Run()
+	blog.Debugf("[builder] %s exited after %s: %v", filepath.Base(spec.Name), time.Since(start).Round(time.Millisecond), err)
 	if err == nil {
 		return 0, nil
 	}
@@ -55,6 +86,8 @@ func (OSRunner) Run(ctx context.Context, spec CommandSpec, stdout, stderr io.Wri
 	return -1, err
 }
 
+var blog = logx.For(logx.ModuleBuilder)
+
 type VivadoBuilder struct {
 	VivadoBin         string
 	Runner            Runner
@@ -74,6 +107,9 @@ func NewVivadoBuilder(vivadoBin string, runner Runner) *VivadoBuilder {
 	}
 }
 
+// Tools reports Vivado as the only tool writing to the job logs.
+func (b *VivadoBuilder) Tools() []string { return []string{"vivado"} }
+
 func (b *VivadoBuilder) Build(ctx context.Context, job BuildJob) (BuildResult, error) {
 	report := func(step, message string) {
 		if job.Progress != nil {
@@ -98,38 +134,19 @@ func (b *VivadoBuilder) Build(ctx context.Context, job BuildJob) (BuildResult, e
 		return BuildResult{ExitCode: 1}, fmt.Errorf("write build.tcl: %w", err)
 	}
 
-	consolePath := filepath.Join(job.ArtifactsDir, "console.log")
-	consoleFile, err := os.OpenFile(consolePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	consoleFile, closeConsole, err := createConsoleLog(job.ArtifactsDir)
 	if err != nil {
-		return BuildResult{ExitCode: 1}, fmt.Errorf("create console log: %w", err)
+		return BuildResult{ExitCode: 1}, err
 	}
-	defer consoleFile.Close()
+	defer closeConsole()
 
 	report("launch", "starting vivado")
 	progressWriter := newStepProgressWriter(consoleFile, report)
-	heartbeatDone := make(chan struct{})
-	heartbeatInterval := b.HeartbeatInterval
-	if heartbeatInterval <= 0 {
-		heartbeatInterval = 30 * time.Second
-	}
-	go func() {
-		ticker := time.NewTicker(heartbeatInterval)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-heartbeatDone:
-				return
-			case <-ctx.Done():
-				return
-			case <-ticker.C:
-				report("", "")
-			}
-		}
-	}()
+	stopHeartbeat := startHeartbeat(ctx, b.HeartbeatInterval, report)
 
-	spec := buildVivadoCommand(b.OSName, b.VivadoBin, tclPath, job.WorkDir)
+	spec := BuildCommand(b.OSName, b.VivadoBin, tclPath, job.WorkDir)
 	exitCode, runErr := b.Runner.Run(ctx, spec, progressWriter, progressWriter)
-	close(heartbeatDone)
+	stopHeartbeat()
 	progressWriter.Flush()
 
 	copyIfExists(filepath.Join(job.WorkDir, "vivado.log"), filepath.Join(job.ArtifactsDir, "vivado.log"))
@@ -154,11 +171,65 @@ func (b *VivadoBuilder) Build(ctx context.Context, job BuildJob) (BuildResult, e
 	return BuildResult{ExitCode: exitCode, Message: "vivado build succeeded"}, nil
 }
 
+// startHeartbeat reports an empty progress update every interval (30s
+// when unset) until the returned stop function is called or ctx ends.
+func startHeartbeat(ctx context.Context, interval time.Duration, report func(step, message string)) (stop func()) {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				report("", "")
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// readSourceCommand reads src in its manifest language, into its library
+// when one is set. Include dirs only apply to Verilog.
+func readSourceCommand(job BuildJob, src, includeArg string) string {
+	file := tclBrace(filepath.ToSlash(filepath.Join(job.SourceDir, filepath.FromSlash(src))))
+	libArg := ""
+	if lib := job.Manifest.SourceOptions[src].Library; lib != "" {
+		libArg = " -library " + tclWord(lib)
+	}
+	switch job.Manifest.SourceLanguage(src) {
+	case manifest.LanguageVHDL:
+		return fmt.Sprintf("read_vhdl%s %s", libArg, file)
+	case manifest.LanguageVHDL2008:
+		return fmt.Sprintf("read_vhdl -vhdl2008%s %s", libArg, file)
+	case manifest.LanguageVerilog:
+		return fmt.Sprintf("read_verilog%s%s %s", libArg, includeArg, file)
+	default:
+		return fmt.Sprintf("read_verilog -sv%s%s %s", libArg, includeArg, file)
+	}
+}
+
+// GenerateTCL renders the batch script Vivado runs for job. The server
+// and `spadeforge-cli repro` share it so a local rerun sees the same script.
+// Manifests asking for project mode get generateProjectTCL's script.
 func GenerateTCL(job BuildJob) string {
+	if job.Manifest.Build.Mode == manifest.BuildModeProject {
+		return generateProjectTCL(job)
+	}
 	lines := []string{
 		"set_msg_config -id {Common 17-55} -suppress",
 		`puts "SPADEFORGE_STEP:read_sources"`,
 	}
+	if len(job.Manifest.IP) > 0 || len(job.Manifest.BlockDesigns) > 0 {
+		// IP and block designs need a project to generate into.
+		lines = append(lines, fmt.Sprintf("create_project -in_memory -part %s", tclWord(job.Manifest.Part)))
+	}
 	includeArg := ""
 	if len(job.Manifest.IncludeDirs) > 0 {
 		absIncludeDirs := make([]string, 0, len(job.Manifest.IncludeDirs))
@@ -168,14 +239,15 @@ func GenerateTCL(job BuildJob) string {
 		includeArg = " -include_dirs " + tclBrace(strings.Join(absIncludeDirs, " "))
 	}
 	for _, src := range job.Manifest.Sources {
-		lines = append(lines, fmt.Sprintf("read_verilog -sv%s %s", includeArg, tclBrace(filepath.ToSlash(filepath.Join(job.SourceDir, filepath.FromSlash(src))))))
+		lines = append(lines, readSourceCommand(job, src, includeArg))
 	}
 	for _, xdc := range job.Manifest.Constraints {
 		lines = append(lines, fmt.Sprintf("read_xdc %s", tclBrace(filepath.ToSlash(filepath.Join(job.SourceDir, filepath.FromSlash(xdc))))))
 	}
+	lines = append(lines, ipCommands(job)...)
 	lines = append(lines,
 		`puts "SPADEFORGE_STEP:synth"`,
-		fmt.Sprintf("synth_design -top %s -part %s", tclWord(job.Manifest.Top), tclWord(job.Manifest.Part)),
+		fmt.Sprintf("synth_design -top %s -part %s%s", tclWord(job.Manifest.Top), tclWord(job.Manifest.Part), synthDefineArgs(job.Manifest)),
 		`puts "SPADEFORGE_STEP:opt"`,
 		"opt_design",
 		`puts "SPADEFORGE_STEP:place"`,
@@ -192,8 +264,67 @@ func GenerateTCL(job BuildJob) string {
 	return strings.Join(lines, "\n") + "\n"
 }
 
-func buildVivadoCommand(osName, vivadoBin, tclPath, workDir string) CommandSpec {
-	args := []string{"-mode", "batch", "-source", tclPath}
+// ipCommands reads the manifest's IP cores and block designs and generates
+// their output products. IP is upgraded to the running Vivado version and
+// synthesized with the design rather than out of context, and each block
+// design gets an HDL wrapper the top can instantiate.
+func ipCommands(job BuildJob) []string {
+	if len(job.Manifest.IP) == 0 && len(job.Manifest.BlockDesigns) == 0 {
+		return nil
+	}
+	file := func(rel string) string {
+		return tclBrace(filepath.ToSlash(filepath.Join(job.SourceDir, filepath.FromSlash(rel))))
+	}
+	lines := []string{`puts "SPADEFORGE_STEP:ip"`}
+	for _, ip := range job.Manifest.IP {
+		lines = append(lines, "read_ip "+file(ip))
+	}
+	if len(job.Manifest.IP) > 0 {
+		lines = append(lines, "upgrade_ip [get_ips]")
+	}
+	for _, ip := range job.Manifest.IP {
+		lines = append(lines,
+			fmt.Sprintf("set_property generate_synth_checkpoint false [get_files %s]", file(ip)),
+			fmt.Sprintf("generate_target all [get_files %s]", file(ip)),
+		)
+	}
+	for _, bd := range job.Manifest.BlockDesigns {
+		if strings.EqualFold(filepath.Ext(bd), ".tcl") {
+			lines = append(lines, "source "+file(bd), "set bd [get_files [current_bd_design].bd]")
+		} else {
+			lines = append(lines, "read_bd "+file(bd), fmt.Sprintf("set bd [get_files %s]", file(bd)))
+		}
+		lines = append(lines,
+			"set_property synth_checkpoint_mode None $bd",
+			"generate_target all $bd",
+			"add_files -norecurse [make_wrapper -files $bd -top]",
+		)
+	}
+	return lines
+}
+
+// synthDefineArgs renders the manifest's defines and top parameters as
+// synth_design arguments.
+func synthDefineArgs(m manifest.Manifest) string {
+	var b strings.Builder
+	for _, name := range manifest.SortedKeys(m.Defines) {
+		if value := m.Defines[name]; value != "" {
+			name += "=" + value
+		}
+		b.WriteString(" -verilog_define " + tclBrace(name))
+	}
+	for _, name := range manifest.SortedKeys(m.TopParams) {
+		b.WriteString(" -generic " + tclBrace(name+"="+m.TopParams[name]))
+	}
+	return b.String()
+}
+
+// BuildCommand is the Vivado invocation that runs tclPath in batch mode.
+func BuildCommand(osName, vivadoBin, tclPath, workDir string) CommandSpec {
+	return vivadoCommand(osName, vivadoBin, workDir, "-mode", "batch", "-source", tclPath)
+}
+
+func vivadoCommand(osName, vivadoBin, workDir string, args ...string) CommandSpec {
 	if strings.EqualFold(osName, "windows") {
 		return CommandSpec{
 			Name: "cmd.exe",
@@ -204,6 +335,51 @@ func buildVivadoCommand(osName, vivadoBin, tclPath, workDir string) CommandSpec
 	return CommandSpec{Name: vivadoBin, Args: args, Dir: workDir}
 }
 
+// Version runs `vivado -version` and returns the first non-empty line of
+// its output, e.g. "Vivado v2023.2 (64-bit)".
+func (b *VivadoBuilder) Version(ctx context.Context) (string, error) {
+	var out bytes.Buffer
+	spec := vivadoCommand(b.OSName, b.VivadoBin, "", "-version")
+	if _, err := b.Runner.Run(ctx, spec, &out, &out); err != nil {
+		if msg := strings.TrimSpace(out.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, firstLine(msg))
+		}
+		return "", err
+	}
+	if line := firstLine(out.String()); line != "" {
+		return line, nil
+	}
+	return "", errors.New("vivado -version printed nothing")
+}
+
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if t := strings.TrimSpace(line); t != "" {
+			return t
+		}
+	}
+	return ""
+}
+
+// createConsoleLog creates console.log in dir along with its logtime
+// index. The returned writer stamps each line; closeFn closes both files.
+func createConsoleLog(dir string) (w io.Writer, closeFn func(), err error) {
+	consolePath := filepath.Join(dir, "console.log")
+	consoleFile, err := os.OpenFile(consolePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create console log: %w", err)
+	}
+	indexFile, err := os.OpenFile(logtime.IndexPath(consolePath), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		consoleFile.Close()
+		return nil, nil, fmt.Errorf("create console log index: %w", err)
+	}
+	return logtime.NewWriter(consoleFile, indexFile), func() {
+		_ = consoleFile.Close()
+		_ = indexFile.Close()
+	}, nil
+}
+
 func copyIfExists(src, dst string) {
 	rf, err := os.Open(src)
 	if err != nil {
@@ -276,6 +452,11 @@ func (w *stepProgressWriter) handleLine(line string) {
 
 func parseStepLine(line string) (string, bool) {
 	const marker = "SPADEFORGE_STEP:"
+	// Vivado echoes each sourced Tcl command as "# <command>"; only the
+	// puts output itself marks a step.
+	if strings.HasPrefix(strings.TrimSpace(line), "#") {
+		return "", false
+	}
 	idx := strings.Index(line, marker)
 	if idx < 0 {
 		return "", false
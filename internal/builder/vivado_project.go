@@ -0,0 +1,137 @@
+package builder
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/mblsha/spadeforge/internal/manifest"
+)
+
+// projectFileTypes maps manifest languages to Vivado file_type values.
+var projectFileTypes = map[string]string{
+	manifest.LanguageSystemVerilog: "SystemVerilog",
+	manifest.LanguageVerilog:       "Verilog",
+	manifest.LanguageVHDL:          "VHDL",
+	manifest.LanguageVHDL2008:      "VHDL 2008",
+}
+
+// waitOnRunProc waits for a run, echoes its log into the console so steps'
+// diagnostics reach the job log, keeps a copy as <run>.log among the
+// artifacts, and fails the script when the run did.
+const waitOnRunProc = `proc spadeforge_wait_on_run {run artifacts} {
+    wait_on_run $run
+    set status [get_property STATUS [get_runs $run]]
+    set log [file join [get_property DIRECTORY [get_runs $run]] runme.log]
+    if {[file exists $log]} {
+        set f [open $log]
+        puts [read $f]
+        close $f
+        file copy -force $log [file join $artifacts $run.log]
+    }
+    puts "$run: $status"
+    if {[string match -nocase "*error*" $status] || [string match -nocase "*cancel*" $status]} {
+        error "$run failed: $status"
+    }
+}`
+
+// generateProjectTCL renders the project-mode script: the sources go into
+// an on-disk project under the work dir, and synth_1 and impl_1 run
+// through launch_runs. impl_1 is advanced one step at a time so the usual
+// step markers still frame opt, place, route and bitstream.
+func generateProjectTCL(job BuildJob) string {
+	m := job.Manifest
+	file := func(rel string) string {
+		return tclBrace(filepath.ToSlash(filepath.Join(job.SourceDir, filepath.FromSlash(rel))))
+	}
+	artifact := func(name string) string {
+		return tclBrace(filepath.ToSlash(filepath.Join(job.ArtifactsDir, name)))
+	}
+	artifactsDir := tclBrace(filepath.ToSlash(job.ArtifactsDir))
+
+	lines := []string{
+		"set_msg_config -id {Common 17-55} -suppress",
+		`puts "SPADEFORGE_STEP:read_sources"`,
+		fmt.Sprintf("create_project -force -part %s spadeforge project", tclWord(m.Part)),
+	}
+	if m.Build.BoardPart != "" {
+		lines = append(lines, fmt.Sprintf("set_property board_part %s [current_project]", tclWord(m.Build.BoardPart)))
+	}
+	for _, src := range m.Sources {
+		lines = append(lines,
+			"add_files -norecurse "+file(src),
+			fmt.Sprintf("set_property file_type %s [get_files %s]", tclBrace(projectFileTypes[m.SourceLanguage(src)]), file(src)),
+		)
+		if lib := m.SourceOptions[src].Library; lib != "" {
+			lines = append(lines, fmt.Sprintf("set_property library %s [get_files %s]", tclWord(lib), file(src)))
+		}
+	}
+	if len(m.IncludeDirs) > 0 {
+		dirs := make([]string, 0, len(m.IncludeDirs))
+		for _, dir := range m.IncludeDirs {
+			dirs = append(dirs, filepath.ToSlash(filepath.Join(job.SourceDir, filepath.FromSlash(dir))))
+		}
+		lines = append(lines, fmt.Sprintf("set_property include_dirs %s [current_fileset]", tclBrace(strings.Join(dirs, " "))))
+	}
+	if len(m.Defines) > 0 {
+		defines := make([]string, 0, len(m.Defines))
+		for _, name := range manifest.SortedKeys(m.Defines) {
+			if value := m.Defines[name]; value != "" {
+				name += "=" + value
+			}
+			defines = append(defines, name)
+		}
+		lines = append(lines, fmt.Sprintf("set_property verilog_define %s [current_fileset]", tclBrace(strings.Join(defines, " "))))
+	}
+	if len(m.TopParams) > 0 {
+		generics := make([]string, 0, len(m.TopParams))
+		for _, name := range manifest.SortedKeys(m.TopParams) {
+			generics = append(generics, name+"="+m.TopParams[name])
+		}
+		lines = append(lines, fmt.Sprintf("set_property generic %s [current_fileset]", tclBrace(strings.Join(generics, " "))))
+	}
+	for _, xdc := range m.Constraints {
+		lines = append(lines, "add_files -fileset constrs_1 -norecurse "+file(xdc))
+	}
+	// The runs generate IP and block design output products themselves.
+	for _, ip := range m.IP {
+		lines = append(lines, "add_files -norecurse "+file(ip))
+	}
+	if len(m.IP) > 0 {
+		lines = append(lines, "upgrade_ip [get_ips]")
+	}
+	for _, bd := range m.BlockDesigns {
+		if strings.EqualFold(filepath.Ext(bd), ".tcl") {
+			lines = append(lines, "source "+file(bd), "set bd [get_files [current_bd_design].bd]")
+		} else {
+			lines = append(lines, "add_files -norecurse "+file(bd), fmt.Sprintf("set bd [get_files %s]", file(bd)))
+		}
+		lines = append(lines, "add_files -norecurse [make_wrapper -files $bd -top]")
+	}
+	lines = append(lines,
+		fmt.Sprintf("set_property top %s [current_fileset]", tclWord(m.Top)),
+		waitOnRunProc,
+		`puts "SPADEFORGE_STEP:synth"`,
+		"launch_runs synth_1",
+		"spadeforge_wait_on_run synth_1 "+artifactsDir,
+	)
+	for _, step := range []string{"opt", "place", "route"} {
+		lines = append(lines,
+			fmt.Sprintf(`puts "SPADEFORGE_STEP:%s"`, step),
+			fmt.Sprintf("launch_runs impl_1 -to_step %s_design", step),
+			"spadeforge_wait_on_run impl_1 "+artifactsDir,
+		)
+	}
+	lines = append(lines,
+		`puts "SPADEFORGE_STEP:reports"`,
+		"open_run impl_1",
+		"report_timing_summary -file "+artifact("timing.rpt"),
+		"report_utilization -file "+artifact("utilization.rpt"),
+		`puts "SPADEFORGE_STEP:bitstream"`,
+		"launch_runs impl_1 -to_step write_bitstream",
+		"spadeforge_wait_on_run impl_1 "+artifactsDir,
+		fmt.Sprintf("file copy -force [file join [get_property DIRECTORY [get_runs impl_1]] %s] %s", tclWord(m.Top+".bit"), artifact("design.bit")),
+		"exit",
+	)
+	return strings.Join(lines, "\n") + "\n"
+}
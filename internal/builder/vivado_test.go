@@ -45,6 +45,138 @@ func TestTclGeneration_ContainsExpectedReadCommands(t *testing.T) {
 	}
 }
 
+func TestTclGeneration_PassesDefinesAndTopParams(t *testing.T) {
+	job := BuildJob{
+		SourceDir:    "/tmp/src",
+		ArtifactsDir: "/tmp/artifacts",
+		Manifest: manifest.Manifest{
+			Top:       "top",
+			Part:      "xc7a35tcsg324-1",
+			Sources:   []string{"hdl/top.sv"},
+			Defines:   map[string]string{"SIM": "", "CLK_HZ": "100000000"},
+			TopParams: map[string]string{"WIDTH": "8", "INIT": "8'hFF"},
+		},
+	}
+	tcl := GenerateTCL(job)
+	want := "synth_design -top top -part xc7a35tcsg324-1 -verilog_define {CLK_HZ=100000000} -verilog_define {SIM} -generic {INIT=8'hFF} -generic {WIDTH=8}\n"
+	if !strings.Contains(tcl, want) {
+		t.Fatalf("expected %q in tcl:\n%s", want, tcl)
+	}
+
+	script := GenerateYosysScript(job, LatticePart{Family: "ice40"})
+	for _, want := range []string{"read_verilog -sv -DCLK_HZ=100000000 -DSIM /tmp/src/hdl/top.sv\n", "chparam -set INIT 8'hFF top\nchparam -set WIDTH 8 top\nsynth_ice40"} {
+		if !strings.Contains(script, want) {
+			t.Fatalf("expected %q in yosys script:\n%s", want, script)
+		}
+	}
+}
+
+func TestTclGeneration_ReadsMixedLanguageSources(t *testing.T) {
+	job := BuildJob{
+		SourceDir:    "/tmp/src",
+		ArtifactsDir: "/tmp/artifacts",
+		Manifest: manifest.Manifest{
+			Top:         "top",
+			Part:        "xc7a35tcsg324-1",
+			Sources:     []string{"hdl/top.sv", "hdl/legacy.v", "hdl/uart.vhd", "hdl/fifo.vhdl"},
+			IncludeDirs: []string{"inc"},
+			SourceOptions: map[string]manifest.SourceOption{
+				"hdl/legacy.v":  {Language: manifest.LanguageVerilog},
+				"hdl/uart.vhd":  {Library: "uart_lib"},
+				"hdl/fifo.vhdl": {Language: manifest.LanguageVHDL2008, Library: "fifo_lib"},
+			},
+		},
+	}
+	tcl := GenerateTCL(job)
+	for _, want := range []string{
+		"read_verilog -sv -include_dirs {/tmp/src/inc} {/tmp/src/hdl/top.sv}\n",
+		"read_verilog -include_dirs {/tmp/src/inc} {/tmp/src/hdl/legacy.v}\n",
+		"read_vhdl -library uart_lib {/tmp/src/hdl/uart.vhd}\n",
+		"read_vhdl -vhdl2008 -library fifo_lib {/tmp/src/hdl/fifo.vhdl}\n",
+	} {
+		if !strings.Contains(tcl, want) {
+			t.Fatalf("expected %q in tcl:\n%s", want, tcl)
+		}
+	}
+}
+
+func TestTclGeneration_GeneratesIPAndBlockDesigns(t *testing.T) {
+	job := BuildJob{
+		SourceDir:    "/tmp/src",
+		ArtifactsDir: "/tmp/artifacts",
+		Manifest: manifest.Manifest{
+			Top:          "top",
+			Part:         "xc7a35tcsg324-1",
+			Sources:      []string{"hdl/top.sv"},
+			IP:           []string{"ip/clk_wiz_0/clk_wiz_0.xci"},
+			BlockDesigns: []string{"bd/system/system.bd", "bd/mb.tcl"},
+		},
+	}
+	tcl := GenerateTCL(job)
+	for _, want := range []string{
+		"SPADEFORGE_STEP:read_sources\"\ncreate_project -in_memory -part xc7a35tcsg324-1\n",
+		"read_ip {/tmp/src/ip/clk_wiz_0/clk_wiz_0.xci}\nupgrade_ip [get_ips]\n" +
+			"set_property generate_synth_checkpoint false [get_files {/tmp/src/ip/clk_wiz_0/clk_wiz_0.xci}]\n" +
+			"generate_target all [get_files {/tmp/src/ip/clk_wiz_0/clk_wiz_0.xci}]\n",
+		"read_bd {/tmp/src/bd/system/system.bd}\nset bd [get_files {/tmp/src/bd/system/system.bd}]\n" +
+			"set_property synth_checkpoint_mode None $bd\ngenerate_target all $bd\nadd_files -norecurse [make_wrapper -files $bd -top]\n",
+		"source {/tmp/src/bd/mb.tcl}\nset bd [get_files [current_bd_design].bd]\n",
+	} {
+		if !strings.Contains(tcl, want) {
+			t.Fatalf("expected %q in tcl:\n%s", want, tcl)
+		}
+	}
+	if strings.Index(tcl, "make_wrapper") > strings.Index(tcl, "synth_design") {
+		t.Fatalf("block designs must be generated before synthesis:\n%s", tcl)
+	}
+
+	job.Manifest.IP, job.Manifest.BlockDesigns = nil, nil
+	if tcl := GenerateTCL(job); strings.Contains(tcl, "create_project") || strings.Contains(tcl, "SPADEFORGE_STEP:ip") {
+		t.Fatalf("plain designs should stay in non-project mode:\n%s", tcl)
+	}
+}
+
+func TestTclGeneration_ProjectModeUsesRuns(t *testing.T) {
+	job := BuildJob{
+		SourceDir:    "/tmp/src",
+		ArtifactsDir: "/tmp/artifacts",
+		Manifest: manifest.Manifest{
+			Top:           "top",
+			Part:          "xc7a35tcsg324-1",
+			Sources:       []string{"hdl/top.sv", "hdl/fifo.vhd"},
+			SourceOptions: map[string]manifest.SourceOption{"hdl/fifo.vhd": {Language: manifest.LanguageVHDL2008, Library: "fifo_lib"}},
+			Constraints:   []string{"constraints/top.xdc"},
+			Defines:       map[string]string{"SIM": "", "CLK_HZ": "100"},
+			TopParams:     map[string]string{"WIDTH": "8"},
+			IP:            []string{"ip/clk/clk.xci"},
+			Build:         manifest.Build{Mode: manifest.BuildModeProject, BoardPart: "digilentinc.com:arty-a7-35:part0:1.1"},
+		},
+	}
+	tcl := GenerateTCL(job)
+	for _, want := range []string{
+		"create_project -force -part xc7a35tcsg324-1 spadeforge project\nset_property board_part digilentinc.com:arty-a7-35:part0:1.1 [current_project]\n",
+		"add_files -norecurse {/tmp/src/hdl/top.sv}\nset_property file_type {SystemVerilog} [get_files {/tmp/src/hdl/top.sv}]\n",
+		"set_property file_type {VHDL 2008} [get_files {/tmp/src/hdl/fifo.vhd}]\nset_property library fifo_lib [get_files {/tmp/src/hdl/fifo.vhd}]\n",
+		"set_property verilog_define {CLK_HZ=100 SIM} [current_fileset]\nset_property generic {WIDTH=8} [current_fileset]\n",
+		"add_files -fileset constrs_1 -norecurse {/tmp/src/constraints/top.xdc}\n",
+		"add_files -norecurse {/tmp/src/ip/clk/clk.xci}\nupgrade_ip [get_ips]\n",
+		"set_property top top [current_fileset]\n",
+		"puts \"SPADEFORGE_STEP:synth\"\nlaunch_runs synth_1\nspadeforge_wait_on_run synth_1 {/tmp/artifacts}\n",
+		"puts \"SPADEFORGE_STEP:place\"\nlaunch_runs impl_1 -to_step place_design\n",
+		"puts \"SPADEFORGE_STEP:bitstream\"\nlaunch_runs impl_1 -to_step write_bitstream\n",
+		"file copy -force [file join [get_property DIRECTORY [get_runs impl_1]] top.bit] {/tmp/artifacts/design.bit}\n",
+	} {
+		if !strings.Contains(tcl, want) {
+			t.Fatalf("expected %q in tcl:\n%s", want, tcl)
+		}
+	}
+	for _, unwanted := range []string{"read_verilog", "synth_design", "write_bitstream -force"} {
+		if strings.Contains(tcl, unwanted) {
+			t.Fatalf("project mode should not run %q:\n%s", unwanted, tcl)
+		}
+	}
+}
+
 func TestParseStepLine(t *testing.T) {
 	step, ok := parseStepLine("INFO: SPADEFORGE_STEP:route")
 	if !ok || step != "route" {
@@ -54,10 +186,13 @@ func TestParseStepLine(t *testing.T) {
 	if ok {
 		t.Fatalf("did not expect marker parse")
 	}
+	if _, ok := parseStepLine(`# puts "SPADEFORGE_STEP:route"`); ok {
+		t.Fatalf("did not expect the echoed Tcl command to mark a step")
+	}
 }
 
 func TestVivadoCommand_WrapsBatWithCmdExe(t *testing.T) {
-	spec := buildVivadoCommand("windows", `C:\Xilinx\Vivado\bin\vivado.bat`, `C:\work\build.tcl`, `C:\work`)
+	spec := BuildCommand("windows", `C:\Xilinx\Vivado\bin\vivado.bat`, `C:\work\build.tcl`, `C:\work`)
 	if spec.Name != "cmd.exe" {
 		t.Fatalf("expected cmd.exe, got %s", spec.Name)
 	}
@@ -113,7 +248,7 @@ func TestVivadoBuilder_CollectsLogsAndReports(t *testing.T) {
 	if err != nil {
 		t.Fatalf("build failed: %v", err)
 	}
-	for _, file := range []string{"console.log", "vivado.log", "vivado.jou", "timing.rpt", "utilization.rpt", "design.bit"} {
+	for _, file := range []string{"console.log", "console.log.times", "vivado.log", "vivado.jou", "timing.rpt", "utilization.rpt", "design.bit"} {
 		if _, err := os.Stat(filepath.Join(job.ArtifactsDir, file)); err != nil {
 			t.Fatalf("expected %s in artifacts: %v", file, err)
 		}
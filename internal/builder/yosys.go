@@ -0,0 +1,272 @@
+package builder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/manifest"
+)
+
+// LatticePart is an open-toolchain target parsed from a manifest part such
+// as "ice40-hx8k-ct256" or "ecp5-25k-CABGA381-6".
+type LatticePart struct {
+	// Family is "ice40" or "ecp5" and picks the synth_* pass, the nextpnr
+	// variant and the packer.
+	Family string
+	// Device is the nextpnr device flag without dashes, e.g. "hx8k" or
+	// "um5g-85k".
+	Device  string
+	Package string
+	// Speed is the ECP5 speed grade; empty uses the nextpnr default.
+	Speed string
+}
+
+var latticeDevices = map[string][]string{
+	"ice40": {"lp384", "lp1k", "lp4k", "lp8k", "hx1k", "hx4k", "hx8k", "up3k", "up5k", "u1k", "u2k", "u4k"},
+	"ecp5":  {"um5g-25k", "um5g-45k", "um5g-85k", "um-25k", "um-45k", "um-85k", "12k", "25k", "45k", "85k"},
+}
+
+// ParseLatticePart reads "<family>-<device>-<package>[-<speed>]". Family and
+// device are case-insensitive; the package is passed to nextpnr as given.
+// iCE40 parts take no speed grade.
+func ParseLatticePart(part string) (LatticePart, error) {
+	raw := strings.TrimSpace(part)
+	family, rest, _ := strings.Cut(raw, "-")
+	family = strings.ToLower(family)
+	devices, ok := latticeDevices[family]
+	if !ok {
+		return LatticePart{}, fmt.Errorf("part %q is not an open-toolchain part; expected ice40-<device>-<package> or ecp5-<device>-<package>[-<speed>]", part)
+	}
+	for _, device := range devices {
+		pkg, found := strings.CutPrefix(strings.ToLower(rest), device+"-")
+		if !found {
+			continue
+		}
+		// Keep the package's original case.
+		pkg = rest[len(rest)-len(pkg):]
+		p := LatticePart{Family: family, Device: device, Package: pkg}
+		if family == "ecp5" {
+			if pkgName, speed, ok := strings.Cut(pkg, "-"); ok {
+				p.Package, p.Speed = pkgName, speed
+			}
+		}
+		if p.Package == "" || strings.Contains(p.Package, "-") {
+			return LatticePart{}, fmt.Errorf("part %q has no valid package after device %s", part, device)
+		}
+		return p, nil
+	}
+	return LatticePart{}, fmt.Errorf("part %q names an unknown %s device; known: %s", part, family, strings.Join(devices, ", "))
+}
+
+// Bitstream is the artifact the packer writes: iCE40 images are raw .bin
+// files, ECP5 ones .bit files.
+func (p LatticePart) Bitstream() string {
+	if p.Family == "ice40" {
+		return "design.bin"
+	}
+	return "design.bit"
+}
+
+// YosysNextpnrBuilder builds Lattice iCE40 and ECP5 designs with yosys,
+// nextpnr-ice40/nextpnr-ecp5 and icepack/ecppack. BinDir, when set, holds
+// the tools; otherwise they are looked up on PATH (or inside the toolchain
+// image when Runner is a ContainerRunner).
+type YosysNextpnrBuilder struct {
+	BinDir            string
+	Runner            Runner
+	HeartbeatInterval time.Duration
+}
+
+func NewYosysNextpnrBuilder(binDir string, runner Runner) *YosysNextpnrBuilder {
+	if runner == nil {
+		runner = OSRunner{}
+	}
+	return &YosysNextpnrBuilder{
+		BinDir:            binDir,
+		Runner:            runner,
+		HeartbeatInterval: 30 * time.Second,
+	}
+}
+
+// Tools reports the tools whose messages end up in console.log.
+func (b *YosysNextpnrBuilder) Tools() []string { return []string{"yosys", "nextpnr"} }
+
+// Outputs reports the tool logs kept next to the bitstream; the artifact
+// manifest reads the yosys version from yosys.log.
+func (b *YosysNextpnrBuilder) Outputs() []string { return []string{"yosys.log", "nextpnr.log"} }
+
+type toolStep struct {
+	step    string
+	message string
+	spec    CommandSpec
+}
+
+func (b *YosysNextpnrBuilder) Build(ctx context.Context, job BuildJob) (BuildResult, error) {
+	report := func(step, message string) {
+		if job.Progress != nil {
+			job.Progress(ProgressUpdate{
+				Step:        step,
+				Message:     message,
+				HeartbeatAt: time.Now().UTC(),
+			})
+		}
+	}
+
+	part, err := ParseLatticePart(job.Manifest.Part)
+	if err != nil {
+		return BuildResult{ExitCode: 1, Message: "unsupported part"}, err
+	}
+	if err := os.MkdirAll(job.ArtifactsDir, 0o755); err != nil {
+		return BuildResult{ExitCode: 1}, fmt.Errorf("create artifacts directory: %w", err)
+	}
+	if err := os.MkdirAll(job.WorkDir, 0o755); err != nil {
+		return BuildResult{ExitCode: 1}, fmt.Errorf("create work directory: %w", err)
+	}
+	steps, err := b.steps(job, part)
+	if err != nil {
+		return BuildResult{ExitCode: 1, Message: "unsupported constraints"}, err
+	}
+	scriptPath := filepath.Join(job.WorkDir, "synth.ys")
+	if err := os.WriteFile(scriptPath, []byte(GenerateYosysScript(job, part)), 0o644); err != nil {
+		return BuildResult{ExitCode: 1}, fmt.Errorf("write synth.ys: %w", err)
+	}
+
+	consoleFile, closeConsole, err := createConsoleLog(job.ArtifactsDir)
+	if err != nil {
+		return BuildResult{ExitCode: 1}, err
+	}
+	defer closeConsole()
+
+	report("launch", "starting yosys")
+	stopHeartbeat := startHeartbeat(ctx, b.HeartbeatInterval, report)
+	defer stopHeartbeat()
+
+	for _, s := range steps {
+		report(s.step, s.message)
+		tool := filepath.Base(s.spec.Name)
+		exitCode, runErr := b.Runner.Run(ctx, s.spec, consoleFile, consoleFile)
+		if runErr != nil {
+			return BuildResult{ExitCode: exitCode, Message: tool + " invocation failed"}, runErr
+		}
+		if exitCode != 0 {
+			return BuildResult{ExitCode: exitCode, Message: tool + " exited non-zero"}, fmt.Errorf("%s exited %d", tool, exitCode)
+		}
+	}
+
+	fi, err := os.Stat(filepath.Join(job.ArtifactsDir, part.Bitstream()))
+	if err != nil {
+		return BuildResult{ExitCode: 0, Message: "missing bitstream"}, fmt.Errorf("missing bitstream: %w", err)
+	}
+	if fi.Size() == 0 {
+		return BuildResult{ExitCode: 0, Message: "empty bitstream"}, errors.New("bitstream is empty")
+	}
+	return BuildResult{ExitCode: 0, Message: "yosys/nextpnr build succeeded"}, nil
+}
+
+// steps lists the synth, place-and-route and pack commands for part. Pin
+// constraints must be .pcf files for iCE40 (at most one) and .lpf files for
+// ECP5.
+func (b *YosysNextpnrBuilder) steps(job BuildJob, part LatticePart) ([]toolStep, error) {
+	work := func(name string) string { return filepath.Join(job.WorkDir, name) }
+	for _, src := range job.Manifest.Sources {
+		if manifest.IsVHDL(job.Manifest.SourceLanguage(src)) {
+			return nil, fmt.Errorf("source %s is VHDL, which the yosys flow cannot read", src)
+		}
+	}
+	if len(job.Manifest.IP) > 0 || len(job.Manifest.BlockDesigns) > 0 {
+		return nil, fmt.Errorf("the yosys flow cannot build Xilinx IP or block designs")
+	}
+	if job.Manifest.Build.Mode == manifest.BuildModeProject {
+		return nil, fmt.Errorf("project mode needs the vivado toolchain")
+	}
+	constraintExt, constraintFlag := ".pcf", "--pcf"
+	if part.Family == "ecp5" {
+		constraintExt, constraintFlag = ".lpf", "--lpf"
+	}
+	var constraints []string
+	for _, c := range job.Manifest.Constraints {
+		if !strings.EqualFold(filepath.Ext(c), constraintExt) {
+			return nil, fmt.Errorf("constraint %s is not a %s file, which the %s flow needs", c, constraintExt, part.Family)
+		}
+		constraints = append(constraints, filepath.Join(job.SourceDir, filepath.FromSlash(c)))
+	}
+	if part.Family == "ice40" && len(constraints) > 1 {
+		return nil, fmt.Errorf("the ice40 flow takes one .pcf file, got %d", len(constraints))
+	}
+
+	pnrArgs := []string{"--" + part.Device, "--package", part.Package, "--json", work("design.json"), "--log", filepath.Join(job.ArtifactsDir, "nextpnr.log")}
+	if part.Speed != "" {
+		pnrArgs = append(pnrArgs, "--speed", part.Speed)
+	}
+	for _, c := range constraints {
+		pnrArgs = append(pnrArgs, constraintFlag, c)
+	}
+	if len(constraints) == 0 {
+		pnrArgs = append(pnrArgs, constraintFlag+"-allow-unconstrained")
+	}
+
+	bitstream := filepath.Join(job.ArtifactsDir, part.Bitstream())
+	var pnr, pack CommandSpec
+	if part.Family == "ice40" {
+		pnr = b.command(job.WorkDir, "nextpnr-ice40", append(pnrArgs, "--asc", work("design.asc"))...)
+		pack = b.command(job.WorkDir, "icepack", work("design.asc"), bitstream)
+	} else {
+		pnr = b.command(job.WorkDir, "nextpnr-ecp5", append(pnrArgs, "--textcfg", work("design.config"))...)
+		pack = b.command(job.WorkDir, "ecppack", work("design.config"), bitstream)
+	}
+	return []toolStep{
+		{step: "synth", message: "running yosys", spec: b.command(job.WorkDir, "yosys", "-l", filepath.Join(job.ArtifactsDir, "yosys.log"), "-s", work("synth.ys"))},
+		{step: "route", message: "running " + pnr.Name, spec: pnr},
+		{step: "bitstream", message: "running " + filepath.Base(pack.Name), spec: pack},
+	}, nil
+}
+
+func (b *YosysNextpnrBuilder) command(dir, tool string, args ...string) CommandSpec {
+	name := tool
+	if b.BinDir != "" {
+		name = filepath.Join(b.BinDir, tool)
+	}
+	return CommandSpec{Name: name, Args: args, Dir: dir}
+}
+
+// GenerateYosysScript renders the synthesis script for job: every source
+// read as SystemVerilog with the manifest's include dirs and defines, the
+// top parameters set, then the family's synth pass writing design.json to
+// the work dir.
+func GenerateYosysScript(job BuildJob, part LatticePart) string {
+	var includes strings.Builder
+	for _, dir := range job.Manifest.IncludeDirs {
+		includes.WriteString(" " + yosysQuote("-I"+filepath.ToSlash(filepath.Join(job.SourceDir, filepath.FromSlash(dir)))))
+	}
+	for _, name := range manifest.SortedKeys(job.Manifest.Defines) {
+		if value := job.Manifest.Defines[name]; value != "" {
+			name += "=" + value
+		}
+		includes.WriteString(" -D" + name)
+	}
+	lines := make([]string, 0, len(job.Manifest.Sources)+1)
+	for _, src := range job.Manifest.Sources {
+		flag := " -sv"
+		if job.Manifest.SourceLanguage(src) == manifest.LanguageVerilog {
+			flag = ""
+		}
+		lines = append(lines, fmt.Sprintf("read_verilog%s%s %s", flag, includes.String(), yosysQuote(filepath.ToSlash(filepath.Join(job.SourceDir, filepath.FromSlash(src))))))
+	}
+	for _, name := range manifest.SortedKeys(job.Manifest.TopParams) {
+		lines = append(lines, fmt.Sprintf("chparam -set %s %s %s", name, job.Manifest.TopParams[name], job.Manifest.Top))
+	}
+	lines = append(lines, fmt.Sprintf("synth_%s -top %s -json %s", part.Family, job.Manifest.Top, yosysQuote(filepath.ToSlash(filepath.Join(job.WorkDir, "design.json")))))
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func yosysQuote(v string) string {
+	if strings.ContainsAny(v, " \t\";") {
+		return `"` + strings.ReplaceAll(v, `"`, `\"`) + `"`
+	}
+	return v
+}
@@ -0,0 +1,161 @@
+package builder
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mblsha/spadeforge/internal/manifest"
+)
+
+func TestParseLatticePart(t *testing.T) {
+	cases := []struct {
+		part string
+		want LatticePart
+	}{
+		{"ice40-hx8k-ct256", LatticePart{Family: "ice40", Device: "hx8k", Package: "ct256"}},
+		{"iCE40-UP5K-sg48", LatticePart{Family: "ice40", Device: "up5k", Package: "sg48"}},
+		{"ecp5-25k-CABGA381-6", LatticePart{Family: "ecp5", Device: "25k", Package: "CABGA381", Speed: "6"}},
+		{"ecp5-um5g-85k-CABGA381", LatticePart{Family: "ecp5", Device: "um5g-85k", Package: "CABGA381"}},
+	}
+	for _, tc := range cases {
+		got, err := ParseLatticePart(tc.part)
+		if err != nil {
+			t.Fatalf("ParseLatticePart(%q): %v", tc.part, err)
+		}
+		if got != tc.want {
+			t.Fatalf("ParseLatticePart(%q) = %+v, want %+v", tc.part, got, tc.want)
+		}
+	}
+	for _, bad := range []string{"xc7a35tcsg324-1", "ice40-hx9k-ct256", "ice40-hx8k", "ice40-hx8k-ct256-6"} {
+		if _, err := ParseLatticePart(bad); err == nil {
+			t.Fatalf("ParseLatticePart(%q) should fail", bad)
+		}
+	}
+}
+
+type toolRunner struct {
+	specs []CommandSpec
+	hook  func(spec CommandSpec) error
+}
+
+func (r *toolRunner) Run(ctx context.Context, spec CommandSpec, stdout, stderr io.Writer) (int, error) {
+	r.specs = append(r.specs, spec)
+	_, _ = io.WriteString(stdout, filepath.Base(spec.Name)+" ran\n")
+	if r.hook != nil {
+		if err := r.hook(spec); err != nil {
+			return 1, err
+		}
+	}
+	return 0, nil
+}
+
+func TestYosysNextpnrBuilder_ICE40Flow(t *testing.T) {
+	job := makeBuildJob(t)
+	job.Manifest.Part = "ice40-hx8k-ct256"
+	job.Manifest.Constraints = []string{"pins.pcf"}
+	var steps []string
+	job.Progress = func(u ProgressUpdate) {
+		if u.Step != "" {
+			steps = append(steps, u.Step)
+		}
+	}
+	runner := &toolRunner{hook: func(spec CommandSpec) error {
+		if filepath.Base(spec.Name) == "icepack" {
+			return os.WriteFile(spec.Args[1], []byte("bin"), 0o644)
+		}
+		return nil
+	}}
+	b := NewYosysNextpnrBuilder("/opt/oss/bin", runner)
+
+	res, err := b.Build(context.Background(), job)
+	if err != nil {
+		t.Fatalf("build failed: %v (%+v)", err, res)
+	}
+	var tools []string
+	for _, spec := range runner.specs {
+		tools = append(tools, spec.Name)
+	}
+	if want := []string{"/opt/oss/bin/yosys", "/opt/oss/bin/nextpnr-ice40", "/opt/oss/bin/icepack"}; !reflect.DeepEqual(tools, want) {
+		t.Fatalf("tools = %v, want %v", tools, want)
+	}
+	pnr := strings.Join(runner.specs[1].Args, " ")
+	for _, want := range []string{"--hx8k --package ct256", "--pcf " + filepath.Join(job.SourceDir, "pins.pcf"), "--asc " + filepath.Join(job.WorkDir, "design.asc")} {
+		if !strings.Contains(pnr, want) {
+			t.Fatalf("expected %q in nextpnr args %q", want, pnr)
+		}
+	}
+	if !reflect.DeepEqual(steps, []string{"launch", "synth", "route", "bitstream"}) {
+		t.Fatalf("progress steps = %v", steps)
+	}
+	script, err := os.ReadFile(filepath.Join(job.WorkDir, "synth.ys"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(script), "synth_ice40 -top top -json "+filepath.ToSlash(filepath.Join(job.WorkDir, "design.json"))) {
+		t.Fatalf("unexpected synth.ys:\n%s", script)
+	}
+	if console, _ := os.ReadFile(filepath.Join(job.ArtifactsDir, "console.log")); !strings.Contains(string(console), "icepack ran") {
+		t.Fatalf("expected tool output in console.log, got %q", console)
+	}
+}
+
+func TestYosysNextpnrBuilder_ECP5FlowAndConstraintChecks(t *testing.T) {
+	job := makeBuildJob(t)
+	job.Manifest.Part = "ecp5-25k-CABGA381-6"
+	runner := &toolRunner{hook: func(spec CommandSpec) error {
+		if spec.Name == "ecppack" {
+			return os.WriteFile(spec.Args[1], []byte("bit"), 0o644)
+		}
+		return nil
+	}}
+	b := NewYosysNextpnrBuilder("", runner)
+	if _, err := b.Build(context.Background(), job); err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+	pnr := strings.Join(runner.specs[1].Args, " ")
+	if runner.specs[1].Name != "nextpnr-ecp5" || !strings.Contains(pnr, "--25k --package CABGA381") || !strings.Contains(pnr, "--speed 6") || !strings.Contains(pnr, "--lpf-allow-unconstrained") {
+		t.Fatalf("unexpected nextpnr command %s %s", runner.specs[1].Name, pnr)
+	}
+	if _, err := os.Stat(filepath.Join(job.ArtifactsDir, "design.bit")); err != nil {
+		t.Fatalf("expected design.bit: %v", err)
+	}
+
+	job.Manifest.Constraints = []string{"top.xdc"}
+	runner.specs = nil
+	if _, err := b.Build(context.Background(), job); err == nil || !strings.Contains(err.Error(), ".lpf") {
+		t.Fatalf("expected an .xdc constraint to be rejected, got %v", err)
+	}
+	if len(runner.specs) != 0 {
+		t.Fatalf("no tool should run with bad constraints, ran %+v", runner.specs)
+	}
+
+	job.Manifest.Constraints = nil
+	job.Manifest.Sources = append(job.Manifest.Sources, "uart.vhd")
+	if _, err := b.Build(context.Background(), job); err == nil || !strings.Contains(err.Error(), "VHDL") {
+		t.Fatalf("expected a VHDL source to be rejected, got %v", err)
+	}
+	if len(runner.specs) != 0 {
+		t.Fatalf("no tool should run with VHDL sources, ran %+v", runner.specs)
+	}
+}
+
+func TestToolchainBuilder_DispatchesByManifest(t *testing.T) {
+	vivado, yosys := &FakeBuilder{}, &FakeBuilder{}
+	tb := &ToolchainBuilder{Default: "vivado", Builders: map[string]Builder{"vivado": vivado, "yosys": yosys}}
+
+	if b, err := tb.For(manifest.Manifest{}); err != nil || b != vivado {
+		t.Fatalf("default toolchain = %v, %v", b, err)
+	}
+	if b, err := tb.For(manifest.Manifest{Toolchain: "yosys"}); err != nil || b != yosys {
+		t.Fatalf("yosys toolchain = %v, %v", b, err)
+	}
+	delete(tb.Builders, "yosys")
+	if _, err := tb.Build(context.Background(), BuildJob{Manifest: manifest.Manifest{Toolchain: "yosys"}}); err == nil {
+		t.Fatal("expected an error for a toolchain the server lacks")
+	}
+}
@@ -0,0 +1,86 @@
+// Package buildinfo holds the version metadata `spadeforge release` embeds
+// into every binary with -ldflags -X.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// Set at link time; see LDFlags.
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+// Info is what /v1/version reports.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	GoVersion string `json:"go_version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	// ReleaseURL is where the CLIs fetch releases for self-update, when
+	// the server is configured with one; "{version}" stands for the
+	// version to fetch.
+	ReleaseURL string `json:"release_url,omitempty"`
+}
+
+// Get returns the running binary's build info. A binary built without
+// release ldflags falls back to the VCS revision the go tool recorded.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+	if info.Commit == "" {
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			for _, s := range bi.Settings {
+				switch s.Key {
+				case "vcs.revision":
+					info.Commit = s.Value
+				case "vcs.time":
+					if info.Date == "" {
+						info.Date = s.Value
+					}
+				}
+			}
+		}
+	}
+	return info
+}
+
+// String is the one-line form printed by --version flags and logs.
+func (i Info) String() string {
+	parts := []string{i.Version}
+	if i.Commit != "" {
+		commit := i.Commit
+		if len(commit) > 12 {
+			commit = commit[:12]
+		}
+		parts = append(parts, commit)
+	}
+	if i.Date != "" {
+		parts = append(parts, i.Date)
+	}
+	return strings.Join(parts, " ") + " (" + i.GoVersion + " " + i.OS + "/" + i.Arch + ")"
+}
+
+// LDFlags returns the -ldflags value that stamps version, commit and date
+// into a binary, stripping debug info as release builds do.
+func LDFlags(version, commit, date string) string {
+	const pkg = "github.com/mblsha/spadeforge/internal/buildinfo"
+	return strings.Join([]string{
+		"-s", "-w",
+		"-X", pkg + ".Version=" + version,
+		"-X", pkg + ".Commit=" + commit,
+		"-X", pkg + ".Date=" + date,
+	}, " ")
+}
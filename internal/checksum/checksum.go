@@ -0,0 +1,36 @@
+package checksum
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+)
+
+// Copy streams src into dst while hashing it, so callers never need to hold
+// the whole payload in memory. It returns the hex SHA-256 digest and the
+// number of bytes copied.
+func Copy(dst io.Writer, src io.Reader) (string, int64, error) {
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(dst, h), src)
+	if err != nil {
+		return "", n, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// Reader returns the hex SHA-256 digest of everything read from r.
+func Reader(r io.Reader) (string, error) {
+	sum, _, err := Copy(io.Discard, r)
+	return sum, err
+}
+
+// File returns the hex SHA-256 digest of the file at path.
+func File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return Reader(f)
+}
@@ -0,0 +1,98 @@
+package checksum
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestFile_MatchesInMemoryDigest(t *testing.T) {
+	payload := bytes.Repeat([]byte("spadeforge"), 100_000)
+	path := filepath.Join(t.TempDir(), "design.dcp")
+	if err := os.WriteFile(path, payload, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := File(path)
+	if err != nil {
+		t.Fatalf("hash file: %v", err)
+	}
+	want := sha256.Sum256(payload)
+	if got != hex.EncodeToString(want[:]) {
+		t.Fatalf("digest mismatch: got %s", got)
+	}
+}
+
+func TestCopy_WritesAndHashes(t *testing.T) {
+	var out bytes.Buffer
+	sum, n, err := Copy(&out, strings.NewReader("bitstream"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len("bitstream")) || out.String() != "bitstream" {
+		t.Fatalf("unexpected copy result n=%d out=%q", n, out.String())
+	}
+	want := sha256.Sum256([]byte("bitstream"))
+	if sum != hex.EncodeToString(want[:]) {
+		t.Fatalf("digest mismatch: got %s", sum)
+	}
+}
+
+func TestFile_MissingFile(t *testing.T) {
+	if _, err := File(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Fatalf("expected error for missing file")
+	}
+}
+
+func TestFile_DoesNotBufferWholeFile(t *testing.T) {
+	const size = 32 << 20
+	path := filepath.Join(t.TempDir(), "large.bin")
+	if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	if _, err := File(path); err != nil {
+		t.Fatal(err)
+	}
+	runtime.ReadMemStats(&after)
+
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > size/4 {
+		t.Fatalf("hashing allocated %d bytes for a %d byte file", allocated, size)
+	}
+}
+
+// BenchmarkFile guards against regressing to whole-file reads: allocations
+// per op must stay constant regardless of file size.
+func BenchmarkFile(b *testing.B) {
+	path := filepath.Join(b.TempDir(), "large.bin")
+	f, err := os.Create(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	chunk := bytes.Repeat([]byte{0xA5}, 1<<20)
+	for i := 0; i < 64; i++ {
+		if _, err := f.Write(chunk); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(64 << 20)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := File(path); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -6,8 +6,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/mblsha/spadeforge/internal/manifest"
@@ -20,6 +23,43 @@ type BundleSpec struct {
 	Sources     []string
 	Constraints []string
 	IncludeDirs []string
+	// Headers are Verilog include files bundled next to the sources
+	// without being read as sources; hdl/ joins the include dirs so
+	// `include finds them.
+	Headers []string
+	// SourceOptions set the language and library of sources, keyed by
+	// their entry in Sources.
+	SourceOptions map[string]manifest.SourceOption
+	// IP lists .xci/.xcix files and BlockDesigns .bd files or block design
+	// .tcl scripts. The directory holding each .xci or .bd is bundled with
+	// it, since Vivado keeps the core's other files there.
+	IP           []string
+	BlockDesigns []string
+	// Defines are Verilog macros for every source; TopParams override
+	// parameters of the top module.
+	Defines   map[string]string
+	TopParams map[string]string
+	// Git, when set, is recorded in the manifest so the job is traceable
+	// to its source revision.
+	Git *manifest.GitInfo
+	// BitstreamName is an artifactname template for a renamed copy of
+	// design.bit made by the server.
+	BitstreamName string
+	// Toolchain picks the server builder ("vivado" or "yosys"); empty uses
+	// the server default.
+	Toolchain string
+	// BuildMode is the Vivado flow, "non_project" or "project"; BoardPart
+	// sets the project's board part in project mode.
+	BuildMode string
+	BoardPart string
+	// OnSuccess lists follow-ups the server runs once the build succeeds.
+	OnSuccess []manifest.Action
+	// Retry overrides the server's retry policy for transient failures.
+	Retry *manifest.RetryPolicy
+	// FS, when set, supplies Sources and Constraints as slash-separated
+	// paths inside it instead of the local filesystem, e.g. files a user
+	// picked in a browser.
+	FS fs.FS
 }
 
 func BuildBundle(spec BundleSpec) ([]byte, error) {
@@ -44,10 +84,11 @@ func BuildBundle(spec BundleSpec) ([]byte, error) {
 	manifestConstraints := make([]string, 0, len(spec.Constraints))
 
 	seen := map[string]struct{}{}
+	var sourceOptions map[string]manifest.SourceOption
 
 	for _, src := range spec.Sources {
 		rel := "hdl/" + filepath.Base(src)
-		if err := addFile(zw, rel, src); err != nil {
+		if err := addFile(zw, spec.FS, rel, src); err != nil {
 			_ = zw.Close()
 			return nil, err
 		}
@@ -57,11 +98,49 @@ func BuildBundle(spec BundleSpec) ([]byte, error) {
 		}
 		seen[rel] = struct{}{}
 		manifestSources = append(manifestSources, filepath.ToSlash(rel))
+		if opt, ok := spec.SourceOptions[src]; ok {
+			if sourceOptions == nil {
+				sourceOptions = map[string]manifest.SourceOption{}
+			}
+			sourceOptions[rel] = opt
+		}
+	}
+	if len(sourceOptions) != len(spec.SourceOptions) {
+		_ = zw.Close()
+		return nil, fmt.Errorf("source options name files that are not sources")
+	}
+
+	includeDirs := spec.IncludeDirs
+	for _, h := range spec.Headers {
+		rel := "hdl/" + filepath.Base(h)
+		if err := addFile(zw, spec.FS, rel, h); err != nil {
+			_ = zw.Close()
+			return nil, err
+		}
+		if _, dup := seen[rel]; dup {
+			_ = zw.Close()
+			return nil, fmt.Errorf("duplicate bundle path: %s", rel)
+		}
+		seen[rel] = struct{}{}
+	}
+	if len(spec.Headers) > 0 && !slices.Contains(includeDirs, "hdl") {
+		includeDirs = append(slices.Clone(includeDirs), "hdl")
+	}
+
+	manifestIP, err := addDesignFiles(zw, spec.FS, "ip", spec.IP, seen)
+	if err != nil {
+		_ = zw.Close()
+		return nil, err
+	}
+	manifestBlockDesigns, err := addDesignFiles(zw, spec.FS, "bd", spec.BlockDesigns, seen)
+	if err != nil {
+		_ = zw.Close()
+		return nil, err
 	}
 
 	for _, c := range spec.Constraints {
 		rel := "constraints/" + filepath.Base(c)
-		if err := addFile(zw, rel, c); err != nil {
+		if err := addFile(zw, spec.FS, rel, c); err != nil {
 			_ = zw.Close()
 			return nil, err
 		}
@@ -74,16 +153,28 @@ func BuildBundle(spec BundleSpec) ([]byte, error) {
 	}
 
 	mf := manifest.Manifest{
-		Schema:      1,
-		Project:     project,
-		Top:         spec.Top,
-		Part:        spec.Part,
-		Sources:     manifestSources,
-		Constraints: manifestConstraints,
-		IncludeDirs: spec.IncludeDirs,
+		Schema:        1,
+		Project:       project,
+		Top:           spec.Top,
+		Part:          spec.Part,
+		Sources:       manifestSources,
+		Constraints:   manifestConstraints,
+		IncludeDirs:   includeDirs,
+		Defines:       spec.Defines,
+		TopParams:     spec.TopParams,
+		SourceOptions: sourceOptions,
+		IP:            manifestIP,
+		BlockDesigns:  manifestBlockDesigns,
+		Git:           spec.Git,
+		Toolchain:     strings.TrimSpace(spec.Toolchain),
+		Artifacts:     manifest.ArtifactRules{BitstreamName: strings.TrimSpace(spec.BitstreamName)},
 		Build: manifest.Build{
-			Steps: []string{"synth", "impl", "bitstream"},
+			Steps:     []string{"synth", "impl", "bitstream"},
+			Mode:      strings.TrimSpace(spec.BuildMode),
+			BoardPart: strings.TrimSpace(spec.BoardPart),
 		},
+		OnSuccess: spec.OnSuccess,
+		Retry:     spec.Retry,
 	}
 	rawManifest, err := json.MarshalIndent(mf, "", "  ")
 	if err != nil {
@@ -106,8 +197,65 @@ func BuildBundle(spec BundleSpec) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-func addFile(zw *zip.Writer, archivePath, sourcePath string) error {
-	raw, err := os.ReadFile(sourcePath)
+// addDesignFiles bundles IP or block design files under prefix and returns
+// their bundle paths. A .tcl script is bundled alone; any other file brings
+// its whole directory along as prefix/<dir name>/.
+func addDesignFiles(zw *zip.Writer, fsys fs.FS, prefix string, files []string, seen map[string]struct{}) ([]string, error) {
+	var out []string
+	for _, f := range files {
+		if strings.EqualFold(filepath.Ext(f), ".tcl") {
+			rel := prefix + "/" + filepath.Base(f)
+			if _, dup := seen[rel]; dup {
+				return nil, fmt.Errorf("duplicate bundle path: %s", rel)
+			}
+			seen[rel] = struct{}{}
+			if err := addFile(zw, fsys, rel, f); err != nil {
+				return nil, err
+			}
+			out = append(out, rel)
+			continue
+		}
+		dir := filepath.Dir(f)
+		if fsys != nil {
+			dir = path.Dir(f)
+		}
+		relDir := prefix + "/" + filepath.Base(dir)
+		if _, dup := seen[relDir]; dup {
+			return nil, fmt.Errorf("duplicate bundle path: %s", relDir)
+		}
+		seen[relDir] = struct{}{}
+		walk := func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(filepath.FromSlash(dir), filepath.FromSlash(p))
+			if err != nil {
+				return err
+			}
+			return addFile(zw, fsys, relDir+"/"+filepath.ToSlash(rel), p)
+		}
+		var err error
+		if fsys != nil {
+			err = fs.WalkDir(fsys, dir, walk)
+		} else {
+			err = filepath.WalkDir(dir, walk)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("bundle %s: %w", dir, err)
+		}
+		out = append(out, relDir+"/"+filepath.Base(f))
+	}
+	return out, nil
+}
+
+func addFile(zw *zip.Writer, fsys fs.FS, archivePath, sourcePath string) error {
+	var raw []byte
+	var err error
+	if fsys != nil {
+		raw, err = fs.ReadFile(fsys, sourcePath)
+	} else {
+		raw, err = os.ReadFile(sourcePath)
+	}
 	if err != nil {
 		return fmt.Errorf("read %s: %w", sourcePath, err)
 	}
@@ -1,10 +1,10 @@
 package client
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -13,9 +13,18 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/mblsha/spadeforge/internal/buildinfo"
+	"github.com/mblsha/spadeforge/internal/httpretry"
+	"github.com/mblsha/spadeforge/internal/httptransport"
 	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/logx"
+	"github.com/mblsha/spadeforge/internal/manifest"
+	"github.com/mblsha/spadeforge/internal/pollwait"
+	"github.com/mblsha/spadeforge/internal/sse"
+	"github.com/mblsha/spadeforge/internal/websocket"
 )
 
 const defaultAuthHeader = "X-Build-Token"
@@ -25,58 +34,133 @@ type HTTPClient struct {
 	Token      string
 	AuthHeader string
 	Client     *http.Client
+
+	// StreamIdleTimeout bounds how long StreamEvents waits without receiving
+	// any data, keepalives included, before reconnecting. Zero uses
+	// sse.DefaultIdleTimeout.
+	StreamIdleTimeout time.Duration
+
+	// Retry governs retries of idempotent GETs and download resumption. The
+	// zero value uses httpretry.Default.
+	Retry httpretry.Policy
+
+	// Submitter identifies this user to the server's fair queue; empty
+	// lets the server use the client IP.
+	Submitter string
+	// NoCache asks the server to build even when its build cache holds a
+	// job with the same inputs.
+	NoCache bool
+	// ChunkSize is the chunk size of SubmitResumable uploads; zero uses
+	// 4 MiB.
+	ChunkSize int64
+
+	// CAFile and InsecureSkipVerify configure TLS when Client is nil. Proxy
+	// settings are always taken from HTTPS_PROXY/HTTP_PROXY/NO_PROXY.
+	CAFile             string
+	InsecureSkipVerify bool
+	// CertFile and KeyFile are a client certificate and key for servers
+	// that accept one in place of the token.
+	CertFile string
+	KeyFile  string
+	// LimitRate caps uploads and downloads, together, to this many bytes
+	// per second when Client is nil. Zero is unlimited.
+	LimitRate int64
+
+	transportOnce sync.Once
+	transport     *http.Client
 }
 
-func (c *HTTPClient) SubmitBundle(ctx context.Context, bundle []byte) (string, error) {
+// Submit uploads a bundle and returns the server's structured response,
+// including its normalized view of the manifest.
+func (c *HTTPClient) Submit(ctx context.Context, bundle []byte) (*job.SubmitResponse, error) {
 	var body bytes.Buffer
 	mw := multipart.NewWriter(&body)
 	fw, err := mw.CreateFormFile("bundle", "bundle.zip")
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if _, err := fw.Write(bundle); err != nil {
-		return "", err
+		return nil, err
+	}
+	if submitter := strings.TrimSpace(c.Submitter); submitter != "" {
+		if err := mw.WriteField("submitter", submitter); err != nil {
+			return nil, err
+		}
+	}
+	if c.NoCache {
+		if err := mw.WriteField("no_cache", "1"); err != nil {
+			return nil, err
+		}
 	}
 	if err := mw.Close(); err != nil {
-		return "", err
+		return nil, err
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.buildURL("/v1/jobs"), &body)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	req.Header.Set("Content-Type", mw.FormDataContentType())
 	c.setAuth(req)
 
 	resp, err := c.httpClient().Do(req)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusAccepted {
 		raw, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("submit failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
-	}
-	var payload struct {
-		JobID string `json:"job_id"`
+		return nil, newSubmitError(resp.StatusCode, raw)
 	}
+	var payload job.SubmitResponse
 	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
-		return "", err
+		return nil, err
 	}
 	if payload.JobID == "" {
-		return "", fmt.Errorf("submit response missing job_id")
+		return nil, fmt.Errorf("submit response missing job_id")
 	}
-	return payload.JobID, nil
+	return &payload, nil
 }
 
-func (c *HTTPClient) GetJob(ctx context.Context, jobID string) (*job.Record, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.buildURL(path.Join("/v1/jobs", jobID)), nil)
+// SubmitError is returned when the server rejects a bundle. Details holds
+// per-field manifest problems when the server reported them.
+type SubmitError struct {
+	StatusCode int
+	Message    string
+	Details    []manifest.FieldError
+	body       string
+}
+
+func (e *SubmitError) Error() string {
+	return fmt.Sprintf("submit failed: status=%d body=%s", e.StatusCode, e.body)
+}
+
+func newSubmitError(status int, raw []byte) *SubmitError {
+	e := &SubmitError{StatusCode: status, body: strings.TrimSpace(string(raw))}
+	var payload struct {
+		Error   string                `json:"error"`
+		Details []manifest.FieldError `json:"details"`
+	}
+	if err := json.Unmarshal(raw, &payload); err == nil {
+		e.Message = payload.Error
+		e.Details = payload.Details
+	}
+	return e
+}
+
+// SubmitBundle uploads the bundle with SubmitResumable and returns the job
+// ID.
+func (c *HTTPClient) SubmitBundle(ctx context.Context, bundle []byte) (string, error) {
+	resp, err := c.SubmitResumable(ctx, bundle)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	c.setAuth(req)
-	resp, err := c.httpClient().Do(req)
+	return resp.JobID, nil
+}
+
+func (c *HTTPClient) GetJob(ctx context.Context, jobID string) (*job.Record, error) {
+	resp, err := c.get(ctx, c.buildURL(path.Join("/v1/jobs", jobID)))
 	if err != nil {
 		return nil, err
 	}
@@ -92,10 +176,81 @@ func (c *HTTPClient) GetJob(ctx context.Context, jobID string) (*job.Record, err
 	return &record, nil
 }
 
+// GetJobs fetches several job records with one POST /v1/jobs/status call.
+func (c *HTTPClient) GetJobs(ctx context.Context, jobIDs []string) (*job.StatusResponse, error) {
+	payload, err := json.Marshal(job.StatusRequest{JobIDs: jobIDs})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.buildURL("/v1/jobs/status"), bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.setAuth(req)
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("get jobs status failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	var out job.StatusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListJobsOptions filters and pages ListJobs. Zero values use the server
+// defaults.
+type ListJobsOptions struct {
+	States []job.State
+	Limit  int
+	Offset int
+}
+
+// ListJobs fetches one page of GET /v1/jobs, newest first.
+func (c *HTTPClient) ListJobs(ctx context.Context, opts ListJobsOptions) (*job.JobList, error) {
+	q := url.Values{}
+	for _, state := range opts.States {
+		q.Add("state", string(state))
+	}
+	if opts.Limit > 0 {
+		q.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Offset > 0 {
+		q.Set("offset", strconv.Itoa(opts.Offset))
+	}
+	reqURL := c.buildURL("/v1/jobs")
+	if len(q) > 0 {
+		reqURL += "?" + q.Encode()
+	}
+	resp, err := c.get(ctx, reqURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("list jobs failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	var out job.JobList
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
 func (c *HTTPClient) WaitForTerminal(ctx context.Context, jobID string, pollInterval time.Duration) (*job.Record, error) {
 	return c.WaitForTerminalWithProgress(ctx, jobID, pollInterval, nil)
 }
 
+// WaitForTerminalWithProgress polls the job until it finishes. pollInterval
+// is the fastest rate, used while the job is queued and right after a state
+// or step change; the interval backs off while the job stays in one step.
 func (c *HTTPClient) WaitForTerminalWithProgress(
 	ctx context.Context,
 	jobID string,
@@ -105,8 +260,7 @@ func (c *HTTPClient) WaitForTerminalWithProgress(
 	if pollInterval <= 0 {
 		pollInterval = 500 * time.Millisecond
 	}
-	ticker := time.NewTicker(pollInterval)
-	defer ticker.Stop()
+	backoff := pollwait.New(pollInterval)
 	for {
 		record, err := c.GetJob(ctx, jobID)
 		if err != nil {
@@ -118,31 +272,89 @@ func (c *HTTPClient) WaitForTerminalWithProgress(
 		if record.Terminal() {
 			return record, nil
 		}
+		wait := backoff.Next(string(record.State)+"/"+record.CurrentStep, record.State == job.StateQueued)
+		timer := time.NewTimer(wait)
 		select {
 		case <-ctx.Done():
+			timer.Stop()
 			return nil, ctx.Err()
-		case <-ticker.C:
+		case <-timer.C:
 		}
 	}
 }
 
 func (c *HTTPClient) DownloadArtifacts(ctx context.Context, jobID string, out io.Writer) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.buildURL(path.Join("/v1/jobs", jobID, "artifacts")), nil)
+	return c.download(ctx, c.buildURL(path.Join("/v1/jobs", jobID, "artifacts")), out, "download artifacts")
+}
+
+// DownloadBundle writes the job's original request zip to out.
+func (c *HTTPClient) DownloadBundle(ctx context.Context, jobID string, out io.Writer) error {
+	return c.download(ctx, c.buildURL(path.Join("/v1/jobs", jobID, "bundle")), out, "download bundle")
+}
+
+// DownloadFlashImage writes the job's bitstream converted to a "bin" or
+// "mcs" flash image to out. offset is the flash address an .mcs file starts
+// at.
+func (c *HTTPClient) DownloadFlashImage(ctx context.Context, jobID, format string, offset uint32, out io.Writer) error {
+	q := url.Values{}
+	if format != "" {
+		q.Set("format", format)
+	}
+	if offset != 0 {
+		q.Set("offset", fmt.Sprintf("0x%x", offset))
+	}
+	reqURL := c.buildURL(path.Join("/v1/jobs", jobID, "flash-image"))
+	if len(q) > 0 {
+		reqURL += "?" + q.Encode()
+	}
+	return c.download(ctx, reqURL, out, "download flash image")
+}
+
+func (c *HTTPClient) ListWorkDir(ctx context.Context, jobID string) ([]job.WorkDirEntry, error) {
+	resp, err := c.get(ctx, c.buildURL(path.Join("/v1/jobs", jobID, "workdir")))
 	if err != nil {
-		return err
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("list work dir failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	var payload struct {
+		Items []job.WorkDirEntry `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, err
+	}
+	return payload.Items, nil
+}
+
+func (c *HTTPClient) DownloadWorkDirFile(ctx context.Context, jobID, relPath string, out io.Writer) error {
+	return c.download(ctx, c.buildURL(path.Join("/v1/jobs", jobID, "workdir", relPath)), out, "download work dir file")
+}
+
+// CancelJob aborts a queued or running job. The returned record is
+// CANCELED for a queued job and still RUNNING while a build is stopped.
+func (c *HTTPClient) CancelJob(ctx context.Context, jobID string) (*job.Record, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.buildURL(path.Join("/v1/jobs", jobID, "cancel")), nil)
+	if err != nil {
+		return nil, err
 	}
 	c.setAuth(req)
 	resp, err := c.httpClient().Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
 		raw, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("download artifacts failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
+		return nil, fmt.Errorf("cancel failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
 	}
-	_, err = io.Copy(out, resp.Body)
-	return err
+	var rec job.Record
+	if err := json.NewDecoder(resp.Body).Decode(&rec); err != nil {
+		return nil, err
+	}
+	return &rec, nil
 }
 
 func (c *HTTPClient) KillJob(ctx context.Context, jobID string) error {
@@ -163,6 +375,175 @@ func (c *HTTPClient) KillJob(ctx context.Context, jobID string) error {
 	return nil
 }
 
+// SetBaseline marks a succeeded job as its project's baseline.
+func (c *HTTPClient) SetBaseline(ctx context.Context, jobID string) (*job.Record, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.buildURL(path.Join("/v1/jobs", jobID, "baseline")), nil)
+	if err != nil {
+		return nil, err
+	}
+	c.setAuth(req)
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("set baseline failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	var record job.Record
+	if err := json.NewDecoder(resp.Body).Decode(&record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// PinJob keeps a job from retention; with pinned false it unpins it.
+func (c *HTTPClient) PinJob(ctx context.Context, jobID string, pinned bool) (*job.Record, error) {
+	method := http.MethodPost
+	if !pinned {
+		method = http.MethodDelete
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.buildURL(path.Join("/v1/jobs", jobID, "pin")), nil)
+	if err != nil {
+		return nil, err
+	}
+	c.setAuth(req)
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("pin job failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	var record job.Record
+	if err := json.NewDecoder(resp.Body).Decode(&record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// GetRun returns the run jobID belongs to.
+func (c *HTTPClient) GetRun(ctx context.Context, jobID string) (*job.Run, error) {
+	resp, err := c.get(ctx, c.buildURL(path.Join("/v1/runs", jobID)))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("get run failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	var run job.Run
+	if err := json.NewDecoder(resp.Body).Decode(&run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// GetProjectBaseline returns the project's baseline job.
+func (c *HTTPClient) GetProjectBaseline(ctx context.Context, project string) (*job.Record, error) {
+	resp, err := c.get(ctx, c.buildURL(path.Join("/v1/projects", project, "baseline")))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("get baseline failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	var record job.Record
+	if err := json.NewDecoder(resp.Body).Decode(&record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// GetEnergyStats fetches per-project build energy totals.
+func (c *HTTPClient) GetEnergyStats(ctx context.Context) (*job.EnergyStats, error) {
+	resp, err := c.get(ctx, c.buildURL("/v1/stats/energy"))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("get energy stats failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	var stats job.EnergyStats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// GetVersion fetches the server's build info from /v1/version.
+func (c *HTTPClient) GetVersion(ctx context.Context) (*buildinfo.Info, error) {
+	resp, err := c.get(ctx, c.buildURL("/v1/version"))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("get version failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	var info buildinfo.Info
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// GetLogLevel fetches the server's active log filters.
+func (c *HTTPClient) GetLogLevel(ctx context.Context) (*logx.Settings, error) {
+	resp, err := c.get(ctx, c.buildURL("/v1/admin/loglevel"))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("get log level failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	var out logx.Settings
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetLogLevel updates the server's log filters and returns the result. An
+// empty Level keeps the global level; module entries are merged.
+func (c *HTTPClient) SetLogLevel(ctx context.Context, settings logx.Settings) (*logx.Settings, error) {
+	payload, err := json.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.buildURL("/v1/admin/loglevel"), bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.setAuth(req)
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("set log level failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	var out logx.Settings
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
 func (c *HTTPClient) KillAllVivado(ctx context.Context) (string, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.buildURL("/v1/kill-all-vivado"), nil)
 	if err != nil {
@@ -188,12 +569,7 @@ func (c *HTTPClient) KillAllVivado(ctx context.Context) (string, error) {
 }
 
 func (c *HTTPClient) GetDiagnostics(ctx context.Context, jobID string) (*job.DiagnosticsReport, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.buildURL(path.Join("/v1/jobs", jobID, "diagnostics")), nil)
-	if err != nil {
-		return nil, err
-	}
-	c.setAuth(req)
-	resp, err := c.httpClient().Do(req)
+	resp, err := c.get(ctx, c.buildURL(path.Join("/v1/jobs", jobID, "diagnostics")))
 	if err != nil {
 		return nil, err
 	}
@@ -209,6 +585,94 @@ func (c *HTTPClient) GetDiagnostics(ctx context.Context, jobID string) (*job.Dia
 	return &report, nil
 }
 
+// DownloadLog streams a job log ("console.log" or "vivado.log"; empty means
+// console.log) into out. With gz set the server compresses the log and out
+// receives a gzip file.
+func (c *HTTPClient) DownloadLog(ctx context.Context, jobID, name string, gz bool, out io.Writer) error {
+	return c.DownloadLogSince(ctx, jobID, name, gz, time.Time{}, out)
+}
+
+// DownloadLogSince is DownloadLog limited to the lines written at or after
+// since; a zero since downloads the whole log. Logs the server has no line
+// timestamps for are downloaded whole.
+func (c *HTTPClient) DownloadLogSince(ctx context.Context, jobID, name string, gz bool, since time.Time, out io.Writer) error {
+	parsed, err := url.Parse(c.buildURL(path.Join("/v1/jobs", jobID, "log")))
+	if err != nil {
+		return err
+	}
+	q := parsed.Query()
+	if name != "" {
+		q.Set("file", name)
+	}
+	if gz {
+		q.Set("format", "gz")
+	}
+	if !since.IsZero() {
+		q.Set("since", since.UTC().Format(time.RFC3339))
+	}
+	parsed.RawQuery = q.Encode()
+	return c.download(ctx, parsed.String(), out, "download log")
+}
+
+// GetProjectDiagnosticsSummary fetches recurring diagnostics across the
+// project's last limit builds; limit <= 0 uses the server default.
+func (c *HTTPClient) GetProjectDiagnosticsSummary(ctx context.Context, project string, limit int) (*job.ProjectDiagnosticsSummary, error) {
+	parsed, err := url.Parse(c.buildURL(path.Join("/v1/projects", project, "diagnostics", "summary")))
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 {
+		parsed.RawQuery = url.Values{"limit": {strconv.Itoa(limit)}}.Encode()
+	}
+	resp, err := c.get(ctx, parsed.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("get project diagnostics summary failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	var summary job.ProjectDiagnosticsSummary
+	if err := json.NewDecoder(resp.Body).Decode(&summary); err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// SearchLog greps a job log on the server, returning contextLines lines
+// around each match; a negative value uses the server default.
+func (c *HTTPClient) SearchLog(ctx context.Context, jobID, name, query string, contextLines int) (*job.LogSearchResult, error) {
+	parsed, err := url.Parse(c.buildURL(path.Join("/v1/jobs", jobID, "log", "search")))
+	if err != nil {
+		return nil, err
+	}
+	q := parsed.Query()
+	q.Set("q", query)
+	if name != "" {
+		q.Set("file", name)
+	}
+	if contextLines >= 0 {
+		q.Set("context", strconv.Itoa(contextLines))
+	}
+	parsed.RawQuery = q.Encode()
+
+	resp, err := c.get(ctx, parsed.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("search log failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	var result job.LogSearchResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
 func (c *HTTPClient) GetLogTail(ctx context.Context, jobID string, lines int) (string, error) {
 	if lines <= 0 {
 		lines = 200
@@ -222,12 +686,7 @@ func (c *HTTPClient) GetLogTail(ctx context.Context, jobID string, lines int) (s
 	q.Set("lines", strconv.Itoa(lines))
 	parsed.RawQuery = q.Encode()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, parsed.String(), nil)
-	if err != nil {
-		return "", err
-	}
-	c.setAuth(req)
-	resp, err := c.httpClient().Do(req)
+	resp, err := c.get(ctx, parsed.String())
 	if err != nil {
 		return "", err
 	}
@@ -242,25 +701,76 @@ func (c *HTTPClient) GetLogTail(ctx context.Context, jobID string, lines int) (s
 	return string(raw), nil
 }
 
+// StreamEvents follows the job's server-sent event stream until the server
+// closes it. A connection that goes silent for longer than
+// StreamIdleTimeout is treated as dead and re-established from the last
+// received event, honoring the server's retry hint.
+// A dropped_events notice triggers an immediate reconnect from the last
+// received event, so onEvent sees the events the server skipped.
 func (c *HTTPClient) StreamEvents(ctx context.Context, jobID string, since int64, onEvent func(*job.Event)) error {
-	reqURL := c.buildURL(path.Join("/v1/jobs", jobID, "events"))
-	parsed, err := url.Parse(reqURL)
+	dec := &sse.Decoder{IdleTimeout: c.streamIdleTimeout()}
+	return sse.Follow(ctx, since, eventSeq, onEvent, dec.RetryDelay, func(since int64, emit func(*job.Event)) error {
+		return c.streamEventsOnce(ctx, path.Join("/v1/jobs", jobID, "events"), nil, since, dec, emit)
+	})
+}
+
+// StreamAllEvents follows GET /v1/events, every job's events numbered by a
+// server-wide seq, until ctx is done. states, when set, limits the stream to
+// events in those states. since=0 starts with a snapshot event per queued or
+// running job. Stalls and dropped_events notices reconnect from the last
+// received event as in StreamEvents.
+func (c *HTTPClient) StreamAllEvents(ctx context.Context, since int64, states []job.State, onEvent func(*job.Event)) error {
+	query := url.Values{}
+	if len(states) > 0 {
+		names := make([]string, len(states))
+		for i, st := range states {
+			names[i] = string(st)
+		}
+		query.Set("state", strings.Join(names, ","))
+	}
+	dec := &sse.Decoder{IdleTimeout: c.streamIdleTimeout()}
+	return sse.Follow(ctx, since, eventSeq, onEvent, dec.RetryDelay, func(since int64, emit func(*job.Event)) error {
+		err := c.streamEventsOnce(ctx, "/v1/events", query, since, dec, emit)
+		if err == nil && ctx.Err() == nil {
+			// The server closed a stream that should stay open, e.g. on
+			// restart; resume like after a stall.
+			return sse.ErrStalled
+		}
+		return err
+	})
+}
+
+// StreamEventsWS is StreamEvents over the /events/ws WebSocket endpoint, for
+// networks whose proxies buffer or block SSE. The events, idle detection
+// (server pings count as data) and reconnect behavior are the same.
+func (c *HTTPClient) StreamEventsWS(ctx context.Context, jobID string, since int64, onEvent func(*job.Event)) error {
+	return sse.Follow(ctx, since, eventSeq, onEvent, func() time.Duration { return sse.DefaultRetry }, func(since int64, emit func(*job.Event)) error {
+		err := c.streamEventsWSOnce(ctx, jobID, since, emit)
+		if errors.Is(err, websocket.ErrIdle) {
+			return fmt.Errorf("%w: %w", sse.ErrStalled, err)
+		}
+		return err
+	})
+}
+
+// eventSeq is the resume point sse.Follow tracks.
+func eventSeq(ev *job.Event) int64 { return ev.Seq }
+
+func (c *HTTPClient) streamEventsOnce(ctx context.Context, eventsPath string, query url.Values, since int64, dec *sse.Decoder, onEvent func(*job.Event)) error {
+	parsed, err := url.Parse(c.buildURL(eventsPath))
 	if err != nil {
 		return err
 	}
+	q := parsed.Query()
+	for k, vs := range query {
+		q[k] = vs
+	}
 	if since > 0 {
-		q := parsed.Query()
 		q.Set("since", strconv.FormatInt(since, 10))
-		parsed.RawQuery = q.Encode()
-	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, parsed.String(), nil)
-	if err != nil {
-		return err
 	}
-	c.setAuth(req)
+	parsed.RawQuery = q.Encode()
 
-	resp, err := c.httpClient().Do(req)
+	resp, err := c.get(ctx, parsed.String())
 	if err != nil {
 		return err
 	}
@@ -270,45 +780,73 @@ func (c *HTTPClient) StreamEvents(ctx context.Context, jobID string, since int64
 		return fmt.Errorf("stream events failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
 	}
 
-	scanner := bufio.NewScanner(resp.Body)
-	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
-	dataLines := make([]string, 0, 4)
-	dispatch := func() error {
-		if len(dataLines) == 0 {
-			return nil
-		}
-		payload := strings.Join(dataLines, "\n")
-		dataLines = dataLines[:0]
+	return dec.Decode(resp.Body, func(data string) error {
 		var ev job.Event
-		if err := json.Unmarshal([]byte(payload), &ev); err != nil {
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
 			return fmt.Errorf("decode sse event: %w", err)
 		}
-		if onEvent != nil {
-			onEvent(&ev)
+		if ev.Type == job.EventDroppedEvents {
+			return sse.ErrResync
 		}
+		onEvent(&ev)
 		return nil
+	})
+}
+
+func (c *HTTPClient) streamEventsWSOnce(ctx context.Context, jobID string, since int64, onEvent func(*job.Event)) error {
+	reqURL := c.buildURL(path.Join("/v1/jobs", jobID, "events", "ws"))
+	parsed, err := url.Parse(reqURL)
+	if err != nil {
+		return err
+	}
+	if since > 0 {
+		q := parsed.Query()
+		q.Set("since", strconv.FormatInt(since, 10))
+		parsed.RawQuery = q.Encode()
 	}
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		if line == "" {
-			if err := dispatch(); err != nil {
-				return err
+	header := http.Header{}
+	c.setAuthHeader(header)
+	conn, err := websocket.Dial(ctx, c.httpClient(), parsed.String(), header)
+	if err != nil {
+		var hsErr *websocket.HandshakeError
+		if errors.As(err, &hsErr) {
+			return fmt.Errorf("stream events failed: status=%d body=%s", hsErr.StatusCode, hsErr.Body)
+		}
+		return err
+	}
+	defer conn.Close(websocket.CloseNormal, "")
+	conn.SetIdleTimeout(c.streamIdleTimeout())
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close(websocket.CloseNormal, "") })
+	defer stop()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if errors.Is(err, websocket.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
 			}
-			continue
+			return err
 		}
-		if strings.HasPrefix(line, ":") {
-			continue
+		var ev job.Event
+		if err := json.Unmarshal(data, &ev); err != nil {
+			return fmt.Errorf("decode websocket event: %w", err)
 		}
-		if strings.HasPrefix(line, "data:") {
-			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
-			dataLines = append(dataLines, data)
+		if ev.Type == job.EventDroppedEvents {
+			return sse.ErrResync
 		}
+		onEvent(&ev)
 	}
-	if err := scanner.Err(); err != nil {
-		return err
+}
+
+func (c *HTTPClient) streamIdleTimeout() time.Duration {
+	if c.StreamIdleTimeout > 0 {
+		return c.StreamIdleTimeout
 	}
-	return dispatch()
+	return sse.DefaultIdleTimeout
 }
 
 func (c *HTTPClient) buildURL(pathPart string) string {
@@ -324,19 +862,56 @@ func (c *HTTPClient) buildURL(pathPart string) string {
 	return u.String()
 }
 
+// get issues an idempotent GET, retrying transient failures per c.Retry.
+func (c *HTTPClient) get(ctx context.Context, reqURL string) (*http.Response, error) {
+	return c.Retry.Do(ctx, c.httpClient(), func() (*http.Request, error) {
+		return c.newGetRequest(ctx, reqURL)
+	})
+}
+
+// download streams a GET body into out, resuming interrupted transfers.
+func (c *HTTPClient) download(ctx context.Context, reqURL string, out io.Writer, op string) error {
+	return c.Retry.Download(ctx, c.httpClient(), func() (*http.Request, error) {
+		return c.newGetRequest(ctx, reqURL)
+	}, out, func(resp *http.Response) error {
+		raw, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s failed: status=%d body=%s", op, resp.StatusCode, strings.TrimSpace(string(raw)))
+	})
+}
+
+func (c *HTTPClient) newGetRequest(ctx context.Context, reqURL string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.setAuth(req)
+	return req, nil
+}
+
 func (c *HTTPClient) httpClient() *http.Client {
 	if c.Client != nil {
 		return c.Client
 	}
-	return http.DefaultClient
+	opts := httptransport.Options{CAFile: c.CAFile, InsecureSkipVerify: c.InsecureSkipVerify, CertFile: c.CertFile, KeyFile: c.KeyFile, LimitRate: c.LimitRate}
+	if !opts.Enabled() {
+		return http.DefaultClient
+	}
+	c.transportOnce.Do(func() {
+		c.transport = httptransport.ClientOrError(opts)
+	})
+	return c.transport
 }
 
 func (c *HTTPClient) setAuth(req *http.Request) {
+	c.setAuthHeader(req.Header)
+}
+
+func (c *HTTPClient) setAuthHeader(h http.Header) {
 	header := c.AuthHeader
 	if header == "" {
 		header = defaultAuthHeader
 	}
 	if strings.TrimSpace(c.Token) != "" {
-		req.Header.Set(header, c.Token)
+		h.Set(header, c.Token)
 	}
 }
@@ -4,15 +4,22 @@ import (
 	"archive/zip"
 	"bytes"
 	"context"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"testing/fstest"
+	"time"
 
+	"github.com/mblsha/spadeforge/internal/httpretry"
 	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/websocket"
 )
 
 func TestBundleBuilder_IncludesSpadeSVAndXDCAndManifest(t *testing.T) {
@@ -50,6 +57,59 @@ func TestBundleBuilder_IncludesSpadeSVAndXDCAndManifest(t *testing.T) {
 	}
 }
 
+func TestBundleBuilder_ReadsFromFS(t *testing.T) {
+	files := fstest.MapFS{
+		"picked/top.sv":   {Data: []byte("module top;endmodule\n")},
+		"picked/pins.xdc": {Data: []byte("set_property PACKAGE_PIN W5 [get_ports clk]\n")},
+	}
+	bundle, err := BuildBundle(BundleSpec{
+		Project:     "demo",
+		Top:         "top",
+		Part:        "xc7a35tcsg324-1",
+		Sources:     []string{"picked/top.sv"},
+		Constraints: []string{"picked/pins.xdc"},
+		FS:          files,
+	})
+	if err != nil {
+		t.Fatalf("build bundle failed: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(bundle), int64(len(bundle)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for _, f := range zr.File {
+		seen[f.Name] = true
+	}
+	if !seen["hdl/top.sv"] || !seen["constraints/pins.xdc"] {
+		t.Fatalf("unexpected bundle contents: %v", seen)
+	}
+
+	if _, err := BuildBundle(BundleSpec{Project: "demo", Top: "top", Part: "p", Sources: []string{"missing.sv"}, FS: files}); err == nil {
+		t.Fatal("expected an error for a file missing from FS")
+	}
+}
+
+// The browser submitter builds this package with GOOS=js GOARCH=wasm,
+// where os/exec is unavailable.
+func TestClientCore_BuildsForWasmWithoutExec(t *testing.T) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go toolchain not in PATH")
+	}
+	cmd := exec.Command(goBin, "list", "-deps", ".")
+	cmd.Env = append(os.Environ(), "GOOS=js", "GOARCH=wasm")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("go list for js/wasm: %v\n%s", err, out)
+	}
+	for _, dep := range strings.Fields(string(out)) {
+		if dep == "os/exec" {
+			t.Fatal("the client core must not import os/exec under js/wasm")
+		}
+	}
+}
+
 func TestBundleBuilder_RequiresProject(t *testing.T) {
 	tmp := t.TempDir()
 	src := filepath.Join(tmp, "spade.sv")
@@ -113,6 +173,11 @@ func TestClient_SubmitAndDownloadAgainstTestServer(t *testing.T) {
 		case r.Method == http.MethodGet && r.URL.Path == "/v1/jobs/j1/diagnostics":
 			w.Header().Set("Content-Type", "application/json")
 			_, _ = w.Write([]byte(`{"schema":1,"error_count":1,"warning_count":0,"info_count":0,"diagnostics":[{"severity":"ERROR","code":"Synth 8-2716","message":"syntax error","file":"hdl/spade.sv","line":1}]}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/jobs/j1/log":
+			if r.URL.Query().Get("file") != "vivado.log" || r.URL.Query().Get("format") != "gz" {
+				t.Fatalf("unexpected log query: %q", r.URL.RawQuery)
+			}
+			_, _ = w.Write([]byte("gzdata"))
 		case r.Method == http.MethodGet && r.URL.Path == "/v1/jobs/j1/tail":
 			if r.URL.Query().Get("lines") != "3" {
 				t.Fatalf("expected lines=3, got %q", r.URL.Query().Get("lines"))
@@ -181,6 +246,14 @@ func TestClient_SubmitAndDownloadAgainstTestServer(t *testing.T) {
 		t.Fatalf("unexpected tail: %q", tail)
 	}
 
+	var logBuf bytes.Buffer
+	if err := c.DownloadLog(context.Background(), "j1", "vivado.log", true, &logBuf); err != nil {
+		t.Fatal(err)
+	}
+	if logBuf.String() != "gzdata" {
+		t.Fatalf("unexpected log payload: %q", logBuf.String())
+	}
+
 	eventCount := 0
 	if err := c.StreamEvents(context.Background(), "j1", 0, func(ev *job.Event) {
 		eventCount++
@@ -191,3 +264,215 @@ func TestClient_SubmitAndDownloadAgainstTestServer(t *testing.T) {
 		t.Fatalf("expected 2 streamed events, got %d", eventCount)
 	}
 }
+
+func TestStreamEvents_ReconnectsAfterStallFromLastSeq(t *testing.T) {
+	var calls atomic.Int32
+	sinceSeen := make(chan string, 4)
+	release := make(chan struct{})
+	defer close(release)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sinceSeen <- r.URL.Query().Get("since")
+		w.Header().Set("Content-Type", "text/event-stream")
+		if calls.Add(1) == 1 {
+			_, _ = w.Write([]byte("retry: 10\n\ndata: {\"seq\":1,\"job_id\":\"j1\",\"state\":\"RUNNING\"}\n\n"))
+			w.(http.Flusher).Flush()
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		_, _ = w.Write([]byte("data: {\"seq\":2,\"job_id\":\"j1\",\"state\":\"SUCCEEDED\"}\n\n"))
+	}))
+	defer ts.Close()
+
+	c := &HTTPClient{BaseURL: ts.URL, StreamIdleTimeout: 50 * time.Millisecond}
+	var seqs []int64
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.StreamEvents(ctx, "j1", 0, func(ev *job.Event) {
+		seqs = append(seqs, ev.Seq)
+	}); err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	if len(seqs) != 2 || seqs[0] != 1 || seqs[1] != 2 {
+		t.Fatalf("unexpected events: %v", seqs)
+	}
+	if first, second := <-sinceSeen, <-sinceSeen; first != "" || second != "1" {
+		t.Fatalf("unexpected since values: %q %q", first, second)
+	}
+}
+
+func TestStreamAllEvents_FiltersByStateAndResumesAfterServerClose(t *testing.T) {
+	var calls atomic.Int32
+	queries := make(chan string, 4)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/events" {
+			http.NotFound(w, r)
+			return
+		}
+		queries <- r.URL.RawQuery
+		w.Header().Set("Content-Type", "text/event-stream")
+		if calls.Add(1) == 1 {
+			// A restarting server ends the stream early.
+			_, _ = w.Write([]byte("retry: 10\n\ndata: {\"seq\":7,\"job_id\":\"j1\",\"state\":\"FAILED\"}\n\n"))
+			return
+		}
+		_, _ = w.Write([]byte("data: {\"seq\":8,\"job_id\":\"j2\",\"state\":\"SUCCEEDED\"}\n\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	c := &HTTPClient{BaseURL: ts.URL}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var jobs []string
+	err := c.StreamAllEvents(ctx, 0, []job.State{job.StateFailed, job.StateSucceeded}, func(ev *job.Event) {
+		jobs = append(jobs, ev.JobID)
+		if len(jobs) == 2 {
+			cancel()
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the stream to run until canceled, got %v", err)
+	}
+	if len(jobs) != 2 || jobs[0] != "j1" || jobs[1] != "j2" {
+		t.Fatalf("unexpected events: %v", jobs)
+	}
+	if first, second := <-queries, <-queries; first != "state=FAILED%2CSUCCEEDED" || second != "since=7&state=FAILED%2CSUCCEEDED" {
+		t.Fatalf("unexpected queries: %q %q", first, second)
+	}
+}
+
+func TestStreamEvents_ResyncsAfterDroppedEventsNotice(t *testing.T) {
+	var calls atomic.Int32
+	sinceSeen := make(chan string, 4)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sinceSeen <- r.URL.Query().Get("since")
+		w.Header().Set("Content-Type", "text/event-stream")
+		if calls.Add(1) == 1 {
+			_, _ = w.Write([]byte("data: {\"seq\":1,\"job_id\":\"j1\",\"state\":\"RUNNING\"}\n\n"))
+			_, _ = w.Write([]byte("data: {\"seq\":1,\"job_id\":\"j1\",\"type\":\"dropped_events\",\"state\":\"RUNNING\",\"dropped\":1}\n\n"))
+			_, _ = w.Write([]byte("data: {\"seq\":3,\"job_id\":\"j1\",\"state\":\"RUNNING\"}\n\n"))
+			return
+		}
+		_, _ = w.Write([]byte("data: {\"seq\":2,\"job_id\":\"j1\",\"state\":\"RUNNING\"}\n\n"))
+		_, _ = w.Write([]byte("data: {\"seq\":3,\"job_id\":\"j1\",\"state\":\"SUCCEEDED\"}\n\n"))
+	}))
+	defer ts.Close()
+
+	c := &HTTPClient{BaseURL: ts.URL}
+	var seqs []int64
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.StreamEvents(ctx, "j1", 0, func(ev *job.Event) {
+		seqs = append(seqs, ev.Seq)
+	}); err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	if len(seqs) != 3 || seqs[0] != 1 || seqs[1] != 2 || seqs[2] != 3 {
+		t.Fatalf("unexpected events: %v", seqs)
+	}
+	if first, second := <-sinceSeen, <-sinceSeen; first != "" || second != "1" {
+		t.Fatalf("unexpected since values: %q %q", first, second)
+	}
+}
+
+func TestStreamEventsWS_ReconnectsAfterStallFromLastSeq(t *testing.T) {
+	var calls atomic.Int32
+	sinceSeen := make(chan string, 4)
+	release := make(chan struct{})
+	defer close(release)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/jobs/j1/events/ws" || r.Header.Get("X-Build-Token") != "secret" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		sinceSeen <- r.URL.Query().Get("since")
+		conn, err := websocket.Accept(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close(websocket.CloseNormal, "")
+		if calls.Add(1) == 1 {
+			_ = conn.WriteText([]byte(`{"seq":1,"job_id":"j1","state":"RUNNING"}`))
+			<-release
+			return
+		}
+		_ = conn.WriteText([]byte(`{"seq":2,"job_id":"j1","state":"SUCCEEDED"}`))
+	}))
+	defer ts.Close()
+
+	c := &HTTPClient{BaseURL: ts.URL, Token: "secret", StreamIdleTimeout: 50 * time.Millisecond}
+	var seqs []int64
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := c.StreamEventsWS(ctx, "j1", 0, func(ev *job.Event) {
+		seqs = append(seqs, ev.Seq)
+	}); err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	if len(seqs) != 2 || seqs[0] != 1 || seqs[1] != 2 {
+		t.Fatalf("unexpected events: %v", seqs)
+	}
+	if first, second := <-sinceSeen, <-sinceSeen; first != "" || second != "1" {
+		t.Fatalf("unexpected since values: %q %q", first, second)
+	}
+}
+
+func TestStreamEventsWS_ReportsRejectedUpgrade(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "proxy says no", http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	c := &HTTPClient{BaseURL: ts.URL}
+	err := c.StreamEventsWS(context.Background(), "j1", 0, nil)
+	if err == nil || !strings.Contains(err.Error(), "status=403") {
+		t.Fatalf("expected a 403 error, got %v", err)
+	}
+}
+
+func TestClient_GetJobRetriesTransientFailures(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"j1","state":"RUNNING"}`))
+	}))
+	defer ts.Close()
+
+	c := &HTTPClient{BaseURL: ts.URL, Retry: httpretry.Policy{BaseDelay: time.Millisecond}}
+	rec, err := c.GetJob(context.Background(), "j1")
+	if err != nil {
+		t.Fatalf("get job failed: %v", err)
+	}
+	if rec.State != job.StateRunning || calls.Load() != 2 {
+		t.Fatalf("unexpected result: state=%s calls=%d", rec.State, calls.Load())
+	}
+}
+
+func TestClient_SubmitErrorCarriesValidationDetails(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"validate manifest: /top: top is required","details":[{"path":"/top","message":"top is required"}]}`))
+	}))
+	defer ts.Close()
+
+	c := &HTTPClient{BaseURL: ts.URL}
+	_, err := c.Submit(context.Background(), []byte("zip"))
+	var submitErr *SubmitError
+	if !errors.As(err, &submitErr) {
+		t.Fatalf("expected *SubmitError, got %T %v", err, err)
+	}
+	if submitErr.StatusCode != http.StatusBadRequest || len(submitErr.Details) != 1 || submitErr.Details[0].Path != "/top" {
+		t.Fatalf("unexpected submit error: %+v", submitErr)
+	}
+	if !strings.Contains(err.Error(), "status=400") {
+		t.Fatalf("expected status in message, got %q", err.Error())
+	}
+}
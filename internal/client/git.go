@@ -0,0 +1,46 @@
+//go:build !js
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+
+	"github.com/mblsha/spadeforge/internal/manifest"
+)
+
+// DetectGit describes the git checkout containing dir: HEAD commit, branch
+// (empty when detached), whether tracked files have uncommitted changes, and
+// the origin remote with credentials removed. It returns nil when dir is not
+// inside a git work tree or git is not installed.
+func DetectGit(ctx context.Context, dir string) *manifest.GitInfo {
+	commit, err := gitOutput(ctx, dir, "rev-parse", "HEAD")
+	if err != nil || commit == "" {
+		return nil
+	}
+	info := &manifest.GitInfo{Commit: commit}
+	if branch, err := gitOutput(ctx, dir, "symbolic-ref", "--quiet", "--short", "HEAD"); err == nil {
+		info.Branch = branch
+	}
+	if status, err := gitOutput(ctx, dir, "status", "--porcelain", "--untracked-files=no"); err == nil {
+		info.Dirty = status != ""
+	}
+	if remote, err := gitOutput(ctx, dir, "remote", "get-url", "origin"); err == nil {
+		info.Remote = remote
+	}
+	info.Normalize()
+	return info
+}
+
+func gitOutput(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out.String()), nil
+}
@@ -0,0 +1,14 @@
+//go:build js
+
+package client
+
+import (
+	"context"
+
+	"github.com/mblsha/spadeforge/internal/manifest"
+)
+
+// DetectGit always returns nil in the browser, which cannot run git.
+func DetectGit(ctx context.Context, dir string) *manifest.GitInfo {
+	return nil
+}
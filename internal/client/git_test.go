@@ -0,0 +1,50 @@
+//go:build !js
+
+package client
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectGit_ReportsCommitBranchDirtyAndRemote(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@example.com", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	if got := DetectGit(context.Background(), dir); got != nil {
+		t.Fatalf("expected nil outside a repo, got %+v", got)
+	}
+
+	run("init", "-q", "-b", "main")
+	run("remote", "add", "origin", "https://token@example.com/acme/blinky.git")
+	if err := os.WriteFile(filepath.Join(dir, "top.sv"), []byte("module top; endmodule\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	run("add", "top.sv")
+	run("commit", "-q", "-m", "init")
+
+	info := DetectGit(context.Background(), dir)
+	if info == nil || len(info.Commit) != 40 || info.Branch != "main" || info.Dirty || info.Remote != "https://example.com/acme/blinky.git" {
+		t.Fatalf("unexpected git info: %+v", info)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "top.sv"), []byte("module top; wire x; endmodule\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if info := DetectGit(context.Background(), dir); info == nil || !info.Dirty {
+		t.Fatalf("expected dirty tree, got %+v", info)
+	}
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+// ErrMeUnsupported means the server predates /v1/me.
+var ErrMeUnsupported = errors.New("server does not support /v1/me")
+
+// GetMe returns who the server takes this client to be and the submission
+// defaults it stores for it.
+func (c *HTTPClient) GetMe(ctx context.Context) (*job.Me, error) {
+	resp, err := c.get(ctx, c.buildURL("/v1/me"))
+	if err != nil {
+		return nil, err
+	}
+	return decodeMe(resp, "get me")
+}
+
+// SetDefaults replaces the submission defaults the server stores for this
+// client. Zero defaults clear them.
+func (c *HTTPClient) SetDefaults(ctx context.Context, defaults job.ClientDefaults) (*job.Me, error) {
+	payload, err := json.Marshal(defaults)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Retry.Do(ctx, c.httpClient(), func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.buildURL("/v1/me/defaults"), bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		c.setAuth(req)
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decodeMe(resp, "set defaults")
+}
+
+func decodeMe(resp *http.Response, op string) (*job.Me, error) {
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		return nil, ErrMeUnsupported
+	default:
+		raw, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%s failed: status=%d body=%s", op, resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	var me job.Me
+	if err := json.NewDecoder(resp.Body).Decode(&me); err != nil {
+		return nil, err
+	}
+	return &me, nil
+}
@@ -0,0 +1,201 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/mblsha/spadeforge/internal/httpretry"
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+// defaultChunkSize is the resumable upload chunk size when ChunkSize is
+// unset.
+const defaultChunkSize = 4 << 20
+
+// errUploadsUnsupported means the server predates /v1/uploads.
+var errUploadsUnsupported = errors.New("server does not support resumable uploads")
+
+// SubmitResumable uploads bundle in chunks through /v1/uploads and
+// finalizes it into a job. After a dropped connection or a transient error
+// it asks the server how many bytes arrived and continues from there, giving
+// up after Retry's attempts pass without progress. The finalized upload is
+// checked against the bundle's SHA-256 on the server. Servers without
+// /v1/uploads get a plain Submit.
+func (c *HTTPClient) SubmitResumable(ctx context.Context, bundle []byte) (*job.SubmitResponse, error) {
+	if len(bundle) == 0 {
+		return c.Submit(ctx, bundle)
+	}
+	sum := sha256.Sum256(bundle)
+	up, err := c.createUpload(ctx, job.UploadRequest{
+		Size:      int64(len(bundle)),
+		SHA256:    hex.EncodeToString(sum[:]),
+		Submitter: strings.TrimSpace(c.Submitter),
+		NoCache:   c.NoCache,
+	})
+	if errors.Is(err, errUploadsUnsupported) {
+		return c.Submit(ctx, bundle)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := c.sendChunks(ctx, up, bundle); err != nil {
+		return nil, err
+	}
+	return c.finalizeUpload(ctx, up.ID)
+}
+
+func (c *HTTPClient) createUpload(ctx context.Context, req job.UploadRequest) (*job.Upload, error) {
+	raw, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Retry.Do(ctx, c.httpClient(), func() (*http.Request, error) {
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.buildURL("/v1/uploads"), bytes.NewReader(raw))
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		c.setAuth(httpReq)
+		return httpReq, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusCreated:
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		return nil, errUploadsUnsupported
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("create upload failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var up job.Upload
+	if err := json.NewDecoder(resp.Body).Decode(&up); err != nil {
+		return nil, err
+	}
+	if up.ID == "" {
+		return nil, fmt.Errorf("create upload response missing upload_id")
+	}
+	return &up, nil
+}
+
+// sendChunks sends bundle from the upload's offset to the end.
+func (c *HTTPClient) sendChunks(ctx context.Context, up *job.Upload, bundle []byte) error {
+	chunk := c.ChunkSize
+	if chunk <= 0 {
+		chunk = defaultChunkSize
+	}
+	size := int64(len(bundle))
+	offset := up.Offset
+	failures := 0
+	for offset < size {
+		end := min(offset+chunk, size)
+		next, retry, err := c.patchChunk(ctx, up.ID, offset, bundle[offset:end])
+		if err == nil {
+			offset = next.Offset
+			failures = 0
+			continue
+		}
+		if !retry || ctx.Err() != nil {
+			return err
+		}
+		failures++
+		if failures >= c.Retry.Attempts() {
+			return fmt.Errorf("upload stalled at byte %d of %d: %w", offset, size, err)
+		}
+		if err := httpretry.Sleep(ctx, c.Retry.Backoff(failures)); err != nil {
+			return err
+		}
+		// Part of the chunk may have arrived before the failure.
+		if st, err := c.getUpload(ctx, up.ID); err == nil {
+			if st.Offset > offset {
+				failures = 0
+			}
+			offset = st.Offset
+		}
+	}
+	return nil
+}
+
+// patchChunk sends one chunk. retry reports whether the failure is
+// transient, so the upload can resume.
+func (c *HTTPClient) patchChunk(ctx context.Context, id string, offset int64, data []byte) (_ *job.Upload, retry bool, _ error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, c.buildURL(path.Join("/v1/uploads", id)), bytes.NewReader(data))
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set(job.UploadOffsetHeader, strconv.FormatInt(offset, 10))
+	c.setAuth(req)
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		retry := resp.StatusCode == http.StatusConflict || resp.StatusCode >= 500 || httpretry.Retryable(resp.StatusCode)
+		return nil, retry, fmt.Errorf("upload chunk failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var up job.Upload
+	if err := json.NewDecoder(resp.Body).Decode(&up); err != nil {
+		return nil, true, err
+	}
+	return &up, false, nil
+}
+
+func (c *HTTPClient) getUpload(ctx context.Context, id string) (*job.Upload, error) {
+	resp, err := c.get(ctx, c.buildURL(path.Join("/v1/uploads", id)))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("get upload failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var up job.Upload
+	if err := json.NewDecoder(resp.Body).Decode(&up); err != nil {
+		return nil, err
+	}
+	return &up, nil
+}
+
+// finalizeUpload turns a complete upload into a job. It is not retried:
+// after a dropped connection the job may already exist and the upload be
+// gone.
+func (c *HTTPClient) finalizeUpload(ctx context.Context, id string) (*job.SubmitResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.buildURL(path.Join("/v1/uploads", id, "finalize")), nil)
+	if err != nil {
+		return nil, err
+	}
+	c.setAuth(req)
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		raw, _ := io.ReadAll(resp.Body)
+		return nil, newSubmitError(resp.StatusCode, raw)
+	}
+	var payload job.SubmitResponse
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, err
+	}
+	if payload.JobID == "" {
+		return nil, fmt.Errorf("submit response missing job_id")
+	}
+	return &payload, nil
+}
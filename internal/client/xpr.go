@@ -0,0 +1,226 @@
+package client
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mblsha/spadeforge/internal/manifest"
+)
+
+// xprProject is the part of a Vivado .xpr file ImportXPR reads.
+type xprProject struct {
+	Options  []xprOption  `xml:"Configuration>Option"`
+	FileSets []xprFileSet `xml:"FileSets>FileSet"`
+	Runs     []xprRun     `xml:"Runs>Run"`
+}
+
+type xprOption struct {
+	Name string `xml:"Name,attr"`
+	Val  string `xml:"Val,attr"`
+}
+
+type xprFileSet struct {
+	Name    string      `xml:"Name,attr"`
+	Type    string      `xml:"Type,attr"`
+	Files   []xprFile   `xml:"File"`
+	Options []xprOption `xml:"Config>Option"`
+}
+
+type xprFile struct {
+	Path string `xml:"Path,attr"`
+	Info struct {
+		SFType string      `xml:"SFType,attr"`
+		Attrs  []xprOption `xml:"Attr"`
+	} `xml:"FileInfo"`
+}
+
+type xprRun struct {
+	ID         string `xml:"Id,attr"`
+	SrcSet     string `xml:"SrcSet,attr"`
+	ConstrsSet string `xml:"ConstrsSet,attr"`
+}
+
+// ImportXPR reads a Vivado project file and returns a bundle spec with its
+// name, part, top module, synthesis sources, headers, IP, block designs,
+// XDC constraints, Verilog defines, generics and per-file language and
+// library. It follows the file sets of the synth_1 run, or the first design
+// and constraint sets when there is none. Files disabled or used only in
+// simulation are left out; any other files, such as memory initialization
+// files, are returned as skipped so the caller can warn.
+func ImportXPR(path string) (BundleSpec, []string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return BundleSpec{}, nil, err
+	}
+	var proj xprProject
+	if err := xml.Unmarshal(raw, &proj); err != nil {
+		return BundleSpec{}, nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	spec := BundleSpec{Project: name, Part: xprOptionValue(proj.Options, "Part")}
+
+	srcSet, constrsSet := proj.activeFileSets()
+	if srcSet == nil {
+		return BundleSpec{}, nil, fmt.Errorf("%s has no design source file set", path)
+	}
+	spec.Top = xprOptionValue(srcSet.Options, "TopModule")
+	for _, opt := range srcSet.Options {
+		var target *map[string]string
+		switch opt.Name {
+		case "VerilogDefine":
+			target = &spec.Defines
+		case "Generic":
+			target = &spec.TopParams
+		default:
+			continue
+		}
+		for _, entry := range strings.Fields(opt.Val) {
+			if *target == nil {
+				*target = map[string]string{}
+			}
+			k, v, _ := strings.Cut(entry, "=")
+			(*target)[k] = v
+		}
+	}
+
+	var skipped []string
+	for _, f := range srcSet.Files {
+		if !f.usedIn("synthesis") {
+			continue
+		}
+		local, err := xprFilePath(f.Path, dir, name)
+		if err != nil {
+			return BundleSpec{}, nil, err
+		}
+		switch strings.ToLower(filepath.Ext(local)) {
+		case ".vh", ".svh":
+			spec.Headers = append(spec.Headers, local)
+			continue
+		case ".xci", ".xcix":
+			spec.IP = append(spec.IP, local)
+			continue
+		case ".bd":
+			spec.BlockDesigns = append(spec.BlockDesigns, local)
+			continue
+		case ".sv", ".v", ".vhd", ".vhdl":
+		default:
+			skipped = append(skipped, local)
+			continue
+		}
+		spec.Sources = append(spec.Sources, local)
+		if opt := f.sourceOption(local); opt != (manifest.SourceOption{}) {
+			if spec.SourceOptions == nil {
+				spec.SourceOptions = map[string]manifest.SourceOption{}
+			}
+			spec.SourceOptions[local] = opt
+		}
+	}
+	if constrsSet != nil {
+		for _, f := range constrsSet.Files {
+			if !f.usedIn("synthesis", "implementation") {
+				continue
+			}
+			local, err := xprFilePath(f.Path, dir, name)
+			if err != nil {
+				return BundleSpec{}, nil, err
+			}
+			if !strings.EqualFold(filepath.Ext(local), ".xdc") {
+				skipped = append(skipped, local)
+				continue
+			}
+			spec.Constraints = append(spec.Constraints, local)
+		}
+	}
+	return spec, skipped, nil
+}
+
+// activeFileSets returns the source and constraint sets synth_1 builds.
+func (p xprProject) activeFileSets() (src, constrs *xprFileSet) {
+	srcName, constrsName := "", ""
+	for _, run := range p.Runs {
+		if run.ID == "synth_1" {
+			srcName, constrsName = run.SrcSet, run.ConstrsSet
+			break
+		}
+	}
+	for i := range p.FileSets {
+		set := &p.FileSets[i]
+		switch set.Type {
+		case "DesignSrcs":
+			if src == nil && (srcName == "" || set.Name == srcName) {
+				src = set
+			}
+		case "Constrs":
+			if constrs == nil && (constrsName == "" || set.Name == constrsName) {
+				constrs = set
+			}
+		}
+	}
+	return src, constrs
+}
+
+// usedIn reports whether the file is enabled for any of the given flows.
+// Files without UsedIn attributes are used everywhere.
+func (f xprFile) usedIn(flows ...string) bool {
+	listed, used := false, false
+	for _, a := range f.Info.Attrs {
+		switch a.Name {
+		case "AutoDisabled":
+			if a.Val == "1" {
+				return false
+			}
+		case "UsedIn":
+			listed = true
+			for _, flow := range flows {
+				used = used || a.Val == flow
+			}
+		}
+	}
+	return used || !listed
+}
+
+// sourceOption maps the file's type and library to manifest options.
+// Vivado reads .v files as plain Verilog, unlike the manifest default.
+func (f xprFile) sourceOption(local string) manifest.SourceOption {
+	var opt manifest.SourceOption
+	switch {
+	case f.Info.SFType == "VHDL2008":
+		opt.Language = manifest.LanguageVHDL2008
+	case f.Info.SFType == "SystemVerilog":
+	case strings.EqualFold(filepath.Ext(local), ".v"):
+		opt.Language = manifest.LanguageVerilog
+	}
+	if lib := xprOptionValue(f.Info.Attrs, "Library"); lib != "xil_defaultlib" {
+		opt.Library = lib
+	}
+	return opt
+}
+
+// xprFilePath resolves a project file path. Vivado writes them relative to
+// the project directory ($PPRDIR) or its <name>.srcs directory ($PSRCDIR).
+func xprFilePath(raw, dir, name string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, "$PPRDIR"):
+		raw = filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(raw, "$PPRDIR")))
+	case strings.HasPrefix(raw, "$PSRCDIR"):
+		raw = filepath.Join(dir, name+".srcs", filepath.FromSlash(strings.TrimPrefix(raw, "$PSRCDIR")))
+	case strings.HasPrefix(raw, "$"):
+		return "", fmt.Errorf("unsupported project path variable in %s", raw)
+	case !filepath.IsAbs(filepath.FromSlash(raw)):
+		raw = filepath.Join(dir, filepath.FromSlash(raw))
+	}
+	return filepath.Clean(raw), nil
+}
+
+func xprOptionValue(opts []xprOption, name string) string {
+	for _, o := range opts {
+		if o.Name == name {
+			return o.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,161 @@
+package client
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mblsha/spadeforge/internal/manifest"
+)
+
+const testXPR = `<?xml version="1.0" encoding="UTF-8"?>
+<Project Version="7" Minor="61" Path="/home/dev/blinky/blinky.xpr">
+  <Configuration>
+    <Option Name="Part" Val="xc7a35tcsg324-1"/>
+  </Configuration>
+  <FileSets Version="1" Minor="31">
+    <FileSet Name="sources_1" Type="DesignSrcs" RelSrcDir="$PSRCDIR/sources_1">
+      <File Path="$PPRDIR/../rtl/top.sv"/>
+      <File Path="$PPRDIR/../rtl/legacy.v">
+        <FileInfo>
+          <Attr Name="UsedIn" Val="synthesis"/>
+          <Attr Name="UsedIn" Val="simulation"/>
+        </FileInfo>
+      </File>
+      <File Path="$PSRCDIR/sources_1/new/fifo.vhd">
+        <FileInfo SFType="VHDL2008">
+          <Attr Name="Library" Val="fifo_lib"/>
+        </FileInfo>
+      </File>
+      <File Path="$PPRDIR/../rtl/defs.vh"/>
+      <File Path="$PSRCDIR/sources_1/ip/clk_wiz_0/clk_wiz_0.xci"/>
+      <File Path="$PPRDIR/../rtl/rom.mem"/>
+      <File Path="$PPRDIR/../rtl/tb.sv">
+        <FileInfo>
+          <Attr Name="UsedIn" Val="simulation"/>
+        </FileInfo>
+      </File>
+      <File Path="$PPRDIR/../rtl/old.sv">
+        <FileInfo>
+          <Attr Name="AutoDisabled" Val="1"/>
+        </FileInfo>
+      </File>
+      <Config>
+        <Option Name="DesignMode" Val="RTL"/>
+        <Option Name="TopModule" Val="top"/>
+        <Option Name="VerilogDefine" Val="SIM CLK_HZ=100000000"/>
+        <Option Name="Generic" Val="WIDTH=8"/>
+      </Config>
+    </FileSet>
+    <FileSet Name="constrs_old" Type="Constrs" RelSrcDir="$PSRCDIR/constrs_old">
+      <File Path="$PPRDIR/../old.xdc"/>
+    </FileSet>
+    <FileSet Name="constrs_1" Type="Constrs" RelSrcDir="$PSRCDIR/constrs_1">
+      <File Path="$PPRDIR/../pins.xdc"/>
+    </FileSet>
+  </FileSets>
+  <Runs Version="1" Minor="20">
+    <Run Id="synth_1" Type="Ft3:Synth" SrcSet="sources_1" Part="xc7a35tcsg324-1" ConstrsSet="constrs_1"/>
+  </Runs>
+</Project>
+`
+
+func TestImportXPR_ReadsActiveFileSets(t *testing.T) {
+	root := t.TempDir()
+	projDir := filepath.Join(root, "vivado")
+	files := map[string]string{
+		"rtl/top.sv":   "module top; endmodule\n",
+		"rtl/legacy.v": "module legacy; endmodule\n",
+		"rtl/defs.vh":  "`define WIDTH 8\n",
+		"rtl/rom.mem":  "00\n",
+		"vivado/blinky.srcs/sources_1/new/fifo.vhd": "entity fifo is end;\n",
+		"pins.xdc": "set_property PACKAGE_PIN W5 [get_ports clk]\n",
+		"vivado/blinky.srcs/sources_1/ip/clk_wiz_0/clk_wiz_0.xci": "{}\n",
+		"vivado/blinky.srcs/sources_1/ip/clk_wiz_0/clk_wiz_0.xml": "<ip/>\n",
+		"vivado/blinky.xpr": testXPR,
+	}
+	for rel, body := range files {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	spec, skipped, err := ImportXPR(filepath.Join(projDir, "blinky.xpr"))
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if spec.Project != "blinky" || spec.Top != "top" || spec.Part != "xc7a35tcsg324-1" {
+		t.Fatalf("unexpected project settings %q %q %q", spec.Project, spec.Top, spec.Part)
+	}
+	rtl := func(name string) string { return filepath.Join(root, "rtl", name) }
+	fifo := filepath.Join(projDir, "blinky.srcs", "sources_1", "new", "fifo.vhd")
+	if want := []string{rtl("top.sv"), rtl("legacy.v"), fifo}; !reflect.DeepEqual(spec.Sources, want) {
+		t.Fatalf("sources = %v, want %v", spec.Sources, want)
+	}
+	if want := []string{rtl("defs.vh")}; !reflect.DeepEqual(spec.Headers, want) {
+		t.Fatalf("headers = %v, want %v", spec.Headers, want)
+	}
+	if want := []string{filepath.Join(projDir, "blinky.srcs", "sources_1", "ip", "clk_wiz_0", "clk_wiz_0.xci")}; !reflect.DeepEqual(spec.IP, want) {
+		t.Fatalf("ip = %v, want %v", spec.IP, want)
+	}
+	if want := []string{filepath.Join(root, "pins.xdc")}; !reflect.DeepEqual(spec.Constraints, want) {
+		t.Fatalf("constraints = %v, want %v", spec.Constraints, want)
+	}
+	if want := []string{rtl("rom.mem")}; !reflect.DeepEqual(skipped, want) {
+		t.Fatalf("skipped = %v, want %v", skipped, want)
+	}
+	if want := map[string]string{"SIM": "", "CLK_HZ": "100000000"}; !reflect.DeepEqual(spec.Defines, want) {
+		t.Fatalf("defines = %v", spec.Defines)
+	}
+	if want := map[string]string{"WIDTH": "8"}; !reflect.DeepEqual(spec.TopParams, want) {
+		t.Fatalf("top params = %v", spec.TopParams)
+	}
+
+	bundle, err := BuildBundle(spec)
+	if err != nil {
+		t.Fatalf("build bundle failed: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(bundle), int64(len(bundle)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mf manifest.Manifest
+	seen := map[string]bool{}
+	for _, f := range zr.File {
+		seen[f.Name] = true
+		if f.Name != "manifest.json" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, _ := io.ReadAll(rc)
+		rc.Close()
+		if err := json.Unmarshal(raw, &mf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wantOpts := map[string]manifest.SourceOption{
+		"hdl/legacy.v": {Language: manifest.LanguageVerilog},
+		"hdl/fifo.vhd": {Language: manifest.LanguageVHDL2008, Library: "fifo_lib"},
+	}
+	if !reflect.DeepEqual(mf.SourceOptions, wantOpts) {
+		t.Fatalf("source options = %v, want %v", mf.SourceOptions, wantOpts)
+	}
+	if !seen["ip/clk_wiz_0/clk_wiz_0.xml"] || !reflect.DeepEqual(mf.IP, []string{"ip/clk_wiz_0/clk_wiz_0.xci"}) {
+		t.Fatalf("IP directory not bundled: files %v, manifest ip %v", seen, mf.IP)
+	}
+	if !reflect.DeepEqual(mf.IncludeDirs, []string{"hdl"}) {
+		t.Fatalf("include dirs = %v, want [hdl]", mf.IncludeDirs)
+	}
+}
@@ -0,0 +1,144 @@
+// Package clientdefaults keeps the submission defaults clients store on the
+// build server, in one JSON file keyed by client.
+package clientdefaults
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/mblsha/spadeforge/internal/artifactname"
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/manifest"
+)
+
+// maxFieldLen bounds each stored value; defaults are names and short paths.
+const maxFieldLen = 256
+
+// Store reads the file on first use and rewrites it on every change.
+type Store struct {
+	path string
+
+	mu     sync.Mutex
+	loaded bool
+	all    map[string]job.ClientDefaults
+}
+
+// New returns a store backed by the JSON file at path, which need not exist
+// yet.
+func New(path string) *Store {
+	return &Store{path: path}
+}
+
+// Get returns the defaults stored for client; unknown clients get none.
+func (s *Store) Get(client string) (job.ClientDefaults, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadLocked(); err != nil {
+		return job.ClientDefaults{}, err
+	}
+	return s.all[client], nil
+}
+
+// Set replaces client's defaults after normalizing and validating them, and
+// returns what was stored. All-empty defaults remove the client's entry.
+func (s *Store) Set(client string, d job.ClientDefaults) (job.ClientDefaults, error) {
+	if err := Normalize(&d); err != nil {
+		return job.ClientDefaults{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadLocked(); err != nil {
+		return job.ClientDefaults{}, err
+	}
+	next := make(map[string]job.ClientDefaults, len(s.all)+1)
+	for k, v := range s.all {
+		next[k] = v
+	}
+	if d == (job.ClientDefaults{}) {
+		delete(next, client)
+	} else {
+		next[client] = d
+	}
+	if err := s.writeLocked(next); err != nil {
+		return job.ClientDefaults{}, err
+	}
+	s.all = next
+	return d, nil
+}
+
+// Normalize trims d and checks every field the server can check: the
+// toolchain, the name templates and the lengths.
+func Normalize(d *job.ClientDefaults) error {
+	fields := []*string{&d.Part, &d.Toolchain, &d.Board, &d.OutputDir, &d.OutputName, &d.BitstreamName}
+	for _, f := range fields {
+		*f = strings.TrimSpace(*f)
+		if len(*f) > maxFieldLen {
+			return fmt.Errorf("default values must be at most %d bytes", maxFieldLen)
+		}
+		if strings.ContainsAny(*f, "\r\n") {
+			return errors.New("default values must be a single line")
+		}
+	}
+	d.Toolchain = strings.ToLower(d.Toolchain)
+	if d.Toolchain != "" && d.Toolchain != manifest.ToolchainVivado && d.Toolchain != manifest.ToolchainYosys {
+		return fmt.Errorf(`toolchain must be "vivado" or "yosys", got %q`, d.Toolchain)
+	}
+	if d.OutputName != "" {
+		if err := artifactname.Validate(d.OutputName); err != nil {
+			return fmt.Errorf("output_name: %w", err)
+		}
+	}
+	if d.BitstreamName != "" {
+		if err := artifactname.Validate(d.BitstreamName); err != nil {
+			return fmt.Errorf("bitstream_name: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *Store) loadLocked() error {
+	if s.loaded {
+		return nil
+	}
+	raw, err := os.ReadFile(s.path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		s.all = map[string]job.ClientDefaults{}
+	case err != nil:
+		return fmt.Errorf("read client defaults: %w", err)
+	default:
+		all := map[string]job.ClientDefaults{}
+		if err := json.Unmarshal(raw, &all); err != nil {
+			return fmt.Errorf("parse client defaults %s: %w", s.path, err)
+		}
+		s.all = all
+	}
+	s.loaded = true
+	return nil
+}
+
+// writeLocked replaces the file through a temporary one so a crash never
+// leaves it half written.
+func (s *Store) writeLocked(all map[string]job.ClientDefaults) error {
+	raw, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal client defaults: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("create client defaults dir: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o644); err != nil {
+		return fmt.Errorf("write client defaults: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("write client defaults: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package clientdefaults
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+func TestStore_SetGetAndClear(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "client_defaults.json")
+	s := New(path)
+	if got, err := s.Get("token:ci"); err != nil || got != (job.ClientDefaults{}) {
+		t.Fatalf("Get before any Set = %+v, %v", got, err)
+	}
+	if _, err := s.Set("token:ci", job.ClientDefaults{Part: "xc7a35tcsg324-1", OutputName: "{project}"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Set("ip:10.0.0.2", job.ClientDefaults{Board: "arty"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := New(path).Get("token:ci"); got.Part != "xc7a35tcsg324-1" || got.OutputName != "{project}" {
+		t.Fatalf("reloaded defaults = %+v", got)
+	}
+
+	if _, err := s.Set("token:ci", job.ClientDefaults{}); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), "token:ci") || !strings.Contains(string(raw), "ip:10.0.0.2") {
+		t.Fatalf("file after clearing token:ci:\n%s", raw)
+	}
+}
+
+func TestNormalize_RejectsBadValues(t *testing.T) {
+	for _, d := range []job.ClientDefaults{
+		{Toolchain: "quartus"},
+		{OutputName: "{nope}"},
+		{BitstreamName: "../x"},
+		{Part: "a\nb"},
+		{OutputDir: strings.Repeat("x", maxFieldLen+1)},
+	} {
+		if err := Normalize(&d); err == nil {
+			t.Fatalf("Normalize(%+v) should fail", d)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/authz"
+)
+
+// envPrefix marks the variables FromEnv reads.
+const envPrefix = "SPADEFORGE_"
+
+// redacted replaces secrets in the effective config.
+const redacted = "<redacted>"
+
+// CheckEnv loads the config from environ ("KEY=value" entries, as from
+// os.Environ) like FromEnv, and lists the SPADEFORGE_ variables no setting
+// reads, which are usually typos. Unused variables are only listed when the
+// config loads.
+func CheckEnv(environ []string) (Config, []string, error) {
+	vars := map[string]string{}
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			vars[k] = v
+		}
+	}
+	read := map[string]bool{}
+	cfg, err := fromEnv(func(k string) string {
+		read[k] = true
+		return vars[k]
+	})
+	if err != nil {
+		return cfg, nil, err
+	}
+	var unused []string
+	for k := range vars {
+		if strings.HasPrefix(k, envPrefix) && !read[k] {
+			unused = append(unused, k)
+		}
+	}
+	sort.Strings(unused)
+	return cfg, unused, nil
+}
+
+// Effective returns the config as a JSON-friendly map with secrets
+// redacted: tokens are replaced and URL passwords masked. Durations are
+// rendered like "30m0s".
+func (c Config) Effective() map[string]any {
+	if c.Token != "" {
+		c.Token = redacted
+	}
+	if c.LoaderToken != "" {
+		c.LoaderToken = redacted
+	}
+	c.Tokens = authz.Redacted(c.Tokens, redacted)
+	c.MQTTURL = redactURL(c.MQTTURL)
+	c.Loaders = redactURLs(c.Loaders)
+	c.Webhooks = redactURLs(c.Webhooks)
+	return effectiveFields(c)
+}
+
+func effectiveFields(c any) map[string]any {
+	out := map[string]any{}
+	v := reflect.ValueOf(c)
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		value := v.Field(i).Interface()
+		if d, ok := value.(time.Duration); ok {
+			value = d.String()
+		}
+		out[field.Name] = value
+	}
+	return out
+}
+
+func redactURLs(urls map[string]string) map[string]string {
+	if urls == nil {
+		return nil
+	}
+	out := make(map[string]string, len(urls))
+	for name, raw := range urls {
+		out[name] = redactURL(raw)
+	}
+	return out
+}
+
+// redactURL masks the password and query of raw, which may carry
+// credentials.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || raw == "" {
+		return raw
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "xxxxx")
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = "xxxxx"
+	}
+	return u.String()
+}
@@ -4,53 +4,238 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/mblsha/spadeforge/internal/artifactname"
+	"github.com/mblsha/spadeforge/internal/authz"
+	"github.com/mblsha/spadeforge/internal/logx"
+	"github.com/mblsha/spadeforge/internal/manifest"
+	"github.com/mblsha/spadeforge/internal/mqtt"
+	"github.com/mblsha/spadeforge/internal/pathglob"
+	"github.com/mblsha/spadeforge/internal/sse"
 )
 
 const (
 	defaultListenAddr              = ":8080"
 	defaultAuthHeader              = "X-Build-Token"
 	defaultMaxUploadBytes    int64 = 256 << 20
+	defaultUploadTTL               = 24 * time.Hour
+	defaultChaosMaxDelay           = 5 * time.Second
 	defaultMaxFiles                = 4096
 	defaultMaxExtractedTotal int64 = 1024 << 20
 	defaultMaxExtractedFile  int64 = 256 << 20
+	defaultSSEKeepalive            = 15 * time.Second
+	defaultRateLimitBurst          = 20
 	defaultWorkerTimeout           = 2 * time.Hour
 	defaultRetentionDays           = 14
+	defaultRetentionInterval       = time.Hour
+	defaultRetentionGrace          = 24 * time.Hour
+	defaultMirrorKeep              = 10
 	defaultVivadoBin               = "vivado"
 	defaultDiscoveryEnabled        = true
 	defaultDiscoveryService        = "_spadeforge._tcp"
 	defaultDiscoveryDomain         = "local."
 	defaultDiscoveryInstance       = "spadeforge"
+	defaultMQTTTopicPrefix         = "spadeforge"
+)
+
+// Orphan actions for OrphanAction. Quarantined dirs are moved into a
+// ".orphaned" dir under their root for an operator to inspect.
+const (
+	OrphanQuarantine = "quarantine"
+	OrphanRemove     = "remove"
+	OrphanOff        = "off"
 )
 
 // Config controls server behavior.
 type Config struct {
 	ListenAddr string
 	BaseDir    string
+	// WorkRoot and ArtifactsRoot move the per-job work and artifact trees
+	// off BaseDir, e.g. work onto a fast scratch SSD and artifacts onto a
+	// large slow disk. Empty keeps them under BaseDir.
+	WorkRoot      string
+	ArtifactsRoot string
+	// WorkMinFreeBytes pauses dequeuing while the work volume has less free
+	// space than this; 0 disables the check.
+	WorkMinFreeBytes int64
 
 	Token      string
 	AuthHeader string
 	Allowlist  []string
+	// Tokens are named tokens limited to their scopes, from SPADEFORGE_TOKENS
+	// and the JSON file TokensFile. Token keeps full access.
+	Tokens     []authz.Token
+	TokensFile string
+	// FeedPublic serves /v1/feed.json without the token so wallboards need
+	// no credentials; the allowlist and rate limit still apply.
+	FeedPublic bool
+	// TLSCert and TLSKey, when set, make the server listen with HTTPS.
+	// TLSClientCA is a PEM bundle of CAs whose client certificates are
+	// accepted in place of the token.
+	TLSCert     string
+	TLSKey      string
+	TLSClientCA string
 
 	MaxUploadBytes         int64
 	MaxExtractedFiles      int
 	MaxExtractedTotalBytes int64
 	MaxExtractedFileBytes  int64
+	// UploadTTL is how long a resumable upload may go without a new chunk
+	// before it is discarded.
+	UploadTTL time.Duration
 
-	WorkerTimeout   time.Duration
-	RetentionDays   int
+	WorkerTimeout time.Duration
+	// RetentionDays prunes finished jobs older than this many days; 0
+	// keeps them forever. RetentionMaxBytes, when set, also prunes the
+	// oldest finished jobs while stored jobs take more space. The reaper
+	// runs every RetentionInterval and always keeps project baselines and
+	// pinned jobs.
+	RetentionDays     int
+	RetentionMaxBytes int64
+	RetentionInterval time.Duration
+	// RetentionGrace is how long a job the reaper selects stays marked as
+	// expiring, so it can still be pinned, before it is removed; 0 removes
+	// it in the same pass. ExpiryWebhook names the Webhooks entry told
+	// about each expiring job.
+	RetentionGrace  time.Duration
+	ExpiryWebhook   string
 	PreserveWorkDir bool
+	// OrphanAction is what the reaper does with work and artifact dirs
+	// that have no job state file: OrphanQuarantine, OrphanRemove or
+	// OrphanOff.
+	OrphanAction string
+	// BuildCache answers a submit whose manifest and sources match a
+	// SUCCEEDED job with a copy of that job's artifacts instead of a new
+	// build; clients opt out per submit with no_cache.
+	BuildCache bool
+	// RecordDir, when set, saves every Vivado run's output with timing as a
+	// session file that builder.ReplayRunner can feed back in tests.
+	RecordDir string
+	// EncryptionKeyFile, when set, names a 32-byte AES-256 key (raw or
+	// hex) used to encrypt stored request bundles and bitstreams at rest.
+	EncryptionKeyFile string
+	// SecretsDir, when set, holds named secret files that manifests place
+	// into their sources for the duration of a build.
+	SecretsDir string
+	// ReleaseURL is advertised on /v1/version so CLIs can self-update;
+	// "{version}" stands for the release to fetch.
+	ReleaseURL string
+
+	// SSEKeepalive is the interval between keepalive comments on event
+	// streams; keep it below any NAT or proxy idle timeout on the path.
+	SSEKeepalive time.Duration
+
+	// AccessLog logs every HTTP request with its status and latency.
+	AccessLog bool
+	// LogLevel is the initial verbosity (error, warn, info, debug, trace)
+	// and LogModules overrides it per module (queue, builder, discovery,
+	// http). Both can be changed at runtime via POST /v1/admin/loglevel.
+	LogLevel   string
+	LogModules map[string]string
+	// RateLimit caps guarded requests per second per client IP, with bursts
+	// of up to RateLimitBurst; 0 disables it.
+	RateLimit      float64
+	RateLimitBurst int
+	// SubmitRateLimit caps job submissions per minute per client, the
+	// named token or else the client IP, with bursts of up to
+	// SubmitRateBurst (default: one minute's worth); 0 disables it.
+	SubmitRateLimit float64
+	SubmitRateBurst int
+	// MaxActiveJobsPerClient caps a client's queued and running jobs, and
+	// MaxStorageBytesPerClient the disk space its retained jobs take;
+	// further submits get 429 until jobs finish or expire. 0 disables each.
+	MaxActiveJobsPerClient   int
+	MaxStorageBytesPerClient int64
 
 	VivadoBin string
+	// Builder is the toolchain for jobs whose manifest names none: "vivado"
+	// or "yosys" (yosys + nextpnr for Lattice iCE40/ECP5 parts).
+	Builder string
+	// OSSBinDir holds yosys, nextpnr-ice40/ecp5, icepack and ecppack;
+	// empty looks them up on PATH.
+	OSSBinDir string
+	// ToolchainImage, when set, runs the open-toolchain tools (yosys,
+	// nextpnr, icepack/ecppack) inside this OCI image with ContainerRuntime
+	// instead of from the host PATH. Pin it by digest for reproducible
+	// builds.
+	ToolchainImage   string
+	ContainerRuntime string
+	// UseFakeBuilder swaps Vivado for the in-process fake builder.
+	UseFakeBuilder bool
+
+	// ChaosErrorRate, ChaosSlowRate and ChaosDropRate turn on fault
+	// injection for resilience tests: the share of requests answered with
+	// 500, delayed by up to ChaosMaxDelay, or, for event streams, cut off
+	// within ChaosMaxDelay. ChaosCrashRate is the share of fake builds that
+	// crash mid-step. Never set them on a real server.
+	ChaosErrorRate float64
+	ChaosSlowRate  float64
+	ChaosDropRate  float64
+	ChaosMaxDelay  time.Duration
+	ChaosCrashRate float64
+
+	// ArtifactInclude lists work-dir globs copied into job artifacts in
+	// addition to what the builder writes; ArtifactExclude lists artifact
+	// globs that are dropped before packaging.
+	ArtifactInclude []string
+	ArtifactExclude []string
+	// BitstreamName is the default artifactname template for a renamed
+	// copy of design.bit; the manifest's artifacts.bitstream_name wins.
+	BitstreamName string
+
+	// BuildWatts is the builder's assumed average draw, used to estimate
+	// per-build energy when EnergyRAPL is off or the RAPL counters are not
+	// readable. EnergyPricePerKWh turns energy into a cost; 0 omits it.
+	BuildWatts        float64
+	EnergyRAPL        bool
+	EnergyPricePerKWh float64
+
+	// SubmitterWeights gives submitters more consecutive jobs per
+	// round-robin turn; unlisted submitters weigh 1.
+	SubmitterWeights map[string]int
+
+	// MirrorDir, when set, receives a static copy of each succeeded job's
+	// artifacts matching MirrorInclude, plus an index.json, for plain HTTP
+	// file servers. MirrorKeep bounds the builds kept per project.
+	MirrorDir     string
+	MirrorInclude []string
+	MirrorKeep    int
+
+	// DiagnosticSuppress hides known benign diagnostics for every job, in
+	// addition to the manifest's diagnostics.suppress rules.
+	DiagnosticSuppress []manifest.SuppressRule
 
 	DiscoveryEnabled  bool
 	DiscoveryService  string
 	DiscoveryDomain   string
 	DiscoveryInstance string
+
+	// MQTTURL, when set, is the broker (mqtt://[user:pass@]host[:port] or
+	// mqtts://...) that receives job state transitions under
+	// MQTTTopicPrefix.
+	MQTTURL         string
+	MQTTTopicPrefix string
+
+	// Loaders maps the spadeloader names a manifest's on_success flash
+	// actions may use to base URLs; LoaderToken authenticates to them.
+	// Webhooks maps on_success webhook names to URLs. Manifests only refer
+	// to names, so submitters cannot point the server at arbitrary hosts.
+	Loaders     map[string]string
+	LoaderToken string
+	Webhooks    map[string]string
+
+	// MaxRetries and RetryOn are the default retry policy: a job failing
+	// with one of the RetryOn failure kinds is requeued up to MaxRetries
+	// times. A manifest's retry settings take precedence.
+	MaxRetries int
+	RetryOn    []string
 }
 
 func Default() Config {
@@ -58,75 +243,302 @@ func Default() Config {
 		ListenAddr:             defaultListenAddr,
 		AuthHeader:             defaultAuthHeader,
 		MaxUploadBytes:         defaultMaxUploadBytes,
+		UploadTTL:              defaultUploadTTL,
+		ChaosMaxDelay:          defaultChaosMaxDelay,
 		MaxExtractedFiles:      defaultMaxFiles,
 		MaxExtractedTotalBytes: defaultMaxExtractedTotal,
 		MaxExtractedFileBytes:  defaultMaxExtractedFile,
 		WorkerTimeout:          defaultWorkerTimeout,
+		SSEKeepalive:           defaultSSEKeepalive,
+		RateLimitBurst:         defaultRateLimitBurst,
 		RetentionDays:          defaultRetentionDays,
+		RetentionInterval:      defaultRetentionInterval,
+		RetentionGrace:         defaultRetentionGrace,
+		OrphanAction:           OrphanQuarantine,
+		MirrorInclude:          []string{"*.bit", "artifact_manifest.json"},
+		MirrorKeep:             defaultMirrorKeep,
+		RetryOn:                []string{"internal", "license"},
+		EnergyRAPL:             true,
+		LogLevel:               "info",
 		VivadoBin:              defaultVivadoBin,
+		Builder:                manifest.ToolchainVivado,
+		ContainerRuntime:       "docker",
 		DiscoveryEnabled:       defaultDiscoveryEnabled,
 		DiscoveryService:       defaultDiscoveryService,
 		DiscoveryDomain:        defaultDiscoveryDomain,
 		DiscoveryInstance:      defaultDiscoveryInstance,
+		MQTTTopicPrefix:        defaultMQTTTopicPrefix,
 	}
 }
 
 func FromEnv() (Config, error) {
+	return fromEnv(os.Getenv)
+}
+
+// fromEnv loads the config reading variables with getenv.
+func fromEnv(getenv func(string) string) (Config, error) {
 	cfg := Default()
-	cfg.ListenAddr = getEnv("SPADEFORGE_LISTEN_ADDR", cfg.ListenAddr)
-	cfg.BaseDir = strings.TrimSpace(os.Getenv("SPADEFORGE_BASE_DIR"))
-	cfg.Token = strings.TrimSpace(os.Getenv("SPADEFORGE_TOKEN"))
-	cfg.AuthHeader = getEnv("SPADEFORGE_AUTH_HEADER", cfg.AuthHeader)
-	cfg.Allowlist = parseCSV(os.Getenv("SPADEFORGE_ALLOWLIST"))
-	cfg.VivadoBin = getEnv("SPADEFORGE_VIVADO_BIN", cfg.VivadoBin)
-	cfg.PreserveWorkDir = parseBoolEnv(os.Getenv("SPADEFORGE_PRESERVE_WORK_DIR"))
-	cfg.DiscoveryEnabled = parseBoolEnvWithDefault(os.Getenv("SPADEFORGE_DISCOVERY_ENABLE"), cfg.DiscoveryEnabled)
-	cfg.DiscoveryService = getEnv("SPADEFORGE_DISCOVERY_SERVICE", cfg.DiscoveryService)
-	cfg.DiscoveryDomain = getEnv("SPADEFORGE_DISCOVERY_DOMAIN", cfg.DiscoveryDomain)
-	cfg.DiscoveryInstance = getEnv("SPADEFORGE_DISCOVERY_INSTANCE", cfg.DiscoveryInstance)
-
-	if v := strings.TrimSpace(os.Getenv("SPADEFORGE_MAX_UPLOAD_BYTES")); v != "" {
+	cfg.ListenAddr = getEnv(getenv, "SPADEFORGE_LISTEN_ADDR", cfg.ListenAddr)
+	cfg.BaseDir = strings.TrimSpace(getenv("SPADEFORGE_BASE_DIR"))
+	cfg.WorkRoot = strings.TrimSpace(getenv("SPADEFORGE_WORK_DIR"))
+	cfg.ArtifactsRoot = strings.TrimSpace(getenv("SPADEFORGE_ARTIFACTS_DIR"))
+	cfg.Token = strings.TrimSpace(getenv("SPADEFORGE_TOKEN"))
+	cfg.AuthHeader = getEnv(getenv, "SPADEFORGE_AUTH_HEADER", cfg.AuthHeader)
+	cfg.Allowlist = parseCSV(getenv("SPADEFORGE_ALLOWLIST"))
+	tokens, err := authz.ParseEntries(parseCSV(getenv("SPADEFORGE_TOKENS")))
+	if err != nil {
+		return Config{}, fmt.Errorf("parse SPADEFORGE_TOKENS: %w", err)
+	}
+	cfg.TokensFile = strings.TrimSpace(getenv("SPADEFORGE_TOKENS_FILE"))
+	if cfg.TokensFile != "" {
+		fromFile, err := authz.LoadFile(cfg.TokensFile)
+		if err != nil {
+			return Config{}, err
+		}
+		tokens = append(tokens, fromFile...)
+	}
+	cfg.Tokens = tokens
+	cfg.FeedPublic = parseBoolEnv(getenv("SPADEFORGE_FEED_PUBLIC"))
+	cfg.TLSCert = strings.TrimSpace(getenv("SPADEFORGE_TLS_CERT"))
+	cfg.TLSKey = strings.TrimSpace(getenv("SPADEFORGE_TLS_KEY"))
+	cfg.TLSClientCA = strings.TrimSpace(getenv("SPADEFORGE_TLS_CLIENT_CA"))
+	cfg.VivadoBin = getEnv(getenv, "SPADEFORGE_VIVADO_BIN", cfg.VivadoBin)
+	cfg.Builder = strings.ToLower(getEnv(getenv, "SPADEFORGE_BUILDER", cfg.Builder))
+	cfg.OSSBinDir = strings.TrimSpace(getenv("SPADEFORGE_OSS_BIN_DIR"))
+	cfg.ToolchainImage = strings.TrimSpace(getenv("SPADEFORGE_TOOLCHAIN_IMAGE"))
+	cfg.ContainerRuntime = getEnv(getenv, "SPADEFORGE_CONTAINER_RUNTIME", cfg.ContainerRuntime)
+	cfg.UseFakeBuilder = parseBoolEnv(getenv("SPADEFORGE_USE_FAKE_BUILDER"))
+	cfg.PreserveWorkDir = parseBoolEnv(getenv("SPADEFORGE_PRESERVE_WORK_DIR"))
+	cfg.BuildCache = parseBoolEnv(getenv("SPADEFORGE_BUILD_CACHE"))
+	cfg.RecordDir = strings.TrimSpace(getenv("SPADEFORGE_RECORD_DIR"))
+	cfg.EncryptionKeyFile = strings.TrimSpace(getenv("SPADEFORGE_ENCRYPTION_KEY_FILE"))
+	cfg.SecretsDir = strings.TrimSpace(getenv("SPADEFORGE_SECRETS_DIR"))
+	cfg.ReleaseURL = strings.TrimSpace(getenv("SPADEFORGE_RELEASE_URL"))
+	cfg.AccessLog = parseBoolEnv(getenv("SPADEFORGE_ACCESS_LOG"))
+	cfg.LogLevel = getEnv(getenv, "SPADEFORGE_LOG_LEVEL", cfg.LogLevel)
+	cfg.ArtifactInclude = parseCSV(getenv("SPADEFORGE_ARTIFACT_INCLUDE"))
+	cfg.ArtifactExclude = parseCSV(getenv("SPADEFORGE_ARTIFACT_EXCLUDE"))
+	cfg.BitstreamName = strings.TrimSpace(getenv("SPADEFORGE_BITSTREAM_NAME"))
+	cfg.MirrorDir = strings.TrimSpace(getenv("SPADEFORGE_MIRROR_DIR"))
+	if include := parseCSV(getenv("SPADEFORGE_MIRROR_INCLUDE")); len(include) > 0 {
+		cfg.MirrorInclude = include
+	}
+	suppress, err := manifest.ParseSuppressRules(parseCSV(getenv("SPADEFORGE_DIAGNOSTIC_SUPPRESS")))
+	if err != nil {
+		return Config{}, fmt.Errorf("parse SPADEFORGE_DIAGNOSTIC_SUPPRESS: %w", err)
+	}
+	cfg.DiagnosticSuppress = suppress
+	cfg.DiscoveryEnabled = parseBoolEnvWithDefault(getenv("SPADEFORGE_DISCOVERY_ENABLE"), cfg.DiscoveryEnabled)
+	cfg.DiscoveryService = getEnv(getenv, "SPADEFORGE_DISCOVERY_SERVICE", cfg.DiscoveryService)
+	cfg.DiscoveryDomain = getEnv(getenv, "SPADEFORGE_DISCOVERY_DOMAIN", cfg.DiscoveryDomain)
+	cfg.DiscoveryInstance = getEnv(getenv, "SPADEFORGE_DISCOVERY_INSTANCE", cfg.DiscoveryInstance)
+	cfg.MQTTURL = strings.TrimSpace(getenv("SPADEFORGE_MQTT_URL"))
+	cfg.MQTTTopicPrefix = strings.Trim(getEnv(getenv, "SPADEFORGE_MQTT_TOPIC_PREFIX", cfg.MQTTTopicPrefix), "/")
+	loaders, err := parseNamedURLs(parseCSV(getenv("SPADEFORGE_LOADERS")))
+	if err != nil {
+		return Config{}, fmt.Errorf("parse SPADEFORGE_LOADERS: %w", err)
+	}
+	cfg.Loaders = loaders
+	cfg.LoaderToken = strings.TrimSpace(getenv("SPADEFORGE_LOADER_TOKEN"))
+	webhooks, err := parseNamedURLs(parseCSV(getenv("SPADEFORGE_WEBHOOKS")))
+	if err != nil {
+		return Config{}, fmt.Errorf("parse SPADEFORGE_WEBHOOKS: %w", err)
+	}
+	cfg.Webhooks = webhooks
+
+	if v := strings.TrimSpace(getenv("SPADEFORGE_MAX_UPLOAD_BYTES")); v != "" {
 		n, err := strconv.ParseInt(v, 10, 64)
 		if err != nil {
 			return Config{}, fmt.Errorf("parse SPADEFORGE_MAX_UPLOAD_BYTES: %w", err)
 		}
 		cfg.MaxUploadBytes = n
 	}
-	if v := strings.TrimSpace(os.Getenv("SPADEFORGE_MAX_EXTRACTED_FILES")); v != "" {
+	if v := strings.TrimSpace(getenv("SPADEFORGE_UPLOAD_TTL")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse SPADEFORGE_UPLOAD_TTL: %w", err)
+		}
+		cfg.UploadTTL = d
+	}
+	if v := strings.TrimSpace(getenv("SPADEFORGE_WORK_MIN_FREE_BYTES")); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse SPADEFORGE_WORK_MIN_FREE_BYTES: %w", err)
+		}
+		cfg.WorkMinFreeBytes = n
+	}
+	if v := strings.TrimSpace(getenv("SPADEFORGE_MAX_EXTRACTED_FILES")); v != "" {
 		n, err := strconv.Atoi(v)
 		if err != nil {
 			return Config{}, fmt.Errorf("parse SPADEFORGE_MAX_EXTRACTED_FILES: %w", err)
 		}
 		cfg.MaxExtractedFiles = n
 	}
-	if v := strings.TrimSpace(os.Getenv("SPADEFORGE_MAX_EXTRACTED_TOTAL_BYTES")); v != "" {
+	if v := strings.TrimSpace(getenv("SPADEFORGE_MAX_EXTRACTED_TOTAL_BYTES")); v != "" {
 		n, err := strconv.ParseInt(v, 10, 64)
 		if err != nil {
 			return Config{}, fmt.Errorf("parse SPADEFORGE_MAX_EXTRACTED_TOTAL_BYTES: %w", err)
 		}
 		cfg.MaxExtractedTotalBytes = n
 	}
-	if v := strings.TrimSpace(os.Getenv("SPADEFORGE_MAX_EXTRACTED_FILE_BYTES")); v != "" {
+	if v := strings.TrimSpace(getenv("SPADEFORGE_MAX_EXTRACTED_FILE_BYTES")); v != "" {
 		n, err := strconv.ParseInt(v, 10, 64)
 		if err != nil {
 			return Config{}, fmt.Errorf("parse SPADEFORGE_MAX_EXTRACTED_FILE_BYTES: %w", err)
 		}
 		cfg.MaxExtractedFileBytes = n
 	}
-	if v := strings.TrimSpace(os.Getenv("SPADEFORGE_WORKER_TIMEOUT")); v != "" {
+	if v := strings.TrimSpace(getenv("SPADEFORGE_WORKER_TIMEOUT")); v != "" {
 		d, err := time.ParseDuration(v)
 		if err != nil {
 			return Config{}, fmt.Errorf("parse SPADEFORGE_WORKER_TIMEOUT: %w", err)
 		}
 		cfg.WorkerTimeout = d
 	}
-	if v := strings.TrimSpace(os.Getenv("SPADEFORGE_RETENTION_DAYS")); v != "" {
+	if v := strings.TrimSpace(getenv("SPADEFORGE_RATE_LIMIT")); v != "" {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse SPADEFORGE_RATE_LIMIT: %w", err)
+		}
+		cfg.RateLimit = n
+	}
+	if v := strings.TrimSpace(getenv("SPADEFORGE_RATE_LIMIT_BURST")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse SPADEFORGE_RATE_LIMIT_BURST: %w", err)
+		}
+		cfg.RateLimitBurst = n
+	}
+	if v := strings.TrimSpace(getenv("SPADEFORGE_SUBMIT_RATE_LIMIT")); v != "" {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse SPADEFORGE_SUBMIT_RATE_LIMIT: %w", err)
+		}
+		cfg.SubmitRateLimit = n
+	}
+	if v := strings.TrimSpace(getenv("SPADEFORGE_SUBMIT_RATE_BURST")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse SPADEFORGE_SUBMIT_RATE_BURST: %w", err)
+		}
+		cfg.SubmitRateBurst = n
+	}
+	if v := strings.TrimSpace(getenv("SPADEFORGE_MAX_ACTIVE_JOBS_PER_CLIENT")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse SPADEFORGE_MAX_ACTIVE_JOBS_PER_CLIENT: %w", err)
+		}
+		cfg.MaxActiveJobsPerClient = n
+	}
+	if v := strings.TrimSpace(getenv("SPADEFORGE_MAX_STORAGE_BYTES_PER_CLIENT")); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse SPADEFORGE_MAX_STORAGE_BYTES_PER_CLIENT: %w", err)
+		}
+		cfg.MaxStorageBytesPerClient = n
+	}
+	if v := strings.TrimSpace(getenv("SPADEFORGE_SSE_KEEPALIVE")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse SPADEFORGE_SSE_KEEPALIVE: %w", err)
+		}
+		cfg.SSEKeepalive = d
+	}
+	for key, rate := range map[string]*float64{
+		"SPADEFORGE_CHAOS_ERROR_RATE": &cfg.ChaosErrorRate,
+		"SPADEFORGE_CHAOS_SLOW_RATE":  &cfg.ChaosSlowRate,
+		"SPADEFORGE_CHAOS_DROP_RATE":  &cfg.ChaosDropRate,
+		"SPADEFORGE_CHAOS_CRASH_RATE": &cfg.ChaosCrashRate,
+	} {
+		if v := strings.TrimSpace(getenv(key)); v != "" {
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return Config{}, fmt.Errorf("parse %s: %w", key, err)
+			}
+			*rate = n
+		}
+	}
+	if v := strings.TrimSpace(getenv("SPADEFORGE_CHAOS_MAX_DELAY")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse SPADEFORGE_CHAOS_MAX_DELAY: %w", err)
+		}
+		cfg.ChaosMaxDelay = d
+	}
+	cfg.EnergyRAPL = parseBoolEnvWithDefault(getenv("SPADEFORGE_ENERGY_RAPL"), cfg.EnergyRAPL)
+	if v := strings.TrimSpace(getenv("SPADEFORGE_BUILD_WATTS")); v != "" {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse SPADEFORGE_BUILD_WATTS: %w", err)
+		}
+		cfg.BuildWatts = n
+	}
+	if v := strings.TrimSpace(getenv("SPADEFORGE_ENERGY_PRICE_PER_KWH")); v != "" {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse SPADEFORGE_ENERGY_PRICE_PER_KWH: %w", err)
+		}
+		cfg.EnergyPricePerKWh = n
+	}
+	weights, err := parseWeights(parseCSV(getenv("SPADEFORGE_SUBMITTER_WEIGHTS")))
+	if err != nil {
+		return Config{}, fmt.Errorf("parse SPADEFORGE_SUBMITTER_WEIGHTS: %w", err)
+	}
+	cfg.SubmitterWeights = weights
+	logModules, err := logx.ParseModules(parseCSV(getenv("SPADEFORGE_LOG_MODULES")))
+	if err != nil {
+		return Config{}, fmt.Errorf("parse SPADEFORGE_LOG_MODULES: %w", err)
+	}
+	cfg.LogModules = logModules
+	if v := strings.TrimSpace(getenv("SPADEFORGE_MIRROR_KEEP")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse SPADEFORGE_MIRROR_KEEP: %w", err)
+		}
+		cfg.MirrorKeep = n
+	}
+	if v := strings.TrimSpace(getenv("SPADEFORGE_MAX_RETRIES")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse SPADEFORGE_MAX_RETRIES: %w", err)
+		}
+		cfg.MaxRetries = n
+	}
+	if retryOn := parseCSV(strings.ToLower(getenv("SPADEFORGE_RETRY_ON"))); len(retryOn) > 0 {
+		cfg.RetryOn = retryOn
+	}
+	if v := strings.TrimSpace(getenv("SPADEFORGE_RETENTION_DAYS")); v != "" {
 		n, err := strconv.Atoi(v)
 		if err != nil {
 			return Config{}, fmt.Errorf("parse SPADEFORGE_RETENTION_DAYS: %w", err)
 		}
 		cfg.RetentionDays = n
 	}
+	if v := strings.TrimSpace(getenv("SPADEFORGE_RETENTION_MAX_BYTES")); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse SPADEFORGE_RETENTION_MAX_BYTES: %w", err)
+		}
+		cfg.RetentionMaxBytes = n
+	}
+	if v := strings.TrimSpace(getenv("SPADEFORGE_RETENTION_INTERVAL")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse SPADEFORGE_RETENTION_INTERVAL: %w", err)
+		}
+		cfg.RetentionInterval = d
+	}
+	if v := strings.TrimSpace(getenv("SPADEFORGE_RETENTION_GRACE")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse SPADEFORGE_RETENTION_GRACE: %w", err)
+		}
+		cfg.RetentionGrace = d
+	}
+	cfg.ExpiryWebhook = strings.TrimSpace(getenv("SPADEFORGE_EXPIRY_WEBHOOK"))
+	if v := strings.ToLower(strings.TrimSpace(getenv("SPADEFORGE_ORPHAN_ACTION"))); v != "" {
+		cfg.OrphanAction = v
+	}
 
 	return cfg, cfg.Validate()
 }
@@ -135,15 +547,37 @@ func (c Config) Validate() error {
 	if strings.TrimSpace(c.BaseDir) == "" {
 		return errors.New("base dir is required")
 	}
+	if err := validateRoots(map[string]string{
+		"jobs dir":      c.JobsDir(),
+		"work dir":      c.WorkDir(),
+		"artifacts dir": c.ArtifactsDir(),
+	}); err != nil {
+		return err
+	}
 	if strings.TrimSpace(c.ListenAddr) == "" {
 		return errors.New("listen addr is required")
 	}
 	if strings.TrimSpace(c.AuthHeader) == "" {
 		return errors.New("auth header is required")
 	}
+	if err := authz.Validate(c.Tokens, c.Token); err != nil {
+		return err
+	}
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return errors.New("tls cert and tls key must be set together")
+	}
+	if c.TLSClientCA != "" && c.TLSCert == "" {
+		return errors.New("tls client ca needs tls cert and key")
+	}
+	if c.WorkMinFreeBytes < 0 {
+		return errors.New("work min free bytes must be >= 0")
+	}
 	if c.MaxUploadBytes <= 0 {
 		return errors.New("max upload bytes must be > 0")
 	}
+	if c.UploadTTL <= 0 {
+		return errors.New("upload ttl must be > 0")
+	}
 	if c.MaxExtractedFiles <= 0 {
 		return errors.New("max extracted files must be > 0")
 	}
@@ -156,12 +590,66 @@ func (c Config) Validate() error {
 	if c.WorkerTimeout <= 0 {
 		return errors.New("worker timeout must be > 0")
 	}
+	for name, rate := range map[string]float64{
+		"chaos error rate": c.ChaosErrorRate,
+		"chaos slow rate":  c.ChaosSlowRate,
+		"chaos drop rate":  c.ChaosDropRate,
+		"chaos crash rate": c.ChaosCrashRate,
+	} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("%s must be between 0 and 1", name)
+		}
+	}
+	if c.ChaosMaxDelay < 0 {
+		return errors.New("chaos max delay must be >= 0")
+	}
+	if c.ChaosCrashRate > 0 && !c.UseFakeBuilder {
+		return errors.New("chaos crash rate needs the fake builder")
+	}
+	if c.SSEKeepalive <= 0 {
+		return errors.New("sse keepalive must be > 0")
+	}
+	// Clients treat a stream as dead after sse.DefaultIdleTimeout without
+	// data, so keepalives must arrive sooner.
+	if c.SSEKeepalive >= sse.DefaultIdleTimeout {
+		return fmt.Errorf("sse keepalive must be below the clients' %s idle timeout", sse.DefaultIdleTimeout)
+	}
 	if c.RetentionDays < 0 {
 		return errors.New("retention days must be >= 0")
 	}
+	if c.RetentionMaxBytes < 0 {
+		return errors.New("retention max bytes must be >= 0")
+	}
+	if c.RetentionInterval <= 0 {
+		return errors.New("retention interval must be > 0")
+	}
+	if c.RetentionGrace < 0 {
+		return errors.New("retention grace must be >= 0")
+	}
+	switch c.OrphanAction {
+	case OrphanQuarantine, OrphanRemove, OrphanOff:
+	default:
+		return fmt.Errorf("orphan action must be quarantine, remove or off, got %q", c.OrphanAction)
+	}
+	if c.ExpiryWebhook != "" {
+		if _, ok := c.Webhooks[c.ExpiryWebhook]; !ok {
+			return fmt.Errorf("expiry webhook %q is not in SPADEFORGE_WEBHOOKS", c.ExpiryWebhook)
+		}
+	}
 	if strings.TrimSpace(c.VivadoBin) == "" {
 		return errors.New("vivado bin is required")
 	}
+	if c.Builder != manifest.ToolchainVivado && c.Builder != manifest.ToolchainYosys {
+		return fmt.Errorf("builder %q must be %q or %q", c.Builder, manifest.ToolchainVivado, manifest.ToolchainYosys)
+	}
+	if c.ToolchainImage != "" {
+		if strings.ContainsAny(c.ToolchainImage, " \t") {
+			return fmt.Errorf("toolchain image %q must be a single image reference", c.ToolchainImage)
+		}
+		if strings.TrimSpace(c.ContainerRuntime) == "" {
+			return errors.New("container runtime is required when a toolchain image is set")
+		}
+	}
 	if c.DiscoveryEnabled {
 		if strings.TrimSpace(c.DiscoveryService) == "" {
 			return errors.New("discovery service is required when discovery is enabled")
@@ -170,11 +658,83 @@ func (c Config) Validate() error {
 			return errors.New("discovery domain is required when discovery is enabled")
 		}
 	}
+	if c.MQTTURL != "" {
+		if _, err := mqtt.NewPublisher(c.MQTTURL, ""); err != nil {
+			return err
+		}
+		if c.MQTTTopicPrefix == "" || strings.ContainsAny(c.MQTTTopicPrefix, "+#") {
+			return fmt.Errorf("mqtt topic prefix %q must be non-empty and free of + and # wildcards", c.MQTTTopicPrefix)
+		}
+	}
+	if c.RateLimit < 0 {
+		return errors.New("rate limit must be >= 0")
+	}
+	if c.RateLimit > 0 && c.RateLimitBurst < 1 {
+		return errors.New("rate limit burst must be >= 1")
+	}
+	if c.SubmitRateLimit < 0 || c.SubmitRateBurst < 0 {
+		return errors.New("submit rate limit and burst must be >= 0")
+	}
+	if c.MaxActiveJobsPerClient < 0 || c.MaxStorageBytesPerClient < 0 {
+		return errors.New("per-client quotas must be >= 0")
+	}
 	for _, entry := range c.Allowlist {
 		if err := validateAllowEntry(entry); err != nil {
 			return err
 		}
 	}
+	for _, pattern := range c.ArtifactInclude {
+		if err := pathglob.Validate(pattern); err != nil {
+			return fmt.Errorf("artifact include: %w", err)
+		}
+	}
+	for _, pattern := range c.ArtifactExclude {
+		if err := pathglob.Validate(pattern); err != nil {
+			return fmt.Errorf("artifact exclude: %w", err)
+		}
+	}
+	if c.BuildWatts < 0 {
+		return errors.New("build watts must be >= 0")
+	}
+	if c.EnergyPricePerKWh < 0 {
+		return errors.New("energy price per kWh must be >= 0")
+	}
+	if _, err := logx.ParseLevel(c.LogLevel); err != nil {
+		return err
+	}
+	for module, level := range c.LogModules {
+		if _, err := logx.ParseModules([]string{module + "=" + level}); err != nil {
+			return err
+		}
+	}
+	for submitter, w := range c.SubmitterWeights {
+		if w < 1 {
+			return fmt.Errorf("submitter weight for %q must be >= 1", submitter)
+		}
+	}
+	if c.MaxRetries < 0 || c.MaxRetries > manifest.MaxRetriesLimit {
+		return fmt.Errorf("max retries must be between 0 and %d", manifest.MaxRetriesLimit)
+	}
+	if c.MirrorDir != "" {
+		if c.MirrorKeep < 1 {
+			return errors.New("mirror keep must be >= 1")
+		}
+		for _, pattern := range c.MirrorInclude {
+			if err := pathglob.Validate(pattern); err != nil {
+				return fmt.Errorf("mirror include: %w", err)
+			}
+		}
+	}
+	if c.BitstreamName != "" {
+		if err := artifactname.Validate(c.BitstreamName); err != nil {
+			return fmt.Errorf("bitstream name: %w", err)
+		}
+	}
+	for _, rule := range c.DiagnosticSuppress {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("diagnostic suppress: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -182,20 +742,63 @@ func (c Config) JobsDir() string {
 	return filepath.Join(c.BaseDir, "jobs")
 }
 
+// UploadsDir holds resumable uploads until they are finalized into jobs.
+func (c Config) UploadsDir() string {
+	return filepath.Join(c.BaseDir, "uploads")
+}
+
+// ClientDefaultsPath is the file GET /v1/me reads client defaults from.
+func (c Config) ClientDefaultsPath() string {
+	return filepath.Join(c.BaseDir, "client_defaults.json")
+}
+
 func (c Config) WorkDir() string {
+	if c.WorkRoot != "" {
+		return c.WorkRoot
+	}
 	return filepath.Join(c.BaseDir, "work")
 }
 
 func (c Config) ArtifactsDir() string {
+	if c.ArtifactsRoot != "" {
+		return c.ArtifactsRoot
+	}
 	return filepath.Join(c.BaseDir, "artifacts")
 }
 
+// validateRoots rejects storage roots that coincide or nest, since each
+// tree is listed and cleaned by job ID independently.
+func validateRoots(roots map[string]string) error {
+	names := make([]string, 0, len(roots))
+	for name := range roots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, a := range names {
+		for _, b := range names[i+1:] {
+			if pathWithin(roots[a], roots[b]) || pathWithin(roots[b], roots[a]) {
+				return fmt.Errorf("%s %q and %s %q must not overlap", a, roots[a], b, roots[b])
+			}
+		}
+	}
+	return nil
+}
+
+// pathWithin reports whether p is dir or below it.
+func pathWithin(p, dir string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(p))
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
 func (c Config) AllowlistEnabled() bool {
 	return len(c.Allowlist) > 0
 }
 
-func getEnv(k, fallback string) string {
-	if v := strings.TrimSpace(os.Getenv(k)); v != "" {
+func getEnv(getenv func(string) string, k, fallback string) string {
+	if v := strings.TrimSpace(getenv(k)); v != "" {
 		return v
 	}
 	return fallback
@@ -216,6 +819,47 @@ func parseCSV(v string) []string {
 	return out
 }
 
+// parseWeights parses "name=weight" entries.
+func parseWeights(entries []string) (map[string]int, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]int, len(entries))
+	for _, entry := range entries {
+		name, raw, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("entry %q must be name=weight", entry)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("entry %q: %w", entry, err)
+		}
+		out[strings.TrimSpace(name)] = n
+	}
+	return out, nil
+}
+
+// parseNamedURLs parses "name=http[s]://..." entries.
+func parseNamedURLs(entries []string) (map[string]string, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		name, raw, ok := strings.Cut(entry, "=")
+		name, raw = strings.TrimSpace(name), strings.TrimSpace(raw)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("entry %q must be name=url", entry)
+		}
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("entry %q: url must be http:// or https://", entry)
+		}
+		out[name] = raw
+	}
+	return out, nil
+}
+
 func parseBoolEnv(v string) bool {
 	switch strings.ToLower(strings.TrimSpace(v)) {
 	case "1", "true", "yes", "on":
@@ -5,13 +5,24 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/manifest"
 )
 
+// BuildReport parses Vivado logs; see BuildReportWith for other tools.
 func BuildReport(logs map[string][]byte) job.DiagnosticsReport {
+	parsers, _ := Parsers(DefaultTool)
+	return BuildReportWith(logs, parsers)
+}
+
+// BuildReportWith parses vivado.log and console.log, then any other logs in
+// name order. Each line goes to the parsers in turn and the first match
+// wins; repeated diagnostics are reported once.
+func BuildReportWith(logs map[string][]byte, parsers []LineParser) job.DiagnosticsReport {
 	report := job.DiagnosticsReport{
 		Schema:      1,
 		GeneratedAt: time.Now().UTC(),
@@ -19,18 +30,15 @@ func BuildReport(logs map[string][]byte) job.DiagnosticsReport {
 	}
 	seen := map[string]struct{}{}
 
-	for _, source := range []string{"vivado.log", "console.log"} {
-		raw, ok := logs[source]
-		if !ok {
-			continue
-		}
+	for _, source := range logSources(logs) {
+		raw := logs[source]
 		reader := bufio.NewReader(bytes.NewReader(raw))
 		for {
 			line, err := reader.ReadString('\n')
 			if len(line) > 0 {
 				line = strings.TrimRight(line, "\r\n")
 			}
-			d, ok := parseLine(line, source)
+			d, ok := parseWith(parsers, line, source)
 			if !ok {
 				if err == io.EOF {
 					break
@@ -61,6 +69,35 @@ func BuildReport(logs map[string][]byte) job.DiagnosticsReport {
 	return report
 }
 
+func logSources(logs map[string][]byte) []string {
+	sources := make([]string, 0, len(logs))
+	for _, name := range []string{"vivado.log", "console.log"} {
+		if _, ok := logs[name]; ok {
+			sources = append(sources, name)
+		}
+	}
+	rest := make([]string, 0, len(logs))
+	for name := range logs {
+		if name != "vivado.log" && name != "console.log" {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return append(sources, rest...)
+}
+
+func parseWith(parsers []LineParser, line, source string) (job.Diagnostic, bool) {
+	if strings.TrimSpace(line) == "" {
+		return job.Diagnostic{}, false
+	}
+	for _, p := range parsers {
+		if d, ok := p(line, source); ok {
+			return d, true
+		}
+	}
+	return job.Diagnostic{}, false
+}
+
 func InferFailure(report job.DiagnosticsReport, fallbackMessage string, buildErr error) (string, string) {
 	if d, ok := firstError(report); ok {
 		kind := classify(d)
@@ -76,9 +113,27 @@ func InferFailure(report job.DiagnosticsReport, fallbackMessage string, buildErr
 	return "internal", msg
 }
 
+// ApplySuppressions marks diagnostics matched by any rule as suppressed and
+// recounts the report so they no longer inflate error and warning counts.
+func ApplySuppressions(report *job.DiagnosticsReport, rules []manifest.SuppressRule) {
+	if len(rules) == 0 {
+		return
+	}
+	for i := range report.Diagnostics {
+		d := &report.Diagnostics[i]
+		for _, rule := range rules {
+			if rule.Matches(d.Code, d.File) {
+				d.Suppressed = true
+				break
+			}
+		}
+	}
+	report.Recount()
+}
+
 func firstError(report job.DiagnosticsReport) (job.Diagnostic, bool) {
 	for _, d := range report.Diagnostics {
-		if d.Severity == job.SeverityError {
+		if d.Severity == job.SeverityError && !d.Suppressed {
 			return d, true
 		}
 	}
@@ -89,12 +144,18 @@ func classify(d job.Diagnostic) string {
 	lower := strings.ToLower(d.Message + " " + d.Code + " " + d.Tool + " " + d.File)
 	tool := strings.ToLower(strings.TrimSpace(d.Tool))
 	switch {
+	case strings.Contains(lower, "license") || strings.EqualFold(strings.TrimSpace(d.Code), "Common 17-69"):
+		return "license"
 	case strings.Contains(lower, "syntax"):
 		return "syntax"
 	case strings.Contains(lower, "constraint") || strings.Contains(lower, ".xdc") || strings.Contains(lower, "nstd") || strings.Contains(lower, "ucio") || strings.HasPrefix(tool, "drc"):
 		return "constraints"
-	case strings.Contains(lower, "timing"):
+	case strings.Contains(lower, "timing") || strings.Contains(lower, "max frequency"):
 		return "timing"
+	case tool == "yosys":
+		return "synthesis"
+	case tool == "nextpnr":
+		return "implementation"
 	case strings.HasPrefix(tool, "synth") || strings.Contains(lower, "synthesis failed") || strings.Contains(lower, "module '") && strings.Contains(lower, "not found"):
 		return "synthesis"
 	case strings.HasPrefix(tool, "place") || strings.HasPrefix(tool, "route") || strings.HasPrefix(tool, "vivado") || strings.Contains(lower, "bitstream"):
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/manifest"
 )
 
 func TestBuildReport_ParsesAndDeduplicatesAcrossLogs(t *testing.T) {
@@ -110,6 +111,16 @@ func TestInferFailure_ClassifiesLiveSampleFormats(t *testing.T) {
 	}
 }
 
+func TestInferFailure_ClassifiesLicenseErrors(t *testing.T) {
+	report := BuildReport(map[string][]byte{
+		"vivado.log": []byte("ERROR: [Common 17-69] Command failed: This design contains one or more cells for which bitstream generation is not permitted:\n"),
+	})
+	kind, summary := InferFailure(report, "vivado invocation failed", errors.New("boom"))
+	if kind != "license" {
+		t.Fatalf("expected license kind, got %q (summary=%q)", kind, summary)
+	}
+}
+
 func TestInferFailure_FallbacksWhenNoDiagnostics(t *testing.T) {
 	kind, summary := InferFailure(job.DiagnosticsReport{}, "vivado invocation failed", errors.New("boom"))
 	if kind != "internal" {
@@ -146,3 +157,27 @@ func fixture(t *testing.T, name string) []byte {
 	}
 	return raw
 }
+
+func TestApplySuppressions_FlagsAndRecounts(t *testing.T) {
+	report := BuildReport(map[string][]byte{
+		"vivado.log": []byte(strings.Join([]string{
+			"WARNING: [Synth 8-3331] design top has unconnected port dbg [/w/src/hdl/debug/ila.sv:3]",
+			"WARNING: [Synth 8-7129] Port led[3] is unconnected [/w/src/hdl/top.sv:9]",
+			"ERROR: [Synth 8-2716] syntax error near 'x' [/w/src/hdl/top.sv:12]",
+		}, "\n")),
+	})
+	ApplySuppressions(&report, []manifest.SuppressRule{
+		{Code: "Synth 8-3331", File: "hdl/debug/*.sv"},
+		{Code: "Synth 8-2716"},
+	})
+	if report.WarningCount != 1 || report.ErrorCount != 0 || report.SuppressedCount != 2 {
+		t.Fatalf("unexpected counts: errors=%d warnings=%d suppressed=%d", report.ErrorCount, report.WarningCount, report.SuppressedCount)
+	}
+	if !report.Diagnostics[0].Suppressed || report.Diagnostics[1].Suppressed {
+		t.Fatalf("unexpected suppressed flags: %+v", report.Diagnostics)
+	}
+	kind, _ := InferFailure(report, "build failed", errors.New("exit 1"))
+	if kind != "internal" {
+		t.Fatalf("suppressed errors should not drive classification, got %q", kind)
+	}
+}
@@ -0,0 +1,105 @@
+package diagnostics
+
+import (
+	"path"
+	"strings"
+
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+// hintRule attaches a hint to diagnostics whose code matches pattern, a
+// case-insensitive path.Match glob.
+type hintRule struct {
+	pattern string
+	hint    job.DiagnosticHint
+}
+
+var hintRules = []hintRule{
+	{
+		pattern: "Synth 8-2716",
+		hint: job.DiagnosticHint{
+			Explanation: "The HDL parser hit a syntax error; the real mistake is often on the line before the reported one.",
+			NextSteps: []string{
+				"look for a missing semicolon, unbalanced begin/end or a stray character just above the reported line",
+				"check the file is listed with the right language (SystemVerilog syntax in a .v file is rejected)",
+				"run a local lint (e.g. verilator --lint-only) for a faster edit-check loop",
+			},
+		},
+	},
+	{
+		pattern: "Common 17-69",
+		hint: job.DiagnosticHint{
+			Explanation: "Vivado could not check out a license for the part or feature in use.",
+			NextSteps: []string{
+				"confirm the part is covered by the installed license, or switch to a WebPACK part",
+				"run `spadeforge doctor` on the server to check XILINXD_LICENSE_FILE and license server reachability",
+			},
+		},
+	},
+	{
+		pattern: "Place 30-58",
+		hint: job.DiagnosticHint{
+			Explanation: "IO placement is infeasible: more top-level ports need pins than the constraints and package allow.",
+			NextSteps: []string{
+				"give every top-level port a PACKAGE_PIN in the .xdc, or remove ports the board does not use",
+				"check that no two ports are constrained to the same pin and that the part/package matches the board",
+				"make sure IOSTANDARDs within a bank share a compatible VCCO",
+			},
+		},
+	},
+	{
+		pattern: "DRC NSTD-*",
+		hint: job.DiagnosticHint{
+			Explanation: "Some ports have no IOSTANDARD, so Vivado refuses to write a bitstream that could damage the board.",
+			NextSteps: []string{
+				"add set_property IOSTANDARD LVCMOS33 [get_ports ...] (or the board's standard) for the listed ports",
+				"check port names in the .xdc match the top module exactly, including bus indices",
+			},
+		},
+	},
+	{
+		pattern: "DRC UCIO-*",
+		hint: job.DiagnosticHint{
+			Explanation: "Some ports have no pin location, so the bitstream would drive arbitrary pins.",
+			NextSteps: []string{
+				"add set_property PACKAGE_PIN <pin> [get_ports ...] for the listed ports",
+				"remove unused ports from the top module, or check the .xdc is included in the manifest constraints",
+			},
+		},
+	},
+	{
+		pattern: "missing-top",
+		hint: job.DiagnosticHint{
+			Explanation: "The manifest's top module is not declared in any of the bundled sources.",
+			NextSteps: []string{
+				"fix the top name in the manifest (see the candidates) or add the file that declares it to sources",
+			},
+		},
+	},
+}
+
+// HintFor returns the hint for d's code, if any.
+func HintFor(d job.Diagnostic) (job.DiagnosticHint, bool) {
+	code := strings.ToLower(strings.TrimSpace(d.Code))
+	if code == "" {
+		return job.DiagnosticHint{}, false
+	}
+	for _, r := range hintRules {
+		if ok, _ := path.Match(strings.ToLower(r.pattern), code); ok {
+			return r.hint, true
+		}
+	}
+	return job.DiagnosticHint{}, false
+}
+
+// AttachHints sets Hint on every diagnostic with a known code.
+func AttachHints(report *job.DiagnosticsReport) {
+	for i := range report.Diagnostics {
+		d := &report.Diagnostics[i]
+		if hint, ok := HintFor(*d); ok {
+			h := hint
+			h.NextSteps = append([]string(nil), hint.NextSteps...)
+			d.Hint = &h
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package diagnostics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAttachHints_KnownCodes(t *testing.T) {
+	report := BuildReport(map[string][]byte{
+		"vivado.log": []byte(strings.Join([]string{
+			"ERROR: [Synth 8-2716] syntax error near 'endmodule' [/w/src/hdl/top.sv:12]",
+			"ERROR: [Common 17-69] Command failed: This design contains one or more cells for which bitstream generation is not permitted",
+			"ERROR: [Place 30-58] IO placement is infeasible. Number of unplaced terminals (1) is greater than number of available sites (0).",
+			"ERROR: [DRC NSTD-1] Unspecified I/O Standard: 1 out of 9 logical ports use I/O standard (IOSTANDARD) value 'DEFAULT'",
+			"ERROR: [DRC UCIO-1] Unconstrained Logical Port: 1 out of 9 logical ports have no user assigned specific location constraint (LOC).",
+			"WARNING: [Synth 8-7129] Port led[3] in module top is either unconnected or has no load",
+		}, "\n")),
+	})
+	AttachHints(&report)
+	if len(report.Diagnostics) != 6 {
+		t.Fatalf("expected 6 diagnostics, got %d", len(report.Diagnostics))
+	}
+	for _, d := range report.Diagnostics[:5] {
+		if d.Hint == nil || d.Hint.Explanation == "" || len(d.Hint.NextSteps) == 0 {
+			t.Fatalf("expected hint for %s, got %+v", d.Code, d.Hint)
+		}
+	}
+	if report.Diagnostics[5].Hint != nil {
+		t.Fatalf("unexpected hint for %s", report.Diagnostics[5].Code)
+	}
+
+	report.Diagnostics[3].Hint.NextSteps[0] = "changed"
+	if hint, _ := HintFor(report.Diagnostics[3]); hint.NextSteps[0] == "changed" {
+		t.Fatalf("attached hint aliases the rule table")
+	}
+}
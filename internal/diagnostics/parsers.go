@@ -0,0 +1,139 @@
+package diagnostics
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+// yosysLocated matches frontend messages such as
+// "top.v:12: ERROR: syntax error, unexpected TOK_ID".
+var yosysLocated = regexp.MustCompile(`^(.+?):(\d+): (ERROR|Warning): (.*)$`)
+
+func parseYosysLine(rawLine, source string) (job.Diagnostic, bool) {
+	line := strings.TrimSpace(rawLine)
+	if m := yosysLocated.FindStringSubmatch(line); m != nil {
+		lineNo, _ := parseInt(m[2])
+		return job.Diagnostic{
+			Severity: yosysSeverity(m[3]),
+			Tool:     "yosys",
+			Message:  strings.TrimSpace(m[4]),
+			File:     m[1],
+			Line:     lineNo,
+			Source:   source,
+			Raw:      line,
+		}, true
+	}
+	for _, prefix := range []string{"ERROR:", "Warning:"} {
+		if rest, ok := strings.CutPrefix(line, prefix); ok {
+			return job.Diagnostic{
+				Severity: yosysSeverity(strings.TrimSuffix(prefix, ":")),
+				Tool:     "yosys",
+				Message:  strings.TrimSpace(rest),
+				Source:   source,
+				Raw:      line,
+			}, true
+		}
+	}
+	return job.Diagnostic{}, false
+}
+
+func yosysSeverity(level string) job.DiagnosticSeverity {
+	if level == "ERROR" {
+		return job.SeverityError
+	}
+	return job.SeverityWarning
+}
+
+// nextpnr prefixes messages with "ERROR:", "Warning:" or "Info:"; Info
+// lines are mostly progress chatter, so only errors and warnings count.
+func parseNextpnrLine(rawLine, source string) (job.Diagnostic, bool) {
+	line := strings.TrimSpace(rawLine)
+	var severity job.DiagnosticSeverity
+	var rest string
+	switch {
+	case strings.HasPrefix(line, "ERROR:"):
+		severity, rest = job.SeverityError, strings.TrimPrefix(line, "ERROR:")
+	case strings.HasPrefix(line, "Warning:"):
+		severity, rest = job.SeverityWarning, strings.TrimPrefix(line, "Warning:")
+	default:
+		return job.Diagnostic{}, false
+	}
+	return job.Diagnostic{
+		Severity: severity,
+		Tool:     "nextpnr",
+		Message:  strings.TrimSpace(rest),
+		Source:   source,
+		Raw:      line,
+	}, true
+}
+
+// quartusLine matches "Error (10161): Verilog HDL error at top.v(12): ..."
+// and the Warning/Critical Warning/Info variants.
+var (
+	quartusLine     = regexp.MustCompile(`^(Error|Critical Warning|Warning|Info) \((\d+)\): (.*)$`)
+	quartusLocation = regexp.MustCompile(`\s*File: (.+?) Line: (\d+)\s*$`)
+)
+
+func parseQuartusLine(rawLine, source string) (job.Diagnostic, bool) {
+	line := strings.TrimSpace(rawLine)
+	m := quartusLine.FindStringSubmatch(line)
+	if m == nil {
+		return job.Diagnostic{}, false
+	}
+	d := job.Diagnostic{
+		Tool:    "quartus",
+		Code:    m[2],
+		Message: m[3],
+		Source:  source,
+		Raw:     line,
+	}
+	switch m[1] {
+	case "Error":
+		d.Severity = job.SeverityError
+	case "Info":
+		d.Severity = job.SeverityInfo
+	default:
+		d.Severity = job.SeverityWarning
+	}
+	if loc := quartusLocation.FindStringSubmatchIndex(d.Message); loc != nil {
+		d.File = d.Message[loc[2]:loc[3]]
+		d.Line, _ = parseInt(d.Message[loc[4]:loc[5]])
+		d.Message = d.Message[:loc[0]]
+	}
+	return d, true
+}
+
+// openFPGALoader has no fixed message format; errors usually start with
+// "error" or report a failed step.
+func parseOpenFPGALoaderLine(rawLine, source string) (job.Diagnostic, bool) {
+	line := strings.TrimSpace(rawLine)
+	lower := strings.ToLower(line)
+	d := job.Diagnostic{
+		Tool:    "openFPGALoader",
+		Message: line,
+		Source:  source,
+		Raw:     line,
+	}
+	switch {
+	case strings.HasPrefix(lower, "error"):
+		d.Severity = job.SeverityError
+		d.Message = trimLevelPrefix(line)
+	case strings.HasPrefix(lower, "warning"):
+		d.Severity = job.SeverityWarning
+		d.Message = trimLevelPrefix(line)
+	case strings.Contains(lower, " failed"), strings.HasPrefix(lower, "unable to "):
+		d.Severity = job.SeverityError
+	default:
+		return job.Diagnostic{}, false
+	}
+	return d, true
+}
+
+func trimLevelPrefix(line string) string {
+	if _, rest, ok := strings.Cut(line, ":"); ok && strings.TrimSpace(rest) != "" {
+		return strings.TrimSpace(rest)
+	}
+	return line
+}
@@ -0,0 +1,113 @@
+package diagnostics
+
+import (
+	"testing"
+
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+func TestToolParsers(t *testing.T) {
+	cases := []struct {
+		name   string
+		parse  LineParser
+		line   string
+		want   job.Diagnostic
+		noDiag bool
+	}{
+		{
+			name:  "yosys located",
+			parse: parseYosysLine,
+			line:  "hdl/top.v:7: ERROR: syntax error, unexpected TOK_ENDMODULE",
+			want:  job.Diagnostic{Severity: job.SeverityError, Tool: "yosys", Message: "syntax error, unexpected TOK_ENDMODULE", File: "hdl/top.v", Line: 7},
+		},
+		{
+			name:  "yosys plain",
+			parse: parseYosysLine,
+			line:  "ERROR: Module `\\uart_tx' referenced in module `\\top' in cell `\\u_tx' is not part of the design.",
+			want:  job.Diagnostic{Severity: job.SeverityError, Tool: "yosys", Message: "Module `\\uart_tx' referenced in module `\\top' in cell `\\u_tx' is not part of the design."},
+		},
+		{
+			name:   "yosys progress",
+			parse:  parseYosysLine,
+			line:   "2.3. Executing PROC_CLEAN pass (remove empty switches from decision trees).",
+			noDiag: true,
+		},
+		{
+			name:  "nextpnr warning",
+			parse: parseNextpnrLine,
+			line:  "Warning: Max frequency for clock '$glbnet$clk': 41.32 MHz (FAIL at 48.00 MHz)",
+			want:  job.Diagnostic{Severity: job.SeverityWarning, Tool: "nextpnr", Message: "Max frequency for clock '$glbnet$clk': 41.32 MHz (FAIL at 48.00 MHz)"},
+		},
+		{
+			name:   "nextpnr info",
+			parse:  parseNextpnrLine,
+			line:   "Info: Routing globals...",
+			noDiag: true,
+		},
+		{
+			name:  "quartus error with location",
+			parse: parseQuartusLine,
+			line:  `Error (10161): Verilog HDL error at top.v(12): object "ledz" is not declared. Verify the object name is correct. File: /work/hdl/top.v Line: 12`,
+			want:  job.Diagnostic{Severity: job.SeverityError, Tool: "quartus", Code: "10161", Message: `Verilog HDL error at top.v(12): object "ledz" is not declared. Verify the object name is correct.`, File: "/work/hdl/top.v", Line: 12},
+		},
+		{
+			name:  "quartus critical warning",
+			parse: parseQuartusLine,
+			line:  "Critical Warning (332012): Synopsys Design Constraints File file not found: 'blinky.sdc'.",
+			want:  job.Diagnostic{Severity: job.SeverityWarning, Tool: "quartus", Code: "332012", Message: "Synopsys Design Constraints File file not found: 'blinky.sdc'."},
+		},
+		{
+			name:  "openFPGALoader error",
+			parse: parseOpenFPGALoaderLine,
+			line:  "Error: no device found",
+			want:  job.Diagnostic{Severity: job.SeverityError, Tool: "openFPGALoader", Message: "no device found"},
+		},
+		{
+			name:  "openFPGALoader failed step",
+			parse: parseOpenFPGALoaderLine,
+			line:  "JTAG init failed with: unable to open ftdi device",
+			want:  job.Diagnostic{Severity: job.SeverityError, Tool: "openFPGALoader", Message: "JTAG init failed with: unable to open ftdi device"},
+		},
+		{
+			name:   "openFPGALoader progress",
+			parse:  parseOpenFPGALoaderLine,
+			line:   "Load SRAM: [==================================================] 100.00%",
+			noDiag: true,
+		},
+	}
+	for _, tc := range cases {
+		d, ok := tc.parse(tc.line, "console.log")
+		if tc.noDiag {
+			if ok {
+				t.Fatalf("%s: unexpected diagnostic %+v", tc.name, d)
+			}
+			continue
+		}
+		if !ok {
+			t.Fatalf("%s: no diagnostic", tc.name)
+		}
+		tc.want.Source = "console.log"
+		tc.want.Raw = tc.line
+		if d != tc.want {
+			t.Fatalf("%s:\n got %+v\nwant %+v", tc.name, d, tc.want)
+		}
+	}
+}
+
+func TestInferFailure_ClassifiesOpenToolchainErrors(t *testing.T) {
+	parsers, err := Parsers("yosys", "nextpnr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := BuildReportWith(map[string][]byte{"console.log": []byte("ERROR: Unable to place cell 'u_pll', no BELs remaining to implement cell type 'EHXPLLL'\n")}, parsers)
+	if kind, _ := InferFailure(report, "", nil); kind != "synthesis" {
+		// The yosys parser claims bare ERROR: lines, so this is reported as
+		// a yosys (synthesis) error when both tools share one log.
+		t.Fatalf("kind = %q", kind)
+	}
+	pnrOnly, _ := Parsers("nextpnr")
+	report = BuildReportWith(map[string][]byte{"console.log": []byte("ERROR: Unable to place cell 'u_pll', no BELs remaining to implement cell type 'EHXPLLL'\n")}, pnrOnly)
+	if kind, _ := InferFailure(report, "", nil); kind != "implementation" {
+		t.Fatalf("kind = %q", kind)
+	}
+}
@@ -0,0 +1,85 @@
+package diagnostics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+// DefaultTool is used when a builder does not say which tools it runs.
+const DefaultTool = "vivado"
+
+// LineParser turns one log line into a diagnostic. source is the log file
+// name (e.g. "console.log") and should be copied into the result.
+type LineParser func(line, source string) (job.Diagnostic, bool)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]LineParser{}
+)
+
+func init() {
+	Register("vivado", parseLine)
+	Register("yosys", parseYosysLine)
+	Register("nextpnr", parseNextpnrLine)
+	Register("quartus", parseQuartusLine)
+	Register("openfpgaloader", parseOpenFPGALoaderLine)
+}
+
+// Register makes a line parser available under a tool name. Names are
+// case-insensitive. Like database/sql.Register it panics on an empty
+// name, a nil parser or a duplicate registration, since all three are
+// programming errors.
+func Register(tool string, p LineParser) {
+	name := normalizeTool(tool)
+	if name == "" {
+		panic("diagnostics: Register with empty tool name")
+	}
+	if p == nil {
+		panic("diagnostics: Register parser is nil for " + name)
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, dup := registry[name]; dup {
+		panic("diagnostics: Register called twice for " + name)
+	}
+	registry[name] = p
+}
+
+// Tools lists the registered tool names in sorted order.
+func Tools() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	out := make([]string, 0, len(registry))
+	for name := range registry {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Parsers returns the parsers registered for tools, in order. It fails on
+// an unknown tool. No tools selects DefaultTool.
+func Parsers(tools ...string) ([]LineParser, error) {
+	if len(tools) == 0 {
+		tools = []string{DefaultTool}
+	}
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	out := make([]LineParser, 0, len(tools))
+	for _, tool := range tools {
+		p, ok := registry[normalizeTool(tool)]
+		if !ok {
+			return nil, fmt.Errorf("no diagnostics parser registered for %q", tool)
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func normalizeTool(tool string) string {
+	return strings.ToLower(strings.TrimSpace(tool))
+}
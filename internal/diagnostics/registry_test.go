@@ -0,0 +1,64 @@
+package diagnostics
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+func TestRegistry_BuiltinsAndSelection(t *testing.T) {
+	for _, tool := range []string{"vivado", "yosys", "nextpnr", "quartus", "openfpgaloader"} {
+		if !slices.Contains(Tools(), tool) {
+			t.Fatalf("Tools() = %v, missing %s", Tools(), tool)
+		}
+	}
+	if ps, err := Parsers(" Yosys ", "NEXTPNR"); err != nil || len(ps) != 2 {
+		t.Fatalf("Parsers() = %d, %v", len(ps), err)
+	}
+	if _, err := Parsers("vivado", "gowin"); err == nil {
+		t.Fatalf("expected an error for an unregistered tool")
+	}
+}
+
+func TestRegister_AddsParserAndRejectsDuplicates(t *testing.T) {
+	Register("test-gowin", func(line, source string) (job.Diagnostic, bool) {
+		if line != "ERROR (EX3863) : Syntax error near 'endmodule'" {
+			return job.Diagnostic{}, false
+		}
+		return job.Diagnostic{Severity: job.SeverityError, Tool: "gowin", Code: "EX3863", Message: "Syntax error near 'endmodule'", Source: source}, true
+	})
+	parsers, err := Parsers("test-gowin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := BuildReportWith(map[string][]byte{"console.log": []byte("ERROR (EX3863) : Syntax error near 'endmodule'\nERROR: [Synth 8-2716] not a gowin line\n")}, parsers)
+	if report.ErrorCount != 1 || report.Diagnostics[0].Code != "EX3863" {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected duplicate registration to panic")
+		}
+	}()
+	Register("TEST-GOWIN", parseLine)
+}
+
+func TestBuildReportWith_TriesParsersInOrderAcrossLogs(t *testing.T) {
+	parsers, err := Parsers("yosys", "nextpnr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := BuildReportWith(map[string][]byte{
+		"console.log": []byte("Warning: wire '\\led' is assigned in a block at top.v:12.3-12.10.\n"),
+		"pnr.log":     []byte("Info: Program finished normally.\nERROR: Unable to place cell 'u_pll', no BELs remaining to implement cell type 'EHXPLLL'\n"),
+	}, parsers)
+	if report.ErrorCount != 1 || report.WarningCount != 1 || report.InfoCount != 0 {
+		t.Fatalf("unexpected counts: %+v", report)
+	}
+	// Both prefixes are also nextpnr-shaped; the yosys parser comes first.
+	if report.Diagnostics[0].Tool != "yosys" || report.Diagnostics[1].Tool != "yosys" || report.Diagnostics[1].Source != "pnr.log" {
+		t.Fatalf("unexpected diagnostics: %+v", report.Diagnostics)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -23,6 +24,8 @@ type ServiceEntry struct {
 	Port     int
 	IPv4     []net.IP
 	IPv6     []net.IP
+	// Text holds the TXT records, e.g. "proto=https".
+	Text []string
 }
 
 type Endpoint struct {
@@ -110,8 +113,12 @@ func EndpointFromEntry(entry ServiceEntry) (Endpoint, bool) {
 	if ip.To4() == nil {
 		host = "[" + host + "]"
 	}
+	scheme := "http"
+	if slices.Contains(entry.Text, "proto=https") {
+		scheme = "https"
+	}
 	return Endpoint{
-		URL:      "http://" + host + ":" + strconv.Itoa(entry.Port),
+		URL:      scheme + "://" + host + ":" + strconv.Itoa(entry.Port),
 		Instance: entry.Instance,
 		HostName: entry.HostName,
 		Port:     entry.Port,
@@ -137,6 +144,25 @@ func ParseListenPort(listenAddr string) (int, error) {
 	return port, nil
 }
 
+// IsLoopbackListenHost reports whether a listen host only accepts local
+// connections, in which case advertising it on the network is pointless.
+func IsLoopbackListenHost(host string) bool {
+	trimmed := strings.TrimSpace(host)
+	if trimmed == "" {
+		return false
+	}
+	lowered := strings.ToLower(trimmed)
+	lowered = strings.TrimSuffix(lowered, ".")
+	if lowered == "localhost" {
+		return true
+	}
+	if idx := strings.IndexByte(lowered, '%'); idx >= 0 {
+		lowered = lowered[:idx]
+	}
+	ip := net.ParseIP(lowered)
+	return ip != nil && ip.IsLoopback()
+}
+
 func PrimaryAdvertiseAddrForListenHost(listenHost string, port int) (string, error) {
 	if port <= 0 || port > 65535 {
 		return "", fmt.Errorf("invalid advertise port: %d", port)
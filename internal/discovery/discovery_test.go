@@ -25,6 +25,22 @@ func TestEndpointFromEntry_PrefersNonLoopbackIPv4(t *testing.T) {
 	}
 }
 
+func TestEndpointFromEntry_UsesHTTPSWhenAdvertised(t *testing.T) {
+	entry := ServiceEntry{
+		Instance: "spadeforge",
+		Port:     8443,
+		IPv4:     []net.IP{net.ParseIP("192.168.1.10")},
+		Text:     []string{"proto=https", "path=/healthz"},
+	}
+	ep, ok := EndpointFromEntry(entry)
+	if !ok {
+		t.Fatalf("expected endpoint")
+	}
+	if ep.URL != "https://192.168.1.10:8443" {
+		t.Fatalf("unexpected url: %s", ep.URL)
+	}
+}
+
 func TestEndpointFromEntry_UsesBracketedIPv6(t *testing.T) {
 	entry := ServiceEntry{
 		Instance: "spadeforge",
@@ -7,8 +7,11 @@ import (
 	"strings"
 
 	"github.com/libp2p/zeroconf/v2"
+	"github.com/mblsha/spadeforge/internal/logx"
 )
 
+var dlog = logx.For(logx.ModuleDiscovery)
+
 type MDBrowser struct {
 	ifaces []net.Interface
 }
@@ -46,7 +49,10 @@ func (b *MDBrowser) Browse(ctx context.Context, service, domain string, entries
 					Port:     entry.Port,
 					IPv4:     copyIPs(entry.AddrIPv4),
 					IPv6:     copyIPs(entry.AddrIPv6),
+					Text:     append([]string(nil), entry.Text...),
 				}
+				dlog.Tracef("[discovery] browse %s.%s: instance=%q host=%s port=%d ipv4=%v ipv6=%v",
+					service, domain, converted.Instance, converted.HostName, converted.Port, converted.IPv4, converted.IPv6)
 				select {
 				case <-ctx.Done():
 					return
@@ -56,6 +62,7 @@ func (b *MDBrowser) Browse(ctx context.Context, service, domain string, entries
 		}
 	}()
 
+	dlog.Debugf("[discovery] browsing %s.%s on %s", service, domain, interfaceNames(b.ifaces))
 	if len(b.ifaces) > 0 {
 		return zeroconf.Browse(ctx, service, domain, rawEntries, zeroconf.SelectIfaces(b.ifaces))
 	}
@@ -89,6 +96,7 @@ func StartAdvertiserForListenHost(instance, service, domain string, port int, tx
 		return nil, fmt.Errorf("select advertise interfaces: %w", err)
 	}
 
+	dlog.Debugf("[discovery] advertising instance=%q %s.%s port=%d on %s", instance, service, domain, port, interfaceNames(ifaces))
 	server, err := zeroconf.Register(instance, service, domain, port, txt, ifaces)
 	if err != nil {
 		return nil, fmt.Errorf("start mdns advertiser: %w", err)
@@ -104,6 +112,19 @@ func (a *Advertiser) Close() error {
 	return nil
 }
 
+// interfaceNames describes an interface selection for logs; empty means
+// zeroconf picks every multicast interface.
+func interfaceNames(ifaces []net.Interface) string {
+	if len(ifaces) == 0 {
+		return "all interfaces"
+	}
+	names := make([]string, 0, len(ifaces))
+	for _, iface := range ifaces {
+		names = append(names, iface.Name)
+	}
+	return strings.Join(names, ",")
+}
+
 func copyIPs(in []net.IP) []net.IP {
 	if len(in) == 0 {
 		return nil
@@ -0,0 +1,24 @@
+// Package diskspace reports free space on the filesystem holding a path.
+package diskspace
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnsupported is returned where the platform has no free space query.
+var ErrUnsupported = errors.New("free space query not supported on this platform")
+
+// Format renders n bytes with a binary unit, e.g. "4.2 GiB".
+func Format(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,9 @@
+//go:build !(linux || darwin || freebsd)
+
+package diskspace
+
+// Free returns the bytes available to unprivileged users on dir's
+// filesystem.
+func Free(string) (uint64, error) {
+	return 0, ErrUnsupported
+}
@@ -0,0 +1,67 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/mblsha/spadeforge/internal/diskspace"
+)
+
+// freeBytesFunc is swapped in tests.
+var freeBytesFunc = diskspace.Free
+
+// DiskSpace checks the free space on the filesystem holding dir. Missing
+// directories are measured at their nearest existing parent.
+func DiskSpace(dir string, warnBelow, failBelow uint64, fix string) Check {
+	return Check{Name: "disk space", Run: func(context.Context) Result {
+		probe := nearestExisting(dir)
+		free, err := freeBytesFunc(probe)
+		if errors.Is(err, diskspace.ErrUnsupported) {
+			return skip("%v", err)
+		}
+		if err != nil {
+			return fail(fix, "stat %s: %v", probe, err)
+		}
+		switch {
+		case free < failBelow:
+			return fail(fix, "%s free on %s, need at least %s", diskspace.Format(free), probe, diskspace.Format(failBelow))
+		case free < warnBelow:
+			return warn(fix, "%s free on %s, recommended %s", diskspace.Format(free), probe, diskspace.Format(warnBelow))
+		default:
+			return ok("%s free on %s", diskspace.Format(free), probe)
+		}
+	}}
+}
+
+// Writable checks that dir exists (or can be created) and accepts new files.
+func Writable(name, dir, fix string) Check {
+	return Check{Name: name, Run: func(context.Context) Result {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fail(fix, "create %s: %v", dir, err)
+		}
+		f, err := os.CreateTemp(dir, ".doctor-*")
+		if err != nil {
+			return fail(fix, "write %s: %v", dir, err)
+		}
+		path := f.Name()
+		_ = f.Close()
+		_ = os.Remove(path)
+		return ok("%s is writable", dir)
+	}}
+}
+
+func nearestExisting(dir string) string {
+	p := filepath.Clean(dir)
+	for {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return p
+		}
+		p = parent
+	}
+}
@@ -0,0 +1,139 @@
+// Package doctor runs environment self-checks for the spadeforge and
+// spadeloader servers and renders the results with actionable fixes.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Status is the outcome of a single check.
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+	StatusSkip Status = "skip"
+)
+
+func (s Status) rank() int {
+	switch s {
+	case StatusFail:
+		return 3
+	case StatusWarn:
+		return 2
+	case StatusOK:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Result is what a check reports. Fix is only set for warnings and failures.
+type Result struct {
+	Name       string `json:"name"`
+	Status     Status `json:"status"`
+	Detail     string `json:"detail,omitempty"`
+	Fix        string `json:"fix,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// Check is a named probe. Run must honor ctx cancellation.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) Result
+}
+
+// Report collects check results in the order the checks were given.
+type Report struct {
+	Status      Status    `json:"status"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Checks      []Result  `json:"checks"`
+}
+
+// Failed reports whether any check failed.
+func (r Report) Failed() bool {
+	return r.Status == StatusFail
+}
+
+// Run executes checks concurrently and returns their results in order. The
+// overall status is the worst individual status.
+func Run(ctx context.Context, checks []Check) Report {
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			res := c.Run(ctx)
+			res.Name = c.Name
+			if res.Status == "" {
+				res.Status = StatusOK
+			}
+			res.DurationMS = time.Since(start).Milliseconds()
+			results[i] = res
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, GeneratedAt: time.Now().UTC(), Checks: results}
+	for _, res := range results {
+		if res.Status.rank() > report.Status.rank() {
+			report.Status = res.Status
+		}
+	}
+	return report
+}
+
+// WriteText renders the report for terminals, one check per line with the
+// suggested fix indented below warnings and failures.
+func (r Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, res := range r.Checks {
+		fmt.Fprintf(tw, "[%s]\t%s\t%s\n", label(res.Status), res.Name, res.Detail)
+		if res.Fix != "" && (res.Status == StatusWarn || res.Status == StatusFail) {
+			fmt.Fprintf(tw, "\t\tfix: %s\n", res.Fix)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "overall: %s\n", strings.ToUpper(string(r.Status)))
+	return err
+}
+
+func label(s Status) string {
+	switch s {
+	case StatusOK:
+		return " OK "
+	case StatusWarn:
+		return "WARN"
+	case StatusFail:
+		return "FAIL"
+	default:
+		return "SKIP"
+	}
+}
+
+func ok(format string, args ...any) Result {
+	return Result{Status: StatusOK, Detail: fmt.Sprintf(format, args...)}
+}
+
+func skip(format string, args ...any) Result {
+	return Result{Status: StatusSkip, Detail: fmt.Sprintf(format, args...)}
+}
+
+func warn(fix, format string, args ...any) Result {
+	return Result{Status: StatusWarn, Detail: fmt.Sprintf(format, args...), Fix: fix}
+}
+
+func fail(fix, format string, args ...any) Result {
+	return Result{Status: StatusFail, Detail: fmt.Sprintf(format, args...), Fix: fix}
+}
@@ -0,0 +1,187 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/config"
+	"github.com/mblsha/spadeforge/internal/diskspace"
+)
+
+func fixed(res Result) func(context.Context) Result {
+	return func(context.Context) Result { return res }
+}
+
+func TestRun_KeepsOrderAndReportsWorstStatus(t *testing.T) {
+	report := Run(context.Background(), []Check{
+		{Name: "a", Run: fixed(ok("fine"))},
+		{Name: "b", Run: fixed(warn("do x", "meh"))},
+		{Name: "c", Run: fixed(skip("n/a"))},
+	})
+	if report.Status != StatusWarn || report.Failed() {
+		t.Fatalf("expected overall warn, got %s", report.Status)
+	}
+	names := []string{report.Checks[0].Name, report.Checks[1].Name, report.Checks[2].Name}
+	if strings.Join(names, ",") != "a,b,c" {
+		t.Fatalf("unexpected order: %v", names)
+	}
+
+	var out bytes.Buffer
+	if err := report.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"[ OK ]", "[WARN]", "fix: do x", "[SKIP]", "overall: WARN"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in output:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestDiskSpace_Thresholds(t *testing.T) {
+	orig := freeBytesFunc
+	defer func() { freeBytesFunc = orig }()
+
+	dir := filepath.Join(t.TempDir(), "missing", "child")
+	for _, tc := range []struct {
+		free uint64
+		want Status
+	}{
+		{free: 50 << 30, want: StatusOK},
+		{free: 10 << 30, want: StatusWarn},
+		{free: 1 << 30, want: StatusFail},
+	} {
+		freeBytesFunc = func(string) (uint64, error) { return tc.free, nil }
+		res := DiskSpace(dir, 20<<30, 5<<30, "free some space").Run(context.Background())
+		if res.Status != tc.want {
+			t.Fatalf("free=%d: expected %s, got %s (%s)", tc.free, tc.want, res.Status, res.Detail)
+		}
+	}
+
+	freeBytesFunc = func(string) (uint64, error) { return 0, diskspace.ErrUnsupported }
+	if res := DiskSpace(dir, 1, 1, "").Run(context.Background()); res.Status != StatusSkip {
+		t.Fatalf("expected skip on unsupported platform, got %s", res.Status)
+	}
+}
+
+type versionRunner struct {
+	out string
+	err error
+}
+
+func (r versionRunner) Run(_ context.Context, spec builder.CommandSpec, stdout, _ io.Writer) (int, error) {
+	if len(spec.Args) == 0 || spec.Args[len(spec.Args)-1] != "-version" {
+		return 1, errors.New("unexpected command")
+	}
+	_, _ = io.WriteString(stdout, r.out)
+	return 0, r.err
+}
+
+func TestVivadoCheck_ReportsVersionOrFix(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Default()
+	cfg.VivadoBin = exe
+
+	res := vivadoCheck(cfg, versionRunner{out: "\nVivado v2023.2 (64-bit)\nSW Build 4029153\n"}).Run(context.Background())
+	if res.Status != StatusOK || !strings.HasPrefix(res.Detail, "Vivado v2023.2 (64-bit)") {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+
+	res = vivadoCheck(cfg, versionRunner{out: "error while loading shared libraries: libtinfo.so.5", err: errors.New("exit status 127")}).Run(context.Background())
+	if res.Status != StatusFail || !strings.Contains(res.Detail, "libtinfo") || res.Fix == "" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+
+	cfg.VivadoBin = filepath.Join(t.TempDir(), "vivado")
+	if res := vivadoCheck(cfg, versionRunner{}).Run(context.Background()); res.Status != StatusFail {
+		t.Fatalf("expected missing binary to fail, got %+v", res)
+	}
+
+	cfg.UseFakeBuilder = true
+	if res := vivadoCheck(cfg, versionRunner{}).Run(context.Background()); res.Status != StatusSkip {
+		t.Fatalf("expected skip with fake builder, got %+v", res)
+	}
+}
+
+func TestOSSToolchainCheck_FailsOnlyWhenYosysIsDefault(t *testing.T) {
+	binDir := t.TempDir()
+	cfg := config.Default()
+	cfg.OSSBinDir = binDir
+
+	if res := ossToolchainCheck(cfg).Run(context.Background()); res.Status != StatusWarn || !strings.Contains(res.Detail, "nextpnr-ice40") {
+		t.Fatalf("expected a warning listing missing tools, got %+v", res)
+	}
+	cfg.Builder = "yosys"
+	if res := ossToolchainCheck(cfg).Run(context.Background()); res.Status != StatusFail || res.Fix == "" {
+		t.Fatalf("expected missing tools to fail for the default builder, got %+v", res)
+	}
+
+	for _, tool := range ossTools {
+		if err := os.WriteFile(filepath.Join(binDir, tool), []byte("#!/bin/sh\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if res := ossToolchainCheck(cfg).Run(context.Background()); res.Status != StatusOK {
+		t.Fatalf("expected ok with every tool present, got %+v", res)
+	}
+
+	cfg.ToolchainImage = "ghcr.io/example/oss-cad:1"
+	cfg.ContainerRuntime = filepath.Join(binDir, "missing-runtime")
+	if res := ossToolchainCheck(cfg).Run(context.Background()); res.Status != StatusFail || !strings.Contains(res.Detail, "missing-runtime") {
+		t.Fatalf("expected a missing container runtime to fail, got %+v", res)
+	}
+}
+
+func TestLicenseCheck_ProbesServersAndFiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	cfg := config.Default()
+	t.Setenv("LM_LICENSE_FILE", "")
+	t.Setenv("XILINXD_LICENSE_FILE", port+"@127.0.0.1")
+	if res := licenseCheck(cfg).Run(context.Background()); res.Status != StatusOK {
+		t.Fatalf("expected reachable license server, got %+v", res)
+	}
+
+	missing := filepath.Join(t.TempDir(), "nope.lic")
+	t.Setenv("XILINXD_LICENSE_FILE", port+"@127.0.0.1"+string(filepath.ListSeparator)+missing)
+	if res := licenseCheck(cfg).Run(context.Background()); res.Status != StatusWarn || !strings.Contains(res.Detail, "nope.lic") {
+		t.Fatalf("expected warning for stale entry, got %+v", res)
+	}
+
+	t.Setenv("XILINXD_LICENSE_FILE", missing)
+	if res := licenseCheck(cfg).Run(context.Background()); res.Status != StatusFail {
+		t.Fatalf("expected failure when nothing is reachable, got %+v", res)
+	}
+
+	t.Setenv("XILINXD_LICENSE_FILE", "")
+	if res := licenseCheck(cfg).Run(context.Background()); res.Status != StatusWarn {
+		t.Fatalf("expected WebPACK-only warning, got %+v", res)
+	}
+}
+
+func TestDiscovery_SkipsWhenDisabledAndWarnsOnLoopback(t *testing.T) {
+	res := Discovery(DiscoveryOptions{EnvPrefix: "SPADEFORGE"}).Run(context.Background())
+	if res.Status != StatusSkip {
+		t.Fatalf("expected skip, got %+v", res)
+	}
+	res = Discovery(DiscoveryOptions{Enabled: true, ListenAddr: "127.0.0.1:8080", EnvPrefix: "SPADEFORGE"}).Run(context.Background())
+	if res.Status != StatusWarn || !strings.Contains(res.Fix, "SPADEFORGE_LISTEN_ADDR") {
+		t.Fatalf("expected loopback warning, got %+v", res)
+	}
+}
@@ -0,0 +1,205 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/config"
+	"github.com/mblsha/spadeforge/internal/manifest"
+)
+
+const (
+	forgeDiskWarnBelow = 20 << 30
+	forgeDiskFailBelow = 5 << 30
+
+	// vivadoVersionTimeout covers Vivado's slow cold start on network mounts.
+	vivadoVersionTimeout = 90 * time.Second
+	licenseDialTimeout   = 3 * time.Second
+)
+
+// ForgeChecks returns the spadeforge server checks. runner executes Vivado;
+// nil uses the OS runner.
+func ForgeChecks(cfg config.Config, runner builder.Runner) []Check {
+	checks := []Check{
+		Writable("base dir", cfg.BaseDir, "point SPADEFORGE_BASE_DIR at a directory the server user can write"),
+		DiskSpace(cfg.BaseDir, forgeDiskWarnBelow, forgeDiskFailBelow,
+			"free space under SPADEFORGE_BASE_DIR (lower SPADEFORGE_RETENTION_DAYS) or move it to a larger volume"),
+	}
+	// Separately mounted work and artifact roots get their own checks.
+	for _, root := range []struct{ name, dir, env string }{
+		{"work dir", cfg.WorkRoot, "SPADEFORGE_WORK_DIR"},
+		{"artifacts dir", cfg.ArtifactsRoot, "SPADEFORGE_ARTIFACTS_DIR"},
+	} {
+		if root.dir == "" {
+			continue
+		}
+		disk := DiskSpace(root.dir, forgeDiskWarnBelow, forgeDiskFailBelow,
+			fmt.Sprintf("free space under %s or move it to a larger volume", root.env))
+		disk.Name = root.name + " disk space"
+		checks = append(checks,
+			Writable(root.name, root.dir, fmt.Sprintf("point %s at a directory the server user can write", root.env)),
+			disk,
+		)
+	}
+	return append(checks,
+		vivadoCheck(cfg, runner),
+		licenseCheck(cfg),
+		ossToolchainCheck(cfg),
+		Discovery(DiscoveryOptions{
+			Enabled:    cfg.DiscoveryEnabled,
+			ListenAddr: cfg.ListenAddr,
+			Instance:   cfg.DiscoveryInstance,
+			Service:    cfg.DiscoveryService,
+			Domain:     cfg.DiscoveryDomain,
+			EnvPrefix:  "SPADEFORGE",
+		}),
+	)
+}
+
+func vivadoCheck(cfg config.Config, runner builder.Runner) Check {
+	return Check{Name: "vivado", Run: func(ctx context.Context) Result {
+		if cfg.UseFakeBuilder {
+			return skip("fake builder in use")
+		}
+		const fix = "install Vivado or set SPADEFORGE_VIVADO_BIN to its full path, e.g. /tools/Xilinx/Vivado/2023.2/bin/vivado"
+		path, err := exec.LookPath(cfg.VivadoBin)
+		if err != nil {
+			if cfg.Builder != manifest.ToolchainVivado {
+				return warn(fix, "%q not found; manifests asking for the vivado toolchain will fail: %v", cfg.VivadoBin, err)
+			}
+			return fail(fix, "%q not found: %v", cfg.VivadoBin, err)
+		}
+		ctx, cancel := context.WithTimeout(ctx, vivadoVersionTimeout)
+		defer cancel()
+		b := builder.NewVivadoBuilder(path, runner)
+		version, err := b.Version(ctx)
+		if err != nil {
+			return fail("run `"+path+" -version` by hand; a missing libtinfo/libncurses or an unsourced settings64.sh are the usual causes", "%s -version failed: %v", path, err)
+		}
+		return ok("%s (%s)", version, path)
+	}}
+}
+
+// ossTools are the open-toolchain binaries YosysNextpnrBuilder runs.
+var ossTools = []string{"yosys", "nextpnr-ice40", "nextpnr-ecp5", "icepack", "ecppack"}
+
+// ossToolchainCheck looks for the yosys/nextpnr tools, or for the container
+// runtime when they run from SPADEFORGE_TOOLCHAIN_IMAGE. Missing tools only
+// fail when yosys is the default builder.
+func ossToolchainCheck(cfg config.Config) Check {
+	return Check{Name: "yosys toolchain", Run: func(ctx context.Context) Result {
+		if cfg.UseFakeBuilder {
+			return skip("fake builder in use")
+		}
+		missing := warn
+		if cfg.Builder == manifest.ToolchainYosys {
+			missing = fail
+		}
+		if cfg.ToolchainImage != "" {
+			path, err := exec.LookPath(cfg.ContainerRuntime)
+			if err != nil {
+				return missing("install docker or podman, or set SPADEFORGE_CONTAINER_RUNTIME", "%q not found: %v", cfg.ContainerRuntime, err)
+			}
+			return ok("%s via %s", cfg.ToolchainImage, path)
+		}
+		var found, absent []string
+		for _, tool := range ossTools {
+			name := tool
+			if cfg.OSSBinDir != "" {
+				name = filepath.Join(cfg.OSSBinDir, tool)
+			}
+			if _, err := exec.LookPath(name); err != nil {
+				absent = append(absent, tool)
+			} else {
+				found = append(found, tool)
+			}
+		}
+		if len(absent) > 0 {
+			return missing(
+				"install oss-cad-suite and set SPADEFORGE_OSS_BIN_DIR to its bin directory, or set SPADEFORGE_TOOLCHAIN_IMAGE",
+				"not found: %s", strings.Join(absent, ", "),
+			)
+		}
+		return ok("%s", strings.Join(found, ", "))
+	}}
+}
+
+// licenseCheck inspects the FlexLM variables Vivado reads. WebPACK parts
+// build without a license, so nothing configured is only a warning.
+func licenseCheck(cfg config.Config) Check {
+	return Check{Name: "license", Run: func(ctx context.Context) Result {
+		if cfg.UseFakeBuilder {
+			return skip("fake builder in use")
+		}
+		var sources []string
+		for _, key := range []string{"XILINXD_LICENSE_FILE", "LM_LICENSE_FILE"} {
+			for _, entry := range filepath.SplitList(os.Getenv(key)) {
+				if e := strings.TrimSpace(entry); e != "" {
+					sources = append(sources, e)
+				}
+			}
+		}
+		if home, err := os.UserHomeDir(); err == nil {
+			if lic := filepath.Join(home, ".Xilinx", "Xilinx.lic"); fileExists(lic) {
+				sources = append(sources, lic)
+			}
+		}
+		if len(sources) == 0 {
+			return warn(
+				"set XILINXD_LICENSE_FILE to a port@host license server or .lic file for devices outside the WebPACK set",
+				"no license configured; only WebPACK devices will build",
+			)
+		}
+
+		var problems, good []string
+		for _, src := range sources {
+			if err := probeLicenseSource(ctx, src); err != nil {
+				problems = append(problems, err.Error())
+			} else {
+				good = append(good, src)
+			}
+		}
+		if len(good) == 0 {
+			return fail("check the license server is running and reachable (firewall, VPN), or fix the .lic path", "%s", strings.Join(problems, "; "))
+		}
+		if len(problems) > 0 {
+			return warn("remove stale entries from XILINXD_LICENSE_FILE/LM_LICENSE_FILE", "reachable: %s; unreachable: %s", strings.Join(good, ", "), strings.Join(problems, "; "))
+		}
+		return ok("reachable: %s", strings.Join(good, ", "))
+	}}
+}
+
+// probeLicenseSource checks a single FlexLM entry: port@host servers are
+// dialed, anything else is treated as a license file or directory.
+func probeLicenseSource(ctx context.Context, src string) error {
+	port, host, isServer := strings.Cut(src, "@")
+	if !isServer {
+		if !fileExists(src) {
+			return fmt.Errorf("%s: no such file", src)
+		}
+		return nil
+	}
+	if port == "" {
+		// FlexLM scans 27000-27009 when the port is omitted.
+		port = "27000"
+	}
+	d := net.Dialer{Timeout: licenseDialTimeout}
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return fmt.Errorf("%s: %v", src, err)
+	}
+	_ = conn.Close()
+	return nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
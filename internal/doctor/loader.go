@@ -0,0 +1,204 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	loaderconfig "github.com/mblsha/spadeforge/internal/spadeloader/config"
+)
+
+const (
+	loaderDiskWarnBelow = 1 << 30
+	loaderDiskFailBelow = 100 << 20
+
+	openFPGALoaderTimeout = 20 * time.Second
+	ftdiVendorID          = "0403"
+)
+
+// CommandFunc runs a command and returns its combined output.
+type CommandFunc func(ctx context.Context, name string, args ...string) ([]byte, error)
+
+func runCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, args...).CombinedOutput()
+}
+
+// Linux device paths, swapped in tests.
+var (
+	sysUSBDevicesDir = "/sys/bus/usb/devices"
+	devUSBDir        = "/dev/bus/usb"
+)
+
+// LoaderChecks returns the spadeloader server checks. run executes
+// openFPGALoader; nil uses os/exec.
+func LoaderChecks(cfg loaderconfig.Config, run CommandFunc) []Check {
+	if run == nil {
+		run = runCommand
+	}
+	return []Check{
+		Writable("base dir", cfg.BaseDir, "point SPADELOADER_BASE_DIR at a directory the server user can write"),
+		DiskSpace(cfg.BaseDir, loaderDiskWarnBelow, loaderDiskFailBelow,
+			"free space under SPADELOADER_BASE_DIR or lower SPADELOADER_HISTORY_LIMIT"),
+		openFPGALoaderCheck(cfg, run),
+		ftdiPermissionsCheck(cfg),
+		probeCheck(cfg, run),
+		Discovery(DiscoveryOptions{
+			Enabled:    cfg.DiscoveryEnabled,
+			ListenAddr: cfg.ListenAddr,
+			Instance:   cfg.DiscoveryInstance,
+			Service:    cfg.DiscoveryService,
+			Domain:     cfg.DiscoveryDomain,
+			EnvPrefix:  "SPADELOADER",
+		}),
+	}
+}
+
+func openFPGALoaderCheck(cfg loaderconfig.Config, run CommandFunc) Check {
+	return Check{Name: "openFPGALoader", Run: func(ctx context.Context) Result {
+		if cfg.UseFakeFlasher {
+			return skip("fake flasher in use")
+		}
+		path, err := exec.LookPath(cfg.OpenFPGALoaderBin)
+		if err != nil {
+			return fail("install openFPGALoader (e.g. `apt install openfpgaloader` or `brew install openfpgaloader`) or set SPADELOADER_OPENFPGALOADER_BIN",
+				"%q not found: %v", cfg.OpenFPGALoaderBin, err)
+		}
+		ctx, cancel := context.WithTimeout(ctx, openFPGALoaderTimeout)
+		defer cancel()
+		out, err := run(ctx, path, "--Version")
+		version := firstNonEmptyLine(string(out))
+		if err != nil {
+			return fail("run `"+path+" --Version` by hand; a missing libftdi/libusb shared library is the usual cause", "%s --Version failed: %v %s", path, err, version)
+		}
+		if version == "" {
+			version = "unknown version"
+		}
+		return ok("%s (%s)", version, path)
+	}}
+}
+
+// ftdiPermissionsCheck opens every FTDI USB device node the way libftdi
+// would. Missing udev rules show up as permission errors here long before a
+// flash fails with an opaque "unable to open ftdi device".
+func ftdiPermissionsCheck(cfg loaderconfig.Config) Check {
+	return Check{Name: "usb permissions", Run: func(context.Context) Result {
+		if cfg.UseFakeFlasher {
+			return skip("fake flasher in use")
+		}
+		if runtime.GOOS != "linux" {
+			return skip("udev rules only apply on linux")
+		}
+		nodes, err := ftdiDeviceNodes()
+		if err != nil {
+			return warn("", "scan %s: %v", sysUSBDevicesDir, err)
+		}
+		if len(nodes) == 0 {
+			return skip("no FTDI devices connected")
+		}
+		var denied []string
+		for _, node := range nodes {
+			f, err := os.OpenFile(node, os.O_RDWR, 0)
+			if err != nil {
+				denied = append(denied, fmt.Sprintf("%s (%v)", node, err))
+				continue
+			}
+			_ = f.Close()
+		}
+		if len(denied) > 0 {
+			return fail(
+				"install openFPGALoader's 99-openfpgaloader.rules into /etc/udev/rules.d, run `sudo udevadm control --reload && sudo udevadm trigger`, add the server user to plugdev and replug the board",
+				"%d of %d FTDI devices not accessible: %s", len(denied), len(nodes), strings.Join(denied, ", "),
+			)
+		}
+		return ok("%d FTDI device(s) readable and writable", len(nodes))
+	}}
+}
+
+func ftdiDeviceNodes() ([]string, error) {
+	entries, err := os.ReadDir(sysUSBDevicesDir)
+	if err != nil {
+		return nil, err
+	}
+	var nodes []string
+	for _, e := range entries {
+		dir := filepath.Join(sysUSBDevicesDir, e.Name())
+		if readSysfs(dir, "idVendor") != ftdiVendorID {
+			continue
+		}
+		bus, errBus := strconv.Atoi(readSysfs(dir, "busnum"))
+		dev, errDev := strconv.Atoi(readSysfs(dir, "devnum"))
+		if errBus != nil || errDev != nil {
+			continue
+		}
+		nodes = append(nodes, filepath.Join(devUSBDir, fmt.Sprintf("%03d", bus), fmt.Sprintf("%03d", dev)))
+	}
+	return nodes, nil
+}
+
+func readSysfs(dir, name string) string {
+	raw, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+var (
+	scanFoundPattern = regexp.MustCompile(`(?i)found\s+(\d+)\s+(?:usb\s+)?device`)
+	scanRowPattern   = regexp.MustCompile(`0x[0-9a-fA-F]{4}:0x[0-9a-fA-F]{4}`)
+)
+
+func probeCheck(cfg loaderconfig.Config, run CommandFunc) Check {
+	return Check{Name: "probe", Run: func(ctx context.Context) Result {
+		if cfg.UseFakeFlasher {
+			return skip("fake flasher in use")
+		}
+		path, err := exec.LookPath(cfg.OpenFPGALoaderBin)
+		if err != nil {
+			return skip("openFPGALoader not found")
+		}
+		ctx, cancel := context.WithTimeout(ctx, openFPGALoaderTimeout)
+		defer cancel()
+		out, err := run(ctx, path, "--scan-usb")
+		if err != nil {
+			return fail("check the USB cable and that no other tool (Vivado hw_server, another openFPGALoader) holds the probe",
+				"%s --scan-usb failed: %v %s", path, err, firstNonEmptyLine(string(out)))
+		}
+		probes := parseScanUSB(string(out))
+		if len(probes) == 0 {
+			return fail("plug in the board, try another cable or port, and confirm it appears in `lsusb`", "no JTAG probe detected")
+		}
+		return ok("%d probe(s): %s", len(probes), strings.Join(probes, "; "))
+	}}
+}
+
+// parseScanUSB extracts one summary per probe row of `openFPGALoader
+// --scan-usb`. A "found 0" header wins over stray matches.
+func parseScanUSB(out string) []string {
+	if m := scanFoundPattern.FindStringSubmatch(out); m != nil && m[1] == "0" {
+		return nil
+	}
+	var probes []string
+	for _, line := range strings.Split(out, "\n") {
+		if scanRowPattern.MatchString(line) {
+			probes = append(probes, strings.Join(strings.Fields(line), " "))
+		}
+	}
+	return probes
+}
+
+func firstNonEmptyLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if t := strings.TrimSpace(line); t != "" {
+			return t
+		}
+	}
+	return ""
+}
@@ -0,0 +1,107 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	loaderconfig "github.com/mblsha/spadeforge/internal/spadeloader/config"
+)
+
+const scanUSBOutput = `found 2 USB device
+Bus device vid:pid       probe type      manufacturer serial               product
+001 005    0x0403:0x6010 FTDI2232        Digilent     210319A8B2A5         Digilent USB Device
+003 002    0x0403:0x6014 ft232H          FTDI         FT4ABCDE             C232HM-DDHSL-0
+`
+
+func TestParseScanUSB(t *testing.T) {
+	probes := parseScanUSB(scanUSBOutput)
+	if len(probes) != 2 || !strings.Contains(probes[0], "Digilent") {
+		t.Fatalf("unexpected probes: %v", probes)
+	}
+	if got := parseScanUSB("found 0 USB device\nBus device vid:pid probe type\n"); len(got) != 0 {
+		t.Fatalf("expected no probes, got %v", got)
+	}
+}
+
+func loaderTestConfig(t *testing.T) loaderconfig.Config {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := loaderconfig.Default()
+	cfg.BaseDir = t.TempDir()
+	cfg.OpenFPGALoaderBin = exe
+	return cfg
+}
+
+func TestOpenFPGALoaderAndProbeChecks(t *testing.T) {
+	cfg := loaderTestConfig(t)
+	run := func(_ context.Context, _ string, args ...string) ([]byte, error) {
+		switch args[0] {
+		case "--Version":
+			return []byte("openFPGALoader v0.12.1\n"), nil
+		case "--scan-usb":
+			return []byte(scanUSBOutput), nil
+		}
+		return nil, errors.New("unexpected args")
+	}
+	if res := openFPGALoaderCheck(cfg, run).Run(context.Background()); res.Status != StatusOK || !strings.HasPrefix(res.Detail, "openFPGALoader v0.12.1") {
+		t.Fatalf("unexpected version result: %+v", res)
+	}
+	if res := probeCheck(cfg, run).Run(context.Background()); res.Status != StatusOK || !strings.HasPrefix(res.Detail, "2 probe(s)") {
+		t.Fatalf("unexpected probe result: %+v", res)
+	}
+
+	none := func(context.Context, string, ...string) ([]byte, error) {
+		return []byte("found 0 USB device\n"), nil
+	}
+	if res := probeCheck(cfg, none).Run(context.Background()); res.Status != StatusFail || res.Fix == "" {
+		t.Fatalf("expected failure without probes, got %+v", res)
+	}
+
+	cfg.OpenFPGALoaderBin = filepath.Join(t.TempDir(), "openFPGALoader")
+	if res := openFPGALoaderCheck(cfg, run).Run(context.Background()); res.Status != StatusFail {
+		t.Fatalf("expected missing binary to fail, got %+v", res)
+	}
+
+	cfg.UseFakeFlasher = true
+	for _, c := range []Check{openFPGALoaderCheck(cfg, run), ftdiPermissionsCheck(cfg), probeCheck(cfg, run)} {
+		if res := c.Run(context.Background()); res.Status != StatusSkip {
+			t.Fatalf("%s: expected skip with fake flasher, got %+v", c.Name, res)
+		}
+	}
+}
+
+func TestFTDIDeviceNodes_ReadsSysfs(t *testing.T) {
+	sys := t.TempDir()
+	origSys, origDev := sysUSBDevicesDir, devUSBDir
+	defer func() { sysUSBDevicesDir, devUSBDir = origSys, origDev }()
+	sysUSBDevicesDir, devUSBDir = sys, "/dev/bus/usb"
+
+	writeDevice := func(name, vendor, bus, dev string) {
+		dir := filepath.Join(sys, name)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		for file, value := range map[string]string{"idVendor": vendor, "busnum": bus, "devnum": dev} {
+			if err := os.WriteFile(filepath.Join(dir, file), []byte(value+"\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	writeDevice("1-1", "0403", "1", "5")
+	writeDevice("1-2", "046d", "1", "6")
+
+	nodes, err := ftdiDeviceNodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 || nodes[0] != filepath.Join("/dev/bus/usb", "001", "005") {
+		t.Fatalf("unexpected nodes: %v", nodes)
+	}
+}
@@ -0,0 +1,78 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/discovery"
+)
+
+// DiscoveryOptions describes the advertisement a server would publish.
+type DiscoveryOptions struct {
+	Enabled    bool
+	ListenAddr string
+	Instance   string
+	Service    string
+	Domain     string
+	// EnvPrefix names the server's env vars in fixes, e.g. "SPADEFORGE".
+	EnvPrefix string
+}
+
+// discoveryBrowseTimeout bounds how long the check waits to see its own
+// advertisement come back.
+const discoveryBrowseTimeout = 3 * time.Second
+
+// Discovery registers a short-lived advertisement under a doctor-specific
+// instance name and browses for it, which catches both registration errors
+// and networks that drop multicast.
+func Discovery(opts DiscoveryOptions) Check {
+	return Check{Name: "mdns advertisement", Run: func(ctx context.Context) Result {
+		if !opts.Enabled {
+			return skip("discovery disabled (%s_DISCOVERY_ENABLE=0)", opts.EnvPrefix)
+		}
+		port, err := discovery.ParseListenPort(opts.ListenAddr)
+		if err != nil {
+			return fail(fmt.Sprintf("set %s_LISTEN_ADDR to host:port", opts.EnvPrefix), "%v", err)
+		}
+		host, _, _ := net.SplitHostPort(strings.TrimSpace(opts.ListenAddr))
+		if discovery.IsLoopbackListenHost(host) {
+			return warn(
+				fmt.Sprintf("listen on a LAN address (e.g. %s_LISTEN_ADDR=:%d) so clients can auto-discover the server", opts.EnvPrefix, port),
+				"listen address %q is loopback-only; advertisement is disabled", opts.ListenAddr,
+			)
+		}
+
+		instance := fmt.Sprintf("%s-doctor-%d", strings.TrimSpace(opts.Instance), os.Getpid())
+		adv, err := discovery.StartAdvertiserForListenHost(instance, opts.Service, opts.Domain, port, []string{"doctor=1"}, host)
+		if err != nil {
+			return fail("check that a non-loopback interface is up and UDP 5353 is not held exclusively by another process", "%v", err)
+		}
+		defer adv.Close()
+
+		browser, err := discovery.NewMDBrowser()
+		if err != nil {
+			return warn("", "advertisement registered, browse unavailable: %v", err)
+		}
+		browseCtx, cancel := context.WithTimeout(ctx, discoveryBrowseTimeout)
+		defer cancel()
+		entries := make(chan discovery.ServiceEntry, 8)
+		go func() { _ = browser.Browse(browseCtx, opts.Service, opts.Domain, entries) }()
+		for {
+			select {
+			case entry := <-entries:
+				if entry.Instance == instance {
+					return ok("advertised and browsed back %s.%s", opts.Service, opts.Domain)
+				}
+			case <-browseCtx.Done():
+				return warn(
+					"allow multicast UDP 5353 on the LAN interface (host firewall, Wi-Fi client isolation); clients can still use --server",
+					"advertisement registered but not seen on %s within %s", opts.Service, discoveryBrowseTimeout,
+				)
+			}
+		}
+	}}
+}
@@ -0,0 +1,134 @@
+// Package energy estimates the energy a build consumes, from Linux RAPL
+// counters when they are readable or else from a configured average draw.
+package energy
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRAPLRoot is the Linux powercap sysfs directory.
+const DefaultRAPLRoot = "/sys/class/powercap"
+
+// Sources reported in Usage.Source.
+const (
+	SourceRAPL  = "rapl"
+	SourceWatts = "watts"
+)
+
+// packageZone matches top-level RAPL package zones such as intel-rapl:0;
+// subzones (intel-rapl:0:0) are already included in their package.
+var packageZone = regexp.MustCompile(`^intel-rapl:\d+$`)
+
+// Meter measures builds. The zero value measures nothing.
+type Meter struct {
+	// RAPLRoot is the powercap directory to read; empty disables RAPL.
+	RAPLRoot string
+	// Watts is the assumed average draw used when RAPL is unavailable; 0
+	// disables the estimate.
+	Watts float64
+}
+
+// Sample is a point-in-time reading taken by Start.
+type Sample struct {
+	at     time.Time
+	rapl   map[string]uint64
+	ranges map[string]uint64
+}
+
+// Usage is the energy consumed between two samples.
+type Usage struct {
+	WallSeconds float64
+	Joules      float64
+	Source      string
+}
+
+// Start takes the opening sample of a measurement.
+func (m Meter) Start() Sample {
+	s := Sample{at: time.Now()}
+	if m.RAPLRoot != "" {
+		s.rapl, s.ranges = readRAPL(m.RAPLRoot)
+	}
+	return s
+}
+
+// Stop returns the energy used since start. It prefers RAPL counters, which
+// cover the whole package rather than just the build, and falls back to
+// wall-clock time times Watts. ok is false when neither is available.
+func (m Meter) Stop(start Sample) (Usage, bool) {
+	wall := time.Since(start.at).Seconds()
+	if len(start.rapl) > 0 {
+		end, _ := readRAPL(m.RAPLRoot)
+		if joules, ok := raplDelta(start, end); ok {
+			return Usage{WallSeconds: wall, Joules: joules, Source: SourceRAPL}, true
+		}
+	}
+	if m.Watts > 0 {
+		return Usage{WallSeconds: wall, Joules: wall * m.Watts, Source: SourceWatts}, true
+	}
+	return Usage{}, false
+}
+
+// raplDelta sums per-zone counter increases, allowing for one wraparound
+// at the zone's max_energy_range_uj.
+func raplDelta(start Sample, end map[string]uint64) (float64, bool) {
+	if len(end) != len(start.rapl) {
+		return 0, false
+	}
+	var micro uint64
+	for zone, before := range start.rapl {
+		after, ok := end[zone]
+		if !ok {
+			return 0, false
+		}
+		if after >= before {
+			micro += after - before
+		} else if r := start.ranges[zone]; r > before {
+			micro += r - before + after
+		} else {
+			return 0, false
+		}
+	}
+	return float64(micro) / 1e6, true
+}
+
+func readRAPL(root string) (counters, ranges map[string]uint64) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, nil
+	}
+	counters = map[string]uint64{}
+	ranges = map[string]uint64{}
+	for _, entry := range entries {
+		if !packageZone.MatchString(entry.Name()) {
+			continue
+		}
+		dir := filepath.Join(root, entry.Name())
+		v, ok := readUint(filepath.Join(dir, "energy_uj"))
+		if !ok {
+			// Unreadable counters (root-only on newer kernels) disable RAPL.
+			return nil, nil
+		}
+		counters[entry.Name()] = v
+		if r, ok := readUint(filepath.Join(dir, "max_energy_range_uj")); ok {
+			ranges[entry.Name()] = r
+		}
+	}
+	if len(counters) == 0 {
+		return nil, nil
+	}
+	return counters, ranges
+}
+
+func readUint(path string) (uint64, bool) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(strings.TrimSpace(string(raw)), 10, 64)
+	return v, err == nil
+}
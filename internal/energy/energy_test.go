@@ -0,0 +1,60 @@
+package energy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeZone(t *testing.T, root, zone, energy, maxRange string) {
+	t.Helper()
+	dir := filepath.Join(root, zone)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "energy_uj"), []byte(energy+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if maxRange != "" {
+		if err := os.WriteFile(filepath.Join(dir, "max_energy_range_uj"), []byte(maxRange+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMeter_UsesRAPLCountersWithWraparound(t *testing.T) {
+	root := t.TempDir()
+	writeZone(t, root, "intel-rapl:0", "1000000", "")
+	writeZone(t, root, "intel-rapl:1", "9000000", "10000000")
+	writeZone(t, root, "intel-rapl:0:0", "5", "") // subzone, ignored
+
+	m := Meter{RAPLRoot: root, Watts: 100}
+	start := m.Start()
+	writeZone(t, root, "intel-rapl:0", "3500000", "")
+	writeZone(t, root, "intel-rapl:1", "500000", "10000000")
+	writeZone(t, root, "intel-rapl:0:0", "999999999", "")
+
+	usage, ok := m.Stop(start)
+	if !ok || usage.Source != SourceRAPL {
+		t.Fatalf("expected RAPL usage, got %+v ok=%v", usage, ok)
+	}
+	// 2.5 J on package 0 plus 1.5 J across the wrap on package 1.
+	if usage.Joules != 4 {
+		t.Fatalf("joules = %v, want 4", usage.Joules)
+	}
+}
+
+func TestMeter_FallsBackToConfiguredWatts(t *testing.T) {
+	m := Meter{RAPLRoot: filepath.Join(t.TempDir(), "missing"), Watts: 120}
+	usage, ok := m.Stop(m.Start())
+	if !ok || usage.Source != SourceWatts {
+		t.Fatalf("expected watts estimate, got %+v ok=%v", usage, ok)
+	}
+	if usage.Joules != usage.WallSeconds*120 {
+		t.Fatalf("joules = %v, want wall %v x 120 W", usage.Joules, usage.WallSeconds)
+	}
+
+	if _, ok := (Meter{}).Stop(Meter{}.Start()); ok {
+		t.Fatalf("expected no usage without RAPL or watts")
+	}
+}
@@ -0,0 +1,121 @@
+// Package flashimage turns a bitstream into the images SPI flash
+// programmers take, the way Vivado's write_cfgmem does for a single
+// bitstream: a raw .bin holding the configuration data without the .bit
+// header, or an Intel HEX .mcs placing that data at a flash address.
+package flashimage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	FormatBin = "bin"
+	FormatMCS = "mcs"
+)
+
+// mcsRecordBytes is the data per .mcs line, as write_cfgmem writes them.
+const mcsRecordBytes = 16
+
+// bitMagic starts every Vivado .bit file, up to the 'a' key.
+var bitMagic = []byte{0x00, 0x09, 0x0f, 0xf0, 0x0f, 0xf0, 0x0f, 0xf0, 0x0f, 0xf0, 0x00, 0x00, 0x01}
+
+// ErrFormat marks an unknown image format.
+var ErrFormat = errors.New("unknown flash image format")
+
+// ParseFormat normalizes a format name; empty means bin.
+func ParseFormat(s string) (string, error) {
+	switch f := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(s), ".")); f {
+	case "", FormatBin:
+		return FormatBin, nil
+	case FormatMCS:
+		return FormatMCS, nil
+	default:
+		return "", fmt.Errorf("%w %q; use bin or mcs", ErrFormat, s)
+	}
+}
+
+// ConfigData returns the configuration data of a Vivado .bit file, the
+// part after its keyed header. Input without that header, such as a .bin
+// or a Lattice bitstream, is returned unchanged.
+func ConfigData(bit []byte) ([]byte, error) {
+	if !bytes.HasPrefix(bit, bitMagic) {
+		return bit, nil
+	}
+	pos := len(bitMagic)
+	for _, key := range []byte{'a', 'b', 'c', 'd'} {
+		if pos+3 > len(bit) || bit[pos] != key {
+			return nil, fmt.Errorf("malformed .bit header: missing field %q", key)
+		}
+		pos += 3 + int(binary.BigEndian.Uint16(bit[pos+1:]))
+	}
+	if pos+5 > len(bit) || bit[pos] != 'e' {
+		return nil, errors.New("malformed .bit header: missing data length")
+	}
+	n := int(binary.BigEndian.Uint32(bit[pos+1:]))
+	data := bit[pos+5:]
+	if len(data) < n {
+		return nil, fmt.Errorf("truncated .bit: header announces %d bytes of configuration data, found %d", n, len(data))
+	}
+	return data[:n], nil
+}
+
+// Write converts bit to format and writes it to w. offset is the flash
+// address of the image; it only fits in .mcs files, so a .bin must start at
+// 0 and be programmed at the offset instead.
+func Write(w io.Writer, bit []byte, format string, offset uint32) error {
+	data, err := ConfigData(bit)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case FormatBin:
+		if offset != 0 {
+			return errors.New("a .bin image has no address; program it at the offset instead")
+		}
+		_, err := w.Write(data)
+		return err
+	case FormatMCS:
+		return WriteMCS(w, data, offset)
+	default:
+		return fmt.Errorf("%w %q", ErrFormat, format)
+	}
+}
+
+// WriteMCS writes data as Intel HEX starting at flash address offset, with
+// an extended linear address record at every 64 KiB boundary.
+func WriteMCS(w io.Writer, data []byte, offset uint32) error {
+	if uint64(offset)+uint64(len(data)) > 1<<32 {
+		return errors.New("image does not fit in a 32-bit flash address space")
+	}
+	bw := bufio.NewWriter(w)
+	upper := -1
+	for i := 0; i < len(data); {
+		addr := offset + uint32(i)
+		if int(addr>>16) != upper {
+			upper = int(addr >> 16)
+			writeRecord(bw, 0, 0x04, []byte{byte(upper >> 8), byte(upper)})
+		}
+		// Records never cross a 64 KiB boundary.
+		n := min(mcsRecordBytes, len(data)-i, 0x10000-int(addr&0xffff))
+		writeRecord(bw, uint16(addr), 0x00, data[i:i+n])
+		i += n
+	}
+	writeRecord(bw, 0, 0x01, nil)
+	return bw.Flush()
+}
+
+func writeRecord(w *bufio.Writer, addr uint16, typ byte, data []byte) {
+	sum := byte(len(data)) + byte(addr>>8) + byte(addr) + typ
+	fmt.Fprintf(w, ":%02X%04X%02X", len(data), addr, typ)
+	for _, b := range data {
+		fmt.Fprintf(w, "%02X", b)
+		sum += b
+	}
+	fmt.Fprintf(w, "%02X\n", -sum)
+}
@@ -0,0 +1,73 @@
+package flashimage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// testBit builds a minimal Vivado .bit file around data.
+func testBit(data []byte) []byte {
+	var b bytes.Buffer
+	b.Write(bitMagic)
+	for _, f := range []struct {
+		key   byte
+		value string
+	}{{'a', "top;UserID=0XFFFFFFFF\x00"}, {'b', "7a35tcpg236\x00"}, {'c', "2026/01/02\x00"}, {'d', "10:11:12\x00"}} {
+		b.WriteByte(f.key)
+		_ = binary.Write(&b, binary.BigEndian, uint16(len(f.value)))
+		b.WriteString(f.value)
+	}
+	b.WriteByte('e')
+	_ = binary.Write(&b, binary.BigEndian, uint32(len(data)))
+	b.Write(data)
+	return b.Bytes()
+}
+
+func TestConfigData_StripsBitHeader(t *testing.T) {
+	data := []byte{0xff, 0xff, 0xaa, 0x99, 0x55, 0x66, 0x20, 0x00}
+	got, err := ConfigData(testBit(data))
+	if err != nil {
+		t.Fatalf("ConfigData() error: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("ConfigData() = %x, want %x", got, data)
+	}
+	if got, _ := ConfigData(data); !bytes.Equal(got, data) {
+		t.Fatalf("raw data changed: %x", got)
+	}
+	if _, err := ConfigData(testBit(data)[:40]); err == nil {
+		t.Fatalf("expected error for a truncated .bit")
+	}
+}
+
+func TestWriteMCS_SplitsAt64KiBBoundaries(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteMCS(&out, []byte{0xaa, 0x99, 0x55, 0x66}, 0xfffe); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("got %d lines:\n%s", len(lines), out.String())
+	}
+	for i, line := range []string{":020000040000FA", ":02FFFE00AA99BE", ":020000040001F9", ":02000000556643", ":00000001FF"} {
+		if lines[i] != line {
+			t.Fatalf("line %d = %s, want %s", i, lines[i], line)
+		}
+	}
+}
+
+func TestWrite_BinRejectsOffset(t *testing.T) {
+	bit := testBit([]byte{0xaa, 0x99, 0x55, 0x66})
+	var out bytes.Buffer
+	if err := Write(&out, bit, FormatBin, 0); err != nil || out.Len() != 4 {
+		t.Fatalf("Write(bin) = %v, %d bytes", err, out.Len())
+	}
+	if err := Write(&out, bit, FormatBin, 0x400000); err == nil {
+		t.Fatalf("expected error for a .bin at an offset")
+	}
+	if _, err := ParseFormat("hex"); err == nil {
+		t.Fatalf("expected error for an unknown format")
+	}
+}
@@ -0,0 +1,25 @@
+package httpmw
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+// AccessLog logs one line per request with status, body size and latency.
+// logf nil uses log.Printf.
+func AccessLog(logf func(format string, args ...any)) Middleware {
+	if logf == nil {
+		logf = log.Printf
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := newResponseRecorder(w)
+			next.ServeHTTP(rec, r)
+			logf("[http] %s %s %d %dB %s remote=%s",
+				r.Method, r.URL.RequestURI(), rec.status, rec.bytes,
+				time.Since(start).Round(time.Microsecond), ClientKey(r))
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package httpmw
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/authz"
+)
+
+// TokenAuth rejects requests whose header does not carry token with 401.
+// Requests over a connection with a verified client certificate pass
+// without it. An empty token disables the check.
+func TokenAuth(header, token string) Middleware {
+	return ScopedTokenAuth(header, token, nil, "")
+}
+
+// ScopedTokenAuth is TokenAuth that also accepts the named tokens, as long
+// as they have not expired and allow scope; other named tokens get 403. The
+// matching named token is stored in the request context for handlers to
+// record. With neither token nor tokens set, the check is disabled.
+func ScopedTokenAuth(header, token string, tokens []authz.Token, scope authz.Scope) Middleware {
+	token = strings.TrimSpace(token)
+	return func(next http.Handler) http.Handler {
+		if token == "" && len(tokens) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ClientCertVerified(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			got := strings.TrimSpace(r.Header.Get(header))
+			if token != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			named, ok := authz.Lookup(tokens, got)
+			if !ok || got == "" {
+				writeError(w, http.StatusUnauthorized, "invalid token")
+				return
+			}
+			if err := authz.Check(named, scope, time.Now()); err != nil {
+				status := http.StatusForbidden
+				if errors.Is(err, authz.ErrExpired) {
+					status = http.StatusUnauthorized
+				}
+				writeError(w, status, err.Error())
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(authz.WithToken(r.Context(), named)))
+		})
+	}
+}
+
+// ClientCertVerified reports whether the request came over TLS with a client
+// certificate that chains to the server's configured client CAs. The TLS
+// stack only fills VerifiedChains after checking the certificate, so an
+// unverified or self-signed one never counts.
+func ClientCertVerified(r *http.Request) bool {
+	return r.TLS != nil && len(r.TLS.VerifiedChains) > 0
+}
+
+// Allowlist rejects requests from remote IPs outside entries (IPs or CIDRs)
+// with 403. An empty list allows everyone.
+func Allowlist(entries []string) Middleware {
+	return func(next http.Handler) http.Handler {
+		if len(entries) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip, err := RemoteIP(r.RemoteAddr)
+			if err != nil {
+				writeError(w, http.StatusForbidden, err.Error())
+				return
+			}
+			for _, entry := range entries {
+				if AllowEntryMatches(entry, ip) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			writeError(w, http.StatusForbidden, fmt.Sprintf("remote ip %s is not allowed", ip.String()))
+		})
+	}
+}
+
+// RemoteIP parses the IP out of an http.Request RemoteAddr.
+func RemoteIP(remoteAddr string) (net.IP, error) {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return nil, fmt.Errorf("parse remote addr: %w", err)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid remote ip: %s", host)
+	}
+	return ip, nil
+}
+
+// AllowEntryMatches reports whether ip equals entry or lies in the entry's
+// CIDR. Malformed entries never match.
+func AllowEntryMatches(entry string, ip net.IP) bool {
+	if strings.Contains(entry, "/") {
+		_, cidr, err := net.ParseCIDR(entry)
+		if err != nil {
+			return false
+		}
+		return cidr.Contains(ip)
+	}
+	allowed := net.ParseIP(entry)
+	if allowed == nil {
+		return false
+	}
+	return allowed.Equal(ip)
+}
+
+// ClientKey identifies the client for logs and rate limits: its IP, or the
+// raw RemoteAddr if that does not parse.
+func ClientKey(r *http.Request) string {
+	if ip, err := RemoteIP(r.RemoteAddr); err == nil {
+		return ip.String()
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,146 @@
+package httpmw
+
+import (
+	"context"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ChaosConfig sets how often Chaos injects each fault. Rates are
+// probabilities per request between 0 and 1.
+type ChaosConfig struct {
+	// ErrorRate answers requests with 500 before they reach the handler.
+	ErrorRate float64
+	// SlowRate delays requests by a random time up to MaxDelay.
+	SlowRate float64
+	// DropRate cuts event streams off at a random time within MaxDelay of
+	// their first byte, without a terminating chunk.
+	DropRate float64
+	MaxDelay time.Duration
+	// Rand draws the random numbers; nil uses the global source.
+	Rand *rand.Rand
+}
+
+// Enabled reports whether any fault is configured.
+func (c ChaosConfig) Enabled() bool {
+	return c.ErrorRate > 0 || c.SlowRate > 0 || c.DropRate > 0
+}
+
+// Chaos injects server errors, slow responses and dropped event streams so
+// client retry and reconnect logic can be tested against a real server. It
+// is for test setups only; /healthz is left alone so supervisors don't
+// restart the server.
+func Chaos(c ChaosConfig) Middleware {
+	if !c.Enabled() {
+		return nil
+	}
+	var mu sync.Mutex
+	float := func() float64 {
+		if c.Rand == nil {
+			return rand.Float64()
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return c.Rand.Float64()
+	}
+	jitter := func() time.Duration {
+		return time.Duration(float() * float64(c.MaxDelay))
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/healthz" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if float() < c.SlowRate {
+				d := jitter()
+				log.Printf("[chaos] delaying %s %s by %s", r.Method, r.URL.Path, d)
+				select {
+				case <-time.After(d):
+				case <-r.Context().Done():
+					return
+				}
+			}
+			if float() < c.ErrorRate {
+				log.Printf("[chaos] failing %s %s", r.Method, r.URL.Path)
+				writeError(w, http.StatusInternalServerError, "chaos: injected failure")
+				return
+			}
+			if float() >= c.DropRate {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithCancel(r.Context())
+			defer cancel()
+			dw := &dropWriter{ResponseWriter: w, after: jitter(), cancel: cancel}
+			next.ServeHTTP(dw, r.WithContext(ctx))
+			if dw.dropped() {
+				log.Printf("[chaos] dropped event stream %s after %s", r.URL.Path, dw.after)
+				panic(http.ErrAbortHandler)
+			}
+		})
+	}
+}
+
+// dropWriter arms a timer that cancels the request when the handler starts
+// an event stream; other responses pass through untouched.
+type dropWriter struct {
+	http.ResponseWriter
+	after  time.Duration
+	cancel context.CancelFunc
+
+	once  sync.Once
+	timer *time.Timer
+	fired chan struct{}
+}
+
+func (d *dropWriter) arm() {
+	d.once.Do(func() {
+		if !strings.HasPrefix(d.Header().Get("Content-Type"), "text/event-stream") {
+			return
+		}
+		d.fired = make(chan struct{})
+		d.timer = time.AfterFunc(d.after, func() {
+			close(d.fired)
+			d.cancel()
+		})
+	})
+}
+
+func (d *dropWriter) dropped() bool {
+	if d.timer == nil {
+		return false
+	}
+	d.timer.Stop()
+	select {
+	case <-d.fired:
+		return true
+	default:
+		return false
+	}
+}
+
+func (d *dropWriter) WriteHeader(status int) {
+	d.arm()
+	d.ResponseWriter.WriteHeader(status)
+}
+
+func (d *dropWriter) Write(p []byte) (int, error) {
+	d.arm()
+	return d.ResponseWriter.Write(p)
+}
+
+func (d *dropWriter) Flush() {
+	if f, ok := d.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (d *dropWriter) Unwrap() http.ResponseWriter {
+	return d.ResponseWriter
+}
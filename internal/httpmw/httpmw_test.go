@@ -0,0 +1,277 @@
+package httpmw
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecover_WritesJSON500(t *testing.T) {
+	h := Chain(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	}), Recover())
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/jobs/x", nil))
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rr.Code)
+	}
+	var body map[string]string
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil || body["error"] == "" {
+		t.Fatalf("expected JSON error body, got %q (%v)", rr.Body.String(), err)
+	}
+}
+
+func TestTokenAuthAndAllowlist(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	h := Chain(ok, Allowlist([]string{"10.0.0.0/8", "192.168.1.5"}), TokenAuth("X-Build-Token", "secret"))
+
+	tests := []struct {
+		remote string
+		token  string
+		want   int
+	}{
+		{"10.1.2.3:5000", "secret", http.StatusNoContent},
+		{"192.168.1.5:5000", "secret", http.StatusNoContent},
+		{"10.1.2.3:5000", "wrong", http.StatusUnauthorized},
+		{"172.16.0.1:5000", "secret", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/v1/jobs", nil)
+		req.RemoteAddr = tt.remote
+		req.Header.Set("X-Build-Token", tt.token)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		if rr.Code != tt.want {
+			t.Fatalf("%s token=%q: status = %d, want %d", tt.remote, tt.token, rr.Code, tt.want)
+		}
+	}
+
+	open := Chain(ok, Allowlist(nil), TokenAuth("X-Build-Token", ""))
+	rr := httptest.NewRecorder()
+	open.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/jobs", nil))
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("empty allowlist and token should pass, got %d", rr.Code)
+	}
+}
+
+func TestRateLimit_PerClientWithRetryAfter(t *testing.T) {
+	l := NewRateLimiter(1, 2)
+	now := time.Unix(1000, 0)
+	l.now = func() time.Time { return now }
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}), RateLimit(l))
+
+	do := func(remote string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/jobs", nil)
+		req.RemoteAddr = remote
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+	for i := 0; i < 2; i++ {
+		if rr := do("10.0.0.1:1"); rr.Code != http.StatusOK {
+			t.Fatalf("request %d within burst: status %d", i, rr.Code)
+		}
+	}
+	rr := do("10.0.0.1:2")
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") != "1" {
+		t.Fatalf("expected 429 with Retry-After 1, got %d %q", rr.Code, rr.Header().Get("Retry-After"))
+	}
+	if rr := do("10.0.0.2:1"); rr.Code != http.StatusOK {
+		t.Fatalf("other client should not be limited, got %d", rr.Code)
+	}
+	now = now.Add(time.Second)
+	if rr := do("10.0.0.1:3"); rr.Code != http.StatusOK {
+		t.Fatalf("token should refill after 1s, got %d", rr.Code)
+	}
+	if NewRateLimiter(0, 10) != nil {
+		t.Fatalf("rate 0 should disable the limiter")
+	}
+}
+
+func TestGzip_CompressesJSONButNotEventStreams(t *testing.T) {
+	payload := strings.Repeat(`{"state":"RUNNING"}`, 100)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/json", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, payload)
+	})
+	mux.HandleFunc("/events", func(w http.ResponseWriter, _ *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			t.Errorf("flusher lost behind middleware")
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: x\n\n")
+		flusher.Flush()
+	})
+	ts := httptest.NewServer(Chain(mux, Recover(), AccessLog(func(string, ...any) {}), Gzip()))
+	defer ts.Close()
+
+	get := func(path string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := get("/json")
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected gzip JSON, headers %v", resp.Header)
+	}
+	zr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := io.ReadAll(zr)
+	if string(raw) != payload {
+		t.Fatalf("decompressed body mismatch")
+	}
+
+	resp = get("/events")
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Encoding") != "" {
+		t.Fatalf("event stream must not be compressed")
+	}
+	raw, _ = io.ReadAll(resp.Body)
+	if string(raw) != "data: x\n\n" {
+		t.Fatalf("event body = %q", raw)
+	}
+}
+
+func TestAccessLog_RecordsStatusAndSize(t *testing.T) {
+	var line string
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = io.WriteString(w, "hello")
+	}), AccessLog(func(format string, args ...any) { line = fmt.Sprintf(format, args...) }))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/jobs?x=1", nil)
+	req.RemoteAddr = "10.0.0.7:1234"
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	for _, want := range []string{"POST /v1/jobs?x=1 202 5B", "remote=10.0.0.7"} {
+		if !strings.Contains(line, want) {
+			t.Fatalf("log line %q missing %q", line, want)
+		}
+	}
+}
+
+func TestParseUpload_413NamesLimitAndMalformedIs400(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ParseUpload(w, r, "bundle", 64) {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	post := func(body, contentType string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/jobs", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+	multipartBody := func(payload string) string {
+		return "--b\r\nContent-Disposition: form-data; name=\"f\"\r\n\r\n" + payload + "\r\n--b--\r\n"
+	}
+
+	if rr := post(multipartBody("ok"), "multipart/form-data; boundary=b"); rr.Code != http.StatusNoContent {
+		t.Fatalf("small upload status = %d, body=%s", rr.Code, rr.Body.String())
+	}
+
+	rr := post(multipartBody(strings.Repeat("x", 200)), "multipart/form-data; boundary=b")
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized upload status = %d, want 413", rr.Code)
+	}
+	var body UploadTooLarge
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Upload != "bundle" || body.LimitBytes != 64 || body.Error == "" {
+		t.Fatalf("unexpected 413 body: %+v", body)
+	}
+
+	if rr := post("not multipart", "text/plain"); rr.Code != http.StatusBadRequest {
+		t.Fatalf("malformed upload status = %d, want 400", rr.Code)
+	}
+}
+
+func TestChaos_FailsRequestsButSparesHealthz(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	if Chaos(ChaosConfig{}) != nil {
+		t.Fatalf("Chaos with no faults should be a no-op")
+	}
+	h := Chain(ok, Chaos(ChaosConfig{ErrorRate: 1}))
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/jobs", nil))
+	if rr.Code != http.StatusInternalServerError || !strings.Contains(rr.Body.String(), "chaos") {
+		t.Fatalf("status = %d body=%q, want injected 500", rr.Code, rr.Body.String())
+	}
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("/healthz status = %d, want 204", rr.Code)
+	}
+}
+
+func TestChaos_DropsEventStreamsOnly(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; ; i++ {
+			if _, err := fmt.Fprintf(w, "data: %d\n\n", i); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(5 * time.Millisecond):
+			}
+		}
+	})
+	mux.HandleFunc("/json", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"ok": "yes"})
+	})
+	ts := httptest.NewServer(Chain(mux, Recover(), Chaos(ChaosConfig{DropRate: 1, MaxDelay: 50 * time.Millisecond})))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || !strings.Contains(string(raw), "yes") {
+		t.Fatalf("plain response = %q, %v; want it untouched", raw, err)
+	}
+
+	resp, err = http.Get(ts.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(io.Discard, resp.Body)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatalf("stream ended cleanly, want an aborted connection")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("stream was not dropped")
+	}
+}
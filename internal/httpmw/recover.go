@@ -0,0 +1,33 @@
+package httpmw
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"runtime/debug"
+)
+
+// Recover turns a handler panic into a 500 JSON error and logs the stack.
+// If the response has already started it can only abort the connection.
+func Recover() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := newResponseRecorder(w)
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if err, ok := v.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(v)
+				}
+				log.Printf("[http] panic serving %s %s: %v\n%s", r.Method, r.URL.Path, v, debug.Stack())
+				if rec.wroteHeader {
+					panic(http.ErrAbortHandler)
+				}
+				writeError(rec, http.StatusInternalServerError, "internal server error")
+			}()
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
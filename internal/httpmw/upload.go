@@ -0,0 +1,41 @@
+package httpmw
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// multipartMemory is how much of a multipart form is held in memory before
+// spilling file parts to disk.
+const multipartMemory = 32 << 20
+
+// UploadTooLarge is the 413 body for an upload over its endpoint's limit.
+type UploadTooLarge struct {
+	Error      string `json:"error"`
+	Upload     string `json:"upload"`
+	LimitBytes int64  `json:"limit_bytes"`
+}
+
+// ParseUpload caps the request body at limit bytes and parses the multipart
+// form. An oversized body is answered with 413 and an UploadTooLarge naming
+// the upload kind and its limit; other parse failures with 400. It reports
+// whether the handler should continue.
+func ParseUpload(w http.ResponseWriter, r *http.Request, kind string, limit int64) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+	err := r.ParseMultipartForm(multipartMemory)
+	if err == nil {
+		return true
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeJSON(w, http.StatusRequestEntityTooLarge, UploadTooLarge{
+			Error:      fmt.Sprintf("%s upload exceeds the %d byte limit", kind, limit),
+			Upload:     kind,
+			LimitBytes: limit,
+		})
+		return false
+	}
+	writeError(w, http.StatusBadRequest, "invalid multipart upload: "+err.Error())
+	return false
+}
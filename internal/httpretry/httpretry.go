@@ -0,0 +1,224 @@
+// Package httpretry retries idempotent HTTP requests with jittered
+// exponential backoff for the spadeforge and spadeloader clients.
+package httpretry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Policy controls how many times a request is attempted and how long to wait
+// between attempts. The zero value is usable and behaves like Default.
+type Policy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	// Values below 1 use the default; 1 disables retries.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+const (
+	defaultMaxAttempts = 4
+	defaultBaseDelay   = 250 * time.Millisecond
+	defaultMaxDelay    = 5 * time.Second
+)
+
+// Default returns the policy used when a client does not configure one.
+func Default() Policy {
+	return Policy{
+		MaxAttempts: defaultMaxAttempts,
+		BaseDelay:   defaultBaseDelay,
+		MaxDelay:    defaultMaxDelay,
+	}
+}
+
+func (p Policy) normalized() Policy {
+	d := Default()
+	if p.MaxAttempts < 1 {
+		p.MaxAttempts = d.MaxAttempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = d.BaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = d.MaxDelay
+	}
+	if p.MaxDelay < p.BaseDelay {
+		p.MaxDelay = p.BaseDelay
+	}
+	return p
+}
+
+// Attempts reports the effective number of attempts.
+func (p Policy) Attempts() int {
+	return p.normalized().MaxAttempts
+}
+
+// Backoff returns the full-jitter delay before retry number attempt (1-based).
+func (p Policy) Backoff(attempt int) time.Duration {
+	p = p.normalized()
+	ceiling := p.BaseDelay
+	for i := 1; i < attempt && ceiling < p.MaxDelay; i++ {
+		ceiling *= 2
+	}
+	if ceiling > p.MaxDelay {
+		ceiling = p.MaxDelay
+	}
+	return ceiling/2 + rand.N(ceiling/2+1)
+}
+
+// Retryable reports whether a response status indicates a transient failure.
+func Retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// Do sends the request built by newReq, retrying transport errors and
+// retryable statuses. newReq is called once per attempt so request bodies and
+// headers can be rebuilt. A retryable response on the final attempt is
+// returned as-is so callers can report the server's error body. Do never
+// sleeps past ctx's deadline.
+func (p Policy) Do(ctx context.Context, client *http.Client, newReq func() (*http.Request, error)) (*http.Response, error) {
+	attempts := p.Attempts()
+	for attempt := 1; ; attempt++ {
+		req, err := newReq()
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err == nil && (!Retryable(resp.StatusCode) || attempt >= attempts) {
+			return resp, nil
+		}
+		if err != nil && (ctx.Err() != nil || attempt >= attempts) {
+			return nil, err
+		}
+
+		delay := p.Backoff(attempt)
+		if resp != nil {
+			if hint, ok := retryAfter(resp); ok && hint > delay {
+				delay = hint
+			}
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+			err = fmt.Errorf("status %d", resp.StatusCode)
+		}
+		if sleepErr := Sleep(ctx, delay); sleepErr != nil {
+			return nil, fmt.Errorf("giving up after attempt %d (last error: %v): %w", attempt, err, sleepErr)
+		}
+	}
+}
+
+// Download streams a GET response body into out. If the transfer breaks
+// mid-body it re-requests the remainder with a Range header; servers that
+// ignore ranges resend the whole body and the already written prefix is
+// skipped. onStatus builds the error for any status other than 200/206.
+func (p Policy) Download(ctx context.Context, client *http.Client, newReq func() (*http.Request, error), out io.Writer, onStatus func(*http.Response) error) error {
+	attempts := p.Attempts()
+	var written int64
+	for attempt := 1; ; attempt++ {
+		resp, err := p.Do(ctx, client, func() (*http.Request, error) {
+			req, err := newReq()
+			if err != nil {
+				return nil, err
+			}
+			if written > 0 {
+				req.Header.Set("Range", fmt.Sprintf("bytes=%d-", written))
+			}
+			return req, nil
+		})
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusPartialContent && written > 0 && rangeStart(resp) == written:
+		case resp.StatusCode == http.StatusOK:
+			if written > 0 {
+				if _, err := io.CopyN(io.Discard, resp.Body, written); err != nil {
+					resp.Body.Close()
+					if !IsTransient(ctx, err) || attempt >= attempts {
+						return err
+					}
+					if err := Sleep(ctx, p.Backoff(attempt)); err != nil {
+						return err
+					}
+					continue
+				}
+			}
+		default:
+			err := onStatus(resp)
+			resp.Body.Close()
+			return err
+		}
+
+		n, err := io.Copy(out, resp.Body)
+		resp.Body.Close()
+		written += n
+		if err == nil {
+			return nil
+		}
+		if !IsTransient(ctx, err) || attempt >= attempts {
+			return err
+		}
+		if err := Sleep(ctx, p.Backoff(attempt)); err != nil {
+			return err
+		}
+	}
+}
+
+func rangeStart(resp *http.Response) int64 {
+	var start, end, size int64
+	if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-%d/%d", &start, &end, &size); err != nil {
+		return -1
+	}
+	return start
+}
+
+// Sleep waits for d, returning early with an error if ctx is done or its
+// deadline would expire first.
+func Sleep(ctx context.Context, d time.Duration) error {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return context.DeadlineExceeded
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// IsTransient reports whether err is worth retrying, i.e. it is not caused by
+// the caller's context ending.
+func IsTransient(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		return time.Until(at), true
+	}
+	return 0, false
+}
@@ -0,0 +1,189 @@
+package httpretry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func fastPolicy(attempts int) Policy {
+	return Policy{MaxAttempts: attempts, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+}
+
+func getter(ctx context.Context, url string) func() (*http.Request, error) {
+	return func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	}
+}
+
+func TestDo_RetriesTransientStatus(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	resp, err := fastPolicy(4).Do(ctx, ts.Client(), getter(ctx, ts.URL))
+	if err != nil {
+		t.Fatalf("do failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || calls.Load() != 3 {
+		t.Fatalf("unexpected result: status=%d calls=%d", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestDo_DoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	resp, err := fastPolicy(4).Do(ctx, ts.Client(), getter(ctx, ts.URL))
+	if err != nil {
+		t.Fatalf("do failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound || calls.Load() != 1 {
+		t.Fatalf("unexpected result: status=%d calls=%d", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestDo_ReturnsLastRetryableResponseWhenExhausted(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	resp, err := fastPolicy(2).Do(ctx, ts.Client(), getter(ctx, ts.URL))
+	if err != nil {
+		t.Fatalf("do failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway || calls.Load() != 2 {
+		t.Fatalf("unexpected result: status=%d calls=%d", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestDo_HonorsContextDeadline(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := fastPolicy(5).Do(ctx, ts.Client(), getter(ctx, ts.URL))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Fatalf("retry slept past the context deadline")
+	}
+}
+
+func TestBackoff_StaysWithinBounds(t *testing.T) {
+	p := Policy{MaxAttempts: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt := 1; attempt <= 10; attempt++ {
+		d := p.Backoff(attempt)
+		if d < 50*time.Millisecond || d > time.Second {
+			t.Fatalf("attempt %d: backoff %s out of bounds", attempt, d)
+		}
+	}
+}
+
+func TestDownload_ResumesWithRangeAfterBrokenTransfer(t *testing.T) {
+	payload := bytes.Repeat([]byte("0123456789"), 1000)
+	var calls atomic.Int32
+	var gotRange atomic.Value
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Content-Length", fmt.Sprint(len(payload)))
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(payload[:4000])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		gotRange.Store(r.Header.Get("Range"))
+		http.ServeContent(w, r, "a.zip", time.Time{}, bytes.NewReader(payload))
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	var out bytes.Buffer
+	err := fastPolicy(3).Download(ctx, ts.Client(), getter(ctx, ts.URL), &out, func(resp *http.Response) error {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	})
+	if err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), payload) {
+		t.Fatalf("payload mismatch: got %d bytes", out.Len())
+	}
+	if r, _ := gotRange.Load().(string); r != "bytes=4000-" {
+		t.Fatalf("expected resume range, got %q", r)
+	}
+}
+
+func TestDownload_SkipsPrefixWhenServerIgnoresRange(t *testing.T) {
+	payload := []byte(strings.Repeat("abcdef", 500))
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", fmt.Sprint(len(payload)))
+		w.WriteHeader(http.StatusOK)
+		if calls.Add(1) == 1 {
+			_, _ = w.Write(payload[:1000])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		_, _ = w.Write(payload)
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	var out bytes.Buffer
+	if err := fastPolicy(3).Download(ctx, ts.Client(), getter(ctx, ts.URL), &out, func(resp *http.Response) error {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), payload) {
+		t.Fatalf("payload mismatch: got %d bytes", out.Len())
+	}
+}
+
+func TestDownload_ReportsStatusErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	err := fastPolicy(3).Download(ctx, ts.Client(), getter(ctx, ts.URL), io.Discard, func(resp *http.Response) error {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	})
+	if err == nil || err.Error() != "status 404" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+// Package httptransport builds the HTTP clients used to talk to spadeforge and
+// spadeloader servers: proxy settings come from HTTPS_PROXY/HTTP_PROXY/NO_PROXY,
+// TLS can trust an extra CA bundle or skip verification entirely, a client
+// certificate can stand in for the auth token, and transfers can be capped
+// to a byte rate. ServerTLSConfig is the servers' side of the same TLS setup.
+package httptransport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Options configures TLS and pacing for outgoing requests.
+type Options struct {
+	// CAFile is a PEM bundle trusted in addition to the system roots.
+	CAFile string
+	// InsecureSkipVerify disables server certificate verification.
+	InsecureSkipVerify bool
+	// CertFile and KeyFile are a PEM client certificate and key presented
+	// to servers that accept client certificates.
+	CertFile string
+	KeyFile  string
+	// LimitRate caps request and response bodies, together, to this many
+	// bytes per second. Zero is unlimited.
+	LimitRate int64
+}
+
+// Enabled reports whether the options differ from Go's defaults.
+func (o Options) Enabled() bool {
+	return strings.TrimSpace(o.CAFile) != "" || o.InsecureSkipVerify || o.LimitRate > 0 ||
+		strings.TrimSpace(o.CertFile) != "" || strings.TrimSpace(o.KeyFile) != ""
+}
+
+// NewClient returns an *http.Client whose transport is a clone of
+// http.DefaultTransport (so proxy environment variables are honored) with
+// TLS and the rate limit configured from opts.
+func NewClient(opts Options) (*http.Client, error) {
+	base, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return nil, errors.New("default transport is not *http.Transport")
+	}
+	tr := base.Clone()
+	tr.Proxy = http.ProxyFromEnvironment
+
+	tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if path := strings.TrimSpace(opts.CAFile); path != "" {
+		pool, err := loadCertPool(path)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.RootCAs = pool
+	}
+	if opts.InsecureSkipVerify {
+		tlsCfg.InsecureSkipVerify = true
+	}
+	certFile, keyFile := strings.TrimSpace(opts.CertFile), strings.TrimSpace(opts.KeyFile)
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("client certificate and key must be given together")
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	tr.TLSClientConfig = tlsCfg
+	if opts.LimitRate > 0 {
+		return &http.Client{Transport: &throttledTransport{base: tr, lim: newLimiter(opts.LimitRate)}}, nil
+	}
+	return &http.Client{Transport: tr}, nil
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read ca file: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(raw) {
+		return nil, fmt.Errorf("ca file %s contains no PEM certificates", path)
+	}
+	return pool, nil
+}
+
+// InsecureWarning is printed by the CLIs when verification is disabled.
+const InsecureWarning = "WARNING: TLS certificate verification is DISABLED (--insecure-skip-verify); the server's identity is not checked and traffic, including the auth token, can be intercepted"
+
+// errRoundTripper fails every request with a fixed error. It lets client
+// structs defer transport construction errors to the first request.
+type errRoundTripper struct{ err error }
+
+func (e errRoundTripper) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, e.err
+}
+
+// ClientOrError is NewClient for callers without an error path: a
+// construction failure is reported by every request made with the client.
+func ClientOrError(opts Options) *http.Client {
+	c, err := NewClient(opts)
+	if err != nil {
+		return &http.Client{Transport: errRoundTripper{err: err}}
+	}
+	return c
+}
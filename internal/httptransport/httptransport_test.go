@@ -0,0 +1,147 @@
+package httptransport
+
+import (
+	"bytes"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTLSServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func writeServerCA(t *testing.T, ts *httptest.Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	raw := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewClient_TrustsCAFile(t *testing.T) {
+	ts := newTLSServer(t)
+	c, err := NewClient(Options{CAFile: writeServerCA(t, ts)})
+	if err != nil {
+		t.Fatalf("new client failed: %v", err)
+	}
+	resp, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("request with custom CA failed: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestNewClient_RejectsUnknownCAByDefault(t *testing.T) {
+	ts := newTLSServer(t)
+	c, err := NewClient(Options{})
+	if err != nil {
+		t.Fatalf("new client failed: %v", err)
+	}
+	if resp, err := c.Get(ts.URL); err == nil {
+		resp.Body.Close()
+		t.Fatalf("expected certificate verification failure")
+	}
+}
+
+func TestNewClient_InsecureSkipVerify(t *testing.T) {
+	ts := newTLSServer(t)
+	c, err := NewClient(Options{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("new client failed: %v", err)
+	}
+	resp, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("insecure request failed: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestNewClient_HonorsProxyEnvironment(t *testing.T) {
+	c, err := NewClient(Options{})
+	if err != nil {
+		t.Fatalf("new client failed: %v", err)
+	}
+	tr, ok := c.Transport.(*http.Transport)
+	if !ok || tr.Proxy == nil {
+		t.Fatalf("expected transport with proxy func, got %#v", c.Transport)
+	}
+}
+
+func TestClientOrError_DefersCAFileErrors(t *testing.T) {
+	bad := filepath.Join(t.TempDir(), "bad.pem")
+	if err := os.WriteFile(bad, []byte("not a certificate"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewClient(Options{CAFile: bad}); err == nil {
+		t.Fatalf("expected error for invalid CA file")
+	}
+	c := ClientOrError(Options{CAFile: bad})
+	if resp, err := c.Get("http://127.0.0.1:1"); err == nil {
+		resp.Body.Close()
+		t.Fatalf("expected deferred CA file error")
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	for in, want := range map[string]int64{"": 0, "0": 0, "1500": 1500, "500k": 500 << 10, "2M": 2 << 20, "1G": 1 << 30} {
+		got, err := ParseRate(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseRate(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"fast", "-1M", "2MB"} {
+		if _, err := ParseRate(in); err == nil {
+			t.Fatalf("ParseRate(%q) should fail", in)
+		}
+	}
+}
+
+func TestNewClient_LimitRatePacesUploadsAndDownloads(t *testing.T) {
+	payload := bytes.Repeat([]byte("x"), 64<<10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		_, _ = w.Write(payload)
+	}))
+	defer ts.Close()
+
+	c, err := NewClient(Options{LimitRate: 512 << 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	resp, err := c.Post(ts.URL, "application/octet-stream", bytes.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || len(body) != len(payload) {
+		t.Fatalf("read %d bytes: %v", len(body), err)
+	}
+	// 128 KiB at 512 KiB/s is 250ms, less up to 32 KiB of burst per body.
+	if elapsed := time.Since(start); elapsed < 120*time.Millisecond {
+		t.Fatalf("transfer took %s, expected pacing", elapsed)
+	}
+}
+
+func TestNewClient_ClientCertNeedsKey(t *testing.T) {
+	if _, err := NewClient(Options{CertFile: "client.crt"}); err == nil {
+		t.Fatalf("expected error for a client certificate without a key")
+	}
+	if _, err := NewClient(Options{CertFile: "missing.crt", KeyFile: "missing.key"}); err == nil {
+		t.Fatalf("expected error for unreadable client certificate files")
+	}
+}
@@ -0,0 +1,125 @@
+package httptransport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ParseRate parses a --limit-rate value: bytes per second with an optional
+// K, M or G suffix (powers of 1024), e.g. "500K" or "2M". Empty or "0"
+// means unlimited.
+func ParseRate(s string) (int64, error) {
+	raw := strings.TrimSpace(s)
+	s = raw
+	if s == "" {
+		return 0, nil
+	}
+	mult := int64(1)
+	switch strings.ToUpper(s[len(s)-1:]) {
+	case "K":
+		mult = 1 << 10
+	case "M":
+		mult = 1 << 20
+	case "G":
+		mult = 1 << 30
+	}
+	if mult > 1 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid rate %q: want bytes per second, e.g. 500K or 2M", raw)
+	}
+	return n * mult, nil
+}
+
+// limiter is a token bucket of bytes shared by every body of one client.
+// A read that overdraws the bucket waits until the debt is paid back, so
+// concurrent transfers split the rate between them.
+type limiter struct {
+	rate  float64
+	chunk int
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(bytesPerSec int64) *limiter {
+	// Reads are capped at a tenth of a second's worth of bytes so the rate
+	// stays smooth at the scale of a progress bar.
+	chunk := int(min(max(bytesPerSec/10, 1), 32<<10))
+	return &limiter{rate: float64(bytesPerSec), chunk: chunk, tokens: float64(chunk), last: time.Now()}
+}
+
+// wait takes n bytes from the bucket, blocking while it is in debt.
+func (l *limiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(float64(l.chunk), l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens -= float64(n)
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type throttledBody struct {
+	ctx context.Context
+	rc  io.ReadCloser
+	lim *limiter
+}
+
+func (b *throttledBody) Read(p []byte) (int, error) {
+	if len(p) > b.lim.chunk {
+		p = p[:b.lim.chunk]
+	}
+	n, err := b.rc.Read(p)
+	if n > 0 {
+		if werr := b.lim.wait(b.ctx, n); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return n, err
+}
+
+func (b *throttledBody) Close() error {
+	return b.rc.Close()
+}
+
+// throttledTransport paces request and response bodies through one
+// limiter. Protocol switches (WebSocket) are passed through untouched.
+type throttledTransport struct {
+	base http.RoundTripper
+	lim  *limiter
+}
+
+func (t *throttledTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil && req.Body != http.NoBody {
+		throttled := req.Clone(req.Context())
+		throttled.Body = &throttledBody{ctx: req.Context(), rc: req.Body, lim: t.lim}
+		req = throttled
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode == http.StatusSwitchingProtocols {
+		return resp, err
+	}
+	resp.Body = &throttledBody{ctx: req.Context(), rc: resp.Body, lim: t.lim}
+	return resp, nil
+}
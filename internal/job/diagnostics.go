@@ -20,13 +20,55 @@ type Diagnostic struct {
 	Column   int                `json:"column,omitempty"`
 	Source   string             `json:"source,omitempty"`
 	Raw      string             `json:"raw,omitempty"`
+	// Suppressed marks entries matched by a suppression rule; they are kept
+	// for reference but excluded from the severity counts.
+	Suppressed bool `json:"suppressed,omitempty"`
+	// Hint is set for codes with a known explanation and fix.
+	Hint *DiagnosticHint `json:"hint,omitempty"`
+}
+
+// DiagnosticHint explains a known diagnostic code and what to try next.
+type DiagnosticHint struct {
+	Explanation string   `json:"explanation"`
+	NextSteps   []string `json:"next_steps,omitempty"`
 }
 
 type DiagnosticsReport struct {
-	Schema       int          `json:"schema"`
-	GeneratedAt  time.Time    `json:"generated_at"`
-	ErrorCount   int          `json:"error_count"`
-	WarningCount int          `json:"warning_count"`
-	InfoCount    int          `json:"info_count"`
-	Diagnostics  []Diagnostic `json:"diagnostics"`
+	Schema          int          `json:"schema"`
+	GeneratedAt     time.Time    `json:"generated_at"`
+	ErrorCount      int          `json:"error_count"`
+	WarningCount    int          `json:"warning_count"`
+	InfoCount       int          `json:"info_count"`
+	SuppressedCount int          `json:"suppressed_count,omitempty"`
+	Diagnostics     []Diagnostic `json:"diagnostics"`
+}
+
+// Add appends d and updates the per-severity counts.
+func (r *DiagnosticsReport) Add(d Diagnostic) {
+	r.Diagnostics = append(r.Diagnostics, d)
+	r.count(d)
+}
+
+// Recount recomputes the counts from Diagnostics, e.g. after entries have
+// been marked Suppressed.
+func (r *DiagnosticsReport) Recount() {
+	r.ErrorCount, r.WarningCount, r.InfoCount, r.SuppressedCount = 0, 0, 0, 0
+	for _, d := range r.Diagnostics {
+		r.count(d)
+	}
+}
+
+func (r *DiagnosticsReport) count(d Diagnostic) {
+	if d.Suppressed {
+		r.SuppressedCount++
+		return
+	}
+	switch d.Severity {
+	case SeverityError:
+		r.ErrorCount++
+	case SeverityWarning:
+		r.WarningCount++
+	default:
+		r.InfoCount++
+	}
 }
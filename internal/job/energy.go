@@ -0,0 +1,29 @@
+package job
+
+// EnergyUsage estimates the energy one build consumed. Source is "rapl"
+// when measured from CPU package counters and "watts" when estimated from
+// wall-clock time and the server's configured draw. Cost is in the unit of
+// the configured price per kWh.
+type EnergyUsage struct {
+	Source      string  `json:"source"`
+	WallSeconds float64 `json:"wall_seconds"`
+	Joules      float64 `json:"joules"`
+	WattHours   float64 `json:"watt_hours"`
+	Cost        float64 `json:"cost,omitempty"`
+}
+
+// ProjectEnergy totals EnergyUsage over the builds of one project.
+type ProjectEnergy struct {
+	Project     string  `json:"project,omitempty"`
+	Builds      int     `json:"builds"`
+	WallSeconds float64 `json:"wall_seconds"`
+	Joules      float64 `json:"joules"`
+	WattHours   float64 `json:"watt_hours"`
+	Cost        float64 `json:"cost,omitempty"`
+}
+
+// EnergyStats is returned by GET /v1/stats/energy.
+type EnergyStats struct {
+	Projects []ProjectEnergy `json:"projects"`
+	Total    ProjectEnergy   `json:"total"`
+}
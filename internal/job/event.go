@@ -2,6 +2,16 @@ package job
 
 import "time"
 
+// EventDroppedEvents notices tell a subscriber that it fell behind and
+// missed events; its Seq is the last event delivered before the gap, so
+// clients resync by reconnecting with since=Seq.
+const EventDroppedEvents = "dropped_events"
+
+// EventSnapshot events replace the backlog when a subscriber's since falls
+// outside the retained window; they carry the job's current state at the
+// latest seq.
+const EventSnapshot = "snapshot"
+
 type Event struct {
 	Seq int64 `json:"seq"`
 
@@ -17,11 +27,17 @@ type Event struct {
 	FailureKind    string `json:"failure_kind,omitempty"`
 	FailureSummary string `json:"failure_summary,omitempty"`
 
+	// Attempt is the job's current run, counting from 1.
+	Attempt int `json:"attempt,omitempty"`
+
+	// Dropped is the number of events skipped, set on dropped_events notices.
+	Dropped int64 `json:"dropped,omitempty"`
+
 	HeartbeatAt *time.Time `json:"heartbeat_at,omitempty"`
 	ExitCode    *int       `json:"exit_code,omitempty"`
 	At          time.Time  `json:"at"`
 }
 
 func (e Event) Terminal() bool {
-	return e.State == StateSucceeded || e.State == StateFailed
+	return e.State.Terminal()
 }
@@ -0,0 +1,52 @@
+package job
+
+import "time"
+
+// FeedRecent is how many finished jobs GET /v1/feed.json lists.
+const FeedRecent = 20
+
+// Feed is the queue snapshot served at GET /v1/feed.json for wallboards.
+// It holds no clock-dependent fields, so an unchanged queue yields the same
+// body and ETag on every poll; displays derive elapsed times from
+// StartedAt.
+type Feed struct {
+	Running []FeedJob `json:"running"`
+	// Queued lists queued jobs in the order they will start.
+	Queued []FeedJob `json:"queued"`
+	// Recent lists the last FeedRecent finished jobs, newest first.
+	Recent []FeedJob `json:"recent"`
+}
+
+// FeedJob is the part of a Record a wallboard shows.
+type FeedJob struct {
+	ID          string     `json:"id"`
+	Project     string     `json:"project"`
+	Submitter   string     `json:"submitter,omitempty"`
+	State       State      `json:"state"`
+	CurrentStep string     `json:"current_step,omitempty"`
+	FailureKind string     `json:"failure_kind,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	// DurationSeconds is the run time of a finished job that started.
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+}
+
+// NewFeedJob summarizes rec.
+func NewFeedJob(rec Record) FeedJob {
+	out := FeedJob{
+		ID:          rec.ID,
+		Project:     rec.Manifest.Project,
+		Submitter:   rec.Submitter,
+		State:       rec.State,
+		CurrentStep: rec.CurrentStep,
+		FailureKind: rec.FailureKind,
+		CreatedAt:   rec.CreatedAt,
+		StartedAt:   rec.StartedAt,
+		FinishedAt:  rec.FinishedAt,
+	}
+	if rec.StartedAt != nil && rec.FinishedAt != nil {
+		out.DurationSeconds = rec.FinishedAt.Sub(*rec.StartedAt).Seconds()
+	}
+	return out
+}
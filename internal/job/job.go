@@ -15,8 +15,14 @@ const (
 	StateRunning   State = "RUNNING"
 	StateSucceeded State = "SUCCEEDED"
 	StateFailed    State = "FAILED"
+	StateCanceled  State = "CANCELED"
 )
 
+// Terminal reports whether a job in this state is finished.
+func (s State) Terminal() bool {
+	return s == StateSucceeded || s == StateFailed || s == StateCanceled
+}
+
 type Record struct {
 	ID string `json:"id"`
 
@@ -36,7 +42,67 @@ type Record struct {
 
 	ExitCode *int `json:"exit_code,omitempty"`
 
+	// Submitter identifies who queued the job; queued jobs are dequeued
+	// round-robin across submitters.
+	Submitter string `json:"submitter,omitempty"`
+	// TokenName is the named API token the job was submitted with, for
+	// auditing; empty for the shared token.
+	TokenName string `json:"token_name,omitempty"`
+	// ClientIP is the address the job was submitted from; with TokenName it
+	// decides whose per-client quota the job counts against.
+	ClientIP string `json:"client_ip,omitempty"`
+
 	Manifest manifest.Manifest `json:"manifest"`
+	// Warnings are non-fatal manifest lint findings recorded at submit time.
+	Warnings []manifest.FieldError `json:"warnings,omitempty"`
+
+	Metrics *BuildMetrics `json:"metrics,omitempty"`
+	Energy  *EnergyUsage  `json:"energy,omitempty"`
+	// ProjectBaseline marks the job later builds of its project are
+	// compared against; Baseline holds this job's comparison.
+	ProjectBaseline bool                `json:"project_baseline,omitempty"`
+	Baseline        *BaselineComparison `json:"baseline,omitempty"`
+
+	// Pinned jobs are never removed by retention. ExpiresAt is set when
+	// retention selects the job and gives the time it will be removed
+	// unless it is pinned first.
+	Pinned    bool       `json:"pinned,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// ParentJobID is the job whose on_success submit action queued this
+	// one; FollowUps records the outcome of this job's on_success actions.
+	ParentJobID string     `json:"parent_job_id,omitempty"`
+	FollowUps   []FollowUp `json:"follow_ups,omitempty"`
+
+	// CacheKey hashes the manifest and source files; CachedFrom is the job
+	// whose artifacts this one reused instead of building.
+	CacheKey   string `json:"cache_key,omitempty"`
+	CachedFrom string `json:"cached_from,omitempty"`
+
+	// Attempt counts runs of this job, starting at 1; Retries records each
+	// failed attempt the retry policy requeued.
+	Attempt int            `json:"attempt,omitempty"`
+	Retries []RetryAttempt `json:"retries,omitempty"`
+}
+
+// RetryAttempt is a failed attempt that was retried.
+type RetryAttempt struct {
+	Attempt        int       `json:"attempt"`
+	FailureKind    string    `json:"failure_kind"`
+	FailureSummary string    `json:"failure_summary,omitempty"`
+	At             time.Time `json:"at"`
+}
+
+// FollowUp is the outcome of one on_success action.
+type FollowUp struct {
+	// Action is "flash", "webhook" or "submit"; Target the loader or
+	// webhook name, or the follow-up project.
+	Action string `json:"action"`
+	Target string `json:"target,omitempty"`
+	// JobID is the flash job on the loader or the follow-up build.
+	JobID string    `json:"job_id,omitempty"`
+	Error string    `json:"error,omitempty"`
+	At    time.Time `json:"at"`
 }
 
 func New(id string, m manifest.Manifest, now time.Time) *Record {
@@ -58,6 +124,7 @@ func (r *Record) Transition(next State, now time.Time, message string) error {
 	r.UpdatedAt = n
 	r.Message = message
 	if next == StateRunning {
+		r.Attempt++
 		r.StartedAt = &n
 		r.FinishedAt = nil
 		r.ExitCode = nil
@@ -66,7 +133,7 @@ func (r *Record) Transition(next State, now time.Time, message string) error {
 		r.FailureSummary = ""
 		r.HeartbeatAt = &n
 	}
-	if next == StateSucceeded || next == StateFailed {
+	if next.Terminal() {
 		r.FinishedAt = &n
 		r.HeartbeatAt = &n
 	}
@@ -90,6 +157,26 @@ func (r *Record) MarkFailed(now time.Time, message string, err error, exitCode i
 	return nil
 }
 
+// Requeue returns a failed running job to QUEUED for another attempt,
+// recording the failure in Retries. The failure fields stay set until the
+// next attempt starts.
+func (r *Record) Requeue(now time.Time, failureKind, failureSummary string) error {
+	if r.State != StateRunning {
+		return fmt.Errorf("invalid state for retry: %s", r.State)
+	}
+	n := now.UTC()
+	r.Retries = append(r.Retries, RetryAttempt{Attempt: r.Attempt, FailureKind: failureKind, FailureSummary: failureSummary, At: n})
+	r.State = StateQueued
+	r.UpdatedAt = n
+	r.FailureKind = failureKind
+	r.FailureSummary = failureSummary
+	r.Message = fmt.Sprintf("attempt %d failed (%s); retrying", r.Attempt, failureKind)
+	r.StartedAt = nil
+	r.HeartbeatAt = nil
+	r.ExitCode = nil
+	return nil
+}
+
 func (r *Record) MarkSucceeded(now time.Time, message string, exitCode int) error {
 	if r.State != StateRunning {
 		return fmt.Errorf("invalid state for success: %s", r.State)
@@ -104,8 +191,23 @@ func (r *Record) MarkSucceeded(now time.Time, message string, exitCode int) erro
 	return nil
 }
 
+// MarkCanceled finishes a queued or running job at a user's request.
+func (r *Record) MarkCanceled(now time.Time, message string) error {
+	if r.State != StateRunning && r.State != StateQueued {
+		return fmt.Errorf("invalid state for cancellation: %s", r.State)
+	}
+	if err := r.Transition(StateCanceled, now, message); err != nil {
+		return err
+	}
+	r.Error = ""
+	r.FailureKind = ""
+	r.FailureSummary = ""
+	r.CurrentStep = "canceled"
+	return nil
+}
+
 func (r *Record) Terminal() bool {
-	return r.State == StateSucceeded || r.State == StateFailed
+	return r.State.Terminal()
 }
 
 func isValidTransition(from, to State) bool {
@@ -114,10 +216,10 @@ func isValidTransition(from, to State) bool {
 	}
 	switch from {
 	case StateQueued:
-		return to == StateRunning || to == StateFailed
+		return to == StateRunning || to == StateFailed || to == StateCanceled
 	case StateRunning:
-		return to == StateSucceeded || to == StateFailed
-	case StateSucceeded, StateFailed:
+		return to == StateSucceeded || to == StateFailed || to == StateCanceled
+	case StateSucceeded, StateFailed, StateCanceled:
 		return false
 	default:
 		return false
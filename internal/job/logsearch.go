@@ -0,0 +1,25 @@
+package job
+
+// LogLine is one numbered line of a job log (1-based).
+type LogLine struct {
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+// LogMatch is a line matching a log search with its surrounding context.
+type LogMatch struct {
+	LogLine
+	Before []LogLine `json:"before,omitempty"`
+	After  []LogLine `json:"after,omitempty"`
+}
+
+// LogSearchResult is returned by GET /v1/jobs/{id}/log/search.
+type LogSearchResult struct {
+	File    string     `json:"file"`
+	Query   string     `json:"query"`
+	Context int        `json:"context"`
+	Matches []LogMatch `json:"matches"`
+	// Truncated is set when the match limit was reached before the end of
+	// the log.
+	Truncated bool `json:"truncated,omitempty"`
+}
@@ -0,0 +1,30 @@
+package job
+
+import "time"
+
+// ClientDefaults are submission defaults the server stores per client, so
+// web UIs and scripts read them from GET /v1/me instead of keeping their
+// own config. Empty fields are unset.
+type ClientDefaults struct {
+	Part      string `json:"part,omitempty"`
+	Toolchain string `json:"toolchain,omitempty"`
+	// Board is the board `spadeforge-cli run` flashes.
+	Board string `json:"board,omitempty"`
+	// OutputDir, OutputName and BitstreamName are the CLI's --output-dir,
+	// --output-name and --bitstream-name.
+	OutputDir     string `json:"output_dir,omitempty"`
+	OutputName    string `json:"output_name,omitempty"`
+	BitstreamName string `json:"bitstream_name,omitempty"`
+}
+
+// Me is the body of GET /v1/me: who the server takes the caller to be and
+// the defaults stored for them.
+type Me struct {
+	// Client keys the stored defaults: "token:<name>" for a named token,
+	// "ip:<address>" for everyone else.
+	Client    string         `json:"client"`
+	TokenName string         `json:"token_name,omitempty"`
+	Scopes    []string       `json:"scopes"`
+	ExpiresAt *time.Time     `json:"expires_at,omitempty"`
+	Defaults  ClientDefaults `json:"defaults"`
+}
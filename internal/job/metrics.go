@@ -0,0 +1,45 @@
+package job
+
+// BuildMetrics are the headline numbers parsed from a build's timing and
+// utilization reports and diagnostics. Timing fields are nil when the build
+// produced no timing summary.
+type BuildMetrics struct {
+	WNS *float64 `json:"wns_ns,omitempty"`
+	TNS *float64 `json:"tns_ns,omitempty"`
+	WHS *float64 `json:"whs_ns,omitempty"`
+	THS *float64 `json:"ths_ns,omitempty"`
+
+	// Utilization is keyed by lut, ff, bram, dsp and io.
+	Utilization map[string]ResourceUsage `json:"utilization,omitempty"`
+
+	Errors   int `json:"errors"`
+	Warnings int `json:"warnings"`
+}
+
+// ResourceUsage is one utilization report row.
+type ResourceUsage struct {
+	Used      float64 `json:"used"`
+	Available int     `json:"available"`
+	Percent   float64 `json:"percent"`
+}
+
+// MetricDelta compares one metric against the project baseline. Delta is
+// Current - Baseline; Regressed marks a change for the worse.
+type MetricDelta struct {
+	Metric    string  `json:"metric"`
+	Baseline  float64 `json:"baseline"`
+	Current   float64 `json:"current"`
+	Delta     float64 `json:"delta"`
+	Regressed bool    `json:"regressed,omitempty"`
+	Message   string  `json:"message,omitempty"`
+}
+
+// BaselineComparison is stored on a job built after its project's baseline
+// was set. Summary is a one-line verdict such as
+// "WNS regressed by 0.420 ns vs baseline".
+type BaselineComparison struct {
+	BaselineJobID string        `json:"baseline_job_id"`
+	Deltas        []MetricDelta `json:"deltas"`
+	Regressions   int           `json:"regressions"`
+	Summary       string        `json:"summary"`
+}
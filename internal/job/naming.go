@@ -0,0 +1,19 @@
+package job
+
+import "github.com/mblsha/spadeforge/internal/artifactname"
+
+// NameVars returns the artifact naming template values for the job.
+func (r *Record) NameVars() artifactname.Vars {
+	v := artifactname.Vars{
+		JobID:   r.ID,
+		Project: r.Manifest.Project,
+		Top:     r.Manifest.Top,
+		Part:    r.Manifest.Part,
+		Time:    r.CreatedAt,
+	}
+	if g := r.Manifest.Git; g != nil {
+		v.GitShort = g.Short()
+		v.GitBranch = g.Branch
+	}
+	return v
+}
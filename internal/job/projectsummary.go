@@ -0,0 +1,45 @@
+package job
+
+import "time"
+
+// DiagnosticGroup is one recurring diagnostic across a project's builds.
+// Line numbers are ignored when grouping so edits elsewhere in a file do not
+// split a warning into many groups.
+type DiagnosticGroup struct {
+	ID       string             `json:"id"`
+	Severity DiagnosticSeverity `json:"severity"`
+	Code     string             `json:"code,omitempty"`
+	Message  string             `json:"message"`
+	File     string             `json:"file,omitempty"`
+
+	// Builds counts the builds the diagnostic appeared in; Occurrences
+	// counts every instance across those builds.
+	Builds      int    `json:"builds"`
+	Occurrences int    `json:"occurrences"`
+	FirstJobID  string `json:"first_job_id"`
+	LastJobID   string `json:"last_job_id"`
+	InLatest    bool   `json:"in_latest"`
+}
+
+// BuildDiagnosticsTrend summarizes one build relative to the previous one.
+type BuildDiagnosticsTrend struct {
+	JobID     string    `json:"job_id"`
+	State     State     `json:"state"`
+	CreatedAt time.Time `json:"created_at"`
+	// Commit is the short git commit from the manifest, if recorded.
+	Commit       string `json:"commit,omitempty"`
+	ErrorCount   int    `json:"error_count"`
+	WarningCount int    `json:"warning_count"`
+	// New and Resolved list DiagnosticGroup IDs that appeared or
+	// disappeared compared to the previous build in the window.
+	New      []string `json:"new,omitempty"`
+	Resolved []string `json:"resolved,omitempty"`
+}
+
+// ProjectDiagnosticsSummary is returned by
+// GET /v1/projects/{name}/diagnostics/summary.
+type ProjectDiagnosticsSummary struct {
+	Project string                  `json:"project"`
+	Builds  []BuildDiagnosticsTrend `json:"builds"`
+	Groups  []DiagnosticGroup       `json:"groups"`
+}
@@ -0,0 +1,57 @@
+package job
+
+// Run stages, in pipeline order.
+const (
+	RunStageBuild = "build"
+	RunStageFlash = "flash"
+	RunStageTest  = "test"
+	RunStageDone  = "done"
+)
+
+// Run follows a build through the builds, flashes and hardware tests its
+// on_success actions started, under one ID: the first build's job ID. It is
+// returned by GET /v1/runs/{id}.
+type Run struct {
+	ID string `json:"id"`
+	// State is terminal once nothing in the run is pending: CANCELED if a
+	// build was canceled, FAILED if any build, flash or test failed,
+	// otherwise SUCCEEDED.
+	State State `json:"state"`
+	// Stage is the stage still in progress, or "done".
+	Stage string `json:"stage"`
+	// Error is the first failure in the run.
+	Error string `json:"error,omitempty"`
+
+	Builds  []RunBuild `json:"builds"`
+	Flashes []RunFlash `json:"flashes,omitempty"`
+}
+
+// RunBuild is one build in a run.
+type RunBuild struct {
+	JobID       string `json:"job_id"`
+	ParentJobID string `json:"parent_job_id,omitempty"`
+	Project     string `json:"project"`
+	State       State  `json:"state"`
+	FailureKind string `json:"failure_kind,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// RunFlash is a flash action and the spadeloader job it submitted. State
+// is the loader job's state, empty if the loader could not be asked.
+type RunFlash struct {
+	BuildJobID  string   `json:"build_job_id"`
+	Loader      string   `json:"loader"`
+	JobID       string   `json:"job_id,omitempty"`
+	Board       string   `json:"board,omitempty"`
+	State       State    `json:"state,omitempty"`
+	Step        string   `json:"step,omitempty"`
+	FailureKind string   `json:"failure_kind,omitempty"`
+	Error       string   `json:"error,omitempty"`
+	Test        *RunTest `json:"test,omitempty"`
+}
+
+// RunTest is the verdict of the hardware test that followed a flash.
+type RunTest struct {
+	Passed  bool   `json:"passed"`
+	Summary string `json:"summary,omitempty"`
+}
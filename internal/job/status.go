@@ -0,0 +1,48 @@
+package job
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxStatusJobIDs bounds how many jobs one POST /v1/jobs/status call may ask
+// about.
+const MaxStatusJobIDs = 500
+
+// StatusRequest is the body of POST /v1/jobs/status.
+type StatusRequest struct {
+	JobIDs []string `json:"job_ids"`
+}
+
+// StatusResponse lists the requested jobs in request order, without
+// duplicates. IDs the server does not know are reported in Missing.
+type StatusResponse struct {
+	Jobs    []Record `json:"jobs"`
+	Missing []string `json:"missing,omitempty"`
+}
+
+const (
+	// DefaultListLimit is the GET /v1/jobs page size when no limit is given.
+	DefaultListLimit = 50
+	// MaxListLimit bounds the page size of GET /v1/jobs.
+	MaxListLimit = 500
+)
+
+// JobList is one page of GET /v1/jobs, newest first. Total counts every job
+// matching the state filter, so clients can page with Offset.
+type JobList struct {
+	Items  []Record `json:"items"`
+	Total  int      `json:"total"`
+	Limit  int      `json:"limit"`
+	Offset int      `json:"offset"`
+}
+
+// ParseState accepts a state name in any case, e.g. "failed".
+func ParseState(s string) (State, error) {
+	state := State(strings.ToUpper(strings.TrimSpace(s)))
+	switch state {
+	case StateQueued, StateRunning, StateSucceeded, StateFailed, StateCanceled:
+		return state, nil
+	}
+	return "", fmt.Errorf("unknown job state %q", s)
+}
@@ -0,0 +1,35 @@
+package job
+
+import "github.com/mblsha/spadeforge/internal/manifest"
+
+// SubmitResponse is the body returned by POST /v1/jobs. Manifest is the
+// server's parsed and normalized view of the bundle manifest so clients can
+// spot anything the server rewrote.
+type SubmitResponse struct {
+	JobID   string `json:"job_id"`
+	Project string `json:"project"`
+	State   State  `json:"state"`
+
+	Manifest manifest.Manifest `json:"manifest"`
+	// Warnings are non-fatal manifest lint findings.
+	Warnings []manifest.FieldError `json:"warnings,omitempty"`
+
+	// QueuePosition is 1 for the next job to start and 0 once the job is
+	// already running. JobsAhead also counts jobs currently running.
+	QueuePosition int `json:"queue_position"`
+	JobsAhead     int `json:"jobs_ahead"`
+	// CachedFrom is set when the server answered from its build cache; the
+	// job is then already SUCCEEDED with that job's artifacts.
+	CachedFrom string `json:"cached_from,omitempty"`
+
+	Limits Limits `json:"limits"`
+}
+
+// Limits reports the server-side bundle and build limits.
+type Limits struct {
+	MaxUploadBytes         int64 `json:"max_upload_bytes"`
+	MaxExtractedFiles      int   `json:"max_extracted_files"`
+	MaxExtractedTotalBytes int64 `json:"max_extracted_total_bytes"`
+	MaxExtractedFileBytes  int64 `json:"max_extracted_file_bytes"`
+	WorkerTimeoutSeconds   int64 `json:"worker_timeout_seconds"`
+}
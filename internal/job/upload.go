@@ -0,0 +1,32 @@
+package job
+
+import "time"
+
+// UploadOffsetHeader carries the byte offset of a PATCH /v1/uploads/{id}
+// chunk.
+const UploadOffsetHeader = "Upload-Offset"
+
+// UploadRequest is the body of POST /v1/uploads. Submitter and NoCache
+// apply to the job the finalized upload becomes, as the form fields of the
+// same names do on POST /v1/jobs.
+type UploadRequest struct {
+	Size      int64  `json:"size"`
+	SHA256    string `json:"sha256"`
+	Submitter string `json:"submitter,omitempty"`
+	NoCache   bool   `json:"no_cache,omitempty"`
+}
+
+// Upload is a resumable bundle upload. Offset is how many bytes the server
+// holds; a client resumes by sending the chunk starting there.
+type Upload struct {
+	ID        string    `json:"upload_id"`
+	Size      int64     `json:"size"`
+	Offset    int64     `json:"offset"`
+	SHA256    string    `json:"sha256"`
+	Submitter string    `json:"submitter,omitempty"`
+	NoCache   bool      `json:"no_cache,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// ExpiresAt moves forward with every chunk; idle uploads are removed
+	// after it.
+	ExpiresAt time.Time `json:"expires_at"`
+}
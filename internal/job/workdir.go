@@ -0,0 +1,10 @@
+package job
+
+import "time"
+
+// WorkDirEntry describes one file in a preserved job work dir.
+type WorkDirEntry struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
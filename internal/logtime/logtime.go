@@ -0,0 +1,88 @@
+// Package logtime records when each line of a log was written, in an index
+// file next to the log, so a reader can fetch just the lines written after
+// a point in time while the log itself stays plain text for parsers.
+package logtime
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IndexPath returns the index file kept for logPath.
+func IndexPath(logPath string) string {
+	return logPath + ".times"
+}
+
+// Writer passes writes through to a log and, for each line, appends
+// "<RFC3339Nano> <byte offset>" to the index, stamped when the line's
+// first byte arrived.
+type Writer struct {
+	mu        sync.Mutex
+	out       io.Writer
+	idx       io.Writer
+	offset    int64
+	lineStart bool
+	now       func() time.Time
+}
+
+// NewWriter returns a Writer logging to out and indexing into idx.
+func NewWriter(out, idx io.Writer) *Writer {
+	return &Writer{out: out, idx: idx, lineStart: true, now: time.Now}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n, err := w.out.Write(p)
+	if n <= 0 {
+		return n, err
+	}
+	stamp := w.now().UTC().Format(time.RFC3339Nano)
+	for i := 0; i < n; {
+		if w.lineStart {
+			// Index failures only cost since-filtering, never the log.
+			_, _ = fmt.Fprintf(w.idx, "%s %d\n", stamp, w.offset+int64(i))
+			w.lineStart = false
+		}
+		nl := bytes.IndexByte(p[i:n], '\n')
+		if nl < 0 {
+			break
+		}
+		i += nl + 1
+		w.lineStart = true
+	}
+	w.offset += int64(n)
+	return n, err
+}
+
+// Offset reads an index and returns the byte offset of the first line
+// written at or after since. ok is false when no line is that recent.
+func Offset(idx io.Reader, since time.Time) (offset int64, ok bool, err error) {
+	sc := bufio.NewScanner(idx)
+	for sc.Scan() {
+		stampText, offText, found := strings.Cut(sc.Text(), " ")
+		if !found {
+			continue
+		}
+		stamp, err := time.Parse(time.RFC3339Nano, stampText)
+		if err != nil {
+			continue
+		}
+		if stamp.Before(since) {
+			continue
+		}
+		off, err := strconv.ParseInt(offText, 10, 64)
+		if err != nil {
+			continue
+		}
+		return off, true, nil
+	}
+	return 0, false, sc.Err()
+}
@@ -0,0 +1,49 @@
+package logtime
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriter_IndexesLineStartsAcrossWrites(t *testing.T) {
+	var log, idx bytes.Buffer
+	w := NewWriter(&log, &idx)
+	base := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	clock := base
+	w.now = func() time.Time { return clock }
+
+	_, _ = w.Write([]byte("one\ntw"))
+	clock = base.Add(time.Minute)
+	_, _ = w.Write([]byte("o\nthree\n"))
+	clock = base.Add(2 * time.Minute)
+	_, _ = w.Write([]byte("four"))
+
+	if log.String() != "one\ntwo\nthree\nfour" {
+		t.Fatalf("log = %q", log.String())
+	}
+	want := strings.Join([]string{
+		"2026-10-16T12:00:00Z 0",
+		"2026-10-16T12:00:00Z 4",
+		"2026-10-16T12:01:00Z 8",
+		"2026-10-16T12:02:00Z 14",
+	}, "\n") + "\n"
+	if idx.String() != want {
+		t.Fatalf("index = %q, want %q", idx.String(), want)
+	}
+
+	for since, wantOff := range map[time.Time]int64{
+		base.Add(-time.Hour):       0,
+		base.Add(30 * time.Second): 8,
+		base.Add(2 * time.Minute):  14,
+	} {
+		off, ok, err := Offset(strings.NewReader(idx.String()), since)
+		if err != nil || !ok || off != wantOff {
+			t.Fatalf("Offset(%s) = %d, %v, %v; want %d", since, off, ok, err, wantOff)
+		}
+	}
+	if _, ok, _ := Offset(strings.NewReader(idx.String()), base.Add(time.Hour)); ok {
+		t.Fatal("expected no line after the last write")
+	}
+}
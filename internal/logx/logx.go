@@ -0,0 +1,183 @@
+// Package logx adds runtime-adjustable verbosity to the standard logger.
+// Each subsystem logs through its own Logger, and the global level can be
+// overridden per module without restarting the server.
+package logx
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Level orders log verbosity; higher levels are noisier.
+type Level int
+
+const (
+	LevelError Level = iota
+	LevelWarn
+	LevelInfo
+	LevelDebug
+	LevelTrace
+)
+
+var levelNames = []string{"error", "warn", "info", "debug", "trace"}
+
+func (l Level) String() string {
+	if l < LevelError || l > LevelTrace {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel accepts a level name, case-insensitively.
+func ParseLevel(s string) (Level, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	for i, n := range levelNames {
+		if n == name {
+			return Level(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q (want one of %s)", s, strings.Join(levelNames, ", "))
+}
+
+// Module names that can be filtered individually.
+const (
+	ModuleQueue     = "queue"
+	ModuleBuilder   = "builder"
+	ModuleDiscovery = "discovery"
+	ModuleHTTP      = "http"
+)
+
+// Modules lists every filterable module.
+var Modules = []string{ModuleQueue, ModuleBuilder, ModuleDiscovery, ModuleHTTP}
+
+// Settings is the serialisable form of the active filters. Modules maps a
+// module name to its level; modules without an entry use Level.
+type Settings struct {
+	Level   string            `json:"level"`
+	Modules map[string]string `json:"modules"`
+}
+
+var (
+	mu        sync.RWMutex
+	baseLevel = LevelInfo
+	overrides = map[string]Level{}
+)
+
+// Current returns the active filters.
+func Current() Settings {
+	mu.RLock()
+	defer mu.RUnlock()
+	s := Settings{Level: baseLevel.String(), Modules: make(map[string]string, len(overrides))}
+	for module, level := range overrides {
+		s.Modules[module] = level.String()
+	}
+	return s
+}
+
+// Apply updates the filters. An empty Level keeps the current global level.
+// Module entries are merged into the existing overrides; an empty value or
+// "default" removes a module's override. Nothing changes if any entry is
+// invalid.
+func Apply(s Settings) error {
+	base := Level(-1)
+	if strings.TrimSpace(s.Level) != "" {
+		level, err := ParseLevel(s.Level)
+		if err != nil {
+			return err
+		}
+		base = level
+	}
+	set := map[string]Level{}
+	var clear []string
+	for module, raw := range s.Modules {
+		module = strings.ToLower(strings.TrimSpace(module))
+		if !knownModule(module) {
+			return fmt.Errorf("unknown log module %q (want one of %s)", module, strings.Join(Modules, ", "))
+		}
+		raw = strings.TrimSpace(raw)
+		if raw == "" || strings.EqualFold(raw, "default") {
+			clear = append(clear, module)
+			continue
+		}
+		level, err := ParseLevel(raw)
+		if err != nil {
+			return fmt.Errorf("module %s: %w", module, err)
+		}
+		set[module] = level
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if base >= 0 {
+		baseLevel = base
+	}
+	for _, module := range clear {
+		delete(overrides, module)
+	}
+	for module, level := range set {
+		overrides[module] = level
+	}
+	return nil
+}
+
+// ParseModules parses CSV `module=level` entries as used by
+// SPADEFORGE_LOG_MODULES.
+func ParseModules(entries []string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, entry := range entries {
+		module, level, ok := strings.Cut(entry, "=")
+		module = strings.ToLower(strings.TrimSpace(module))
+		if !ok || module == "" {
+			return nil, fmt.Errorf("invalid log module entry %q (want module=level)", entry)
+		}
+		if !knownModule(module) {
+			return nil, fmt.Errorf("unknown log module %q (want one of %s)", module, strings.Join(Modules, ", "))
+		}
+		if _, err := ParseLevel(level); err != nil {
+			return nil, fmt.Errorf("module %s: %w", module, err)
+		}
+		out[module] = strings.ToLower(strings.TrimSpace(level))
+	}
+	return out, nil
+}
+
+func knownModule(module string) bool {
+	return slices.Contains(Modules, module)
+}
+
+// Logger writes to the standard logger on behalf of one module.
+type Logger struct {
+	module string
+}
+
+// For returns the Logger of module.
+func For(module string) Logger {
+	return Logger{module: module}
+}
+
+// Enabled reports whether messages at level would be written.
+func (l Logger) Enabled(level Level) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	limit, ok := overrides[l.module]
+	if !ok {
+		limit = baseLevel
+	}
+	return level <= limit
+}
+
+// Logf writes a message at level if the module's filter allows it.
+func (l Logger) Logf(level Level, format string, args ...any) {
+	if l.Enabled(level) {
+		log.Printf(format, args...)
+	}
+}
+
+func (l Logger) Errorf(format string, args ...any) { l.Logf(LevelError, format, args...) }
+func (l Logger) Warnf(format string, args ...any)  { l.Logf(LevelWarn, format, args...) }
+func (l Logger) Infof(format string, args ...any)  { l.Logf(LevelInfo, format, args...) }
+func (l Logger) Debugf(format string, args ...any) { l.Logf(LevelDebug, format, args...) }
+func (l Logger) Tracef(format string, args ...any) { l.Logf(LevelTrace, format, args...) }
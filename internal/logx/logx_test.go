@@ -0,0 +1,74 @@
+package logx
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func resetFilters(t *testing.T) {
+	t.Helper()
+	reset := func() {
+		mu.Lock()
+		baseLevel = LevelInfo
+		overrides = map[string]Level{}
+		mu.Unlock()
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestApply_ModuleOverridesGlobalLevel(t *testing.T) {
+	resetFilters(t)
+	var buf bytes.Buffer
+	prevOut, prevFlags := log.Writer(), log.Flags()
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	t.Cleanup(func() {
+		log.SetOutput(prevOut)
+		log.SetFlags(prevFlags)
+	})
+
+	queue, discovery := For(ModuleQueue), For(ModuleDiscovery)
+	discovery.Debugf("hidden at info")
+	if err := Apply(Settings{Modules: map[string]string{ModuleDiscovery: "trace"}}); err != nil {
+		t.Fatal(err)
+	}
+	discovery.Tracef("discovery trace")
+	queue.Debugf("queue debug")
+	queue.Infof("queue info")
+	if got, want := buf.String(), "discovery trace\nqueue info\n"; got != want {
+		t.Fatalf("log output = %q, want %q", got, want)
+	}
+
+	if err := Apply(Settings{Level: "warn", Modules: map[string]string{ModuleDiscovery: "default"}}); err != nil {
+		t.Fatal(err)
+	}
+	if discovery.Enabled(LevelInfo) || !queue.Enabled(LevelWarn) {
+		t.Fatalf("expected warn to apply to every module after clearing the override")
+	}
+	if got := Current(); got.Level != "warn" || len(got.Modules) != 0 {
+		t.Fatalf("unexpected settings: %+v", got)
+	}
+}
+
+func TestApply_RejectsInvalidEntriesAtomically(t *testing.T) {
+	resetFilters(t)
+	for _, s := range []Settings{
+		{Level: "verbose"},
+		{Level: "debug", Modules: map[string]string{"scheduler": "trace"}},
+		{Modules: map[string]string{ModuleHTTP: "debug", ModuleQueue: "loud"}},
+	} {
+		err := Apply(s)
+		if err == nil {
+			t.Fatalf("expected error for %+v", s)
+		}
+		if !strings.Contains(err.Error(), "unknown") {
+			t.Fatalf("unexpected error for %+v: %v", s, err)
+		}
+	}
+	if got := Current(); got.Level != "info" || len(got.Modules) != 0 {
+		t.Fatalf("expected filters unchanged after rejected updates, got %+v", got)
+	}
+}
@@ -0,0 +1,127 @@
+package manifest
+
+import (
+	"strings"
+
+	"github.com/mblsha/spadeforge/internal/artifactname"
+)
+
+// Follow-up action kinds, as reported in job records.
+const (
+	ActionFlash   = "flash"
+	ActionWebhook = "webhook"
+	ActionSubmit  = "submit"
+)
+
+// Action is one on_success follow-up the server runs after the build
+// succeeds, so a build→flash→test chain does not depend on the submitting
+// machine staying awake. Exactly one of Flash, Webhook and Submit is set.
+type Action struct {
+	Flash   *FlashAction   `json:"flash,omitempty"`
+	Webhook *WebhookAction `json:"webhook,omitempty"`
+	Submit  *SubmitAction  `json:"submit,omitempty"`
+}
+
+// FlashAction sends design.bit to a spadeloader. Loader names an entry of
+// the server's SPADEFORGE_LOADERS; DesignName is an artifactname template
+// and defaults to "{project}".
+type FlashAction struct {
+	Loader     string `json:"loader"`
+	Board      string `json:"board"`
+	DesignName string `json:"design_name,omitempty"`
+	DryRun     bool   `json:"dry_run,omitempty"`
+}
+
+// WebhookAction POSTs the finished job record to the URL the server
+// configures for Name in SPADEFORGE_WEBHOOKS.
+type WebhookAction struct {
+	Name string `json:"name"`
+}
+
+// SubmitAction queues a follow-up build of the same bundle with the given
+// fields replaced, e.g. a simulation top. Project is an artifactname
+// template; empty fields keep the finished job's values. OnSuccess is the
+// follow-up's own chain; the finished job's actions are not inherited.
+type SubmitAction struct {
+	Project   string   `json:"project,omitempty"`
+	Top       string   `json:"top,omitempty"`
+	Part      string   `json:"part,omitempty"`
+	Toolchain string   `json:"toolchain,omitempty"`
+	Build     *Build   `json:"build,omitempty"`
+	OnSuccess []Action `json:"on_success,omitempty"`
+}
+
+// Kind names the action that is set, or "" when none or several are.
+func (a Action) Kind() string {
+	kind, n := "", 0
+	if a.Flash != nil {
+		kind, n = ActionFlash, n+1
+	}
+	if a.Webhook != nil {
+		kind, n = ActionWebhook, n+1
+	}
+	if a.Submit != nil {
+		kind, n = ActionSubmit, n+1
+	}
+	if n != 1 {
+		return ""
+	}
+	return kind
+}
+
+// Target is the loader or webhook name, or the follow-up project template.
+func (a Action) Target() string {
+	switch {
+	case a.Flash != nil:
+		return a.Flash.Loader
+	case a.Webhook != nil:
+		return a.Webhook.Name
+	case a.Submit != nil:
+		return a.Submit.Project
+	}
+	return ""
+}
+
+// validateActions normalizes actions in place and records problems under
+// the pointer prefix.
+func validateActions(verr *ValidationError, prefix []any, actions []Action) {
+	for i := range actions {
+		a := &actions[i]
+		at := append(append([]any{}, prefix...), i)
+		switch a.Kind() {
+		case ActionFlash:
+			a.Flash.Loader = strings.TrimSpace(a.Flash.Loader)
+			a.Flash.Board = strings.TrimSpace(a.Flash.Board)
+			if a.Flash.Loader == "" {
+				verr.add(pointer(append(at, "flash", "loader")...), "loader is required", nil)
+			}
+			if a.Flash.Board == "" {
+				verr.add(pointer(append(at, "flash", "board")...), "board is required", nil)
+			}
+			if a.Flash.DesignName != "" {
+				if err := artifactname.Validate(a.Flash.DesignName); err != nil {
+					verr.add(pointer(append(at, "flash", "design_name")...), err.Error(), a.Flash.DesignName)
+				}
+			}
+		case ActionWebhook:
+			a.Webhook.Name = strings.TrimSpace(a.Webhook.Name)
+			if a.Webhook.Name == "" {
+				verr.add(pointer(append(at, "webhook", "name")...), "name is required", nil)
+			}
+		case ActionSubmit:
+			s := a.Submit
+			if s.Project != "" {
+				if err := artifactname.Validate(s.Project); err != nil {
+					verr.add(pointer(append(at, "submit", "project")...), err.Error(), s.Project)
+				}
+			}
+			s.Toolchain = strings.ToLower(strings.TrimSpace(s.Toolchain))
+			if s.Toolchain != "" && s.Toolchain != ToolchainVivado && s.Toolchain != ToolchainYosys {
+				verr.add(pointer(append(at, "submit", "toolchain")...), `toolchain must be "vivado" or "yosys"`, s.Toolchain)
+			}
+			validateActions(verr, append(at, "submit", "on_success"), s.OnSuccess)
+		default:
+			verr.add(pointer(at...), "exactly one of flash, webhook or submit is required", nil)
+		}
+	}
+}
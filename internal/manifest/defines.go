@@ -0,0 +1,40 @@
+package manifest
+
+import (
+	"regexp"
+	"sort"
+)
+
+var (
+	hdlName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*$`)
+	// hdlValue accepts numbers, sized literals such as 8'hFF, reals and
+	// identifiers. Anything else could break out of the synthesis script.
+	hdlValue = regexp.MustCompile(`^[A-Za-z0-9_'.+-]+$`)
+)
+
+// SortedKeys returns the keys of a manifest map such as defines in order,
+// so generated scripts are stable.
+func SortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// validateDefines checks the names and values of defines or top_params. A
+// define may have an empty value; a parameter needs one.
+func validateDefines(verr *ValidationError, field string, values map[string]string, valueRequired bool) {
+	for _, name := range SortedKeys(values) {
+		value := values[name]
+		switch {
+		case !hdlName.MatchString(name):
+			verr.add(pointer(field, name), "name must be a Verilog identifier", name)
+		case value == "" && valueRequired:
+			verr.add(pointer(field, name), "value is required", value)
+		case value != "" && !hdlValue.MatchString(value):
+			verr.add(pointer(field, name), "value must be a number, sized literal or identifier", value)
+		}
+	}
+}
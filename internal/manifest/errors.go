@@ -0,0 +1,66 @@
+package manifest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FieldError describes one manifest problem. Path is a JSON pointer
+// (RFC 6901) into manifest.json, e.g. "/sources/2".
+type FieldError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+	Value   any    `json:"value,omitempty"`
+}
+
+func (e FieldError) String() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// ValidationError collects every problem found by Validate so a submitter
+// can fix them in one round trip.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		parts = append(parts, fe.String())
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (e *ValidationError) add(path, message string, value any) {
+	e.Errors = append(e.Errors, FieldError{Path: path, Message: message, Value: value})
+}
+
+func (e *ValidationError) errOrNil() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+// pointer builds a JSON pointer from field names and indexes, escaping "~"
+// and "/" as RFC 6901 requires.
+func pointer(parts ...any) string {
+	var b strings.Builder
+	for _, p := range parts {
+		b.WriteByte('/')
+		switch v := p.(type) {
+		case int:
+			b.WriteString(strconv.Itoa(v))
+		default:
+			s := fmt.Sprint(v)
+			s = strings.ReplaceAll(s, "~", "~0")
+			s = strings.ReplaceAll(s, "/", "~1")
+			b.WriteString(s)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,62 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Normalize trims the fields, lowercases the commit and drops credentials
+// from the remote URL.
+func (g *GitInfo) Normalize() {
+	g.Commit = strings.ToLower(strings.TrimSpace(g.Commit))
+	g.Branch = strings.TrimSpace(g.Branch)
+	g.Remote = RedactRemote(g.Remote)
+}
+
+// Validate checks that the commit is a hex object name.
+func (g GitInfo) Validate() error {
+	if g.Commit == "" {
+		return errors.New("git commit is required")
+	}
+	if len(g.Commit) < 7 || len(g.Commit) > 64 {
+		return fmt.Errorf("git commit %q must be 7-64 hex digits", g.Commit)
+	}
+	for _, r := range g.Commit {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return fmt.Errorf("git commit %q must be 7-64 hex digits", g.Commit)
+		}
+	}
+	return nil
+}
+
+// Short returns the abbreviated commit, suffixed with "-dirty" when the
+// working tree had uncommitted changes.
+func (g GitInfo) Short() string {
+	short := g.Commit
+	if len(short) > 12 {
+		short = short[:12]
+	}
+	if g.Dirty {
+		short += "-dirty"
+	}
+	return short
+}
+
+// RedactRemote strips user info (tokens, passwords) from an http(s) or ssh
+// remote URL. scp-style remotes like git@host:repo are kept as is.
+func RedactRemote(remote string) string {
+	remote = strings.TrimSpace(remote)
+	if !strings.Contains(remote, "://") {
+		return remote
+	}
+	u, err := url.Parse(remote)
+	if err != nil || u.User == nil {
+		return remote
+	}
+	if _, hasPassword := u.User.Password(); hasPassword || u.Scheme == "http" || u.Scheme == "https" {
+		u.User = nil
+	}
+	return u.String()
+}
@@ -0,0 +1,121 @@
+package manifest
+
+import (
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	svBlockComment    = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	svLineComment     = regexp.MustCompile(`//[^\n]*`)
+	vhdlLineComment   = regexp.MustCompile(`--[^\n]*`)
+	svModulePattern   = regexp.MustCompile(`(?m)^[ \t]*(?:macromodule|module)[ \t]+(?:(?:automatic|static)[ \t]+)?([A-Za-z_][A-Za-z0-9_$]*)`)
+	vhdlEntityPattern = regexp.MustCompile(`(?im)^[ \t]*entity[ \t]+([A-Za-z][A-Za-z0-9_]*)[ \t]+is\b`)
+)
+
+// hdlLanguage classifies a source path by extension; "" means not HDL.
+func hdlLanguage(p string) string {
+	switch strings.ToLower(path.Ext(p)) {
+	case ".sv", ".v", ".svh", ".vh":
+		return "verilog"
+	case ".vhd", ".vhdl":
+		return "vhdl"
+	default:
+		return ""
+	}
+}
+
+// DeclaredModules does a cheap, comment-aware scan of the HDL sources under
+// root and returns the sorted, de-duplicated Verilog/SystemVerilog module and
+// VHDL entity names they declare. Unreadable or non-HDL sources are skipped.
+func DeclaredModules(root string, sources []string) []string {
+	seen := map[string]struct{}{}
+	for _, src := range sources {
+		lang := hdlLanguage(src)
+		if lang == "" {
+			continue
+		}
+		full, err := safeJoin(root, src)
+		if err != nil {
+			continue
+		}
+		raw, err := os.ReadFile(full)
+		if err != nil {
+			continue
+		}
+		for _, name := range scanDeclarations(lang, string(raw)) {
+			seen[name] = struct{}{}
+		}
+	}
+	out := make([]string, 0, len(seen))
+	for name := range seen {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func scanDeclarations(lang, text string) []string {
+	var matches [][]string
+	switch lang {
+	case "verilog":
+		text = svBlockComment.ReplaceAllString(text, "")
+		text = svLineComment.ReplaceAllString(text, "")
+		matches = svModulePattern.FindAllStringSubmatch(text, -1)
+	case "vhdl":
+		text = vhdlLineComment.ReplaceAllString(text, "")
+		matches = vhdlEntityPattern.FindAllStringSubmatch(text, -1)
+	}
+	out := make([]string, 0, len(matches))
+	for _, m := range matches {
+		out = append(out, m[1])
+	}
+	return out
+}
+
+// hasHDLSources reports whether any source can be scanned by DeclaredModules.
+func hasHDLSources(sources []string) bool {
+	for _, src := range sources {
+		if hdlLanguage(src) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// RankCandidates orders declared names by edit distance to want (ties keep
+// alphabetical order) and returns at most limit of them.
+func RankCandidates(want string, declared []string, limit int) []string {
+	ranked := append([]string(nil), declared...)
+	lw := strings.ToLower(want)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return levenshtein(lw, strings.ToLower(ranked[i])) < levenshtein(lw, strings.ToLower(ranked[j]))
+	})
+	if limit > 0 && len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,83 @@
+package manifest
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Source languages. Sources are read as SystemVerilog unless their
+// extension or source_options say otherwise.
+const (
+	LanguageSystemVerilog = "systemverilog"
+	LanguageVerilog       = "verilog"
+	LanguageVHDL          = "vhdl"
+	LanguageVHDL2008      = "vhdl2008"
+)
+
+// Languages lists every language a source may be read as.
+var Languages = []string{LanguageSystemVerilog, LanguageVerilog, LanguageVHDL, LanguageVHDL2008}
+
+var libraryPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// SourceOption overrides how one source is read. Library is the HDL
+// library the source is compiled into; empty uses the tool's default
+// (xil_defaultlib in Vivado).
+type SourceOption struct {
+	Language string `json:"language,omitempty"`
+	Library  string `json:"library,omitempty"`
+}
+
+// SourceLanguage returns the language src is read as: its source_options
+// entry, else VHDL for .vhd/.vhdl files, else SystemVerilog.
+func (m Manifest) SourceLanguage(src string) string {
+	if lang := m.SourceOptions[src].Language; lang != "" {
+		return lang
+	}
+	if hdlLanguage(src) == "vhdl" {
+		return LanguageVHDL
+	}
+	return LanguageSystemVerilog
+}
+
+// IsVHDL reports whether lang is one of the VHDL languages.
+func IsVHDL(lang string) bool {
+	return lang == LanguageVHDL || lang == LanguageVHDL2008
+}
+
+// validateSourceOptions normalizes source_options in place. Keys are
+// cleaned like sources and must name one.
+func validateSourceOptions(verr *ValidationError, m *Manifest) {
+	if len(m.SourceOptions) == 0 {
+		m.SourceOptions = nil
+		return
+	}
+	cleaned := make(map[string]SourceOption, len(m.SourceOptions))
+	for _, key := range SortedKeys(m.SourceOptions) {
+		opt := m.SourceOptions[key]
+		src, err := sanitizePath(key)
+		if err != nil {
+			verr.add(pointer("source_options", key), err.Error(), key)
+			continue
+		}
+		if !slices.Contains(m.Sources, src) {
+			verr.add(pointer("source_options", key), "not listed in sources", key)
+			continue
+		}
+		if _, dup := cleaned[src]; dup {
+			verr.add(pointer("source_options", key), fmt.Sprintf("%s is given twice", src), key)
+			continue
+		}
+		opt.Language = strings.ToLower(strings.TrimSpace(opt.Language))
+		if opt.Language != "" && !slices.Contains(Languages, opt.Language) {
+			verr.add(pointer("source_options", key, "language"), "language must be one of "+strings.Join(Languages, ", "), opt.Language)
+		}
+		opt.Library = strings.TrimSpace(opt.Library)
+		if opt.Library != "" && !libraryPattern.MatchString(opt.Library) {
+			verr.add(pointer("source_options", key, "library"), "library must be letters, digits and '_', starting with a letter", opt.Library)
+		}
+		cleaned[src] = opt
+	}
+	m.SourceOptions = cleaned
+}
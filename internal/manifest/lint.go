@@ -0,0 +1,92 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Lint runs non-fatal checks on a manifest that already passed Validate and
+// returns warnings in the same shape as validation errors.
+func (m Manifest) Lint(root string) []FieldError {
+	var out []FieldError
+
+	if len(m.Constraints) == 0 && isPhysicalPart(m.Part) {
+		out = append(out, FieldError{
+			Path:    pointer("constraints"),
+			Message: fmt.Sprintf("no constraints for physical part %s; I/O pins will be unplaced and bitstream generation may fail", m.Part),
+		})
+	}
+
+	out = append(out, lintDuplicates("sources", m.Sources)...)
+	out = append(out, lintDuplicates("constraints", m.Constraints)...)
+
+	if hasHDLSources(m.Sources) {
+		if w, ok := lintTop(m.Top, DeclaredModules(root, m.Sources)); ok {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+func lintDuplicates(field string, items []string) []FieldError {
+	var out []FieldError
+	first := map[string]int{}
+	for i, item := range items {
+		if j, dup := first[item]; dup {
+			out = append(out, FieldError{
+				Path:    pointer(field, i),
+				Message: fmt.Sprintf("duplicate of %s", pointer(field, j)),
+				Value:   item,
+			})
+			continue
+		}
+		first[item] = i
+	}
+	return out
+}
+
+func lintTop(top string, declared []string) (FieldError, bool) {
+	for _, name := range declared {
+		if name == top {
+			return FieldError{}, false
+		}
+	}
+	for _, name := range declared {
+		if strings.EqualFold(name, top) {
+			return FieldError{
+				Path:    pointer("top"),
+				Message: fmt.Sprintf("top %q differs only in case from declared module %q", top, name),
+				Value:   top,
+			}, true
+		}
+	}
+	return FieldError{
+		Path:    pointer("top"),
+		Message: fmt.Sprintf("top %q is not declared in any source (candidates: %s)", top, FormatCandidates(RankCandidates(top, declared, 0))),
+		Value:   top,
+	}, true
+}
+
+// FormatCandidates renders module names for messages, truncating long lists.
+func FormatCandidates(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+	const limit = 8
+	if len(names) > limit {
+		return strings.Join(names[:limit], ", ") + fmt.Sprintf(", ... %d more", len(names)-limit)
+	}
+	return strings.Join(names, ", ")
+}
+
+// isPhysicalPart reports whether part names a Xilinx device, as opposed to a
+// placeholder used for synthesis-only experiments.
+func isPhysicalPart(part string) bool {
+	p := strings.ToLower(strings.TrimSpace(part))
+	for _, prefix := range []string{"xc", "xa", "xq"} {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
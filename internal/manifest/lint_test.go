@@ -0,0 +1,96 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSource(t *testing.T, root, rel, content string) {
+	t.Helper()
+	full := filepath.Join(root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func lintPaths(warnings []FieldError) map[string]FieldError {
+	out := map[string]FieldError{}
+	for _, w := range warnings {
+		out[w.Path] = w
+	}
+	return out
+}
+
+func TestDeclaredModules_SkipsCommentsAndReadsVHDL(t *testing.T) {
+	root := t.TempDir()
+	writeSource(t, root, "hdl/a.sv", "// module commented;\n/* module blocked;\n*/\nmodule top(input clk);\nendmodule\n  module automatic helper;\nendmodule\n")
+	writeSource(t, root, "hdl/b.vhd", "-- entity ghost is\nENTITY blinky IS\nend entity;\n")
+	writeSource(t, root, "hdl/notes.txt", "module not_hdl;\n")
+
+	got := DeclaredModules(root, []string{"hdl/a.sv", "hdl/b.vhd", "hdl/notes.txt"})
+	if strings.Join(got, ",") != "blinky,helper,top" {
+		t.Fatalf("unexpected modules: %v", got)
+	}
+}
+
+func TestLint_ReportsMissingConstraintsDuplicatesAndTopCase(t *testing.T) {
+	root := t.TempDir()
+	writeSource(t, root, "hdl/top.sv", "module Top;\nendmodule\n")
+	m := Manifest{
+		Project: "demo",
+		Top:     "top",
+		Part:    "xc7a35tcsg324-1",
+		Sources: []string{"hdl/top.sv", "hdl/top.sv"},
+	}
+	got := lintPaths(m.Lint(root))
+	if _, ok := got["/constraints"]; !ok {
+		t.Fatalf("expected missing constraints warning, got %v", got)
+	}
+	if w, ok := got["/sources/1"]; !ok || w.Message != "duplicate of /sources/0" {
+		t.Fatalf("expected duplicate source warning, got %v", got)
+	}
+	if w, ok := got["/top"]; !ok || !strings.Contains(w.Message, "differs only in case") {
+		t.Fatalf("expected top case warning, got %v", got)
+	}
+}
+
+func TestLint_CleanManifestHasNoWarnings(t *testing.T) {
+	root := t.TempDir()
+	writeSource(t, root, "hdl/top.sv", "module top;\nendmodule\n")
+	m := Manifest{
+		Project:     "demo",
+		Top:         "top",
+		Part:        "xc7a35tcsg324-1",
+		Sources:     []string{"hdl/top.sv"},
+		Constraints: []string{"constraints/top.xdc"},
+	}
+	if got := m.Lint(root); len(got) != 0 {
+		t.Fatalf("expected no warnings, got %v", got)
+	}
+}
+
+func TestLint_TopNotDeclaredListsCandidates(t *testing.T) {
+	root := t.TempDir()
+	writeSource(t, root, "hdl/a.sv", "module alpha;\nendmodule\nmodule beta;\nendmodule\n")
+	m := Manifest{Top: "gamma", Part: "placeholder", Sources: []string{"hdl/a.sv"}}
+	got := lintPaths(m.Lint(root))
+	w, ok := got["/top"]
+	if !ok || !strings.Contains(w.Message, "alpha, beta") {
+		t.Fatalf("expected candidates in top warning, got %v", got)
+	}
+	if _, ok := got["/constraints"]; ok {
+		t.Fatalf("did not expect constraints warning for non-physical part")
+	}
+}
+
+func TestRankCandidates_OrdersByEditDistance(t *testing.T) {
+	got := RankCandidates("blinky_tpo", []string{"adder", "blinky_top", "blinky"}, 2)
+	if strings.Join(got, ",") != "blinky_top,blinky" {
+		t.Fatalf("unexpected ranking: %v", got)
+	}
+}
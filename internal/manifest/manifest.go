@@ -7,22 +7,130 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+
+	"github.com/mblsha/spadeforge/internal/artifactname"
+	"github.com/mblsha/spadeforge/internal/pathglob"
 )
 
 type Build struct {
 	Steps []string `json:"steps,omitempty"`
+	// Mode picks the Vivado flow: the non-project batch flow (the default)
+	// or, experimentally, a project built with launch_runs.
+	Mode string `json:"mode,omitempty"`
+	// BoardPart sets the project's board_part, e.g.
+	// digilentinc.com:arty-a7-35:part0:1.1. It needs project mode.
+	BoardPart string `json:"board_part,omitempty"`
+}
+
+// Build modes.
+const (
+	BuildModeNonProject = "non_project"
+	BuildModeProject    = "project"
+)
+
+var boardPartPattern = regexp.MustCompile(`^[A-Za-z0-9_.:-]+$`)
+
+// validateBuild normalizes the build mode and checks the board part.
+func validateBuild(verr *ValidationError, b *Build) {
+	b.Mode = strings.ToLower(strings.TrimSpace(b.Mode))
+	if b.Mode != "" && b.Mode != BuildModeNonProject && b.Mode != BuildModeProject {
+		verr.add(pointer("build", "mode"), `mode must be "non_project" or "project"`, b.Mode)
+	}
+	b.BoardPart = strings.TrimSpace(b.BoardPart)
+	switch {
+	case b.BoardPart == "":
+	case b.Mode != BuildModeProject:
+		verr.add(pointer("build", "board_part"), `board_part needs mode "project"`, b.BoardPart)
+	case !boardPartPattern.MatchString(b.BoardPart):
+		verr.add(pointer("build", "board_part"), "board_part must be letters, digits and '_', '.', ':' or '-'", b.BoardPart)
+	}
+}
+
+// ArtifactRules selects which work-dir outputs are copied into the job
+// artifacts (Include) and which artifacts are dropped (Exclude). Patterns are
+// slash-separated globs relative to the work dir where "**" spans directories.
+// BitstreamName is an artifactname template for an extra, descriptively
+// named copy of design.bit.
+type ArtifactRules struct {
+	Include       []string `json:"include,omitempty"`
+	Exclude       []string `json:"exclude,omitempty"`
+	BitstreamName string   `json:"bitstream_name,omitempty"`
+}
+
+// SuppressRule hides a known, benign diagnostic. Code is matched
+// case-insensitively and may use path.Match wildcards ("Synth 8-7*"). File,
+// when set, is a pathglob pattern matched against the diagnostic's file or
+// any trailing part of it, so "hdl/debug/*.sv" also matches absolute paths.
+type SuppressRule struct {
+	Code   string `json:"code"`
+	File   string `json:"file,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// DiagnosticsRules configures how parsed diagnostics are reported.
+type DiagnosticsRules struct {
+	Suppress []SuppressRule `json:"suppress,omitempty"`
 }
 
+// GitInfo records the source revision a bundle was built from.
+// spadeforge-cli fills it in when submitting from a git checkout.
+type GitInfo struct {
+	Commit string `json:"commit"`
+	Branch string `json:"branch,omitempty"`
+	Dirty  bool   `json:"dirty,omitempty"`
+	Remote string `json:"remote,omitempty"`
+}
+
+// Toolchains a manifest may ask for. An empty toolchain uses the server's
+// default builder.
+const (
+	ToolchainVivado = "vivado"
+	ToolchainYosys  = "yosys"
+)
+
 type Manifest struct {
-	Schema      int      `json:"schema"`
-	Project     string   `json:"project,omitempty"`
-	Top         string   `json:"top"`
-	Part        string   `json:"part"`
-	Sources     []string `json:"sources"`
-	Constraints []string `json:"constraints,omitempty"`
-	IncludeDirs []string `json:"include_dirs,omitempty"`
-	Build       Build    `json:"build,omitempty"`
+	Schema  int      `json:"schema"`
+	Project string   `json:"project,omitempty"`
+	Top     string   `json:"top"`
+	Part    string   `json:"part"`
+	Sources []string `json:"sources"`
+	// SourceOptions override the language and library of sources, keyed
+	// by their path in Sources.
+	SourceOptions map[string]SourceOption `json:"source_options,omitempty"`
+	Constraints   []string                `json:"constraints,omitempty"`
+	IncludeDirs   []string                `json:"include_dirs,omitempty"`
+	// IP lists Xilinx IP cores (.xci/.xcix) and BlockDesigns lists block
+	// designs (.bd, or a .tcl script from write_bd_tcl). Vivado generates
+	// their output products before synthesis, so each IP or .bd should sit
+	// in its own directory as Vivado lays it out.
+	IP           []string `json:"ip,omitempty"`
+	BlockDesigns []string `json:"block_designs,omitempty"`
+	// Defines are Verilog macros set while reading every source. An empty
+	// value defines the name without one.
+	Defines map[string]string `json:"defines,omitempty"`
+	// TopParams override parameters (VHDL generics) of the top module.
+	TopParams map[string]string `json:"top_params,omitempty"`
+	Build     Build             `json:"build,omitempty"`
+	// Toolchain selects the builder for this job ("vivado" or "yosys").
+	Toolchain string `json:"toolchain,omitempty"`
+
+	Artifacts   ArtifactRules    `json:"artifacts,omitempty"`
+	Diagnostics DiagnosticsRules `json:"diagnostics,omitempty"`
+
+	Git *GitInfo `json:"git,omitempty"`
+
+	// OnSuccess lists follow-ups the server runs once the build succeeds.
+	OnSuccess []Action `json:"on_success,omitempty"`
+
+	// Retry overrides the server's retry policy for transient failures.
+	Retry *RetryPolicy `json:"retry,omitempty"`
+
+	// Secrets are server-side files placed into the sources while the
+	// build runs, so keys never travel inside the bundle.
+	Secrets []SecretRef `json:"secrets,omitempty"`
 }
 
 func Parse(raw []byte) (Manifest, error) {
@@ -36,70 +144,149 @@ func Parse(raw []byte) (Manifest, error) {
 	return m, nil
 }
 
+// Validate normalizes the manifest in place and checks it against the
+// extracted bundle at root. All problems are reported together as a
+// *ValidationError.
 func (m *Manifest) Validate(root string) error {
+	verr := &ValidationError{}
+
 	m.Project = strings.TrimSpace(m.Project)
 	if m.Project == "" {
-		return errors.New("project is required")
+		verr.add(pointer("project"), "project is required", nil)
 	}
 	if strings.TrimSpace(m.Top) == "" {
-		return errors.New("top is required")
+		verr.add(pointer("top"), "top is required", nil)
 	}
 	if strings.TrimSpace(m.Part) == "" {
-		return errors.New("part is required")
+		verr.add(pointer("part"), "part is required", nil)
 	}
 	if len(m.Sources) == 0 {
-		return errors.New("at least one source is required")
+		verr.add(pointer("sources"), "at least one source is required", nil)
 	}
 
-	cleanedSources, err := sanitizeList(m.Sources)
-	if err != nil {
-		return fmt.Errorf("sources: %w", err)
+	m.Toolchain = strings.ToLower(strings.TrimSpace(m.Toolchain))
+	if m.Toolchain != "" && m.Toolchain != ToolchainVivado && m.Toolchain != ToolchainYosys {
+		verr.add(pointer("toolchain"), `toolchain must be "vivado" or "yosys"`, m.Toolchain)
 	}
-	cleanedConstraints, err := sanitizeList(m.Constraints)
-	if err != nil {
-		return fmt.Errorf("constraints: %w", err)
+	validateBuild(verr, &m.Build)
+
+	m.Sources = sanitizeList(verr, "sources", m.Sources)
+	m.Constraints = sanitizeList(verr, "constraints", m.Constraints)
+	m.IncludeDirs = sanitizeList(verr, "include_dirs", m.IncludeDirs)
+	m.IP = sanitizeList(verr, "ip", m.IP)
+	m.BlockDesigns = sanitizeList(verr, "block_designs", m.BlockDesigns)
+	validateSourceOptions(verr, m)
+
+	validateDefines(verr, "defines", m.Defines, false)
+	validateDefines(verr, "top_params", m.TopParams, true)
+
+	for i, pattern := range m.Artifacts.Include {
+		if err := pathglob.Validate(pattern); err != nil {
+			verr.add(pointer("artifacts", "include", i), err.Error(), pattern)
+		}
 	}
-	cleanedIncludeDirs, err := sanitizeList(m.IncludeDirs)
-	if err != nil {
-		return fmt.Errorf("include_dirs: %w", err)
+	for i, pattern := range m.Artifacts.Exclude {
+		if err := pathglob.Validate(pattern); err != nil {
+			verr.add(pointer("artifacts", "exclude", i), err.Error(), pattern)
+		}
+	}
+
+	if m.Artifacts.BitstreamName != "" {
+		if err := artifactname.Validate(m.Artifacts.BitstreamName); err != nil {
+			verr.add(pointer("artifacts", "bitstream_name"), err.Error(), m.Artifacts.BitstreamName)
+		}
+	}
+
+	for i, rule := range m.Diagnostics.Suppress {
+		if err := rule.Validate(); err != nil {
+			verr.add(pointer("diagnostics", "suppress", i), err.Error(), rule.Code)
+		}
+	}
+
+	validateActions(verr, []any{"on_success"}, m.OnSuccess)
+	if m.Retry != nil {
+		validateRetry(verr, m.Retry)
+	}
+
+	if m.Git != nil {
+		m.Git.Normalize()
+		if err := m.Git.Validate(); err != nil {
+			verr.add(pointer("git", "commit"), err.Error(), m.Git.Commit)
+		}
 	}
 
-	m.Sources = cleanedSources
-	m.Constraints = cleanedConstraints
-	m.IncludeDirs = cleanedIncludeDirs
+	validateSecrets(verr, root, m)
 
-	for _, source := range m.Sources {
+	for i, source := range m.Sources {
+		if source == "" {
+			continue
+		}
 		if err := fileExistsUnderRoot(root, source); err != nil {
-			return fmt.Errorf("source %q: %w", source, err)
+			verr.add(pointer("sources", i), describeMissing("source", err), source)
 		}
 	}
-	for _, c := range m.Constraints {
+	for i, c := range m.Constraints {
+		if c == "" {
+			continue
+		}
 		if err := fileExistsUnderRoot(root, c); err != nil {
-			return fmt.Errorf("constraint %q: %w", c, err)
+			verr.add(pointer("constraints", i), describeMissing("constraint", err), c)
 		}
 	}
-	for _, d := range m.IncludeDirs {
+	checkFiles(verr, root, "ip", "ip", m.IP, ".xci", ".xcix")
+	checkFiles(verr, root, "block_designs", "block design", m.BlockDesigns, ".bd", ".tcl")
+	for i, d := range m.IncludeDirs {
+		if d == "" {
+			continue
+		}
 		if err := dirExistsUnderRoot(root, d); err != nil {
-			return fmt.Errorf("include_dir %q: %w", d, err)
+			verr.add(pointer("include_dirs", i), describeMissing("include dir", err), d)
 		}
 	}
 
-	return nil
+	return verr.errOrNil()
 }
 
-func sanitizeList(items []string) ([]string, error) {
+// sanitizeList cleans every entry, recording failures under field. Entries
+// that fail keep their position as "" so later checks can skip them while
+// indexes in error paths still match the submitted manifest.
+func sanitizeList(verr *ValidationError, field string, items []string) []string {
 	if len(items) == 0 {
-		return nil, nil
+		return nil
 	}
 	out := make([]string, 0, len(items))
-	for _, item := range items {
+	for i, item := range items {
 		cleaned, err := sanitizePath(item)
 		if err != nil {
-			return nil, err
+			verr.add(pointer(field, i), err.Error(), item)
 		}
 		out = append(out, cleaned)
 	}
-	return out, nil
+	return out
+}
+
+// checkFiles checks that every non-empty entry of field has one of exts
+// and exists in the bundle.
+func checkFiles(verr *ValidationError, root, field, kind string, items []string, exts ...string) {
+	for i, item := range items {
+		if item == "" {
+			continue
+		}
+		if !slices.Contains(exts, strings.ToLower(path.Ext(item))) {
+			verr.add(pointer(field, i), fmt.Sprintf("%s must be a %s file", kind, strings.Join(exts, " or ")), item)
+			continue
+		}
+		if err := fileExistsUnderRoot(root, item); err != nil {
+			verr.add(pointer(field, i), describeMissing(kind, err), item)
+		}
+	}
+}
+
+func describeMissing(kind string, err error) string {
+	if errors.Is(err, os.ErrNotExist) {
+		return kind + " not found in bundle"
+	}
+	return fmt.Sprintf("%s: %v", kind, err)
 }
 
 func sanitizePath(p string) (string, error) {
@@ -1,8 +1,10 @@
 package manifest
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -78,3 +80,283 @@ func TestManifestValidate_Success(t *testing.T) {
 		t.Fatalf("validate failed: %v", err)
 	}
 }
+
+func TestManifestValidate_RejectsInvalidArtifactGlob(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "hdl"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "hdl", "spade.sv"), []byte("module top;endmodule\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := Manifest{
+		Project:   "demo",
+		Top:       "top",
+		Part:      "xc7",
+		Sources:   []string{"hdl/spade.sv"},
+		Artifacts: ArtifactRules{Exclude: []string{"["}},
+	}
+	if err := m.Validate(root); err == nil {
+		t.Fatalf("expected invalid artifact glob rejection")
+	}
+}
+
+func TestManifestValidate_ReportsAllErrorsWithPointers(t *testing.T) {
+	root := t.TempDir()
+	m := Manifest{
+		Part:        "xc7",
+		Sources:     []string{"hdl/missing.sv", "../escape.sv"},
+		Constraints: []string{"/abs.xdc"},
+		Artifacts:   ArtifactRules{Include: []string{"ok/**", "["}},
+	}
+	err := m.Validate(root)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *ValidationError, got %T %v", err, err)
+	}
+
+	got := map[string]FieldError{}
+	for _, fe := range verr.Errors {
+		got[fe.Path] = fe
+	}
+	for _, path := range []string{"/project", "/top", "/sources/0", "/sources/1", "/constraints/0", "/artifacts/include/1"} {
+		if _, ok := got[path]; !ok {
+			t.Fatalf("missing error for %s in %v", path, verr.Errors)
+		}
+	}
+	if got["/sources/1"].Value != "../escape.sv" {
+		t.Fatalf("expected offending value, got %#v", got["/sources/1"].Value)
+	}
+	if got["/sources/0"].Message != "source not found in bundle" {
+		t.Fatalf("unexpected message: %q", got["/sources/0"].Message)
+	}
+}
+
+func TestManifestValidate_ChecksOnSuccessActions(t *testing.T) {
+	m := Manifest{
+		OnSuccess: []Action{
+			{Flash: &FlashAction{Loader: " bench ", Board: "arty", DesignName: "{project}-{git_short}"}},
+			{Flash: &FlashAction{Loader: "bench"}, Webhook: &WebhookAction{Name: "ci"}},
+			{Submit: &SubmitAction{Project: "{nope}", OnSuccess: []Action{{Webhook: &WebhookAction{}}}}},
+		},
+	}
+	err := m.Validate(t.TempDir())
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *ValidationError, got %T %v", err, err)
+	}
+	got := map[string]bool{}
+	for _, fe := range verr.Errors {
+		got[fe.Path] = true
+	}
+	for _, path := range []string{"/on_success/1", "/on_success/2/submit/project", "/on_success/2/submit/on_success/0/webhook/name"} {
+		if !got[path] {
+			t.Fatalf("missing error for %s in %v", path, verr.Errors)
+		}
+	}
+	if got["/on_success/0/flash/loader"] || got["/on_success/0/flash/design_name"] {
+		t.Fatalf("valid flash action rejected: %v", verr.Errors)
+	}
+	if m.OnSuccess[0].Flash.Loader != "bench" || m.OnSuccess[0].Kind() != ActionFlash {
+		t.Fatalf("flash action not normalized: %+v", m.OnSuccess[0].Flash)
+	}
+}
+
+func TestManifestValidate_ChecksSecrets(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "keys"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "keys", "bundled.nky"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m := Manifest{
+		Secrets: []SecretRef{
+			{Name: "aes_key"},
+			{Name: "../etc/passwd"},
+			{Name: "other", Path: "keys/bundled.nky"},
+			{Name: "dup", Path: "secrets/aes_key"},
+			{Name: "abs", Path: "/tmp/key"},
+			{Name: "src", Path: "./hdl/top.sv"},
+			{Name: "ipkey", Path: "ip/clk.xci"},
+		},
+		Sources: []string{"hdl/top.sv"},
+		IP:      []string{"ip/clk.xci"},
+	}
+	err := m.Validate(root)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *ValidationError, got %T %v", err, err)
+	}
+	got := map[string]string{}
+	for _, fe := range verr.Errors {
+		got[fe.Path] = fe.Message
+	}
+	for _, path := range []string{"/secrets/1/name", "/secrets/2/path", "/secrets/3/path", "/secrets/4/path"} {
+		if _, ok := got[path]; !ok {
+			t.Fatalf("missing error for %s in %v", path, verr.Errors)
+		}
+	}
+	if _, ok := got["/secrets/0/name"]; ok {
+		t.Fatalf("valid secret rejected: %v", verr.Errors)
+	}
+	if m.Secrets[0].Path != "secrets/aes_key" {
+		t.Fatalf("default secret path = %q", m.Secrets[0].Path)
+	}
+	if got["/secrets/2/path"] != "path already exists in bundle" {
+		t.Fatalf("unexpected message: %q", got["/secrets/2/path"])
+	}
+	if got["/secrets/5/path"] != "path is used by sources" || got["/secrets/6/path"] != "path is used by ip" {
+		t.Fatalf("unexpected collision messages: %q, %q", got["/secrets/5/path"], got["/secrets/6/path"])
+	}
+}
+
+func TestManifestValidate_ChecksDefinesAndTopParams(t *testing.T) {
+	m := Manifest{
+		Defines:   map[string]string{"SIM": "", "CLK_HZ": "100000000", "1BAD": "1", "EVIL": "1} ; exec rm"},
+		TopParams: map[string]string{"WIDTH": "8", "INIT": "8'hFF", "EMPTY": ""},
+	}
+	err := m.Validate(t.TempDir())
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *ValidationError, got %T %v", err, err)
+	}
+	got := map[string]string{}
+	for _, fe := range verr.Errors {
+		got[fe.Path] = fe.Message
+	}
+	for path, msg := range map[string]string{
+		"/defines/1BAD":     "name must be a Verilog identifier",
+		"/defines/EVIL":     "value must be a number, sized literal or identifier",
+		"/top_params/EMPTY": "value is required",
+	} {
+		if got[path] != msg {
+			t.Fatalf("error for %s = %q, want %q (all: %v)", path, got[path], msg, verr.Errors)
+		}
+	}
+	for _, path := range []string{"/defines/SIM", "/defines/CLK_HZ", "/top_params/WIDTH", "/top_params/INIT"} {
+		if _, ok := got[path]; ok {
+			t.Fatalf("valid entry %s rejected: %v", path, verr.Errors)
+		}
+	}
+}
+
+func TestManifestValidate_ChecksSourceOptions(t *testing.T) {
+	m := Manifest{
+		Sources: []string{"hdl/top.sv", "hdl/core.vhd", "hdl/legacy.v"},
+		SourceOptions: map[string]SourceOption{
+			"./hdl/core.vhd": {Language: " VHDL2008 ", Library: "work_lib"},
+			"hdl/legacy.v":   {Language: "ada", Library: "1lib"},
+			"hdl/missing.v":  {Language: "verilog"},
+		},
+	}
+	err := m.Validate(t.TempDir())
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *ValidationError, got %T %v", err, err)
+	}
+	got := map[string]string{}
+	for _, fe := range verr.Errors {
+		got[fe.Path] = fe.Message
+	}
+	for path, msg := range map[string]string{
+		"/source_options/hdl~1missing.v":         "not listed in sources",
+		"/source_options/hdl~1legacy.v/language": "language must be one of systemverilog, verilog, vhdl, vhdl2008",
+		"/source_options/hdl~1legacy.v/library":  "library must be letters, digits and '_', starting with a letter",
+	} {
+		if got[path] != msg {
+			t.Fatalf("error for %s = %q, want %q (all: %v)", path, got[path], msg, verr.Errors)
+		}
+	}
+	if opt := m.SourceOptions["hdl/core.vhd"]; opt.Language != LanguageVHDL2008 || opt.Library != "work_lib" {
+		t.Fatalf("core.vhd options not normalized: %+v", m.SourceOptions)
+	}
+	for src, want := range map[string]string{"hdl/top.sv": LanguageSystemVerilog, "hdl/core.vhd": LanguageVHDL2008} {
+		if lang := m.SourceLanguage(src); lang != want {
+			t.Fatalf("SourceLanguage(%s) = %q, want %q", src, lang, want)
+		}
+	}
+	if lang := (Manifest{}).SourceLanguage("rtl/uart.VHDL"); lang != LanguageVHDL {
+		t.Fatalf("SourceLanguage(.VHDL) = %q, want vhdl", lang)
+	}
+}
+
+func TestManifestValidate_ChecksIPAndBlockDesigns(t *testing.T) {
+	root := t.TempDir()
+	for _, rel := range []string{"hdl/top.sv", "ip/clk/clk.xci", "bd/system/system.bd", "bd/mb.tcl"} {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := Manifest{
+		Project:      "demo",
+		Top:          "top",
+		Part:         "xc7a35tcsg324-1",
+		Sources:      []string{"hdl/top.sv"},
+		IP:           []string{"ip/clk/clk.xci", "ip/fifo/fifo.xci", "hdl/top.sv"},
+		BlockDesigns: []string{"bd/system/system.bd", "./bd/mb.tcl", "bd/system.xpr"},
+	}
+	err := m.Validate(root)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *ValidationError, got %T %v", err, err)
+	}
+	want := map[string]string{
+		"/ip/1":            "ip not found in bundle",
+		"/ip/2":            "ip must be a .xci or .xcix file",
+		"/block_designs/2": "block design must be a .bd or .tcl file",
+	}
+	if len(verr.Errors) != len(want) {
+		t.Fatalf("expected %d errors, got %v", len(want), verr.Errors)
+	}
+	for _, fe := range verr.Errors {
+		if want[fe.Path] != fe.Message {
+			t.Fatalf("error for %s = %q, want %q", fe.Path, fe.Message, want[fe.Path])
+		}
+	}
+	if m.BlockDesigns[1] != "bd/mb.tcl" {
+		t.Fatalf("block design path not cleaned: %q", m.BlockDesigns[1])
+	}
+}
+
+func TestManifestValidate_ChecksBuildMode(t *testing.T) {
+	for _, tc := range []struct {
+		build Build
+		path  string
+		msg   string
+	}{
+		{Build{Mode: " Project ", BoardPart: "digilentinc.com:arty-a7-35:part0:1.1"}, "", ""},
+		{Build{Mode: "ide"}, "/build/mode", `mode must be "non_project" or "project"`},
+		{Build{BoardPart: "digilentinc.com:arty-a7-35:part0:1.1"}, "/build/board_part", `board_part needs mode "project"`},
+		{Build{Mode: "project", BoardPart: "arty; exit"}, "/build/board_part", "board_part must be letters, digits and '_', '.', ':' or '-'"},
+	} {
+		m := Manifest{Build: tc.build}
+		var verr *ValidationError
+		if !errors.As(m.Validate(t.TempDir()), &verr) {
+			t.Fatalf("%+v: expected *ValidationError", tc.build)
+		}
+		got := ""
+		for _, fe := range verr.Errors {
+			if strings.HasPrefix(fe.Path, "/build/") {
+				got = fe.Path + " " + fe.Message
+			}
+		}
+		if want := strings.TrimSpace(tc.path + " " + tc.msg); got != want {
+			t.Fatalf("%+v: got %q, want %q", tc.build, got, want)
+		}
+		if tc.path == "" && m.Build.Mode != BuildModeProject {
+			t.Fatalf("mode not normalized: %q", m.Build.Mode)
+		}
+	}
+}
+
+func TestPointer_EscapesSpecialCharacters(t *testing.T) {
+	if got := pointer("a/b", "c~d", 3); got != "/a~1b/c~0d/3" {
+		t.Fatalf("unexpected pointer: %s", got)
+	}
+}
@@ -0,0 +1,44 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxRetriesLimit caps max_retries so a broken build cannot occupy the
+// builder indefinitely.
+const MaxRetriesLimit = 10
+
+// RetryPolicy requeues a failed job whose failure kind is in RetryOn, up
+// to MaxRetries times. An empty RetryOn uses the server's default kinds.
+type RetryPolicy struct {
+	MaxRetries int      `json:"max_retries"`
+	RetryOn    []string `json:"retry_on,omitempty"`
+}
+
+// Retries reports whether a job that failed with kind, after already being
+// retried the given number of times, should run again.
+func (p RetryPolicy) Retries(kind string, retried int) bool {
+	if retried >= p.MaxRetries {
+		return false
+	}
+	for _, k := range p.RetryOn {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// validateRetry normalizes the retry policy in place.
+func validateRetry(verr *ValidationError, p *RetryPolicy) {
+	if p.MaxRetries < 0 || p.MaxRetries > MaxRetriesLimit {
+		verr.add(pointer("retry", "max_retries"), fmt.Sprintf("max_retries must be between 0 and %d", MaxRetriesLimit), p.MaxRetries)
+	}
+	for i, kind := range p.RetryOn {
+		p.RetryOn[i] = strings.ToLower(strings.TrimSpace(kind))
+		if p.RetryOn[i] == "" {
+			verr.add(pointer("retry", "retry_on", i), "failure kind cannot be empty", kind)
+		}
+	}
+}
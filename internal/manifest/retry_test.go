@@ -0,0 +1,33 @@
+package manifest
+
+import "testing"
+
+func TestRetryPolicy_Retries(t *testing.T) {
+	p := RetryPolicy{MaxRetries: 2, RetryOn: []string{"internal", "license"}}
+	if !p.Retries("license", 0) || !p.Retries("internal", 1) {
+		t.Fatalf("expected retries within budget")
+	}
+	if p.Retries("internal", 2) {
+		t.Fatalf("expected budget of 2 retries to be exhausted")
+	}
+	if p.Retries("syntax", 0) {
+		t.Fatalf("syntax errors are not in retry_on")
+	}
+}
+
+func TestValidate_ReportsBadRetryPolicy(t *testing.T) {
+	root := t.TempDir()
+	writeSource(t, root, "hdl/top.sv", "module top; endmodule\n")
+	m := Manifest{
+		Project: "demo", Top: "top", Part: "xc7a35t", Sources: []string{"hdl/top.sv"},
+		Retry: &RetryPolicy{MaxRetries: MaxRetriesLimit + 1, RetryOn: []string{" License ", ""}},
+	}
+	err := m.Validate(root)
+	verr, ok := err.(*ValidationError)
+	if !ok || len(verr.Errors) != 2 || verr.Errors[0].Path != "/retry/max_retries" || verr.Errors[1].Path != "/retry/retry_on/1" {
+		t.Fatalf("expected retry errors, got %v", err)
+	}
+	if m.Retry.RetryOn[0] != "license" {
+		t.Fatalf("retry_on not normalized: %q", m.Retry.RetryOn)
+	}
+}
@@ -0,0 +1,99 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// SecretRef asks the server to place one of its named secrets, such as a
+// bitstream encryption key or a license string, into the source tree for
+// the duration of the build. Path is relative to the bundle root and
+// defaults to secrets/<name>; the bundle must not contain a file there and
+// no source, constraint, IP or block design may be listed there.
+type SecretRef struct {
+	Name string `json:"name"`
+	Path string `json:"path,omitempty"`
+}
+
+var secretNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ValidSecretName reports whether name can name a file in the server's
+// secrets directory.
+func ValidSecretName(name string) bool {
+	return len(name) <= 128 && secretNamePattern.MatchString(name)
+}
+
+// validateSecrets normalizes the secret references in place. A secret may
+// not take the place of a file the manifest lists, since the server would
+// overwrite it during the build and delete it afterwards.
+func validateSecrets(verr *ValidationError, root string, m *Manifest) {
+	refs := m.Secrets
+	listed := m.listedFiles()
+	seen := map[string]bool{}
+	for i := range refs {
+		ref := &refs[i]
+		if !ValidSecretName(ref.Name) {
+			verr.add(pointer("secrets", i, "name"), "name must be letters, digits, '.', '_' or '-'", ref.Name)
+			continue
+		}
+		if ref.Path == "" {
+			ref.Path = "secrets/" + ref.Name
+		}
+		cleaned, err := sanitizePath(ref.Path)
+		if err != nil {
+			verr.add(pointer("secrets", i, "path"), err.Error(), ref.Path)
+			continue
+		}
+		ref.Path = cleaned
+		if seen[cleaned] {
+			verr.add(pointer("secrets", i, "path"), "path is used by another secret", cleaned)
+			continue
+		}
+		seen[cleaned] = true
+		if field := secretCollision(listed, cleaned); field != "" {
+			verr.add(pointer("secrets", i, "path"), "path is used by "+field, cleaned)
+			continue
+		}
+		if err := fileExistsUnderRoot(root, cleaned); err == nil {
+			verr.add(pointer("secrets", i, "path"), "path already exists in bundle", cleaned)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			verr.add(pointer("secrets", i, "path"), fmt.Sprintf("secret path: %v", err), cleaned)
+		}
+	}
+}
+
+// listedFiles maps the cleaned bundle paths the manifest names to the
+// field that names them.
+func (m *Manifest) listedFiles() map[string]string {
+	listed := map[string]string{"manifest.json": "manifest.json"}
+	for _, field := range []struct {
+		name  string
+		paths []string
+	}{
+		{"sources", m.Sources},
+		{"constraints", m.Constraints},
+		{"ip", m.IP},
+		{"block_designs", m.BlockDesigns},
+	} {
+		for _, p := range field.paths {
+			if cleaned, err := sanitizePath(p); err == nil {
+				listed[cleaned] = field.name
+			}
+		}
+	}
+	return listed
+}
+
+// secretCollision returns the field listing secret, a file secret would
+// sit inside, or a file under secret, or "" when there is none.
+func secretCollision(listed map[string]string, secret string) string {
+	for p, field := range listed {
+		if p == secret || strings.HasPrefix(secret, p+"/") || strings.HasPrefix(p, secret+"/") {
+			return field
+		}
+	}
+	return ""
+}
@@ -0,0 +1,70 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/mblsha/spadeforge/internal/pathglob"
+)
+
+// Validate checks that the rule has a code and well-formed patterns.
+func (r SuppressRule) Validate() error {
+	code := strings.TrimSpace(r.Code)
+	if code == "" {
+		return errors.New("suppress rule code is required")
+	}
+	if _, err := path.Match(strings.ToLower(code), ""); err != nil {
+		return fmt.Errorf("invalid suppress code pattern %q: %w", code, err)
+	}
+	if strings.TrimSpace(r.File) != "" {
+		if err := pathglob.Validate(r.File); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the rule applies to a diagnostic with the given
+// code and file.
+func (r SuppressRule) Matches(code, file string) bool {
+	pattern := strings.ToLower(strings.TrimSpace(r.Code))
+	if pattern == "" {
+		return false
+	}
+	if ok, _ := path.Match(pattern, strings.ToLower(strings.TrimSpace(code))); !ok {
+		return false
+	}
+	glob := strings.TrimSpace(r.File)
+	if glob == "" {
+		return true
+	}
+	name := strings.Trim(strings.ReplaceAll(file, "\\", "/"), "/")
+	for name != "" {
+		if pathglob.Match(glob, name) {
+			return true
+		}
+		i := strings.IndexByte(name, '/')
+		if i < 0 {
+			break
+		}
+		name = name[i+1:]
+	}
+	return false
+}
+
+// ParseSuppressRules parses the server-wide CSV form, where each entry is
+// "code" or "code@fileglob".
+func ParseSuppressRules(entries []string) ([]SuppressRule, error) {
+	var rules []SuppressRule
+	for _, entry := range entries {
+		code, file, _ := strings.Cut(entry, "@")
+		rule := SuppressRule{Code: strings.TrimSpace(code), File: strings.TrimSpace(file)}
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
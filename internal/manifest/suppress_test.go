@@ -0,0 +1,52 @@
+package manifest
+
+import "testing"
+
+func TestSuppressRule_Matches(t *testing.T) {
+	tests := []struct {
+		rule SuppressRule
+		code string
+		file string
+		want bool
+	}{
+		{SuppressRule{Code: "Synth 8-3331"}, "synth 8-3331", "", true},
+		{SuppressRule{Code: "Synth 8-3331"}, "Synth 8-3332", "", false},
+		{SuppressRule{Code: "Synth 8-7*"}, "Synth 8-7129", "hdl/top.sv", true},
+		{SuppressRule{Code: "Synth 8-3331", File: "hdl/debug/*.sv"}, "Synth 8-3331", "/srv/spadeforge/work/j1/src/hdl/debug/ila.sv", true},
+		{SuppressRule{Code: "Synth 8-3331", File: "hdl/debug/*.sv"}, "Synth 8-3331", "/srv/work/j1/src/hdl/top.sv", false},
+		{SuppressRule{Code: "Synth 8-3331", File: "ila.sv"}, "Synth 8-3331", `C:\work\src\hdl\debug\ila.sv`, true},
+		{SuppressRule{Code: "Synth 8-3331", File: "*.sv"}, "Synth 8-3331", "", false},
+	}
+	for _, tt := range tests {
+		if got := tt.rule.Matches(tt.code, tt.file); got != tt.want {
+			t.Fatalf("%+v.Matches(%q, %q) = %v, want %v", tt.rule, tt.code, tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestParseSuppressRules(t *testing.T) {
+	rules, err := ParseSuppressRules([]string{"Synth 8-3331@hdl/debug/**", "DRC NSTD-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[0].File != "hdl/debug/**" || rules[1].Code != "DRC NSTD-1" || rules[1].File != "" {
+		t.Fatalf("unexpected rules: %+v", rules)
+	}
+	if _, err := ParseSuppressRules([]string{"@hdl/*.sv"}); err == nil {
+		t.Fatalf("expected error for rule without code")
+	}
+}
+
+func TestValidate_ReportsBadSuppressRules(t *testing.T) {
+	root := t.TempDir()
+	writeSource(t, root, "hdl/top.sv", "module top; endmodule\n")
+	m := Manifest{
+		Project: "demo", Top: "top", Part: "xc7a35t", Sources: []string{"hdl/top.sv"},
+		Diagnostics: DiagnosticsRules{Suppress: []SuppressRule{{Code: "Synth 8-3331"}, {Code: " "}}},
+	}
+	err := m.Validate(root)
+	verr, ok := err.(*ValidationError)
+	if !ok || len(verr.Errors) != 1 || verr.Errors[0].Path != "/diagnostics/suppress/1" {
+		t.Fatalf("expected suppress rule error, got %v", err)
+	}
+}
@@ -0,0 +1,243 @@
+// Package metrics keeps process counters, gauges and histograms and serves
+// them in the Prometheus text exposition format, without pulling in the
+// Prometheus client library.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the Prometheus text exposition format media type.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Registry holds metric families in registration order.
+type Registry struct {
+	mu       sync.Mutex
+	families []family
+}
+
+type family interface {
+	write(w *bufio.Writer)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) add(f family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.families = append(r.families, f)
+}
+
+// WriteText writes every family in the text exposition format.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	families := slices.Clone(r.families)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		f.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler serves the registry for Prometheus scrapes.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		_ = r.WriteText(w)
+	})
+}
+
+// Counter is a monotonically increasing value, optionally split by labels.
+type Counter struct {
+	name, help string
+	labels     []string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// Counter registers a counter. Inc and Add take one value per label name.
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	c := &Counter{name: name, help: help, labels: labels, values: map[string]float64{}}
+	if len(labels) == 0 {
+		c.values[""] = 0
+	}
+	r.add(c)
+	return c
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increases the counter; negative values are ignored.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	key := formatLabels(c.labels, labelValues)
+	c.mu.Lock()
+	c.values[key] += v
+	c.mu.Unlock()
+}
+
+// Value returns the counter for the given label values.
+func (c *Counter) Value(labelValues ...string) float64 {
+	key := formatLabels(c.labels, labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[key]
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.mu.Lock()
+	keys := make([]string, 0, len(c.values))
+	for k := range c.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	values := make([]float64, len(keys))
+	for i, k := range keys {
+		values[i] = c.values[k]
+	}
+	c.mu.Unlock()
+
+	writeHeader(w, c.name, c.help, "counter")
+	for i, k := range keys {
+		writeSample(w, c.name, k, values[i])
+	}
+}
+
+// valueFunc is a gauge or counter read from fn at scrape time.
+type valueFunc struct {
+	name, help, kind string
+	fn               func() float64
+}
+
+// GaugeFunc registers a gauge whose value fn reports at scrape time.
+func (r *Registry) GaugeFunc(name, help string, fn func() float64) {
+	r.add(&valueFunc{name: name, help: help, kind: "gauge", fn: fn})
+}
+
+// CounterFunc registers a counter kept elsewhere and read at scrape time.
+func (r *Registry) CounterFunc(name, help string, fn func() float64) {
+	r.add(&valueFunc{name: name, help: help, kind: "counter", fn: fn})
+}
+
+func (g *valueFunc) write(w *bufio.Writer) {
+	writeHeader(w, g.name, g.help, g.kind)
+	writeSample(w, g.name, "", g.fn())
+}
+
+// Histogram counts observations into cumulative buckets.
+type Histogram struct {
+	name, help string
+	bounds     []float64
+
+	mu     sync.Mutex
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// Histogram registers a histogram with the given ascending upper bounds;
+// the +Inf bucket is implied.
+func (r *Registry) Histogram(name, help string, bounds []float64) *Histogram {
+	bounds = slices.Clone(bounds)
+	slices.Sort(bounds)
+	h := &Histogram{name: name, help: help, bounds: bounds, counts: make([]uint64, len(bounds))}
+	r.add(h)
+	return h
+}
+
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, bound := range h.bounds {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// Count returns the number of observations.
+func (h *Histogram) Count() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.count
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.mu.Lock()
+	counts := slices.Clone(h.counts)
+	sum, count := h.sum, h.count
+	h.mu.Unlock()
+
+	writeHeader(w, h.name, h.help, "histogram")
+	for i, bound := range h.bounds {
+		writeSample(w, h.name+"_bucket", `le="`+formatFloat(bound)+`"`, float64(counts[i]))
+	}
+	writeSample(w, h.name+"_bucket", `le="+Inf"`, float64(count))
+	writeSample(w, h.name+"_sum", "", sum)
+	writeSample(w, h.name+"_count", "", float64(count))
+}
+
+func writeHeader(w *bufio.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(help), name, kind)
+}
+
+func writeSample(w *bufio.Writer, name, labels string, v float64) {
+	if labels != "" {
+		fmt.Fprintf(w, "%s{%s} %s\n", name, labels, formatFloat(v))
+		return
+	}
+	fmt.Fprintf(w, "%s %s\n", name, formatFloat(v))
+}
+
+// formatLabels renders name="value" pairs; missing values are empty.
+func formatLabels(names, values []string) string {
+	parts := make([]string, len(names))
+	for i, name := range names {
+		v := ""
+		if i < len(values) {
+			v = values[i]
+		}
+		parts[i] = name + `="` + escapeLabel(v) + `"`
+	}
+	return strings.Join(parts, ",")
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
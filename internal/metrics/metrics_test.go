@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistryWritesTextFormat(t *testing.T) {
+	r := NewRegistry()
+	submitted := r.Counter("demo_jobs_submitted_total", "Jobs accepted.")
+	finished := r.Counter("demo_jobs_finished_total", "Jobs that finished, by state.", "state")
+	r.GaugeFunc("demo_queue_depth", "Queued jobs.", func() float64 { return 3 })
+	h := r.Histogram("demo_duration_seconds", "Run time.", []float64{10, 1})
+
+	submitted.Inc()
+	submitted.Add(2)
+	finished.Inc("succeeded")
+	finished.Inc("failed")
+	finished.Inc("failed")
+	h.Observe(0.5)
+	h.Observe(5)
+	h.Observe(50)
+
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP demo_jobs_submitted_total Jobs accepted.
+# TYPE demo_jobs_submitted_total counter
+demo_jobs_submitted_total 3
+# HELP demo_jobs_finished_total Jobs that finished, by state.
+# TYPE demo_jobs_finished_total counter
+demo_jobs_finished_total{state="failed"} 2
+demo_jobs_finished_total{state="succeeded"} 1
+# HELP demo_queue_depth Queued jobs.
+# TYPE demo_queue_depth gauge
+demo_queue_depth 3
+# HELP demo_duration_seconds Run time.
+# TYPE demo_duration_seconds histogram
+demo_duration_seconds_bucket{le="1"} 1
+demo_duration_seconds_bucket{le="10"} 2
+demo_duration_seconds_bucket{le="+Inf"} 3
+demo_duration_seconds_sum 55.5
+demo_duration_seconds_count 3
+`
+	if got := buf.String(); got != want {
+		t.Fatalf("unexpected exposition:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCounterEscapesLabelsAndIgnoresNegative(t *testing.T) {
+	r := NewRegistry()
+	c := r.Counter("demo_total", "Line one\nline two.", "kind")
+	c.Inc(`a"b\c`)
+	c.Add(-1, `a"b\c`)
+
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, `# HELP demo_total Line one\nline two.`) {
+		t.Fatalf("help not escaped:\n%s", out)
+	}
+	if !strings.Contains(out, `demo_total{kind="a\"b\\c"} 1`) {
+		t.Fatalf("label not escaped or negative add applied:\n%s", out)
+	}
+}
+
+func TestHandlerSetsContentType(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("demo_total", "Demo.")
+	rr := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rr.Header().Get("Content-Type"); ct != ContentType {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(rr.Body.String(), "demo_total 0") {
+		t.Fatalf("unexpected body: %s", rr.Body.String())
+	}
+}
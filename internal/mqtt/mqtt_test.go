@@ -0,0 +1,113 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/mqtt/mqtttest"
+)
+
+// collect reads the broker's packets up to the client's DISCONNECT.
+func collect(t *testing.T, b *mqtttest.Broker) []mqtttest.Packet {
+	t.Helper()
+	var packets []mqtttest.Packet
+	for {
+		select {
+		case p := <-b.Packets():
+			packets = append(packets, p)
+			if p.Header == packetDisconnect {
+				return packets
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for DISCONNECT; got %d packets", len(packets))
+		}
+	}
+}
+
+func readString(b []byte) (string, []byte) {
+	n := binary.BigEndian.Uint16(b)
+	return string(b[2 : 2+n]), b[2+n:]
+}
+
+func TestPublisher_ConnectsPublishesAndDisconnects(t *testing.T) {
+	broker := mqtttest.NewBroker(t, 0)
+	p, err := NewPublisher("mqtt://lab:secret@"+broker.Addr, "spadeforge-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p.Publish(context.Background(),
+		Message{Topic: "spadeforge/jobs/j1", Payload: []byte(`{"state":"SUCCEEDED"}`)},
+		Message{Topic: "spadeforge/projects/blinky", Payload: []byte("x"), Retain: true},
+	)
+	if err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	packets := collect(t, broker)
+	if len(packets) != 4 {
+		t.Fatalf("expected connect, 2 publishes, disconnect; got %d packets", len(packets))
+	}
+	proto, rest := readString(packets[0].Body)
+	if proto != "MQTT" || rest[0] != 4 || rest[1] != connectCleanSession|connectUsername|connectPassword {
+		t.Fatalf("unexpected connect header %q % x", proto, rest[:4])
+	}
+	clientID, rest := readString(rest[4:])
+	user, rest := readString(rest)
+	pass, _ := readString(rest)
+	if clientID != "spadeforge-test" || user != "lab" || pass != "secret" {
+		t.Fatalf("connect payload = %q %q %q", clientID, user, pass)
+	}
+
+	topic, payload := readString(packets[1].Body)
+	if packets[1].Header != packetPublish || topic != "spadeforge/jobs/j1" || !strings.Contains(string(payload), "SUCCEEDED") {
+		t.Fatalf("first publish = %#x %q %q", packets[1].Header, topic, payload)
+	}
+	if packets[2].Header != packetPublish|flagRetain {
+		t.Fatalf("expected the project message retained, header %#x", packets[2].Header)
+	}
+	if packets[3].Header != packetDisconnect {
+		t.Fatalf("expected disconnect, got %#x", packets[3].Header)
+	}
+}
+
+func TestPublisher_ReportsRefusedConnection(t *testing.T) {
+	broker := mqtttest.NewBroker(t, 5)
+	p, err := NewPublisher("mqtt://"+broker.Addr, "spadeforge-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Publish(context.Background(), Message{Topic: "t", Payload: []byte("x")}); err == nil || !strings.Contains(err.Error(), "not authorized") {
+		t.Fatalf("expected a not-authorized error, got %v", err)
+	}
+}
+
+func TestNewPublisher_ValidatesURL(t *testing.T) {
+	p, err := NewPublisher("mqtts://broker.lan", "id")
+	if err != nil || p.Addr() != "broker.lan:8883" || !p.useTLS {
+		t.Fatalf("mqtts default = %+v, %v", p, err)
+	}
+	for _, bad := range []string{"http://broker", "mqtt://", "broker:1883"} {
+		if _, err := NewPublisher(bad, "id"); err == nil {
+			t.Fatalf("NewPublisher(%q) should fail", bad)
+		}
+	}
+}
+
+func TestWritePacket_EncodesLongRemainingLength(t *testing.T) {
+	var b strings.Builder
+	body := make([]byte, 321)
+	if err := writePacket(&b, packetPublish, body); err != nil {
+		t.Fatal(err)
+	}
+	header, got, err := ReadPacket(bufio.NewReader(strings.NewReader(b.String())))
+	if err != nil || header != packetPublish || len(got) != 321 {
+		t.Fatalf("round trip = %#x, %d bytes, %v", header, len(got), err)
+	}
+	if raw := b.String(); raw[1] != byte(0xC1) || raw[2] != 0x02 {
+		t.Fatalf("remaining length bytes = % x", raw[1:3])
+	}
+}
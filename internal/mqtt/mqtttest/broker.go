@@ -0,0 +1,119 @@
+// Package mqtttest provides a fake MQTT broker for tests of the mqtt
+// client and the code that publishes through it. It imports nothing from
+// package mqtt so that package's own tests can use it.
+package mqtttest
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+)
+
+// Control packet types, in the high nibble of the first header byte.
+const (
+	Connect    = 0x10
+	Publish    = 0x30
+	Disconnect = 0xE0
+)
+
+// Packet is one control packet the broker received.
+type Packet struct {
+	Header byte
+	Body   []byte
+}
+
+// Type returns the packet's control packet type.
+func (p Packet) Type() byte {
+	return p.Header & 0xF0
+}
+
+// Publication decodes a QoS 0 PUBLISH packet.
+func (p Packet) Publication() (topic string, payload []byte, retain bool) {
+	n := int(binary.BigEndian.Uint16(p.Body))
+	return string(p.Body[2 : 2+n]), p.Body[2+n:], p.Header&0x01 != 0
+}
+
+// Broker accepts any number of connections on a loopback port, answers
+// CONNECT with a CONNACK carrying its return code, and passes on every
+// packet it receives, including the CONNECT.
+type Broker struct {
+	// Addr is the host:port to connect to.
+	Addr string
+
+	packets chan Packet
+}
+
+// NewBroker starts a broker that answers CONNECT with code (0 accepts the
+// connection) and stops when the test ends.
+func NewBroker(t testing.TB, code byte) *Broker {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	b := &Broker{Addr: ln.Addr().String(), packets: make(chan Packet, 64)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn, code)
+		}
+	}()
+	return b
+}
+
+// Packets delivers the received packets, in order for each connection.
+func (b *Broker) Packets() <-chan Packet {
+	return b.packets
+}
+
+func (b *Broker) serve(conn net.Conn, code byte) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		p, err := readPacket(r)
+		if err != nil {
+			return
+		}
+		if p.Type() == Connect {
+			_, _ = conn.Write([]byte{0x20, 2, 0, code})
+		}
+		b.packets <- p
+		if p.Type() == Disconnect {
+			return
+		}
+	}
+}
+
+func readPacket(r *bufio.Reader) (Packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return Packet{}, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return Packet{}, errors.New("mqtttest: malformed remaining length")
+		}
+		digit, err := r.ReadByte()
+		if err != nil {
+			return Packet{}, err
+		}
+		length += int(digit&0x7F) * multiplier
+		if digit&0x80 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return Packet{}, err
+	}
+	return Packet{Header: header, Body: body}, nil
+}
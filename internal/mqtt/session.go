@@ -0,0 +1,246 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	packetSubscribe = 0x82
+	packetSubAck    = 0x90
+	packetPingReq   = 0xC0
+	packetPingResp  = 0xD0
+)
+
+// ErrNotConnected is returned by Session.Publish between connections.
+var ErrNotConnected = errors.New("mqtt: not connected")
+
+// SessionOptions configure a long-lived Session.
+type SessionOptions struct {
+	// Will is published by the broker when the connection drops without a
+	// DISCONNECT, typically a retained "offline" availability message. The
+	// session publishes it itself before a clean shutdown.
+	Will *Message
+	// KeepAlive is the ping interval; 30s when zero.
+	KeepAlive time.Duration
+	// Subscribe lists topic filters subscribed at QoS 0 on every connect.
+	Subscribe []string
+	// OnConnect runs after each successful connect and subscribe, so
+	// retained state can be (re)published.
+	OnConnect func(s *Session)
+	// OnMessage receives every incoming publication on the session's read
+	// goroutine; it must not block for long.
+	OnMessage func(Message)
+	// OnError reports connection failures before each reconnect attempt.
+	OnError func(error)
+}
+
+// Session keeps one connection to the broker open, resubscribing and
+// calling OnConnect after every reconnect.
+type Session struct {
+	pub  *Publisher
+	opts SessionOptions
+
+	mu   sync.Mutex
+	conn net.Conn
+	w    *bufio.Writer
+}
+
+// NewSession prepares a session with the broker p points at; Run connects.
+func NewSession(p *Publisher, opts SessionOptions) *Session {
+	if opts.KeepAlive <= 0 {
+		opts.KeepAlive = 30 * time.Second
+	}
+	return &Session{pub: p, opts: opts}
+}
+
+// Run connects and serves the session until ctx is done, reconnecting with
+// backoff (1s doubling to 30s) when the connection fails.
+func (s *Session) Run(ctx context.Context) {
+	backoff := time.Second
+	for {
+		start := time.Now()
+		err := s.serve(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil && s.opts.OnError != nil {
+			s.opts.OnError(err)
+		}
+		if time.Since(start) > time.Minute {
+			backoff = time.Second
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, 30*time.Second)
+	}
+}
+
+// Publish sends msgs over the current connection.
+func (s *Session) Publish(msgs ...Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return ErrNotConnected
+	}
+	for _, msg := range msgs {
+		if err := writePublish(s.w, msg); err != nil {
+			return err
+		}
+	}
+	if err := s.w.Flush(); err != nil {
+		return fmt.Errorf("mqtt publish: %w", err)
+	}
+	return nil
+}
+
+func (s *Session) writeLocked(header byte, body []byte) error {
+	if s.conn == nil {
+		return ErrNotConnected
+	}
+	if err := writePacket(s.w, header, body); err != nil {
+		return err
+	}
+	return s.w.Flush()
+}
+
+// serve runs one connection until it fails or ctx is done.
+func (s *Session) serve(ctx context.Context) error {
+	dialCtx, cancel := context.WithTimeout(ctx, s.pub.Timeout)
+	conn, err := s.pub.dial(dialCtx)
+	cancel()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	_ = conn.SetDeadline(time.Now().Add(s.pub.Timeout))
+	if err := s.pub.handshake(w, r, s.opts.Will, s.opts.KeepAlive); err != nil {
+		return err
+	}
+	if len(s.opts.Subscribe) > 0 {
+		if err := writePacket(w, packetSubscribe, subscribeBody(1, s.opts.Subscribe)); err != nil {
+			return err
+		}
+		if err := w.Flush(); err != nil {
+			return fmt.Errorf("mqtt subscribe: %w", err)
+		}
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	s.mu.Lock()
+	s.conn, s.w = conn, w
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.conn, s.w = nil, nil
+		s.mu.Unlock()
+	}()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(s.opts.KeepAlive)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				s.mu.Lock()
+				if will := s.opts.Will; will != nil && s.conn != nil {
+					_ = writePublish(s.w, *will)
+				}
+				_ = s.writeLocked(packetDisconnect, nil)
+				s.mu.Unlock()
+				conn.Close()
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				s.mu.Lock()
+				err := s.writeLocked(packetPingReq, nil)
+				s.mu.Unlock()
+				if err != nil {
+					conn.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	if s.opts.OnConnect != nil {
+		s.opts.OnConnect(s)
+	}
+	for {
+		// The broker answers every ping, so silence for longer than two
+		// intervals means the connection is gone.
+		_ = conn.SetReadDeadline(time.Now().Add(2 * s.opts.KeepAlive))
+		header, body, err := ReadPacket(r)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("mqtt read from %s: %w", s.pub.addr, err)
+		}
+		switch header & 0xF0 {
+		case packetPublish:
+			msg, err := parsePublish(header, body)
+			if err != nil {
+				return err
+			}
+			if s.opts.OnMessage != nil {
+				s.opts.OnMessage(msg)
+			}
+		case packetSubAck:
+			if len(body) > 2 {
+				for _, code := range body[2:] {
+					if code == 0x80 {
+						return errors.New("mqtt: broker rejected a subscription")
+					}
+				}
+			}
+		case packetPingResp:
+		}
+	}
+}
+
+func subscribeBody(packetID uint16, filters []string) []byte {
+	body := binary.BigEndian.AppendUint16(nil, packetID)
+	for _, f := range filters {
+		body = appendString(body, f)
+		body = append(body, 0) // QoS 0
+	}
+	return body
+}
+
+// parsePublish decodes an incoming PUBLISH; QoS 1 and 2 publications carry
+// a packet identifier that is skipped, as subscriptions are QoS 0.
+func parsePublish(header byte, body []byte) (Message, error) {
+	if len(body) < 2 {
+		return Message{}, errors.New("mqtt: short PUBLISH")
+	}
+	n := int(binary.BigEndian.Uint16(body))
+	if len(body) < 2+n {
+		return Message{}, errors.New("mqtt: short PUBLISH topic")
+	}
+	msg := Message{Topic: string(body[2 : 2+n]), Retain: header&flagRetain != 0}
+	rest := body[2+n:]
+	if qos := (header >> 1) & 0x03; qos > 0 {
+		if len(rest) < 2 {
+			return Message{}, errors.New("mqtt: short PUBLISH packet id")
+		}
+		rest = rest[2:]
+	}
+	msg.Payload = rest
+	return msg, nil
+}
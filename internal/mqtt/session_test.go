@@ -0,0 +1,119 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSession_SubscribesReceivesAndPublishesWillOnShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	type brokerView struct {
+		connect   []byte
+		filters   []string
+		published []Message
+	}
+	result := make(chan brokerView, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		var view brokerView
+		for {
+			header, body, err := ReadPacket(r)
+			if err != nil {
+				break
+			}
+			switch header & 0xF0 {
+			case packetConnect:
+				view.connect = body
+				_, _ = conn.Write([]byte{packetConnAck, 2, 0, 0})
+			case packetSubscribe & 0xF0:
+				rest := body[2:]
+				for len(rest) > 0 {
+					n := int(binary.BigEndian.Uint16(rest))
+					view.filters = append(view.filters, string(rest[2:2+n]))
+					rest = rest[3+n:]
+				}
+				_, _ = conn.Write([]byte{packetSubAck, 3, body[0], body[1], 0})
+				// Deliver a command as a QoS 1 publication with packet id 7.
+				cmd := appendString(nil, "loader/boards/arty/flash")
+				cmd = append(cmd, 0, 7)
+				cmd = append(cmd, "PRESS"...)
+				var pkt []byte
+				pkt = append(pkt, packetPublish|0x02, byte(len(cmd)))
+				_, _ = conn.Write(append(pkt, cmd...))
+			case packetPublish:
+				msg, _ := parsePublish(header, body)
+				view.published = append(view.published, msg)
+			}
+			if header == packetDisconnect {
+				break
+			}
+		}
+		result <- view
+	}()
+
+	p, err := NewPublisher("mqtt://"+ln.Addr().String(), "loader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	received := make(chan Message, 1)
+	s := NewSession(p, SessionOptions{
+		Will:      &Message{Topic: "loader/status", Payload: []byte("offline"), Retain: true},
+		KeepAlive: time.Minute,
+		Subscribe: []string{"loader/boards/+/flash"},
+		OnConnect: func(s *Session) {
+			if err := s.Publish(Message{Topic: "loader/status", Payload: []byte("online"), Retain: true}); err != nil {
+				t.Errorf("publish on connect: %v", err)
+			}
+		},
+		OnMessage: func(m Message) { received <- m },
+		OnError:   func(err error) { t.Errorf("session error: %v", err) },
+	})
+	stopped := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(stopped)
+	}()
+
+	select {
+	case m := <-received:
+		if m.Topic != "loader/boards/arty/flash" || string(m.Payload) != "PRESS" {
+			t.Fatalf("received %q %q", m.Topic, m.Payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the command")
+	}
+	cancel()
+	<-stopped
+
+	view := <-result
+	if flags := view.connect[7]; flags&connectWill == 0 || flags&connectWillRetain == 0 {
+		t.Fatalf("connect flags %#x lack a retained will", flags)
+	}
+	if keepAlive := binary.BigEndian.Uint16(view.connect[8:]); keepAlive != 60 {
+		t.Fatalf("keepalive = %d, want 60", keepAlive)
+	}
+	if len(view.filters) != 1 || view.filters[0] != "loader/boards/+/flash" {
+		t.Fatalf("filters = %v", view.filters)
+	}
+	if len(view.published) != 2 || string(view.published[0].Payload) != "online" || string(view.published[1].Payload) != "offline" {
+		t.Fatalf("published = %+v, want online then offline", view.published)
+	}
+	if err := s.Publish(Message{Topic: "x"}); err != ErrNotConnected {
+		t.Fatalf("publish after shutdown = %v, want ErrNotConnected", err)
+	}
+}
@@ -0,0 +1,222 @@
+// Package openapi builds the OpenAPI 3 document that the spadeforge and
+// spadeloader servers serve at /openapi.json. Servers describe each route as
+// they register it, and the request and response schemas are derived by
+// reflection from the same Go types the handlers encode and the bundled
+// clients decode, so the document cannot drift from either.
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Op describes one route.
+type Op struct {
+	Summary string
+	// Query lists the query parameters.
+	Query []Param
+	// Request is a value of the JSON request body type; nil for none.
+	Request any
+	// Form lists multipart/form-data fields; Files are the file fields
+	// among them.
+	Form  []string
+	Files []string
+	// Status is the success status; zero means 200.
+	Status int
+	// Response is a value of the JSON success body type. With ContentType
+	// set instead, the body is that media type, e.g. a zip or an SSE
+	// stream.
+	Response    any
+	ContentType string
+	// Events is a value of the type each message of a text/event-stream
+	// response carries.
+	Events any
+	// Public routes need no token.
+	Public bool
+}
+
+// Param is a query parameter.
+type Param struct {
+	Name        string
+	Description string
+	// Type is a JSON schema type; empty means string.
+	Type string
+}
+
+// Spec is an OpenAPI document under construction. It is safe to serve while
+// routes are still being added.
+type Spec struct {
+	title      string
+	version    string
+	authHeader string
+
+	mu      sync.Mutex
+	paths   map[string]map[string]any
+	schemas map[string]any
+	names   map[reflect.Type]string
+}
+
+// New starts a document for a server whose token goes in authHeader.
+func New(title, version, authHeader string) *Spec {
+	return &Spec{
+		title:      title,
+		version:    version,
+		authHeader: authHeader,
+		paths:      map[string]map[string]any{},
+		schemas:    map[string]any{},
+		names:      map[reflect.Type]string{},
+	}
+}
+
+// Add documents the route registered on a ServeMux under pattern, e.g.
+// "GET /v1/jobs/{id}".
+func (s *Spec) Add(pattern string, op Op) {
+	method, route, ok := strings.Cut(pattern, " ")
+	if !ok {
+		method, route = "GET", pattern
+	}
+	route = strings.ReplaceAll(route, "...}", "}")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	operation := map[string]any{"summary": op.Summary}
+	var params []any
+	for _, name := range pathParams(route) {
+		params = append(params, map[string]any{"name": name, "in": "path", "required": true, "schema": map[string]any{"type": "string"}})
+	}
+	for _, p := range op.Query {
+		typ := p.Type
+		if typ == "" {
+			typ = "string"
+		}
+		param := map[string]any{"name": p.Name, "in": "query", "schema": map[string]any{"type": typ}}
+		if p.Description != "" {
+			param["description"] = p.Description
+		}
+		params = append(params, param)
+	}
+	if len(params) > 0 {
+		operation["parameters"] = params
+	}
+
+	switch {
+	case op.Request != nil:
+		operation["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": map[string]any{"schema": s.schemaFor(reflect.TypeOf(op.Request))}},
+		}
+	case len(op.Form) > 0 || len(op.Files) > 0:
+		props := map[string]any{}
+		for _, f := range op.Form {
+			props[f] = map[string]any{"type": "string"}
+		}
+		for _, f := range op.Files {
+			props[f] = map[string]any{"type": "string", "format": "binary"}
+		}
+		schema := map[string]any{"type": "object", "properties": props}
+		if len(op.Files) > 0 {
+			schema["required"] = op.Files
+		}
+		operation["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{"multipart/form-data": map[string]any{"schema": schema}},
+		}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]any{"description": http.StatusText(status)}
+	switch {
+	case op.Response != nil:
+		success["content"] = map[string]any{"application/json": map[string]any{"schema": s.schemaFor(reflect.TypeOf(op.Response))}}
+	case op.Events != nil:
+		success["content"] = map[string]any{"text/event-stream": map[string]any{"schema": s.schemaFor(reflect.TypeOf(op.Events))}}
+	case op.ContentType != "":
+		success["content"] = map[string]any{op.ContentType: map[string]any{"schema": map[string]any{"type": "string"}}}
+	}
+	responses := map[string]any{
+		strconv.Itoa(status): success,
+		"default":            map[string]any{"description": "Error", "content": map[string]any{"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/Error"}}}},
+	}
+	operation["responses"] = responses
+	if op.Public {
+		operation["security"] = []any{}
+	}
+
+	if s.paths[route] == nil {
+		s.paths[route] = map[string]any{}
+	}
+	s.paths[route][strings.ToLower(method)] = operation
+}
+
+// Paths returns the documented routes as "METHOD /path", sorted.
+func (s *Spec) Paths() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []string
+	for route, methods := range s.paths {
+		for method := range methods {
+			out = append(out, strings.ToUpper(method)+" "+route)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// MarshalJSON renders the document.
+func (s *Spec) MarshalJSON() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	schemas := map[string]any{
+		"Error": map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"error": map[string]any{"type": "string"}},
+		},
+	}
+	for name, schema := range s.schemas {
+		schemas[name] = schema
+	}
+	return json.Marshal(map[string]any{
+		"openapi": "3.0.3",
+		"info":    map[string]any{"title": s.title, "version": s.version},
+		"paths":   s.paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"token": map[string]any{"type": "apiKey", "in": "header", "name": s.authHeader},
+			},
+		},
+		"security": []any{map[string]any{"token": []any{}}},
+	})
+}
+
+// Handler serves the document as JSON.
+func (s *Spec) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		raw, err := json.Marshal(s)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(raw)
+	})
+}
+
+func pathParams(route string) []string {
+	var out []string
+	for _, seg := range strings.Split(route, "/") {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			out = append(out, strings.Trim(seg, "{}"))
+		}
+	}
+	return out
+}
@@ -0,0 +1,126 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type node struct {
+	Name     string        `json:"name"`
+	Note     string        `json:"note,omitempty"`
+	Parent   *node         `json:"parent,omitempty"`
+	Children []node        `json:"children"`
+	Created  time.Time     `json:"created_at"`
+	Timeout  time.Duration `json:"timeout"`
+	internal string
+	embedded
+}
+
+type embedded struct {
+	Flag bool `json:"flag"`
+}
+
+func render(t *testing.T, s *Spec) map[string]any {
+	t.Helper()
+	raw, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestAdd_DescribesRouteAndSchemas(t *testing.T) {
+	s := New("test", "v1", "X-Token")
+	s.Add("GET /v1/nodes/{id}/files/{path...}", Op{
+		Summary:  "get",
+		Query:    []Param{{Name: "limit", Type: "integer"}},
+		Response: node{},
+	})
+	s.Add("POST /v1/nodes", Op{Request: node{}, Status: http.StatusCreated, Response: node{}})
+
+	if got, want := s.Paths(), []string{"GET /v1/nodes/{id}/files/{path}", "POST /v1/nodes"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Paths() = %v, want %v", got, want)
+	}
+
+	doc := render(t, s)
+	if doc["openapi"] != "3.0.3" {
+		t.Fatalf("openapi = %v", doc["openapi"])
+	}
+	get := doc["paths"].(map[string]any)["/v1/nodes/{id}/files/{path}"].(map[string]any)["get"].(map[string]any)
+	var params []string
+	for _, p := range get["parameters"].([]any) {
+		p := p.(map[string]any)
+		params = append(params, p["in"].(string)+":"+p["name"].(string))
+	}
+	if want := []string{"path:id", "path:path", "query:limit"}; !reflect.DeepEqual(params, want) {
+		t.Fatalf("parameters = %v, want %v", params, want)
+	}
+	if _, ok := doc["paths"].(map[string]any)["/v1/nodes"].(map[string]any)["post"].(map[string]any)["responses"].(map[string]any)["201"]; !ok {
+		t.Fatalf("POST /v1/nodes is missing its 201 response")
+	}
+
+	schema := doc["components"].(map[string]any)["schemas"].(map[string]any)["node"].(map[string]any)
+	props := schema["properties"].(map[string]any)
+	for _, name := range []string{"name", "note", "parent", "children", "created_at", "timeout", "flag"} {
+		if _, ok := props[name]; !ok {
+			t.Fatalf("node schema is missing %q: %v", name, props)
+		}
+	}
+	if _, ok := props["internal"]; ok {
+		t.Fatalf("node schema includes an unexported field")
+	}
+	if got := props["parent"].(map[string]any)["$ref"]; got != "#/components/schemas/node" {
+		t.Fatalf("parent $ref = %v", got)
+	}
+	if got := props["created_at"].(map[string]any)["format"]; got != "date-time" {
+		t.Fatalf("created_at format = %v", got)
+	}
+	var required []string
+	for _, r := range schema["required"].([]any) {
+		required = append(required, r.(string))
+	}
+	if want := []string{"name", "children", "created_at", "timeout", "flag"}; !reflect.DeepEqual(required, want) {
+		t.Fatalf("required = %v, want %v", required, want)
+	}
+}
+
+func TestHandler_ServesPublicRoutesWithoutSecurity(t *testing.T) {
+	s := New("test", "v1", "X-Token")
+	s.Add("GET /healthz", Op{Public: true})
+	s.Add("POST /v1/upload", Op{Form: []string{"name"}, Files: []string{"file"}, ContentType: "text/plain"})
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	paths := doc["paths"].(map[string]any)
+	health := paths["/healthz"].(map[string]any)["get"].(map[string]any)
+	if sec, ok := health["security"].([]any); !ok || len(sec) != 0 {
+		t.Fatalf("/healthz security = %v, want empty", health["security"])
+	}
+	upload := paths["/v1/upload"].(map[string]any)["post"].(map[string]any)
+	if _, ok := upload["security"]; ok {
+		t.Fatalf("/v1/upload should inherit the document security")
+	}
+	form := upload["requestBody"].(map[string]any)["content"].(map[string]any)["multipart/form-data"].(map[string]any)["schema"].(map[string]any)
+	if got := form["properties"].(map[string]any)["file"].(map[string]any)["format"]; got != "binary" {
+		t.Fatalf("file field format = %v", got)
+	}
+	scheme := doc["components"].(map[string]any)["securitySchemes"].(map[string]any)["token"].(map[string]any)
+	if scheme["name"] != "X-Token" {
+		t.Fatalf("security scheme header = %v", scheme["name"])
+	}
+}
@@ -0,0 +1,77 @@
+package pathglob
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Match reports whether the slash-separated relative path name matches
+// pattern. Segments use path.Match syntax and "**" matches zero or more
+// whole segments. A pattern without a slash is matched against the base
+// name only, so "*.jou" matches "vivado.jou" and "runs/impl/vivado.jou".
+func Match(pattern, name string) bool {
+	pattern = strings.Trim(strings.TrimSpace(pattern), "/")
+	name = strings.Trim(name, "/")
+	if pattern == "" || name == "" {
+		return false
+	}
+	if !strings.Contains(pattern, "/") && pattern != "**" {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// MatchAny reports whether name matches at least one of patterns.
+func MatchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if Match(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that pattern is well-formed.
+func Validate(pattern string) error {
+	trimmed := strings.Trim(strings.TrimSpace(pattern), "/")
+	if trimmed == "" {
+		return fmt.Errorf("glob pattern cannot be empty")
+	}
+	for _, seg := range strings.Split(trimmed, "/") {
+		if seg == "**" {
+			continue
+		}
+		if seg == ".." {
+			return fmt.Errorf("glob pattern %q must not contain ..", pattern)
+		}
+		if _, err := path.Match(seg, ""); err != nil {
+			return fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(rest, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+	return len(name) == 0
+}
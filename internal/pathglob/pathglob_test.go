@@ -0,0 +1,41 @@
+package pathglob
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{pattern: "*.jou", name: "vivado.jou", want: true},
+		{pattern: "*.jou", name: "runs/impl/vivado.jou", want: true},
+		{pattern: "*.jou", name: "vivado.log", want: false},
+		{pattern: "reports/**", name: "reports/timing.rpt", want: true},
+		{pattern: "reports/**", name: "reports/nested/drc.rpt", want: true},
+		{pattern: "reports/**", name: "timing.rpt", want: false},
+		{pattern: "**/*.dcp", name: "post_synth.dcp", want: true},
+		{pattern: "**/*.dcp", name: "checkpoints/post_route.dcp", want: true},
+		{pattern: "checkpoints/*.dcp", name: "checkpoints/deep/post_route.dcp", want: false},
+		{pattern: "**", name: "anything/at/all", want: true},
+		{pattern: "", name: "vivado.log", want: false},
+	}
+	for _, tc := range tests {
+		if got := Match(tc.pattern, tc.name); got != tc.want {
+			t.Fatalf("Match(%q, %q) = %v, want %v", tc.pattern, tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, ok := range []string{"*.jou", "reports/**", "**/*.dcp"} {
+		if err := Validate(ok); err != nil {
+			t.Fatalf("expected %q valid: %v", ok, err)
+		}
+	}
+	for _, bad := range []string{"", "[", "../secret/*"} {
+		if err := Validate(bad); err == nil {
+			t.Fatalf("expected %q invalid", bad)
+		}
+	}
+}
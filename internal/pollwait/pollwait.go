@@ -0,0 +1,48 @@
+// Package pollwait paces job status polling for the spadeforge and
+// spadeloader clients: fast around state changes, slower while a job sits in
+// one long step.
+package pollwait
+
+import "time"
+
+// DefaultMax caps the poll interval unless the base interval is larger.
+const DefaultMax = 5 * time.Second
+
+// Backoff adapts the poll interval to job progress. The zero value is not
+// usable; create one with New.
+type Backoff struct {
+	base time.Duration
+	max  time.Duration
+	cur  time.Duration
+	last string
+	seen bool
+}
+
+// New returns a Backoff starting at base and growing up to DefaultMax (or
+// base, if that is larger).
+func New(base time.Duration) *Backoff {
+	limit := DefaultMax
+	if base > limit {
+		limit = base
+	}
+	return &Backoff{base: base, max: limit, cur: base}
+}
+
+// Next returns how long to wait before the next poll. phase identifies the
+// job's current state and step; a change resets the interval to base. fast
+// keeps the interval at base, e.g. while a queued job is likely to start.
+// Otherwise the interval grows by half per unchanged poll.
+func (b *Backoff) Next(phase string, fast bool) time.Duration {
+	changed := !b.seen || phase != b.last
+	b.seen = true
+	b.last = phase
+	if changed || fast {
+		b.cur = b.base
+		return b.cur
+	}
+	b.cur += b.cur / 2
+	if b.cur > b.max {
+		b.cur = b.max
+	}
+	return b.cur
+}
@@ -0,0 +1,53 @@
+package pollwait
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff_GrowsWhileUnchangedAndResetsOnChange(t *testing.T) {
+	b := New(time.Second)
+	got := []time.Duration{
+		b.Next("RUNNING/route", false),
+		b.Next("RUNNING/route", false),
+		b.Next("RUNNING/route", false),
+		b.Next("RUNNING/route", false),
+		b.Next("RUNNING/route", false),
+		b.Next("RUNNING/route", false),
+		b.Next("RUNNING/bitstream", false),
+	}
+	want := []time.Duration{
+		time.Second,
+		1500 * time.Millisecond,
+		2250 * time.Millisecond,
+		3375 * time.Millisecond,
+		DefaultMax,
+		DefaultMax,
+		time.Second,
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("poll %d: got %s want %s (all: %v)", i, got[i], want[i], got)
+		}
+	}
+}
+
+func TestBackoff_FastKeepsBaseInterval(t *testing.T) {
+	b := New(100 * time.Millisecond)
+	for i := 0; i < 5; i++ {
+		if got := b.Next("QUEUED/", true); got != 100*time.Millisecond {
+			t.Fatalf("poll %d: expected base interval while fast, got %s", i, got)
+		}
+	}
+	if got := b.Next("QUEUED/", false); got != 150*time.Millisecond {
+		t.Fatalf("expected growth once fast ends, got %s", got)
+	}
+}
+
+func TestBackoff_BaseAboveDefaultMax(t *testing.T) {
+	b := New(10 * time.Second)
+	b.Next("RUNNING/route", false)
+	if got := b.Next("RUNNING/route", false); got != 10*time.Second {
+		t.Fatalf("expected interval capped at base, got %s", got)
+	}
+}
@@ -1,18 +1,22 @@
 package queue
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/mblsha/spadeforge/internal/artifactname"
 	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/checksum"
 	"github.com/mblsha/spadeforge/internal/diagnostics"
 	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/manifest"
+	"github.com/mblsha/spadeforge/internal/pathglob"
 )
 
 const (
@@ -40,7 +44,11 @@ func (m *Manager) ReadConsoleTail(jobID string, lines int) ([]byte, error) {
 	return tailLastLines(raw, lines), nil
 }
 
-func (m *Manager) writeDiagnosticsReport(jobID string) job.DiagnosticsReport {
+// writeDiagnosticsReport parses the job logs into diagnostics.json. extra
+// diagnostics (e.g. manifest lint findings) are listed before log entries.
+// Entries matching the server or manifest suppression rules are kept but
+// flagged and left out of the counts; known codes get a fix hint.
+func (m *Manager) writeDiagnosticsReport(jobID string, rules manifest.DiagnosticsRules, extra ...job.Diagnostic) job.DiagnosticsReport {
 	artDir := m.store.ArtifactsJobDir(jobID)
 	_ = os.MkdirAll(artDir, 0o755)
 	logs := map[string][]byte{}
@@ -50,7 +58,18 @@ func (m *Manager) writeDiagnosticsReport(jobID string) job.DiagnosticsReport {
 			logs[name] = raw
 		}
 	}
-	report := diagnostics.BuildReport(logs)
+	var mf manifest.Manifest
+	if rec, ok := m.Get(jobID); ok {
+		mf = rec.Manifest
+	}
+	report := diagnostics.BuildReportWith(logs, m.diagnosticParsers(mf))
+	if len(extra) > 0 {
+		report.Diagnostics = append(append([]job.Diagnostic(nil), extra...), report.Diagnostics...)
+		report.Recount()
+	}
+	suppress := append(append([]manifest.SuppressRule(nil), m.cfg.DiagnosticSuppress...), rules.Suppress...)
+	diagnostics.ApplySuppressions(&report, suppress)
+	diagnostics.AttachHints(&report)
 	raw, err := json.MarshalIndent(report, "", "  ")
 	if err == nil {
 		_ = os.WriteFile(filepath.Join(artDir, diagnosticsFileName), raw, 0o644)
@@ -58,6 +77,207 @@ func (m *Manager) writeDiagnosticsReport(jobID string) job.DiagnosticsReport {
 	return report
 }
 
+// builderFor resolves the builder that runs jobs with this manifest when
+// the server picks one per toolchain.
+func (m *Manager) builderFor(mf manifest.Manifest) builder.Builder {
+	if tb, ok := m.builder.(*builder.ToolchainBuilder); ok {
+		if b, err := tb.For(mf); err == nil {
+			return b
+		}
+	}
+	return m.builder
+}
+
+// diagnosticParsers picks the log parsers for the tools the job's builder
+// runs, falling back to Vivado when a tool has no registered parser.
+func (m *Manager) diagnosticParsers(mf manifest.Manifest) []diagnostics.LineParser {
+	var tools []string
+	if tr, ok := m.builderFor(mf).(builder.ToolReporter); ok {
+		tools = tr.Tools()
+	}
+	parsers, err := diagnostics.Parsers(tools...)
+	if err != nil {
+		qlog.Warnf("%v; using the %s parser", err, diagnostics.DefaultTool)
+		parsers, _ = diagnostics.Parsers()
+	}
+	return parsers
+}
+
+// lintDiagnostics converts manifest lint warnings into INFO diagnostics.
+func lintDiagnostics(warnings []manifest.FieldError) []job.Diagnostic {
+	out := make([]job.Diagnostic, 0, len(warnings))
+	for _, w := range warnings {
+		d := job.Diagnostic{
+			Severity: job.SeverityInfo,
+			Tool:     "spadeforge",
+			Code:     "manifest-lint",
+			Message:  w.Message,
+			File:     "manifest.json",
+			Source:   w.Path,
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// protectedArtifacts are never dropped by exclude rules because the API
+// serves them directly (logs, diagnostics input, bitstream).
+var protectedArtifacts = map[string]struct{}{
+	"console.log":       {},
+	"console.log.times": {},
+	"vivado.log":        {},
+	"design.bit":        {},
+	"design.bin":        {},
+}
+
+// applyArtifactRules copies work dir files matching the include rules into
+// the artifacts and drops artifacts matching the exclude rules, except the
+// protected ones and the outputs the job's builder reports.
+func (m *Manager) applyArtifactRules(jobID string, mf manifest.Manifest) error {
+	rules := mf.Artifacts
+	include := append(append([]string(nil), m.cfg.ArtifactInclude...), rules.Include...)
+	exclude := append(append([]string(nil), m.cfg.ArtifactExclude...), rules.Exclude...)
+	if len(include) == 0 && len(exclude) == 0 {
+		return nil
+	}
+
+	artDir := m.store.ArtifactsJobDir(jobID)
+	if len(include) > 0 {
+		workDir := m.store.WorkJobDir(jobID)
+		srcDir := m.store.SourceDir(jobID)
+		err := filepath.WalkDir(workDir, func(pathNow string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if pathNow == srcDir {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			// WalkDir does not follow symlinks, but copyFile would.
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(workDir, pathNow)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			if !pathglob.MatchAny(include, rel) || pathglob.MatchAny(exclude, rel) {
+				return nil
+			}
+			return copyFile(pathNow, filepath.Join(artDir, filepath.FromSlash(rel)))
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if len(exclude) == 0 {
+		return nil
+	}
+	var outputs []string
+	if reporter, ok := m.builderFor(mf).(builder.OutputReporter); ok {
+		outputs = reporter.Outputs()
+	}
+	return filepath.WalkDir(artDir, func(pathNow string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(artDir, pathNow)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if _, ok := protectedArtifacts[rel]; ok {
+			return nil
+		}
+		if slices.Contains(outputs, rel) {
+			return nil
+		}
+		if pathglob.MatchAny(exclude, rel) {
+			return os.Remove(pathNow)
+		}
+		return nil
+	})
+}
+
+// copyNamedBitstream adds a copy of design.bit named by the manifest's (or
+// else the server's) bitstream name template, appending ".bit" when the
+// expanded name has no extension. It returns the new artifact name, or ""
+// when there is nothing to copy.
+func (m *Manager) copyNamedBitstream(rec *job.Record) (string, error) {
+	tmpl := rec.Manifest.Artifacts.BitstreamName
+	if tmpl == "" {
+		tmpl = m.cfg.BitstreamName
+	}
+	if tmpl == "" {
+		return "", nil
+	}
+	name, err := artifactname.Expand(tmpl, rec.NameVars())
+	if err != nil {
+		return "", err
+	}
+	if filepath.Ext(name) == "" {
+		name += ".bit"
+	}
+	artDir := m.store.ArtifactsJobDir(rec.ID)
+	src := filepath.Join(artDir, "design.bit")
+	if _, err := os.Stat(src); err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	if name == "design.bit" {
+		return "", nil
+	}
+	return name, copyFile(src, filepath.Join(artDir, name))
+}
+
+// plainFileSHA256 hashes the plaintext of a stored file.
+func (m *Manager) plainFileSHA256(path string) (string, error) {
+	f, err := m.store.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return checksum.Reader(f)
+}
+
+// copyPlainFile is copyFile writing the plaintext of an encrypted src.
+func (m *Manager) copyPlainFile(src, dst string) error {
+	return copyFileWith(m.store.Open, src, dst)
+}
+
+func copyFile(src, dst string) error {
+	return copyFileWith(func(path string) (io.ReadCloser, error) { return os.Open(path) }, src, dst)
+}
+
+func copyFileWith(open func(path string) (io.ReadCloser, error), src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	rf, err := open(src)
+	if err != nil {
+		return err
+	}
+	defer rf.Close()
+	wf, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(wf, rf); err != nil {
+		wf.Close()
+		return err
+	}
+	return wf.Close()
+}
+
 func inferFailure(report job.DiagnosticsReport, fallbackMessage string, buildErr error) (string, string) {
 	return diagnostics.InferFailure(report, fallbackMessage, buildErr)
 }
@@ -84,6 +304,12 @@ type artifactManifest struct {
 
 	RequestBundleSHA256 string `json:"request_bundle_sha256,omitempty"`
 
+	Git *manifest.GitInfo `json:"git,omitempty"`
+
+	Cache *cacheProvenance `json:"cache,omitempty"`
+
+	Energy *job.EnergyUsage `json:"energy,omitempty"`
+
 	Builder struct {
 		Name    string `json:"name"`
 		Version string `json:"version,omitempty"`
@@ -104,6 +330,7 @@ func (m *Manager) writeArtifactManifest(
 	finalState job.State,
 	result builder.BuildResult,
 	report job.DiagnosticsReport,
+	energyUsage *job.EnergyUsage,
 	failureKind string,
 	failureSummary string,
 ) error {
@@ -112,13 +339,13 @@ func (m *Manager) writeArtifactManifest(
 		return err
 	}
 
-	files, err := collectArtifactFiles(artDir)
+	files, err := m.collectArtifactFiles(artDir)
 	if err != nil {
 		return err
 	}
-	reqHash, _ := sha256File(m.store.RequestZipPath(jobID))
-	builderName, builderVersion, builderBinary := m.builderInfo(filepath.Join(artDir, "vivado.log"))
+	reqHash, _ := m.plainFileSHA256(m.store.RequestZipPath(jobID))
 	rec, _ := m.Get(jobID)
+	builderName, builderVersion, builderBinary := m.builderInfo(rec, artDir)
 
 	meta := artifactManifest{
 		Schema:              1,
@@ -131,8 +358,15 @@ func (m *Manager) writeArtifactManifest(
 		ResultMessage:       result.Message,
 		ExitCode:            result.ExitCode,
 		RequestBundleSHA256: reqHash,
+		Energy:              energyUsage,
 		Files:               files,
 	}
+	if rec != nil {
+		meta.Git = rec.Manifest.Git
+		if rec.CacheKey != "" {
+			meta.Cache = &cacheProvenance{Key: rec.CacheKey, Hit: rec.CachedFrom != "", SourceJobID: rec.CachedFrom}
+		}
+	}
 	meta.Builder.Name = builderName
 	meta.Builder.Version = builderVersion
 	meta.Builder.Binary = builderBinary
@@ -147,14 +381,32 @@ func (m *Manager) writeArtifactManifest(
 	return os.WriteFile(filepath.Join(artDir, artifactManifestName), raw, 0o644)
 }
 
-func (m *Manager) builderInfo(vivadoLogPath string) (name string, version string, binary string) {
-	switch m.builder.(type) {
+func (m *Manager) builderInfo(rec *job.Record, artDir string) (name string, version string, binary string) {
+	var mf manifest.Manifest
+	if rec != nil {
+		mf = rec.Manifest
+	}
+	switch b := m.builderFor(mf).(type) {
 	case *builder.FakeBuilder:
 		return "fake", "fake", "fake"
+	case *builder.YosysNextpnrBuilder:
+		name = "yosys+nextpnr"
+		binary = "yosys"
+		if b.BinDir != "" {
+			binary = filepath.Join(b.BinDir, "yosys")
+		}
+		raw, err := os.ReadFile(filepath.Join(artDir, "yosys.log"))
+		if err == nil {
+			version = parseYosysVersion(raw)
+		}
+		if version == "" {
+			version = "unknown"
+		}
+		return name, version, binary
 	default:
 		name = "vivado"
 		binary = m.cfg.VivadoBin
-		raw, err := os.ReadFile(vivadoLogPath)
+		raw, err := os.ReadFile(filepath.Join(artDir, "vivado.log"))
 		if err == nil {
 			version = parseVivadoVersion(raw)
 		}
@@ -165,7 +417,9 @@ func (m *Manager) builderInfo(vivadoLogPath string) (name string, version string
 	}
 }
 
-func collectArtifactFiles(artDir string) ([]artifactFile, error) {
+// collectArtifactFiles lists the artifacts with the size and checksum of
+// their plaintext, so encryption at rest does not change them.
+func (m *Manager) collectArtifactFiles(artDir string) ([]artifactFile, error) {
 	files := make([]artifactFile, 0)
 	err := filepath.WalkDir(artDir, func(pathNow string, d os.DirEntry, err error) error {
 		if err != nil {
@@ -182,17 +436,18 @@ func collectArtifactFiles(artDir string) ([]artifactFile, error) {
 		if rel == artifactManifestName {
 			return nil
 		}
-		fi, err := d.Info()
+		f, err := m.store.Open(pathNow)
 		if err != nil {
 			return err
 		}
-		sum, err := sha256File(pathNow)
+		sum, size, err := checksum.Copy(io.Discard, f)
+		f.Close()
 		if err != nil {
 			return err
 		}
 		files = append(files, artifactFile{
 			Path:   rel,
-			Size:   fi.Size(),
+			Size:   size,
 			SHA256: sum,
 		})
 		return nil
@@ -206,15 +461,6 @@ func collectArtifactFiles(artDir string) ([]artifactFile, error) {
 	return files, nil
 }
 
-func sha256File(path string) (string, error) {
-	raw, err := os.ReadFile(path)
-	if err != nil {
-		return "", err
-	}
-	sum := sha256.Sum256(raw)
-	return hex.EncodeToString(sum[:]), nil
-}
-
 func parseVivadoVersion(raw []byte) string {
 	lines := strings.Split(string(raw), "\n")
 	for _, line := range lines {
@@ -250,3 +496,14 @@ func tailLastLines(raw []byte, lines int) []byte {
 	start := len(parts) - lines
 	return []byte(strings.Join(parts[start:], "\n") + "\n")
 }
+
+// parseYosysVersion finds the "Yosys 0.38 (git sha1 ...)" banner line.
+func parseYosysVersion(raw []byte) string {
+	for _, line := range strings.Split(string(raw), "\n") {
+		frag := strings.Fields(strings.TrimSpace(line))
+		if len(frag) >= 2 && frag[0] == "Yosys" && frag[1] != "" && frag[1][0] >= '0' && frag[1][0] <= '9' {
+			return frag[1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,188 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/reports"
+)
+
+// ErrInvalidBaseline is returned when a job cannot be a project baseline.
+var ErrInvalidBaseline = errors.New("invalid baseline")
+
+// timingEpsilon ignores timing changes below Vivado's report precision.
+const timingEpsilon = 0.0005
+
+// collectMetrics reads timing.rpt and utilization.rpt from the job artifacts
+// and takes the error and warning counts from the diagnostics report.
+func (m *Manager) collectMetrics(jobID string, report job.DiagnosticsReport) *job.BuildMetrics {
+	artDir := m.store.ArtifactsJobDir(jobID)
+	metrics := &job.BuildMetrics{Errors: report.ErrorCount, Warnings: report.WarningCount}
+	if raw, err := os.ReadFile(filepath.Join(artDir, "timing.rpt")); err == nil {
+		if t, ok := reports.ParseTiming(raw); ok {
+			metrics.WNS, metrics.TNS, metrics.WHS, metrics.THS = &t.WNS, &t.TNS, &t.WHS, &t.THS
+		}
+	}
+	if raw, err := os.ReadFile(filepath.Join(artDir, "utilization.rpt")); err == nil {
+		if util := reports.ParseUtilization(raw); len(util) > 0 {
+			metrics.Utilization = make(map[string]job.ResourceUsage, len(util))
+			for key, r := range util {
+				metrics.Utilization[key] = job.ResourceUsage{Used: r.Used, Available: r.Available, Percent: r.Percent}
+			}
+		}
+	}
+	return metrics
+}
+
+// SetBaseline marks a succeeded job as its project's baseline, replacing the
+// previous one. Builds that finish afterwards are compared against it.
+func (m *Manager) SetBaseline(jobID string) (*job.Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rec, ok := m.jobs[jobID]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	if rec.State != job.StateSucceeded {
+		return nil, fmt.Errorf("%w: job %s is %s; only succeeded jobs can be a baseline", ErrInvalidBaseline, jobID, rec.State)
+	}
+	if rec.Metrics == nil {
+		return nil, fmt.Errorf("%w: job %s has no build metrics", ErrInvalidBaseline, jobID)
+	}
+	now := time.Now().UTC()
+	if prev := m.projectBaselineLocked(rec.Manifest.Project); prev != nil && prev.ID != rec.ID {
+		prev.ProjectBaseline = false
+		prev.UpdatedAt = now
+		_ = m.store.Save(prev)
+	}
+	rec.ProjectBaseline = true
+	rec.UpdatedAt = now
+	if err := m.store.Save(rec); err != nil {
+		return nil, err
+	}
+	copyRec := *rec
+	return &copyRec, nil
+}
+
+// ProjectBaseline returns the project's baseline job, if one is set.
+func (m *Manager) ProjectBaseline(project string) (*job.Record, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rec := m.projectBaselineLocked(project)
+	if rec == nil {
+		return nil, false
+	}
+	copyRec := *rec
+	return &copyRec, true
+}
+
+func (m *Manager) projectBaselineLocked(project string) *job.Record {
+	for _, rec := range m.jobs {
+		if rec.ProjectBaseline && rec.Manifest.Project == project {
+			return rec
+		}
+	}
+	return nil
+}
+
+// compareToBaselineLocked compares rec's metrics with its project's
+// baseline. It returns nil when there is no baseline or rec is the baseline.
+func (m *Manager) compareToBaselineLocked(rec *job.Record) *job.BaselineComparison {
+	base := m.projectBaselineLocked(rec.Manifest.Project)
+	if base == nil || base.ID == rec.ID || base.Metrics == nil || rec.Metrics == nil {
+		return nil
+	}
+	return compareMetrics(base.ID, base.Metrics, rec.Metrics)
+}
+
+func compareMetrics(baselineID string, base, cur *job.BuildMetrics) *job.BaselineComparison {
+	cmp := &job.BaselineComparison{BaselineJobID: baselineID, Deltas: []job.MetricDelta{}}
+	add := func(d job.MetricDelta) {
+		d.Delta = roundTo(d.Current-d.Baseline, 3)
+		if d.Regressed {
+			cmp.Regressions++
+		}
+		cmp.Deltas = append(cmp.Deltas, d)
+	}
+
+	// Slack: lower is worse.
+	timing := []struct {
+		name      string
+		base, cur *float64
+	}{
+		{"WNS", base.WNS, cur.WNS},
+		{"TNS", base.TNS, cur.TNS},
+		{"WHS", base.WHS, cur.WHS},
+		{"THS", base.THS, cur.THS},
+	}
+	for _, t := range timing {
+		if t.base == nil || t.cur == nil {
+			continue
+		}
+		d := job.MetricDelta{Metric: strings.ToLower(t.name) + "_ns", Baseline: *t.base, Current: *t.cur}
+		if diff := *t.cur - *t.base; diff < -timingEpsilon {
+			d.Regressed = true
+			d.Message = fmt.Sprintf("%s regressed by %.3f ns vs baseline", t.name, -diff)
+		} else if diff > timingEpsilon {
+			d.Message = fmt.Sprintf("%s improved by %.3f ns vs baseline", t.name, diff)
+		}
+		add(d)
+	}
+
+	// Resources and warnings: higher is worse.
+	keys := make([]string, 0, len(cur.Utilization))
+	for key := range cur.Utilization {
+		if _, ok := base.Utilization[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		b, c := base.Utilization[key], cur.Utilization[key]
+		d := job.MetricDelta{Metric: key + "_used", Baseline: b.Used, Current: c.Used}
+		if c.Used > b.Used {
+			d.Regressed = true
+			d.Message = fmt.Sprintf("%s usage up by %s vs baseline (%.2f%% -> %.2f%%)", strings.ToUpper(key), formatCount(c.Used-b.Used), b.Percent, c.Percent)
+		}
+		add(d)
+	}
+	warnings := job.MetricDelta{Metric: "warnings", Baseline: float64(base.Warnings), Current: float64(cur.Warnings)}
+	if cur.Warnings > base.Warnings {
+		warnings.Regressed = true
+		warnings.Message = fmt.Sprintf("%d more warnings vs baseline", cur.Warnings-base.Warnings)
+	}
+	add(warnings)
+
+	var regressions []string
+	for _, d := range cmp.Deltas {
+		if d.Regressed {
+			regressions = append(regressions, d.Message)
+		}
+	}
+	if len(regressions) == 0 {
+		cmp.Summary = "no regressions vs baseline " + baselineID
+	} else {
+		cmp.Summary = strings.Join(regressions, "; ")
+	}
+	return cmp
+}
+
+func roundTo(v float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(v*scale) / scale
+}
+
+func formatCount(v float64) string {
+	if v == math.Trunc(v) {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.1f", v)
+}
@@ -0,0 +1,103 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/store"
+)
+
+func fakeTimingReport(wns float64) string {
+	return fmt.Sprintf(`| Design Timing Summary
+    WNS(ns)      TNS(ns)  TNS Failing Endpoints  TNS Total Endpoints      WHS(ns)      THS(ns)  THS Failing Endpoints  THS Total Endpoints
+    -------      -------  ---------------------  -------------------      -------      -------  ---------------------  -------------------
+     %.3f        0.000                      0                  123        0.101        0.000                      0                  123
+`, wns)
+}
+
+func fakeUtilizationReport(luts int) string {
+	return fmt.Sprintf(`| Slice LUTs              | %d |     0 |     20800 |  %.2f |
+| Slice Registers         |   40 |     0 |     41600 |  0.10 |
+`, luts, float64(luts)*100/20800)
+}
+
+func TestBaseline_LaterBuildsRecordDeltas(t *testing.T) {
+	cfg := testConfig(t)
+	st := store.New(cfg)
+	fb := &builder.FakeBuilder{
+		FailProjects:      map[string]error{"broken": errors.New("synth failed")},
+		TimingReport:      fakeTimingReport(1.25),
+		UtilizationReport: fakeUtilizationReport(520),
+	}
+	mgr := New(cfg, st, fb)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	build := func(project string) *job.Record {
+		t.Helper()
+		rec, err := mgr.Submit(context.Background(), bytes.NewReader(validBundleBytes(t, project)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return waitForTerminalState(t, mgr, rec.ID)
+	}
+
+	first := build("blinky")
+	if first.Metrics == nil || first.Metrics.WNS == nil || *first.Metrics.WNS != 1.25 || first.Metrics.Utilization["lut"].Used != 520 {
+		t.Fatalf("expected parsed metrics, got %+v", first.Metrics)
+	}
+	if first.Baseline != nil {
+		t.Fatalf("expected no comparison without a baseline, got %+v", first.Baseline)
+	}
+
+	if _, err := mgr.SetBaseline("nope"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	failed := build("broken")
+	if _, err := mgr.SetBaseline(failed.ID); !errors.Is(err, ErrInvalidBaseline) {
+		t.Fatalf("expected failed job to be rejected, got %v", err)
+	}
+	if _, err := mgr.SetBaseline(first.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	fb.SetReports(fakeTimingReport(0.83), fakeUtilizationReport(560))
+	second := build("blinky")
+	cmp := second.Baseline
+	if cmp == nil || cmp.BaselineJobID != first.ID {
+		t.Fatalf("expected comparison against %s, got %+v", first.ID, cmp)
+	}
+	if cmp.Regressions != 2 || !strings.Contains(cmp.Summary, "WNS regressed by 0.420 ns vs baseline") || !strings.Contains(cmp.Summary, "LUT usage up by 40") {
+		t.Fatalf("unexpected comparison: %+v", cmp)
+	}
+
+	if _, err := mgr.SetBaseline(second.ID); err != nil {
+		t.Fatal(err)
+	}
+	if base, ok := mgr.ProjectBaseline("blinky"); !ok || base.ID != second.ID {
+		t.Fatalf("expected new baseline %s, got %+v", second.ID, base)
+	}
+	if old, _ := mgr.Get(first.ID); old.ProjectBaseline {
+		t.Fatalf("expected previous baseline to be cleared")
+	}
+	if onDisk, err := st.Load(first.ID); err != nil || onDisk.ProjectBaseline {
+		t.Fatalf("expected cleared baseline to be persisted, got %+v err=%v", onDisk, err)
+	}
+
+	fb.SetReports(fakeTimingReport(1.0), fakeUtilizationReport(500))
+	third := build("blinky")
+	if third.Baseline == nil || third.Baseline.Regressions != 0 || !strings.HasPrefix(third.Baseline.Summary, "no regressions") {
+		t.Fatalf("expected clean comparison, got %+v", third.Baseline)
+	}
+}
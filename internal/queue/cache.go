@@ -0,0 +1,166 @@
+package queue
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/checksum"
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/manifest"
+)
+
+// cacheProvenance is recorded in artifact_manifest.json: the job's build
+// cache key and, on a hit, the job whose artifacts were reused.
+type cacheProvenance struct {
+	Key         string `json:"key"`
+	Hit         bool   `json:"hit"`
+	SourceJobID string `json:"source_job_id,omitempty"`
+}
+
+// cacheKey hashes what determines a build's output: the manifest, less the
+// git metadata and on_success actions, and the SHA256 of every bundled
+// source file.
+func cacheKey(mf manifest.Manifest, sourceDir string) (string, error) {
+	mf.Git = nil
+	mf.OnSuccess = nil
+	rawManifest, err := json.Marshal(mf)
+	if err != nil {
+		return "", err
+	}
+	var files []string
+	err = filepath.WalkDir(sourceDir, func(pathNow string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(sourceDir, pathNow)
+		if err != nil {
+			return err
+		}
+		if rel = filepath.ToSlash(rel); rel != "manifest.json" {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+
+	h := sha256.New()
+	h.Write(rawManifest)
+	for _, rel := range files {
+		sum, err := checksum.File(filepath.Join(sourceDir, filepath.FromSlash(rel)))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "\n%s\x00%s", rel, sum)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// cachedBuildLocked returns the newest SUCCEEDED job built from the same
+// inputs whose artifacts are still on disk, or nil.
+func (m *Manager) cachedBuildLocked(key string) *job.Record {
+	var best *job.Record
+	for _, rec := range m.jobs {
+		if rec.CacheKey != key || rec.State != job.StateSucceeded || rec.FinishedAt == nil {
+			continue
+		}
+		if best != nil && !rec.FinishedAt.After(*best.FinishedAt) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(m.store.ArtifactsJobDir(rec.ID), artifactManifestName)); err != nil {
+			continue
+		}
+		best = rec
+	}
+	return best
+}
+
+// finishFromCache completes the queued job rec with a copy of the cached
+// job's artifacts instead of building it, then runs its on_success
+// actions. It returns false, leaving rec queued, when there is no usable
+// cached build.
+func (m *Manager) finishFromCache(ctx context.Context, rec *job.Record) bool {
+	m.mu.RLock()
+	src := m.cachedBuildLocked(rec.CacheKey)
+	var srcRec job.Record
+	if src != nil {
+		srcRec = *src
+	}
+	m.mu.RUnlock()
+	if src == nil {
+		return false
+	}
+	prefix := jobLogPrefix(rec.ID, rec.Manifest.Project)
+	if err := m.copyCachedArtifacts(srcRec.ID, rec.ID); err != nil {
+		qlog.Warnf("%s reuse artifacts of %s: %v; building instead", prefix, srcRec.ID, err)
+		_ = os.RemoveAll(m.store.ArtifactsJobDir(rec.ID))
+		return false
+	}
+	// The source may predate the encryption key.
+	if err := m.store.SealBitstreams(rec.ID); err != nil {
+		qlog.Warnf("%s encrypt bitstreams: %v", prefix, err)
+	}
+
+	message := fmt.Sprintf("reused artifacts of job %s", srcRec.ID)
+	m.mu.Lock()
+	cur, ok := m.jobs[rec.ID]
+	if !ok {
+		m.mu.Unlock()
+		return true
+	}
+	now := time.Now()
+	_ = cur.Transition(job.StateRunning, now, message)
+	_ = cur.MarkSucceeded(now, message, 0)
+	cur.CachedFrom = srcRec.ID
+	cur.CurrentStep = "done"
+	cur.Metrics = srcRec.Metrics
+	m.mu.Unlock()
+
+	var report job.DiagnosticsReport
+	if raw, err := m.ReadDiagnostics(rec.ID); err == nil {
+		_ = json.Unmarshal(raw, &report)
+	}
+	_ = m.writeArtifactManifest(rec.ID, job.StateSucceeded, builder.BuildResult{Message: message}, report, nil, "", "")
+
+	m.mu.Lock()
+	_ = m.store.Save(cur)
+	m.emitEventLocked(cur, "succeeded")
+	followUp := *cur
+	m.mu.Unlock()
+	qlog.Infof("%s cache hit: %s", prefix, message)
+
+	if len(followUp.Manifest.OnSuccess) > 0 {
+		go m.runFollowUps(context.WithoutCancel(ctx), followUp)
+	}
+	return true
+}
+
+// copyCachedArtifacts copies the source job's artifacts, except its
+// artifact_manifest.json, into dstID's artifacts dir.
+func (m *Manager) copyCachedArtifacts(srcID, dstID string) error {
+	srcDir := m.store.ArtifactsJobDir(srcID)
+	dstDir := m.store.ArtifactsJobDir(dstID)
+	return filepath.WalkDir(srcDir, func(pathNow string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(srcDir, pathNow)
+		if err != nil {
+			return err
+		}
+		if filepath.ToSlash(rel) == artifactManifestName {
+			return nil
+		}
+		return copyFile(pathNow, filepath.Join(dstDir, rel))
+	})
+}
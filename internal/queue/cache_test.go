@@ -0,0 +1,110 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/manifest"
+	"github.com/mblsha/spadeforge/internal/store"
+)
+
+func TestSubmit_BuildCacheReusesMatchingSucceededJob(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.BuildCache = true
+	st := store.New(cfg)
+	mgr := New(cfg, st, &builder.FakeBuilder{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := mgr.Submit(context.Background(), bytes.NewReader(validBundleBytes(t, "blinky")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done := waitForTerminalState(t, mgr, first.ID); done.State != job.StateSucceeded || done.CachedFrom != "" {
+		t.Fatalf("first build = %s cached_from=%q", done.State, done.CachedFrom)
+	}
+
+	// Git metadata does not change the build, so it still hits.
+	mf := manifest.Manifest{
+		Schema:  1,
+		Project: "blinky",
+		Top:     "top",
+		Part:    "xc7a35tcsg324-1",
+		Sources: []string{"hdl/spade.sv"},
+		Build:   manifest.Build{Steps: []string{"synth", "impl", "bitstream"}},
+		Git:     &manifest.GitInfo{Commit: "0123456789abcdef0123456789abcdef01234567"},
+	}
+	withGit := bundleFromManifest(t, mf, "module top; endmodule\n")
+	hit, err := mgr.Submit(context.Background(), bytes.NewReader(withGit))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hit.State != job.StateSucceeded || hit.CachedFrom != first.ID || hit.CacheKey != first.CacheKey {
+		t.Fatalf("cache hit = state %s cached_from %q key %q, want SUCCEEDED from %s", hit.State, hit.CachedFrom, hit.CacheKey, first.ID)
+	}
+	bit, err := os.ReadFile(filepath.Join(st.ArtifactsJobDir(hit.ID), "design.bit"))
+	if err != nil || string(bit) != "fake-bitstream" {
+		t.Fatalf("cached design.bit = %q, %v", bit, err)
+	}
+	raw, err := os.ReadFile(filepath.Join(st.ArtifactsJobDir(hit.ID), artifactManifestName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var meta artifactManifest
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		t.Fatal(err)
+	}
+	if meta.JobID != hit.ID || meta.Cache == nil || !meta.Cache.Hit || meta.Cache.SourceJobID != first.ID || meta.Cache.Key != first.CacheKey {
+		t.Fatalf("artifact manifest job %q cache %+v", meta.JobID, meta.Cache)
+	}
+
+	noCache, err := mgr.SubmitWithOptions(context.Background(), SubmitOptions{NoCache: true}, bytes.NewReader(validBundleBytes(t, "blinky")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done := waitForTerminalState(t, mgr, noCache.ID); done.CachedFrom != "" {
+		t.Fatalf("no_cache submit reused %s", done.CachedFrom)
+	}
+
+	changed, err := mgr.Submit(context.Background(), bytes.NewReader(bundleWithTop(t, "blinky", "top", "module top(input a); endmodule\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed.State != job.StateQueued || changed.CacheKey == first.CacheKey {
+		t.Fatalf("changed source = state %s key %q", changed.State, changed.CacheKey)
+	}
+	waitForTerminalState(t, mgr, changed.ID)
+}
+
+func TestSubmit_BuildCacheDisabledByDefault(t *testing.T) {
+	cfg := testConfig(t)
+	mgr := New(cfg, store.New(cfg), &builder.FakeBuilder{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := mgr.Submit(context.Background(), bytes.NewReader(validBundleBytes(t, "blinky")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForTerminalState(t, mgr, first.ID)
+	second, err := mgr.Submit(context.Background(), bytes.NewReader(validBundleBytes(t, "blinky")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.State != job.StateQueued || second.CachedFrom != "" {
+		t.Fatalf("second submit = state %s cached_from %q", second.State, second.CachedFrom)
+	}
+	waitForTerminalState(t, mgr, second.ID)
+}
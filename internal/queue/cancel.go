@@ -0,0 +1,61 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+// ErrJobFinished is returned by CancelJob for jobs that already ended.
+var ErrJobFinished = errors.New("job already finished")
+
+// CancelJob aborts a queued or running job. A queued job is removed from
+// the queue and marked CANCELED at once. A running job has its build
+// context canceled, which stops Vivado; the worker then marks it CANCELED.
+// The returned record reflects the job right after the request.
+func (m *Manager) CancelJob(jobID string) (*job.Record, error) {
+	rec, removeWorkDir, err := m.cancelJob(jobID)
+	if removeWorkDir {
+		_ = m.store.RemoveWorkDir(jobID)
+	}
+	return rec, err
+}
+
+// cancelJob does CancelJob's work under m.mu and reports whether the
+// canceled job's work dir should be removed once the lock is released.
+func (m *Manager) cancelJob(jobID string) (*job.Record, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec, ok := m.jobs[jobID]
+	if !ok {
+		return nil, false, os.ErrNotExist
+	}
+	removeWorkDir := false
+	switch rec.State {
+	case job.StateQueued:
+		m.pending.remove(jobID)
+		if err := rec.MarkCanceled(time.Now(), "canceled while queued"); err != nil {
+			return nil, false, err
+		}
+		_ = m.store.Save(rec)
+		m.emitEventLocked(rec, "canceled")
+		qlog.Infof("%s canceled while queued", jobLogPrefix(rec.ID, rec.Manifest.Project))
+		// No worker picks the job up to remove the extracted bundle.
+		removeWorkDir = !m.cfg.PreserveWorkDir
+	case job.StateRunning:
+		cancel, ok := m.cancels[jobID]
+		if !ok {
+			return nil, false, fmt.Errorf("job %s is running without a cancel handle", jobID)
+		}
+		m.canceling[jobID] = true
+		qlog.Infof("%s cancel requested", jobLogPrefix(rec.ID, rec.Manifest.Project))
+		cancel()
+	default:
+		return nil, false, fmt.Errorf("%w: job %s is %s", ErrJobFinished, jobID, rec.State)
+	}
+	copyRec := *rec
+	return &copyRec, removeWorkDir, nil
+}
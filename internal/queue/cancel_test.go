@@ -0,0 +1,92 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/store"
+)
+
+func TestCancelJob_QueuedAndRunning(t *testing.T) {
+	cfg := testConfig(t)
+	st := store.New(cfg)
+	block := make(chan struct{})
+	mgr := New(cfg, st, &builder.FakeBuilder{BlockCh: block})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	running, err := mgr.Submit(context.Background(), bytes.NewReader(validBundleBytes(t, "running")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForState(t, mgr, running.ID, job.StateRunning)
+	queued, err := mgr.Submit(context.Background(), bytes.NewReader(validBundleBytes(t, "queued")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := mgr.Submit(context.Background(), bytes.NewReader(validBundleBytes(t, "next")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, ch, unsubscribe, ok := mgr.SubscribeEvents(queued.ID, 0)
+	if !ok {
+		t.Fatal("subscribe failed")
+	}
+	defer unsubscribe()
+
+	rec, err := mgr.CancelJob(queued.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.State != job.StateCanceled || rec.FinishedAt == nil {
+		t.Fatalf("queued cancel = %+v, want CANCELED", rec)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected the queued event in the backlog, got %+v", events)
+	}
+	if ev := <-ch; ev.Type != "canceled" || !ev.Terminal() {
+		t.Fatalf("expected a terminal canceled event, got %+v", ev)
+	}
+	if _, err := os.Stat(st.WorkJobDir(queued.ID)); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("work dir of the canceled queued job still exists: %v", err)
+	}
+	if pos, _, ok := mgr.QueuePosition(next.ID); !ok || pos != 1 {
+		t.Fatalf("QueuePosition(next) = %d, %v; want 1 once the canceled job left the queue", pos, ok)
+	}
+
+	rec, err = mgr.CancelJob(running.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.State != job.StateRunning {
+		t.Fatalf("running cancel returned state %s, want RUNNING until the build stops", rec.State)
+	}
+	final := waitForTerminalState(t, mgr, running.ID)
+	if final.State != job.StateCanceled || final.FailureKind != "" {
+		t.Fatalf("running job ended %s kind=%q, want CANCELED", final.State, final.FailureKind)
+	}
+	if saved, err := st.Load(running.ID); err != nil || saved.State != job.StateCanceled {
+		t.Fatalf("persisted state = %v, %v", saved, err)
+	}
+
+	// The next job still runs once the canceled one is gone.
+	close(block)
+	if final := waitForTerminalState(t, mgr, next.ID); final.State != job.StateSucceeded {
+		t.Fatalf("next job ended %s", final.State)
+	}
+	if _, err := mgr.CancelJob(next.ID); !errors.Is(err, ErrJobFinished) {
+		t.Fatalf("cancel of finished job err = %v, want ErrJobFinished", err)
+	}
+	if _, err := mgr.CancelJob("missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("cancel of unknown job err = %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/diskspace"
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+// diskRecheckInterval is how often a paused queue measures free space
+// again.
+var diskRecheckInterval = 30 * time.Second
+
+// DiskStatus reports whether dequeuing is paused because the work volume
+// is low on space.
+type DiskStatus struct {
+	Paused       bool   `json:"paused"`
+	Reason       string `json:"reason,omitempty"`
+	FreeBytes    uint64 `json:"free_bytes,omitempty"`
+	MinFreeBytes int64  `json:"min_free_bytes,omitempty"`
+}
+
+// DiskStatus returns the latest work-volume measurement.
+func (m *Manager) DiskStatus() DiskStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.disk
+}
+
+// diskPaused measures free space on the work volume and pauses or resumes
+// dequeuing. Running builds are left alone; only new jobs wait. A volume
+// whose free space cannot be measured never blocks the queue.
+func (m *Manager) diskPaused() bool {
+	minFree := m.cfg.WorkMinFreeBytes
+	if minFree <= 0 {
+		return false
+	}
+	free, err := m.freeBytes(m.cfg.WorkDir())
+	if err != nil {
+		if !errors.Is(err, diskspace.ErrUnsupported) {
+			qlog.Warnf("[queue] measure free space on %s: %v", m.cfg.WorkDir(), err)
+		}
+		m.setDiskStatus(DiskStatus{})
+		return false
+	}
+	status := DiskStatus{FreeBytes: free, MinFreeBytes: minFree}
+	if free < uint64(minFree) {
+		status.Paused = true
+		status.Reason = fmt.Sprintf("waiting for disk space: %s free on the work volume, need %s",
+			diskspace.Format(free), diskspace.Format(uint64(minFree)))
+	}
+	m.setDiskStatus(status)
+	return status.Paused
+}
+
+// setDiskStatus records status and, when the queue pauses or resumes,
+// explains it on every queued job.
+func (m *Manager) setDiskStatus(status DiskStatus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	prev := m.disk
+	m.disk = status
+	if prev.Paused == status.Paused {
+		return
+	}
+	if status.Paused {
+		qlog.Warnf("[queue] dequeuing paused: %s", status.Reason)
+	} else {
+		qlog.Infof("[queue] dequeuing resumed: %s free on the work volume", diskspace.Format(status.FreeBytes))
+	}
+	now := time.Now().UTC()
+	for _, rec := range m.jobs {
+		if rec.State != job.StateQueued {
+			continue
+		}
+		switch {
+		case status.Paused:
+			rec.Message = status.Reason
+		case rec.Message == prev.Reason:
+			rec.Message = ""
+		default:
+			continue
+		}
+		rec.UpdatedAt = now
+		_ = m.store.Save(rec)
+		m.emitEventLocked(rec, "queued")
+	}
+}
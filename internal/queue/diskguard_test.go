@@ -0,0 +1,64 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/store"
+)
+
+func TestDiskGuard_PausesDequeueUntilSpaceReturns(t *testing.T) {
+	prevInterval := diskRecheckInterval
+	diskRecheckInterval = 10 * time.Millisecond
+	t.Cleanup(func() { diskRecheckInterval = prevInterval })
+
+	cfg := testConfig(t)
+	cfg.WorkMinFreeBytes = 10 << 30
+	st := store.New(cfg)
+	fake := &builder.FakeBuilder{}
+	mgr := New(cfg, st, fake)
+	var free atomic.Uint64
+	free.Store(1 << 30)
+	mgr.freeBytes = func(string) (uint64, error) { return free.Load(), nil }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := mgr.Submit(context.Background(), bytes.NewReader(validBundleBytes(t, "tight")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for !mgr.DiskStatus().Paused {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected queue to pause on low disk space")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	queued, _ := mgr.Get(rec.ID)
+	if queued.State != job.StateQueued {
+		t.Fatalf("expected job to stay queued while paused, got %s", queued.State)
+	}
+	if !strings.Contains(queued.Message, "waiting for disk space: 1.0 GiB free") {
+		t.Fatalf("expected explanatory message on queued job, got %q", queued.Message)
+	}
+
+	free.Store(20 << 30)
+	final := waitForTerminalState(t, mgr, rec.ID)
+	if final.State != job.StateSucceeded {
+		t.Fatalf("expected job to run once space returned, got %s", final.State)
+	}
+	if status := mgr.DiskStatus(); status.Paused || status.FreeBytes != 20<<30 {
+		t.Fatalf("unexpected disk status after resume: %+v", status)
+	}
+}
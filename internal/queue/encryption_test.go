@@ -0,0 +1,122 @@
+package queue
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mblsha/spadeforge/internal/atrest"
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/store"
+)
+
+func TestWorker_EncryptsBundleAndBitstreamAtRest(t *testing.T) {
+	cfg := testConfig(t)
+	st := store.New(cfg)
+	key, err := atrest.NewKey(bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.SetKey(key)
+	mgr := New(cfg, st, &builder.FakeBuilder{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := mgr.Submit(context.Background(), bytes.NewReader(validBundleBytes(t, "sealed")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	final := waitForTerminalState(t, mgr, rec.ID)
+	if final.State != job.StateSucceeded {
+		t.Fatalf("expected success, got %s error=%s", final.State, final.Error)
+	}
+
+	bitPath := filepath.Join(st.ArtifactsJobDir(rec.ID), "design.bit")
+	for _, path := range []string{st.RequestZipPath(rec.ID), bitPath} {
+		sealed, err := atrest.IsSealed(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sealed {
+			t.Fatalf("expected %s to be encrypted at rest", filepath.Base(path))
+		}
+	}
+	if raw, _ := os.ReadFile(bitPath); bytes.Contains(raw, []byte("fake-bitstream")) {
+		t.Fatalf("bitstream plaintext visible on disk")
+	}
+
+	bundle, err := mgr.OpenBundle(rec.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawBundle, err := io.ReadAll(bundle)
+	bundle.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := zip.NewReader(bytes.NewReader(rawBundle), int64(len(rawBundle))); err != nil {
+		t.Fatalf("decrypted bundle is not a zip: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := mgr.DownloadArtifacts(rec.ID, &out); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotBit []byte
+	for _, f := range zr.File {
+		if f.Name != "design.bit" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotBit, _ = io.ReadAll(rc)
+		rc.Close()
+	}
+	if string(gotBit) != "fake-bitstream" {
+		t.Fatalf("downloaded design.bit = %q, want plaintext", gotBit)
+	}
+
+	rawMeta, err := os.ReadFile(filepath.Join(st.ArtifactsJobDir(rec.ID), "artifact_manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var meta artifactManifest
+	if err := json.Unmarshal(rawMeta, &meta); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte("fake-bitstream"))
+	found := false
+	for _, f := range meta.Files {
+		if f.Path == "design.bit" {
+			found = true
+			if f.SHA256 != hex.EncodeToString(sum[:]) {
+				t.Fatalf("design.bit sha256 = %s, want plaintext hash", f.SHA256)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("design.bit missing from artifact manifest")
+	}
+	bundleSum := sha256.Sum256(rawBundle)
+	if meta.RequestBundleSHA256 != hex.EncodeToString(bundleSum[:]) {
+		t.Fatalf("request bundle sha256 = %s, want plaintext hash", meta.RequestBundleSHA256)
+	}
+}
@@ -0,0 +1,85 @@
+package queue
+
+import (
+	"sort"
+
+	"github.com/mblsha/spadeforge/internal/config"
+	"github.com/mblsha/spadeforge/internal/energy"
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+func newEnergyMeter(cfg config.Config) energy.Meter {
+	meter := energy.Meter{Watts: cfg.BuildWatts}
+	if cfg.EnergyRAPL {
+		meter.RAPLRoot = energy.DefaultRAPLRoot
+	}
+	return meter
+}
+
+// energyUsage closes the measurement opened by start, or returns nil when
+// neither RAPL nor a configured draw is available.
+func (m *Manager) energyUsage(start energy.Sample) *job.EnergyUsage {
+	usage, ok := m.meter.Stop(start)
+	if !ok {
+		return nil
+	}
+	out := &job.EnergyUsage{
+		Source:      usage.Source,
+		WallSeconds: roundTo(usage.WallSeconds, 3),
+		Joules:      roundTo(usage.Joules, 3),
+		WattHours:   roundTo(usage.Joules/3600, 4),
+	}
+	if m.cfg.EnergyPricePerKWh > 0 {
+		out.Cost = roundTo(usage.Joules/3.6e6*m.cfg.EnergyPricePerKWh, 4)
+	}
+	return out
+}
+
+// EnergyStats totals recorded build energy per project, largest first.
+func (m *Manager) EnergyStats() job.EnergyStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	byProject := map[string]*job.ProjectEnergy{}
+	stats := job.EnergyStats{Projects: []job.ProjectEnergy{}}
+	for _, rec := range m.jobs {
+		if rec.Energy == nil {
+			continue
+		}
+		p, ok := byProject[rec.Manifest.Project]
+		if !ok {
+			p = &job.ProjectEnergy{Project: rec.Manifest.Project}
+			byProject[rec.Manifest.Project] = p
+		}
+		addEnergy(p, rec.Energy)
+		addEnergy(&stats.Total, rec.Energy)
+	}
+	for _, p := range byProject {
+		roundEnergy(p)
+		stats.Projects = append(stats.Projects, *p)
+	}
+	roundEnergy(&stats.Total)
+	sort.Slice(stats.Projects, func(i, j int) bool {
+		a, b := stats.Projects[i], stats.Projects[j]
+		if a.Joules != b.Joules {
+			return a.Joules > b.Joules
+		}
+		return a.Project < b.Project
+	})
+	return stats
+}
+
+func addEnergy(p *job.ProjectEnergy, u *job.EnergyUsage) {
+	p.Builds++
+	p.WallSeconds += u.WallSeconds
+	p.Joules += u.Joules
+	p.WattHours += u.WattHours
+	p.Cost += u.Cost
+}
+
+func roundEnergy(p *job.ProjectEnergy) {
+	p.WallSeconds = roundTo(p.WallSeconds, 3)
+	p.Joules = roundTo(p.Joules, 3)
+	p.WattHours = roundTo(p.WattHours, 4)
+	p.Cost = roundTo(p.Cost, 4)
+}
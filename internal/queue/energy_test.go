@@ -0,0 +1,69 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/energy"
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/store"
+)
+
+func TestEnergy_RecordedPerBuildAndAggregatedPerProject(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.EnergyRAPL = false
+	cfg.BuildWatts = 150
+	cfg.EnergyPricePerKWh = 0.3
+	st := store.New(cfg)
+	mgr := New(cfg, st, &builder.FakeBuilder{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var recs []*job.Record
+	for _, project := range []string{"blinky", "blinky", "uart"} {
+		rec, err := mgr.Submit(context.Background(), bytes.NewReader(validBundleBytes(t, project)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		recs = append(recs, waitForTerminalState(t, mgr, rec.ID))
+	}
+
+	first := recs[0]
+	if first.Energy == nil || first.Energy.Source != energy.SourceWatts {
+		t.Fatalf("expected watts-based energy, got %+v", first.Energy)
+	}
+	raw, err := os.ReadFile(filepath.Join(st.ArtifactsJobDir(first.ID), artifactManifestName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var meta artifactManifest
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		t.Fatal(err)
+	}
+	if meta.Energy == nil || meta.Energy.Joules != first.Energy.Joules {
+		t.Fatalf("artifact manifest energy = %+v, want %+v", meta.Energy, first.Energy)
+	}
+
+	stats := mgr.EnergyStats()
+	if len(stats.Projects) != 2 || stats.Total.Builds != 3 {
+		t.Fatalf("unexpected energy stats: %+v", stats)
+	}
+	for _, p := range stats.Projects {
+		want := 1
+		if p.Project == "blinky" {
+			want = 2
+		}
+		if p.Builds != want {
+			t.Fatalf("project %s builds = %d, want %d", p.Project, p.Builds, want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package queue
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/store"
+)
+
+// eventLog appends events to the jobs' events.jsonl from its own
+// goroutine, so emitting an event under Manager.mu does no disk I/O.
+// Unlike the MQTT backlog it never drops events. Once the writer has
+// stopped, events are written by the goroutine that emits them.
+type eventLog struct {
+	store *store.Store
+
+	mu      sync.Mutex
+	pending []job.Event
+	stopped bool
+	wake    chan struct{}
+
+	// writeMu keeps flushes, and so each job's events, in order.
+	writeMu sync.Mutex
+}
+
+func newEventLog(st *store.Store) *eventLog {
+	return &eventLog{store: st, wake: make(chan struct{}, 1)}
+}
+
+func (l *eventLog) enqueue(ev job.Event) {
+	l.mu.Lock()
+	l.pending = append(l.pending, ev)
+	stopped := l.stopped
+	l.mu.Unlock()
+	if stopped {
+		l.flush()
+		return
+	}
+	select {
+	case l.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run writes queued events until ctx ends, then writes what is left.
+func (l *eventLog) run(ctx context.Context) {
+	for {
+		l.flush()
+		select {
+		case <-ctx.Done():
+			l.mu.Lock()
+			l.stopped = true
+			l.mu.Unlock()
+			l.flush()
+			return
+		case <-l.wake:
+		}
+	}
+}
+
+// flush writes every event queued before it was called.
+func (l *eventLog) flush() {
+	l.writeMu.Lock()
+	defer l.writeMu.Unlock()
+	l.mu.Lock()
+	batch := l.pending
+	l.pending = nil
+	l.mu.Unlock()
+	for _, ev := range batch {
+		if err := l.store.AppendEvent(ev); err != nil {
+			qlog.Warnf("%s persist event: %v", jobLogPrefix(ev.JobID, ev.Project), err)
+		}
+	}
+}
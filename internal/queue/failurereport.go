@@ -0,0 +1,125 @@
+package queue
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+const (
+	failureReportName       = "failure_report.json"
+	failureReportMaxErrors  = 10
+	failureReportTailLines  = 40
+	failureReportTailSource = "console.log"
+)
+
+// failureReport is the compact verdict CI systems upload for a failed build.
+type failureReport struct {
+	Schema      int       `json:"schema"`
+	JobID       string    `json:"job_id"`
+	Project     string    `json:"project"`
+	GeneratedAt time.Time `json:"generated_at"`
+
+	Kind     string `json:"kind"`
+	Summary  string `json:"summary"`
+	Message  string `json:"message,omitempty"`
+	ExitCode int    `json:"exit_code"`
+
+	ErrorCount   int            `json:"error_count"`
+	WarningCount int            `json:"warning_count"`
+	Errors       []failureError `json:"errors"`
+
+	TailSource string `json:"tail_source,omitempty"`
+	Tail       string `json:"tail,omitempty"`
+
+	Logs map[string]failureLogLink `json:"logs"`
+}
+
+type failureError struct {
+	Code    string              `json:"code,omitempty"`
+	Message string              `json:"message"`
+	File    string              `json:"file,omitempty"`
+	Line    int                 `json:"line,omitempty"`
+	Column  int                 `json:"column,omitempty"`
+	Hint    *job.DiagnosticHint `json:"hint,omitempty"`
+}
+
+// failureLogLink points at a full log both inside the artifacts zip and on
+// the API.
+type failureLogLink struct {
+	Artifact string `json:"artifact"`
+	URL      string `json:"url"`
+}
+
+// writeFailureReport writes failure_report.json into the job's artifacts:
+// the failure kind and summary, the first unsuppressed errors with their
+// locations, a console tail and where to fetch the full logs.
+func (m *Manager) writeFailureReport(
+	jobID string,
+	result builder.BuildResult,
+	report job.DiagnosticsReport,
+	failureKind string,
+	failureSummary string,
+) error {
+	artDir := m.store.ArtifactsJobDir(jobID)
+	if err := os.MkdirAll(artDir, 0o755); err != nil {
+		return err
+	}
+	rec, _ := m.Get(jobID)
+
+	out := failureReport{
+		Schema:       1,
+		JobID:        jobID,
+		Project:      projectName(rec),
+		GeneratedAt:  time.Now().UTC(),
+		Kind:         failureKind,
+		Summary:      failureSummary,
+		Message:      result.Message,
+		ExitCode:     result.ExitCode,
+		ErrorCount:   report.ErrorCount,
+		WarningCount: report.WarningCount,
+		Errors:       []failureError{},
+		Logs:         map[string]failureLogLink{},
+	}
+	for _, d := range report.Diagnostics {
+		if d.Severity != job.SeverityError || d.Suppressed {
+			continue
+		}
+		out.Errors = append(out.Errors, failureError{
+			Code:    d.Code,
+			Message: d.Message,
+			File:    d.File,
+			Line:    d.Line,
+			Column:  d.Column,
+			Hint:    d.Hint,
+		})
+		if len(out.Errors) >= failureReportMaxErrors {
+			break
+		}
+	}
+
+	for _, name := range LogNames {
+		if _, err := os.Stat(filepath.Join(artDir, name)); err != nil {
+			continue
+		}
+		out.Logs[name] = failureLogLink{
+			Artifact: name,
+			URL:      "/v1/jobs/" + jobID + "/log?file=" + name,
+		}
+	}
+	if raw, err := os.ReadFile(filepath.Join(artDir, failureReportTailSource)); err == nil && len(raw) > 0 {
+		out.TailSource = failureReportTailSource
+		out.Tail = strings.TrimRight(string(tailLastLines(raw, failureReportTailLines)), "\n")
+	}
+
+	raw, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(artDir, failureReportName), raw, 0o644)
+}
@@ -0,0 +1,129 @@
+package queue
+
+import (
+	"slices"
+	"sync"
+)
+
+// fairQueue hands out queued job IDs round-robin across submitters, so one
+// submitter's batch cannot starve the others. A submitter with weight w
+// gets up to w consecutive jobs per turn; unlisted submitters weigh 1.
+// Within a submitter, jobs run in submission order.
+type fairQueue struct {
+	mu      sync.Mutex
+	weights map[string]int
+	lanes   map[string][]string
+	// turns lists submitters with queued jobs; turns[0] is being served and
+	// has taken used jobs this turn.
+	turns []string
+	used  int
+
+	wake chan struct{}
+}
+
+func newFairQueue(weights map[string]int) *fairQueue {
+	return &fairQueue{
+		weights: weights,
+		lanes:   map[string][]string{},
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+func (q *fairQueue) push(submitter, jobID string) {
+	q.mu.Lock()
+	if _, ok := q.lanes[submitter]; !ok {
+		q.turns = append(q.turns, submitter)
+	}
+	q.lanes[submitter] = append(q.lanes[submitter], jobID)
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *fairQueue) pop() (string, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.popLocked()
+}
+
+func (q *fairQueue) popLocked() (string, bool) {
+	if len(q.turns) == 0 {
+		return "", false
+	}
+	submitter := q.turns[0]
+	lane := q.lanes[submitter]
+	jobID := lane[0]
+	q.used++
+	if len(lane) == 1 {
+		delete(q.lanes, submitter)
+		q.turns = q.turns[1:]
+		q.used = 0
+		return jobID, true
+	}
+	q.lanes[submitter] = lane[1:]
+	if q.used >= q.weight(submitter) {
+		q.turns = append(q.turns[1:], submitter)
+		q.used = 0
+	}
+	return jobID, true
+}
+
+// remove drops a queued job, e.g. when it is canceled, and reports whether
+// it was queued.
+func (q *fairQueue) remove(jobID string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, submitter := range q.turns {
+		lane := q.lanes[submitter]
+		idx := slices.Index(lane, jobID)
+		if idx < 0 {
+			continue
+		}
+		if len(lane) > 1 {
+			q.lanes[submitter] = slices.Delete(slices.Clone(lane), idx, idx+1)
+			return true
+		}
+		delete(q.lanes, submitter)
+		q.turns = slices.Delete(q.turns, i, i+1)
+		if i == 0 {
+			q.used = 0
+		}
+		return true
+	}
+	return false
+}
+
+// order returns the queued job IDs in the order pop would return them.
+func (q *fairQueue) order() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	sim := fairQueue{
+		weights: q.weights,
+		lanes:   make(map[string][]string, len(q.lanes)),
+		turns:   append([]string(nil), q.turns...),
+		used:    q.used,
+	}
+	total := 0
+	for submitter, lane := range q.lanes {
+		sim.lanes[submitter] = lane
+		total += len(lane)
+	}
+	out := make([]string, 0, total)
+	for {
+		jobID, ok := sim.popLocked()
+		if !ok {
+			return out
+		}
+		out = append(out, jobID)
+	}
+}
+
+func (q *fairQueue) weight(submitter string) int {
+	if w := q.weights[submitter]; w > 0 {
+		return w
+	}
+	return 1
+}
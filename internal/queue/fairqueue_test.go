@@ -0,0 +1,118 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/store"
+)
+
+func TestFairQueue_RoundRobinAcrossSubmitters(t *testing.T) {
+	q := newFairQueue(map[string]int{"ci": 2})
+	for _, id := range []string{"a1", "a2", "a3", "a4"} {
+		q.push("alice", id)
+	}
+	q.push("bob", "b1")
+	for _, id := range []string{"c1", "c2", "c3"} {
+		q.push("ci", id)
+	}
+
+	want := []string{"a1", "b1", "c1", "c2", "a2", "c3", "a3", "a4"}
+	if got := q.order(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("order() = %v, want %v", got, want)
+	}
+	var got []string
+	for {
+		id, ok := q.pop()
+		if !ok {
+			break
+		}
+		got = append(got, id)
+		if id == "a2" {
+			// A submitter arriving mid-round joins the end of the rotation.
+			q.push("dave", "d1")
+			want = []string{"a1", "b1", "c1", "c2", "a2", "c3", "a3", "d1", "a4"}
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("pop sequence = %v, want %v", got, want)
+	}
+}
+
+func TestManager_DequeuesFairlyBySubmitter(t *testing.T) {
+	cfg := testConfig(t)
+	st := store.New(cfg)
+	block := make(chan struct{})
+	fb := &builder.FakeBuilder{BlockCh: block}
+	mgr := New(cfg, st, fb)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	submit := func(submitter string) *job.Record {
+		t.Helper()
+		rec, err := mgr.SubmitFrom(context.Background(), submitter, bytes.NewReader(validBundleBytes(t, "blinky")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rec
+	}
+	running := submit("alice")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if rec, ok := mgr.Get(running.ID); ok && rec.State == job.StateRunning {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("first job never started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	matrix := []*job.Record{submit("alice"), submit("alice"), submit("alice")}
+	bob := submit("bob")
+	if bob.Submitter != "bob" {
+		t.Fatalf("submitter not recorded: %q", bob.Submitter)
+	}
+	if pos, ahead, _ := mgr.QueuePosition(bob.ID); pos != 2 || ahead != 2 {
+		t.Fatalf("bob queue position = %d ahead = %d, want 2 and 2", pos, ahead)
+	}
+
+	close(block)
+	for _, rec := range append(matrix, bob) {
+		waitForTerminalState(t, mgr, rec.ID)
+	}
+	var got []string
+	for _, call := range fb.Calls {
+		got = append(got, call.ID)
+	}
+	want := []string{running.ID, matrix[0].ID, bob.ID, matrix[1].ID, matrix[2].ID}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("build order = %v, want %v", got, want)
+	}
+}
+
+func TestFairQueue_Remove(t *testing.T) {
+	q := newFairQueue(nil)
+	q.push("alice", "a1")
+	q.push("alice", "a2")
+	q.push("bob", "b1")
+
+	if !q.remove("a1") || !q.remove("b1") {
+		t.Fatal("expected queued jobs to be removed")
+	}
+	if q.remove("b1") {
+		t.Fatal("removing twice should report false")
+	}
+	if got := q.order(); !reflect.DeepEqual(got, []string{"a2"}) {
+		t.Fatalf("order() = %v, want [a2]", got)
+	}
+}
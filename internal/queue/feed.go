@@ -0,0 +1,76 @@
+package queue
+
+import (
+	"sort"
+
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+// Feed returns the wallboard snapshot: running jobs oldest first, queued
+// jobs in the fair dequeue order and the last recent finished jobs.
+func (m *Manager) Feed(recent int) job.Feed {
+	order := m.pending.order()
+	feed := job.Feed{Running: []job.FeedJob{}, Queued: []job.FeedJob{}, Recent: []job.FeedJob{}}
+
+	m.mu.RLock()
+	var running, queued, finished []*job.Record
+	for _, rec := range m.jobs {
+		switch {
+		case rec.State == job.StateRunning:
+			running = append(running, rec)
+		case rec.State == job.StateQueued:
+			queued = append(queued, rec)
+		case rec.State.Terminal():
+			finished = append(finished, rec)
+		}
+	}
+	// Queued jobs not in the fair queue yet, e.g. while being submitted,
+	// go last.
+	rank := make(map[string]int, len(order))
+	for i, id := range order {
+		rank[id] = i
+	}
+	sort.Slice(queued, func(i, j int) bool {
+		ri, iok := rank[queued[i].ID]
+		rj, jok := rank[queued[j].ID]
+		if iok != jok {
+			return iok
+		}
+		if iok {
+			return ri < rj
+		}
+		return queuedBefore(queued[i], queued[j])
+	})
+	sort.Slice(running, func(i, j int) bool { return queuedBefore(running[i], running[j]) })
+	sort.Slice(finished, func(i, j int) bool { return finishedAfter(finished[i], finished[j]) })
+	if recent >= 0 && len(finished) > recent {
+		finished = finished[:recent]
+	}
+	for _, rec := range running {
+		feed.Running = append(feed.Running, job.NewFeedJob(*rec))
+	}
+	for _, rec := range queued {
+		feed.Queued = append(feed.Queued, job.NewFeedJob(*rec))
+	}
+	for _, rec := range finished {
+		feed.Recent = append(feed.Recent, job.NewFeedJob(*rec))
+	}
+	m.mu.RUnlock()
+	return feed
+}
+
+// finishedAfter orders finished jobs newest first by finish time, falling
+// back to the update time for jobs canceled before they started.
+func finishedAfter(a, b *job.Record) bool {
+	at, bt := a.UpdatedAt, b.UpdatedAt
+	if a.FinishedAt != nil {
+		at = *a.FinishedAt
+	}
+	if b.FinishedAt != nil {
+		bt = *b.FinishedAt
+	}
+	if !at.Equal(bt) {
+		return at.After(bt)
+	}
+	return a.ID > b.ID
+}
@@ -0,0 +1,76 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/store"
+)
+
+func TestFeed_ListsRunningQueuedInDequeueOrderAndRecent(t *testing.T) {
+	cfg := testConfig(t)
+	st := store.New(cfg)
+	block := make(chan struct{})
+	mgr := New(cfg, st, &builder.FakeBuilder{BlockCh: block})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	submit := func(submitter string) *job.Record {
+		t.Helper()
+		rec, err := mgr.SubmitFrom(context.Background(), submitter, bytes.NewReader(validBundleBytes(t, "blinky")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rec
+	}
+	running := submit("alice")
+	waitForState(t, mgr, running.ID, job.StateRunning)
+	second, third := submit("alice"), submit("alice")
+	bob := submit("bob")
+
+	ids := func(jobs []job.FeedJob) []string {
+		out := []string{}
+		for _, j := range jobs {
+			out = append(out, j.ID)
+		}
+		return out
+	}
+	feed := mgr.Feed(job.FeedRecent)
+	if got := ids(feed.Running); !reflect.DeepEqual(got, []string{running.ID}) {
+		t.Fatalf("running = %v, want %v", got, running.ID)
+	}
+	if got, want := ids(feed.Queued), []string{second.ID, bob.ID, third.ID}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("queued = %v, want %v", got, want)
+	}
+	if len(feed.Recent) != 0 {
+		t.Fatalf("recent = %v, want none", ids(feed.Recent))
+	}
+	if feed.Running[0].Project != "blinky" || feed.Running[0].StartedAt == nil {
+		t.Fatalf("running job = %+v", feed.Running[0])
+	}
+
+	close(block)
+	for _, rec := range []*job.Record{running, second, bob, third} {
+		waitForTerminalState(t, mgr, rec.ID)
+	}
+	feed = mgr.Feed(2)
+	if len(feed.Running) != 0 || len(feed.Queued) != 0 {
+		t.Fatalf("running = %v, queued = %v; want none", ids(feed.Running), ids(feed.Queued))
+	}
+	if got, want := ids(feed.Recent), []string{third.ID, bob.ID}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("recent = %v, want %v", got, want)
+	}
+	for _, j := range feed.Recent {
+		if j.State != job.StateSucceeded || j.FinishedAt == nil || j.DurationSeconds < 0 {
+			t.Fatalf("recent job = %+v", j)
+		}
+	}
+}
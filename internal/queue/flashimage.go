@@ -0,0 +1,41 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mblsha/spadeforge/internal/flashimage"
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+// ErrNotSucceeded means a job has no finished bitstream to convert.
+var ErrNotSucceeded = errors.New("job has not succeeded")
+
+// WriteFlashImage converts the job's bitstream into an SPI flash image in
+// format (see flashimage.Write) at flash address offset, so a job that only
+// produced a .bit can still be programmed into flash without a rebuild.
+func (m *Manager) WriteFlashImage(jobID, format string, offset uint32, w io.Writer) error {
+	rec, ok := m.Get(jobID)
+	if !ok {
+		return os.ErrNotExist
+	}
+	if rec.State != job.StateSucceeded {
+		return fmt.Errorf("%w (state %s)", ErrNotSucceeded, rec.State)
+	}
+	path, err := m.bitstreamPath(jobID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", os.ErrNotExist, err)
+	}
+	f, err := m.store.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	bit, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	return flashimage.Write(w, bit, format, offset)
+}
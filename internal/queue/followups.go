@@ -0,0 +1,259 @@
+package queue
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/artifactname"
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/manifest"
+	loaderclient "github.com/mblsha/spadeforge/internal/spadeloader/client"
+)
+
+// followUpTimeout bounds each on_success action.
+const followUpTimeout = 2 * time.Minute
+
+// checkActionTargets rejects on_success actions naming loaders or webhooks
+// the server does not know, at submit time rather than after the build.
+func (m *Manager) checkActionTargets(actions []manifest.Action) error {
+	for i, a := range actions {
+		switch {
+		case a.Flash != nil:
+			if _, ok := m.cfg.Loaders[a.Flash.Loader]; !ok {
+				return fmt.Errorf("on_success[%d]: unknown loader %q (configured: %s)", i, a.Flash.Loader, knownNames(m.cfg.Loaders))
+			}
+		case a.Webhook != nil:
+			if _, ok := m.cfg.Webhooks[a.Webhook.Name]; !ok {
+				return fmt.Errorf("on_success[%d]: unknown webhook %q (configured: %s)", i, a.Webhook.Name, knownNames(m.cfg.Webhooks))
+			}
+		case a.Submit != nil:
+			if err := m.checkActionTargets(a.Submit.OnSuccess); err != nil {
+				return fmt.Errorf("on_success[%d].submit.%w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+func knownNames(targets map[string]string) string {
+	if len(targets) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(targets))
+	for name := range targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// runFollowUps runs rec's on_success actions in order and records each
+// outcome on the job. A failed action is logged and does not stop the
+// ones after it.
+func (m *Manager) runFollowUps(ctx context.Context, rec job.Record) {
+	prefix := jobLogPrefix(rec.ID, rec.Manifest.Project)
+	for _, action := range rec.Manifest.OnSuccess {
+		actionCtx, cancel := context.WithTimeout(ctx, followUpTimeout)
+		result := job.FollowUp{Action: action.Kind(), Target: action.Target()}
+		var err error
+		switch {
+		case action.Flash != nil:
+			result.JobID, err = m.flashFollowUp(actionCtx, &rec, action.Flash)
+		case action.Webhook != nil:
+			err = m.webhookFollowUp(actionCtx, &rec, action.Webhook)
+		case action.Submit != nil:
+			var next *job.Record
+			next, err = m.submitFollowUp(actionCtx, &rec, action.Submit)
+			if next != nil {
+				result.JobID, result.Target = next.ID, next.Manifest.Project
+			}
+		}
+		cancel()
+		if err != nil {
+			result.Error = err.Error()
+			qlog.Warnf("%s on_success %s %s: %v", prefix, result.Action, result.Target, err)
+		} else {
+			qlog.Infof("%s on_success %s %s done job=%s", prefix, result.Action, result.Target, result.JobID)
+		}
+		result.At = time.Now().UTC()
+
+		m.mu.Lock()
+		if cur, ok := m.jobs[rec.ID]; ok {
+			cur.FollowUps = append(cur.FollowUps, result)
+			_ = m.store.Save(cur)
+		}
+		m.mu.Unlock()
+	}
+}
+
+// flashFollowUp sends the job's bitstream to the named spadeloader and
+// returns the loader's job ID.
+func (m *Manager) flashFollowUp(ctx context.Context, rec *job.Record, a *manifest.FlashAction) (string, error) {
+	baseURL, ok := m.cfg.Loaders[a.Loader]
+	if !ok {
+		return "", fmt.Errorf("unknown loader %q", a.Loader)
+	}
+	bitstreamPath, err := m.bitstreamPath(rec.ID)
+	if err != nil {
+		return "", err
+	}
+	design := a.DesignName
+	if design == "" {
+		design = "{project}"
+	}
+	design, err = artifactname.Expand(design, rec.NameVars())
+	if err != nil {
+		return "", err
+	}
+	c := &loaderclient.HTTPClient{BaseURL: baseURL, Token: m.cfg.LoaderToken}
+	var loaderJobID string
+	err = m.store.WithPlainFile(bitstreamPath, func(bitstream string) error {
+		loaderJobID, err = c.SubmitFlash(ctx, loaderclient.SubmitRequest{
+			Board:         a.Board,
+			DesignName:    design,
+			BitstreamPath: bitstream,
+			DryRun:        a.DryRun,
+		})
+		return err
+	})
+	return loaderJobID, err
+}
+
+// bitstreamPath finds the programming file the builder wrote: design.bit,
+// or design.bin for iCE40 parts.
+func (m *Manager) bitstreamPath(jobID string) (string, error) {
+	for _, name := range []string{"design.bit", "design.bin"} {
+		p := filepath.Join(m.store.ArtifactsJobDir(jobID), name)
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	}
+	return "", errors.New("no design.bit or design.bin among the artifacts")
+}
+
+// webhookPayload is the JSON body POSTed by webhook actions.
+type webhookPayload struct {
+	Event string     `json:"event"`
+	Job   job.Record `json:"job"`
+}
+
+func (m *Manager) webhookFollowUp(ctx context.Context, rec *job.Record, a *manifest.WebhookAction) error {
+	target, ok := m.cfg.Webhooks[a.Name]
+	if !ok {
+		return fmt.Errorf("unknown webhook %q", a.Name)
+	}
+	return m.postWebhook(ctx, target, webhookPayload{Event: "succeeded", Job: *rec})
+}
+
+// postWebhook POSTs payload as JSON to target.
+func (m *Manager) postWebhook(ctx context.Context, target string, payload webhookPayload) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("webhook failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// submitFollowUp queues the finished job's bundle again with a's fields
+// replacing the manifest's.
+func (m *Manager) submitFollowUp(ctx context.Context, rec *job.Record, a *manifest.SubmitAction) (*job.Record, error) {
+	mf := rec.Manifest
+	mf.OnSuccess = a.OnSuccess
+	if a.Project != "" {
+		project, err := artifactname.Expand(a.Project, rec.NameVars())
+		if err != nil {
+			return nil, err
+		}
+		mf.Project = project
+	}
+	if a.Top != "" {
+		mf.Top = a.Top
+	}
+	if a.Part != "" {
+		mf.Part = a.Part
+	}
+	if a.Toolchain != "" {
+		mf.Toolchain = a.Toolchain
+	}
+	if a.Build != nil {
+		mf.Build = *a.Build
+	}
+	rawManifest, err := json.MarshalIndent(mf, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	var next *job.Record
+	err = m.store.WithPlainFile(m.store.RequestZipPath(rec.ID), func(zipPath string) error {
+		src, err := zip.OpenReader(zipPath)
+		if err != nil {
+			return fmt.Errorf("open bundle: %w", err)
+		}
+		defer src.Close()
+		next, err = m.resubmitBundle(ctx, rec, &src.Reader, rawManifest)
+		return err
+	})
+	return next, err
+}
+
+// resubmitBundle submits src again with its manifest.json replaced by
+// rawManifest.
+func (m *Manager) resubmitBundle(ctx context.Context, rec *job.Record, src *zip.Reader, rawManifest []byte) (*job.Record, error) {
+	pr, pw := io.Pipe()
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		pw.CloseWithError(rewriteBundleManifest(pw, src, rawManifest))
+	}()
+	next, err := m.submit(ctx, SubmitOptions{Submitter: rec.Submitter, TokenName: rec.TokenName, ClientIP: rec.ClientIP}, rec.ID, pr)
+	// Unblocks the writer if submit stopped reading early.
+	pr.CloseWithError(errors.New("follow-up submit finished"))
+	<-written
+	return next, err
+}
+
+// rewriteBundleManifest copies bundle to w with manifest.json replaced.
+func rewriteBundleManifest(w io.Writer, bundle *zip.Reader, rawManifest []byte) error {
+	zw := zip.NewWriter(w)
+	for _, f := range bundle.File {
+		if f.Name == "manifest.json" {
+			continue
+		}
+		if err := zw.Copy(f); err != nil {
+			return err
+		}
+	}
+	mw, err := zw.Create("manifest.json")
+	if err != nil {
+		return err
+	}
+	if _, err := mw.Write(rawManifest); err != nil {
+		return err
+	}
+	return zw.Close()
+}
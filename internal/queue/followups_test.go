@@ -0,0 +1,132 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/manifest"
+	"github.com/mblsha/spadeforge/internal/store"
+)
+
+func TestFollowUps_FlashWebhookAndChainedSubmit(t *testing.T) {
+	type flashed struct{ board, design, token, bitstream string }
+	flashes := make(chan flashed, 2)
+	loader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f, _, err := r.FormFile("bitstream")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var bit bytes.Buffer
+		_, _ = bit.ReadFrom(f)
+		flashes <- flashed{r.FormValue("board"), r.FormValue("design_name"), r.Header.Get("X-Build-Token"), bit.String()}
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"job_id":"flash-1"}`))
+	}))
+	defer loader.Close()
+	hooks := make(chan webhookPayload, 2)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p webhookPayload
+		_ = json.NewDecoder(r.Body).Decode(&p)
+		hooks <- p
+	}))
+	defer webhook.Close()
+
+	cfg := testConfig(t)
+	cfg.Loaders = map[string]string{"bench": loader.URL}
+	cfg.LoaderToken = "loader-secret"
+	cfg.Webhooks = map[string]string{"ci": webhook.URL}
+	mgr := New(cfg, store.New(cfg), &builder.FakeBuilder{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	mf := manifest.Manifest{
+		Schema:  1,
+		Project: "blinky",
+		Top:     "top",
+		Part:    "xc7a35tcsg324-1",
+		Sources: []string{"hdl/spade.sv"},
+		OnSuccess: []manifest.Action{
+			{Flash: &manifest.FlashAction{Loader: "bench", Board: "arty", DesignName: "{project}-{top}"}},
+			{Submit: &manifest.SubmitAction{
+				Project:   "{project}-sim",
+				Top:       "tb",
+				OnSuccess: []manifest.Action{{Webhook: &manifest.WebhookAction{Name: "ci"}}},
+			}},
+		},
+	}
+	rec, err := mgr.Submit(context.Background(), bytes.NewReader(bundleFromManifest(t, mf, "module top; endmodule\nmodule tb; endmodule\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case f := <-flashes:
+		if f.board != "arty" || f.design != "blinky-top" || f.token != "loader-secret" || f.bitstream != "fake-bitstream" {
+			t.Fatalf("flash request = %+v", f)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no flash submitted")
+	}
+
+	var hook webhookPayload
+	select {
+	case hook = <-hooks:
+	case <-time.After(5 * time.Second):
+		t.Fatal("chained follow-up did not call the webhook")
+	}
+	if hook.Job.ParentJobID != rec.ID || hook.Job.Manifest.Project != "blinky-sim" || hook.Job.Manifest.Top != "tb" {
+		t.Fatalf("follow-up job = parent %q project %q top %q", hook.Job.ParentJobID, hook.Job.Manifest.Project, hook.Job.Manifest.Top)
+	}
+	if len(hook.Job.Manifest.OnSuccess) != 1 || hook.Job.Manifest.OnSuccess[0].Webhook == nil {
+		t.Fatalf("follow-up should carry only its own chain, got %+v", hook.Job.Manifest.OnSuccess)
+	}
+
+	parent, _ := mgr.Get(rec.ID)
+	if len(parent.FollowUps) != 2 {
+		t.Fatalf("follow-ups = %+v", parent.FollowUps)
+	}
+	if fu := parent.FollowUps[0]; fu.Action != manifest.ActionFlash || fu.Target != "bench" || fu.JobID != "flash-1" || fu.Error != "" {
+		t.Fatalf("flash follow-up = %+v", fu)
+	}
+	if fu := parent.FollowUps[1]; fu.Action != manifest.ActionSubmit || fu.JobID != hook.Job.ID || fu.Target != "blinky-sim" {
+		t.Fatalf("submit follow-up = %+v", fu)
+	}
+	if child := waitForTerminalState(t, mgr, hook.Job.ID); child.State != job.StateSucceeded {
+		t.Fatalf("follow-up build = %s", child.State)
+	}
+}
+
+func TestSubmit_RejectsUnknownFollowUpTargets(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Loaders = map[string]string{"bench": "http://bench.lan"}
+	mgr := New(cfg, store.New(cfg), &builder.FakeBuilder{})
+
+	mf := manifest.Manifest{
+		Schema:    1,
+		Project:   "blinky",
+		Top:       "top",
+		Part:      "xc7a35tcsg324-1",
+		Sources:   []string{"hdl/spade.sv"},
+		OnSuccess: []manifest.Action{{Submit: &manifest.SubmitAction{OnSuccess: []manifest.Action{{Flash: &manifest.FlashAction{Loader: "lab", Board: "arty"}}}}}},
+	}
+	_, err := mgr.Submit(context.Background(), bytes.NewReader(bundleFromManifest(t, mf, "module top; endmodule\n")))
+	if err == nil || !strings.Contains(err.Error(), `unknown loader "lab" (configured: bench)`) {
+		t.Fatalf("expected unknown loader rejection, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package queue
+
+import (
+	"sort"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+// maxGlobalEvents bounds the server-wide event backlog kept for resuming
+// GET /v1/events.
+const maxGlobalEvents = 1024
+
+// SubscribeAllEvents streams every job's events. They carry a server-wide
+// Seq instead of the per-job one, so since resumes across jobs. since=0, or
+// a since outside the retained backlog, starts with one snapshot event per
+// queued or running job at the latest seq. Unlike per-job streams, the
+// channel stays open until cancel is called.
+func (m *Manager) SubscribeAllEvents(since int64) ([]job.Event, <-chan job.Event, func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	backlog := m.globalEventsSinceLocked(since)
+	buf := m.subscriberBuf
+	if buf <= 0 {
+		buf = 1
+	}
+	ch := make(chan job.Event, buf)
+	sub := &eventSubscriber{ch: ch, lastSeq: m.globalSeq}
+	if since > 0 && since <= m.globalSeq {
+		sub.lastSeq = since
+	}
+	if len(backlog) > 0 {
+		sub.lastSeq = backlog[len(backlog)-1].Seq
+	}
+	m.globalSubscribers[ch] = sub
+	cancel := func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if _, ok := m.globalSubscribers[ch]; ok {
+			delete(m.globalSubscribers, ch)
+			close(ch)
+		}
+	}
+	return backlog, ch, cancel
+}
+
+func (m *Manager) globalEventsSinceLocked(since int64) []job.Event {
+	src := m.globalEvents
+	if since <= 0 || since > m.globalSeq || (len(src) > 0 && since < src[0].Seq-1) {
+		return m.activeSnapshotsLocked()
+	}
+	out := make([]job.Event, 0, len(src))
+	for _, ev := range src {
+		if ev.Seq > since {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+// activeSnapshotsLocked describes each queued or running job, oldest first,
+// as a snapshot event at the latest server-wide seq.
+func (m *Manager) activeSnapshotsLocked() []job.Event {
+	now := time.Now().UTC()
+	var out []job.Event
+	for _, rec := range m.jobs {
+		if !rec.Terminal() {
+			out = append(out, recordEvent(rec, job.EventSnapshot, m.globalSeq, now))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := m.jobs[out[i].JobID], m.jobs[out[j].JobID]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+	return out
+}
+
+// emitGlobalLocked renumbers a job event into the server-wide sequence and
+// fans it out to SubscribeAllEvents streams.
+func (m *Manager) emitGlobalLocked(ev job.Event) {
+	m.globalSeq++
+	ev.Seq = m.globalSeq
+	m.globalEvents = append(m.globalEvents, ev)
+	if len(m.globalEvents) > maxGlobalEvents {
+		m.globalEvents = m.globalEvents[len(m.globalEvents)-maxGlobalEvents:]
+	}
+	for _, sub := range m.globalSubscribers {
+		m.droppedEvents += sub.publish(ev)
+	}
+}
@@ -0,0 +1,45 @@
+package queue
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+// ListOptions selects a page of jobs for ListJobs. An empty States matches
+// every state; Limit <= 0 returns everything after Offset.
+type ListOptions struct {
+	States []job.State
+	Limit  int
+	Offset int
+}
+
+// ListJobs returns the jobs matching opts newest first, along with the
+// number of matching jobs before paging.
+func (m *Manager) ListJobs(opts ListOptions) ([]job.Record, int) {
+	m.mu.RLock()
+	out := make([]job.Record, 0, len(m.jobs))
+	for _, rec := range m.jobs {
+		if len(opts.States) > 0 && !slices.Contains(opts.States, rec.State) {
+			continue
+		}
+		out = append(out, *rec)
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].ID > out[j].ID
+		}
+		return out[i].CreatedAt.After(out[j].CreatedAt)
+	})
+	total := len(out)
+	if opts.Offset > 0 {
+		out = out[min(opts.Offset, total):]
+	}
+	if opts.Limit > 0 && opts.Limit < len(out) {
+		out = out[:opts.Limit]
+	}
+	return out, total
+}
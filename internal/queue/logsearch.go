@@ -0,0 +1,123 @@
+package queue
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+const (
+	DefaultLogSearchContext = 3
+	MaxLogSearchContext     = 50
+	DefaultLogSearchMatches = 200
+	MaxLogSearchMatches     = 2000
+
+	maxLogSearchQuery = 1024
+	maxLogLineBytes   = 4 << 20
+)
+
+// LogSearchOptions controls SearchLog. Zero values use the defaults.
+type LogSearchOptions struct {
+	File       string
+	Query      string
+	Context    int
+	MaxMatches int
+}
+
+// ErrInvalidLogSearch wraps problems with the caller's search parameters.
+var ErrInvalidLogSearch = errors.New("invalid log search")
+
+// SearchLog scans a job log line by line for a regular expression without
+// loading it into memory, returning each match with opts.Context lines on
+// either side.
+func (m *Manager) SearchLog(jobID string, opts LogSearchOptions) (*job.LogSearchResult, error) {
+	if opts.File == "" {
+		opts.File = "console.log"
+	}
+	if opts.Query == "" {
+		return nil, fmt.Errorf("%w: q is required", ErrInvalidLogSearch)
+	}
+	if len(opts.Query) > maxLogSearchQuery {
+		return nil, fmt.Errorf("%w: q longer than %d bytes", ErrInvalidLogSearch, maxLogSearchQuery)
+	}
+	re, err := regexp.Compile(opts.Query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidLogSearch, err)
+	}
+	if opts.Context < 0 || opts.Context > MaxLogSearchContext {
+		return nil, fmt.Errorf("%w: context must be between 0 and %d", ErrInvalidLogSearch, MaxLogSearchContext)
+	}
+	if opts.MaxMatches <= 0 {
+		opts.MaxMatches = DefaultLogSearchMatches
+	}
+	if opts.MaxMatches > MaxLogSearchMatches {
+		opts.MaxMatches = MaxLogSearchMatches
+	}
+
+	f, err := m.OpenLog(jobID, opts.File)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	result := &job.LogSearchResult{File: opts.File, Query: opts.Query, Context: opts.Context, Matches: []job.LogMatch{}}
+	if err := searchLines(f, re, opts.Context, opts.MaxMatches, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func searchLines(r io.Reader, re *regexp.Regexp, contextLines, maxMatches int, result *job.LogSearchResult) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), maxLogLineBytes)
+
+	before := make([]job.LogLine, 0, contextLines)
+	// open holds indexes of matches still collecting after-context.
+	var open []int
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := job.LogLine{Line: lineNo, Text: sc.Text()}
+
+		stillOpen := open[:0]
+		for _, idx := range open {
+			m := &result.Matches[idx]
+			m.After = append(m.After, line)
+			if len(m.After) < contextLines {
+				stillOpen = append(stillOpen, idx)
+			}
+		}
+		open = stillOpen
+
+		if re.MatchString(line.Text) {
+			if len(result.Matches) == maxMatches {
+				result.Truncated = true
+				if len(open) == 0 {
+					return nil
+				}
+			} else {
+				result.Matches = append(result.Matches, job.LogMatch{
+					LogLine: line,
+					Before:  append([]job.LogLine(nil), before...),
+				})
+				if contextLines > 0 {
+					open = append(open, len(result.Matches)-1)
+				}
+			}
+		} else if result.Truncated && len(open) == 0 {
+			return nil
+		}
+
+		if contextLines > 0 {
+			if len(before) == contextLines {
+				before = append(before[:0], before[1:]...)
+			}
+			before = append(before, line)
+		}
+	}
+	return sc.Err()
+}
@@ -0,0 +1,41 @@
+package queue
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+func TestSearchLines_ContextAndOverlap(t *testing.T) {
+	log := strings.Join([]string{"a", "ERROR one", "b", "ERROR two", "c", "d", "e"}, "\n")
+	var result job.LogSearchResult
+	if err := searchLines(strings.NewReader(log), regexp.MustCompile(`^ERROR`), 1, 10, &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Matches) != 2 || result.Truncated {
+		t.Fatalf("unexpected matches: %+v", result)
+	}
+	first, second := result.Matches[0], result.Matches[1]
+	if first.Line != 2 || len(first.Before) != 1 || first.Before[0].Text != "a" || len(first.After) != 1 || first.After[0].Text != "b" {
+		t.Fatalf("unexpected first match: %+v", first)
+	}
+	if second.Line != 4 || second.Before[0].Line != 3 || second.After[0].Line != 5 {
+		t.Fatalf("unexpected second match: %+v", second)
+	}
+}
+
+func TestSearchLines_TruncatesButFinishesContext(t *testing.T) {
+	log := strings.Join([]string{"hit 1", "x", "hit 2", "y", "hit 3", "z"}, "\n")
+	var result job.LogSearchResult
+	if err := searchLines(strings.NewReader(log), regexp.MustCompile(`hit`), 1, 2, &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Matches) != 2 || !result.Truncated {
+		t.Fatalf("expected truncation after 2 matches, got %+v", result)
+	}
+	if after := result.Matches[1].After; len(after) != 1 || after[0].Text != "y" {
+		t.Fatalf("expected after-context for last kept match, got %+v", after)
+	}
+}
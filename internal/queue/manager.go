@@ -1,16 +1,16 @@
 package queue
 
 import (
-	"bytes"
 	"context"
 	crand "crypto/rand"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -18,7 +18,11 @@ import (
 	spadearchive "github.com/mblsha/spadeforge/internal/archive"
 	"github.com/mblsha/spadeforge/internal/builder"
 	"github.com/mblsha/spadeforge/internal/config"
+	"github.com/mblsha/spadeforge/internal/diskspace"
+	"github.com/mblsha/spadeforge/internal/energy"
 	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/logtime"
+	"github.com/mblsha/spadeforge/internal/logx"
 	"github.com/mblsha/spadeforge/internal/manifest"
 	"github.com/mblsha/spadeforge/internal/store"
 )
@@ -27,35 +31,87 @@ type Manager struct {
 	cfg     config.Config
 	store   *store.Store
 	builder builder.Builder
+	meter   energy.Meter
 
 	mu      sync.RWMutex
 	jobs    map[string]*job.Record
-	queue   chan string
+	pending *fairQueue
 	cancels map[string]context.CancelFunc
+	// canceling marks running jobs whose context was canceled by
+	// CancelJob, so they end CANCELED rather than FAILED.
+	canceling map[string]bool
 
 	events          map[string][]job.Event
 	nextEventSeq    map[string]int64
-	subscribers     map[string]map[chan job.Event]struct{}
+	subscribers     map[string]map[chan job.Event]*eventSubscriber
+	eventLog        *eventLog
 	maxEventsPerJob int
 	subscriberBuf   int
+	droppedEvents   int64
+
+	// globalEvents and globalSubscribers back SubscribeAllEvents, numbered
+	// by globalSeq across all jobs.
+	globalEvents      []job.Event
+	globalSeq         int64
+	globalSubscribers map[chan job.Event]*eventSubscriber
+
+	// disk is the latest work-volume measurement; freeBytes is swapped in
+	// tests.
+	disk      DiskStatus
+	freeBytes func(dir string) (uint64, error)
+
+	// lastReap is the latest retention pass.
+	lastReap *ReapResult
+	// lastOrphanSweep is the latest orphaned dir sweep.
+	lastOrphanSweep *OrphanSweep
+
+	// mirrorMu serializes writes to the static mirror and its index.
+	mirrorMu sync.Mutex
+
+	// storageUsage caches ClientUsage's disk measurement of finished jobs.
+	storageMu    sync.Mutex
+	storageUsage map[string]measuredStorage
+
+	// mqtt publishes state transitions when an MQTT broker is configured.
+	mqtt *mqttNotifier
+
+	// metrics backs the /metrics endpoint.
+	metrics *managerMetrics
 
 	once sync.Once
 }
 
 func New(cfg config.Config, st *store.Store, b builder.Builder) *Manager {
-	return &Manager{
+	m := &Manager{
 		cfg:             cfg,
 		store:           st,
 		builder:         b,
+		meter:           newEnergyMeter(cfg),
+		freeBytes:       diskspace.Free,
 		jobs:            map[string]*job.Record{},
-		queue:           make(chan string, 4096),
+		pending:         newFairQueue(cfg.SubmitterWeights),
 		cancels:         map[string]context.CancelFunc{},
+		canceling:       map[string]bool{},
 		events:          map[string][]job.Event{},
 		nextEventSeq:    map[string]int64{},
-		subscribers:     map[string]map[chan job.Event]struct{}{},
+		subscribers:     map[string]map[chan job.Event]*eventSubscriber{},
+		eventLog:        newEventLog(st),
 		maxEventsPerJob: 512,
 		subscriberBuf:   128,
+		storageUsage:    map[string]measuredStorage{},
+
+		globalSubscribers: map[chan job.Event]*eventSubscriber{},
+	}
+	m.metrics = newManagerMetrics(m)
+	if cfg.MQTTURL != "" {
+		n, err := newMQTTNotifier(cfg.MQTTURL, cfg.MQTTTopicPrefix)
+		if err != nil {
+			qlog.Warnf("[queue] mqtt publishing disabled: %v", err)
+		} else {
+			m.mqtt = n
+		}
 	}
+	return m
 }
 
 func (m *Manager) Start(ctx context.Context) error {
@@ -67,39 +123,82 @@ func (m *Manager) Start(ctx context.Context) error {
 	}
 
 	m.once.Do(func() {
+		if m.mqtt != nil {
+			go m.mqtt.run(ctx)
+		}
+		go m.eventLog.run(ctx)
 		go m.worker(ctx)
+		go m.reapLoop(ctx)
 	})
 	return nil
 }
 
 func (m *Manager) Submit(ctx context.Context, bundle io.Reader) (*job.Record, error) {
-	_ = ctx
+	return m.SubmitFrom(ctx, "", bundle)
+}
+
+// SubmitFrom queues a bundle on behalf of submitter, the identity used to
+// share the builder fairly between users.
+func (m *Manager) SubmitFrom(ctx context.Context, submitter string, bundle io.Reader) (*job.Record, error) {
+	return m.SubmitWithOptions(ctx, SubmitOptions{Submitter: submitter}, bundle)
+}
+
+// SubmitOptions are per-request submit settings.
+type SubmitOptions struct {
+	// Submitter is the identity used to share the builder fairly.
+	Submitter string
+	// NoCache builds the bundle even when the build cache holds a job
+	// with the same inputs.
+	NoCache bool
+	// TokenName is the named API token that submitted the bundle.
+	TokenName string
+	// ClientIP is the address the bundle came from.
+	ClientIP string
+}
+
+// SubmitWithOptions queues a bundle, or completes it at once from the build
+// cache when that is enabled and holds a SUCCEEDED job with the same
+// manifest and sources.
+func (m *Manager) SubmitWithOptions(ctx context.Context, opts SubmitOptions, bundle io.Reader) (*job.Record, error) {
+	return m.submit(ctx, opts, "", bundle)
+}
+
+// submit queues a bundle; parentID is set for on_success follow-ups.
+func (m *Manager) submit(ctx context.Context, opts SubmitOptions, parentID string, bundle io.Reader) (_ *job.Record, err error) {
 	id, err := newJobID()
 	if err != nil {
 		return nil, fmt.Errorf("generate job id: %w", err)
 	}
+	// A rejected bundle leaves no state file, so nothing else would ever
+	// clean its directories up.
+	defer func() {
+		if err != nil {
+			_ = m.store.RemoveJob(id)
+		}
+	}()
 	if err := m.store.CreateJobLayout(id); err != nil {
 		return nil, err
 	}
 
-	var buf bytes.Buffer
-	if _, err := io.Copy(&buf, bundle); err != nil {
-		return nil, fmt.Errorf("read uploaded bundle: %w", err)
-	}
-
-	if err := m.store.WriteRequestZip(id, bytes.NewReader(buf.Bytes())); err != nil {
+	upload := &countingReader{r: bundle}
+	err = m.store.WriteRequestZip(id, upload)
+	m.metrics.uploadBytes.Add(float64(upload.n))
+	if err != nil {
 		return nil, err
 	}
 
-	if _, err := spadearchive.ExtractZipSecure(
-		m.store.RequestZipPath(id),
-		m.store.SourceDir(id),
-		spadearchive.Limits{
-			MaxFiles:      m.cfg.MaxExtractedFiles,
-			MaxTotalBytes: m.cfg.MaxExtractedTotalBytes,
-			MaxFileBytes:  m.cfg.MaxExtractedFileBytes,
-		},
-	); err != nil {
+	if err := m.store.WithPlainFile(m.store.RequestZipPath(id), func(zipPath string) error {
+		_, err := spadearchive.ExtractZipSecure(
+			zipPath,
+			m.store.SourceDir(id),
+			spadearchive.Limits{
+				MaxFiles:      m.cfg.MaxExtractedFiles,
+				MaxTotalBytes: m.cfg.MaxExtractedTotalBytes,
+				MaxFileBytes:  m.cfg.MaxExtractedFileBytes,
+			},
+		)
+		return err
+	}); err != nil {
 		return nil, fmt.Errorf("extract bundle: %w", err)
 	}
 
@@ -115,8 +214,28 @@ func (m *Manager) Submit(ctx context.Context, bundle io.Reader) (*job.Record, er
 	if err := mf.Validate(m.store.SourceDir(id)); err != nil {
 		return nil, fmt.Errorf("validate manifest: %w", err)
 	}
+	if err := m.checkActionTargets(mf.OnSuccess); err != nil {
+		return nil, fmt.Errorf("validate manifest: %w", err)
+	}
+	if err := m.checkSecrets(mf.Secrets); err != nil {
+		return nil, fmt.Errorf("validate manifest: %w", err)
+	}
+
+	key, err := cacheKey(mf, m.store.SourceDir(id))
+	if err != nil {
+		return nil, fmt.Errorf("hash bundle: %w", err)
+	}
 
 	rec := job.New(id, mf, time.Now())
+	rec.Submitter = strings.TrimSpace(opts.Submitter)
+	rec.TokenName = opts.TokenName
+	rec.ClientIP = opts.ClientIP
+	rec.ParentJobID = parentID
+	rec.CacheKey = key
+	if disk := m.DiskStatus(); disk.Paused {
+		rec.Message = disk.Reason
+	}
+	rec.Warnings = mf.Lint(m.store.SourceDir(id))
 	if err := m.store.Save(rec); err != nil {
 		return nil, err
 	}
@@ -125,9 +244,27 @@ func (m *Manager) Submit(ctx context.Context, bundle io.Reader) (*job.Record, er
 	m.jobs[id] = rec
 	m.emitEventLocked(rec, "queued")
 	m.mu.Unlock()
-	log.Printf("%s queued top=%q part=%q", jobLogPrefix(rec.ID, rec.Manifest.Project), rec.Manifest.Top, rec.Manifest.Part)
+	m.metrics.submitted.Inc()
+	if g := rec.Manifest.Git; g != nil {
+		qlog.Infof("%s queued top=%q part=%q git=%s branch=%q", jobLogPrefix(rec.ID, rec.Manifest.Project), rec.Manifest.Top, rec.Manifest.Part, g.Short(), g.Branch)
+	} else {
+		qlog.Infof("%s queued top=%q part=%q", jobLogPrefix(rec.ID, rec.Manifest.Project), rec.Manifest.Top, rec.Manifest.Part)
+	}
+	if rec.TokenName != "" {
+		qlog.Infof("%s submitted with token %q", jobLogPrefix(rec.ID, rec.Manifest.Project), rec.TokenName)
+	}
+	for _, w := range rec.Warnings {
+		qlog.Infof("%s manifest lint %s", jobLogPrefix(rec.ID, rec.Manifest.Project), w)
+	}
 
-	m.enqueue(id)
+	// The cache key covers secret names, not their contents, so a rotated
+	// key would otherwise be answered with a bitstream built from the old one.
+	if m.cfg.BuildCache && !opts.NoCache && len(mf.Secrets) == 0 && m.finishFromCache(ctx, rec) {
+		if done, ok := m.Get(rec.ID); ok {
+			return done, nil
+		}
+	}
+	m.enqueue(rec)
 	return rec, nil
 }
 
@@ -142,6 +279,43 @@ func (m *Manager) Get(jobID string) (*job.Record, bool) {
 	return &copyRec, true
 }
 
+// QueuePosition reports where a queued job sits in the fair dequeue order:
+// position 1 starts next. ahead counts queued jobs that will start earlier
+// plus jobs currently running. Both are 0 once the job has started.
+func (m *Manager) QueuePosition(jobID string) (position, ahead int, ok bool) {
+	order := m.pending.order()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rec, ok := m.jobs[jobID]
+	if !ok {
+		return 0, 0, false
+	}
+	if rec.State != job.StateQueued {
+		return 0, 0, true
+	}
+	for _, id := range order {
+		if id == jobID {
+			break
+		}
+		if other, ok := m.jobs[id]; ok && other.State == job.StateQueued {
+			position++
+		}
+	}
+	for _, other := range m.jobs {
+		if other.State == job.StateRunning {
+			ahead++
+		}
+	}
+	return position + 1, ahead + position, true
+}
+
+func queuedBefore(a, b *job.Record) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID < b.ID
+}
+
 func (m *Manager) KillJob(jobID string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -156,7 +330,7 @@ func (m *Manager) KillJob(jobID string) error {
 	if !ok {
 		return fmt.Errorf("job %s is queued but not yet running", jobID)
 	}
-	log.Printf("%s kill requested", jobLogPrefix(rec.ID, rec.Manifest.Project))
+	qlog.Infof("%s kill requested", jobLogPrefix(rec.ID, rec.Manifest.Project))
 	cancel()
 	return nil
 }
@@ -173,7 +347,7 @@ func (m *Manager) DownloadArtifacts(jobID string, w io.Writer) error {
 	if _, err := os.Stat(artifactsDir); err != nil {
 		return err
 	}
-	return spadearchive.WriteZipFromDir(artifactsDir, w)
+	return spadearchive.WriteZipFromDirFunc(artifactsDir, w, m.store.Open)
 }
 
 func (m *Manager) ReadConsoleLog(jobID string) ([]byte, error) {
@@ -181,21 +355,88 @@ func (m *Manager) ReadConsoleLog(jobID string) ([]byte, error) {
 	return os.ReadFile(path)
 }
 
+// LogNames are the job logs served by OpenLog.
+var LogNames = []string{"console.log", "vivado.log"}
+
+// ErrUnknownLog is returned by OpenLog for names outside LogNames.
+var ErrUnknownLog = errors.New("unknown log; expected console.log or vivado.log")
+
+// OpenLog opens one of the job's logs for streaming.
+func (m *Manager) OpenLog(jobID, name string) (*os.File, error) {
+	if !slices.Contains(LogNames, name) {
+		return nil, ErrUnknownLog
+	}
+	return os.Open(filepath.Join(m.store.ArtifactsJobDir(jobID), name))
+}
+
+// OpenLogSince opens one of the job's logs positioned at the first line
+// written at or after since, and returns that offset. Logs without a
+// logtime index are returned whole, and a log with no line that recent is
+// positioned at its end.
+func (m *Manager) OpenLogSince(jobID, name string, since time.Time) (*os.File, int64, error) {
+	f, err := m.OpenLog(jobID, name)
+	if err != nil || since.IsZero() {
+		return f, 0, err
+	}
+	idx, err := os.Open(logtime.IndexPath(f.Name()))
+	if err != nil {
+		return f, 0, nil
+	}
+	defer idx.Close()
+	offset, ok, err := logtime.Offset(idx, since)
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	whence := io.SeekStart
+	if !ok {
+		whence = io.SeekEnd
+	}
+	pos, err := f.Seek(offset, whence)
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, pos, nil
+}
+
+// OpenBundle opens the job's original request zip, exactly as submitted.
+// It is an *os.File unless the zip is encrypted at rest.
+func (m *Manager) OpenBundle(jobID string) (io.ReadCloser, error) {
+	return m.store.Open(m.store.RequestZipPath(jobID))
+}
+
 func (m *Manager) recoverJobs() error {
 	recs, err := m.store.LoadAll()
 	if err != nil {
 		return err
 	}
+	sort.Slice(recs, func(i, j int) bool { return queuedBefore(recs[i], recs[j]) })
 	for _, rec := range recs {
 		m.jobs[rec.ID] = rec
+		m.recoverEvents(rec)
 		switch rec.State {
+		case job.StateSucceeded, job.StateFailed, job.StateCanceled:
+			// A crash between the terminal save and the cleanup at the end
+			// of process leaves the work dir behind.
+			if !m.cfg.PreserveWorkDir {
+				_ = m.store.RemoveWorkDir(rec.ID)
+			} else {
+				m.scrubSecrets(rec)
+			}
 		case job.StateQueued:
-			m.enqueue(rec.ID)
+			m.enqueue(rec)
 		case job.StateRunning:
+			// A crash mid-build leaves the secrets in place.
+			m.scrubSecrets(rec)
 			now := time.Now().UTC()
 			rec.State = job.StateQueued
 			rec.UpdatedAt = now
 			rec.Message = "requeued after restart"
+			// The interrupted run is started again, not counted as a retry.
+			if rec.Attempt > 0 {
+				rec.Attempt--
+			}
 			rec.Error = ""
 			rec.FailureKind = ""
 			rec.FailureSummary = ""
@@ -207,19 +448,48 @@ func (m *Manager) recoverJobs() error {
 			if err := m.store.Save(rec); err != nil {
 				return err
 			}
-			m.enqueue(rec.ID)
+			m.emitEventLocked(rec, "queued")
+			m.enqueue(rec)
 		}
 	}
 	return nil
 }
 
+// recoverEvents reloads the job's persisted events so subscribers can
+// resume with since across a restart.
+func (m *Manager) recoverEvents(rec *job.Record) {
+	events, err := m.store.LoadEvents(rec.ID, m.maxEventsPerJob)
+	if err != nil {
+		qlog.Warnf("%s load events: %v", jobLogPrefix(rec.ID, rec.Manifest.Project), err)
+		return
+	}
+	if n := len(events); n > 0 {
+		m.events[rec.ID] = events
+		m.nextEventSeq[rec.ID] = events[n-1].Seq
+	}
+}
+
 func (m *Manager) worker(ctx context.Context) {
 	for {
+		if ctx.Err() != nil {
+			return
+		}
+		if m.diskPaused() {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(diskRecheckInterval):
+			}
+			continue
+		}
+		if id, ok := m.pending.pop(); ok {
+			m.process(ctx, id)
+			continue
+		}
 		select {
 		case <-ctx.Done():
 			return
-		case id := <-m.queue:
-			m.process(ctx, id)
+		case <-m.pending.wake:
 		}
 	}
 }
@@ -248,40 +518,117 @@ func (m *Manager) process(parentCtx context.Context, id string) {
 	_ = m.store.Save(rec)
 	m.emitEventLocked(rec, "running")
 	m.mu.Unlock()
-	log.Printf("%s started top=%q part=%q", jobLogPrefix(id, project), startTop, startPart)
-
-	result, buildErr := m.builder.Build(ctx, builder.BuildJob{
-		ID:           rec.ID,
-		WorkDir:      m.store.WorkJobDir(rec.ID),
-		SourceDir:    m.store.SourceDir(rec.ID),
-		ArtifactsDir: m.store.ArtifactsJobDir(rec.ID),
-		Manifest:     rec.Manifest,
-		Progress:     m.progressUpdater(rec.ID),
-	})
+	qlog.Infof("%s started top=%q part=%q", jobLogPrefix(id, project), startTop, startPart)
+
+	var (
+		result      builder.BuildResult
+		buildErr    error
+		energyUsage *job.EnergyUsage
+	)
+	extraDiags := lintDiagnostics(rec.Warnings)
+	pre := m.preflight(rec)
+	if pre == nil {
+		pre = m.materializeSecrets(rec)
+	}
+	if pre != nil {
+		qlog.Infof("%s preflight failed: %s", jobLogPrefix(id, project), pre.Summary)
+		result = m.writePreflightLog(rec.ID, pre)
+		buildErr = pre
+		extraDiags = append([]job.Diagnostic{pre.Diagnostic}, extraDiags...)
+	} else {
+		sample := m.meter.Start()
+		buildStart := time.Now()
+		result, buildErr = m.builder.Build(ctx, builder.BuildJob{
+			ID:           rec.ID,
+			WorkDir:      m.store.WorkJobDir(rec.ID),
+			SourceDir:    m.store.SourceDir(rec.ID),
+			ArtifactsDir: m.store.ArtifactsJobDir(rec.ID),
+			Manifest:     rec.Manifest,
+			Progress:     m.progressUpdater(rec.ID),
+		})
+		m.scrubSecrets(rec)
+		m.metrics.buildDuration.Observe(time.Since(buildStart).Seconds())
+		energyUsage = m.energyUsage(sample)
+	}
+
+	m.mu.RLock()
+	canceled := buildErr != nil && m.canceling[id]
+	m.mu.RUnlock()
 
 	finalState := job.StateSucceeded
-	if buildErr != nil {
+	if canceled {
+		finalState = job.StateCanceled
+	} else if buildErr != nil {
 		finalState = job.StateFailed
 	}
 
-	diagReport := m.writeDiagnosticsReport(rec.ID)
+	if err := m.applyArtifactRules(rec.ID, rec.Manifest); err != nil {
+		qlog.Warnf("%s apply artifact rules: %v", jobLogPrefix(id, project), err)
+	}
+	if finalState == job.StateSucceeded {
+		if name, err := m.copyNamedBitstream(rec); err != nil {
+			qlog.Warnf("%s name bitstream: %v", jobLogPrefix(id, project), err)
+		} else if name != "" {
+			qlog.Infof("%s bitstream copied to %s", jobLogPrefix(id, project), name)
+		}
+	}
+	if err := m.store.SealBitstreams(rec.ID); err != nil {
+		qlog.Warnf("%s encrypt bitstreams: %v", jobLogPrefix(id, project), err)
+	}
+	diagReport := m.writeDiagnosticsReport(rec.ID, rec.Manifest.Diagnostics, extraDiags...)
+	metrics := m.collectMetrics(rec.ID, diagReport)
 	failureKind := ""
 	failureSummary := ""
-	if finalState == job.StateFailed {
+	if pre != nil {
+		failureKind, failureSummary = pre.Kind, pre.Summary
+	} else if finalState == job.StateFailed {
 		failureKind, failureSummary = inferFailure(diagReport, result.Message, buildErr)
 	}
-	_ = m.writeArtifactManifest(rec.ID, finalState, result, diagReport, failureKind, failureSummary)
+	// Preflight failures and killed builds would fail the same way again.
+	retry := finalState == job.StateFailed && pre == nil && !errors.Is(buildErr, context.Canceled) &&
+		m.retryPolicy(rec.Manifest).Retries(failureKind, len(rec.Retries))
+	if finalState == job.StateFailed && !retry {
+		if err := m.writeFailureReport(rec.ID, result, diagReport, failureKind, failureSummary); err != nil {
+			qlog.Warnf("%s write failure report: %v", jobLogPrefix(id, project), err)
+		}
+	}
+	_ = m.writeArtifactManifest(rec.ID, finalState, result, diagReport, energyUsage, failureKind, failureSummary)
 
 	m.mu.Lock()
 	delete(m.cancels, id)
+	delete(m.canceling, id)
 	rec, ok = m.jobs[id]
 	if !ok {
 		m.mu.Unlock()
 		return
 	}
 	now := time.Now()
+	rec.Metrics = metrics
+	rec.Energy = energyUsage
+	rec.Baseline = m.compareToBaselineLocked(rec)
+	baselineLog := ""
+	if rec.Baseline != nil {
+		baselineLog = fmt.Sprintf("%s baseline %s: %s", jobLogPrefix(id, rec.Manifest.Project), rec.Baseline.BaselineJobID, rec.Baseline.Summary)
+	}
 	terminalLog := ""
-	if buildErr != nil {
+	if canceled {
+		if markErr := rec.MarkCanceled(now, "canceled while running"); markErr != nil {
+			rec.State = job.StateCanceled
+			rec.UpdatedAt = now.UTC()
+			rec.Message = "canceled while running"
+			rec.FinishedAt = &rec.UpdatedAt
+			rec.CurrentStep = "canceled"
+		}
+		rec.ExitCode = &result.ExitCode
+		terminalLog = fmt.Sprintf("%s canceled while running", jobLogPrefix(id, rec.Manifest.Project))
+		m.emitEventLocked(rec, "canceled")
+	} else if retry {
+		_ = rec.Requeue(now, failureKind, failureSummary)
+		rec.CurrentStep = ""
+		terminalLog = fmt.Sprintf("%s attempt %d failed kind=%s summary=%q; retrying (%d of %d)",
+			jobLogPrefix(id, rec.Manifest.Project), rec.Attempt, failureKind, failureSummary, len(rec.Retries), m.retryPolicy(rec.Manifest).MaxRetries)
+		m.emitEventLocked(rec, "retrying")
+	} else if buildErr != nil {
 		if markErr := rec.MarkFailed(now, result.Message, buildErr, result.ExitCode); markErr != nil {
 			rec.State = job.StateFailed
 			rec.UpdatedAt = now.UTC()
@@ -325,19 +672,51 @@ func (m *Manager) process(parentCtx context.Context, id string) {
 	}
 	_ = m.store.Save(rec)
 	jobID := rec.ID
+	followUp := *rec
 	preserveWorkDir := m.cfg.PreserveWorkDir
 	m.mu.Unlock()
 	if terminalLog != "" {
-		log.Print(terminalLog)
+		qlog.Infof("%s", terminalLog)
+	}
+	if baselineLog != "" {
+		qlog.Infof("%s", baselineLog)
+	}
+	if buildErr == nil && m.cfg.MirrorDir != "" {
+		if mirrored, ok := m.Get(jobID); ok {
+			if err := m.mirrorArtifacts(mirrored); err != nil {
+				qlog.Warnf("%s mirror artifacts: %v", jobLogPrefix(jobID, project), err)
+			}
+		}
 	}
 
+	if retry {
+		// The work dir holds the extracted sources the next attempt builds.
+		m.enqueue(&followUp)
+		return
+	}
 	if !preserveWorkDir {
 		_ = m.store.RemoveWorkDir(jobID)
 	}
+	if buildErr == nil && len(followUp.Manifest.OnSuccess) > 0 {
+		go m.runFollowUps(parentCtx, followUp)
+	}
 }
 
-func (m *Manager) enqueue(jobID string) {
-	m.queue <- jobID
+// retryPolicy is the server's retry policy with the manifest's overrides.
+func (m *Manager) retryPolicy(mf manifest.Manifest) manifest.RetryPolicy {
+	p := manifest.RetryPolicy{MaxRetries: m.cfg.MaxRetries, RetryOn: m.cfg.RetryOn}
+	if mf.Retry != nil {
+		p.MaxRetries = mf.Retry.MaxRetries
+		if len(mf.Retry.RetryOn) > 0 {
+			p.RetryOn = mf.Retry.RetryOn
+		}
+	}
+	return p
+}
+
+func (m *Manager) enqueue(rec *job.Record) {
+	m.pending.push(rec.Submitter, rec.ID)
+	qlog.Debugf("%s enqueued submitter=%q", jobLogPrefix(rec.ID, rec.Manifest.Project), rec.Submitter)
 }
 
 func newJobID() (string, error) {
@@ -378,8 +757,9 @@ func (m *Manager) progressUpdater(jobID string) builder.ProgressFunc {
 		m.emitEventLocked(rec, "progress")
 		m.mu.Unlock()
 		if logStep != "" {
-			log.Printf("%s step=%s", jobLogPrefix(jobID, project), logStep)
+			qlog.Infof("%s step=%s", jobLogPrefix(jobID, project), logStep)
 		}
+		qlog.Tracef("%s heartbeat step=%s message=%q", jobLogPrefix(jobID, project), update.Step, update.Message)
 	}
 }
 
@@ -401,7 +781,7 @@ func (m *Manager) SubscribeEvents(jobID string, since int64) ([]job.Event, <-cha
 		return nil, nil, nil, false
 	}
 
-	backlog := m.eventsSinceLocked(jobID, since)
+	backlog := m.eventsSinceLocked(rec, since)
 	if rec.Terminal() {
 		return backlog, nil, func() {}, true
 	}
@@ -412,9 +792,13 @@ func (m *Manager) SubscribeEvents(jobID string, since int64) ([]job.Event, <-cha
 	}
 	ch := make(chan job.Event, buf)
 	if m.subscribers[jobID] == nil {
-		m.subscribers[jobID] = map[chan job.Event]struct{}{}
+		m.subscribers[jobID] = map[chan job.Event]*eventSubscriber{}
+	}
+	sub := &eventSubscriber{ch: ch, lastSeq: since}
+	if len(backlog) > 0 {
+		sub.lastSeq = backlog[len(backlog)-1].Seq
 	}
-	m.subscribers[jobID][ch] = struct{}{}
+	m.subscribers[jobID][ch] = sub
 	cancel := func() {
 		m.mu.Lock()
 		defer m.mu.Unlock()
@@ -433,8 +817,17 @@ func (m *Manager) SubscribeEvents(jobID string, since int64) ([]job.Event, <-cha
 	return backlog, ch, cancel, true
 }
 
-func (m *Manager) eventsSinceLocked(jobID string, since int64) []job.Event {
-	src := m.events[jobID]
+// eventsSinceLocked returns the retained events after since. When since
+// falls outside the retained window (older events were compacted away, or
+// since is ahead of the job's sequence after a restart), the backlog is a
+// single snapshot event built from the current record at the latest seq, so
+// the subscriber resumes from a consistent state instead of a partial one.
+func (m *Manager) eventsSinceLocked(rec *job.Record, since int64) []job.Event {
+	src := m.events[rec.ID]
+	latest := m.nextEventSeq[rec.ID]
+	if since > latest || (len(src) > 0 && since < src[0].Seq-1) {
+		return []job.Event{recordEvent(rec, job.EventSnapshot, latest, time.Now().UTC())}
+	}
 	if len(src) == 0 {
 		return nil
 	}
@@ -448,10 +841,29 @@ func (m *Manager) eventsSinceLocked(jobID string, since int64) []job.Event {
 }
 
 func (m *Manager) emitEventLocked(rec *job.Record, eventType string) {
-	now := time.Now().UTC()
 	seq := m.nextEventSeq[rec.ID] + 1
 	m.nextEventSeq[rec.ID] = seq
 
+	ev := recordEvent(rec, eventType, seq, time.Now().UTC())
+	list := append(m.events[rec.ID], ev)
+	if len(list) > m.maxEventsPerJob {
+		list = list[len(list)-m.maxEventsPerJob:]
+	}
+	m.events[rec.ID] = list
+	m.eventLog.enqueue(ev)
+
+	for _, sub := range m.subscribers[rec.ID] {
+		m.droppedEvents += sub.publish(ev)
+	}
+	m.emitGlobalLocked(ev)
+	m.observeEventLocked(rec, eventType)
+	if m.mqtt != nil {
+		m.mqtt.enqueue(ev)
+	}
+}
+
+// recordEvent snapshots rec into an event with the given type and seq.
+func recordEvent(rec *job.Record, eventType string, seq int64, now time.Time) job.Event {
 	var heartbeat *time.Time
 	if rec.HeartbeatAt != nil {
 		hb := rec.HeartbeatAt.UTC()
@@ -463,7 +875,7 @@ func (m *Manager) emitEventLocked(rec *job.Record, eventType string) {
 		exitCode = &ec
 	}
 
-	ev := job.Event{
+	return job.Event{
 		Seq:            seq,
 		JobID:          rec.ID,
 		Project:        rec.Manifest.Project,
@@ -474,21 +886,15 @@ func (m *Manager) emitEventLocked(rec *job.Record, eventType string) {
 		Error:          rec.Error,
 		FailureKind:    rec.FailureKind,
 		FailureSummary: rec.FailureSummary,
+		Attempt:        rec.Attempt,
 		HeartbeatAt:    heartbeat,
 		ExitCode:       exitCode,
 		At:             now,
 	}
-	list := append(m.events[rec.ID], ev)
-	if len(list) > m.maxEventsPerJob {
-		list = list[len(list)-m.maxEventsPerJob:]
-	}
-	m.events[rec.ID] = list
-
-	for ch := range m.subscribers[rec.ID] {
-		publishEvent(ch, ev)
-	}
 }
 
+var qlog = logx.For(logx.ModuleQueue)
+
 func jobLogPrefix(jobID, project string) string {
 	project = strings.TrimSpace(project)
 	if project == "" {
@@ -503,26 +909,3 @@ func projectName(rec *job.Record) string {
 	}
 	return strings.TrimSpace(rec.Manifest.Project)
 }
-
-func publishEvent(ch chan job.Event, ev job.Event) {
-	select {
-	case ch <- ev:
-		return
-	default:
-	}
-
-	// For non-terminal updates, dropping events is acceptable when a subscriber is slow.
-	if !ev.Terminal() {
-		return
-	}
-
-	// Guarantee terminal delivery: evict one queued event and retry.
-	select {
-	case <-ch:
-	default:
-	}
-	select {
-	case ch <- ev:
-	default:
-	}
-}
@@ -6,6 +6,8 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
@@ -179,6 +181,72 @@ func TestWorker_UpdatesStatesCorrectly_OnFailure(t *testing.T) {
 	}
 }
 
+func TestWorker_WritesFailureReport(t *testing.T) {
+	cfg := testConfig(t)
+	st := store.New(cfg)
+	fb := &builder.FakeBuilder{
+		FailProjects: map[string]error{"fail": errors.New("forced failure")},
+		VivadoLog:    "ERROR: [Synth 8-2716] syntax error near 'endmodule' [/w/src/hdl/top.sv:12]\n",
+	}
+	mgr := New(cfg, st, fb)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	okRec, err := mgr.Submit(context.Background(), bytes.NewReader(validBundleBytes(t, "ok")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	failRec, err := mgr.Submit(context.Background(), bytes.NewReader(validBundleBytes(t, "fail")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForTerminalState(t, mgr, okRec.ID)
+	waitForTerminalState(t, mgr, failRec.ID)
+
+	if _, err := os.Stat(filepath.Join(st.ArtifactsJobDir(okRec.ID), "failure_report.json")); !os.IsNotExist(err) {
+		t.Fatalf("did not expect failure report on success")
+	}
+	raw, err := os.ReadFile(filepath.Join(st.ArtifactsJobDir(failRec.ID), "failure_report.json"))
+	if err != nil {
+		t.Fatalf("expected failure report: %v", err)
+	}
+	var report struct {
+		Kind    string `json:"kind"`
+		Summary string `json:"summary"`
+		Errors  []struct {
+			Code string `json:"code"`
+			File string `json:"file"`
+			Line int    `json:"line"`
+			Hint *struct {
+				Explanation string `json:"explanation"`
+			} `json:"hint"`
+		} `json:"errors"`
+		Tail string `json:"tail"`
+		Logs map[string]struct {
+			URL string `json:"url"`
+		} `json:"logs"`
+	}
+	if err := json.Unmarshal(raw, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Kind != "syntax" || report.Summary == "" {
+		t.Fatalf("unexpected verdict: %+v", report)
+	}
+	if len(report.Errors) == 0 || report.Errors[0].Code != "Synth 8-2716" || report.Errors[0].Line != 12 || report.Errors[0].Hint == nil {
+		t.Fatalf("unexpected errors: %+v", report.Errors)
+	}
+	if report.Tail == "" {
+		t.Fatalf("expected console tail excerpt")
+	}
+	if got := report.Logs["vivado.log"].URL; got != "/v1/jobs/"+failRec.ID+"/log?file=vivado.log" {
+		t.Fatalf("unexpected vivado.log link %q", got)
+	}
+}
+
 func TestQueue_IsSequential(t *testing.T) {
 	cfg := testConfig(t)
 	st := store.New(cfg)
@@ -357,6 +425,222 @@ func TestWorker_PreserveWorkDirOption(t *testing.T) {
 	}
 }
 
+func TestOpenWorkDirFile_RejectsSymlinks(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.PreserveWorkDir = true
+	st := store.New(cfg)
+	mgr := New(cfg, st, &builder.FakeBuilder{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := mgr.Submit(context.Background(), bytes.NewReader(validBundleBytes(t, "links")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForTerminalState(t, mgr, rec.ID)
+
+	outside := filepath.Join(t.TempDir(), "passwd")
+	if err := os.WriteFile(outside, []byte("root:x:0:0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	workDir := st.WorkJobDir(rec.ID)
+	if err := os.Symlink(outside, filepath.Join(workDir, "leak.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Dir(outside), filepath.Join(workDir, "leakdir")); err != nil {
+		t.Fatal(err)
+	}
+	for _, rel := range []string{"leak.txt", "leakdir/passwd"} {
+		if f, _, err := mgr.OpenWorkDirFile(rec.ID, rel); err == nil {
+			f.Close()
+			t.Fatalf("OpenWorkDirFile(%s) followed a symlink out of the work dir", rel)
+		}
+	}
+	if f, _, err := mgr.OpenWorkDirFile(rec.ID, "src/hdl/spade.sv"); err != nil {
+		t.Fatalf("OpenWorkDirFile(src/hdl/spade.sv) error: %v", err)
+	} else {
+		f.Close()
+	}
+}
+
+func TestApplyArtifactRules_SkipsSymlinks(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.ArtifactInclude = []string{"reports/**"}
+	st := store.New(cfg)
+	mgr := New(cfg, st, &builder.FakeBuilder{})
+
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(outside, []byte("secret\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reports := filepath.Join(st.WorkJobDir("job1"), "reports")
+	if err := os.MkdirAll(reports, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(reports, "drc.rpt"), []byte("drc\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(reports, "leak.rpt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(st.ArtifactsJobDir("job1"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.applyArtifactRules("job1", manifest.Manifest{}); err != nil {
+		t.Fatalf("applyArtifactRules error: %v", err)
+	}
+	artDir := st.ArtifactsJobDir("job1")
+	if _, err := os.Stat(filepath.Join(artDir, "reports", "drc.rpt")); err != nil {
+		t.Fatalf("expected reports/drc.rpt: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(artDir, "reports", "leak.rpt")); !os.IsNotExist(err) {
+		t.Fatalf("symlinked report was copied, lstat err=%v", err)
+	}
+}
+
+func TestWorker_ArtifactIncludeExcludeRules(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.ArtifactInclude = []string{"reports/**"}
+	cfg.ArtifactExclude = []string{"*.jou", "console.log"}
+	st := store.New(cfg)
+	mgr := New(cfg, st, &builder.FakeBuilder{
+		WorkFiles: map[string]string{
+			"reports/drc.rpt":     "drc fake\n",
+			"reports/deep/io.rpt": "io fake\n",
+			"post_synth.dcp":      "checkpoint\n",
+			"reports/scratch.jou": "journal\n",
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := mgr.Submit(context.Background(), bytes.NewReader(validBundleBytes(t, "globs")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	final := waitForTerminalState(t, mgr, rec.ID)
+	if final.State != job.StateSucceeded {
+		t.Fatalf("expected success, got %s error=%s", final.State, final.Error)
+	}
+
+	artDir := st.ArtifactsJobDir(rec.ID)
+	for _, want := range []string{"reports/drc.rpt", "reports/deep/io.rpt", "console.log", "design.bit"} {
+		if _, err := os.Stat(filepath.Join(artDir, filepath.FromSlash(want))); err != nil {
+			t.Fatalf("expected artifact %s: %v", want, err)
+		}
+	}
+	for _, unwanted := range []string{"post_synth.dcp", "vivado.jou", "reports/scratch.jou", "src/hdl/spade.sv"} {
+		if _, err := os.Stat(filepath.Join(artDir, filepath.FromSlash(unwanted))); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be absent from artifacts, stat err=%v", unwanted, err)
+		}
+	}
+}
+
+// latticeRunner stands in for yosys, nextpnr and icepack, writing the
+// files each would.
+type latticeRunner struct{}
+
+func (latticeRunner) Run(_ context.Context, spec builder.CommandSpec, stdout, _ io.Writer) (int, error) {
+	flag := map[string]string{"yosys": "-l", "nextpnr-ice40": "--log"}[filepath.Base(spec.Name)]
+	for i, arg := range spec.Args {
+		if arg == flag && i+1 < len(spec.Args) {
+			if err := os.WriteFile(spec.Args[i+1], []byte("Yosys 0.38 (git sha1 543faed)\n"), 0o644); err != nil {
+				return 1, err
+			}
+		}
+	}
+	if filepath.Base(spec.Name) == "icepack" {
+		if err := os.WriteFile(spec.Args[1], []byte("bin"), 0o644); err != nil {
+			return 1, err
+		}
+	}
+	fmt.Fprintf(stdout, "%s ran\n", filepath.Base(spec.Name))
+	return 0, nil
+}
+
+func TestWorker_ArtifactExcludeKeepsYosysLogs(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.ArtifactExclude = []string{"*.log"}
+	st := store.New(cfg)
+	mgr := New(cfg, st, builder.NewYosysNextpnrBuilder("", latticeRunner{}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	mf := manifest.Manifest{Schema: 1, Project: "lattice", Top: "top", Part: "ice40-hx8k-ct256", Sources: []string{"hdl/spade.sv"}, Constraints: []string{"pins.pcf"}}
+	rawManifest, err := json.Marshal(mf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bundle bytes.Buffer
+	zw := zip.NewWriter(&bundle)
+	addZipFile(t, zw, "manifest.json", rawManifest)
+	addZipFile(t, zw, "hdl/spade.sv", []byte("module top; endmodule\n"))
+	addZipFile(t, zw, "pins.pcf", []byte("set_io clk J3\n"))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := mgr.Submit(context.Background(), &bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if final := waitForTerminalState(t, mgr, rec.ID); final.State != job.StateSucceeded {
+		t.Fatalf("expected success, got %s error=%s", final.State, final.Error)
+	}
+
+	artDir := st.ArtifactsJobDir(rec.ID)
+	for _, want := range []string{"yosys.log", "nextpnr.log", "console.log", "design.bin"} {
+		if _, err := os.Stat(filepath.Join(artDir, want)); err != nil {
+			t.Fatalf("expected artifact %s to survive the *.log exclude: %v", want, err)
+		}
+	}
+	raw, err := os.ReadFile(filepath.Join(artDir, "artifact_manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(raw), `"version": "0.38"`) {
+		t.Fatalf("expected the yosys version in the artifact manifest:\n%s", raw)
+	}
+}
+
+func TestWorker_CopiesBitstreamUnderTemplateName(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.BitstreamName = "{project}-{part}-{git_short}"
+	st := store.New(cfg)
+	mgr := New(cfg, st, &builder.FakeBuilder{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := mgr.Submit(context.Background(), bytes.NewReader(validBundleBytes(t, "blinky")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	final := waitForTerminalState(t, mgr, rec.ID)
+	if final.State != job.StateSucceeded {
+		t.Fatalf("expected success, got %s error=%s", final.State, final.Error)
+	}
+
+	artDir := st.ArtifactsJobDir(rec.ID)
+	for _, want := range []string{"design.bit", "blinky-xc7a35tcsg324-1-nogit.bit"} {
+		if _, err := os.Stat(filepath.Join(artDir, want)); err != nil {
+			t.Fatalf("expected artifact %s: %v", want, err)
+		}
+	}
+}
+
 func TestEvents_SubscribeProvidesBacklog(t *testing.T) {
 	cfg := testConfig(t)
 	st := store.New(cfg)
@@ -445,6 +729,171 @@ func TestEvents_TerminalEventDeliveredWhenSubscriberBackedUp(t *testing.T) {
 	}
 }
 
+func TestEvents_SlowSubscriberGetsDroppedNotice(t *testing.T) {
+	cfg := testConfig(t)
+	st := store.New(cfg)
+	mgr := New(cfg, st, &builder.FakeBuilder{})
+	mgr.subscriberBuf = 1
+
+	rec := job.New("job1", manifest.Manifest{Top: "top", Part: "part", Sources: []string{"hdl/spade.sv"}}, time.Now())
+	if err := rec.Transition(job.StateRunning, time.Now(), "running"); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr.mu.Lock()
+	mgr.jobs[rec.ID] = rec
+	mgr.emitEventLocked(rec, "running")
+	mgr.mu.Unlock()
+
+	_, ch, release, ok := mgr.SubscribeEvents(rec.ID, 0)
+	if !ok || ch == nil {
+		t.Fatalf("expected live subscription channel")
+	}
+	defer release()
+
+	mgr.mu.Lock()
+	for _, step := range []string{"synth", "place", "route"} {
+		rec.CurrentStep = step
+		mgr.emitEventLocked(rec, "progress")
+	}
+	mgr.mu.Unlock()
+
+	if ev := <-ch; ev.Step != "synth" || ev.Seq != 2 {
+		t.Fatalf("expected first progress event, got %+v", ev)
+	}
+	if stats := mgr.EventStats(); stats.Subscribers != 1 || stats.SlowSubscribers != 1 || stats.DroppedEvents != 2 {
+		t.Fatalf("unexpected event stats: %+v", stats)
+	}
+
+	mgr.mu.Lock()
+	rec.CurrentStep = "bitstream"
+	mgr.emitEventLocked(rec, "progress")
+	mgr.mu.Unlock()
+
+	notice := <-ch
+	if notice.Type != job.EventDroppedEvents || notice.Dropped != 2 || notice.Seq != 2 || notice.Terminal() {
+		t.Fatalf("unexpected dropped notice: %+v", notice)
+	}
+
+	backlog, _, cancelResync, ok := mgr.SubscribeEvents(rec.ID, notice.Seq)
+	if !ok {
+		t.Fatalf("expected resync subscription")
+	}
+	defer cancelResync()
+	if len(backlog) != 3 || backlog[0].Step != "place" || backlog[2].Step != "bitstream" {
+		t.Fatalf("unexpected resync backlog: %+v", backlog)
+	}
+}
+
+func TestEvents_SnapshotWhenSinceOutsideRetainedWindow(t *testing.T) {
+	cfg := testConfig(t)
+	st := store.New(cfg)
+	mgr := New(cfg, st, &builder.FakeBuilder{})
+	mgr.maxEventsPerJob = 2
+
+	rec := job.New("job1", manifest.Manifest{Project: "demo", Top: "top", Part: "part", Sources: []string{"hdl/spade.sv"}}, time.Now())
+	if err := rec.Transition(job.StateRunning, time.Now(), "running"); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr.mu.Lock()
+	mgr.jobs[rec.ID] = rec
+	for _, step := range []string{"synth", "place", "route", "bitstream"} {
+		rec.CurrentStep = step
+		mgr.emitEventLocked(rec, "progress")
+	}
+	mgr.mu.Unlock()
+
+	backlog, _, release, ok := mgr.SubscribeEvents(rec.ID, 2)
+	if !ok {
+		t.Fatalf("expected subscription")
+	}
+	release()
+	if len(backlog) != 2 || backlog[0].Seq != 3 || backlog[1].Seq != 4 {
+		t.Fatalf("expected retained backlog after seq 2, got %+v", backlog)
+	}
+
+	latest := int64(4)
+	for _, since := range []int64{0, 1, 9} {
+		backlog, ch, release, ok := mgr.SubscribeEvents(rec.ID, since)
+		if !ok {
+			t.Fatalf("expected subscription")
+		}
+		if len(backlog) != 1 {
+			release()
+			t.Fatalf("since=%d: expected one snapshot event, got %+v", since, backlog)
+		}
+		snap := backlog[0]
+		if snap.Type != job.EventSnapshot || snap.Seq != latest || snap.Step != "bitstream" || snap.State != job.StateRunning || snap.Project != "demo" {
+			release()
+			t.Fatalf("since=%d: unexpected snapshot: %+v", since, snap)
+		}
+
+		mgr.mu.Lock()
+		mgr.emitEventLocked(rec, "progress")
+		mgr.mu.Unlock()
+		latest++
+		if ev := <-ch; ev.Seq != latest {
+			release()
+			t.Fatalf("since=%d: expected live event after snapshot, got %+v", since, ev)
+		}
+		release()
+	}
+}
+
+func TestEvents_BacklogSurvivesRestart(t *testing.T) {
+	cfg := testConfig(t)
+	st := store.New(cfg)
+	mgr := New(cfg, st, &builder.FakeBuilder{})
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := mgr.Submit(context.Background(), bytes.NewReader(validBundleBytes(t, "demo")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForTerminalState(t, mgr, rec.ID)
+	before, _, _, _ := mgr.SubscribeEvents(rec.ID, 0)
+	cancel()
+	mgr.eventLog.flush()
+
+	// A crash mid-append leaves a torn last line.
+	f, err := os.OpenFile(st.EventsPath(rec.ID), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"seq":99,"job_`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	restarted := New(cfg, st, &builder.FakeBuilder{})
+	if err := restarted.recoverJobs(); err != nil {
+		t.Fatal(err)
+	}
+	after, _, _, ok := restarted.SubscribeEvents(rec.ID, 1)
+	if !ok {
+		t.Fatalf("expected job after restart")
+	}
+	if len(after) != len(before)-1 {
+		t.Fatalf("expected %d events after seq 1, got %+v", len(before)-1, after)
+	}
+	for i, ev := range after {
+		if want := before[i+1]; ev.Seq != want.Seq || ev.Type != want.Type || ev.State != want.State {
+			t.Fatalf("event %d: expected %+v, got %+v", i, want, ev)
+		}
+	}
+
+	raw, err := os.ReadFile(st.EventsPath(rec.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), `"seq":99`) {
+		t.Fatalf("expected torn line to be compacted away:\n%s", raw)
+	}
+}
+
 func waitForTerminalState(t *testing.T, mgr *Manager, id string) *job.Record {
 	t.Helper()
 	deadline := time.Now().Add(5 * time.Second)
@@ -472,19 +921,114 @@ func waitForState(t *testing.T, mgr *Manager, id string, want job.State) {
 	t.Fatalf("timed out waiting for state %s: %s", want, id)
 }
 
+func TestSubmit_RecordsManifestLintAsInfoDiagnostics(t *testing.T) {
+	cfg := testConfig(t)
+	st := store.New(cfg)
+	mgr := New(cfg, st, &builder.FakeBuilder{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := mgr.Submit(context.Background(), bytes.NewReader(validBundleBytes(t, "lint")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.Warnings) != 1 || rec.Warnings[0].Path != "/constraints" {
+		t.Fatalf("expected missing constraints lint warning, got %+v", rec.Warnings)
+	}
+	waitForTerminalState(t, mgr, rec.ID)
+
+	raw, err := os.ReadFile(filepath.Join(st.ArtifactsJobDir(rec.ID), "diagnostics.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report job.DiagnosticsReport
+	if err := json.Unmarshal(raw, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.InfoCount < 1 || len(report.Diagnostics) == 0 {
+		t.Fatalf("expected info diagnostics, got %+v", report)
+	}
+	first := report.Diagnostics[0]
+	if first.Severity != job.SeverityInfo || first.Code != "manifest-lint" || first.Source != "/constraints" {
+		t.Fatalf("unexpected lint diagnostic: %+v", first)
+	}
+}
+
+func TestWorker_MissingTopFailsBeforeBuild(t *testing.T) {
+	cfg := testConfig(t)
+	st := store.New(cfg)
+	fb := &builder.FakeBuilder{}
+	mgr := New(cfg, st, fb)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	source := "module blinky_top(input clk); endmodule\nmodule uart_tx; endmodule\n"
+	rec, err := mgr.Submit(context.Background(), bytes.NewReader(bundleWithTop(t, "typo", "blinky_tpo", source)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	final := waitForTerminalState(t, mgr, rec.ID)
+	if final.State != job.StateFailed || final.FailureKind != "syntax" {
+		t.Fatalf("expected syntax failure, got state=%s kind=%q", final.State, final.FailureKind)
+	}
+	if !strings.Contains(final.FailureSummary, "candidates: blinky_top, uart_tx") {
+		t.Fatalf("expected ranked candidates in summary, got %q", final.FailureSummary)
+	}
+	if len(fb.Calls) != 0 {
+		t.Fatalf("builder should not run when top is missing, got %d calls", len(fb.Calls))
+	}
+
+	tail, err := mgr.ReadConsoleTail(rec.ID, 10)
+	if err != nil || !strings.Contains(string(tail), "blinky_tpo") {
+		t.Fatalf("expected preflight message in console log, got %q err=%v", tail, err)
+	}
+	raw, err := mgr.ReadDiagnostics(rec.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report job.DiagnosticsReport
+	if err := json.Unmarshal(raw, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.ErrorCount != 1 || report.Diagnostics[0].Code != "missing-top" {
+		t.Fatalf("expected missing-top error diagnostic first, got %+v", report)
+	}
+	if report.Diagnostics[0].Hint == nil {
+		t.Fatalf("expected missing-top diagnostic to carry a hint")
+	}
+}
+
 func validBundleBytes(t *testing.T, project string) []byte {
 	t.Helper()
+	return bundleWithTop(t, project, "top", "module top; endmodule\n")
+}
+
+func bundleWithTop(t *testing.T, project, top, source string) []byte {
+	t.Helper()
 
 	mf := manifest.Manifest{
 		Schema:  1,
 		Project: project,
-		Top:     "top",
+		Top:     top,
 		Part:    "xc7a35tcsg324-1",
 		Sources: []string{"hdl/spade.sv"},
 		Build: manifest.Build{
 			Steps: []string{"synth", "impl", "bitstream"},
 		},
 	}
+	return bundleFromManifest(t, mf, source)
+}
+
+func bundleFromManifest(t *testing.T, mf manifest.Manifest, source string) []byte {
+	t.Helper()
 	rawManifest, err := json.Marshal(mf)
 	if err != nil {
 		t.Fatal(err)
@@ -493,7 +1037,7 @@ func validBundleBytes(t *testing.T, project string) []byte {
 	var buf bytes.Buffer
 	zw := zip.NewWriter(&buf)
 	addZipFile(t, zw, "manifest.json", rawManifest)
-	addZipFile(t, zw, "hdl/spade.sv", []byte("module top; endmodule\n"))
+	addZipFile(t, zw, "hdl/spade.sv", []byte(source))
 	if err := zw.Close(); err != nil {
 		t.Fatal(err)
 	}
@@ -522,3 +1066,123 @@ func testConfig(t *testing.T) config.Config {
 	cfg.MaxExtractedFileBytes = 5 << 20
 	return cfg
 }
+
+func TestWorker_UsesDiagnosticsParsersForBuilderTools(t *testing.T) {
+	cfg := testConfig(t)
+	st := store.New(cfg)
+	yosysLog := "1. Executing Verilog-2005 frontend: hdl/top.v\nhdl/top.v:7: ERROR: syntax error, unexpected TOK_ENDMODULE\n"
+	fb := &builder.FakeBuilder{
+		FailProjects:    map[string]error{"fail": errors.New("yosys exited 1")},
+		ConsoleLog:      yosysLog,
+		VivadoLog:       yosysLog,
+		DiagnosticTools: []string{"yosys"},
+	}
+	mgr := New(cfg, st, fb)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := mgr.Submit(context.Background(), bytes.NewReader(validBundleBytes(t, "fail")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	final := waitForTerminalState(t, mgr, rec.ID)
+	if final.State != job.StateFailed || final.FailureKind != "syntax" || !strings.Contains(final.FailureSummary, "hdl/top.v:7") {
+		t.Fatalf("expected yosys syntax failure, got state=%s kind=%q summary=%q", final.State, final.FailureKind, final.FailureSummary)
+	}
+}
+
+func TestWorker_RoutesJobsByManifestToolchain(t *testing.T) {
+	cfg := testConfig(t)
+	st := store.New(cfg)
+	yosysLog := "hdl/top.v:7: ERROR: syntax error, unexpected TOK_ENDMODULE\n"
+	yosys := &builder.FakeBuilder{
+		FailProjects:    map[string]error{"lattice": errors.New("yosys exited 1")},
+		ConsoleLog:      yosysLog,
+		VivadoLog:       yosysLog,
+		DiagnosticTools: []string{"yosys"},
+	}
+	tb := &builder.ToolchainBuilder{
+		Default:  manifest.ToolchainVivado,
+		Builders: map[string]builder.Builder{manifest.ToolchainVivado: &builder.FakeBuilder{}, manifest.ToolchainYosys: yosys},
+	}
+	mgr := New(cfg, st, tb)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	vivadoJob, err := mgr.Submit(context.Background(), bytes.NewReader(validBundleBytes(t, "lattice")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if final := waitForTerminalState(t, mgr, vivadoJob.ID); final.State != job.StateSucceeded {
+		t.Fatalf("default toolchain job = %s, want SUCCEEDED from the vivado builder", final.State)
+	}
+
+	mf := manifest.Manifest{Schema: 1, Project: "lattice", Top: "top", Part: "ice40-hx8k-ct256", Toolchain: "Yosys", Sources: []string{"hdl/spade.sv"}}
+	rec, err := mgr.Submit(context.Background(), bytes.NewReader(bundleFromManifest(t, mf, "module top; endmodule\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	final := waitForTerminalState(t, mgr, rec.ID)
+	if final.State != job.StateFailed || final.FailureKind != "syntax" || !strings.Contains(final.FailureSummary, "hdl/top.v:7") {
+		t.Fatalf("expected the yosys builder and parser, got state=%s kind=%q summary=%q", final.State, final.FailureKind, final.FailureSummary)
+	}
+}
+
+func TestSubscribeAllEvents_SnapshotsActiveJobsAndResumesAcrossJobs(t *testing.T) {
+	cfg := testConfig(t)
+	mgr := New(cfg, store.New(cfg), &builder.FakeBuilder{})
+	now := time.Now()
+	running := job.New("job1", manifest.Manifest{Project: "a", Top: "top", Part: "part", Sources: []string{"hdl/spade.sv"}}, now)
+	done := job.New("job2", manifest.Manifest{Project: "b", Top: "top", Part: "part", Sources: []string{"hdl/spade.sv"}}, now.Add(time.Second))
+
+	mgr.mu.Lock()
+	mgr.jobs[running.ID] = running
+	mgr.jobs[done.ID] = done
+	mgr.emitEventLocked(running, "queued")
+	mgr.emitEventLocked(done, "queued")
+	if err := running.Transition(job.StateRunning, now, "running"); err != nil {
+		t.Fatal(err)
+	}
+	mgr.emitEventLocked(running, "running")
+	if err := done.Transition(job.StateCanceled, now, "canceled"); err != nil {
+		t.Fatal(err)
+	}
+	mgr.emitEventLocked(done, "canceled")
+	mgr.mu.Unlock()
+
+	backlog, _, release := mgr.SubscribeAllEvents(0)
+	release()
+	if len(backlog) != 1 || backlog[0].Type != job.EventSnapshot || backlog[0].JobID != "job1" || backlog[0].Seq != 4 {
+		t.Fatalf("since=0 backlog = %+v, want one snapshot of the running job at seq 4", backlog)
+	}
+
+	backlog, ch, release := mgr.SubscribeAllEvents(2)
+	defer release()
+	if len(backlog) != 2 || backlog[0].Seq != 3 || backlog[0].JobID != "job1" || backlog[1].Seq != 4 || backlog[1].JobID != "job2" {
+		t.Fatalf("since=2 backlog = %+v, want server-wide seqs 3 and 4", backlog)
+	}
+
+	mgr.mu.Lock()
+	running.CurrentStep = "synth"
+	mgr.emitEventLocked(running, "progress")
+	mgr.mu.Unlock()
+	select {
+	case ev := <-ch:
+		if ev.Seq != 5 || ev.JobID != "job1" || ev.Step != "synth" {
+			t.Fatalf("live event = %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no live event")
+	}
+	if stats := mgr.EventStats(); stats.Subscribers != 1 {
+		t.Fatalf("subscribers = %d, want the global stream counted", stats.Subscribers)
+	}
+}
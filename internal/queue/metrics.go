@@ -0,0 +1,84 @@
+package queue
+
+import (
+	"io"
+
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/metrics"
+)
+
+// buildDurationBuckets span a quick synthesis run to a long implementation.
+var buildDurationBuckets = []float64{30, 60, 120, 300, 600, 1200, 1800, 3600, 7200, 14400}
+
+// managerMetrics are the series a Manager exports on /metrics.
+type managerMetrics struct {
+	registry      *metrics.Registry
+	submitted     *metrics.Counter
+	finished      *metrics.Counter
+	uploadBytes   *metrics.Counter
+	buildDuration *metrics.Histogram
+	orphans       *metrics.Counter
+}
+
+func newManagerMetrics(m *Manager) *managerMetrics {
+	r := metrics.NewRegistry()
+	mm := &managerMetrics{
+		registry:      r,
+		submitted:     r.Counter("spadeforge_jobs_submitted_total", "Bundles accepted as jobs, including build cache hits."),
+		finished:      r.Counter("spadeforge_jobs_finished_total", "Jobs that reached a terminal state, by state.", "state"),
+		uploadBytes:   r.Counter("spadeforge_upload_bytes_total", "Bundle bytes received by submits, accepted or not."),
+		buildDuration: r.Histogram("spadeforge_build_duration_seconds", "Builder run time per attempt, excluding preflight failures.", buildDurationBuckets),
+		orphans:       r.Counter("spadeforge_orphan_dirs_total", "Work and artifact dirs without job state cleaned up by the reaper, by action.", "action"),
+	}
+	r.GaugeFunc("spadeforge_queue_depth", "Jobs waiting for the builder.", func() float64 {
+		return float64(m.countState(job.StateQueued))
+	})
+	r.GaugeFunc("spadeforge_jobs_running", "Jobs being built.", func() float64 {
+		return float64(m.countState(job.StateRunning))
+	})
+	r.GaugeFunc("spadeforge_event_subscribers", "Open job and global event streams (SSE and WebSocket).", func() float64 {
+		return float64(m.EventStats().Subscribers)
+	})
+	r.CounterFunc("spadeforge_events_dropped_total", "Events dropped for slow subscribers.", func() float64 {
+		return float64(m.EventStats().DroppedEvents)
+	})
+	return mm
+}
+
+// Metrics returns the registry served on /metrics.
+func (m *Manager) Metrics() *metrics.Registry {
+	return m.metrics.registry
+}
+
+// observeEventLocked counts terminal transitions as they are emitted, so
+// jobs finished by cancel or the build cache are counted with built ones.
+func (m *Manager) observeEventLocked(rec *job.Record, eventType string) {
+	switch eventType {
+	case "succeeded", "failed", "canceled":
+		m.metrics.finished.Inc(string(rec.State))
+	}
+}
+
+func (m *Manager) countState(state job.State) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	n := 0
+	for _, rec := range m.jobs {
+		if rec.State == state {
+			n++
+		}
+	}
+	return n
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,231 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/checksum"
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/manifest"
+	"github.com/mblsha/spadeforge/internal/pathglob"
+)
+
+const (
+	mirrorIndexName = "index.json"
+	mirrorBuildName = "build.json"
+)
+
+// mirrorBuild describes one mirrored job. File paths are relative to the
+// mirror root so static clients can fetch them directly.
+type mirrorBuild struct {
+	JobID      string            `json:"job_id"`
+	Project    string            `json:"project"`
+	Top        string            `json:"top"`
+	Part       string            `json:"part"`
+	Git        *manifest.GitInfo `json:"git,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	FinishedAt time.Time         `json:"finished_at"`
+	Files      []artifactFile    `json:"files"`
+}
+
+type mirrorProject struct {
+	Project string        `json:"project"`
+	Latest  mirrorBuild   `json:"latest"`
+	Builds  []mirrorBuild `json:"builds"`
+}
+
+type mirrorIndex struct {
+	Schema      int             `json:"schema"`
+	GeneratedAt time.Time       `json:"generated_at"`
+	Projects    []mirrorProject `json:"projects"`
+}
+
+// mirrorArtifacts copies the succeeded job's artifacts matching
+// MirrorInclude to <mirror>/<project>/<job_id>/, prunes the project down to
+// MirrorKeep builds and rewrites index.json. Files are staged and renamed
+// into place so a static server never exposes a partial build.
+func (m *Manager) mirrorArtifacts(rec *job.Record) error {
+	m.mirrorMu.Lock()
+	defer m.mirrorMu.Unlock()
+
+	root := m.cfg.MirrorDir
+	projectDir := filepath.Join(root, mirrorDirName(rec.Manifest.Project))
+	if err := os.MkdirAll(projectDir, 0o755); err != nil {
+		return err
+	}
+	staging, err := os.MkdirTemp(projectDir, ".staging-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	artDir := m.store.ArtifactsJobDir(rec.ID)
+	files, err := m.collectArtifactFiles(artDir)
+	if err != nil {
+		return err
+	}
+	// artifact_manifest.json is skipped by collectArtifactFiles; mirror it
+	// too when it matches.
+	files = append(files, artifactFile{Path: artifactManifestName})
+	urlPrefix := path.Join(mirrorDirName(rec.Manifest.Project), rec.ID)
+	build := mirrorBuild{
+		JobID:     rec.ID,
+		Project:   rec.Manifest.Project,
+		Top:       rec.Manifest.Top,
+		Part:      rec.Manifest.Part,
+		Git:       rec.Manifest.Git,
+		CreatedAt: rec.CreatedAt,
+		Files:     []artifactFile{},
+	}
+	if rec.FinishedAt != nil {
+		build.FinishedAt = *rec.FinishedAt
+	}
+	for _, f := range files {
+		if !pathglob.MatchAny(m.cfg.MirrorInclude, f.Path) {
+			continue
+		}
+		src := filepath.Join(artDir, filepath.FromSlash(f.Path))
+		if err := m.copyPlainFile(src, filepath.Join(staging, filepath.FromSlash(f.Path))); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		if f.SHA256 == "" {
+			fi, err := os.Stat(src)
+			if err != nil {
+				return err
+			}
+			sum, err := checksum.File(src)
+			if err != nil {
+				return err
+			}
+			f.Size, f.SHA256 = fi.Size(), sum
+		}
+		f.Path = path.Join(urlPrefix, f.Path)
+		build.Files = append(build.Files, f)
+	}
+	if err := writeJSONFile(filepath.Join(staging, mirrorBuildName), build); err != nil {
+		return err
+	}
+
+	jobDir := filepath.Join(projectDir, rec.ID)
+	if err := os.RemoveAll(jobDir); err != nil {
+		return err
+	}
+	if err := os.Rename(staging, jobDir); err != nil {
+		return err
+	}
+	if err := m.pruneMirrorProject(projectDir); err != nil {
+		return err
+	}
+	return m.writeMirrorIndex()
+}
+
+// pruneMirrorProject keeps the newest MirrorKeep builds of a project.
+func (m *Manager) pruneMirrorProject(projectDir string) error {
+	builds, err := readMirrorBuilds(projectDir)
+	if err != nil {
+		return err
+	}
+	for _, b := range builds[min(len(builds), m.cfg.MirrorKeep):] {
+		if err := os.RemoveAll(filepath.Join(projectDir, b.JobID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Manager) writeMirrorIndex() error {
+	root := m.cfg.MirrorDir
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return err
+	}
+	index := mirrorIndex{Schema: 1, GeneratedAt: time.Now().UTC(), Projects: []mirrorProject{}}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		builds, err := readMirrorBuilds(filepath.Join(root, entry.Name()))
+		if err != nil {
+			return err
+		}
+		if len(builds) == 0 {
+			continue
+		}
+		index.Projects = append(index.Projects, mirrorProject{Project: builds[0].Project, Latest: builds[0], Builds: builds})
+	}
+	sort.Slice(index.Projects, func(i, j int) bool {
+		return index.Projects[i].Project < index.Projects[j].Project
+	})
+	return writeJSONFile(filepath.Join(root, mirrorIndexName), index)
+}
+
+// readMirrorBuilds loads every build.json under projectDir, newest first.
+func readMirrorBuilds(projectDir string) ([]mirrorBuild, error) {
+	entries, err := os.ReadDir(projectDir)
+	if err != nil {
+		return nil, err
+	}
+	builds := make([]mirrorBuild, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		raw, err := os.ReadFile(filepath.Join(projectDir, entry.Name(), mirrorBuildName))
+		if err != nil {
+			continue
+		}
+		var b mirrorBuild
+		if err := json.Unmarshal(raw, &b); err != nil {
+			continue
+		}
+		builds = append(builds, b)
+	}
+	sort.Slice(builds, func(i, j int) bool {
+		if !builds[i].FinishedAt.Equal(builds[j].FinishedAt) {
+			return builds[i].FinishedAt.After(builds[j].FinishedAt)
+		}
+		return builds[i].JobID > builds[j].JobID
+	})
+	return builds, nil
+}
+
+// writeJSONFile writes v atomically via a temp file and rename.
+func writeJSONFile(name string, v any) error {
+	raw, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := name + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("replace %s: %w", filepath.Base(name), err)
+	}
+	return nil
+}
+
+// mirrorDirName turns a project name into a single path component.
+func mirrorDirName(project string) string {
+	name := strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':':
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(project))
+	if name == "" || strings.HasPrefix(name, ".") {
+		name = "_" + name
+	}
+	return name
+}
@@ -0,0 +1,101 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/store"
+)
+
+func TestMirror_PublishesSucceededBuildsWithIndex(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.MirrorDir = filepath.Join(t.TempDir(), "mirror")
+	cfg.MirrorKeep = 1
+	st := store.New(cfg)
+	mgr := New(cfg, st, &builder.FakeBuilder{
+		FailProjects: map[string]error{"broken": errors.New("synth failed")},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	build := func(project string) *job.Record {
+		t.Helper()
+		rec, err := mgr.Submit(context.Background(), bytes.NewReader(validBundleBytes(t, project)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return waitForTerminalState(t, mgr, rec.ID)
+	}
+
+	first := build("blinky")
+	waitForMirrorLatest(t, cfg.MirrorDir, "blinky", first.ID)
+	if failed := build("broken"); failed.State != job.StateFailed {
+		t.Fatalf("expected broken build to fail, got %s", failed.State)
+	}
+	second := build("blinky")
+	index := waitForMirrorLatest(t, cfg.MirrorDir, "blinky", second.ID)
+
+	if len(index.Projects) != 1 {
+		t.Fatalf("expected only the succeeded project in the index, got %+v", index.Projects)
+	}
+	builds := index.Projects[0].Builds
+	if len(builds) != 1 || builds[0].JobID != second.ID {
+		t.Fatalf("expected mirror to keep only the newest build, got %+v", builds)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.MirrorDir, "blinky", first.ID)); !os.IsNotExist(err) {
+		t.Fatalf("expected pruned build dir to be removed, got %v", err)
+	}
+
+	paths := map[string]bool{}
+	for _, f := range builds[0].Files {
+		paths[f.Path] = true
+		if f.SHA256 == "" {
+			t.Fatalf("expected checksum for %s", f.Path)
+		}
+		if _, err := os.Stat(filepath.Join(cfg.MirrorDir, filepath.FromSlash(f.Path))); err != nil {
+			t.Fatalf("expected mirrored file %s: %v", f.Path, err)
+		}
+	}
+	for _, want := range []string{"blinky/" + second.ID + "/design.bit", "blinky/" + second.ID + "/artifact_manifest.json"} {
+		if !paths[want] {
+			t.Fatalf("expected %s in mirrored files, got %+v", want, builds[0].Files)
+		}
+	}
+	if paths["blinky/"+second.ID+"/vivado.log"] {
+		t.Fatalf("expected vivado.log to be excluded by the default include list")
+	}
+}
+
+func waitForMirrorLatest(t *testing.T, root, project, jobID string) mirrorIndex {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		raw, err := os.ReadFile(filepath.Join(root, mirrorIndexName))
+		if err == nil {
+			var index mirrorIndex
+			if err := json.Unmarshal(raw, &index); err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range index.Projects {
+				if p.Project == project && p.Latest.JobID == jobID {
+					return index
+				}
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for mirror index to list %s as latest for %s", jobID, project)
+	return mirrorIndex{}
+}
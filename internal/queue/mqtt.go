@@ -0,0 +1,87 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/mqtt"
+)
+
+// mqttBacklog bounds the state transitions waiting for the broker; when it
+// is full new ones are dropped so a slow or unreachable broker never holds
+// up the queue.
+const mqttBacklog = 64
+
+// mqttNotifier publishes job state transitions (every event but progress)
+// from its own goroutine.
+type mqttNotifier struct {
+	pub    *mqtt.Publisher
+	prefix string
+	events chan job.Event
+}
+
+func newMQTTNotifier(rawURL, prefix string) (*mqttNotifier, error) {
+	host, _ := os.Hostname()
+	clientID := "spadeforge"
+	if host != "" {
+		clientID += "-" + host
+	}
+	pub, err := mqtt.NewPublisher(rawURL, clientID)
+	if err != nil {
+		return nil, err
+	}
+	return &mqttNotifier{pub: pub, prefix: strings.Trim(prefix, "/"), events: make(chan job.Event, mqttBacklog)}, nil
+}
+
+func (n *mqttNotifier) enqueue(ev job.Event) {
+	if ev.Type == "progress" {
+		return
+	}
+	select {
+	case n.events <- ev:
+	default:
+		qlog.Warnf("%s mqtt backlog full, dropping %s event", jobLogPrefix(ev.JobID, ev.Project), ev.Type)
+	}
+}
+
+func (n *mqttNotifier) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-n.events:
+			msgs, err := mqttMessages(n.prefix, ev)
+			if err == nil {
+				err = n.pub.Publish(ctx, msgs...)
+			}
+			if err != nil {
+				qlog.Warnf("%s publish %s event to mqtt %s: %v", jobLogPrefix(ev.JobID, ev.Project), ev.Type, n.pub.Addr(), err)
+			}
+		}
+	}
+}
+
+// mqttMessages renders ev for <prefix>/jobs/<id>/state and, retained so a
+// subscriber sees the latest build as soon as it connects,
+// <prefix>/projects/<project>/state.
+func mqttMessages(prefix string, ev job.Event) ([]mqtt.Message, error) {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return nil, err
+	}
+	return []mqtt.Message{
+		{Topic: prefix + "/jobs/" + topicLevel(ev.JobID) + "/state", Payload: payload},
+		{Topic: prefix + "/projects/" + topicLevel(ev.Project) + "/state", Payload: payload, Retain: true},
+	}, nil
+}
+
+// topicLevel keeps a name to one topic level without wildcards.
+func topicLevel(name string) string {
+	if name == "" {
+		return "_"
+	}
+	return strings.NewReplacer("/", "_", "+", "_", "#", "_").Replace(name)
+}
@@ -0,0 +1,77 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/mqtt/mqtttest"
+	"github.com/mblsha/spadeforge/internal/store"
+)
+
+func TestManager_PublishesStateTransitionsToMQTT(t *testing.T) {
+	broker := mqtttest.NewBroker(t, 0)
+	cfg := testConfig(t)
+	cfg.MQTTURL = "mqtt://" + broker.Addr
+	cfg.MQTTTopicPrefix = "lab/fpga"
+	mgr := New(cfg, store.New(cfg), &builder.FakeBuilder{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := mgr.Submit(context.Background(), bytes.NewReader(validBundleBytes(t, "blinky")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForTerminalState(t, mgr, rec.ID)
+
+	var types []string
+	timeout := time.After(5 * time.Second)
+	for len(types) < 3 {
+		select {
+		case p := <-broker.Packets():
+			if p.Type() != mqtttest.Publish {
+				continue
+			}
+			topic, payload, retain := p.Publication()
+			var ev job.Event
+			if err := json.Unmarshal(payload, &ev); err != nil {
+				t.Fatalf("decode payload: %v", err)
+			}
+			switch topic {
+			case "lab/fpga/jobs/" + rec.ID + "/state":
+				if retain {
+					t.Fatalf("job topic should not be retained")
+				}
+				types = append(types, ev.Type)
+			case "lab/fpga/projects/blinky/state":
+				if !retain {
+					t.Fatalf("project topic should be retained")
+				}
+			default:
+				t.Fatalf("unexpected topic %q", topic)
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for mqtt messages; got %v", types)
+		}
+	}
+	if want := []string{"queued", "running", "succeeded"}; types[0] != want[0] || types[1] != want[1] || types[2] != want[2] {
+		t.Fatalf("published event types = %v, want %v", types, want)
+	}
+}
+
+func TestMQTTMessages_KeepNamesToOneTopicLevel(t *testing.T) {
+	msgs, err := mqttMessages("spadeforge", job.Event{JobID: "j1", Project: "team/blink#y", Type: "failed", State: job.StateFailed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msgs[1].Topic != "spadeforge/projects/team_blink_y/state" {
+		t.Fatalf("project topic = %q", msgs[1].Topic)
+	}
+}
@@ -0,0 +1,124 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/config"
+	"github.com/mblsha/spadeforge/internal/diskspace"
+	"github.com/mblsha/spadeforge/internal/store"
+)
+
+// orphanMinAge spares dirs that were touched recently: a submit creates a
+// job's dirs before its first state file is saved.
+const orphanMinAge = time.Hour
+
+// quarantineDir holds quarantined orphans under each root. The leading dot
+// keeps it from ever looking like a job ID.
+const quarantineDir = ".orphaned"
+
+// OrphanSweep describes one pass over the work and artifact roots for
+// dirs without a job state file.
+type OrphanSweep struct {
+	At     time.Time `json:"at"`
+	Action string    `json:"action"`
+	// Found counts orphaned dirs; Bytes is their size.
+	Found       int   `json:"found"`
+	Bytes       int64 `json:"bytes"`
+	Removed     int   `json:"removed,omitempty"`
+	Quarantined int   `json:"quarantined,omitempty"`
+	// Failed counts orphans that could not be removed or moved.
+	Failed int `json:"failed,omitempty"`
+}
+
+// SweepOrphans finds dirs under the work and artifact roots that belong to
+// no known job and have no state file, left by crashes or by hand, and
+// removes or quarantines them according to OrphanAction.
+func (m *Manager) SweepOrphans(now time.Time) OrphanSweep {
+	result := OrphanSweep{At: now.UTC(), Action: m.cfg.OrphanAction}
+	if m.cfg.OrphanAction == config.OrphanOff {
+		return result
+	}
+	for _, root := range []string{m.cfg.WorkDir(), m.cfg.ArtifactsDir()} {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				qlog.Warnf("[queue] orphan sweep: %v", err)
+			}
+			continue
+		}
+		for _, entry := range entries {
+			id := entry.Name()
+			if !entry.IsDir() || strings.HasPrefix(id, ".") || !m.orphaned(id) {
+				continue
+			}
+			dir := filepath.Join(root, id)
+			info, err := entry.Info()
+			if err != nil || now.Sub(info.ModTime()) < orphanMinAge {
+				continue
+			}
+			size := store.DirBytes(dir)
+			result.Found++
+			result.Bytes += size
+			if err := m.disposeOrphan(root, id, now); err != nil {
+				qlog.Warnf("[queue] orphan sweep: %v", err)
+				result.Failed++
+				continue
+			}
+			if m.cfg.OrphanAction == config.OrphanRemove {
+				result.Removed++
+				m.metrics.orphans.Inc("removed")
+			} else {
+				result.Quarantined++
+				m.metrics.orphans.Inc("quarantined")
+			}
+		}
+	}
+	if result.Found > 0 {
+		qlog.Warnf("[queue] orphan sweep found %d dir(s) without job state (%s): removed %d, quarantined %d, failed %d",
+			result.Found, diskspace.Format(uint64(result.Bytes)), result.Removed, result.Quarantined, result.Failed)
+	}
+
+	m.mu.Lock()
+	m.lastOrphanSweep = &result
+	m.mu.Unlock()
+	return result
+}
+
+// orphaned reports whether id has neither a job in memory nor a state file.
+func (m *Manager) orphaned(id string) bool {
+	m.mu.RLock()
+	_, known := m.jobs[id]
+	m.mu.RUnlock()
+	if known {
+		return false
+	}
+	_, err := os.Stat(m.store.StatePath(id))
+	return errors.Is(err, os.ErrNotExist)
+}
+
+func (m *Manager) disposeOrphan(root, id string, now time.Time) error {
+	dir := filepath.Join(root, id)
+	if m.cfg.OrphanAction == config.OrphanRemove {
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("remove %s: %w", dir, err)
+		}
+		return nil
+	}
+	qdir := filepath.Join(root, quarantineDir)
+	if err := os.MkdirAll(qdir, 0o755); err != nil {
+		return fmt.Errorf("create quarantine dir: %w", err)
+	}
+	dst := filepath.Join(qdir, id)
+	if _, err := os.Lstat(dst); err == nil {
+		dst += "-" + now.UTC().Format("20060102T150405Z")
+	}
+	if err := os.Rename(dir, dst); err != nil {
+		return fmt.Errorf("quarantine %s: %w", dir, err)
+	}
+	return nil
+}
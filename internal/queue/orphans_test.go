@@ -0,0 +1,70 @@
+package queue
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/config"
+)
+
+func TestSweepOrphans_QuarantinesOrRemovesDirsWithoutState(t *testing.T) {
+	mgr, ids := startRetentionManager(t, func(cfg *config.Config) {
+		cfg.PreserveWorkDir = true
+	})
+	orphan := func(root, id string) string {
+		dir := filepath.Join(root, id)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "design.bit"), []byte("left behind"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	}
+	workOrphan := orphan(mgr.cfg.WorkDir(), "deadbeef")
+	artOrphan := orphan(mgr.cfg.ArtifactsDir(), "deadbeef")
+
+	if res := mgr.SweepOrphans(time.Now()); res.Found != 0 {
+		t.Fatalf("fresh dirs should be spared, got %+v", res)
+	}
+	later := time.Now().Add(2 * orphanMinAge)
+	res := mgr.SweepOrphans(later)
+	if res.Found != 2 || res.Quarantined != 2 || res.Bytes != 2*int64(len("left behind")) {
+		t.Fatalf("unexpected sweep: %+v", res)
+	}
+	for _, dir := range []string{workOrphan, artOrphan} {
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			t.Fatalf("expected %s moved away, got %v", dir, err)
+		}
+		if _, err := os.Stat(filepath.Join(filepath.Dir(dir), quarantineDir, "deadbeef", "design.bit")); err != nil {
+			t.Fatalf("quarantined copy of %s: %v", dir, err)
+		}
+	}
+	for _, id := range ids {
+		for _, dir := range []string{mgr.store.WorkJobDir(id), mgr.store.ArtifactsJobDir(id)} {
+			if _, err := os.Stat(dir); err != nil {
+				t.Fatalf("known job dir %s: %v", dir, err)
+			}
+		}
+	}
+	if usage := mgr.StorageUsage(); usage.LastOrphanSweep == nil || usage.LastOrphanSweep.Quarantined != 2 {
+		t.Fatalf("storage usage sweep = %+v", usage.LastOrphanSweep)
+	}
+
+	mgr.cfg.OrphanAction = config.OrphanRemove
+	artOrphan = orphan(mgr.cfg.ArtifactsDir(), "deadbeef")
+	if res := mgr.SweepOrphans(later); res.Removed != 1 || res.Found != 1 {
+		t.Fatalf("unexpected remove sweep: %+v", res)
+	}
+	if _, err := os.Stat(artOrphan); !os.IsNotExist(err) {
+		t.Fatalf("expected %s removed, got %v", artOrphan, err)
+	}
+
+	mgr.cfg.OrphanAction = config.OrphanOff
+	orphan(mgr.cfg.WorkDir(), "cafe")
+	if res := mgr.SweepOrphans(later); res.Found != 0 {
+		t.Fatalf("sweep with action off = %+v", res)
+	}
+}
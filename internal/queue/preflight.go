@@ -0,0 +1,63 @@
+package queue
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/manifest"
+)
+
+// maxTopCandidates bounds the module names suggested for a missing top.
+const maxTopCandidates = 5
+
+// preflightFailure is a problem found before the builder runs. It carries
+// its own classification so process can skip log-based inference.
+type preflightFailure struct {
+	Kind       string
+	Summary    string
+	Diagnostic job.Diagnostic
+}
+
+func (f *preflightFailure) Error() string {
+	return f.Summary
+}
+
+// preflight runs cheap checks on the bundled sources so that the most common
+// mistakes fail in milliseconds instead of after a full Vivado launch. Only
+// a top that is definitely absent is reported: if the scan finds no
+// declarations at all (unsupported syntax, generated sources) Vivado decides.
+func (m *Manager) preflight(rec *job.Record) *preflightFailure {
+	top := rec.Manifest.Top
+	declared := manifest.DeclaredModules(m.store.SourceDir(rec.ID), rec.Manifest.Sources)
+	if len(declared) == 0 || slices.Contains(declared, top) {
+		return nil
+	}
+	candidates := manifest.RankCandidates(top, declared, maxTopCandidates)
+	msg := fmt.Sprintf("top module %q is not declared in any source; candidates: %s", top, manifest.FormatCandidates(candidates))
+	return &preflightFailure{
+		Kind:    "syntax",
+		Summary: "[spadeforge missing-top] " + msg,
+		Diagnostic: job.Diagnostic{
+			Severity: job.SeverityError,
+			Tool:     "spadeforge",
+			Code:     "missing-top",
+			Message:  msg,
+			File:     "manifest.json",
+			Source:   "/top",
+		},
+	}
+}
+
+// writePreflightLog records a preflight failure in console.log so the usual
+// log and tail endpoints explain why the build never started.
+func (m *Manager) writePreflightLog(jobID string, f *preflightFailure) builder.BuildResult {
+	artDir := m.store.ArtifactsJobDir(jobID)
+	_ = os.MkdirAll(artDir, 0o755)
+	line := fmt.Sprintf("spadeforge preflight: %s\n", f.Diagnostic.Message)
+	_ = os.WriteFile(filepath.Join(artDir, "console.log"), []byte(line), 0o644)
+	return builder.BuildResult{ExitCode: 1, Message: "preflight failed: " + f.Diagnostic.Message}
+}
@@ -0,0 +1,162 @@
+package queue
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+const (
+	DefaultProjectSummaryBuilds = 10
+	MaxProjectSummaryBuilds     = 100
+)
+
+// ProjectDiagnosticsSummary groups the ERROR and WARNING diagnostics of the
+// project's last limit finished builds and reports, per build, which groups
+// are new or resolved relative to the build before it. It returns
+// os.ErrNotExist when the project has no finished builds.
+func (m *Manager) ProjectDiagnosticsSummary(project string, limit int) (*job.ProjectDiagnosticsSummary, error) {
+	if limit <= 0 {
+		limit = DefaultProjectSummaryBuilds
+	}
+	if limit > MaxProjectSummaryBuilds {
+		limit = MaxProjectSummaryBuilds
+	}
+
+	recs := m.finishedProjectJobs(project)
+	if len(recs) == 0 {
+		return nil, os.ErrNotExist
+	}
+	if len(recs) > limit {
+		recs = recs[len(recs)-limit:]
+	}
+
+	summary := &job.ProjectDiagnosticsSummary{
+		Project: project,
+		Builds:  make([]job.BuildDiagnosticsTrend, 0, len(recs)),
+		Groups:  []job.DiagnosticGroup{},
+	}
+	groups := map[string]*job.DiagnosticGroup{}
+	var prev map[string]bool
+	for _, rec := range recs {
+		raw, err := m.ReadDiagnostics(rec.ID)
+		if err != nil {
+			continue
+		}
+		var report job.DiagnosticsReport
+		if err := json.Unmarshal(raw, &report); err != nil {
+			continue
+		}
+
+		trend := job.BuildDiagnosticsTrend{JobID: rec.ID, State: rec.State, CreatedAt: rec.CreatedAt}
+		if rec.Manifest.Git != nil {
+			trend.Commit = rec.Manifest.Git.Short()
+		}
+		present := map[string]bool{}
+		for _, d := range report.Diagnostics {
+			if d.Suppressed {
+				continue
+			}
+			switch d.Severity {
+			case job.SeverityError:
+				trend.ErrorCount++
+			case job.SeverityWarning:
+				trend.WarningCount++
+			default:
+				continue
+			}
+			id := diagnosticGroupID(d)
+			g, ok := groups[id]
+			if !ok {
+				g = &job.DiagnosticGroup{
+					ID:         id,
+					Severity:   d.Severity,
+					Code:       d.Code,
+					Message:    d.Message,
+					File:       d.File,
+					FirstJobID: rec.ID,
+				}
+				groups[id] = g
+			}
+			g.Occurrences++
+			if !present[id] {
+				present[id] = true
+				g.Builds++
+				g.LastJobID = rec.ID
+			}
+		}
+		if prev != nil {
+			for id := range present {
+				if !prev[id] {
+					trend.New = append(trend.New, id)
+				}
+			}
+			for id := range prev {
+				if !present[id] {
+					trend.Resolved = append(trend.Resolved, id)
+				}
+			}
+			sort.Strings(trend.New)
+			sort.Strings(trend.Resolved)
+		}
+		summary.Builds = append(summary.Builds, trend)
+		prev = present
+	}
+
+	for id, g := range groups {
+		g.InLatest = prev[id]
+		summary.Groups = append(summary.Groups, *g)
+	}
+	sort.Slice(summary.Groups, func(i, j int) bool {
+		a, b := summary.Groups[i], summary.Groups[j]
+		if a.InLatest != b.InLatest {
+			return a.InLatest
+		}
+		if a.Severity != b.Severity {
+			return a.Severity == job.SeverityError
+		}
+		if a.Builds != b.Builds {
+			return a.Builds > b.Builds
+		}
+		if a.Occurrences != b.Occurrences {
+			return a.Occurrences > b.Occurrences
+		}
+		return a.ID < b.ID
+	})
+	return summary, nil
+}
+
+// finishedProjectJobs returns the project's terminal jobs, oldest first.
+func (m *Manager) finishedProjectJobs(project string) []*job.Record {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []*job.Record
+	for _, rec := range m.jobs {
+		// Canceled builds stopped part-way and would report diagnostics
+		// as resolved that simply never ran.
+		if rec.Manifest.Project == project && rec.Terminal() && rec.State != job.StateCanceled {
+			copyRec := *rec
+			out = append(out, &copyRec)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// diagnosticGroupID is a short stable hash of the fields that identify a
+// recurring diagnostic.
+func diagnosticGroupID(d job.Diagnostic) string {
+	key := strings.Join([]string{string(d.Severity), d.Code, d.Message, d.File}, "\x00")
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:6])
+}
@@ -0,0 +1,77 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/store"
+)
+
+func TestProjectDiagnosticsSummary_TracksNewAndResolved(t *testing.T) {
+	cfg := testConfig(t)
+	st := store.New(cfg)
+	fb := &builder.FakeBuilder{}
+	mgr := New(cfg, st, fb)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	const (
+		unconnected = "WARNING: [Synth 8-3331] design top has unconnected port dbg [hdl/spade.sv:3]\n"
+		unused      = "WARNING: [Synth 8-7129] Port led[3] in module top is either unconnected or has no load [hdl/spade.sv:9]\n"
+		moved       = "WARNING: [Synth 8-3331] design top has unconnected port dbg [hdl/spade.sv:7]\n"
+	)
+	var ids []string
+	for _, vivadoLog := range []string{unconnected, unconnected + unused, moved} {
+		fb.VivadoLog = vivadoLog
+		rec, err := mgr.Submit(context.Background(), bytes.NewReader(validBundleBytes(t, "health")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		waitForTerminalState(t, mgr, rec.ID)
+		ids = append(ids, rec.ID)
+	}
+
+	summary, err := mgr.ProjectDiagnosticsSummary("health", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Builds) != 3 || summary.Builds[0].JobID != ids[0] {
+		t.Fatalf("unexpected builds: %+v", summary.Builds)
+	}
+	if len(summary.Groups) != 2 {
+		t.Fatalf("expected 2 groups (line shifts merged), got %+v", summary.Groups)
+	}
+	recurring, transient := summary.Groups[0], summary.Groups[1]
+	if !recurring.InLatest || recurring.Builds != 3 || recurring.Code != "Synth 8-3331" {
+		t.Fatalf("unexpected recurring group: %+v", recurring)
+	}
+	if transient.InLatest || transient.Builds != 1 || transient.FirstJobID != ids[1] {
+		t.Fatalf("unexpected transient group: %+v", transient)
+	}
+	if b := summary.Builds[1]; len(b.New) != 1 || b.New[0] != transient.ID || len(b.Resolved) != 0 {
+		t.Fatalf("expected build 2 to introduce the unused-port warning, got %+v", b)
+	}
+	if b := summary.Builds[2]; len(b.Resolved) != 1 || b.Resolved[0] != transient.ID || len(b.New) != 0 {
+		t.Fatalf("expected build 3 to resolve the unused-port warning, got %+v", b)
+	}
+
+	limited, err := mgr.ProjectDiagnosticsSummary("health", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(limited.Builds) != 2 || limited.Builds[0].JobID != ids[1] || limited.Builds[0].New != nil {
+		t.Fatalf("expected window to start at build 2 without trend, got %+v", limited.Builds)
+	}
+
+	if _, err := mgr.ProjectDiagnosticsSummary("nope", 0); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected not-exist for unknown project, got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package queue
+
+import "time"
+
+// storageUsageTTL is how long ClientUsage reuses a finished job's measured
+// size when the retention interval is unset.
+const storageUsageTTL = time.Minute
+
+// ClientUsage is what one client's jobs hold, for per-client quotas.
+type ClientUsage struct {
+	ActiveJobs   int
+	StorageBytes int64
+}
+
+// measuredStorage is a finished job's size on disk as of at.
+type measuredStorage struct {
+	bytes    int64
+	finished time.Time
+	at       time.Time
+}
+
+// ClientUsage counts the queued and running jobs of one client: those
+// submitted with the named token tokenName or, when tokenName is empty,
+// those submitted from ip without a named token. With storage set it also
+// measures the space all of the client's retained jobs take on disk.
+// Measuring walks the job dirs, so the size of a finished job is reused
+// for a retention interval; queued and running jobs are measured each time.
+func (m *Manager) ClientUsage(tokenName, ip string, storage bool) ClientUsage {
+	var usage ClientUsage
+	finishedAt := map[string]time.Time{}
+	m.mu.RLock()
+	for id, rec := range m.jobs {
+		if rec.TokenName != tokenName || (tokenName == "" && rec.ClientIP != ip) {
+			continue
+		}
+		var at time.Time
+		if !rec.State.Terminal() {
+			usage.ActiveJobs++
+		} else if rec.FinishedAt != nil {
+			at = *rec.FinishedAt
+		}
+		finishedAt[id] = at
+	}
+	m.mu.RUnlock()
+
+	if !storage {
+		return usage
+	}
+	ttl := m.cfg.RetentionInterval
+	if ttl <= 0 {
+		ttl = storageUsageTTL
+	}
+	now := time.Now()
+	for id, at := range finishedAt {
+		m.storageMu.Lock()
+		cached, ok := m.storageUsage[id]
+		m.storageMu.Unlock()
+		if ok && !at.IsZero() && cached.finished.Equal(at) && now.Sub(cached.at) < ttl {
+			usage.StorageBytes += cached.bytes
+			continue
+		}
+		bytes := m.store.JobUsage(id).Total()
+		usage.StorageBytes += bytes
+		if !at.IsZero() {
+			m.storageMu.Lock()
+			m.storageUsage[id] = measuredStorage{bytes: bytes, finished: at, at: now}
+			m.storageMu.Unlock()
+		}
+	}
+	return usage
+}
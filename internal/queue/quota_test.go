@@ -0,0 +1,42 @@
+package queue
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/store"
+)
+
+func TestClientUsage_ReusesFinishedJobSizeForRetentionInterval(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.RetentionInterval = time.Hour
+	st := store.New(cfg)
+	mgr := New(cfg, st, &builder.FakeBuilder{})
+	finished := time.Now()
+	mgr.jobs["job1"] = &job.Record{ID: "job1", State: job.StateSucceeded, TokenName: "ci", FinishedAt: &finished}
+	artDir := st.ArtifactsJobDir("job1")
+	if err := os.MkdirAll(artDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(artDir, "a.rpt"), make([]byte, 100), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := mgr.ClientUsage("ci", "", true).StorageBytes; got != 100 {
+		t.Fatalf("StorageBytes = %d, want 100", got)
+	}
+	if err := os.WriteFile(filepath.Join(artDir, "b.rpt"), make([]byte, 50), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := mgr.ClientUsage("ci", "", true).StorageBytes; got != 100 {
+		t.Fatalf("StorageBytes within the interval = %d, want the cached 100", got)
+	}
+	mgr.storageUsage["job1"] = measuredStorage{bytes: 100, finished: finished, at: time.Now().Add(-2 * time.Hour)}
+	if got := mgr.ClientUsage("ci", "", true).StorageBytes; got != 150 {
+		t.Fatalf("StorageBytes after the interval = %d, want 150", got)
+	}
+}
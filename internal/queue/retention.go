@@ -0,0 +1,264 @@
+package queue
+
+import (
+	"context"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/diskspace"
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/store"
+)
+
+// expiryWebhookTimeout bounds each expiry notification so a slow receiver
+// cannot stall the reaper.
+const expiryWebhookTimeout = 10 * time.Second
+
+// ReapResult describes one retention pass. ExpiringJobs counts the jobs
+// newly marked as expiring, which are removed by a later pass once their
+// grace period ends.
+type ReapResult struct {
+	At           time.Time `json:"at"`
+	RemovedJobs  int       `json:"removed_jobs"`
+	FreedBytes   int64     `json:"freed_bytes"`
+	ExpiringJobs int       `json:"expiring_jobs,omitempty"`
+}
+
+// StorageUsage is returned by GET /v1/storage.
+type StorageUsage struct {
+	Jobs int `json:"jobs"`
+	store.Usage
+	TotalBytes int64 `json:"total_bytes"`
+
+	RetentionDays     int          `json:"retention_days"`
+	RetentionMaxBytes int64        `json:"retention_max_bytes,omitempty"`
+	LastReap          *ReapResult  `json:"last_reap,omitempty"`
+	LastOrphanSweep   *OrphanSweep `json:"last_orphan_sweep,omitempty"`
+}
+
+// StorageUsage measures the space every known job takes on disk.
+func (m *Manager) StorageUsage() StorageUsage {
+	m.mu.RLock()
+	ids := make([]string, 0, len(m.jobs))
+	for id := range m.jobs {
+		ids = append(ids, id)
+	}
+	usage := StorageUsage{
+		Jobs:              len(ids),
+		RetentionDays:     m.cfg.RetentionDays,
+		RetentionMaxBytes: m.cfg.RetentionMaxBytes,
+		LastReap:          m.lastReap,
+		LastOrphanSweep:   m.lastOrphanSweep,
+	}
+	m.mu.RUnlock()
+
+	for _, id := range ids {
+		u := m.store.JobUsage(id)
+		usage.JobsBytes += u.JobsBytes
+		usage.WorkBytes += u.WorkBytes
+		usage.ArtifactsBytes += u.ArtifactsBytes
+	}
+	usage.TotalBytes = usage.Total()
+	return usage
+}
+
+// reapLoop prunes expired jobs and sweeps orphaned dirs at startup and
+// every RetentionInterval.
+func (m *Manager) reapLoop(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.RetentionInterval)
+	defer ticker.Stop()
+	for {
+		m.Reap(time.Now())
+		m.SweepOrphans(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reap removes finished jobs, with their request zips, work dirs and
+// artifacts, that finished more than RetentionDays before now. With
+// RetentionMaxBytes set it then removes the oldest remaining finished jobs
+// until stored jobs fit. Queued and running jobs, project baselines and
+// pinned jobs are never removed.
+//
+// With RetentionGrace set, a selected job is first marked as expiring: it
+// gets an expires_at, an "expiring" event and the expiry webhook, and is
+// only removed by a pass after that time. Pinning it in the meantime keeps
+// it; a job that is no longer selected has its expires_at cleared.
+func (m *Manager) Reap(now time.Time) ReapResult {
+	type candidate struct {
+		id       string
+		finished time.Time
+	}
+	m.mu.RLock()
+	ids := make([]string, 0, len(m.jobs))
+	var candidates []candidate
+	for id, rec := range m.jobs {
+		ids = append(ids, id)
+		if reapable(rec) {
+			candidates = append(candidates, candidate{id: id, finished: *rec.FinishedAt})
+		}
+	}
+	m.mu.RUnlock()
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].finished.Before(candidates[j].finished)
+	})
+
+	sizes := make(map[string]int64, len(ids))
+	var total int64
+	if m.cfg.RetentionMaxBytes > 0 {
+		for _, id := range ids {
+			sizes[id] = m.store.JobUsage(id).Total()
+			total += sizes[id]
+		}
+	}
+	cutoff := now.Add(-time.Duration(m.cfg.RetentionDays) * 24 * time.Hour)
+	selected := map[string]bool{}
+	var doomed []string
+	for _, c := range candidates {
+		expired := m.cfg.RetentionDays > 0 && c.finished.Before(cutoff)
+		overBudget := m.cfg.RetentionMaxBytes > 0 && total > m.cfg.RetentionMaxBytes
+		if !expired && !overBudget {
+			break
+		}
+		selected[c.id] = true
+		doomed = append(doomed, c.id)
+		total -= sizes[c.id]
+	}
+
+	result := ReapResult{At: now.UTC()}
+	var expiring []job.Record
+	m.mu.Lock()
+	for _, c := range candidates {
+		if rec, ok := m.jobs[c.id]; ok && !selected[c.id] && rec.ExpiresAt != nil {
+			rec.ExpiresAt = nil
+			_ = m.store.Save(rec)
+		}
+	}
+	if m.cfg.RetentionGrace > 0 {
+		due := doomed[:0]
+		for _, id := range doomed {
+			rec, ok := m.jobs[id]
+			if !ok || !reapable(rec) {
+				continue
+			}
+			if rec.ExpiresAt == nil {
+				expiresAt := now.Add(m.cfg.RetentionGrace).UTC()
+				rec.ExpiresAt = &expiresAt
+				_ = m.store.Save(rec)
+				m.emitEventLocked(rec, "expiring")
+				expiring = append(expiring, *rec)
+				continue
+			}
+			if now.Before(*rec.ExpiresAt) {
+				continue
+			}
+			due = append(due, id)
+		}
+		doomed = due
+	}
+	m.mu.Unlock()
+
+	result.ExpiringJobs = len(expiring)
+	for i := range expiring {
+		rec := &expiring[i]
+		qlog.Infof("%s retention: expiring at %s unless pinned", jobLogPrefix(rec.ID, rec.Manifest.Project), rec.ExpiresAt.Format(time.RFC3339))
+		m.notifyExpiring(rec)
+	}
+
+	for _, id := range doomed {
+		freed := sizes[id]
+		if m.cfg.RetentionMaxBytes <= 0 {
+			freed = m.store.JobUsage(id).Total()
+		}
+		m.mu.Lock()
+		rec, ok := m.jobs[id]
+		if !ok || !reapable(rec) {
+			m.mu.Unlock()
+			continue
+		}
+		m.dropJobLocked(id)
+		m.mu.Unlock()
+		if err := m.store.RemoveJob(id); err != nil {
+			qlog.Warnf("%s retention: %v", jobLogPrefix(id, rec.Manifest.Project), err)
+			continue
+		}
+		result.RemovedJobs++
+		result.FreedBytes += freed
+	}
+	if result.RemovedJobs > 0 {
+		qlog.Infof("[queue] retention removed %d job(s), freed %s", result.RemovedJobs, diskspace.Format(uint64(result.FreedBytes)))
+	}
+
+	m.mu.Lock()
+	m.lastReap = &result
+	m.mu.Unlock()
+	return result
+}
+
+// reapable reports whether retention may select rec.
+func reapable(rec *job.Record) bool {
+	return rec.State.Terminal() && rec.FinishedAt != nil && !rec.ProjectBaseline && !rec.Pinned
+}
+
+// notifyExpiring posts an "expiring" payload to the expiry webhook.
+func (m *Manager) notifyExpiring(rec *job.Record) {
+	if m.cfg.ExpiryWebhook == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), expiryWebhookTimeout)
+	defer cancel()
+	if err := m.postWebhook(ctx, m.cfg.Webhooks[m.cfg.ExpiryWebhook], webhookPayload{Event: "expiring", Job: *rec}); err != nil {
+		qlog.Warnf("%s retention: expiry webhook %s: %v", jobLogPrefix(rec.ID, rec.Manifest.Project), m.cfg.ExpiryWebhook, err)
+	}
+}
+
+// PinJob keeps a job from being removed by retention, cancelling a pending
+// expiry. UnpinJob makes it eligible again.
+func (m *Manager) PinJob(jobID string) (*job.Record, error) {
+	return m.setPinned(jobID, true)
+}
+
+func (m *Manager) UnpinJob(jobID string) (*job.Record, error) {
+	return m.setPinned(jobID, false)
+}
+
+func (m *Manager) setPinned(jobID string, pinned bool) (*job.Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec, ok := m.jobs[jobID]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	rec.Pinned = pinned
+	rec.ExpiresAt = nil
+	rec.UpdatedAt = time.Now().UTC()
+	if err := m.store.Save(rec); err != nil {
+		return nil, err
+	}
+	if pinned {
+		qlog.Infof("%s pinned", jobLogPrefix(rec.ID, rec.Manifest.Project))
+	} else {
+		qlog.Infof("%s unpinned", jobLogPrefix(rec.ID, rec.Manifest.Project))
+	}
+	copyRec := *rec
+	return &copyRec, nil
+}
+
+// dropJobLocked forgets a job and ends its event streams.
+func (m *Manager) dropJobLocked(jobID string) {
+	for ch := range m.subscribers[jobID] {
+		close(ch)
+	}
+	delete(m.subscribers, jobID)
+	delete(m.events, jobID)
+	delete(m.nextEventSeq, jobID)
+	delete(m.jobs, jobID)
+	m.storageMu.Lock()
+	delete(m.storageUsage, jobID)
+	m.storageMu.Unlock()
+}
@@ -0,0 +1,165 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/config"
+	"github.com/mblsha/spadeforge/internal/store"
+)
+
+// startRetentionManager runs three builds to completion and returns their
+// IDs, oldest first.
+func startRetentionManager(t *testing.T, mutate func(*config.Config)) (*Manager, []string) {
+	t.Helper()
+	cfg := testConfig(t)
+	cfg.RetentionInterval = time.Hour
+	mutate(&cfg)
+	mgr := New(cfg, store.New(cfg), &builder.FakeBuilder{})
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for range 3 {
+		rec, err := mgr.Submit(context.Background(), bytes.NewReader(validBundleBytes(t, "blinky")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		waitForTerminalState(t, mgr, rec.ID)
+		ids = append(ids, rec.ID)
+	}
+	return mgr, ids
+}
+
+func TestReap_RemovesExpiredJobsButKeepsBaselines(t *testing.T) {
+	mgr, ids := startRetentionManager(t, func(cfg *config.Config) {
+		cfg.RetentionDays = 14
+		cfg.RetentionGrace = 0
+	})
+	if _, err := mgr.SetBaseline(ids[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	if res := mgr.Reap(time.Now()); res.RemovedJobs != 0 {
+		t.Fatalf("fresh jobs should be kept, got %+v", res)
+	}
+	res := mgr.Reap(time.Now().Add(15 * 24 * time.Hour))
+	if res.RemovedJobs != 2 || res.FreedBytes <= 0 {
+		t.Fatalf("unexpected reap result: %+v", res)
+	}
+	if _, ok := mgr.Get(ids[0]); !ok {
+		t.Fatalf("baseline job %s was removed", ids[0])
+	}
+	for _, id := range ids[1:] {
+		if _, ok := mgr.Get(id); ok {
+			t.Fatalf("expired job %s still known", id)
+		}
+		for _, dir := range []string{mgr.store.JobDir(id), mgr.store.ArtifactsJobDir(id)} {
+			if _, err := os.Stat(dir); !os.IsNotExist(err) {
+				t.Fatalf("expected %s removed, got %v", dir, err)
+			}
+		}
+	}
+
+	usage := mgr.StorageUsage()
+	if usage.Jobs != 1 || usage.TotalBytes <= 0 || usage.LastReap == nil || usage.LastReap.RemovedJobs != 2 {
+		t.Fatalf("unexpected storage usage: %+v", usage)
+	}
+}
+
+func TestReap_PrunesOldestJobsOverByteBudget(t *testing.T) {
+	mgr, ids := startRetentionManager(t, func(cfg *config.Config) {
+		cfg.RetentionDays = 0
+		cfg.RetentionGrace = 0
+	})
+	perJob := mgr.store.JobUsage(ids[2]).Total()
+	mgr.cfg.RetentionMaxBytes = perJob + perJob/2
+
+	if res := mgr.Reap(time.Now().Add(365 * 24 * time.Hour)); res.RemovedJobs != 2 {
+		t.Fatalf("unexpected reap result: %+v", res)
+	}
+	for i, id := range ids {
+		if _, ok := mgr.Get(id); ok != (i == 2) {
+			t.Fatalf("job %d (%s) known=%v after reap", i, id, ok)
+		}
+	}
+}
+
+func TestReap_WarnsThenRemovesAfterGraceUnlessPinned(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		payloads []webhookPayload
+	)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p webhookPayload
+		_ = json.NewDecoder(r.Body).Decode(&p)
+		mu.Lock()
+		payloads = append(payloads, p)
+		mu.Unlock()
+	}))
+	defer hook.Close()
+
+	mgr, ids := startRetentionManager(t, func(cfg *config.Config) {
+		cfg.RetentionDays = 14
+		cfg.RetentionGrace = 24 * time.Hour
+		cfg.Webhooks = map[string]string{"ops": hook.URL}
+		cfg.ExpiryWebhook = "ops"
+	})
+	first := time.Now().Add(15 * 24 * time.Hour)
+	res := mgr.Reap(first)
+	if res.RemovedJobs != 0 || res.ExpiringJobs != 3 {
+		t.Fatalf("first pass should only warn, got %+v", res)
+	}
+	rec, _ := mgr.Get(ids[1])
+	if rec.ExpiresAt == nil || !rec.ExpiresAt.Equal(first.Add(24*time.Hour).UTC()) {
+		t.Fatalf("unexpected expires_at %v", rec.ExpiresAt)
+	}
+	backlog, _, _, _ := mgr.SubscribeEvents(ids[1], 0)
+	if len(backlog) == 0 || backlog[len(backlog)-1].Type != "expiring" {
+		t.Fatalf("expected a trailing expiring event, got %+v", backlog)
+	}
+	mu.Lock()
+	if len(payloads) != 3 || payloads[0].Event != "expiring" || payloads[0].Job.ExpiresAt == nil {
+		t.Fatalf("unexpected webhook payloads: %+v", payloads)
+	}
+	mu.Unlock()
+
+	if res := mgr.Reap(first.Add(time.Hour)); res.RemovedJobs != 0 || res.ExpiringJobs != 0 {
+		t.Fatalf("jobs in grace should be kept without a second warning, got %+v", res)
+	}
+
+	pinned, err := mgr.PinJob(ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pinned.Pinned || pinned.ExpiresAt != nil {
+		t.Fatalf("pin should clear expiry: %+v", pinned)
+	}
+
+	if res := mgr.Reap(first.Add(25 * time.Hour)); res.RemovedJobs != 2 {
+		t.Fatalf("expected the two unpinned jobs removed, got %+v", res)
+	}
+	if _, ok := mgr.Get(ids[0]); !ok {
+		t.Fatalf("pinned job %s was removed", ids[0])
+	}
+
+	if _, err := mgr.UnpinJob(ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	if res := mgr.Reap(first.Add(26 * time.Hour)); res.ExpiringJobs != 1 || res.RemovedJobs != 0 {
+		t.Fatalf("unpinned job should get a fresh grace period, got %+v", res)
+	}
+	if _, err := mgr.PinJob("missing"); !os.IsNotExist(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/manifest"
+	"github.com/mblsha/spadeforge/internal/store"
+)
+
+// flakyBuilder fails its first failures builds without diagnostics, which
+// classify as "internal".
+type flakyBuilder struct {
+	builder.FakeBuilder
+	failures atomic.Int32
+}
+
+func (b *flakyBuilder) Build(ctx context.Context, j builder.BuildJob) (builder.BuildResult, error) {
+	if b.failures.Add(-1) >= 0 {
+		return builder.BuildResult{Message: "vivado crashed", ExitCode: 139}, errors.New("vivado crashed")
+	}
+	return b.FakeBuilder.Build(ctx, j)
+}
+
+func TestRetry_RequeuesTransientFailures(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.MaxRetries = 2
+	b := &flakyBuilder{}
+	b.failures.Store(2)
+	mgr := New(cfg, store.New(cfg), b)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := mgr.Submit(context.Background(), bytes.NewReader(validBundleBytes(t, "flaky")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := waitForTerminalState(t, mgr, rec.ID)
+	if done.State != job.StateSucceeded || done.Attempt != 3 || len(done.Retries) != 2 {
+		t.Fatalf("state %s attempt %d retries %+v", done.State, done.Attempt, done.Retries)
+	}
+	if r := done.Retries[0]; r.Attempt != 1 || r.FailureKind != "internal" || r.FailureSummary != "vivado crashed" {
+		t.Fatalf("first retry = %+v", r)
+	}
+	if done.FailureKind != "" {
+		t.Fatalf("succeeded job kept failure kind %q", done.FailureKind)
+	}
+
+	backlog, _, unsubscribe, _ := mgr.SubscribeEvents(rec.ID, 0)
+	unsubscribe()
+	var retrying []job.Event
+	for _, ev := range backlog {
+		if ev.Type == "retrying" {
+			retrying = append(retrying, ev)
+		}
+	}
+	if len(retrying) != 2 || retrying[1].State != job.StateQueued || retrying[1].Attempt != 2 || retrying[1].FailureKind != "internal" {
+		t.Fatalf("retrying events = %+v", retrying)
+	}
+}
+
+func TestRetry_ManifestPolicyOverridesServer(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.MaxRetries = 5
+	b := &flakyBuilder{}
+	b.failures.Store(10)
+	mgr := New(cfg, store.New(cfg), b)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	submit := func(policy *manifest.RetryPolicy) *job.Record {
+		t.Helper()
+		mf := manifest.Manifest{Schema: 1, Project: "flaky", Top: "top", Part: "xc7a35tcsg324-1", Sources: []string{"hdl/spade.sv"}, Retry: policy}
+		rec, err := mgr.Submit(context.Background(), bytes.NewReader(bundleFromManifest(t, mf, "module top; endmodule\n")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return waitForTerminalState(t, mgr, rec.ID)
+	}
+
+	if done := submit(&manifest.RetryPolicy{MaxRetries: 1}); done.State != job.StateFailed || done.Attempt != 2 || len(done.Retries) != 1 {
+		t.Fatalf("max_retries=1: state %s attempt %d retries %d", done.State, done.Attempt, len(done.Retries))
+	}
+	if done := submit(&manifest.RetryPolicy{MaxRetries: 3, RetryOn: []string{"license"}}); done.State != job.StateFailed || done.Attempt != 1 || done.FailureKind != "internal" {
+		t.Fatalf("retry_on=license: state %s attempt %d kind %q", done.State, done.Attempt, done.FailureKind)
+	}
+}
+
+func TestRetry_ChaosCrashIsRetriedAsInternal(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.MaxRetries = 1
+	mgr := New(cfg, store.New(cfg), &builder.FakeBuilder{CrashRate: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := mgr.Submit(context.Background(), bytes.NewReader(validBundleBytes(t, "crashy")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := waitForTerminalState(t, mgr, rec.ID)
+	if done.State != job.StateFailed || done.Attempt != 2 || done.FailureKind != "internal" {
+		t.Fatalf("state %s attempt %d kind %q, want FAILED after a retry as internal", done.State, done.Attempt, done.FailureKind)
+	}
+	if done.ExitCode == nil || *done.ExitCode != 139 {
+		t.Fatalf("exit code = %v, want 139", done.ExitCode)
+	}
+}
@@ -0,0 +1,105 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/manifest"
+	"github.com/mblsha/spadeforge/internal/store"
+)
+
+func TestSeparateRoots_WorkAndArtifactsLiveOutsideBaseDir(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.WorkRoot = filepath.Join(t.TempDir(), "scratch")
+	cfg.ArtifactsRoot = filepath.Join(t.TempDir(), "bulk")
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	st := store.New(cfg)
+	mgr := New(cfg, st, &builder.FakeBuilder{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := mgr.Submit(context.Background(), bytes.NewReader(validBundleBytes(t, "split")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := st.WorkJobDir(rec.ID); filepath.Dir(got) != cfg.WorkRoot {
+		t.Fatalf("work dir %s not under %s", got, cfg.WorkRoot)
+	}
+	final := waitForTerminalState(t, mgr, rec.ID)
+	if final.State != job.StateSucceeded {
+		t.Fatalf("expected success, got %s: %s", final.State, final.Error)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.ArtifactsRoot, rec.ID, "design.bit")); err != nil {
+		t.Fatalf("expected bitstream in artifacts root: %v", err)
+	}
+	if _, err := os.Stat(st.WorkJobDir(rec.ID)); !os.IsNotExist(err) {
+		t.Fatalf("expected work dir removed after build, got %v", err)
+	}
+	for _, dir := range []string{"work", "artifacts"} {
+		if _, err := os.Stat(filepath.Join(cfg.BaseDir, dir)); !os.IsNotExist(err) {
+			t.Fatalf("expected no %s dir under base dir, got %v", dir, err)
+		}
+	}
+
+	if _, err := mgr.Submit(context.Background(), bytes.NewReader([]byte("not a zip"))); err == nil {
+		t.Fatalf("expected invalid bundle to be rejected")
+	}
+	for _, root := range []string{cfg.JobsDir(), cfg.WorkRoot, cfg.ArtifactsRoot} {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range entries {
+			if entry.Name() != rec.ID {
+				t.Fatalf("expected rejected bundle to leave nothing in %s, found %s", root, entry.Name())
+			}
+		}
+	}
+}
+
+func TestRecoverJobs_RemovesLeftoverWorkDirsOfFinishedJobs(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.WorkRoot = filepath.Join(t.TempDir(), "scratch")
+	st := store.New(cfg)
+	if err := st.EnsureDirs(); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().Add(-time.Hour)
+	done := job.New("done", manifest.Manifest{Top: "top", Part: "part", Sources: []string{"hdl/spade.sv"}}, now)
+	if err := done.Transition(job.StateRunning, now, "running"); err != nil {
+		t.Fatal(err)
+	}
+	if err := done.Transition(job.StateSucceeded, now.Add(time.Minute), "done"); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.CreateJobLayout(done.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Save(done); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr := New(cfg, st, &builder.FakeBuilder{})
+	if err := mgr.recoverJobs(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(st.WorkJobDir(done.ID)); !os.IsNotExist(err) {
+		t.Fatalf("expected leftover work dir to be removed, got %v", err)
+	}
+	if _, err := os.Stat(st.ArtifactsJobDir(done.ID)); err != nil {
+		t.Fatalf("expected artifacts to be kept: %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package queue
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/manifest"
+	loaderclient "github.com/mblsha/spadeforge/internal/spadeloader/client"
+	loaderjob "github.com/mblsha/spadeforge/internal/spadeloader/job"
+)
+
+// runLoaderTimeout bounds each loader lookup while assembling a run.
+const runLoaderTimeout = 10 * time.Second
+
+// Run assembles the run jobID belongs to: the first build up its
+// ParentJobID chain, the builds queued by its submit actions, and each
+// flash action's loader job with its hardware test verdict. It returns
+// os.ErrNotExist for unknown jobs.
+func (m *Manager) Run(ctx context.Context, jobID string) (*job.Run, error) {
+	m.mu.RLock()
+	root, ok := m.jobs[jobID]
+	for ok && root.ParentJobID != "" {
+		parent, found := m.jobs[root.ParentJobID]
+		if !found {
+			break
+		}
+		root = parent
+	}
+	if !ok {
+		m.mu.RUnlock()
+		return nil, os.ErrNotExist
+	}
+	var builds []job.Record
+	for pending := []*job.Record{root}; len(pending) > 0; pending = pending[1:] {
+		rec := pending[0]
+		builds = append(builds, *rec)
+		for _, f := range rec.FollowUps {
+			if child, found := m.jobs[f.JobID]; found && f.Action == manifest.ActionSubmit {
+				pending = append(pending, child)
+			}
+		}
+	}
+	// root is live and changes under m.mu; use the copy in builds from here.
+	rootRec := builds[0]
+	m.mu.RUnlock()
+
+	run := &job.Run{ID: rootRec.ID, Builds: make([]job.RunBuild, 0, len(builds))}
+	var buildPending, flashPending, testPending bool
+	var canceled bool
+	fail := func(msg string) {
+		if run.Error == "" {
+			run.Error = msg
+		}
+	}
+	for _, rec := range builds {
+		run.Builds = append(run.Builds, job.RunBuild{
+			JobID:       rec.ID,
+			ParentJobID: rec.ParentJobID,
+			Project:     rec.Manifest.Project,
+			State:       rec.State,
+			FailureKind: rec.FailureKind,
+			Error:       rec.Error,
+		})
+		switch rec.State {
+		case job.StateFailed:
+			fail(fmt.Sprintf("build %s failed: %s", rec.ID, cmp.Or(rec.FailureSummary, rec.Error)))
+		case job.StateCanceled:
+			canceled = true
+			fail(fmt.Sprintf("build %s canceled", rec.ID))
+		case job.StateSucceeded:
+			// Follow-ups are recorded one by one once each action returns.
+			if next := len(rec.FollowUps); next < len(rec.Manifest.OnSuccess) {
+				if rec.Manifest.OnSuccess[next].Flash != nil {
+					flashPending = true
+				} else {
+					buildPending = true
+				}
+			}
+		default:
+			buildPending = true
+		}
+
+		for i, f := range rec.FollowUps {
+			if f.Error != "" {
+				fail(fmt.Sprintf("on_success %s %s of build %s: %s", f.Action, f.Target, rec.ID, f.Error))
+			}
+			if f.Action != manifest.ActionFlash {
+				continue
+			}
+			flash := job.RunFlash{BuildJobID: rec.ID, Loader: f.Target, JobID: f.JobID, Error: f.Error}
+			if i < len(rec.Manifest.OnSuccess) && rec.Manifest.OnSuccess[i].Flash != nil {
+				flash.Board = rec.Manifest.OnSuccess[i].Flash.Board
+			}
+			if f.JobID != "" {
+				m.fillRunFlash(ctx, &flash)
+			}
+			switch {
+			case flash.JobID == "":
+			case flash.State == job.StateFailed:
+				fail(fmt.Sprintf("flash %s on %s failed: %s", flash.JobID, flash.Loader, flash.Error))
+			case !flash.State.Terminal() && flash.Step == "test":
+				testPending = true
+			case !flash.State.Terminal():
+				flashPending = true
+			}
+			run.Flashes = append(run.Flashes, flash)
+		}
+	}
+
+	switch {
+	case buildPending:
+		run.Stage = job.RunStageBuild
+	case flashPending:
+		run.Stage = job.RunStageFlash
+	case testPending:
+		run.Stage = job.RunStageTest
+	default:
+		run.Stage = job.RunStageDone
+	}
+	switch {
+	case run.Stage != job.RunStageDone && rootRec.State == job.StateQueued:
+		run.State = job.StateQueued
+	case run.Stage != job.RunStageDone:
+		run.State = job.StateRunning
+	case canceled:
+		run.State = job.StateCanceled
+	case run.Error != "":
+		run.State = job.StateFailed
+	default:
+		run.State = job.StateSucceeded
+	}
+	return run, nil
+}
+
+// fillRunFlash asks the flash's loader for its job. When the loader cannot
+// be asked the flash is left without a state, so the run stays pending.
+func (m *Manager) fillRunFlash(ctx context.Context, flash *job.RunFlash) {
+	baseURL, ok := m.cfg.Loaders[flash.Loader]
+	if !ok {
+		flash.Error = fmt.Sprintf("unknown loader %q", flash.Loader)
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, runLoaderTimeout)
+	defer cancel()
+	c := &loaderclient.HTTPClient{BaseURL: baseURL, Token: m.cfg.LoaderToken}
+	rec, err := c.GetJob(ctx, flash.JobID)
+	if err != nil {
+		flash.Error = err.Error()
+		return
+	}
+	flash.State = job.State(rec.State)
+	flash.Board = rec.Board
+	flash.FailureKind = rec.FailureKind
+	flash.Error = rec.Error
+	if rec.State == loaderjob.StateRunning {
+		flash.Step = rec.CurrentStep
+	}
+	if rec.Test != nil {
+		flash.Test = &job.RunTest{Passed: rec.Test.Passed, Summary: rec.Test.Summary}
+	}
+}
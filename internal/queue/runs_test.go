@@ -0,0 +1,113 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/manifest"
+	loaderjob "github.com/mblsha/spadeforge/internal/spadeloader/job"
+	"github.com/mblsha/spadeforge/internal/store"
+)
+
+func TestRun_RollsUpBuildsFlashAndTest(t *testing.T) {
+	var mu sync.Mutex
+	flashRec := loaderjob.Record{ID: "flash-1", State: loaderjob.StateRunning, CurrentStep: "test", Board: "arty"}
+	loader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte(`{"job_id":"flash-1"}`))
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		_ = json.NewEncoder(w).Encode(flashRec)
+	}))
+	defer loader.Close()
+
+	cfg := testConfig(t)
+	cfg.Loaders = map[string]string{"bench": loader.URL}
+	mgr := New(cfg, store.New(cfg), &builder.FakeBuilder{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	mf := manifest.Manifest{
+		Schema:  1,
+		Project: "blinky",
+		Top:     "top",
+		Part:    "xc7a35tcsg324-1",
+		Sources: []string{"hdl/spade.sv"},
+		OnSuccess: []manifest.Action{
+			{Flash: &manifest.FlashAction{Loader: "bench", Board: "arty"}},
+			{Submit: &manifest.SubmitAction{Project: "{project}-sim", Top: "tb"}},
+		},
+	}
+	rec, err := mgr.Submit(context.Background(), bytes.NewReader(bundleFromManifest(t, mf, "module top; endmodule\nmodule tb; endmodule\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := waitForRun(t, mgr, rec.ID, func(r *job.Run) bool { return r.Stage == job.RunStageTest })
+	if run.ID != rec.ID || run.State != job.StateRunning || run.Stage != job.RunStageTest {
+		t.Fatalf("run = %+v", run)
+	}
+	if len(run.Builds) != 2 || run.Builds[1].ParentJobID != rec.ID || run.Builds[1].Project != "blinky-sim" {
+		t.Fatalf("builds = %+v", run.Builds)
+	}
+	if len(run.Flashes) != 1 || run.Flashes[0].JobID != "flash-1" || run.Flashes[0].Step != "test" {
+		t.Fatalf("flashes = %+v", run.Flashes)
+	}
+
+	// The follow-up build's ID resolves to the same run.
+	child, err := mgr.Run(context.Background(), run.Builds[1].JobID)
+	if err != nil || child.ID != rec.ID {
+		t.Fatalf("child run = %+v, %v", child, err)
+	}
+
+	mu.Lock()
+	flashRec.State = loaderjob.StateFailed
+	flashRec.CurrentStep = "failed"
+	flashRec.FailureKind = loaderjob.FailureTestFailed
+	flashRec.Error = "hardware test failed"
+	flashRec.Test = &loaderjob.TestResult{Summary: "expected \"PASS\"", Log: "hil.log"}
+	mu.Unlock()
+	run = waitForRun(t, mgr, rec.ID, func(r *job.Run) bool { return r.State.Terminal() })
+	if run.State != job.StateFailed || run.Stage != job.RunStageDone || !strings.Contains(run.Error, "flash flash-1 on bench failed") {
+		t.Fatalf("run = %+v", run)
+	}
+	if f := run.Flashes[0]; f.Test == nil || f.Test.Passed || f.FailureKind != loaderjob.FailureTestFailed {
+		t.Fatalf("flash = %+v", f)
+	}
+
+	if _, err := mgr.Run(context.Background(), "missing"); err == nil {
+		t.Fatal("expected error for unknown job")
+	}
+}
+
+// waitForRun polls the run of jobID until done reports true or five
+// seconds pass, and returns the last run seen.
+func waitForRun(t *testing.T, mgr *Manager, jobID string, done func(*job.Run) bool) *job.Run {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		run, err := mgr.Run(context.Background(), jobID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if done(run) || time.Now().After(deadline) {
+			return run
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,127 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/manifest"
+)
+
+// checkSecrets confirms at submit time that every secret the manifest
+// names exists in the secrets directory.
+func (m *Manager) checkSecrets(refs []manifest.SecretRef) error {
+	if len(refs) == 0 {
+		return nil
+	}
+	if m.cfg.SecretsDir == "" {
+		return errors.New("secrets: this server has no secrets directory (SPADEFORGE_SECRETS_DIR)")
+	}
+	for i, ref := range refs {
+		if _, err := m.secretPath(ref.Name); err != nil {
+			return fmt.Errorf("secrets[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// secretPath returns the stored file for a secret name.
+func (m *Manager) secretPath(name string) (string, error) {
+	if !manifest.ValidSecretName(name) {
+		return "", fmt.Errorf("invalid secret name %q", name)
+	}
+	p := filepath.Join(m.cfg.SecretsDir, name)
+	fi, err := os.Stat(p)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("unknown secret %q", name)
+		}
+		return "", fmt.Errorf("secret %q: %w", name, err)
+	}
+	if !fi.Mode().IsRegular() {
+		return "", fmt.Errorf("secret %q is not a regular file", name)
+	}
+	return p, nil
+}
+
+// materializeSecrets writes the job's secrets into its source dir, readable
+// by the server user only. On failure nothing is left behind and the build
+// fails before the builder starts.
+func (m *Manager) materializeSecrets(rec *job.Record) *preflightFailure {
+	for i, ref := range rec.Manifest.Secrets {
+		if err := m.writeSecret(rec.ID, ref); err != nil {
+			m.scrubSecrets(rec)
+			msg := fmt.Sprintf("secret %q could not be provided: %v", ref.Name, err)
+			return &preflightFailure{
+				Kind:    "internal",
+				Summary: "[spadeforge secret-unavailable] " + msg,
+				Diagnostic: job.Diagnostic{
+					Severity: job.SeverityError,
+					Tool:     "spadeforge",
+					Code:     "secret-unavailable",
+					Message:  msg,
+					File:     "manifest.json",
+					Source:   fmt.Sprintf("/secrets/%d", i),
+				},
+			}
+		}
+	}
+	if n := len(rec.Manifest.Secrets); n > 0 {
+		qlog.Infof("%s provided %d secret(s)", jobLogPrefix(rec.ID, rec.Manifest.Project), n)
+	}
+	return nil
+}
+
+func (m *Manager) writeSecret(jobID string, ref manifest.SecretRef) error {
+	src, err := m.secretPath(ref.Name)
+	if err != nil {
+		return err
+	}
+	raw, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	dst := filepath.Join(m.store.SourceDir(jobID), filepath.FromSlash(ref.Path))
+	if err := os.MkdirAll(filepath.Dir(dst), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, raw, 0o600)
+}
+
+// scrubSecrets removes the job's secrets from its source dir, along with
+// any directories that held nothing else.
+func (m *Manager) scrubSecrets(rec *job.Record) {
+	root := m.store.SourceDir(rec.ID)
+	for _, ref := range rec.Manifest.Secrets {
+		if ref.Path == "" {
+			continue
+		}
+		err := os.Remove(filepath.Join(root, filepath.FromSlash(ref.Path)))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			qlog.Warnf("%s scrub secret %q: %v", jobLogPrefix(rec.ID, rec.Manifest.Project), ref.Name, err)
+			continue
+		}
+		for dir := path.Dir(ref.Path); dir != "."; dir = path.Dir(dir) {
+			if os.Remove(filepath.Join(root, filepath.FromSlash(dir))) != nil {
+				break
+			}
+		}
+	}
+}
+
+// isSecretPath reports whether rel, relative to the job's work dir, is
+// where one of its secrets is placed.
+func isSecretPath(rec *job.Record, rel string) bool {
+	rel = path.Clean(strings.ReplaceAll(rel, "\\", "/"))
+	for _, ref := range rec.Manifest.Secrets {
+		if ref.Path != "" && rel == path.Join("src", ref.Path) {
+			return true
+		}
+	}
+	return false
+}
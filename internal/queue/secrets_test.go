@@ -0,0 +1,135 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/manifest"
+	"github.com/mblsha/spadeforge/internal/store"
+)
+
+// secretReadingBuilder records what the build saw at rel in its sources.
+type secretReadingBuilder struct {
+	builder.FakeBuilder
+	rel  string
+	seen string
+	mode os.FileMode
+}
+
+func (b *secretReadingBuilder) Build(ctx context.Context, j builder.BuildJob) (builder.BuildResult, error) {
+	p := filepath.Join(j.SourceDir, filepath.FromSlash(b.rel))
+	if raw, err := os.ReadFile(p); err == nil {
+		b.seen = string(raw)
+	}
+	if fi, err := os.Stat(p); err == nil {
+		b.mode = fi.Mode().Perm()
+	}
+	return b.FakeBuilder.Build(ctx, j)
+}
+
+func secretBundle(t *testing.T, project string, refs ...manifest.SecretRef) []byte {
+	t.Helper()
+	return bundleFromManifest(t, manifest.Manifest{
+		Schema:  1,
+		Project: project,
+		Top:     "top",
+		Part:    "xc7a35tcsg324-1",
+		Sources: []string{"hdl/spade.sv"},
+		Secrets: refs,
+	}, "module top; endmodule\n")
+}
+
+func TestWorker_ProvidesSecretsDuringBuildAndScrubsThem(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.SecretsDir = t.TempDir()
+	cfg.PreserveWorkDir = true
+	if err := os.WriteFile(filepath.Join(cfg.SecretsDir, "aes_key"), []byte("0123abcd"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	st := store.New(cfg)
+	b := &secretReadingBuilder{rel: "keys/design.nky"}
+	mgr := New(cfg, st, b)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := mgr.Submit(context.Background(), bytes.NewReader(secretBundle(t, "secret", manifest.SecretRef{Name: "aes_key", Path: "keys/design.nky"})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	final := waitForTerminalState(t, mgr, rec.ID)
+	if final.State != job.StateSucceeded {
+		t.Fatalf("expected success, got %s error=%s", final.State, final.Error)
+	}
+	if b.seen != "0123abcd" {
+		t.Fatalf("build saw secret %q", b.seen)
+	}
+	if b.mode != 0o600 {
+		t.Fatalf("secret mode = %o, want 600", b.mode)
+	}
+	if _, err := os.Stat(filepath.Join(st.SourceDir(rec.ID), "keys")); !os.IsNotExist(err) {
+		t.Fatalf("expected secret dir to be scrubbed, stat err=%v", err)
+	}
+	if _, _, err := mgr.OpenWorkDirFile(rec.ID, "src/keys/design.nky"); !os.IsNotExist(err) {
+		t.Fatalf("expected secret path to be hidden, got %v", err)
+	}
+}
+
+func TestSubmit_RejectsUnknownSecret(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.SecretsDir = t.TempDir()
+	mgr := New(cfg, store.New(cfg), &builder.FakeBuilder{})
+
+	_, err := mgr.Submit(context.Background(), bytes.NewReader(secretBundle(t, "secret", manifest.SecretRef{Name: "missing"})))
+	if err == nil || !strings.Contains(err.Error(), `unknown secret "missing"`) {
+		t.Fatalf("expected unknown secret error, got %v", err)
+	}
+
+	cfg.SecretsDir = ""
+	mgr = New(cfg, store.New(cfg), &builder.FakeBuilder{})
+	_, err = mgr.Submit(context.Background(), bytes.NewReader(secretBundle(t, "secret", manifest.SecretRef{Name: "aes_key"})))
+	if err == nil || !strings.Contains(err.Error(), "no secrets directory") {
+		t.Fatalf("expected disabled secrets error, got %v", err)
+	}
+}
+
+func TestWorker_FailsWhenSecretDisappears(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.SecretsDir = t.TempDir()
+	secret := filepath.Join(cfg.SecretsDir, "license")
+	if err := os.WriteFile(secret, []byte("LIC"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	st := store.New(cfg)
+	mgr := New(cfg, st, &builder.FakeBuilder{})
+
+	rec, err := mgr.Submit(context.Background(), bytes.NewReader(secretBundle(t, "secret", manifest.SecretRef{Name: "license"})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(secret); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	final := waitForTerminalState(t, mgr, rec.ID)
+	if final.State != job.StateFailed || final.FailureKind != "internal" {
+		t.Fatalf("expected internal failure, got %s kind=%s", final.State, final.FailureKind)
+	}
+	if !strings.Contains(final.FailureSummary, "secret-unavailable") {
+		t.Fatalf("unexpected summary %q", final.FailureSummary)
+	}
+}
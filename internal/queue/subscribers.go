@@ -0,0 +1,113 @@
+package queue
+
+import (
+	"fmt"
+
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+// EventStats reports live SSE subscribers and how many events were dropped
+// because a subscriber could not keep up.
+type EventStats struct {
+	Subscribers     int   `json:"subscribers"`
+	SlowSubscribers int   `json:"slow_subscribers"`
+	DroppedEvents   int64 `json:"dropped_events"`
+}
+
+// eventSubscriber tracks one live event stream. lastSeq is the last event
+// handed to the channel and pending counts drops not yet reported to the
+// subscriber with a dropped_events notice.
+type eventSubscriber struct {
+	ch      chan job.Event
+	lastSeq int64
+	pending int64
+	dropped int64
+}
+
+// publish delivers ev without blocking and returns how many events were
+// dropped. Before the next event after a gap, the subscriber gets a
+// dropped_events notice so it can resync via since.
+func (s *eventSubscriber) publish(ev job.Event) int64 {
+	if s.pending > 0 && !ev.Terminal() {
+		if !s.offer(s.dropNotice(ev)) {
+			s.drop(1)
+			return 1
+		}
+		s.pending = 0
+	}
+	if s.offer(ev) {
+		s.lastSeq = ev.Seq
+		return 0
+	}
+
+	// For non-terminal updates, dropping events is acceptable when a subscriber is slow.
+	if !ev.Terminal() {
+		s.drop(1)
+		return 1
+	}
+
+	// Guarantee terminal delivery: evict one queued event and retry. The
+	// terminal event carries the final state, so no notice is needed.
+	var dropped int64
+	select {
+	case <-s.ch:
+		dropped++
+	default:
+	}
+	if s.offer(ev) {
+		s.lastSeq = ev.Seq
+	} else {
+		dropped++
+	}
+	s.drop(dropped)
+	return dropped
+}
+
+func (s *eventSubscriber) offer(ev job.Event) bool {
+	select {
+	case s.ch <- ev:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *eventSubscriber) drop(n int64) {
+	s.pending += n
+	s.dropped += n
+}
+
+func (s *eventSubscriber) dropNotice(next job.Event) job.Event {
+	return job.Event{
+		Seq:     s.lastSeq,
+		JobID:   next.JobID,
+		Project: next.Project,
+		Type:    job.EventDroppedEvents,
+		State:   next.State,
+		Message: fmt.Sprintf("%d events dropped; resync with since=%d", s.pending, s.lastSeq),
+		Dropped: s.pending,
+		At:      next.At,
+	}
+}
+
+// EventStats returns subscriber and drop counters for the metrics endpoint.
+func (m *Manager) EventStats() EventStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	stats := EventStats{DroppedEvents: m.droppedEvents}
+	for _, subs := range m.subscribers {
+		for _, sub := range subs {
+			stats.Subscribers++
+			if sub.dropped > 0 {
+				stats.SlowSubscribers++
+			}
+		}
+	}
+	for _, sub := range m.globalSubscribers {
+		stats.Subscribers++
+		if sub.dropped > 0 {
+			stats.SlowSubscribers++
+		}
+	}
+	return stats
+}
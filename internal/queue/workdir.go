@@ -0,0 +1,144 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+// ErrWorkDirNotPreserved is returned when work dir browsing is requested but
+// the server removes work dirs after each job.
+var ErrWorkDirNotPreserved = errors.New("work dir is not preserved; enable SPADEFORGE_PRESERVE_WORK_DIR")
+
+// ListWorkDir returns every regular file under the job work dir, sorted by path.
+func (m *Manager) ListWorkDir(jobID string) ([]job.WorkDirEntry, error) {
+	rec, ok := m.Get(jobID)
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	if !m.cfg.PreserveWorkDir {
+		return nil, ErrWorkDirNotPreserved
+	}
+	root := m.store.WorkJobDir(jobID)
+	entries := make([]job.WorkDirEntry, 0)
+	err := filepath.WalkDir(root, func(pathNow string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, pathNow)
+		if err != nil {
+			return err
+		}
+		if isSecretPath(rec, rel) {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entries = append(entries, job.WorkDirEntry{
+			Path:    filepath.ToSlash(rel),
+			Size:    fi.Size(),
+			ModTime: fi.ModTime().UTC(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+	return entries, nil
+}
+
+// OpenWorkDirFile opens a single file from the job work dir. The caller must
+// close the returned file.
+func (m *Manager) OpenWorkDirFile(jobID, rel string) (*os.File, os.FileInfo, error) {
+	rec, ok := m.Get(jobID)
+	if !ok {
+		return nil, nil, os.ErrNotExist
+	}
+	if !m.cfg.PreserveWorkDir {
+		return nil, nil, ErrWorkDirNotPreserved
+	}
+	full, err := workDirPath(m.store.WorkJobDir(jobID), rel)
+	if err != nil {
+		return nil, nil, err
+	}
+	if isSecretPath(rec, rel) {
+		return nil, nil, os.ErrNotExist
+	}
+	// Serve only what ListWorkDir lists: a symlink left by the bundle or
+	// the build could point at a secret or outside the work dir.
+	if err := checkNoSymlinks(m.store.WorkJobDir(jobID), full); err != nil {
+		if errors.Is(err, errSymlink) {
+			return nil, nil, fmt.Errorf("%s is not a regular file", rel)
+		}
+		return nil, nil, err
+	}
+	f, err := os.Open(full)
+	if err != nil {
+		return nil, nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	if !fi.Mode().IsRegular() {
+		f.Close()
+		return nil, nil, fmt.Errorf("%s is not a regular file", rel)
+	}
+	return f, fi, nil
+}
+
+func workDirPath(root, rel string) (string, error) {
+	raw := strings.TrimSpace(strings.ReplaceAll(rel, "\\", "/"))
+	if raw == "" || strings.HasPrefix(raw, "/") || (len(raw) >= 2 && raw[1] == ':') {
+		return "", fmt.Errorf("invalid work dir path %q", rel)
+	}
+	cleaned := path.Clean(raw)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("invalid work dir path %q", rel)
+	}
+	cleanRoot := filepath.Clean(root)
+	full := filepath.Join(cleanRoot, filepath.FromSlash(cleaned))
+	if !strings.HasPrefix(full, cleanRoot+string(os.PathSeparator)) {
+		return "", fmt.Errorf("invalid work dir path %q", rel)
+	}
+	return full, nil
+}
+
+// errSymlink is returned by checkNoSymlinks.
+var errSymlink = errors.New("path goes through a symlink")
+
+// checkNoSymlinks returns errSymlink when full, a path under root, or any
+// directory between them is a symlink.
+func checkNoSymlinks(root, full string) error {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return err
+	}
+	real, err := filepath.EvalSymlinks(full)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(root, full)
+	if err != nil {
+		return err
+	}
+	if real != filepath.Join(realRoot, rel) {
+		return errSymlink
+	}
+	return nil
+}
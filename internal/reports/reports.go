@@ -0,0 +1,106 @@
+// Package reports extracts headline numbers from Vivado's
+// report_timing_summary and report_utilization output.
+package reports
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Timing holds the design timing summary, in ns.
+type Timing struct {
+	WNS float64
+	TNS float64
+	WHS float64
+	THS float64
+}
+
+// ParseTiming reads the "Design Timing Summary" table of timing.rpt. It
+// returns false when the table is missing or malformed.
+func ParseTiming(raw []byte) (Timing, bool) {
+	lines := strings.Split(string(raw), "\n")
+	for i, line := range lines {
+		if !strings.Contains(line, "WNS(ns)") || !strings.Contains(line, "WHS(ns)") {
+			continue
+		}
+		for _, next := range lines[i+1:] {
+			fields := strings.Fields(next)
+			if len(fields) == 0 || strings.HasPrefix(fields[0], "---") {
+				continue
+			}
+			if len(fields) < 6 {
+				return Timing{}, false
+			}
+			nums := make([]float64, 6)
+			for j := range nums {
+				v, err := strconv.ParseFloat(fields[j], 64)
+				if err != nil {
+					return Timing{}, false
+				}
+				nums[j] = v
+			}
+			// Columns: WNS TNS failing total WHS THS ...
+			return Timing{WNS: nums[0], TNS: nums[1], WHS: nums[4], THS: nums[5]}, true
+		}
+		return Timing{}, false
+	}
+	return Timing{}, false
+}
+
+// Resource is one row of the utilization report. Used is fractional for
+// half-size block RAM tiles.
+type Resource struct {
+	Used      float64 `json:"used"`
+	Available int     `json:"available"`
+	Percent   float64 `json:"percent"`
+}
+
+// utilizationRows maps report row names (7-series and UltraScale) to stable
+// resource keys.
+var utilizationRows = map[string]string{
+	"slice luts":      "lut",
+	"clb luts":        "lut",
+	"slice registers": "ff",
+	"clb registers":   "ff",
+	"block ram tile":  "bram",
+	"dsps":            "dsp",
+	"bonded iob":      "io",
+}
+
+// ParseUtilization reads the headline resources of utilization.rpt, keyed
+// as lut, ff, bram, dsp and io. Only the first row for each key counts.
+func ParseUtilization(raw []byte) map[string]Resource {
+	out := map[string]Resource{}
+	for _, line := range strings.Split(string(raw), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "|") {
+			continue
+		}
+		cells := strings.Split(strings.Trim(line, "|"), "|")
+		if len(cells) < 4 {
+			continue
+		}
+		name := strings.ToLower(strings.TrimRight(strings.TrimSpace(cells[0]), "*"))
+		key, ok := utilizationRows[strings.TrimSpace(name)]
+		if !ok {
+			continue
+		}
+		if _, dup := out[key]; dup {
+			continue
+		}
+		used, err := strconv.ParseFloat(strings.TrimSpace(cells[1]), 64)
+		if err != nil {
+			continue
+		}
+		avail, err := strconv.ParseFloat(strings.TrimSpace(cells[len(cells)-2]), 64)
+		if err != nil {
+			continue
+		}
+		pct, err := strconv.ParseFloat(strings.TrimSpace(cells[len(cells)-1]), 64)
+		if err != nil {
+			continue
+		}
+		out[key] = Resource{Used: used, Available: int(avail), Percent: pct}
+	}
+	return out
+}
@@ -0,0 +1,65 @@
+package reports
+
+import "testing"
+
+const timingRpt = `Timing Report
+
+------------------------------------------------------------------------------------------------
+| Design Timing Summary
+| ---------------------
+------------------------------------------------------------------------------------------------
+
+    WNS(ns)      TNS(ns)  TNS Failing Endpoints  TNS Total Endpoints      WHS(ns)      THS(ns)  THS Failing Endpoints  THS Total Endpoints     WPWS(ns)     TPWS(ns)  TPWS Failing Endpoints  TPWS Total Endpoints  
+    -------      -------  ---------------------  -------------------      -------      -------  ---------------------  -------------------     --------     --------  ----------------------  --------------------  
+     -0.420       -3.125                     12                  123        0.101        0.000                      0                  123        4.500        0.000                       0                    65  
+`
+
+const utilizationRpt = `1. Slice Logic
+--------------
+
++-------------------------+------+-------+------------+-----------+-------+
+|        Site Type        | Used | Fixed | Prohibited | Available | Util% |
++-------------------------+------+-------+------------+-----------+-------+
+| Slice LUTs*             |  520 |     0 |          0 |     20800 |  2.50 |
+|   LUT as Logic          |  500 |     0 |          0 |     20800 |  2.40 |
+| Slice Registers         |  400 |     0 |          0 |     41600 |  0.96 |
++-------------------------+------+-------+------------+-----------+-------+
+
+3. Memory
+---------
+
++-------------------+------+-------+------------+-----------+-------+
+|     Site Type     | Used | Fixed | Prohibited | Available | Util% |
++-------------------+------+-------+------------+-----------+-------+
+| Block RAM Tile    |  1.5 |     0 |          0 |        50 |  3.00 |
++-------------------+------+-------+------------+-----------+-------+
+
+| DSPs           |    0 |     0 |          0 |        90 |  0.00 |
+| Bonded IOB     |    5 |     5 |          0 |       210 |  2.38 |
+`
+
+func TestParseTiming(t *testing.T) {
+	got, ok := ParseTiming([]byte(timingRpt))
+	if !ok {
+		t.Fatalf("expected timing summary")
+	}
+	if got.WNS != -0.42 || got.TNS != -3.125 || got.WHS != 0.101 || got.THS != 0 {
+		t.Fatalf("unexpected timing: %+v", got)
+	}
+	if _, ok := ParseTiming([]byte("timing fake\n")); ok {
+		t.Fatalf("expected no summary in fake report")
+	}
+}
+
+func TestParseUtilization(t *testing.T) {
+	got := ParseUtilization([]byte(utilizationRpt))
+	if got["lut"] != (Resource{Used: 520, Available: 20800, Percent: 2.5}) {
+		t.Fatalf("unexpected lut: %+v", got["lut"])
+	}
+	if got["ff"].Used != 400 || got["bram"].Used != 1.5 || got["bram"].Percent != 3 || got["dsp"].Available != 90 || got["io"].Used != 5 {
+		t.Fatalf("unexpected utilization: %+v", got)
+	}
+	if len(got) != 5 {
+		t.Fatalf("expected 5 resources, got %+v", got)
+	}
+}
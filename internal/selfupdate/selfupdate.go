@@ -0,0 +1,317 @@
+// Package selfupdate replaces a running CLI with the matching build from a
+// release published by `spadeforge release`: one archive per platform plus
+// a SHA256SUMS file, optionally signed with ed25519 as SHA256SUMS.sig.
+package selfupdate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/mblsha/spadeforge/internal/buildinfo"
+)
+
+const (
+	ChecksumsName = "SHA256SUMS"
+	SignatureName = "SHA256SUMS.sig"
+
+	maxChecksumsBytes = 1 << 20
+	maxArchiveBytes   = 512 << 20
+)
+
+// ArchiveName is the release archive holding the binaries for a platform.
+func ArchiveName(version, goos, goarch string) string {
+	ext := ".tar.gz"
+	if goos == "windows" {
+		ext = ".zip"
+	}
+	return fmt.Sprintf("spadeforge_%s_%s_%s%s", version, goos, goarch, ext)
+}
+
+// ExpandURL fills "{version}" in a release URL template, e.g.
+// https://github.com/mblsha/spadeforge/releases/download/{version}.
+func ExpandURL(template, version string) string {
+	return strings.ReplaceAll(strings.TrimSpace(template), "{version}", version)
+}
+
+// Resolve picks the version to install and where to fetch it. Flags win;
+// whatever they leave empty comes from the server's /v1/version, so a CLI
+// follows the server it talks to.
+func Resolve(version, releaseURL string, server func() (*buildinfo.Info, error)) (string, string, error) {
+	version, releaseURL = strings.TrimSpace(version), strings.TrimSpace(releaseURL)
+	if version == "" || releaseURL == "" {
+		info, err := server()
+		if err != nil {
+			return "", "", err
+		}
+		if version == "" {
+			version = info.Version
+		}
+		if releaseURL == "" {
+			releaseURL = info.ReleaseURL
+		}
+	}
+	if version == "" || version == "dev" {
+		return "", "", errors.New("the server runs an unreleased build; pass --version")
+	}
+	if releaseURL == "" {
+		return "", "", errors.New("no release URL; pass --release-url or configure one on the server")
+	}
+	return version, releaseURL, nil
+}
+
+// Options describes one update.
+type Options struct {
+	// ReleaseURL is the directory holding the release's archives and
+	// SHA256SUMS; "{version}" is replaced with Version.
+	ReleaseURL string
+	Version    string
+	// Binary is the command to replace, e.g. "spadeforge-cli".
+	Binary string
+	// Executable is the file to replace; empty means the running binary.
+	Executable string
+	// PublicKey, when set, requires SHA256SUMS to carry a valid signature.
+	PublicKey ed25519.PublicKey
+
+	Client *http.Client
+	// GOOS and GOARCH default to the running platform.
+	GOOS   string
+	GOARCH string
+}
+
+// Update downloads the release archive for the platform, checks it against
+// SHA256SUMS (and its signature) and swaps the binary in place. It returns
+// the path it replaced.
+func Update(ctx context.Context, opts Options) (string, error) {
+	if opts.GOOS == "" {
+		opts.GOOS = runtime.GOOS
+	}
+	if opts.GOARCH == "" {
+		opts.GOARCH = runtime.GOARCH
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	exe := opts.Executable
+	if exe == "" {
+		var err error
+		if exe, err = os.Executable(); err != nil {
+			return "", err
+		}
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	base := strings.TrimRight(ExpandURL(opts.ReleaseURL, opts.Version), "/")
+	if base == "" {
+		return "", errors.New("no release URL")
+	}
+
+	sums, err := fetch(ctx, opts.Client, base+"/"+ChecksumsName, maxChecksumsBytes)
+	if err != nil {
+		return "", err
+	}
+	if opts.PublicKey != nil {
+		sig, err := fetch(ctx, opts.Client, base+"/"+SignatureName, maxChecksumsBytes)
+		if err != nil {
+			return "", err
+		}
+		if err := Verify(opts.PublicKey, sums, sig); err != nil {
+			return "", err
+		}
+	}
+	archive := ArchiveName(opts.Version, opts.GOOS, opts.GOARCH)
+	want, ok := lookupChecksum(sums, archive)
+	if !ok {
+		return "", fmt.Errorf("%s lists no %s; is there a build for %s/%s?", ChecksumsName, archive, opts.GOOS, opts.GOARCH)
+	}
+	raw, err := fetch(ctx, opts.Client, base+"/"+archive, maxArchiveBytes)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return "", fmt.Errorf("%s: checksum %s does not match %s", archive, got, want)
+	}
+
+	name := opts.Binary
+	if opts.GOOS == "windows" {
+		name += ".exe"
+	}
+	prefix := strings.TrimSuffix(strings.TrimSuffix(archive, ".zip"), ".tar.gz")
+	bin, err := extract(raw, archive, prefix+"/"+name)
+	if err != nil {
+		return "", err
+	}
+	return exe, replace(exe, bin)
+}
+
+func fetch(ctx context.Context, c *http.Client, url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: status=%d", url, resp.StatusCode)
+	}
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("GET %s: %w", url, err)
+	}
+	if int64(len(raw)) > limit {
+		return nil, fmt.Errorf("GET %s: larger than %d bytes", url, limit)
+	}
+	return raw, nil
+}
+
+// lookupChecksum finds name in a `sha256sum` style listing.
+func lookupChecksum(sums []byte, name string) (string, bool) {
+	sc := bufio.NewScanner(bytes.NewReader(sums))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), true
+		}
+	}
+	return "", false
+}
+
+func extract(raw []byte, archive, entry string) ([]byte, error) {
+	if strings.HasSuffix(archive, ".zip") {
+		zr, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", archive, err)
+		}
+		for _, f := range zr.File {
+			if f.Name != entry {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			return io.ReadAll(rc)
+		}
+		return nil, fmt.Errorf("%s has no %s", archive, entry)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", archive, err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s has no %s", archive, entry)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", archive, err)
+		}
+		if hdr.Name == entry {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// replace swaps exe for bin through a temporary file in the same directory,
+// so a failed update leaves the old binary in place. Windows cannot
+// overwrite a running executable, so it is first moved aside to exe.old.
+func replace(exe string, bin []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(exe), "."+filepath.Base(exe)+".new-*")
+	if err != nil {
+		return fmt.Errorf("write new binary: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(bin)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("write new binary: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o755); err != nil {
+		return err
+	}
+	if runtime.GOOS == "windows" {
+		old := exe + ".old"
+		_ = os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			return fmt.Errorf("move old binary aside: %w", err)
+		}
+	}
+	if err := os.Rename(tmp.Name(), exe); err != nil {
+		return fmt.Errorf("replace %s: %w", exe, err)
+	}
+	return nil
+}
+
+// Sign returns the SHA256SUMS.sig contents for sums.
+func Sign(key ed25519.PrivateKey, sums []byte) []byte {
+	return []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(key, sums)) + "\n")
+}
+
+// Verify checks a SHA256SUMS.sig written by Sign.
+func Verify(key ed25519.PublicKey, sums, sig []byte) error {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil {
+		return fmt.Errorf("%s: %w", SignatureName, err)
+	}
+	if !ed25519.Verify(key, sums, raw) {
+		return fmt.Errorf("%s does not match the release public key", SignatureName)
+	}
+	return nil
+}
+
+// ParsePublicKey reads a 32-byte ed25519 public key in hex or base64.
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	raw, err := decodeKey(s, ed25519.PublicKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("public key: %w", err)
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// LoadPrivateKey reads a 32-byte ed25519 seed, in hex or base64, from path.
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	seed, err := decodeKey(string(raw), ed25519.SeedSize)
+	if err != nil {
+		return nil, fmt.Errorf("signing key %s: %w", path, err)
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+func decodeKey(s string, size int) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if raw, err := hex.DecodeString(s); err == nil && len(raw) == size {
+		return raw, nil
+	}
+	if raw, err := base64.StdEncoding.DecodeString(s); err == nil && len(raw) == size {
+		return raw, nil
+	}
+	return nil, fmt.Errorf("want %d bytes as hex or base64", size)
+}
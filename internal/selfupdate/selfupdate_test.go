@@ -0,0 +1,189 @@
+package selfupdate
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mblsha/spadeforge/internal/buildinfo"
+)
+
+// testRelease serves a v2.0.0 release for linux/amd64 under /v2.0.0/.
+type testRelease struct {
+	files map[string][]byte
+}
+
+func newTestRelease(t *testing.T, key ed25519.PrivateKey) *testRelease {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, body := range map[string]string{
+		"spadeforge_v2.0.0_linux_amd64/spadeforge-cli": "new cli",
+		"spadeforge_v2.0.0_linux_amd64/README.md":      "readme",
+	} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(body))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	archive := ArchiveName("v2.0.0", "linux", "amd64")
+	sum := sha256.Sum256(buf.Bytes())
+	sums := []byte(fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), archive))
+	r := &testRelease{files: map[string][]byte{archive: buf.Bytes(), ChecksumsName: sums}}
+	if key != nil {
+		r.files[SignatureName] = Sign(key, sums)
+	}
+	return r
+}
+
+func (r *testRelease) serve(t *testing.T) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, ok := r.files[strings.TrimPrefix(req.URL.Path, "/v2.0.0/")]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func testExecutable(t *testing.T) string {
+	t.Helper()
+	exe := filepath.Join(t.TempDir(), "spadeforge-cli")
+	if err := os.WriteFile(exe, []byte("old cli"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return exe
+}
+
+func TestUpdate_ReplacesBinaryFromSignedRelease(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := newTestRelease(t, priv).serve(t)
+	exe := testExecutable(t)
+
+	got, err := Update(context.Background(), Options{
+		ReleaseURL: ts.URL + "/{version}/",
+		Version:    "v2.0.0",
+		Binary:     "spadeforge-cli",
+		Executable: exe,
+		PublicKey:  pub,
+		GOOS:       "linux",
+		GOARCH:     "amd64",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != exe {
+		t.Fatalf("replaced %s, want %s", got, exe)
+	}
+	raw, err := os.ReadFile(exe)
+	if err != nil || string(raw) != "new cli" {
+		t.Fatalf("executable = %q, %v", raw, err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(exe))
+	if len(entries) != 1 {
+		t.Fatalf("expected no leftovers next to the binary, got %v", entries)
+	}
+}
+
+func TestUpdate_RejectsTamperedReleases(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		key    ed25519.PublicKey
+		tamper func(r *testRelease)
+		want   string
+	}{
+		{"checksum", nil, func(r *testRelease) {
+			r.files[ArchiveName("v2.0.0", "linux", "amd64")] = []byte("not the archive")
+		}, "does not match"},
+		{"wrong key", otherPub, func(*testRelease) {}, "does not match the release public key"},
+		{"missing signature", pub, func(r *testRelease) { delete(r.files, SignatureName) }, "status=404"},
+		{"no build for platform", nil, func(r *testRelease) { r.files[ChecksumsName] = []byte("") }, "is there a build for linux/amd64"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newTestRelease(t, priv)
+			tc.tamper(r)
+			ts := r.serve(t)
+			exe := testExecutable(t)
+			_, err := Update(context.Background(), Options{
+				ReleaseURL: ts.URL + "/{version}",
+				Version:    "v2.0.0",
+				Binary:     "spadeforge-cli",
+				Executable: exe,
+				PublicKey:  tc.key,
+				GOOS:       "linux",
+				GOARCH:     "amd64",
+			})
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("expected error containing %q, got %v", tc.want, err)
+			}
+			if raw, _ := os.ReadFile(exe); string(raw) != "old cli" {
+				t.Fatalf("binary changed after a failed update: %q", raw)
+			}
+		})
+	}
+}
+
+func TestResolve_PrefersFlagsOverServer(t *testing.T) {
+	calls := 0
+	server := func() (*buildinfo.Info, error) {
+		calls++
+		return &buildinfo.Info{Version: "v1.4.0", ReleaseURL: "https://example.com/{version}"}, nil
+	}
+
+	version, url, err := Resolve("", "", server)
+	if err != nil || version != "v1.4.0" || url != "https://example.com/{version}" {
+		t.Fatalf("Resolve from server = %q, %q, %v", version, url, err)
+	}
+	version, url, err = Resolve("v1.5.0", "https://mirror/{version}", server)
+	if err != nil || version != "v1.5.0" || url != "https://mirror/{version}" || calls != 1 {
+		t.Fatalf("Resolve from flags = %q, %q, %v (server calls %d)", version, url, err, calls)
+	}
+
+	dev := func() (*buildinfo.Info, error) { return &buildinfo.Info{Version: "dev"}, nil }
+	if _, _, err := Resolve("", "https://mirror", dev); err == nil || !strings.Contains(err.Error(), "unreleased") {
+		t.Fatalf("expected unreleased error, got %v", err)
+	}
+	if _, _, err := Resolve("v1.5.0", "", dev); err == nil || !strings.Contains(err.Error(), "no release URL") {
+		t.Fatalf("expected missing URL error, got %v", err)
+	}
+	failing := func() (*buildinfo.Info, error) { return nil, errors.New("offline") }
+	if _, _, err := Resolve("", "", failing); err == nil || err.Error() != "offline" {
+		t.Fatalf("expected server error, got %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+// handleFeed serves the wallboard snapshot. The body only changes when the
+// queue does, so it carries an ETag and pollers that send it back get a
+// bodiless 304. The ETag is weak because Gzip may re-encode the body.
+func (a *API) handleFeed(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(a.manager.Feed(job.FeedRecent)); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	etag := `W/"` + hex.EncodeToString(sum[:8]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(buf.Bytes())
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/mblsha/spadeforge/internal/authz"
+	"github.com/mblsha/spadeforge/internal/clientdefaults"
+	"github.com/mblsha/spadeforge/internal/httpmw"
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+// fullAccessScopes are reported for callers without a named token: the
+// shared token, a client certificate or an open server.
+var fullAccessScopes = []string{string(authz.ScopeRead), string(authz.ScopeSubmit), string(authz.ScopeAdmin)}
+
+// me describes the caller. Defaults are keyed by named token so they follow
+// a pipeline between hosts; everyone else is told apart by IP, as the
+// submit quotas do.
+func (a *API) me(r *http.Request) job.Me {
+	me := job.Me{Client: "ip:" + httpmw.ClientKey(r), Scopes: fullAccessScopes}
+	if t, ok := authz.FromContext(r.Context()); ok {
+		me.Client = "token:" + t.Name
+		me.TokenName = t.Name
+		me.ExpiresAt = t.ExpiresAt
+		me.Scopes = make([]string, len(t.Scopes))
+		for i, s := range t.Scopes {
+			me.Scopes[i] = string(s)
+		}
+	}
+	return me
+}
+
+func (a *API) handleGetMe(w http.ResponseWriter, r *http.Request) {
+	me := a.me(r)
+	defaults, err := a.defaults.Get(me.Client)
+	if err != nil {
+		hlog.Errorf("load client defaults: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "client defaults unavailable"})
+		return
+	}
+	me.Defaults = defaults
+	writeJSON(w, http.StatusOK, me)
+}
+
+// handleSetDefaults replaces the caller's stored defaults; an empty object
+// clears them.
+func (a *API) handleSetDefaults(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 64<<10)
+	var req job.ClientDefaults
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid defaults: " + err.Error()})
+		return
+	}
+	me := a.me(r)
+	if err := clientdefaults.Normalize(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	defaults, err := a.defaults.Set(me.Client, req)
+	if err != nil {
+		hlog.Errorf("store client defaults: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "could not store client defaults"})
+		return
+	}
+	me.Defaults = defaults
+	writeJSON(w, http.StatusOK, me)
+}
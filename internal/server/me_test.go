@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mblsha/spadeforge/internal/authz"
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/client"
+	"github.com/mblsha/spadeforge/internal/clientdefaults"
+	"github.com/mblsha/spadeforge/internal/config"
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+func TestMe_StoresDefaultsPerClient(t *testing.T) {
+	ts, cfg, _, cancel := newTestServerWithConfig(t, &builder.FakeBuilder{}, func(cfg *config.Config) {
+		cfg.Tokens = []authz.Token{
+			{Name: "ci", Token: "ci-secret", Scopes: []authz.Scope{authz.ScopeRead, authz.ScopeSubmit}},
+			{Name: "dash", Token: "dash-secret", Scopes: []authz.Scope{authz.ScopeRead}},
+		}
+	})
+	defer cancel()
+	ctx := context.Background()
+	newClient := func(token string) *client.HTTPClient {
+		return &client.HTTPClient{BaseURL: ts.URL, Token: token, AuthHeader: cfg.AuthHeader}
+	}
+
+	ci := newClient("ci-secret")
+	me, err := ci.GetMe(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if me.Client != "token:ci" || me.TokenName != "ci" || strings.Join(me.Scopes, ",") != "read,submit" || me.Defaults != (job.ClientDefaults{}) {
+		t.Fatalf("unexpected me: %+v", me)
+	}
+	me, err = ci.SetDefaults(ctx, job.ClientDefaults{Part: " xc7a35tcsg324-1 ", Toolchain: "Vivado", Board: "arty"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := job.ClientDefaults{Part: "xc7a35tcsg324-1", Toolchain: "vivado", Board: "arty"}
+	if me.Defaults != want {
+		t.Fatalf("stored defaults = %+v, want %+v", me.Defaults, want)
+	}
+	if _, err := ci.SetDefaults(ctx, job.ClientDefaults{Toolchain: "quartus"}); err == nil || !strings.Contains(err.Error(), "status=400") {
+		t.Fatalf("bad toolchain error = %v, want 400", err)
+	}
+
+	// A fresh store reads the same file, as after a restart.
+	got, err := clientdefaults.New(cfg.ClientDefaultsPath()).Get("token:ci")
+	if err != nil || got != want {
+		t.Fatalf("defaults after restart = %+v, %v", got, err)
+	}
+
+	shared, err := newClient(cfg.Token).GetMe(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(shared.Client, "ip:") || shared.TokenName != "" || shared.Defaults != (job.ClientDefaults{}) {
+		t.Fatalf("shared token me = %+v", shared)
+	}
+
+	dash := newClient("dash-secret")
+	if _, err := dash.SetDefaults(ctx, job.ClientDefaults{Part: "x"}); err == nil || !strings.Contains(err.Error(), "status=403") {
+		t.Fatalf("read-only token set defaults error = %v, want 403", err)
+	}
+
+	if me, err = ci.SetDefaults(ctx, job.ClientDefaults{}); err != nil || me.Defaults != (job.ClientDefaults{}) {
+		t.Fatalf("clearing defaults = %+v, %v", me, err)
+	}
+	resp, err := http.Get(ts.URL + "/v1/me")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("unauthenticated status = %d, want 401", resp.StatusCode)
+	}
+}
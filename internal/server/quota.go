@@ -0,0 +1,96 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/authz"
+	"github.com/mblsha/spadeforge/internal/diskspace"
+	"github.com/mblsha/spadeforge/internal/httpmw"
+)
+
+// activeQuotaRetryAfter is the Retry-After for clients at their active job
+// quota; when one of their jobs finishes is anyone's guess.
+const activeQuotaRetryAfter = 30 * time.Second
+
+// storageQuotaRetryAfter is the Retry-After for clients at their storage
+// quota when the server has no retention interval to wait for.
+const storageQuotaRetryAfter = 5 * time.Minute
+
+// newSubmitLimiter turns the per-minute submit rate into a token bucket.
+// The burst defaults to one minute's worth.
+func newSubmitLimiter(perMinute float64, burst int) *httpmw.RateLimiter {
+	if burst < 1 {
+		burst = int(math.Ceil(perMinute))
+	}
+	return httpmw.NewRateLimiter(perMinute/60, burst)
+}
+
+// limitSubmits enforces the per-client quotas and submit rate on POST
+// /v1/jobs. A client is its named token or, without one, its IP. It runs
+// after the token check, so the token name is known.
+func (a *API) limitSubmits(next http.Handler) http.Handler {
+	if !a.quotasEnabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.rejectOverQuota(w, r, true) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limitUploads turns away new resumable uploads from clients already at
+// their job or storage quota. It does not charge the submit rate: the job
+// is created, and the quotas are enforced again, at finalize.
+func (a *API) limitUploads(next http.Handler) http.Handler {
+	if !a.quotasEnabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.rejectOverQuota(w, r, false) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *API) quotasEnabled() bool {
+	return a.submitLimiter != nil || a.cfg.MaxActiveJobsPerClient > 0 || a.cfg.MaxStorageBytesPerClient > 0
+}
+
+// rejectOverQuota answers 429 with Retry-After and returns true when the
+// client is at its active job or storage quota or, with charge set, over
+// its submit rate, which a passing check then counts as one submit.
+func (a *API) rejectOverQuota(w http.ResponseWriter, r *http.Request, charge bool) bool {
+	name, ip := authz.Name(r.Context()), httpmw.ClientKey(r)
+	client := "client " + ip
+	if name != "" {
+		client = fmt.Sprintf("token %q", name)
+	}
+
+	usage := a.manager.ClientUsage(name, ip, a.cfg.MaxStorageBytesPerClient > 0)
+	if max := a.cfg.MaxActiveJobsPerClient; max > 0 && usage.ActiveJobs >= max {
+		httpmw.TooManyRequests(w, activeQuotaRetryAfter, fmt.Sprintf("%s has %d queued or running jobs; the limit is %d", client, usage.ActiveJobs, max))
+		return true
+	}
+	if max := a.cfg.MaxStorageBytesPerClient; max > 0 && usage.StorageBytes >= max {
+		retryAfter := a.cfg.RetentionInterval
+		if retryAfter <= 0 {
+			retryAfter = storageQuotaRetryAfter
+		}
+		httpmw.TooManyRequests(w, retryAfter, fmt.Sprintf("%s stores %s of jobs; the limit is %s", client,
+			diskspace.Format(uint64(usage.StorageBytes)), diskspace.Format(uint64(max))))
+		return true
+	}
+	if charge && a.submitLimiter != nil {
+		if ok, wait := a.submitLimiter.Allow(client); !ok {
+			httpmw.TooManyRequests(w, wait, fmt.Sprintf("%s exceeded the submit rate limit of %g per minute", client, a.cfg.SubmitRateLimit))
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mblsha/spadeforge/internal/authz"
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/config"
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/queue"
+	"github.com/mblsha/spadeforge/internal/store"
+)
+
+func TestSubmitQuota_ActiveJobsPerToken(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	ts, cfg, _, cancel := newTestServerWithConfig(t, &builder.FakeBuilder{BlockCh: block}, func(cfg *config.Config) {
+		cfg.MaxActiveJobsPerClient = 1
+		cfg.Tokens = []authz.Token{
+			{Name: "ci", Token: "ci-secret", Scopes: []authz.Scope{authz.ScopeSubmit}},
+			{Name: "nightly", Token: "nightly-secret", Scopes: []authz.Scope{authz.ScopeSubmit}},
+		}
+	})
+	defer cancel()
+
+	ci := cfg
+	ci.Token = "ci-secret"
+	submitBundle(t, ts.URL, ci, validBundleBytes(t, "ok"))
+	status, retryAfter, body := postBundle(t, ts.URL, ci, validBundleBytes(t, "ok"))
+	if status != http.StatusTooManyRequests || retryAfter == "" || !strings.Contains(body, `token \"ci\"`) {
+		t.Fatalf("second ci submit = %d retry-after=%q body=%s", status, retryAfter, body)
+	}
+
+	nightly := cfg
+	nightly.Token = "nightly-secret"
+	submitBundle(t, ts.URL, nightly, validBundleBytes(t, "ok"))
+}
+
+func TestSubmitQuota_AppliesAtUploadFinalize(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	ts, cfg, _, cancel := newTestServerWithConfig(t, &builder.FakeBuilder{BlockCh: block}, func(cfg *config.Config) {
+		cfg.MaxActiveJobsPerClient = 1
+	})
+	defer cancel()
+
+	do := func(method, path string, header http.Header, body []byte) (int, string, string) {
+		t.Helper()
+		req, err := http.NewRequest(method, ts.URL+path, bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		req.Header.Set(cfg.AuthHeader, cfg.Token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		raw, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, resp.Header.Get("Retry-After"), string(raw)
+	}
+	// Both uploads are opened while the client has no jobs.
+	bundle := validBundleBytes(t, "ok")
+	sum := sha256.Sum256(bundle)
+	var ids []string
+	for range 2 {
+		status, _, body := do(http.MethodPost, "/v1/uploads", nil, []byte(fmt.Sprintf(`{"size":%d,"sha256":"%x"}`, len(bundle), sum)))
+		if status != http.StatusCreated {
+			t.Fatalf("create upload = %d body=%s", status, body)
+		}
+		var up job.Upload
+		if err := json.Unmarshal([]byte(body), &up); err != nil {
+			t.Fatal(err)
+		}
+		if status, _, body := do(http.MethodPatch, "/v1/uploads/"+up.ID, http.Header{job.UploadOffsetHeader: {"0"}}, bundle); status != http.StatusOK {
+			t.Fatalf("append = %d body=%s", status, body)
+		}
+		ids = append(ids, up.ID)
+	}
+
+	if status, _, body := do(http.MethodPost, "/v1/uploads/"+ids[0]+"/finalize", nil, nil); status != http.StatusAccepted {
+		t.Fatalf("first finalize = %d body=%s", status, body)
+	}
+	status, retryAfter, body := do(http.MethodPost, "/v1/uploads/"+ids[1]+"/finalize", nil, nil)
+	if status != http.StatusTooManyRequests || retryAfter == "" || !strings.Contains(body, "queued or running jobs") {
+		t.Fatalf("second finalize = %d retry-after=%q body=%s, want 429", status, retryAfter, body)
+	}
+	if status, _, _ := do(http.MethodGet, "/v1/uploads/"+ids[1], nil, nil); status != http.StatusOK {
+		t.Fatalf("upload turned away by the quota was removed: %d", status)
+	}
+	if status, _, body := do(http.MethodPost, "/v1/uploads", nil, []byte(fmt.Sprintf(`{"size":%d,"sha256":"%x"}`, len(bundle), sum))); status != http.StatusTooManyRequests {
+		t.Fatalf("create upload at the quota = %d body=%s, want 429", status, body)
+	}
+}
+
+func TestSubmitQuota_RateLimitPerClient(t *testing.T) {
+	ts, cfg, _, cancel := newTestServerWithConfig(t, &builder.FakeBuilder{}, func(cfg *config.Config) {
+		cfg.SubmitRateLimit = 2
+	})
+	defer cancel()
+
+	submitBundle(t, ts.URL, cfg, validBundleBytes(t, "ok"))
+	submitBundle(t, ts.URL, cfg, validBundleBytes(t, "ok"))
+	status, retryAfter, body := postBundle(t, ts.URL, cfg, validBundleBytes(t, "ok"))
+	if status != http.StatusTooManyRequests {
+		t.Fatalf("third submit = %d body=%s", status, body)
+	}
+	if secs, err := strconv.Atoi(retryAfter); err != nil || secs < 1 || secs > 30 {
+		t.Fatalf("Retry-After = %q", retryAfter)
+	}
+
+	resp := authGet(t, ts.URL+"/v1/jobs", cfg)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("reads are not submit limited, got %d", resp.StatusCode)
+	}
+}
+
+func TestSubmitQuota_StorageFallsBackToFixedRetryAfter(t *testing.T) {
+	cfg := config.Default()
+	cfg.BaseDir = t.TempDir()
+	cfg.Token = "secret"
+	cfg.MaxStorageBytesPerClient = 1
+	cfg.RetentionInterval = 0
+	// The manager is not started: its reap loop needs a retention interval.
+	mgr := queue.New(cfg, store.New(cfg), &builder.FakeBuilder{})
+	ts := httptest.NewServer(New(cfg, mgr).Handler())
+	defer ts.Close()
+
+	submitBundle(t, ts.URL, cfg, validBundleBytes(t, "ok"))
+	status, retryAfter, body := postBundle(t, ts.URL, cfg, validBundleBytes(t, "ok"))
+	if status != http.StatusTooManyRequests || !strings.Contains(body, "stores") {
+		t.Fatalf("submit over the storage quota = %d body=%s", status, body)
+	}
+	if want := strconv.Itoa(int(storageQuotaRetryAfter.Seconds())); retryAfter != want {
+		t.Fatalf("Retry-After = %q, want %s", retryAfter, want)
+	}
+}
+
+// postBundle submits bundle and returns the status, Retry-After header and
+// body without requiring success.
+func postBundle(t *testing.T, baseURL string, cfg config.Config, bundle []byte) (int, string, string) {
+	t.Helper()
+	body, contentType := multipartBody(t, bundle)
+	req, err := http.NewRequest(http.MethodPost, baseURL+"/v1/jobs", &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set(cfg.AuthHeader, cfg.Token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, resp.Header.Get("Retry-After"), string(raw)
+}
@@ -2,102 +2,280 @@ package server
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net"
+	"io"
+	"log"
 	"net/http"
 	"os"
 	"os/exec"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
 
+	"github.com/mblsha/spadeforge/internal/authz"
+	"github.com/mblsha/spadeforge/internal/buildinfo"
+	"github.com/mblsha/spadeforge/internal/clientdefaults"
 	"github.com/mblsha/spadeforge/internal/config"
+	"github.com/mblsha/spadeforge/internal/doctor"
+	"github.com/mblsha/spadeforge/internal/flashimage"
+	"github.com/mblsha/spadeforge/internal/httpmw"
 	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/logx"
+	"github.com/mblsha/spadeforge/internal/manifest"
+	"github.com/mblsha/spadeforge/internal/openapi"
 	"github.com/mblsha/spadeforge/internal/queue"
+	"github.com/mblsha/spadeforge/internal/sse"
+	"github.com/mblsha/spadeforge/internal/upload"
+	"github.com/mblsha/spadeforge/internal/websocket"
 )
 
 type API struct {
 	cfg     config.Config
 	manager *queue.Manager
 	mux     *http.ServeMux
+	limiter *httpmw.RateLimiter
+	// submitLimiter rate-limits job submissions per client.
+	submitLimiter *httpmw.RateLimiter
+	uploads       *upload.Store
+	defaults      *clientdefaults.Store
+	spec          *openapi.Spec
+
+	// finalizeMu makes the quota check and the submit of a finalized
+	// upload one step, so concurrent finalizes cannot overrun a quota.
+	finalizeMu sync.Mutex
 }
 
 var execCommand = exec.Command
 
+var hlog = logx.For(logx.ModuleHTTP)
+
 func New(cfg config.Config, manager *queue.Manager) *API {
-	a := &API{cfg: cfg, manager: manager, mux: http.NewServeMux()}
+	a := &API{
+		cfg:           cfg,
+		manager:       manager,
+		mux:           http.NewServeMux(),
+		limiter:       httpmw.NewRateLimiter(cfg.RateLimit, cfg.RateLimitBurst),
+		submitLimiter: newSubmitLimiter(cfg.SubmitRateLimit, cfg.SubmitRateBurst),
+		uploads:       upload.New(cfg.UploadsDir(), cfg.MaxUploadBytes, cfg.UploadTTL),
+		defaults:      clientdefaults.New(cfg.ClientDefaultsPath()),
+		spec:          openapi.New("spadeforge", buildinfo.Get().Version, cfg.AuthHeader),
+	}
 	a.routes()
 	return a
 }
 
+// Handler wraps the routes in the shared middleware. Request lines are
+// logged at info with SPADEFORGE_ACCESS_LOG, otherwise at debug so they can
+// be switched on at runtime via the http log module.
 func (a *API) Handler() http.Handler {
-	return a.mux
+	accessLog := httpmw.AccessLog(hlog.Debugf)
+	if a.cfg.AccessLog {
+		accessLog = httpmw.AccessLog(hlog.Infof)
+	}
+	chaos := httpmw.Chaos(httpmw.ChaosConfig{
+		ErrorRate: a.cfg.ChaosErrorRate,
+		SlowRate:  a.cfg.ChaosSlowRate,
+		DropRate:  a.cfg.ChaosDropRate,
+		MaxDelay:  a.cfg.ChaosMaxDelay,
+	})
+	return httpmw.Chain(a.mux, httpmw.Recover(), accessLog, chaos, httpmw.Gzip())
 }
 
 func (a *API) routes() {
 	a.mux.HandleFunc("GET /healthz", a.handleHealthz)
-	a.mux.Handle("POST /v1/jobs", a.guard(http.HandlerFunc(a.handleSubmitJob)))
-	a.mux.Handle("GET /v1/jobs/{id}", a.guard(http.HandlerFunc(a.handleGetJob)))
-	a.mux.Handle("GET /v1/jobs/{id}/artifacts", a.guard(http.HandlerFunc(a.handleGetArtifacts)))
-	a.mux.Handle("GET /v1/jobs/{id}/log", a.guard(http.HandlerFunc(a.handleGetLog)))
-	a.mux.Handle("GET /v1/jobs/{id}/tail", a.guard(http.HandlerFunc(a.handleGetTail)))
-	a.mux.Handle("GET /v1/jobs/{id}/diagnostics", a.guard(http.HandlerFunc(a.handleGetDiagnostics)))
-	a.mux.Handle("GET /v1/jobs/{id}/events", a.guard(http.HandlerFunc(a.handleGetEvents)))
-	a.mux.Handle("POST /v1/jobs/{id}/kill", a.guard(http.HandlerFunc(a.handleKillJob)))
-	a.mux.Handle("POST /v1/kill-all-vivado", a.guard(http.HandlerFunc(a.handleKillAllVivado)))
-}
-
-func (a *API) guard(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if err := a.checkAllowlist(r); err != nil {
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
-			return
-		}
-		if err := a.checkToken(r); err != nil {
-			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
-			return
-		}
-		next.ServeHTTP(w, r)
+	a.spec.Add("GET /healthz", openapi.Op{Summary: "Liveness check", Response: map[string]string{}, Public: true})
+	a.mux.Handle("GET /openapi.json", a.spec.Handler())
+	a.spec.Add("GET /openapi.json", openapi.Op{Summary: "This OpenAPI document", ContentType: "application/json", Public: true})
+
+	a.handle("GET /metrics", authz.ScopeRead, a.manager.Metrics().Handler(), openapi.Op{Summary: "Prometheus metrics", ContentType: "text/plain"})
+	a.handle("GET /v1/version", authz.ScopeRead, http.HandlerFunc(a.handleVersion), openapi.Op{Summary: "Server version", Response: buildinfo.Info{}})
+	a.handle("GET /v1/me", authz.ScopeRead, http.HandlerFunc(a.handleGetMe), openapi.Op{Summary: "The caller's identity, scopes and stored submission defaults", Response: job.Me{}})
+	a.handle("PUT /v1/me/defaults", authz.ScopeSubmit, http.HandlerFunc(a.handleSetDefaults), openapi.Op{Summary: "Replace the caller's stored submission defaults", Request: job.ClientDefaults{}, Response: job.Me{}})
+	a.handle("POST /v1/jobs", authz.ScopeSubmit, a.limitSubmits(http.HandlerFunc(a.handleSubmitJob)), openapi.Op{
+		Summary: "Submit a source bundle",
+		Form:    []string{"submitter", "no_cache"},
+		Files:   []string{"bundle"},
+		Status:  http.StatusAccepted, Response: job.SubmitResponse{},
+	})
+	a.handle("POST /v1/uploads", authz.ScopeSubmit, a.limitUploads(http.HandlerFunc(a.handleCreateUpload)), openapi.Op{
+		Summary: "Start a resumable bundle upload", Request: job.UploadRequest{},
+		Status: http.StatusCreated, Response: job.Upload{},
+	})
+	a.handle("GET /v1/uploads/{id}", authz.ScopeSubmit, http.HandlerFunc(a.handleGetUpload), openapi.Op{Summary: "Resumable upload offset", Response: job.Upload{}})
+	a.handle("PATCH /v1/uploads/{id}", authz.ScopeSubmit, http.HandlerFunc(a.handleAppendUpload), openapi.Op{
+		Summary: "Append a chunk at the Upload-Offset header", Response: job.Upload{},
+	})
+	a.handle("DELETE /v1/uploads/{id}", authz.ScopeSubmit, http.HandlerFunc(a.handleDeleteUpload), openapi.Op{Summary: "Abort a resumable upload", Status: http.StatusNoContent})
+	a.handle("POST /v1/uploads/{id}/finalize", authz.ScopeSubmit, http.HandlerFunc(a.handleFinalizeUpload), openapi.Op{
+		Summary: "Verify a complete upload and submit it", Status: http.StatusAccepted, Response: job.SubmitResponse{},
+	})
+	a.handle("GET /v1/jobs", authz.ScopeRead, http.HandlerFunc(a.handleListJobs), openapi.Op{
+		Summary:  "List jobs, newest first",
+		Query:    []openapi.Param{{Name: "state", Description: "repeatable or comma-separated"}, {Name: "limit", Type: "integer"}, {Name: "offset", Type: "integer"}},
+		Response: job.JobList{},
+	})
+	a.handle("POST /v1/jobs/status", authz.ScopeRead, http.HandlerFunc(a.handleJobsStatus), openapi.Op{
+		Summary: "Status of several jobs", Request: job.StatusRequest{}, Response: job.StatusResponse{},
+	})
+	a.handle("GET /v1/events", authz.ScopeRead, http.HandlerFunc(a.handleGetAllEvents), openapi.Op{
+		Summary: "Events of every job",
+		Query:   []openapi.Param{{Name: "since", Type: "integer"}, {Name: "state", Description: "repeatable or comma-separated"}},
+		Events:  job.Event{},
+	})
+	a.handle("GET /v1/jobs/{id}", authz.ScopeRead, http.HandlerFunc(a.handleGetJob), openapi.Op{Summary: "Job state", Response: job.Record{}})
+	a.handle("GET /v1/jobs/{id}/artifacts", authz.ScopeRead, http.HandlerFunc(a.handleGetArtifacts), openapi.Op{Summary: "Job artifacts", ContentType: "application/zip"})
+	a.handle("GET /v1/jobs/{id}/flash-image", authz.ScopeRead, http.HandlerFunc(a.handleGetFlashImage), openapi.Op{
+		Summary:     "The job's bitstream converted to an SPI flash image",
+		Query:       []openapi.Param{{Name: "format", Description: "bin (default) or mcs"}, {Name: "offset", Description: "flash address for mcs, decimal or 0x hex"}},
+		ContentType: "application/octet-stream",
+	})
+	a.handle("GET /v1/jobs/{id}/bundle", authz.ScopeRead, http.HandlerFunc(a.handleGetBundle), openapi.Op{Summary: "The submitted bundle", ContentType: "application/zip"})
+	a.handle("GET /v1/jobs/{id}/log", authz.ScopeRead, http.HandlerFunc(a.handleGetLog), openapi.Op{
+		Summary:     "Build log",
+		Query:       []openapi.Param{{Name: "file", Description: "console.log or vivado.log"}, {Name: "format", Description: "text or gz"}, {Name: "since", Description: "RFC 3339 time"}},
+		ContentType: "text/plain",
+	})
+	a.handle("GET /v1/jobs/{id}/log/search", authz.ScopeRead, http.HandlerFunc(a.handleSearchLog), openapi.Op{
+		Summary:  "Search a build log",
+		Query:    []openapi.Param{{Name: "q", Description: "regular expression"}, {Name: "file"}, {Name: "context", Type: "integer"}, {Name: "max", Type: "integer"}},
+		Response: job.LogSearchResult{},
+	})
+	a.handle("GET /v1/jobs/{id}/tail", authz.ScopeRead, http.HandlerFunc(a.handleGetTail), openapi.Op{
+		Summary: "Last lines of the console log", Query: []openapi.Param{{Name: "lines", Type: "integer"}}, ContentType: "text/plain",
+	})
+	a.handle("GET /v1/jobs/{id}/diagnostics", authz.ScopeRead, http.HandlerFunc(a.handleGetDiagnostics), openapi.Op{Summary: "Parsed diagnostics", Response: job.DiagnosticsReport{}})
+	a.handle("GET /v1/jobs/{id}/events", authz.ScopeRead, http.HandlerFunc(a.handleGetEvents), openapi.Op{
+		Summary: "Job events", Query: []openapi.Param{{Name: "since", Type: "integer"}}, Events: job.Event{},
+	})
+	a.handle("GET /v1/jobs/{id}/events/ws", authz.ScopeRead, http.HandlerFunc(a.handleGetEventsWS), openapi.Op{
+		Summary: "Job events over WebSocket, one Event per message", Query: []openapi.Param{{Name: "since", Type: "integer"}}, Status: http.StatusSwitchingProtocols,
+	})
+	a.handle("GET /v1/jobs/{id}/workdir", authz.ScopeRead, http.HandlerFunc(a.handleListWorkDir), openapi.Op{
+		Summary: "List a preserved work dir",
+		Response: struct {
+			JobID string             `json:"job_id"`
+			Items []job.WorkDirEntry `json:"items"`
+		}{},
+	})
+	a.handle("GET /v1/jobs/{id}/workdir/{path...}", authz.ScopeRead, http.HandlerFunc(a.handleGetWorkDirFile), openapi.Op{Summary: "A file from a preserved work dir", ContentType: "application/octet-stream"})
+	a.handle("POST /v1/jobs/{id}/cancel", authz.ScopeSubmit, http.HandlerFunc(a.handleCancelJob), openapi.Op{Summary: "Cancel a job", Response: job.Record{}})
+	a.handle("POST /v1/jobs/{id}/kill", authz.ScopeAdmin, http.HandlerFunc(a.handleKillJob), openapi.Op{Summary: "Kill a running build", Status: http.StatusAccepted, Response: map[string]string{}})
+	a.handle("POST /v1/jobs/{id}/baseline", authz.ScopeAdmin, http.HandlerFunc(a.handleSetBaseline), openapi.Op{Summary: "Make a job its project's baseline", Response: job.Record{}})
+	a.handle("POST /v1/jobs/{id}/pin", authz.ScopeAdmin, http.HandlerFunc(a.handlePinJob), openapi.Op{Summary: "Pin a job", Response: job.Record{}})
+	a.handle("DELETE /v1/jobs/{id}/pin", authz.ScopeAdmin, http.HandlerFunc(a.handlePinJob), openapi.Op{Summary: "Unpin a job", Response: job.Record{}})
+	a.handle("GET /v1/runs/{id}", authz.ScopeRead, http.HandlerFunc(a.handleGetRun), openapi.Op{Summary: "The run a build belongs to", Response: job.Run{}})
+	a.handle("POST /v1/kill-all-vivado", authz.ScopeAdmin, http.HandlerFunc(a.handleKillAllVivado), openapi.Op{Summary: "Kill every Vivado process", Response: map[string]string{}})
+	a.handle("GET /v1/projects/{name}/diagnostics/summary", authz.ScopeRead, http.HandlerFunc(a.handleProjectDiagnosticsSummary), openapi.Op{
+		Summary: "Recurring diagnostics across a project's builds", Query: []openapi.Param{{Name: "limit", Type: "integer"}}, Response: job.ProjectDiagnosticsSummary{},
+	})
+	a.handle("GET /v1/projects/{name}/baseline", authz.ScopeRead, http.HandlerFunc(a.handleGetBaseline), openapi.Op{Summary: "A project's baseline job", Response: job.Record{}})
+	feed := openapi.Op{Summary: "Queue snapshot for wallboards, with an ETag", Response: job.Feed{}, Public: a.cfg.FeedPublic}
+	if a.cfg.FeedPublic {
+		a.mux.Handle("GET /v1/feed.json", httpmw.Chain(http.HandlerFunc(a.handleFeed),
+			httpmw.Allowlist(a.cfg.Allowlist),
+			httpmw.RateLimit(a.limiter),
+		))
+		a.spec.Add("GET /v1/feed.json", feed)
+	} else {
+		a.handle("GET /v1/feed.json", authz.ScopeRead, http.HandlerFunc(a.handleFeed), feed)
+	}
+	a.handle("GET /v1/storage", authz.ScopeRead, http.HandlerFunc(a.handleStorage), openapi.Op{Summary: "Storage usage and retention", Response: queue.StorageUsage{}})
+	a.handle("GET /v1/stats/energy", authz.ScopeRead, http.HandlerFunc(a.handleEnergyStats), openapi.Op{Summary: "Build energy per project", Response: job.EnergyStats{}})
+	a.handle("GET /v1/admin/selftest", authz.ScopeAdmin, http.HandlerFunc(a.handleSelfTest), openapi.Op{Summary: "Run the doctor checks", Response: doctor.Report{}})
+	a.handle("GET /v1/admin/metrics", authz.ScopeAdmin, http.HandlerFunc(a.handleMetrics), openapi.Op{
+		Summary: "Event subscriber and disk counters",
+		Response: struct {
+			Events queue.EventStats `json:"events"`
+			Disk   queue.DiskStatus `json:"disk"`
+		}{},
 	})
+	a.handle("GET /v1/admin/loglevel", authz.ScopeAdmin, http.HandlerFunc(a.handleGetLogLevel), openapi.Op{Summary: "Log verbosity", Response: logx.Settings{}})
+	a.handle("POST /v1/admin/loglevel", authz.ScopeAdmin, http.HandlerFunc(a.handleSetLogLevel), openapi.Op{Summary: "Change log verbosity", Request: logx.Settings{}, Response: logx.Settings{}})
 }
 
-func (a *API) checkToken(r *http.Request) error {
-	if strings.TrimSpace(a.cfg.Token) == "" {
-		return nil
-	}
-	if strings.TrimSpace(r.Header.Get(a.cfg.AuthHeader)) != a.cfg.Token {
-		return errors.New("invalid token")
-	}
-	return nil
+// handle registers a route guarded for tokens with scope and documents it
+// in the OpenAPI spec.
+func (a *API) handle(pattern string, scope authz.Scope, h http.Handler, op openapi.Op) {
+	a.mux.Handle(pattern, a.guard(scope, h))
+	a.spec.Add(pattern, op)
 }
 
-func (a *API) checkAllowlist(r *http.Request) error {
-	if !a.cfg.AllowlistEnabled() {
-		return nil
-	}
-	ip, err := remoteIP(r.RemoteAddr)
-	if err != nil {
-		return err
-	}
-	for _, allow := range a.cfg.Allowlist {
-		if allowEntryMatches(allow, ip) {
-			return nil
-		}
-	}
-	return fmt.Errorf("remote ip %s is not allowed", ip.String())
+// guard applies the allowlist, rate limit and token checks every /v1
+// route shares; named tokens also need scope.
+func (a *API) guard(scope authz.Scope, next http.Handler) http.Handler {
+	return httpmw.Chain(next,
+		httpmw.Allowlist(a.cfg.Allowlist),
+		httpmw.RateLimit(a.limiter),
+		httpmw.ScopedTokenAuth(a.cfg.AuthHeader, a.cfg.Token, a.cfg.Tokens, scope),
+	)
 }
 
 func (a *API) handleHealthz(w http.ResponseWriter, _ *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
+// handleVersion reports the version, commit and build date stamped in by
+// `spadeforge release`, and where the CLIs can fetch that release.
+func (a *API) handleVersion(w http.ResponseWriter, _ *http.Request) {
+	info := buildinfo.Get()
+	info.ReleaseURL = a.cfg.ReleaseURL
+	writeJSON(w, http.StatusOK, info)
+}
+
+// handleStorage reports the space stored jobs take and the retention
+// settings that bound it.
+func (a *API) handleStorage(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, a.manager.StorageUsage())
+}
+
+// handleMetrics reports server counters as JSON.
+func (a *API) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"events": a.manager.EventStats(), "disk": a.manager.DiskStatus()})
+}
+
+func (a *API) handleGetLogLevel(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, logx.Current())
+}
+
+// handleSetLogLevel changes log verbosity without a restart. The body is a
+// partial logx.Settings: an omitted level keeps the global level and module
+// entries merge into the existing filters ("default" clears one).
+func (a *API) handleSetLogLevel(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	var req logx.Settings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid loglevel request: " + err.Error()})
+		return
+	}
+	if err := logx.Apply(req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	current := logx.Current()
+	log.Printf("log level set to %s modules=%v remote=%s", current.Level, current.Modules, r.RemoteAddr)
+	writeJSON(w, http.StatusOK, current)
+}
+
+// handleSelfTest runs the same checks as `spadeforge doctor`. Any failed
+// check turns the response into a 503 so monitors can alert on it.
+func (a *API) handleSelfTest(w http.ResponseWriter, r *http.Request) {
+	report := doctor.Run(r.Context(), doctor.ForgeChecks(a.cfg, nil))
+	status := http.StatusOK
+	if report.Failed() {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
+
 func (a *API) handleSubmitJob(w http.ResponseWriter, r *http.Request) {
-	r.Body = http.MaxBytesReader(w, r.Body, a.cfg.MaxUploadBytes)
-	if err := r.ParseMultipartForm(32 << 20); err != nil {
-		writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": err.Error()})
+	if !httpmw.ParseUpload(w, r, "bundle", a.cfg.MaxUploadBytes) {
 		return
 	}
 	file, _, err := r.FormFile("bundle")
@@ -107,17 +285,92 @@ func (a *API) handleSubmitJob(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	rec, err := a.manager.Submit(r.Context(), file)
+	submitter, err := submitterIdentity(r)
 	if err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
+	noCache := false
+	if raw := strings.TrimSpace(r.FormValue("no_cache")); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "no_cache must be a boolean"})
+			return
+		}
+		noCache = v
+	}
+	rec, err := a.manager.SubmitWithOptions(r.Context(), queue.SubmitOptions{Submitter: submitter, NoCache: noCache, TokenName: authz.Name(r.Context()), ClientIP: httpmw.ClientKey(r)}, file)
+	a.writeSubmitted(w, rec, err)
+}
 
-	writeJSON(w, http.StatusAccepted, map[string]string{
-		"job_id":  rec.ID,
-		"project": rec.Manifest.Project,
-		"state":   string(rec.State),
-	})
+// writeSubmitted answers a bundle submission with the new job, or with the
+// reason the bundle was rejected.
+func (a *API) writeSubmitted(w http.ResponseWriter, rec *job.Record, err error) {
+	if err != nil {
+		var verr *manifest.ValidationError
+		if errors.As(err, &verr) {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error(), "details": verr.Errors})
+			return
+		}
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	resp := job.SubmitResponse{
+		JobID:    rec.ID,
+		Project:  rec.Manifest.Project,
+		State:    job.StateQueued,
+		Manifest: rec.Manifest,
+		Warnings: rec.Warnings,
+		Limits:   a.limits(),
+	}
+	if snapshot, ok := a.manager.Get(rec.ID); ok {
+		resp.State = snapshot.State
+		resp.Manifest = snapshot.Manifest
+		resp.Warnings = snapshot.Warnings
+		resp.CachedFrom = snapshot.CachedFrom
+	}
+	resp.QueuePosition, resp.JobsAhead, _ = a.manager.QueuePosition(rec.ID)
+	if a.cfg.BuildCache {
+		if resp.CachedFrom != "" {
+			w.Header().Set("X-Cache", "hit")
+		} else {
+			w.Header().Set("X-Cache", "miss")
+		}
+	}
+	writeJSON(w, http.StatusAccepted, resp)
+}
+
+// submitterIdentity returns the submitter form field, or the client IP when
+// it is absent, for fair queueing between users sharing the builder.
+func submitterIdentity(r *http.Request) (string, error) {
+	return checkSubmitter(r, r.FormValue("submitter"))
+}
+
+// checkSubmitter validates a submitter given outside the multipart form,
+// falling back to the client IP like submitterIdentity.
+func checkSubmitter(r *http.Request, submitter string) (string, error) {
+	submitter = strings.TrimSpace(submitter)
+	if submitter == "" {
+		if ip, err := httpmw.RemoteIP(r.RemoteAddr); err == nil {
+			return ip.String(), nil
+		}
+		return r.RemoteAddr, nil
+	}
+	if len(submitter) > 128 || strings.IndexFunc(submitter, unicode.IsControl) >= 0 {
+		return "", errors.New("submitter must be at most 128 printable characters")
+	}
+	return submitter, nil
+}
+
+func (a *API) limits() job.Limits {
+	return job.Limits{
+		MaxUploadBytes:         a.cfg.MaxUploadBytes,
+		MaxExtractedFiles:      a.cfg.MaxExtractedFiles,
+		MaxExtractedTotalBytes: a.cfg.MaxExtractedTotalBytes,
+		MaxExtractedFileBytes:  a.cfg.MaxExtractedFileBytes,
+		WorkerTimeoutSeconds:   int64(a.cfg.WorkerTimeout / time.Second),
+	}
 }
 
 func (a *API) handleGetJob(w http.ResponseWriter, r *http.Request) {
@@ -130,6 +383,94 @@ func (a *API) handleGetJob(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, rec)
 }
 
+// handleGetRun reports the run a job belongs to, rolling up its builds,
+// flashes and hardware tests.
+func (a *API) handleGetRun(w http.ResponseWriter, r *http.Request) {
+	run, err := a.manager.Run(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "run not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, run)
+}
+
+// handleListJobs pages through every job the server knows, newest first,
+// optionally filtered by ?state=FAILED,CANCELED.
+func (a *API) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	opts := queue.ListOptions{Limit: job.DefaultListLimit}
+	for key, dst := range map[string]*int{"limit": &opts.Limit, "offset": &opts.Offset} {
+		if raw := strings.TrimSpace(q.Get(key)); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 0 || (key == "limit" && n == 0) {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid " + key + " query value"})
+				return
+			}
+			*dst = n
+		}
+	}
+	opts.Limit = min(opts.Limit, job.MaxListLimit)
+	states, err := parseStates(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	opts.States = states
+
+	items, total := a.manager.ListJobs(opts)
+	writeJSON(w, http.StatusOK, job.JobList{Items: items, Total: total, Limit: opts.Limit, Offset: opts.Offset})
+}
+
+// parseStates reads the repeatable, comma-separated state query filter.
+func parseStates(r *http.Request) ([]job.State, error) {
+	var states []job.State
+	for _, raw := range r.URL.Query()["state"] {
+		for _, name := range strings.Split(raw, ",") {
+			if strings.TrimSpace(name) == "" {
+				continue
+			}
+			state, err := job.ParseState(name)
+			if err != nil {
+				return nil, err
+			}
+			states = append(states, state)
+		}
+	}
+	return states, nil
+}
+
+// handleJobsStatus returns several job records in one round trip for
+// clients tracking many builds at once.
+func (a *API) handleJobsStatus(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	var req job.StatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid status request: " + err.Error()})
+		return
+	}
+	if len(req.JobIDs) > job.MaxStatusJobIDs {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("too many job_ids: %d > %d", len(req.JobIDs), job.MaxStatusJobIDs)})
+		return
+	}
+
+	resp := job.StatusResponse{Jobs: make([]job.Record, 0, len(req.JobIDs))}
+	seen := map[string]struct{}{}
+	for _, id := range req.JobIDs {
+		id = strings.TrimSpace(id)
+		if _, dup := seen[id]; dup || id == "" {
+			continue
+		}
+		seen[id] = struct{}{}
+		rec, ok := a.manager.Get(id)
+		if !ok {
+			resp.Missing = append(resp.Missing, id)
+			continue
+		}
+		resp.Jobs = append(resp.Jobs, *rec)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
 func (a *API) handleGetArtifacts(w http.ResponseWriter, r *http.Request) {
 	jobID := r.PathValue("id")
 	if _, ok := a.manager.Get(jobID); !ok {
@@ -149,8 +490,80 @@ func (a *API) handleGetArtifacts(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/zip")
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", jobID+"-artifacts.zip"))
-	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write(payload.Bytes())
+	http.ServeContent(w, r, jobID+"-artifacts.zip", time.Time{}, bytes.NewReader(payload.Bytes()))
+}
+
+// handleGetFlashImage converts the job's bitstream to a .bin or .mcs flash
+// image on demand.
+func (a *API) handleGetFlashImage(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	format, err := flashimage.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	var offset uint32
+	if raw := strings.TrimSpace(r.URL.Query().Get("offset")); raw != "" {
+		n, err := strconv.ParseUint(raw, 0, 32)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "offset must be a 32-bit address, decimal or 0x hex"})
+			return
+		}
+		offset = uint32(n)
+	}
+
+	var payload bytes.Buffer
+	if err := a.manager.WriteFlashImage(jobID, format, offset, &payload); err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			status = http.StatusNotFound
+		case errors.Is(err, queue.ErrNotSucceeded):
+			status = http.StatusConflict
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	name := jobID + "-design." + format
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(payload.Bytes()))
+}
+
+// handleGetBundle returns the request zip as submitted, so a job's exact
+// inputs can be rebuilt locally.
+func (a *API) handleGetBundle(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	if _, ok := a.manager.Get(jobID); !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "job not found"})
+		return
+	}
+	f, err := a.manager.OpenBundle(jobID)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "request bundle is no longer available"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", jobID+"-bundle.zip"))
+	file, ok := f.(*os.File)
+	if !ok {
+		// Bundles encrypted at rest are decrypted as they stream, so they
+		// cannot serve ranges.
+		_, _ = io.Copy(w, f)
+		return
+	}
+	fi, err := file.Stat()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	http.ServeContent(w, r, jobID+"-bundle.zip", fi.ModTime(), file)
 }
 
 func (a *API) handleGetLog(w http.ResponseWriter, r *http.Request) {
@@ -159,14 +572,160 @@ func (a *API) handleGetLog(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusNotFound, map[string]string{"error": "job not found"})
 		return
 	}
-	raw, err := a.manager.ReadConsoleLog(jobID)
+	q := r.URL.Query()
+	name := strings.TrimSpace(q.Get("file"))
+	if name == "" {
+		name = "console.log"
+	}
+	format := strings.TrimSpace(q.Get("format"))
+	if format != "" && format != "text" && format != "gz" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid format; expected text or gz"})
+		return
+	}
+	var since time.Time
+	if raw := strings.TrimSpace(q.Get("since")); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid since query value; expected RFC3339"})
+			return
+		}
+		since = t
+	}
+	f, offset, err := a.manager.OpenLogSince(jobID, name, since)
+	if errors.Is(err, queue.ErrUnknownLog) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
 		return
 	}
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	defer f.Close()
+	// X-Log-Offset is where in the log the body starts, so clients can
+	// tell a since-filtered tail from the whole log.
+	w.Header().Set("X-Log-Offset", strconv.FormatInt(offset, 10))
+
+	if format != "gz" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.Copy(w, f)
+		return
+	}
+	// Vivado logs are tens of MB of repetitive text; compress while streaming
+	// rather than buffering. The body is a .gz file, not a Content-Encoding,
+	// so clients save it as-is.
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", jobID+"-"+name+".gz"))
 	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write(raw)
+	zw := gzip.NewWriter(w)
+	zw.Name = name
+	_, _ = io.Copy(zw, f)
+	_ = zw.Close()
+}
+
+func (a *API) handleSearchLog(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	if _, ok := a.manager.Get(jobID); !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "job not found"})
+		return
+	}
+	q := r.URL.Query()
+	opts := queue.LogSearchOptions{
+		File:    strings.TrimSpace(q.Get("file")),
+		Query:   q.Get("q"),
+		Context: queue.DefaultLogSearchContext,
+	}
+	for key, dst := range map[string]*int{"context": &opts.Context, "max": &opts.MaxMatches} {
+		if raw := strings.TrimSpace(q.Get(key)); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid " + key + " query value"})
+				return
+			}
+			*dst = n
+		}
+	}
+	result, err := a.manager.SearchLog(jobID, opts)
+	switch {
+	case errors.Is(err, queue.ErrInvalidLogSearch), errors.Is(err, queue.ErrUnknownLog):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	case errors.Is(err, os.ErrNotExist):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	case err != nil:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (a *API) handleProjectDiagnosticsSummary(w http.ResponseWriter, r *http.Request) {
+	limit := queue.DefaultProjectSummaryBuilds
+	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid limit query value"})
+			return
+		}
+		limit = n
+	}
+	summary, err := a.manager.ProjectDiagnosticsSummary(r.PathValue("name"), limit)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "no finished builds for project"})
+		return
+	}
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// handleSetBaseline makes the job its project's baseline for later builds.
+func (a *API) handleSetBaseline(w http.ResponseWriter, r *http.Request) {
+	rec, err := a.manager.SetBaseline(r.PathValue("id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			status = http.StatusNotFound
+		case errors.Is(err, queue.ErrInvalidBaseline):
+			status = http.StatusConflict
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, rec)
+}
+
+// handlePinJob pins (POST) or unpins (DELETE) a job, which keeps it from
+// retention and cancels a pending expiry.
+func (a *API) handlePinJob(w http.ResponseWriter, r *http.Request) {
+	pin := a.manager.PinJob
+	if r.Method == http.MethodDelete {
+		pin = a.manager.UnpinJob
+	}
+	rec, err := pin(r.PathValue("id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, os.ErrNotExist) {
+			status = http.StatusNotFound
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, rec)
+}
+
+// handleEnergyStats reports estimated build energy per project.
+func (a *API) handleEnergyStats(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, a.manager.EnergyStats())
+}
+
+func (a *API) handleGetBaseline(w http.ResponseWriter, r *http.Request) {
+	rec, ok := a.manager.ProjectBaseline(r.PathValue("name"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "no baseline for project"})
+		return
+	}
+	writeJSON(w, http.StatusOK, rec)
 }
 
 func (a *API) handleGetTail(w http.ResponseWriter, r *http.Request) {
@@ -210,6 +769,40 @@ func (a *API) handleGetDiagnostics(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write(raw)
 }
 
+func (a *API) handleListWorkDir(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	entries, err := a.manager.ListWorkDir(jobID)
+	if err != nil {
+		writeWorkDirError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"job_id": jobID, "items": entries})
+}
+
+func (a *API) handleGetWorkDirFile(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	f, fi, err := a.manager.OpenWorkDirFile(jobID, r.PathValue("path"))
+	if err != nil {
+		writeWorkDirError(w, err)
+		return
+	}
+	defer f.Close()
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fi.Name()))
+	http.ServeContent(w, r, fi.Name(), fi.ModTime(), f)
+}
+
+func writeWorkDirError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	case errors.Is(err, queue.ErrWorkDirNotPreserved):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+}
+
 func (a *API) handleKillAllVivado(w http.ResponseWriter, _ *http.Request) {
 	cmd := killAllVivadoCommand(runtime.GOOS)
 	out, err := cmd.CombinedOutput()
@@ -266,16 +859,35 @@ func (a *API) handleKillJob(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusAccepted, map[string]string{"status": "kill signal sent"})
 }
 
-func (a *API) handleGetEvents(w http.ResponseWriter, r *http.Request) {
+// handleCancelJob answers 200 with the CANCELED record for a queued job, or
+// 202 with the still-running record while the build is being stopped.
+func (a *API) handleCancelJob(w http.ResponseWriter, r *http.Request) {
 	jobID := r.PathValue("id")
-	since := int64(0)
-	if rawSince := strings.TrimSpace(r.URL.Query().Get("since")); rawSince != "" {
-		n, err := strconv.ParseInt(rawSince, 10, 64)
-		if err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid since query value"})
-			return
+	rec, err := a.manager.CancelJob(jobID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			status = http.StatusNotFound
+		case errors.Is(err, queue.ErrJobFinished):
+			status = http.StatusConflict
 		}
-		since = n
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	status := http.StatusOK
+	if !rec.Terminal() {
+		status = http.StatusAccepted
+	}
+	writeJSON(w, status, rec)
+}
+
+func (a *API) handleGetEvents(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	since, err := parseSince(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
 	}
 
 	backlog, ch, cancel, ok := a.manager.SubscribeEvents(jobID, since)
@@ -284,6 +896,8 @@ func (a *API) handleGetEvents(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	defer cancel()
+	hlog.Debugf("[http] events subscribe job=%s since=%d backlog=%d remote=%s", jobID, since, len(backlog), r.RemoteAddr)
+	defer hlog.Debugf("[http] events closed job=%s remote=%s", jobID, r.RemoteAddr)
 
 	flusher, ok := w.(http.Flusher)
 	if !ok {
@@ -297,6 +911,11 @@ func (a *API) handleGetEvents(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("X-Accel-Buffering", "no")
 	w.WriteHeader(http.StatusOK)
 
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", sse.DefaultRetry.Milliseconds()); err != nil {
+		return
+	}
+	flusher.Flush()
+
 	for _, ev := range backlog {
 		if err := writeSSEEvent(w, ev); err != nil {
 			return
@@ -307,7 +926,7 @@ func (a *API) handleGetEvents(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	keepalive := time.NewTicker(15 * time.Second)
+	keepalive := time.NewTicker(a.sseKeepalive())
 	defer keepalive.Stop()
 
 	for {
@@ -317,6 +936,7 @@ func (a *API) handleGetEvents(w http.ResponseWriter, r *http.Request) {
 		case <-keepalive.C:
 			_, _ = w.Write([]byte(": keepalive\n\n"))
 			flusher.Flush()
+			hlog.Tracef("[http] events keepalive job=%s remote=%s", jobID, r.RemoteAddr)
 			if rec, ok := a.manager.Get(jobID); !ok || rec.Terminal() {
 				return
 			}
@@ -335,6 +955,210 @@ func (a *API) handleGetEvents(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleGetAllEvents streams every job's events as SSE, numbered by the
+// server-wide seq, optionally filtered by ?state=RUNNING,FAILED. Unlike the
+// per-job stream it stays open until the client leaves.
+func (a *API) handleGetAllEvents(w http.ResponseWriter, r *http.Request) {
+	since, err := parseSince(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	states, err := parseStates(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "streaming unsupported"})
+		return
+	}
+	wanted := func(ev job.Event) bool {
+		return len(states) == 0 || ev.Type == job.EventDroppedEvents || slices.Contains(states, ev.State)
+	}
+
+	backlog, ch, cancel := a.manager.SubscribeAllEvents(since)
+	defer cancel()
+	hlog.Debugf("[http] events subscribe all since=%d backlog=%d states=%v remote=%s", since, len(backlog), states, r.RemoteAddr)
+	defer hlog.Debugf("[http] events closed all remote=%s", r.RemoteAddr)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", sse.DefaultRetry.Milliseconds()); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	for _, ev := range backlog {
+		if !wanted(ev) {
+			continue
+		}
+		if err := writeSSEEvent(w, ev); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	keepalive := time.NewTicker(a.sseKeepalive())
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			if _, err := w.Write([]byte(": keepalive\n\n")); err != nil {
+				return
+			}
+			flusher.Flush()
+			hlog.Tracef("[http] events keepalive all remote=%s", r.RemoteAddr)
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			if !wanted(ev) {
+				continue
+			}
+			if err := writeSSEEvent(w, ev); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// handleGetEventsWS streams the same events as handleGetEvents, one JSON
+// text message each, for clients behind proxies that handle WebSockets
+// better than SSE. Keepalives are ping frames and the server closes the
+// socket after the terminal event.
+func (a *API) handleGetEventsWS(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	since, err := parseSince(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if !websocket.IsUpgrade(r) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "websocket upgrade required"})
+		return
+	}
+
+	backlog, ch, cancel, ok := a.manager.SubscribeEvents(jobID, since)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "job not found"})
+		return
+	}
+	defer cancel()
+	conn, err := websocket.Accept(w, r)
+	if err != nil {
+		hlog.Debugf("[http] events ws upgrade job=%s remote=%s: %v", jobID, r.RemoteAddr, err)
+		return
+	}
+	hlog.Debugf("[http] events ws subscribe job=%s since=%d backlog=%d remote=%s", jobID, since, len(backlog), r.RemoteAddr)
+	defer hlog.Debugf("[http] events ws closed job=%s remote=%s", jobID, r.RemoteAddr)
+
+	// Reading answers the client's pings and notices when it goes away.
+	clientGone := make(chan struct{})
+	go func() {
+		defer close(clientGone)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	// Give the client a moment to answer the close frame, so it reads the
+	// final events before the connection drops.
+	defer func() {
+		_ = conn.WriteClose(websocket.CloseNormal, "")
+		select {
+		case <-clientGone:
+		case <-time.After(time.Second):
+		}
+		_ = conn.Close(websocket.CloseNormal, "")
+	}()
+
+	for _, ev := range backlog {
+		if err := writeWSEvent(conn, ev); err != nil {
+			return
+		}
+	}
+	if ch == nil {
+		return
+	}
+
+	keepalive := time.NewTicker(a.sseKeepalive())
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-clientGone:
+			return
+		case <-keepalive.C:
+			if err := conn.Ping(); err != nil {
+				return
+			}
+			hlog.Tracef("[http] events ws keepalive job=%s remote=%s", jobID, r.RemoteAddr)
+			if rec, ok := a.manager.Get(jobID); !ok || rec.Terminal() {
+				// The terminal event may still sit in the channel.
+				for {
+					select {
+					case ev, ok := <-ch:
+						if !ok || writeWSEvent(conn, ev) != nil || ev.Terminal() {
+							return
+						}
+					default:
+						return
+					}
+				}
+			}
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := writeWSEvent(conn, ev); err != nil {
+				return
+			}
+			if ev.Terminal() {
+				return
+			}
+		}
+	}
+}
+
+func writeWSEvent(conn *websocket.Conn, ev job.Event) error {
+	raw, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return conn.WriteText(raw)
+}
+
+// parseSince reads the optional since query value of the event streams.
+func parseSince(r *http.Request) (int64, error) {
+	rawSince := strings.TrimSpace(r.URL.Query().Get("since"))
+	if rawSince == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(rawSince, 10, 64)
+	if err != nil {
+		return 0, errors.New("invalid since query value")
+	}
+	return n, nil
+}
+
+func (a *API) sseKeepalive() time.Duration {
+	if a.cfg.SSEKeepalive > 0 {
+		return a.cfg.SSEKeepalive
+	}
+	return 15 * time.Second
+}
+
 func writeSSEEvent(w http.ResponseWriter, ev job.Event) error {
 	raw, err := json.Marshal(ev)
 	if err != nil {
@@ -351,30 +1175,3 @@ func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(v)
 }
-
-func remoteIP(remoteAddr string) (net.IP, error) {
-	host, _, err := net.SplitHostPort(remoteAddr)
-	if err != nil {
-		return nil, fmt.Errorf("parse remote addr: %w", err)
-	}
-	ip := net.ParseIP(host)
-	if ip == nil {
-		return nil, fmt.Errorf("invalid remote ip: %s", host)
-	}
-	return ip, nil
-}
-
-func allowEntryMatches(entry string, ip net.IP) bool {
-	if strings.Contains(entry, "/") {
-		_, cidr, err := net.ParseCIDR(entry)
-		if err != nil {
-			return false
-		}
-		return cidr.Contains(ip)
-	}
-	allowed := net.ParseIP(entry)
-	if allowed == nil {
-		return false
-	}
-	return allowed.Equal(ip)
-}
@@ -2,25 +2,39 @@ package server
 
 import (
 	"archive/zip"
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/buildinfo"
+	"github.com/mblsha/spadeforge/internal/client"
 	"github.com/mblsha/spadeforge/internal/config"
+	"github.com/mblsha/spadeforge/internal/doctor"
+	"github.com/mblsha/spadeforge/internal/httpretry"
 	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/logtime"
+	"github.com/mblsha/spadeforge/internal/logx"
 	"github.com/mblsha/spadeforge/internal/manifest"
 	"github.com/mblsha/spadeforge/internal/queue"
 	"github.com/mblsha/spadeforge/internal/store"
+	"github.com/mblsha/spadeforge/internal/websocket"
 )
 
 func TestHealthz(t *testing.T) {
@@ -37,6 +51,265 @@ func TestHealthz(t *testing.T) {
 	}
 }
 
+func TestOpenAPI_DocumentsRoutesWithoutToken(t *testing.T) {
+	ts, _, _, cancel := newTestServer(t, &builder.FakeBuilder{})
+	defer cancel()
+
+	resp, err := http.Get(ts.URL + "/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var doc struct {
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	for route, method := range map[string]string{
+		"/v1/jobs":                  "post",
+		"/v1/jobs/{id}":             "get",
+		"/v1/jobs/{id}/events":      "get",
+		"/v1/uploads/{id}":          "patch",
+		"/v1/uploads/{id}/finalize": "post",
+		"/v1/admin/selftest":        "get",
+	} {
+		if _, ok := doc.Paths[route][method]; !ok {
+			t.Errorf("%s %s is not documented", method, route)
+		}
+	}
+	for _, name := range []string{"Record", "Event", "DiagnosticsReport", "Upload", "UploadRequest", "Error"} {
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("schema %s is missing", name)
+		}
+	}
+}
+
+func TestFeed_PublicSnapshotWithETag(t *testing.T) {
+	ts, cfg, _, cancel := newTestServerWithConfig(t, &builder.FakeBuilder{}, func(cfg *config.Config) {
+		cfg.FeedPublic = true
+	})
+	defer cancel()
+
+	jobID := submitBundle(t, ts.URL, cfg, validBundleBytes(t, "wallboard"))
+	waitForJobTerminalHTTP(t, ts.URL, cfg, jobID)
+
+	resp, err := http.Get(ts.URL + "/v1/feed.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var feed job.Feed
+	err = json.NewDecoder(resp.Body).Decode(&feed)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 without a token, got %d", resp.StatusCode)
+	}
+	if len(feed.Recent) != 1 || feed.Recent[0].ID != jobID || feed.Recent[0].Project != "wallboard" || feed.Recent[0].State != job.StateSucceeded {
+		t.Fatalf("unexpected feed: %+v", feed)
+	}
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatalf("feed has no ETag")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/v1/feed.json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("If-None-Match", etag)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("expected 304 for an unchanged feed, got %d", resp.StatusCode)
+	}
+
+	// Other routes keep requiring the token.
+	resp, err = http.Get(ts.URL + "/v1/jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for /v1/jobs, got %d", resp.StatusCode)
+	}
+}
+
+func TestFeed_RequiresTokenByDefault(t *testing.T) {
+	ts, _, _, cancel := newTestServer(t, &builder.FakeBuilder{})
+	defer cancel()
+
+	resp, err := http.Get(ts.URL + "/v1/feed.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", resp.StatusCode)
+	}
+}
+
+func TestSelfTest_ReportsFailedChecksWith503(t *testing.T) {
+	ts, cfg, _, cancel := newTestServerWithConfig(t, &builder.FakeBuilder{}, func(cfg *config.Config) {
+		cfg.VivadoBin = filepath.Join(cfg.BaseDir, "no-such-vivado")
+		cfg.DiscoveryEnabled = false
+	})
+	defer cancel()
+
+	resp := authGet(t, ts.URL+"/v1/admin/selftest", cfg)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", resp.StatusCode)
+	}
+	var report doctor.Report
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]doctor.Result{}
+	for _, c := range report.Checks {
+		got[c.Name] = c
+	}
+	if got["vivado"].Status != doctor.StatusFail || got["vivado"].Fix == "" {
+		t.Fatalf("expected failed vivado check with fix, got %+v", got["vivado"])
+	}
+	if got["base dir"].Status != doctor.StatusOK || got["mdns advertisement"].Status != doctor.StatusSkip {
+		t.Fatalf("unexpected checks: %+v", report.Checks)
+	}
+
+	unauth, err := http.Get(ts.URL + "/v1/admin/selftest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unauth.Body.Close()
+	if unauth.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected selftest to require auth, got %d", unauth.StatusCode)
+	}
+}
+
+func TestMetricsEndpoint_ReportsEventStats(t *testing.T) {
+	ts, cfg, _, cancel := newTestServer(t, &builder.FakeBuilder{})
+	defer cancel()
+
+	resp := authGet(t, ts.URL+"/v1/admin/metrics", cfg)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var body struct {
+		Events *queue.EventStats `json:"events"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Events == nil || body.Events.DroppedEvents != 0 || body.Events.Subscribers != 0 {
+		t.Fatalf("unexpected metrics: %+v", body.Events)
+	}
+}
+
+func TestPrometheusMetrics_CountsJobsAndUploads(t *testing.T) {
+	ts, cfg, _, cancel := newTestServer(t, &builder.FakeBuilder{})
+	defer cancel()
+
+	bundle := validBundleBytes(t, "metrics")
+	jobID := submitBundle(t, ts.URL, cfg, bundle)
+	if rec := waitForJobTerminalHTTP(t, ts.URL, cfg, jobID); rec.State != job.StateSucceeded {
+		t.Fatalf("expected success, got %s", rec.State)
+	}
+
+	resp := authGet(t, ts.URL+"/metrics", cfg)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := string(raw)
+	for _, want := range []string{
+		"spadeforge_jobs_submitted_total 1\n",
+		`spadeforge_jobs_finished_total{state="SUCCEEDED"} 1` + "\n",
+		"spadeforge_queue_depth 0\n",
+		"spadeforge_build_duration_seconds_count 1\n",
+		fmt.Sprintf("spadeforge_upload_bytes_total %d\n", len(bundle)),
+		"spadeforge_event_subscribers 0\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("metrics missing %q:\n%s", want, body)
+		}
+	}
+
+	unauth, err := http.Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unauth.Body.Close()
+	if unauth.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", unauth.StatusCode)
+	}
+}
+
+func TestAdminLogLevel_AdjustsFiltersAtRuntime(t *testing.T) {
+	ts, cfg, _, cancel := newTestServer(t, &builder.FakeBuilder{})
+	defer cancel()
+	t.Cleanup(func() {
+		_ = logx.Apply(logx.Settings{Level: "info", Modules: map[string]string{logx.ModuleDiscovery: "default"}})
+	})
+
+	post := func(body string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, ts.URL+"/v1/admin/loglevel", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(cfg.AuthHeader, cfg.Token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := post(`{"level":"debug","modules":{"discovery":"trace"}}`)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if !logx.For(logx.ModuleDiscovery).Enabled(logx.LevelTrace) || logx.For(logx.ModuleQueue).Enabled(logx.LevelTrace) {
+		t.Fatalf("expected discovery at trace and queue at debug, got %+v", logx.Current())
+	}
+
+	bad := post(`{"modules":{"scheduler":"debug"}}`)
+	defer bad.Body.Close()
+	if bad.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown module, got %d", bad.StatusCode)
+	}
+
+	got := authGet(t, ts.URL+"/v1/admin/loglevel", cfg)
+	defer got.Body.Close()
+	var settings logx.Settings
+	if err := json.NewDecoder(got.Body).Decode(&settings); err != nil {
+		t.Fatal(err)
+	}
+	if settings.Level != "debug" || settings.Modules[logx.ModuleDiscovery] != "trace" || len(settings.Modules) != 1 {
+		t.Fatalf("unexpected log settings: %+v", settings)
+	}
+}
+
 func TestSubmitJob_SucceedsAndArtifactsDownload(t *testing.T) {
 	ts, cfg, mgr, cancel := newTestServer(t, &builder.FakeBuilder{})
 	defer cancel()
@@ -69,6 +342,178 @@ func TestSubmitJob_SucceedsAndArtifactsDownload(t *testing.T) {
 	}
 }
 
+func TestBundleEndpoint_ReturnsSubmittedZip(t *testing.T) {
+	ts, cfg, _, cancel := newTestServer(t, &builder.FakeBuilder{FailProjects: map[string]error{"fail": errors.New("forced failure")}})
+	defer cancel()
+
+	bundle := validBundleBytes(t, "fail")
+	jobID := submitBundle(t, ts.URL, cfg, bundle)
+	waitForJobTerminalHTTP(t, ts.URL, cfg, jobID)
+
+	resp := authGet(t, ts.URL+"/v1/jobs/"+jobID+"/bundle", cfg)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/zip" {
+		t.Fatalf("bundle status = %d content-type=%q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(raw, bundle) {
+		t.Fatalf("downloaded bundle differs from the submitted one (%d vs %d bytes)", len(raw), len(bundle))
+	}
+
+	missing := authGet(t, ts.URL+"/v1/jobs/nope/bundle", cfg)
+	defer missing.Body.Close()
+	if missing.StatusCode != http.StatusNotFound {
+		t.Fatalf("unknown job status = %d, want 404", missing.StatusCode)
+	}
+	unauth, err := http.Get(ts.URL + "/v1/jobs/" + jobID + "/bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unauth.Body.Close()
+	if unauth.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("unauthenticated status = %d, want 401", unauth.StatusCode)
+	}
+}
+
+func TestFlashImageEndpoint_ConvertsBitstream(t *testing.T) {
+	ts, cfg, _, cancel := newTestServer(t, &builder.FakeBuilder{FailProjects: map[string]error{"fail": errors.New("forced failure")}})
+	defer cancel()
+
+	jobID := submitBundle(t, ts.URL, cfg, validBundleBytes(t, "ok"))
+	waitForJobTerminalHTTP(t, ts.URL, cfg, jobID)
+
+	bin := authGet(t, ts.URL+"/v1/jobs/"+jobID+"/flash-image", cfg)
+	defer bin.Body.Close()
+	raw, _ := io.ReadAll(bin.Body)
+	if bin.StatusCode != http.StatusOK || string(raw) != "fake-bitstream" {
+		t.Fatalf("bin status = %d body=%q", bin.StatusCode, raw)
+	}
+	if cd := bin.Header.Get("Content-Disposition"); !strings.Contains(cd, jobID+"-design.bin") {
+		t.Fatalf("Content-Disposition = %q", cd)
+	}
+
+	mcs := authGet(t, ts.URL+"/v1/jobs/"+jobID+"/flash-image?format=mcs&offset=0x10000", cfg)
+	defer mcs.Body.Close()
+	raw, _ = io.ReadAll(mcs.Body)
+	if mcs.StatusCode != http.StatusOK || !strings.HasPrefix(string(raw), ":020000040001F9\n") || !strings.HasSuffix(string(raw), ":00000001FF\n") {
+		t.Fatalf("mcs status = %d body=%q", mcs.StatusCode, raw)
+	}
+
+	for path, want := range map[string]int{
+		"/v1/jobs/" + jobID + "/flash-image?format=hex":          http.StatusBadRequest,
+		"/v1/jobs/" + jobID + "/flash-image?offset=0x100":        http.StatusBadRequest,
+		"/v1/jobs/" + jobID + "/flash-image?format=mcs&offset=x": http.StatusBadRequest,
+		"/v1/jobs/nope/flash-image":                              http.StatusNotFound,
+	} {
+		resp := authGet(t, ts.URL+path, cfg)
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("GET %s status = %d, want %d", path, resp.StatusCode, want)
+		}
+	}
+
+	failed := submitBundle(t, ts.URL, cfg, validBundleBytes(t, "fail"))
+	waitForJobTerminalHTTP(t, ts.URL, cfg, failed)
+	resp := authGet(t, ts.URL+"/v1/jobs/"+failed+"/flash-image", cfg)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("failed job status = %d, want 409", resp.StatusCode)
+	}
+}
+
+func TestCancelEndpoint_StopsRunningJob(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	ts, cfg, mgr, cancel := newTestServer(t, &builder.FakeBuilder{BlockCh: block})
+	defer cancel()
+
+	jobID := submitBundle(t, ts.URL, cfg, validBundleBytes(t, "long"))
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		if rec, ok := mgr.Get(jobID); ok && rec.State == job.StateRunning {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("job never started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	postCancel := func(id string) (int, job.Record) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, ts.URL+"/v1/jobs/"+id+"/cancel", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(cfg.AuthHeader, cfg.Token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var rec job.Record
+		_ = json.NewDecoder(resp.Body).Decode(&rec)
+		return resp.StatusCode, rec
+	}
+
+	if status, rec := postCancel(jobID); status != http.StatusAccepted || rec.State != job.StateRunning {
+		t.Fatalf("cancel status = %d state=%s, want 202 RUNNING", status, rec.State)
+	}
+	if final := waitForJobTerminalHTTP(t, ts.URL, cfg, jobID); final.State != job.StateCanceled {
+		t.Fatalf("final state = %s, want CANCELED", final.State)
+	}
+	if status, _ := postCancel(jobID); status != http.StatusConflict {
+		t.Fatalf("second cancel status = %d, want 409", status)
+	}
+	if status, _ := postCancel("missing"); status != http.StatusNotFound {
+		t.Fatalf("unknown job cancel status = %d, want 404", status)
+	}
+}
+
+func TestListJobsEndpoint_FiltersAndPagesNewestFirst(t *testing.T) {
+	fb := &builder.FakeBuilder{FailProjects: map[string]error{"bad": errors.New("forced")}}
+	ts, cfg, _, cancel := newTestServer(t, fb)
+	defer cancel()
+
+	var ids []string
+	for _, project := range []string{"first", "bad", "third"} {
+		id := submitBundle(t, ts.URL, cfg, validBundleBytes(t, project))
+		waitForJobTerminalHTTP(t, ts.URL, cfg, id)
+		ids = append(ids, id)
+	}
+
+	list := func(query string) (int, job.JobList) {
+		t.Helper()
+		resp := authGet(t, ts.URL+"/v1/jobs"+query, cfg)
+		defer resp.Body.Close()
+		var out job.JobList
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+
+	status, page := list("?limit=2")
+	if status != http.StatusOK || page.Total != 3 || len(page.Items) != 2 {
+		t.Fatalf("first page status=%d total=%d items=%d", status, page.Total, len(page.Items))
+	}
+	if page.Items[0].ID != ids[2] || page.Items[1].ID != ids[1] {
+		t.Fatalf("expected newest first, got %s, %s", page.Items[0].ID, page.Items[1].ID)
+	}
+	if _, page = list("?limit=2&offset=2"); len(page.Items) != 1 || page.Items[0].ID != ids[0] || page.Offset != 2 {
+		t.Fatalf("second page = %+v", page)
+	}
+	if _, page = list("?state=failed"); page.Total != 1 || page.Items[0].ID != ids[1] {
+		t.Fatalf("state filter = %+v", page)
+	}
+	for _, query := range []string{"?state=bogus", "?limit=0", "?offset=-1"} {
+		if status, _ := list(query); status != http.StatusBadRequest {
+			t.Fatalf("GET /v1/jobs%s status = %d, want 400", query, status)
+		}
+	}
+}
+
 func TestSubmitJob_FailureIncludesLogsOnly(t *testing.T) {
 	fb := &builder.FakeBuilder{FailProjects: map[string]error{"fail": errors.New("forced")}}
 	ts, cfg, _, cancel := newTestServer(t, fb)
@@ -146,6 +591,15 @@ func TestSubmitJob_RejectsMissingProject(t *testing.T) {
 	if !strings.Contains(string(raw), "project is required") {
 		t.Fatalf("expected missing project error, got %q", string(raw))
 	}
+	var payload struct {
+		Details []manifest.FieldError `json:"details"`
+	}
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(payload.Details) != 1 || payload.Details[0].Path != "/project" {
+		t.Fatalf("expected structured /project detail, got %+v", payload.Details)
+	}
 }
 
 func TestJobStatus_ExposesStepAndHeartbeat(t *testing.T) {
@@ -181,78 +635,423 @@ func TestJobStatus_ExposesStepAndHeartbeat(t *testing.T) {
 			t.Fatal(err)
 		}
 		resp.Body.Close()
-		if running.State == job.StateRunning && running.CurrentStep != "" && running.HeartbeatAt != nil {
-			break
+		if running.State == job.StateRunning && running.CurrentStep != "" && running.HeartbeatAt != nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if running.State != job.StateRunning || running.CurrentStep == "" || running.HeartbeatAt == nil {
+		t.Fatalf("expected running job with progress fields, got %+v", running)
+	}
+
+	close(block)
+	waitForJobTerminalHTTP(t, ts.URL, cfg, jobID)
+}
+
+func TestJobsStatusEndpoint_ReturnsRecordsInRequestOrder(t *testing.T) {
+	ts, cfg, _, cancel := newTestServer(t, &builder.FakeBuilder{})
+	defer cancel()
+
+	first := submitBundle(t, ts.URL, cfg, validBundleBytes(t, "first"))
+	second := submitBundle(t, ts.URL, cfg, validBundleBytes(t, "second"))
+	waitForJobTerminalHTTP(t, ts.URL, cfg, first)
+	waitForJobTerminalHTTP(t, ts.URL, cfg, second)
+
+	post := func(body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, ts.URL+"/v1/jobs/status", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(cfg.AuthHeader, cfg.Token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := post(`{"job_ids":["` + second + `","nope","` + first + `","` + second + `"]}`)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		t.Fatalf("expected 200, got %d body=%s", resp.StatusCode, string(raw))
+	}
+	var out job.StatusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Jobs) != 2 || out.Jobs[0].ID != second || out.Jobs[1].ID != first || out.Jobs[0].Manifest.Project != "second" {
+		t.Fatalf("unexpected jobs: %+v", out.Jobs)
+	}
+	if len(out.Missing) != 1 || out.Missing[0] != "nope" {
+		t.Fatalf("unexpected missing: %v", out.Missing)
+	}
+
+	bad := post(`{"job_ids":`)
+	bad.Body.Close()
+	if bad.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for malformed body, got %d", bad.StatusCode)
+	}
+}
+
+func TestBaselineEndpoints(t *testing.T) {
+	ts, cfg, _, cancel := newTestServer(t, &builder.FakeBuilder{})
+	defer cancel()
+
+	jobID := submitBundle(t, ts.URL, cfg, validBundleBytes(t, "blinky"))
+	waitForJobTerminalHTTP(t, ts.URL, cfg, jobID)
+
+	missing := authGet(t, ts.URL+"/v1/projects/blinky/baseline", cfg)
+	missing.Body.Close()
+	if missing.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 before a baseline is set, got %d", missing.StatusCode)
+	}
+
+	for id, want := range map[string]int{"nope": http.StatusNotFound, jobID: http.StatusOK} {
+		req, err := http.NewRequest(http.MethodPost, ts.URL+"/v1/jobs/"+id+"/baseline", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(cfg.AuthHeader, cfg.Token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("set baseline %s: expected %d, got %d", id, want, resp.StatusCode)
+		}
+	}
+
+	resp := authGet(t, ts.URL+"/v1/projects/blinky/baseline", cfg)
+	defer resp.Body.Close()
+	var rec job.Record
+	if err := json.NewDecoder(resp.Body).Decode(&rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec.ID != jobID || !rec.ProjectBaseline {
+		t.Fatalf("unexpected baseline: %+v", rec)
+	}
+}
+
+func TestVersionEndpoint(t *testing.T) {
+	ts, cfg, _, cancel := newTestServer(t, &builder.FakeBuilder{})
+	defer cancel()
+
+	resp := authGet(t, ts.URL+"/v1/version", cfg)
+	defer resp.Body.Close()
+	var info buildinfo.Info
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || info.Version != buildinfo.Version || info.GoVersion == "" || info.OS == "" {
+		t.Fatalf("unexpected version response %d: %+v", resp.StatusCode, info)
+	}
+}
+
+func TestPinEndpoints(t *testing.T) {
+	ts, cfg, _, cancel := newTestServer(t, &builder.FakeBuilder{})
+	defer cancel()
+
+	jobID := submitBundle(t, ts.URL, cfg, validBundleBytes(t, "blinky"))
+	waitForJobTerminalHTTP(t, ts.URL, cfg, jobID)
+
+	for _, tc := range []struct {
+		method string
+		id     string
+		status int
+		pinned bool
+	}{
+		{http.MethodPost, "nope", http.StatusNotFound, false},
+		{http.MethodPost, jobID, http.StatusOK, true},
+		{http.MethodDelete, jobID, http.StatusOK, false},
+	} {
+		req, err := http.NewRequest(tc.method, ts.URL+"/v1/jobs/"+tc.id+"/pin", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(cfg.AuthHeader, cfg.Token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var rec job.Record
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&rec); err != nil {
+				t.Fatal(err)
+			}
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.status {
+			t.Fatalf("%s pin %s: expected %d, got %d", tc.method, tc.id, tc.status, resp.StatusCode)
+		}
+		if tc.status == http.StatusOK && rec.Pinned != tc.pinned {
+			t.Fatalf("%s pin: expected pinned=%v, got %+v", tc.method, tc.pinned, rec)
+		}
+	}
+}
+
+func TestRunEndpoint_RollsUpBuild(t *testing.T) {
+	ts, cfg, _, cancel := newTestServer(t, &builder.FakeBuilder{})
+	defer cancel()
+
+	jobID := submitBundle(t, ts.URL, cfg, validBundleBytes(t, "blinky"))
+	waitForJobTerminalHTTP(t, ts.URL, cfg, jobID)
+
+	missing := authGet(t, ts.URL+"/v1/runs/nope", cfg)
+	missing.Body.Close()
+	if missing.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown run, got %d", missing.StatusCode)
+	}
+
+	resp := authGet(t, ts.URL+"/v1/runs/"+jobID, cfg)
+	defer resp.Body.Close()
+	var run job.Run
+	if err := json.NewDecoder(resp.Body).Decode(&run); err != nil {
+		t.Fatal(err)
+	}
+	if run.ID != jobID || run.State != job.StateSucceeded || run.Stage != job.RunStageDone || len(run.Builds) != 1 || len(run.Flashes) != 0 {
+		t.Fatalf("unexpected run: %+v", run)
+	}
+}
+
+func TestStorageEndpoint_ReportsUsage(t *testing.T) {
+	ts, cfg, _, cancel := newTestServer(t, &builder.FakeBuilder{})
+	defer cancel()
+
+	jobID := submitBundle(t, ts.URL, cfg, validBundleBytes(t, "blinky"))
+	waitForJobTerminalHTTP(t, ts.URL, cfg, jobID)
+
+	resp := authGet(t, ts.URL+"/v1/storage", cfg)
+	defer resp.Body.Close()
+	var usage queue.StorageUsage
+	if err := json.NewDecoder(resp.Body).Decode(&usage); err != nil {
+		t.Fatal(err)
+	}
+	if usage.Jobs != 1 || usage.JobsBytes <= 0 || usage.ArtifactsBytes <= 0 || usage.TotalBytes != usage.Total() || usage.RetentionDays != cfg.RetentionDays {
+		t.Fatalf("unexpected storage usage: %+v", usage)
+	}
+}
+
+func TestDiagnosticsEndpoint_ReturnsParsedErrors(t *testing.T) {
+	fb := &builder.FakeBuilder{FailProjects: map[string]error{"fail": errors.New("forced")}}
+	ts, cfg, _, cancel := newTestServer(t, fb)
+	defer cancel()
+
+	jobID := submitBundle(t, ts.URL, cfg, validBundleBytes(t, "fail"))
+	waitForJobTerminalHTTP(t, ts.URL, cfg, jobID)
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/v1/jobs/"+jobID+"/diagnostics", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(cfg.AuthHeader, cfg.Token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		t.Fatalf("diagnostics failed: %d body=%s", resp.StatusCode, string(raw))
+	}
+	var report job.DiagnosticsReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if report.ErrorCount == 0 || len(report.Diagnostics) == 0 {
+		t.Fatalf("expected parsed diagnostics, got %+v", report)
+	}
+}
+
+func TestTailEndpoint_ReturnsLastNLines(t *testing.T) {
+	fb := &builder.FakeBuilder{ConsoleLog: "line1\nline2\nline3\n"}
+	ts, cfg, _, cancel := newTestServer(t, fb)
+	defer cancel()
+
+	jobID := submitBundle(t, ts.URL, cfg, validBundleBytes(t, "ok"))
+	waitForJobTerminalHTTP(t, ts.URL, cfg, jobID)
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/v1/jobs/"+jobID+"/tail?lines=2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(cfg.AuthHeader, cfg.Token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		t.Fatalf("tail failed: %d body=%s", resp.StatusCode, string(raw))
+	}
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != "line2\nline3\n" {
+		t.Fatalf("unexpected tail payload: %q", string(raw))
+	}
+}
+
+func TestLogEndpoint_StreamsGzipAndSelectsFile(t *testing.T) {
+	vivadoLog := strings.Repeat("INFO: [Synth 8-7075] helper message\n", 2000)
+	fb := &builder.FakeBuilder{ConsoleLog: "console line\n", VivadoLog: vivadoLog}
+	ts, cfg, _, cancel := newTestServer(t, fb)
+	defer cancel()
+
+	jobID := submitBundle(t, ts.URL, cfg, validBundleBytes(t, "ok"))
+	waitForJobTerminalHTTP(t, ts.URL, cfg, jobID)
+
+	resp := authGet(t, ts.URL+"/v1/jobs/"+jobID+"/log?file=vivado.log&format=gz", cfg)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/gzip" {
+		t.Fatalf("unexpected response: status=%d type=%q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if cd := resp.Header.Get("Content-Disposition"); !strings.Contains(cd, jobID+"-vivado.log.gz") {
+		t.Fatalf("unexpected content disposition: %q", cd)
+	}
+	compressed, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(compressed) >= len(vivadoLog)/10 {
+		t.Fatalf("expected repetitive log to compress well, got %d of %d bytes", len(compressed), len(vivadoLog))
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plain) != vivadoLog || zr.Name != "vivado.log" {
+		t.Fatalf("unexpected decompressed log: name=%q len=%d", zr.Name, len(plain))
+	}
+
+	text := authGet(t, ts.URL+"/v1/jobs/"+jobID+"/log", cfg)
+	raw, _ := io.ReadAll(text.Body)
+	text.Body.Close()
+	if text.StatusCode != http.StatusOK || !strings.Contains(string(raw), "console line") {
+		t.Fatalf("unexpected plain console log: status=%d body=%q", text.StatusCode, raw)
+	}
+
+	for _, query := range []string{"file=../state.json", "format=zip"} {
+		bad := authGet(t, ts.URL+"/v1/jobs/"+jobID+"/log?"+query, cfg)
+		bad.Body.Close()
+		if bad.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", query, bad.StatusCode)
+		}
+	}
+}
+
+func TestLogEndpoint_SinceReturnsNewerLines(t *testing.T) {
+	ts, cfg, _, cancel := newTestServer(t, &builder.FakeBuilder{})
+	defer cancel()
+
+	jobID := submitBundle(t, ts.URL, cfg, validBundleBytes(t, "ok"))
+	waitForJobTerminalHTTP(t, ts.URL, cfg, jobID)
+
+	consolePath := filepath.Join(cfg.ArtifactsDir(), jobID, "console.log")
+	if err := os.WriteFile(consolePath, []byte("early\nlate\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	index := "2026-10-16T12:00:00Z 0\n2026-10-16T12:05:00Z 6\n"
+	if err := os.WriteFile(logtime.IndexPath(consolePath), []byte(index), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for since, want := range map[string]string{
+		"":                     "early\nlate\n",
+		"2026-10-16T12:01:00Z": "late\n",
+		"2026-10-16T13:00:00Z": "",
+	} {
+		resp := authGet(t, ts.URL+"/v1/jobs/"+jobID+"/log?since="+since, cfg)
+		raw, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || string(raw) != want {
+			t.Fatalf("since=%q: status=%d body=%q, want %q", since, resp.StatusCode, raw, want)
 		}
-		time.Sleep(20 * time.Millisecond)
-	}
-	if running.State != job.StateRunning || running.CurrentStep == "" || running.HeartbeatAt == nil {
-		t.Fatalf("expected running job with progress fields, got %+v", running)
 	}
 
-	close(block)
-	waitForJobTerminalHTTP(t, ts.URL, cfg, jobID)
+	bad := authGet(t, ts.URL+"/v1/jobs/"+jobID+"/log?since=yesterday", cfg)
+	bad.Body.Close()
+	if bad.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for bad since, got %d", bad.StatusCode)
+	}
 }
 
-func TestDiagnosticsEndpoint_ReturnsParsedErrors(t *testing.T) {
-	fb := &builder.FakeBuilder{FailProjects: map[string]error{"fail": errors.New("forced")}}
+func TestLogSearchEndpoint_ReturnsMatchesWithContext(t *testing.T) {
+	fb := &builder.FakeBuilder{VivadoLog: "start\nWARNING: [Synth 8-3331] unconnected port dbg\nmid\nERROR: [Place 30-58] IO placement\nend\n"}
 	ts, cfg, _, cancel := newTestServer(t, fb)
 	defer cancel()
 
-	jobID := submitBundle(t, ts.URL, cfg, validBundleBytes(t, "fail"))
+	jobID := submitBundle(t, ts.URL, cfg, validBundleBytes(t, "ok"))
 	waitForJobTerminalHTTP(t, ts.URL, cfg, jobID)
 
-	req, err := http.NewRequest(http.MethodGet, ts.URL+"/v1/jobs/"+jobID+"/diagnostics", nil)
-	if err != nil {
-		t.Fatal(err)
-	}
-	req.Header.Set(cfg.AuthHeader, cfg.Token)
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		t.Fatal(err)
-	}
+	q := url.Values{"q": {`\[(Synth|Place) `}, "file": {"vivado.log"}, "context": {"1"}}
+	resp := authGet(t, ts.URL+"/v1/jobs/"+jobID+"/log/search?"+q.Encode(), cfg)
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
 		raw, _ := io.ReadAll(resp.Body)
-		t.Fatalf("diagnostics failed: %d body=%s", resp.StatusCode, string(raw))
+		t.Fatalf("search failed: %d body=%s", resp.StatusCode, raw)
 	}
-	var report job.DiagnosticsReport
-	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+	var result job.LogSearchResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		t.Fatal(err)
 	}
-	if report.ErrorCount == 0 || len(report.Diagnostics) == 0 {
-		t.Fatalf("expected parsed diagnostics, got %+v", report)
+	if len(result.Matches) != 2 || result.Matches[0].Line != 2 || result.Matches[1].Line != 4 {
+		t.Fatalf("unexpected matches: %+v", result.Matches)
+	}
+	if result.Matches[1].After[0].Text != "end" {
+		t.Fatalf("expected trailing context, got %+v", result.Matches[1])
+	}
+
+	for _, query := range []string{"q=%28", "q=x&context=99", "q=x&file=other.log", "context=1"} {
+		bad := authGet(t, ts.URL+"/v1/jobs/"+jobID+"/log/search?"+query, cfg)
+		bad.Body.Close()
+		if bad.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", query, bad.StatusCode)
+		}
 	}
 }
 
-func TestTailEndpoint_ReturnsLastNLines(t *testing.T) {
-	fb := &builder.FakeBuilder{ConsoleLog: "line1\nline2\nline3\n"}
+func TestProjectDiagnosticsSummaryEndpoint(t *testing.T) {
+	fb := &builder.FakeBuilder{VivadoLog: "WARNING: [Synth 8-3331] design top has unconnected port dbg\n"}
 	ts, cfg, _, cancel := newTestServer(t, fb)
 	defer cancel()
 
+	missing := authGet(t, ts.URL+"/v1/projects/ok/diagnostics/summary", cfg)
+	missing.Body.Close()
+	if missing.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 before any build, got %d", missing.StatusCode)
+	}
+
 	jobID := submitBundle(t, ts.URL, cfg, validBundleBytes(t, "ok"))
 	waitForJobTerminalHTTP(t, ts.URL, cfg, jobID)
 
-	req, err := http.NewRequest(http.MethodGet, ts.URL+"/v1/jobs/"+jobID+"/tail?lines=2", nil)
-	if err != nil {
-		t.Fatal(err)
-	}
-	req.Header.Set(cfg.AuthHeader, cfg.Token)
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		t.Fatal(err)
-	}
+	resp := authGet(t, ts.URL+"/v1/projects/ok/diagnostics/summary?limit=5", cfg)
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		raw, _ := io.ReadAll(resp.Body)
-		t.Fatalf("tail failed: %d body=%s", resp.StatusCode, string(raw))
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
 	}
-	raw, err := io.ReadAll(resp.Body)
-	if err != nil {
+	var summary job.ProjectDiagnosticsSummary
+	if err := json.NewDecoder(resp.Body).Decode(&summary); err != nil {
 		t.Fatal(err)
 	}
-	if string(raw) != "line2\nline3\n" {
-		t.Fatalf("unexpected tail payload: %q", string(raw))
+	if len(summary.Builds) != 1 || summary.Builds[0].JobID != jobID || summary.Builds[0].WarningCount == 0 {
+		t.Fatalf("unexpected builds: %+v", summary.Builds)
+	}
+	if len(summary.Groups) == 0 || summary.Groups[0].Code != "Synth 8-3331" || !summary.Groups[0].InLatest {
+		t.Fatalf("unexpected groups: %+v", summary.Groups)
+	}
+
+	bad := authGet(t, ts.URL+"/v1/projects/ok/diagnostics/summary?limit=zero", cfg)
+	bad.Body.Close()
+	if bad.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for bad limit, got %d", bad.StatusCode)
 	}
 }
 
@@ -293,6 +1092,189 @@ func TestEventsEndpoint_StreamsBacklog(t *testing.T) {
 	}
 }
 
+func TestAllEventsEndpoint_StreamsEveryJobFilteredByState(t *testing.T) {
+	ts, cfg, _, cancel := newTestServer(t, &builder.FakeBuilder{})
+	defer cancel()
+
+	ctx, stop := context.WithTimeout(context.Background(), 5*time.Second)
+	defer stop()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/v1/events?state=succeeded,FAILED", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(cfg.AuthHeader, cfg.Token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		t.Fatalf("events failed: %d body=%s", resp.StatusCode, string(raw))
+	}
+
+	first := submitBundle(t, ts.URL, cfg, validBundleBytes(t, "first"))
+	second := submitBundle(t, ts.URL, cfg, validBundleBytes(t, "second"))
+	finished := map[string]bool{}
+	scanner := bufio.NewScanner(resp.Body)
+	for len(finished) < 2 && scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "event: ") && line != "event: succeeded" {
+			t.Fatalf("state filter let through %q", line)
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			var ev job.Event
+			if err := json.Unmarshal([]byte(data), &ev); err != nil {
+				t.Fatal(err)
+			}
+			finished[ev.JobID] = true
+		}
+	}
+	if !finished[first] || !finished[second] {
+		t.Fatalf("finished jobs = %v, want %s and %s (scan err %v)", finished, first, second, scanner.Err())
+	}
+
+	bad := authGet(t, ts.URL+"/v1/events?state=bogus", cfg)
+	bad.Body.Close()
+	if bad.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown state, got %d", bad.StatusCode)
+	}
+}
+
+func TestEventsWSEndpoint_StreamsBacklogAndLiveEvents(t *testing.T) {
+	block := make(chan struct{})
+	ts, cfg, _, cancel := newTestServerWithConfig(t, &builder.FakeBuilder{BlockCh: block}, func(c *config.Config) {
+		c.SSEKeepalive = 20 * time.Millisecond
+	})
+	defer cancel()
+
+	jobID := submitBundle(t, ts.URL, cfg, validBundleBytes(t, "ok"))
+	header := http.Header{}
+	header.Set(cfg.AuthHeader, cfg.Token)
+	wsURL := ts.URL + "/v1/jobs/" + jobID + "/events/ws"
+
+	if _, err := websocket.Dial(context.Background(), ts.Client(), wsURL, nil); err == nil || !strings.Contains(err.Error(), "status=401") {
+		t.Fatalf("expected unauthenticated upgrade to be rejected, got %v", err)
+	}
+	conn, err := websocket.Dial(context.Background(), ts.Client(), wsURL, header)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close(websocket.CloseNormal, "")
+
+	var types []string
+	for {
+		_, data, err := conn.ReadMessage()
+		if errors.Is(err, websocket.ErrClosed) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		var ev job.Event
+		if err := json.Unmarshal(data, &ev); err != nil {
+			t.Fatalf("decode %q: %v", data, err)
+		}
+		types = append(types, ev.Type)
+		if ev.Type == "running" {
+			// Keepalive pings pass while the build blocks.
+			time.Sleep(60 * time.Millisecond)
+			close(block)
+		}
+	}
+	if len(types) < 3 || types[0] != "queued" || types[len(types)-1] != "succeeded" {
+		t.Fatalf("unexpected event types: %v", types)
+	}
+
+	plain := authGet(t, wsURL, cfg)
+	plain.Body.Close()
+	if plain.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 without an upgrade, got %d", plain.StatusCode)
+	}
+}
+
+func TestEventsEndpoint_SendsRetryHintAndConfiguredKeepalive(t *testing.T) {
+	block := make(chan struct{})
+	ts, cfg, _, cancel := newTestServerWithConfig(t, &builder.FakeBuilder{BlockCh: block}, func(c *config.Config) {
+		c.SSEKeepalive = 20 * time.Millisecond
+	})
+	defer cancel()
+
+	jobID := submitBundle(t, ts.URL, cfg, validBundleBytes(t, "ok"))
+	defer waitForJobTerminalHTTP(t, ts.URL, cfg, jobID)
+	defer close(block)
+
+	ctx, stop := context.WithTimeout(context.Background(), 5*time.Second)
+	defer stop()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/v1/jobs/"+jobID+"/events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(cfg.AuthHeader, cfg.Token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	if !scanner.Scan() || !strings.HasPrefix(scanner.Text(), "retry: ") {
+		t.Fatalf("expected retry hint first, got %q", scanner.Text())
+	}
+	for scanner.Scan() {
+		if scanner.Text() == ": keepalive" {
+			return
+		}
+	}
+	t.Fatalf("no keepalive received: %v", scanner.Err())
+}
+
+func TestSubmit_ResponseEchoesManifestQueuePositionAndLimits(t *testing.T) {
+	block := make(chan struct{})
+	ts, cfg, mgr, cancel := newTestServer(t, &builder.FakeBuilder{BlockCh: block})
+	defer cancel()
+
+	first := submitBundleResponse(t, ts.URL, cfg, validBundleBytes(t, "first"))
+	defer waitForJobTerminalHTTP(t, ts.URL, cfg, first.JobID)
+	released := false
+	release := func() {
+		if !released {
+			released = true
+			close(block)
+		}
+	}
+	defer release()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if rec, ok := mgr.Get(first.JobID); ok && rec.State == job.StateRunning {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("first job never started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	second := submitBundleResponse(t, ts.URL, cfg, validBundleBytes(t, "second"))
+	defer waitForJobTerminalHTTP(t, ts.URL, cfg, second.JobID)
+	defer release()
+	if second.State != job.StateQueued {
+		t.Fatalf("unexpected state: %s", second.State)
+	}
+	if second.Project != "second" || second.Manifest.Project != "second" || second.Manifest.Top == "" || len(second.Manifest.Sources) == 0 {
+		t.Fatalf("manifest not echoed: %+v", second.Manifest)
+	}
+	if second.QueuePosition != 1 || second.JobsAhead != 1 {
+		t.Fatalf("unexpected queue position: position=%d ahead=%d", second.QueuePosition, second.JobsAhead)
+	}
+	if len(second.Warnings) == 0 || second.Warnings[0].Path != "/constraints" {
+		t.Fatalf("expected lint warnings in submit response, got %+v", second.Warnings)
+	}
+	if second.Limits.MaxUploadBytes != cfg.MaxUploadBytes || second.Limits.WorkerTimeoutSeconds != int64(cfg.WorkerTimeout/time.Second) {
+		t.Fatalf("unexpected limits: %+v", second.Limits)
+	}
+}
+
 func TestKillAllVivado_ExecFailureReturnsServerError(t *testing.T) {
 	origExecCommand := execCommand
 	execCommand = func(name string, args ...string) *exec.Cmd {
@@ -331,12 +1313,79 @@ func TestKillAllVivado_ExecFailureReturnsServerError(t *testing.T) {
 	}
 }
 
+func TestWorkDirEndpoints_ListAndFetchPreservedFiles(t *testing.T) {
+	fb := &builder.FakeBuilder{WorkFiles: map[string]string{"checkpoints/post_synth.dcp": "netlist"}}
+	ts, cfg, _, cancel := newTestServerWithConfig(t, fb, func(cfg *config.Config) {
+		cfg.PreserveWorkDir = true
+	})
+	defer cancel()
+
+	jobID := submitBundle(t, ts.URL, cfg, validBundleBytes(t, "ok"))
+	waitForJobTerminalHTTP(t, ts.URL, cfg, jobID)
+
+	resp := authGet(t, ts.URL+"/v1/jobs/"+jobID+"/workdir", cfg)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var listing struct {
+		Items []job.WorkDirEntry `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&listing); err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, item := range listing.Items {
+		if item.Path == "checkpoints/post_synth.dcp" && item.Size == int64(len("netlist")) {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected checkpoint in listing, got %+v", listing.Items)
+	}
+
+	fileResp := authGet(t, ts.URL+"/v1/jobs/"+jobID+"/workdir/checkpoints/post_synth.dcp", cfg)
+	defer fileResp.Body.Close()
+	raw, _ := io.ReadAll(fileResp.Body)
+	if fileResp.StatusCode != http.StatusOK || string(raw) != "netlist" {
+		t.Fatalf("unexpected file response: status=%d body=%q", fileResp.StatusCode, string(raw))
+	}
+
+	escapeResp := authGet(t, ts.URL+"/v1/jobs/"+jobID+"/workdir/..%2F..%2Fjobs", cfg)
+	defer escapeResp.Body.Close()
+	if escapeResp.StatusCode == http.StatusOK {
+		t.Fatalf("expected traversal to be rejected")
+	}
+}
+
+func TestWorkDirEndpoints_NotFoundWhenNotPreserved(t *testing.T) {
+	ts, cfg, _, cancel := newTestServer(t, &builder.FakeBuilder{})
+	defer cancel()
+
+	jobID := submitBundle(t, ts.URL, cfg, validBundleBytes(t, "ok"))
+	waitForJobTerminalHTTP(t, ts.URL, cfg, jobID)
+
+	resp := authGet(t, ts.URL+"/v1/jobs/"+jobID+"/workdir", cfg)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+}
+
 func newTestServer(t *testing.T, b builder.Builder) (*httptest.Server, config.Config, *queue.Manager, context.CancelFunc) {
+	t.Helper()
+	return newTestServerWithConfig(t, b, nil)
+}
+
+func newTestServerWithConfig(t *testing.T, b builder.Builder, mutate func(*config.Config)) (*httptest.Server, config.Config, *queue.Manager, context.CancelFunc) {
 	t.Helper()
 	cfg := config.Default()
 	cfg.BaseDir = t.TempDir()
 	cfg.Token = "secret"
 	cfg.WorkerTimeout = 5 * time.Second
+	if mutate != nil {
+		mutate(&cfg)
+	}
 
 	st := store.New(cfg)
 	mgr := queue.New(cfg, st, b)
@@ -354,6 +1403,11 @@ func newTestServer(t *testing.T, b builder.Builder) (*httptest.Server, config.Co
 }
 
 func submitBundle(t *testing.T, baseURL string, cfg config.Config, bundle []byte) string {
+	t.Helper()
+	return submitBundleResponse(t, baseURL, cfg, bundle).JobID
+}
+
+func submitBundleResponse(t *testing.T, baseURL string, cfg config.Config, bundle []byte) job.SubmitResponse {
 	t.Helper()
 	body, contentType := multipartBody(t, bundle)
 	req, err := http.NewRequest(http.MethodPost, baseURL+"/v1/jobs", &body)
@@ -371,16 +1425,14 @@ func submitBundle(t *testing.T, baseURL string, cfg config.Config, bundle []byte
 		raw, _ := io.ReadAll(resp.Body)
 		t.Fatalf("submit failed: %d body=%s", resp.StatusCode, string(raw))
 	}
-	var payload struct {
-		JobID string `json:"job_id"`
-	}
+	var payload job.SubmitResponse
 	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
 		t.Fatal(err)
 	}
 	if payload.JobID == "" {
 		t.Fatalf("missing job_id")
 	}
-	return payload.JobID
+	return payload
 }
 
 func waitForJobTerminalHTTP(t *testing.T, baseURL string, cfg config.Config, jobID string) *job.Record {
@@ -506,3 +1558,183 @@ func listZipEntries(t *testing.T, raw []byte) map[string]bool {
 	}
 	return out
 }
+
+func authGet(t *testing.T, rawURL string, cfg config.Config) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(cfg.AuthHeader, cfg.Token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestSubmitJob_BuildCacheHeaders(t *testing.T) {
+	ts, cfg, _, cancel := newTestServerWithConfig(t, &builder.FakeBuilder{}, func(c *config.Config) { c.BuildCache = true })
+	defer cancel()
+	defer ts.Close()
+
+	submit := func(noCache string) (job.SubmitResponse, string, int) {
+		t.Helper()
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, err := mw.CreateFormFile("bundle", "bundle.zip")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = fw.Write(validBundleBytes(t, "cached"))
+		if noCache != "" {
+			_ = mw.WriteField("no_cache", noCache)
+		}
+		_ = mw.Close()
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/jobs", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.Header.Set(cfg.AuthHeader, cfg.Token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var out job.SubmitResponse
+		if resp.StatusCode == http.StatusAccepted {
+			_ = json.NewDecoder(resp.Body).Decode(&out)
+		}
+		return out, resp.Header.Get("X-Cache"), resp.StatusCode
+	}
+
+	first, xcache, status := submit("")
+	if status != http.StatusAccepted || xcache != "miss" {
+		t.Fatalf("first submit = %d X-Cache %q", status, xcache)
+	}
+	waitForJobTerminalHTTP(t, ts.URL, cfg, first.JobID)
+
+	hit, xcache, _ := submit("")
+	if xcache != "hit" || hit.State != job.StateSucceeded || hit.CachedFrom != first.JobID {
+		t.Fatalf("second submit = X-Cache %q state %s cached_from %q", xcache, hit.State, hit.CachedFrom)
+	}
+	if bypass, xcache, _ := submit("true"); xcache != "miss" || bypass.CachedFrom != "" {
+		t.Fatalf("no_cache submit = X-Cache %q cached_from %q", xcache, bypass.CachedFrom)
+	}
+	if _, _, status := submit("maybe"); status != http.StatusBadRequest {
+		t.Fatalf("invalid no_cache = %d, want 400", status)
+	}
+}
+
+func TestUploads_ResumeAfterDroppedChunkAndSubmit(t *testing.T) {
+	ts, cfg, _, cancel := newTestServer(t, &builder.FakeBuilder{})
+	defer cancel()
+
+	// The first chunk loses its second half on the way, as when Wi-Fi drops
+	// mid-request: the server keeps what arrived and the client resumes
+	// from the offset the server reports.
+	var dropped atomic.Bool
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPatch && dropped.CompareAndSwap(false, true) {
+			raw, _ := io.ReadAll(r.Body)
+			req, _ := http.NewRequest(http.MethodPatch, ts.URL+r.URL.Path, bytes.NewReader(raw[:len(raw)/2]))
+			req.Header = r.Header.Clone()
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Errorf("forward chunk: %v", err)
+				return
+			}
+			resp.Body.Close()
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		proxied, _ := http.NewRequest(r.Method, ts.URL+r.URL.RequestURI(), r.Body)
+		proxied.Header = r.Header.Clone()
+		resp, err := http.DefaultClient.Do(proxied)
+		if err != nil {
+			t.Errorf("proxy: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+		for k, v := range resp.Header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(resp.StatusCode)
+		_, _ = io.Copy(w, resp.Body)
+	}))
+	defer flaky.Close()
+
+	bundle := validBundleBytes(t, "demo")
+	c := &client.HTTPClient{
+		BaseURL:    flaky.URL,
+		Token:      cfg.Token,
+		AuthHeader: cfg.AuthHeader,
+		ChunkSize:  int64(len(bundle)/3 + 1),
+		Retry:      httpretry.Policy{BaseDelay: time.Millisecond},
+	}
+	resp, err := c.SubmitResumable(context.Background(), bundle)
+	if err != nil {
+		t.Fatalf("SubmitResumable() error: %v", err)
+	}
+	if !dropped.Load() {
+		t.Fatalf("no chunk was dropped")
+	}
+	rec := waitForJobTerminalHTTP(t, ts.URL, cfg, resp.JobID)
+	if rec.State != job.StateSucceeded || rec.Manifest.Project != "demo" {
+		t.Fatalf("job = %s %q, want SUCCEEDED demo", rec.State, rec.Manifest.Project)
+	}
+	if entries, _ := os.ReadDir(cfg.UploadsDir()); len(entries) != 0 {
+		t.Fatalf("finalized upload left %d files behind", len(entries))
+	}
+}
+
+func TestUploads_FinalizeRejectsChecksumMismatch(t *testing.T) {
+	ts, cfg, _, cancel := newTestServer(t, &builder.FakeBuilder{})
+	defer cancel()
+
+	do := func(method, path string, header http.Header, body []byte) (int, string) {
+		t.Helper()
+		req, err := http.NewRequest(method, ts.URL+path, bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		req.Header.Set(cfg.AuthHeader, cfg.Token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		raw, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(raw)
+	}
+
+	status, body := do(http.MethodPost, "/v1/uploads", nil, []byte(`{"size":4,"sha256":"`+strings.Repeat("0", 64)+`"}`))
+	if status != http.StatusCreated {
+		t.Fatalf("create status = %d body=%s", status, body)
+	}
+	var up job.Upload
+	if err := json.Unmarshal([]byte(body), &up); err != nil {
+		t.Fatal(err)
+	}
+
+	status, body = do(http.MethodPost, "/v1/uploads/"+up.ID+"/finalize", nil, nil)
+	if status != http.StatusConflict || !strings.Contains(body, `"offset":0`) {
+		t.Fatalf("finalize of empty upload = %d body=%s, want 409 with the offset", status, body)
+	}
+	status, body = do(http.MethodPatch, "/v1/uploads/"+up.ID, http.Header{job.UploadOffsetHeader: {"2"}}, []byte("ab"))
+	if status != http.StatusConflict {
+		t.Fatalf("chunk at wrong offset = %d body=%s, want 409", status, body)
+	}
+	status, body = do(http.MethodPatch, "/v1/uploads/"+up.ID, http.Header{job.UploadOffsetHeader: {"0"}}, []byte("abcd"))
+	if status != http.StatusOK || !strings.Contains(body, `"offset":4`) {
+		t.Fatalf("chunk = %d body=%s", status, body)
+	}
+	status, body = do(http.MethodPost, "/v1/uploads/"+up.ID+"/finalize", nil, nil)
+	if status != http.StatusUnprocessableEntity || !strings.Contains(body, "sha256 mismatch") {
+		t.Fatalf("finalize = %d body=%s, want 422 sha256 mismatch", status, body)
+	}
+	if status, _ = do(http.MethodGet, "/v1/uploads/"+up.ID, nil, nil); status != http.StatusNotFound {
+		t.Fatalf("mismatching upload still exists: %d", status)
+	}
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/client"
+	"github.com/mblsha/spadeforge/internal/config"
+	"github.com/mblsha/spadeforge/internal/httpretry"
+	"github.com/mblsha/spadeforge/internal/httptransport"
+	"github.com/mblsha/spadeforge/internal/queue"
+	"github.com/mblsha/spadeforge/internal/store"
+)
+
+// testCA issues certificates for TLS tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	dir  string
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	ca := &testCA{dir: t.TempDir()}
+	ca.cert, ca.key = ca.issue(t, name, nil, nil)
+	return ca
+}
+
+// issue signs a certificate for name with ca, or self-signs a CA when ca
+// has none yet.
+func (ca *testCA) issue(t *testing.T, name string, ips []net.IP, usage []x509.ExtKeyUsage) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  ips,
+		ExtKeyUsage:  usage,
+	}
+	parent, signer := tmpl, key
+	if ca.cert == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		parent, signer = ca.cert, ca.key
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+// writePair issues a certificate and writes it and its key as PEM files.
+func (ca *testCA) writePair(t *testing.T, name string, ips []net.IP, usage ...x509.ExtKeyUsage) (certFile, keyFile string) {
+	t.Helper()
+	cert, key := ca.issue(t, name, ips, usage)
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile = filepath.Join(ca.dir, name+".crt")
+	keyFile = filepath.Join(ca.dir, name+".key")
+	writePEM(t, certFile, "CERTIFICATE", cert.Raw)
+	writePEM(t, keyFile, "EC PRIVATE KEY", der)
+	return certFile, keyFile
+}
+
+func (ca *testCA) writeCert(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(ca.dir, "ca.crt")
+	writePEM(t, path, "CERTIFICATE", ca.cert.Raw)
+	return path
+}
+
+func writePEM(t *testing.T, path, typ string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTLS_ClientCertificateReplacesToken(t *testing.T) {
+	ca := newTestCA(t, "test-ca")
+	caFile := ca.writeCert(t)
+	serverCert, serverKey := ca.writePair(t, "server", []net.IP{net.IPv4(127, 0, 0, 1)}, x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.writePair(t, "ci-runner", nil, x509.ExtKeyUsageClientAuth)
+	rogue := newTestCA(t, "rogue-ca")
+	rogueCert, rogueKey := rogue.writePair(t, "intruder", nil, x509.ExtKeyUsageClientAuth)
+
+	cfg := config.Default()
+	cfg.BaseDir = t.TempDir()
+	cfg.Token = "secret"
+	cfg.TLSCert, cfg.TLSKey, cfg.TLSClientCA = serverCert, serverKey, caFile
+	tlsCfg, err := httptransport.ServerTLSConfig(cfg.TLSCert, cfg.TLSKey, cfg.TLSClientCA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := queue.New(cfg, store.New(cfg), &builder.FakeBuilder{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewUnstartedServer(New(cfg, mgr).Handler())
+	ts.TLS = tlsCfg
+	ts.StartTLS()
+	defer ts.Close()
+
+	withCert := &client.HTTPClient{BaseURL: ts.URL, CAFile: caFile, CertFile: clientCert, KeyFile: clientKey}
+	if _, err := withCert.GetVersion(ctx); err != nil {
+		t.Fatalf("client certificate without token: %v", err)
+	}
+	withToken := &client.HTTPClient{BaseURL: ts.URL, CAFile: caFile, Token: cfg.Token}
+	if _, err := withToken.GetVersion(ctx); err != nil {
+		t.Fatalf("token without client certificate: %v", err)
+	}
+	neither := &client.HTTPClient{BaseURL: ts.URL, CAFile: caFile}
+	if _, err := neither.GetVersion(ctx); err == nil {
+		t.Fatalf("expected 401 without token or certificate")
+	}
+	intruder := &client.HTTPClient{BaseURL: ts.URL, CAFile: caFile, CertFile: rogueCert, KeyFile: rogueKey, Retry: httpretry.Policy{MaxAttempts: 1}}
+	if _, err := intruder.GetVersion(ctx); err == nil {
+		t.Fatalf("expected a certificate from another CA to be rejected")
+	}
+
+	// Public routes stay reachable with plain TLS.
+	plain, err := httptransport.NewClient(httptransport.Options{CAFile: caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := plain.Get(ts.URL + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("healthz status = %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/authz"
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/config"
+)
+
+func TestNamedTokens_EnforceScopesAndRecordName(t *testing.T) {
+	expired := time.Now().Add(-time.Hour)
+	ts, cfg, _, cancel := newTestServerWithConfig(t, &builder.FakeBuilder{}, func(cfg *config.Config) {
+		cfg.Tokens = []authz.Token{
+			{Name: "ci", Token: "ci-secret", Scopes: []authz.Scope{authz.ScopeRead, authz.ScopeSubmit}},
+			{Name: "dash", Token: "dash-secret", Scopes: []authz.Scope{authz.ScopeRead}},
+			{Name: "old", Token: "old-secret", Scopes: []authz.Scope{authz.ScopeAdmin}, ExpiresAt: &expired},
+		}
+	})
+	defer cancel()
+
+	ci := cfg
+	ci.Token = "ci-secret"
+	jobID := submitBundle(t, ts.URL, ci, validBundleBytes(t, "ok"))
+	rec := waitForJobTerminalHTTP(t, ts.URL, ci, jobID)
+	if rec.TokenName != "ci" {
+		t.Fatalf("token_name = %q, want ci", rec.TokenName)
+	}
+
+	dash := cfg
+	dash.Token = "dash-secret"
+	resp := authGet(t, ts.URL+"/v1/jobs/"+jobID, dash)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("read token GET status = %d, want 200", resp.StatusCode)
+	}
+	body, contentType := multipartBody(t, validBundleBytes(t, "ok"))
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/v1/jobs", &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set(cfg.AuthHeader, dash.Token)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("read token submit status = %d, want 403", resp.StatusCode)
+	}
+
+	resp = authGet(t, ts.URL+"/v1/admin/selftest", ci)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("submit token admin status = %d, want 403", resp.StatusCode)
+	}
+
+	old := cfg
+	old.Token = "old-secret"
+	resp = authGet(t, ts.URL+"/v1/jobs", old)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expired token status = %d, want 401", resp.StatusCode)
+	}
+
+	resp = authGet(t, ts.URL+"/v1/admin/selftest", cfg)
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		t.Fatalf("shared token admin status = %d", resp.StatusCode)
+	}
+}
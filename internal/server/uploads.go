@@ -0,0 +1,139 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/mblsha/spadeforge/internal/authz"
+	"github.com/mblsha/spadeforge/internal/httpmw"
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/queue"
+	"github.com/mblsha/spadeforge/internal/upload"
+)
+
+// handleCreateUpload starts a resumable bundle upload. Idle uploads are
+// pruned here rather than by a timer, since they only pile up while
+// clients keep creating them.
+func (a *API) handleCreateUpload(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	var req job.UploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid upload request: " + err.Error()})
+		return
+	}
+	if req.Size > a.cfg.MaxUploadBytes {
+		writeJSON(w, http.StatusRequestEntityTooLarge, httpmw.UploadTooLarge{
+			Error:      fmt.Sprintf("bundle upload exceeds the %d byte limit", a.cfg.MaxUploadBytes),
+			Upload:     "bundle",
+			LimitBytes: a.cfg.MaxUploadBytes,
+		})
+		return
+	}
+	submitter, err := checkSubmitter(r, req.Submitter)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	req.Submitter = submitter
+
+	if n, err := a.uploads.Prune(); err != nil {
+		hlog.Warnf("prune uploads: %v", err)
+	} else if n > 0 {
+		hlog.Infof("pruned %d idle uploads", n)
+	}
+	up, err := a.uploads.Create(req)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusCreated, up)
+}
+
+func (a *API) handleGetUpload(w http.ResponseWriter, r *http.Request) {
+	up, err := a.uploads.Get(r.PathValue("id"))
+	if err != nil {
+		writeUploadError(w, up, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, up)
+}
+
+// handleAppendUpload writes the request body at the Upload-Offset header.
+// A chunk cut short by a dropped connection keeps the bytes that arrived.
+func (a *API) handleAppendUpload(w http.ResponseWriter, r *http.Request) {
+	offset, err := strconv.ParseInt(r.Header.Get(job.UploadOffsetHeader), 10, 64)
+	if err != nil || offset < 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": job.UploadOffsetHeader + " header must be a byte offset"})
+		return
+	}
+	up, err := a.uploads.Append(r.PathValue("id"), offset, r.Body)
+	if err != nil {
+		writeUploadError(w, up, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, up)
+}
+
+func (a *API) handleDeleteUpload(w http.ResponseWriter, r *http.Request) {
+	if err := a.uploads.Remove(r.PathValue("id")); err != nil {
+		writeUploadError(w, nil, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleFinalizeUpload verifies a complete upload and submits it as a job,
+// answering like POST /v1/jobs, per-client quotas included. The upload is
+// gone afterwards whether or not the bundle was accepted, unless a quota
+// turned the request away.
+func (a *API) handleFinalizeUpload(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if a.quotasEnabled() {
+		a.finalizeMu.Lock()
+		defer a.finalizeMu.Unlock()
+		// Checked before Open so a client turned away keeps its upload
+		// and can finalize it later.
+		if a.rejectOverQuota(w, r, true) {
+			return
+		}
+	}
+	up, data, err := a.uploads.Open(id)
+	if err != nil {
+		writeUploadError(w, up, err)
+		return
+	}
+	rec, err := a.manager.SubmitWithOptions(r.Context(), queue.SubmitOptions{Submitter: up.Submitter, NoCache: up.NoCache, TokenName: authz.Name(r.Context()), ClientIP: httpmw.ClientKey(r)}, data)
+	// Closing the data removes the upload.
+	if rmErr := data.Close(); rmErr != nil {
+		hlog.Warnf("remove finalized upload %s: %v", id, rmErr)
+	}
+	a.writeSubmitted(w, rec, err)
+}
+
+// writeUploadError maps upload errors to statuses. Offset conflicts and
+// incomplete uploads carry the upload so the client can resume at its
+// offset.
+func writeUploadError(w http.ResponseWriter, up *job.Upload, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, upload.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, upload.ErrOffsetMismatch), errors.Is(err, upload.ErrIncomplete), errors.Is(err, upload.ErrBusy):
+		status = http.StatusConflict
+	case errors.Is(err, upload.ErrTooLarge):
+		status = http.StatusRequestEntityTooLarge
+	case errors.Is(err, upload.ErrChecksum):
+		status = http.StatusUnprocessableEntity
+	}
+	if status == http.StatusInternalServerError {
+		hlog.Errorf("upload: %v", err)
+	}
+	body := map[string]any{"error": err.Error()}
+	if up != nil {
+		body["upload"] = up
+	}
+	writeJSON(w, status, body)
+}
@@ -0,0 +1,175 @@
+// Package bitstream sanity-checks uploaded FPGA bitstreams so text files,
+// wrong-vendor images and truncated transfers are rejected before they
+// reach hardware.
+package bitstream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	FormatXilinx  = "xilinx"
+	FormatLattice = "lattice"
+)
+
+// scanLimit bounds how far into a file the sync word is searched for;
+// vendor headers and padding are far shorter.
+const scanLimit = 64 << 10
+
+// ErrInvalid marks an upload that is not a usable bitstream.
+var ErrInvalid = errors.New("invalid bitstream")
+
+var (
+	// xilinxHeader starts every Vivado .bit file: a 9-byte magic field
+	// followed by the 'a' (design name) key.
+	xilinxHeader = []byte{0x00, 0x09, 0x0f, 0xf0, 0x0f, 0xf0, 0x0f, 0xf0, 0x0f, 0xf0, 0x00, 0x00, 0x01, 'a'}
+	xilinxSync   = []byte{0xaa, 0x99, 0x55, 0x66}
+	// ecp5Preamble is the ECP5/MachXO2/MachXO3 preamble; ice40Preamble the
+	// iCE40 one.
+	ecp5Preamble  = []byte{0xff, 0xff, 0xbd, 0xb3}
+	ice40Preamble = []byte{0x7e, 0xaa, 0x99, 0x7e}
+)
+
+// Info describes a recognised bitstream. Design, Part and Built come from
+// the Xilinx .bit header and are empty for other formats.
+type Info struct {
+	Format string `json:"format"`
+	Design string `json:"design,omitempty"`
+	Part   string `json:"part,omitempty"`
+	Built  string `json:"built,omitempty"`
+}
+
+// Inspect identifies the bitstream in the first size bytes of r. Errors
+// wrap ErrInvalid and say what was wrong.
+func Inspect(r io.ReaderAt, size int64) (Info, error) {
+	if size <= 0 {
+		return Info{}, fmt.Errorf("%w: file is empty", ErrInvalid)
+	}
+	head := make([]byte, min(size, scanLimit))
+	if _, err := r.ReadAt(head, 0); err != nil && !errors.Is(err, io.EOF) {
+		return Info{}, fmt.Errorf("read bitstream: %w", err)
+	}
+
+	if bytes.HasPrefix(head, xilinxHeader) {
+		return inspectXilinxBit(head, size)
+	}
+	switch {
+	case bytes.Contains(head, xilinxSync):
+		return Info{Format: FormatXilinx}, nil
+	case bytes.Contains(head, ecp5Preamble), bytes.Contains(head, ice40Preamble):
+		return Info{Format: FormatLattice}, nil
+	}
+	if looksLikeText(head) {
+		return Info{}, fmt.Errorf("%w: file looks like text, not a bitstream", ErrInvalid)
+	}
+	return Info{}, fmt.Errorf("%w: no Xilinx sync word or Lattice preamble in the first %d bytes", ErrInvalid, len(head))
+}
+
+// inspectXilinxBit walks the keyed header of a Vivado .bit file and checks
+// that the configuration data it announces was uploaded in full.
+func inspectXilinxBit(head []byte, size int64) (Info, error) {
+	info := Info{Format: FormatXilinx}
+	pos := len(xilinxHeader) - 1
+	var date string
+	for _, key := range []byte{'a', 'b', 'c', 'd'} {
+		if pos+3 > len(head) || head[pos] != key {
+			return Info{}, fmt.Errorf("%w: malformed Xilinx header: missing field %q", ErrInvalid, key)
+		}
+		n := int(binary.BigEndian.Uint16(head[pos+1:]))
+		start := pos + 3
+		if start+n > len(head) {
+			return Info{}, fmt.Errorf("%w: malformed Xilinx header: field %q overruns the file", ErrInvalid, key)
+		}
+		value := strings.TrimRight(string(head[start:start+n]), "\x00")
+		switch key {
+		case 'a':
+			info.Design = value
+		case 'b':
+			info.Part = value
+		case 'c':
+			date = value
+		case 'd':
+			info.Built = strings.TrimSpace(date + " " + value)
+		}
+		pos = start + n
+	}
+	if pos+5 > len(head) || head[pos] != 'e' {
+		return Info{}, fmt.Errorf("%w: malformed Xilinx header: missing data length", ErrInvalid)
+	}
+	dataLen := int64(binary.BigEndian.Uint32(head[pos+1:]))
+	dataStart := int64(pos + 5)
+	if got := size - dataStart; got < dataLen {
+		return Info{}, fmt.Errorf("%w: truncated: header announces %d bytes of configuration data but only %d were uploaded", ErrInvalid, dataLen, got)
+	}
+	if !bytes.Contains(head[dataStart:], xilinxSync) {
+		return Info{}, fmt.Errorf("%w: Xilinx header present but no sync word in the configuration data", ErrInvalid)
+	}
+	return info, nil
+}
+
+// looksLikeText reports whether head is valid UTF-8 made of printable
+// characters and whitespace.
+func looksLikeText(head []byte) bool {
+	sample := head[:min(len(head), 512)]
+	for len(sample) > 0 {
+		r, n := utf8.DecodeRune(sample)
+		if r == utf8.RuneError && n <= 1 {
+			// A rune cut off at the sample boundary is still text.
+			return len(sample) < utf8.UTFMax && len(head) > 512
+		}
+		if r < 0x20 && r != '\n' && r != '\r' && r != '\t' {
+			return false
+		}
+		sample = sample[n:]
+	}
+	return true
+}
+
+// boardFormats maps openFPGALoader board names to the bitstream format
+// their FPGA takes. Boards not listed accept any recognised format.
+var boardFormats = map[string]string{
+	"ac701":            FormatXilinx,
+	"alchitry_au":      FormatXilinx,
+	"alchitry_au_plus": FormatXilinx,
+	"arty":             FormatXilinx,
+	"arty_a7_35t":      FormatXilinx,
+	"arty_a7_100t":     FormatXilinx,
+	"arty_s7_25":       FormatXilinx,
+	"arty_s7_50":       FormatXilinx,
+	"basys3":           FormatXilinx,
+	"cmoda7_15t":       FormatXilinx,
+	"cmoda7_35t":       FormatXilinx,
+	"genesys2":         FormatXilinx,
+	"kc705":            FormatXilinx,
+	"nexys_a7_50":      FormatXilinx,
+	"nexys_a7_100":     FormatXilinx,
+	"nexysVideo":       FormatXilinx,
+	"zedboard":         FormatXilinx,
+	"zybo_z7_10":       FormatXilinx,
+	"zybo_z7_20":       FormatXilinx,
+	"colorlight":       FormatLattice,
+	"colorlight-i5":    FormatLattice,
+	"colorlight-i9":    FormatLattice,
+	"ecp5_evn":         FormatLattice,
+	"ice40_generic":    FormatLattice,
+	"icebreaker":       FormatLattice,
+	"machXO2EVN":       FormatLattice,
+	"machXO3EVN":       FormatLattice,
+	"orangeCrab":       FormatLattice,
+	"ulx3s":            FormatLattice,
+}
+
+// CheckBoard rejects a bitstream whose format cannot program board.
+func CheckBoard(board string, info Info) error {
+	want, ok := boardFormats[board]
+	if !ok || want == info.Format {
+		return nil
+	}
+	return fmt.Errorf("%w: %s bitstream cannot program %s, which takes %s bitstreams", ErrInvalid, info.Format, board, want)
+}
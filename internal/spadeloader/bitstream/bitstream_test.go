@@ -0,0 +1,93 @@
+package bitstream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// xilinxBit builds a minimal Vivado-style .bit file with data bytes of
+// configuration data after the header.
+func xilinxBit(part string, data []byte) []byte {
+	var b bytes.Buffer
+	b.Write(xilinxHeader[:len(xilinxHeader)-1])
+	field := func(key byte, value string) {
+		b.WriteByte(key)
+		_ = binary.Write(&b, binary.BigEndian, uint16(len(value)+1))
+		b.WriteString(value)
+		b.WriteByte(0)
+	}
+	field('a', "blink;UserID=0XFFFFFFFF")
+	field('b', part)
+	field('c', "2026/10/16")
+	field('d', "12:00:00")
+	b.WriteByte('e')
+	_ = binary.Write(&b, binary.BigEndian, uint32(len(data)))
+	b.Write(data)
+	return b.Bytes()
+}
+
+func configData() []byte {
+	return append(append(bytes.Repeat([]byte{0xff}, 32), xilinxSync...), bytes.Repeat([]byte{0x20, 0, 0, 0}, 16)...)
+}
+
+func TestInspect_RecognisesFormats(t *testing.T) {
+	bit := xilinxBit("7a35tcsg324", configData())
+	info, err := Inspect(bytes.NewReader(bit), int64(len(bit)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Format != FormatXilinx || info.Part != "7a35tcsg324" || info.Design != "blink;UserID=0XFFFFFFFF" || info.Built != "2026/10/16 12:00:00" {
+		t.Fatalf("unexpected Xilinx info: %+v", info)
+	}
+
+	for name, tc := range map[string]struct {
+		data   []byte
+		format string
+	}{
+		"raw xilinx": {configData(), FormatXilinx},
+		"ecp5":       {append([]byte{0xff, 0x00, 'c', 'o', 'm', 0x00, 0xff}, append(ecp5Preamble, 0xe2, 0, 0, 0)...), FormatLattice},
+		"ice40":      {append([]byte{0xff, 0x00, 0x00, 0xff}, append(ice40Preamble, 0x92, 0x00)...), FormatLattice},
+	} {
+		info, err := Inspect(bytes.NewReader(tc.data), int64(len(tc.data)))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if info.Format != tc.format {
+			t.Fatalf("%s: format = %s, want %s", name, info.Format, tc.format)
+		}
+	}
+}
+
+func TestInspect_RejectsTextTruncatedAndUnknown(t *testing.T) {
+	bit := xilinxBit("7a35tcsg324", configData())
+	for name, tc := range map[string]struct {
+		data []byte
+		want string
+	}{
+		"empty":     {nil, "empty"},
+		"text":      {[]byte("<html>404 Not Found</html>\n"), "looks like text"},
+		"truncated": {bit[:len(bit)-10], "truncated"},
+		"header":    {bit[:20], "malformed Xilinx header"},
+		"unknown":   {bytes.Repeat([]byte{0x00, 0x01, 0xfe}, 100), "no Xilinx sync word"},
+	} {
+		_, err := Inspect(bytes.NewReader(tc.data), int64(len(tc.data)))
+		if !errors.Is(err, ErrInvalid) || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: error = %v, want %q", name, err, tc.want)
+		}
+	}
+}
+
+func TestCheckBoard(t *testing.T) {
+	if err := CheckBoard("alchitry_au", Info{Format: FormatXilinx}); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckBoard("ulx3s", Info{Format: FormatXilinx}); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected wrong-vendor bitstream to be rejected, got %v", err)
+	}
+	if err := CheckBoard("bench-3", Info{Format: FormatLattice}); err != nil {
+		t.Fatalf("expected unknown board to accept any format, got %v", err)
+	}
+}
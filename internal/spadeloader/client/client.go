@@ -1,10 +1,10 @@
 package client
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -15,10 +15,16 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/mblsha/spadeforge/internal/buildinfo"
+	"github.com/mblsha/spadeforge/internal/httpretry"
+	"github.com/mblsha/spadeforge/internal/httptransport"
+	"github.com/mblsha/spadeforge/internal/pollwait"
 	"github.com/mblsha/spadeforge/internal/spadeloader/history"
 	"github.com/mblsha/spadeforge/internal/spadeloader/job"
+	"github.com/mblsha/spadeforge/internal/sse"
 )
 
 const defaultAuthHeader = "X-Build-Token"
@@ -27,6 +33,18 @@ type SubmitRequest struct {
 	Board         string
 	DesignName    string
 	BitstreamPath string
+	// DryRun asks the server to detect the board without programming it.
+	DryRun bool
+	// Mode flash writes the SPI flash at FlashOffset so the design survives
+	// power cycles; empty loads SRAM.
+	Mode        job.Mode
+	FlashOffset uint32
+	// Target picks the programmer when several boards are attached.
+	Target job.Target
+	// Monitor asks the server to capture the board's UART after the flash,
+	// at MonitorBaud when set or else the port's configured rate.
+	Monitor     bool
+	MonitorBaud int
 }
 
 type HTTPClient struct {
@@ -34,72 +52,191 @@ type HTTPClient struct {
 	Token      string
 	AuthHeader string
 	Client     *http.Client
+
+	// StreamIdleTimeout bounds how long StreamEvents waits without receiving
+	// any data, keepalives included, before reconnecting. Zero uses
+	// sse.DefaultIdleTimeout.
+	StreamIdleTimeout time.Duration
+
+	// Retry governs retries of idempotent GETs and download resumption. The
+	// zero value uses httpretry.Default.
+	Retry httpretry.Policy
+
+	// CAFile and InsecureSkipVerify configure TLS when Client is nil. Proxy
+	// settings are always taken from HTTPS_PROXY/HTTP_PROXY/NO_PROXY.
+	CAFile             string
+	InsecureSkipVerify bool
+	// CertFile and KeyFile are a client certificate and key for servers
+	// that accept one in place of the token.
+	CertFile string
+	KeyFile  string
+	// LimitRate caps uploads and downloads, together, to this many bytes
+	// per second when Client is nil. Zero is unlimited.
+	LimitRate int64
+
+	transportOnce sync.Once
+	transport     *http.Client
+}
+
+func writeTargetFields(mw *multipart.Writer, t job.Target) error {
+	fields := [][2]string{{"device", t.Device}, {"cable", t.Cable}}
+	if t.FTDIIndex != nil {
+		fields = append(fields, [2]string{"ftdi_index", strconv.Itoa(*t.FTDIIndex)})
+	}
+	for _, f := range fields {
+		if f[1] == "" {
+			continue
+		}
+		if err := mw.WriteField(f[0], f[1]); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (c *HTTPClient) SubmitFlash(ctx context.Context, req SubmitRequest) (string, error) {
-	file, err := os.Open(req.BitstreamPath)
+	resp, err := c.postFlash(ctx, req, nil)
 	if err != nil {
 		return "", err
 	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		raw, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("submit failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	var payload struct {
+		JobID string `json:"job_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", err
+	}
+	if payload.JobID == "" {
+		return "", fmt.Errorf("submit response missing job_id")
+	}
+	return payload.JobID, nil
+}
+
+// SubmitFlashBatch uploads req's bitstream once and flashes it onto every
+// board in boards, or onto every board tagged t when req.Board is
+// "all-tagged:t" and boards is empty. The server queues one job per board.
+func (c *HTTPClient) SubmitFlashBatch(ctx context.Context, req SubmitRequest, boards []string) (*job.Batch, error) {
+	if len(boards) > 0 {
+		req.Board = ""
+	}
+	resp, err := c.postFlash(ctx, req, boards)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		raw, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("submit batch failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	var b job.Batch
+	if err := json.NewDecoder(resp.Body).Decode(&b); err != nil {
+		return nil, err
+	}
+	if b.ID == "" {
+		return nil, fmt.Errorf("submit response missing batch_id")
+	}
+	return &b, nil
+}
+
+// postFlash posts req's form and bitstream to /v1/jobs.
+func (c *HTTPClient) postFlash(ctx context.Context, req SubmitRequest, boards []string) (*http.Response, error) {
+	file, err := os.Open(req.BitstreamPath)
+	if err != nil {
+		return nil, err
+	}
 	defer file.Close()
 
 	var body bytes.Buffer
 	mw := multipart.NewWriter(&body)
-	if err := mw.WriteField("board", req.Board); err != nil {
-		return "", err
+	if req.Board != "" {
+		if err := mw.WriteField("board", req.Board); err != nil {
+			return nil, err
+		}
+	}
+	if len(boards) > 0 {
+		if err := mw.WriteField("boards", strings.Join(boards, ",")); err != nil {
+			return nil, err
+		}
 	}
 	if err := mw.WriteField("design_name", req.DesignName); err != nil {
-		return "", err
+		return nil, err
+	}
+	if req.DryRun {
+		if err := mw.WriteField("dry_run", "1"); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeTargetFields(mw, req.Target); err != nil {
+		return nil, err
+	}
+	if req.Mode != "" {
+		if err := mw.WriteField("mode", string(req.Mode)); err != nil {
+			return nil, err
+		}
+	}
+	if req.FlashOffset != 0 {
+		if err := mw.WriteField("flash_offset", fmt.Sprintf("0x%x", req.FlashOffset)); err != nil {
+			return nil, err
+		}
+	}
+	if req.Monitor {
+		if err := mw.WriteField("monitor", "1"); err != nil {
+			return nil, err
+		}
+		if req.MonitorBaud > 0 {
+			if err := mw.WriteField("baud", strconv.Itoa(req.MonitorBaud)); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	fw, err := mw.CreateFormFile("bitstream", filepath.Base(req.BitstreamPath))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if _, err := io.Copy(fw, file); err != nil {
-		return "", err
+		return nil, err
 	}
 	if err := mw.Close(); err != nil {
-		return "", err
+		return nil, err
 	}
 
 	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.buildURL("/v1/jobs"), &body)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	httpReq.Header.Set("Content-Type", mw.FormDataContentType())
 	c.setAuth(httpReq)
+	return c.httpClient().Do(httpReq)
+}
 
-	resp, err := c.httpClient().Do(httpReq)
+// GetBatch returns the jobs of a batch submission.
+func (c *HTTPClient) GetBatch(ctx context.Context, batchID string) (*job.Batch, error) {
+	resp, err := c.get(ctx, c.buildURL(path.Join("/v1/batches", batchID)))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusAccepted {
+	if resp.StatusCode != http.StatusOK {
 		raw, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("submit failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
+		return nil, fmt.Errorf("get batch failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
 	}
-	var payload struct {
-		JobID string `json:"job_id"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
-		return "", err
-	}
-	if payload.JobID == "" {
-		return "", fmt.Errorf("submit response missing job_id")
+	var b job.Batch
+	if err := json.NewDecoder(resp.Body).Decode(&b); err != nil {
+		return nil, err
 	}
-	return payload.JobID, nil
+	return &b, nil
 }
 
 func (c *HTTPClient) GetJob(ctx context.Context, jobID string) (*job.Record, error) {
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, c.buildURL(path.Join("/v1/jobs", jobID)), nil)
-	if err != nil {
-		return nil, err
-	}
-	c.setAuth(httpReq)
-
-	resp, err := c.httpClient().Do(httpReq)
+	resp, err := c.get(ctx, c.buildURL(path.Join("/v1/jobs", jobID)))
 	if err != nil {
 		return nil, err
 	}
@@ -129,13 +266,7 @@ func (c *HTTPClient) ListJobs(ctx context.Context, limit int) ([]job.Record, err
 		parsed.RawQuery = q.Encode()
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, parsed.String(), nil)
-	if err != nil {
-		return nil, err
-	}
-	c.setAuth(httpReq)
-
-	resp, err := c.httpClient().Do(httpReq)
+	resp, err := c.get(ctx, parsed.String())
 	if err != nil {
 		return nil, err
 	}
@@ -155,11 +286,45 @@ func (c *HTTPClient) ListJobs(ctx context.Context, limit int) ([]job.Record, err
 	return payload.Items, nil
 }
 
+// Retarget points a reflash at another board or programmer.
+type Retarget struct {
+	// Board replaces the source job's board when set.
+	Board string
+	// Target replaces the source job's programmer selection.
+	Target job.Target
+}
+
 func (c *HTTPClient) ReflashJob(ctx context.Context, sourceJobID string) (string, error) {
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.buildURL(path.Join("/v1/jobs", sourceJobID, "reflash")), nil)
+	return c.ReflashJobTo(ctx, sourceJobID, nil)
+}
+
+// ReflashJobTo reflashes the source job's bitstream onto the board and
+// programmer in to; a nil to keeps the source job's.
+func (c *HTTPClient) ReflashJobTo(ctx context.Context, sourceJobID string, to *Retarget) (string, error) {
+	var body io.Reader
+	if to != nil {
+		form := url.Values{}
+		if to.Board != "" {
+			form.Set("board", to.Board)
+		}
+		if to.Target.Device != "" {
+			form.Set("device", to.Target.Device)
+		}
+		if to.Target.Cable != "" {
+			form.Set("cable", to.Target.Cable)
+		}
+		if to.Target.FTDIIndex != nil {
+			form.Set("ftdi_index", strconv.Itoa(*to.Target.FTDIIndex))
+		}
+		body = strings.NewReader(form.Encode())
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.buildURL(path.Join("/v1/jobs", sourceJobID, "reflash")), body)
 	if err != nil {
 		return "", err
 	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
 	c.setAuth(httpReq)
 
 	resp, err := c.httpClient().Do(httpReq)
@@ -185,6 +350,9 @@ func (c *HTTPClient) ReflashJob(ctx context.Context, sourceJobID string) (string
 	return strings.TrimSpace(payload.JobID), nil
 }
 
+// WaitForTerminalWithProgress polls the job until it finishes. pollInterval
+// is the fastest rate, used while the job is queued and right after a state
+// or step change; the interval backs off while the job stays in one step.
 func (c *HTTPClient) WaitForTerminalWithProgress(
 	ctx context.Context,
 	jobID string,
@@ -194,9 +362,7 @@ func (c *HTTPClient) WaitForTerminalWithProgress(
 	if pollInterval <= 0 {
 		pollInterval = 500 * time.Millisecond
 	}
-	ticker := time.NewTicker(pollInterval)
-	defer ticker.Stop()
-
+	backoff := pollwait.New(pollInterval)
 	for {
 		record, err := c.GetJob(ctx, jobID)
 		if err != nil {
@@ -208,22 +374,52 @@ func (c *HTTPClient) WaitForTerminalWithProgress(
 		if record.Terminal() {
 			return record, nil
 		}
+		wait := backoff.Next(string(record.State)+"/"+record.CurrentStep, record.State == job.StateQueued)
+		timer := time.NewTimer(wait)
 		select {
 		case <-ctx.Done():
+			timer.Stop()
 			return nil, ctx.Err()
-		case <-ticker.C:
+		case <-timer.C:
 		}
 	}
 }
 
-func (c *HTTPClient) GetLog(ctx context.Context, jobID string) (string, error) {
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, c.buildURL(path.Join("/v1/jobs", jobID, "log")), nil)
-	if err != nil {
-		return "", err
+// WaitForBatch polls the batch like WaitForTerminalWithProgress until all of
+// its jobs have finished.
+func (c *HTTPClient) WaitForBatch(ctx context.Context, batchID string, pollInterval time.Duration, onUpdate func(b *job.Batch)) (*job.Batch, error) {
+	if pollInterval <= 0 {
+		pollInterval = 500 * time.Millisecond
 	}
-	c.setAuth(httpReq)
+	backoff := pollwait.New(pollInterval)
+	for {
+		b, err := c.GetBatch(ctx, batchID)
+		if err != nil {
+			return nil, err
+		}
+		if onUpdate != nil {
+			onUpdate(b)
+		}
+		if b.Terminal() {
+			return b, nil
+		}
+		var phase strings.Builder
+		for _, rec := range b.Jobs {
+			phase.WriteString(string(rec.State) + "/" + rec.CurrentStep + ";")
+		}
+		wait := backoff.Next(phase.String(), b.State == job.StateQueued)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
 
-	resp, err := c.httpClient().Do(httpReq)
+func (c *HTTPClient) GetLog(ctx context.Context, jobID string) (string, error) {
+	resp, err := c.get(ctx, c.buildURL(path.Join("/v1/jobs", jobID, "log")))
 	if err != nil {
 		return "", err
 	}
@@ -239,6 +435,36 @@ func (c *HTTPClient) GetLog(ctx context.Context, jobID string) (string, error) {
 	return string(raw), nil
 }
 
+// DownloadArtifacts writes the finished job's artifacts zip to out.
+func (c *HTTPClient) DownloadArtifacts(ctx context.Context, jobID string, out io.Writer) error {
+	resp, err := c.get(ctx, c.buildURL(path.Join("/v1/jobs", jobID, "artifacts")))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("download artifacts failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	_, err = io.Copy(out, resp.Body)
+	return err
+}
+
+// DownloadBitstream writes the bitstream the job was submitted with to out.
+func (c *HTTPClient) DownloadBitstream(ctx context.Context, jobID string, out io.Writer) error {
+	resp, err := c.get(ctx, c.buildURL(path.Join("/v1/jobs", jobID, "bitstream")))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("download bitstream failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	_, err = io.Copy(out, resp.Body)
+	return err
+}
+
 func (c *HTTPClient) GetLogTail(ctx context.Context, jobID string, lines int) (string, error) {
 	if lines <= 0 {
 		lines = 200
@@ -252,13 +478,24 @@ func (c *HTTPClient) GetLogTail(ctx context.Context, jobID string, lines int) (s
 	q.Set("lines", strconv.Itoa(lines))
 	parsed.RawQuery = q.Encode()
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, parsed.String(), nil)
+	resp, err := c.get(ctx, parsed.String())
 	if err != nil {
 		return "", err
 	}
-	c.setAuth(httpReq)
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("get log tail failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	return string(raw), nil
+}
 
-	resp, err := c.httpClient().Do(httpReq)
+// GetSerial returns what the job's serial monitor has captured so far.
+func (c *HTTPClient) GetSerial(ctx context.Context, jobID string) (string, error) {
+	resp, err := c.get(ctx, c.buildURL(path.Join("/v1/jobs", jobID, "serial")))
 	if err != nil {
 		return "", err
 	}
@@ -268,13 +505,91 @@ func (c *HTTPClient) GetLogTail(ctx context.Context, jobID string, lines int) (s
 		return "", err
 	}
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("get log tail failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
+		return "", fmt.Errorf("get serial failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
 	}
 	return string(raw), nil
 }
 
+// StreamSerial follows the job's serial monitor from the start of its
+// capture, calling onChunk for each piece of output, until the Done chunk.
+// The monitor ending with an error is not an error of StreamSerial; the
+// Done chunk carries it.
+func (c *HTTPClient) StreamSerial(ctx context.Context, jobID string, onChunk func(job.SerialChunk)) error {
+	reqURL := c.buildURL(path.Join("/v1/jobs", jobID, "serial"))
+	parsed, err := url.Parse(reqURL)
+	if err != nil {
+		return err
+	}
+	q := parsed.Query()
+	q.Set("follow", "1")
+	parsed.RawQuery = q.Encode()
+
+	resp, err := c.get(ctx, parsed.String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("stream serial failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+
+	done := false
+	dec := &sse.Decoder{IdleTimeout: c.streamIdleTimeout()}
+	err = dec.Decode(resp.Body, func(data string) error {
+		var chunk job.SerialChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("decode serial chunk: %w", err)
+		}
+		done = done || chunk.Done
+		onChunk(chunk)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !done {
+		return errors.New("serial stream closed before the monitor ended")
+	}
+	return nil
+}
+
+// StreamEvents follows the job's server-sent event stream until the server
+// closes it. A connection that goes silent for longer than
+// StreamIdleTimeout is treated as dead and re-established from the last
+// received event, honoring the server's retry hint.
+// A dropped_events notice triggers an immediate reconnect from the last
+// received event, so onEvent sees the events the server skipped.
 func (c *HTTPClient) StreamEvents(ctx context.Context, jobID string, since int64, onEvent func(*job.Event)) error {
-	reqURL := c.buildURL(path.Join("/v1/jobs", jobID, "events"))
+	dec := &sse.Decoder{IdleTimeout: c.streamIdleTimeout()}
+	return sse.Follow(ctx, since, eventSeq, onEvent, dec.RetryDelay, func(since int64, emit func(*job.Event)) error {
+		return c.streamEventsOnce(ctx, path.Join("/v1/jobs", jobID, "events"), since, dec, nil, emit)
+	})
+}
+
+// StreamAllEvents follows GET /v1/events, every job's events numbered by a
+// server-wide seq, until ctx is done. since=0 starts with a snapshot event
+// per queued or running job. Stalls and dropped_events notices reconnect
+// from the last received event as in StreamEvents. onConnect, when set, is
+// called every time the stream is established.
+func (c *HTTPClient) StreamAllEvents(ctx context.Context, since int64, onConnect func(), onEvent func(*job.Event)) error {
+	dec := &sse.Decoder{IdleTimeout: c.streamIdleTimeout()}
+	return sse.Follow(ctx, since, eventSeq, onEvent, dec.RetryDelay, func(since int64, emit func(*job.Event)) error {
+		err := c.streamEventsOnce(ctx, "/v1/events", since, dec, onConnect, emit)
+		if err == nil && ctx.Err() == nil {
+			// The server closed a stream that should stay open, e.g. on
+			// restart; resume like after a stall.
+			return sse.ErrStalled
+		}
+		return err
+	})
+}
+
+// eventSeq is the resume point sse.Follow tracks.
+func eventSeq(ev *job.Event) int64 { return ev.Seq }
+
+func (c *HTTPClient) streamEventsOnce(ctx context.Context, pathPart string, since int64, dec *sse.Decoder, onConnect func(), onEvent func(*job.Event)) error {
+	reqURL := c.buildURL(pathPart)
 	parsed, err := url.Parse(reqURL)
 	if err != nil {
 		return err
@@ -285,13 +600,7 @@ func (c *HTTPClient) StreamEvents(ctx context.Context, jobID string, since int64
 		parsed.RawQuery = q.Encode()
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, parsed.String(), nil)
-	if err != nil {
-		return err
-	}
-	c.setAuth(httpReq)
-
-	resp, err := c.httpClient().Do(httpReq)
+	resp, err := c.get(ctx, parsed.String())
 	if err != nil {
 		return err
 	}
@@ -300,46 +609,28 @@ func (c *HTTPClient) StreamEvents(ctx context.Context, jobID string, since int64
 		raw, _ := io.ReadAll(resp.Body)
 		return fmt.Errorf("stream events failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
 	}
+	if onConnect != nil {
+		onConnect()
+	}
 
-	scanner := bufio.NewScanner(resp.Body)
-	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
-	dataLines := make([]string, 0, 4)
-	dispatch := func() error {
-		if len(dataLines) == 0 {
-			return nil
-		}
-		payload := strings.Join(dataLines, "\n")
-		dataLines = dataLines[:0]
+	return dec.Decode(resp.Body, func(data string) error {
 		var ev job.Event
-		if err := json.Unmarshal([]byte(payload), &ev); err != nil {
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
 			return fmt.Errorf("decode sse event: %w", err)
 		}
-		if onEvent != nil {
-			onEvent(&ev)
+		if ev.Type == job.EventDroppedEvents {
+			return sse.ErrResync
 		}
+		onEvent(&ev)
 		return nil
-	}
+	})
+}
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		if line == "" {
-			if err := dispatch(); err != nil {
-				return err
-			}
-			continue
-		}
-		if strings.HasPrefix(line, ":") {
-			continue
-		}
-		if strings.HasPrefix(line, "data:") {
-			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
-			dataLines = append(dataLines, data)
-		}
-	}
-	if err := scanner.Err(); err != nil {
-		return err
+func (c *HTTPClient) streamIdleTimeout() time.Duration {
+	if c.StreamIdleTimeout > 0 {
+		return c.StreamIdleTimeout
 	}
-	return dispatch()
+	return sse.DefaultIdleTimeout
 }
 
 func (c *HTTPClient) GetRecentDesigns(ctx context.Context, limit int) ([]history.Item, error) {
@@ -354,24 +645,59 @@ func (c *HTTPClient) GetRecentDesigns(ctx context.Context, limit int) ([]history
 		parsed.RawQuery = q.Encode()
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, parsed.String(), nil)
+	resp, err := c.get(ctx, parsed.String())
 	if err != nil {
 		return nil, err
 	}
-	c.setAuth(httpReq)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("get recent designs failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
 
-	resp, err := c.httpClient().Do(httpReq)
+	var payload struct {
+		Items []history.Item `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, err
+	}
+	return payload.Items, nil
+}
+
+// GetKiosk returns the server's kiosk mode and its golden designs.
+func (c *HTTPClient) GetKiosk(ctx context.Context) (*job.KioskStatus, error) {
+	resp, err := c.get(ctx, c.buildURL("/v1/kiosk"))
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
 		raw, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("get recent designs failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
+		return nil, fmt.Errorf("get kiosk failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+
+	var status job.KioskStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// ListDevices returns the programmers the server has seen, connected
+// ones first.
+func (c *HTTPClient) ListDevices(ctx context.Context) ([]job.Device, error) {
+	resp, err := c.get(ctx, c.buildURL("/v1/devices"))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("list devices failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
 	}
 
 	var payload struct {
-		Items []history.Item `json:"items"`
+		Items []job.Device `json:"items"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
 		return nil, err
@@ -379,6 +705,24 @@ func (c *HTTPClient) GetRecentDesigns(ctx context.Context, limit int) ([]history
 	return payload.Items, nil
 }
 
+// GetVersion fetches the server's build info from /v1/version.
+func (c *HTTPClient) GetVersion(ctx context.Context) (*buildinfo.Info, error) {
+	resp, err := c.get(ctx, c.buildURL("/v1/version"))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("get version failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	var info buildinfo.Info
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
 func (c *HTTPClient) buildURL(pathPart string) string {
 	base := strings.TrimRight(c.BaseURL, "/")
 	if base == "" {
@@ -392,11 +736,34 @@ func (c *HTTPClient) buildURL(pathPart string) string {
 	return u.String()
 }
 
+// get issues an idempotent GET, retrying transient failures per c.Retry.
+func (c *HTTPClient) get(ctx context.Context, reqURL string) (*http.Response, error) {
+	return c.Retry.Do(ctx, c.httpClient(), func() (*http.Request, error) {
+		return c.newGetRequest(ctx, reqURL)
+	})
+}
+
+func (c *HTTPClient) newGetRequest(ctx context.Context, reqURL string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.setAuth(req)
+	return req, nil
+}
+
 func (c *HTTPClient) httpClient() *http.Client {
 	if c.Client != nil {
 		return c.Client
 	}
-	return http.DefaultClient
+	opts := httptransport.Options{CAFile: c.CAFile, InsecureSkipVerify: c.InsecureSkipVerify, CertFile: c.CertFile, KeyFile: c.KeyFile, LimitRate: c.LimitRate}
+	if !opts.Enabled() {
+		return http.DefaultClient
+	}
+	c.transportOnce.Do(func() {
+		c.transport = httptransport.ClientOrError(opts)
+	})
+	return c.transport
 }
 
 func (c *HTTPClient) setAuth(req *http.Request) {
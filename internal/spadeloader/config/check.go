@@ -0,0 +1,93 @@
+package config
+
+import (
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/authz"
+)
+
+// envPrefix starts every spadeloader server variable.
+const envPrefix = "SPADELOADER_"
+
+// redacted replaces secrets in the effective config.
+const redacted = "<redacted>"
+
+// CheckEnv is FromEnv over environ ("KEY=value" entries), also returning
+// the SPADELOADER_ variables that no setting read, such as misspelled
+// names, when the config loads. Variables read outside this package, such
+// as the TUI's SPADELOADER_PLAIN, are left for the caller to filter.
+func CheckEnv(environ []string) (Config, []string, error) {
+	vars := map[string]string{}
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			vars[k] = v
+		}
+	}
+	read := map[string]bool{}
+	cfg, err := fromEnv(func(k string) string {
+		read[k] = true
+		return vars[k]
+	})
+	if err != nil {
+		return cfg, nil, err
+	}
+	var unused []string
+	for k := range vars {
+		if strings.HasPrefix(k, envPrefix) && !read[k] {
+			unused = append(unused, k)
+		}
+	}
+	sort.Strings(unused)
+	return cfg, unused, nil
+}
+
+// Effective returns the config for --check-config to print, keyed by field
+// name, with the full and scoped tokens and the MQTT password hidden.
+func (c Config) Effective() map[string]any {
+	if c.Token != "" {
+		c.Token = redacted
+	}
+	if len(c.ScopedTokens) > 0 {
+		scoped := make([]ScopedToken, len(c.ScopedTokens))
+		for i, st := range c.ScopedTokens {
+			scoped[i] = ScopedToken{Token: redacted, Tags: st.Tags}
+		}
+		c.ScopedTokens = scoped
+	}
+	c.Tokens = authz.Redacted(c.Tokens, redacted)
+	c.MQTTURL = redactURL(c.MQTTURL)
+	return effectiveFields(c)
+}
+
+func effectiveFields(c any) map[string]any {
+	out := map[string]any{}
+	v := reflect.ValueOf(c)
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		value := v.Field(i).Interface()
+		if d, ok := value.(time.Duration); ok {
+			value = d.String()
+		}
+		out[field.Name] = value
+	}
+	return out
+}
+
+// redactURL masks the password and query of the broker URL.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || raw == "" {
+		return raw
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "xxxxx")
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = "xxxxx"
+	}
+	return u.String()
+}
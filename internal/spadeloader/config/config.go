@@ -1,6 +1,7 @@
 package config
 
 import (
+	"crypto/subtle"
 	"errors"
 	"fmt"
 	"net"
@@ -8,15 +9,22 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/mblsha/spadeforge/internal/authz"
+	"github.com/mblsha/spadeforge/internal/mqtt"
+	"github.com/mblsha/spadeforge/internal/sse"
 )
 
 const (
 	defaultListenAddr        = ":8080"
 	defaultAuthHeader        = "X-Build-Token"
-	defaultMaxUploadBytes    = int64(64 << 20) // 64 MiB
+	defaultMaxBitstreamBytes = int64(32 << 20) // 32 MiB
+	defaultSSEKeepalive      = 15 * time.Second
+	defaultRateLimitBurst    = 20
 	defaultWorkerTimeout     = 10 * time.Minute
 	defaultOpenFPGALoaderBin = "openFPGALoader"
 	defaultDiscoveryEnabled  = true
@@ -26,6 +34,12 @@ const (
 	defaultHistoryLimit      = 100
 	defaultMacOSCacheAppID   = "io.spadeforge.spadeloader"
 	defaultLinuxCacheAppName = "spadeloader"
+	defaultMQTTTopicPrefix   = "spadeloader"
+	defaultHassDiscovery     = "homeassistant"
+	defaultHILTimeout        = time.Minute
+	defaultHILBaud           = 115200
+	defaultMaxConcurrent     = 4
+	defaultMonitorDuration   = 5 * time.Minute
 )
 
 var boardNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
@@ -34,48 +48,164 @@ var boardNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
 type Config struct {
 	ListenAddr string
 	BaseDir    string
+	// EncryptionKeyFile, when set, names a 32-byte AES-256 key (raw or
+	// hex) used to encrypt stored bitstreams at rest.
+	EncryptionKeyFile string
+	// ReleaseURL is advertised on /v1/version so CLIs can self-update;
+	// "{version}" stands for the release to fetch.
+	ReleaseURL string
 
 	Token         string
 	AuthHeader    string
 	Allowlist     []string
 	AllowedBoards []string
+	// TLSCert and TLSKey, when set, make the server listen with HTTPS.
+	// Client certificates issued by TLSClientCA get full access without a
+	// token.
+	TLSCert     string
+	TLSKey      string
+	TLSClientCA string
+
+	// ScopedTokens may only flash boards carrying one of their tags; Token
+	// keeps full access. BoardTags maps a board to extra tags, and every
+	// board is implicitly tagged with its own name.
+	ScopedTokens []ScopedToken
+	BoardTags    map[string][]string
+	// Tokens are named tokens limited to their scopes (read, flash, admin),
+	// from SPADELOADER_TOKENS and the JSON file TokensFile.
+	Tokens     []authz.Token
+	TokensFile string
 
 	OpenFPGALoaderBin string
+	// DeviceBoards maps a programmer serial from --scan-usb to the board
+	// it is wired to, so /v1/devices can offer boards to pick from.
+	DeviceBoards map[string]string
+
+	// MaxBitstreamBytes caps a bitstream upload; Xilinx 7-series
+	// bitstreams rarely exceed 30 MB.
+	MaxBitstreamBytes int64
+	// CheckBitstreams rejects uploads without a recognisable Xilinx or
+	// Lattice bitstream, or in the wrong format for a known board.
+	CheckBitstreams bool
+	WorkerTimeout   time.Duration
+	// MaxConcurrentFlashes caps flashes running at once. Flashes share a
+	// lane per programmer (or per board when no device is targeted), and
+	// each lane runs one flash at a time.
+	MaxConcurrentFlashes int
 
-	MaxUploadBytes int64
-	WorkerTimeout  time.Duration
+	// SSEKeepalive is the interval between keepalive comments on event
+	// streams; keep it below any NAT or proxy idle timeout on the path.
+	SSEKeepalive time.Duration
+
+	// AccessLog logs every HTTP request with its status and latency.
+	AccessLog bool
+	// RateLimit caps guarded requests per second per client IP, with bursts
+	// of up to RateLimitBurst; 0 disables it.
+	RateLimit      float64
+	RateLimitBurst int
 
 	HistoryLimit    int
 	PreserveWorkDir bool
 	UseFakeFlasher  bool
+	// RecordDir, when set, saves every openFPGALoader run's output with
+	// timing as a session file for builder.ReplayRunner.
+	RecordDir string
 
 	DiscoveryEnabled  bool
 	DiscoveryService  string
 	DiscoveryDomain   string
 	DiscoveryInstance string
+
+	// Kiosk locks the server down for demo booths and teaching labs: uploads
+	// are rejected and only GoldenDesigns can be reflashed.
+	Kiosk         bool
+	GoldenDesigns []GoldenDesign
+
+	// MQTTURL, when set, connects to this broker and exposes every known
+	// board as a Home Assistant device (announced under
+	// HassDiscoveryPrefix) with a state sensor and a button per golden
+	// design; board topics live under MQTTTopicPrefix.
+	MQTTURL             string
+	MQTTTopicPrefix     string
+	HassDiscoveryPrefix string
+
+	// HILTests run after every successful flash of their board; a failing
+	// test fails the job. Each test is bounded by HILTimeout.
+	HILTests   []HILTest
+	HILTimeout time.Duration
+
+	// SerialPorts name each board's UART for post-flash serial monitors,
+	// which capture it for up to MonitorDuration after a successful flash.
+	SerialPorts     []SerialPort
+	MonitorDuration time.Duration
+}
+
+// SerialPort is the UART wired to Board, opened at Baud unless the flash
+// asks for another rate.
+type SerialPort struct {
+	Board string
+	Port  string
+	Baud  int
+}
+
+// ScopedToken is an auth token limited to boards tagged with one of Tags.
+type ScopedToken struct {
+	Token string
+	Tags  []string
+}
+
+// HILTest is a board's post-flash hardware test: either Script, run with
+// the job's details in its environment, or an expect sequence from
+// ExpectFile played against SerialPort.
+type HILTest struct {
+	Board      string
+	Script     string
+	SerialPort string
+	Baud       int
+	ExpectFile string
+}
+
+// GoldenDesign names a design offered in kiosk mode. An empty Board matches
+// the design on every board.
+type GoldenDesign struct {
+	Board  string
+	Design string
 }
 
 func Default() Config {
 	return Config{
-		ListenAddr:        defaultListenAddr,
-		AuthHeader:        defaultAuthHeader,
-		OpenFPGALoaderBin: defaultOpenFPGALoaderBin,
-		MaxUploadBytes:    defaultMaxUploadBytes,
-		WorkerTimeout:     defaultWorkerTimeout,
-		HistoryLimit:      defaultHistoryLimit,
-		DiscoveryEnabled:  defaultDiscoveryEnabled,
-		DiscoveryService:  defaultDiscoveryService,
-		DiscoveryDomain:   defaultDiscoveryDomain,
-		DiscoveryInstance: defaultDiscoveryInstance,
-		PreserveWorkDir:   false,
-		UseFakeFlasher:    false,
+		ListenAddr:           defaultListenAddr,
+		AuthHeader:           defaultAuthHeader,
+		OpenFPGALoaderBin:    defaultOpenFPGALoaderBin,
+		MaxBitstreamBytes:    defaultMaxBitstreamBytes,
+		CheckBitstreams:      true,
+		WorkerTimeout:        defaultWorkerTimeout,
+		MaxConcurrentFlashes: defaultMaxConcurrent,
+		SSEKeepalive:         defaultSSEKeepalive,
+		RateLimitBurst:       defaultRateLimitBurst,
+		HistoryLimit:         defaultHistoryLimit,
+		DiscoveryEnabled:     defaultDiscoveryEnabled,
+		DiscoveryService:     defaultDiscoveryService,
+		DiscoveryDomain:      defaultDiscoveryDomain,
+		DiscoveryInstance:    defaultDiscoveryInstance,
+		PreserveWorkDir:      false,
+		UseFakeFlasher:       false,
+		MQTTTopicPrefix:      defaultMQTTTopicPrefix,
+		HassDiscoveryPrefix:  defaultHassDiscovery,
+		HILTimeout:           defaultHILTimeout,
+		MonitorDuration:      defaultMonitorDuration,
 	}
 }
 
 func FromEnv() (Config, error) {
+	return fromEnv(os.Getenv)
+}
+
+// fromEnv loads the config reading variables with getenv.
+func fromEnv(getenv func(string) string) (Config, error) {
 	cfg := Default()
-	cfg.ListenAddr = getEnv("SPADELOADER_LISTEN_ADDR", cfg.ListenAddr)
-	cfg.BaseDir = strings.TrimSpace(os.Getenv("SPADELOADER_BASE_DIR"))
+	cfg.ListenAddr = getEnv(getenv, "SPADELOADER_LISTEN_ADDR", cfg.ListenAddr)
+	cfg.BaseDir = strings.TrimSpace(getenv("SPADELOADER_BASE_DIR"))
 	if cfg.BaseDir == "" {
 		baseDir, err := defaultBaseDir()
 		if err != nil {
@@ -83,33 +213,135 @@ func FromEnv() (Config, error) {
 		}
 		cfg.BaseDir = baseDir
 	}
-	cfg.Token = strings.TrimSpace(os.Getenv("SPADELOADER_TOKEN"))
-	cfg.AuthHeader = getEnv("SPADELOADER_AUTH_HEADER", cfg.AuthHeader)
-	cfg.Allowlist = parseCSV(os.Getenv("SPADELOADER_ALLOWLIST"))
-	cfg.AllowedBoards = parseCSV(os.Getenv("SPADELOADER_ALLOWED_BOARDS"))
-	cfg.OpenFPGALoaderBin = getEnv("SPADELOADER_OPENFPGALOADER_BIN", cfg.OpenFPGALoaderBin)
-	cfg.PreserveWorkDir = parseBoolEnv(os.Getenv("SPADELOADER_PRESERVE_WORK_DIR"))
-	cfg.UseFakeFlasher = parseBoolEnv(os.Getenv("SPADELOADER_USE_FAKE_FLASHER"))
-	cfg.DiscoveryEnabled = parseBoolEnvWithDefault(os.Getenv("SPADELOADER_DISCOVERY_ENABLE"), cfg.DiscoveryEnabled)
-	cfg.DiscoveryService = getEnv("SPADELOADER_DISCOVERY_SERVICE", cfg.DiscoveryService)
-	cfg.DiscoveryDomain = getEnv("SPADELOADER_DISCOVERY_DOMAIN", cfg.DiscoveryDomain)
-	cfg.DiscoveryInstance = getEnv("SPADELOADER_DISCOVERY_INSTANCE", cfg.DiscoveryInstance)
-
-	if v := strings.TrimSpace(os.Getenv("SPADELOADER_MAX_UPLOAD_BYTES")); v != "" {
+	cfg.EncryptionKeyFile = strings.TrimSpace(getenv("SPADELOADER_ENCRYPTION_KEY_FILE"))
+	cfg.ReleaseURL = strings.TrimSpace(getenv("SPADELOADER_RELEASE_URL"))
+	cfg.Token = strings.TrimSpace(getenv("SPADELOADER_TOKEN"))
+	cfg.AuthHeader = getEnv(getenv, "SPADELOADER_AUTH_HEADER", cfg.AuthHeader)
+	cfg.Allowlist = parseCSV(getenv("SPADELOADER_ALLOWLIST"))
+	cfg.AllowedBoards = parseCSV(getenv("SPADELOADER_ALLOWED_BOARDS"))
+	cfg.TLSCert = strings.TrimSpace(getenv("SPADELOADER_TLS_CERT"))
+	cfg.TLSKey = strings.TrimSpace(getenv("SPADELOADER_TLS_KEY"))
+	cfg.TLSClientCA = strings.TrimSpace(getenv("SPADELOADER_TLS_CLIENT_CA"))
+	scoped, err := ParseScopedTokens(parseCSV(getenv("SPADELOADER_SCOPED_TOKENS")))
+	if err != nil {
+		return Config{}, fmt.Errorf("parse SPADELOADER_SCOPED_TOKENS: %w", err)
+	}
+	cfg.ScopedTokens = scoped
+	tokens, err := authz.ParseEntries(parseCSV(getenv("SPADELOADER_TOKENS")))
+	if err != nil {
+		return Config{}, fmt.Errorf("parse SPADELOADER_TOKENS: %w", err)
+	}
+	cfg.TokensFile = strings.TrimSpace(getenv("SPADELOADER_TOKENS_FILE"))
+	if cfg.TokensFile != "" {
+		fromFile, err := authz.LoadFile(cfg.TokensFile)
+		if err != nil {
+			return Config{}, err
+		}
+		tokens = append(tokens, fromFile...)
+	}
+	cfg.Tokens = tokens
+	tags, err := ParseBoardTags(parseCSV(getenv("SPADELOADER_BOARD_TAGS")))
+	if err != nil {
+		return Config{}, fmt.Errorf("parse SPADELOADER_BOARD_TAGS: %w", err)
+	}
+	cfg.BoardTags = tags
+	deviceBoards, err := ParseDeviceBoards(parseCSV(getenv("SPADELOADER_DEVICE_BOARDS")))
+	if err != nil {
+		return Config{}, fmt.Errorf("parse SPADELOADER_DEVICE_BOARDS: %w", err)
+	}
+	cfg.DeviceBoards = deviceBoards
+	cfg.OpenFPGALoaderBin = getEnv(getenv, "SPADELOADER_OPENFPGALOADER_BIN", cfg.OpenFPGALoaderBin)
+	cfg.PreserveWorkDir = parseBoolEnv(getenv("SPADELOADER_PRESERVE_WORK_DIR"))
+	cfg.RecordDir = strings.TrimSpace(getenv("SPADELOADER_RECORD_DIR"))
+	cfg.AccessLog = parseBoolEnv(getenv("SPADELOADER_ACCESS_LOG"))
+	cfg.UseFakeFlasher = parseBoolEnv(getenv("SPADELOADER_USE_FAKE_FLASHER"))
+	cfg.DiscoveryEnabled = parseBoolEnvWithDefault(getenv("SPADELOADER_DISCOVERY_ENABLE"), cfg.DiscoveryEnabled)
+	cfg.DiscoveryService = getEnv(getenv, "SPADELOADER_DISCOVERY_SERVICE", cfg.DiscoveryService)
+	cfg.DiscoveryDomain = getEnv(getenv, "SPADELOADER_DISCOVERY_DOMAIN", cfg.DiscoveryDomain)
+	cfg.DiscoveryInstance = getEnv(getenv, "SPADELOADER_DISCOVERY_INSTANCE", cfg.DiscoveryInstance)
+	cfg.Kiosk = parseBoolEnv(getenv("SPADELOADER_KIOSK"))
+	cfg.CheckBitstreams = parseBoolEnvWithDefault(getenv("SPADELOADER_CHECK_BITSTREAMS"), cfg.CheckBitstreams)
+	golden, err := ParseGoldenDesigns(parseCSV(getenv("SPADELOADER_GOLDEN_DESIGNS")))
+	if err != nil {
+		return Config{}, fmt.Errorf("parse SPADELOADER_GOLDEN_DESIGNS: %w", err)
+	}
+	cfg.GoldenDesigns = golden
+	hilTests, err := ParseHILTests(parseCSV(getenv("SPADELOADER_HIL_TESTS")))
+	if err != nil {
+		return Config{}, fmt.Errorf("parse SPADELOADER_HIL_TESTS: %w", err)
+	}
+	cfg.HILTests = hilTests
+	serialPorts, err := ParseSerialPorts(parseCSV(getenv("SPADELOADER_SERIAL_PORTS")))
+	if err != nil {
+		return Config{}, fmt.Errorf("parse SPADELOADER_SERIAL_PORTS: %w", err)
+	}
+	cfg.SerialPorts = serialPorts
+	cfg.MQTTURL = strings.TrimSpace(getenv("SPADELOADER_MQTT_URL"))
+	cfg.MQTTTopicPrefix = strings.Trim(getEnv(getenv, "SPADELOADER_MQTT_TOPIC_PREFIX", cfg.MQTTTopicPrefix), "/")
+	cfg.HassDiscoveryPrefix = strings.Trim(getEnv(getenv, "SPADELOADER_HASS_DISCOVERY_PREFIX", cfg.HassDiscoveryPrefix), "/")
+
+	// SPADELOADER_MAX_UPLOAD_BYTES is the older name of the bitstream limit.
+	for _, key := range []string{"SPADELOADER_MAX_UPLOAD_BYTES", "SPADELOADER_MAX_BITSTREAM_BYTES"} {
+		v := strings.TrimSpace(getenv(key))
+		if v == "" {
+			continue
+		}
 		n, err := strconv.ParseInt(v, 10, 64)
 		if err != nil {
-			return Config{}, fmt.Errorf("parse SPADELOADER_MAX_UPLOAD_BYTES: %w", err)
+			return Config{}, fmt.Errorf("parse %s: %w", key, err)
 		}
-		cfg.MaxUploadBytes = n
+		cfg.MaxBitstreamBytes = n
 	}
-	if v := strings.TrimSpace(os.Getenv("SPADELOADER_WORKER_TIMEOUT")); v != "" {
+	if v := strings.TrimSpace(getenv("SPADELOADER_WORKER_TIMEOUT")); v != "" {
 		d, err := time.ParseDuration(v)
 		if err != nil {
 			return Config{}, fmt.Errorf("parse SPADELOADER_WORKER_TIMEOUT: %w", err)
 		}
 		cfg.WorkerTimeout = d
 	}
-	if v := strings.TrimSpace(os.Getenv("SPADELOADER_HISTORY_LIMIT")); v != "" {
+	if v := strings.TrimSpace(getenv("SPADELOADER_MAX_CONCURRENT_FLASHES")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse SPADELOADER_MAX_CONCURRENT_FLASHES: %w", err)
+		}
+		cfg.MaxConcurrentFlashes = n
+	}
+	if v := strings.TrimSpace(getenv("SPADELOADER_RATE_LIMIT")); v != "" {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse SPADELOADER_RATE_LIMIT: %w", err)
+		}
+		cfg.RateLimit = n
+	}
+	if v := strings.TrimSpace(getenv("SPADELOADER_RATE_LIMIT_BURST")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse SPADELOADER_RATE_LIMIT_BURST: %w", err)
+		}
+		cfg.RateLimitBurst = n
+	}
+	if v := strings.TrimSpace(getenv("SPADELOADER_SSE_KEEPALIVE")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse SPADELOADER_SSE_KEEPALIVE: %w", err)
+		}
+		cfg.SSEKeepalive = d
+	}
+	if v := strings.TrimSpace(getenv("SPADELOADER_HIL_TIMEOUT")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse SPADELOADER_HIL_TIMEOUT: %w", err)
+		}
+		cfg.HILTimeout = d
+	}
+	if v := strings.TrimSpace(getenv("SPADELOADER_MONITOR_DURATION")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse SPADELOADER_MONITOR_DURATION: %w", err)
+		}
+		cfg.MonitorDuration = d
+	}
+	if v := strings.TrimSpace(getenv("SPADELOADER_HISTORY_LIMIT")); v != "" {
 		n, err := strconv.Atoi(v)
 		if err != nil {
 			return Config{}, fmt.Errorf("parse SPADELOADER_HISTORY_LIMIT: %w", err)
@@ -130,12 +362,29 @@ func (c Config) Validate() error {
 	if strings.TrimSpace(c.AuthHeader) == "" {
 		return errors.New("auth header is required")
 	}
-	if c.MaxUploadBytes <= 0 {
-		return errors.New("max upload bytes must be > 0")
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return errors.New("tls cert and tls key must be set together")
+	}
+	if c.TLSClientCA != "" && c.TLSCert == "" {
+		return errors.New("tls client ca needs tls cert and key")
+	}
+	if c.MaxBitstreamBytes <= 0 {
+		return errors.New("max bitstream bytes must be > 0")
 	}
 	if c.WorkerTimeout <= 0 {
 		return errors.New("worker timeout must be > 0")
 	}
+	if c.MaxConcurrentFlashes < 1 {
+		return errors.New("max concurrent flashes must be >= 1")
+	}
+	if c.SSEKeepalive <= 0 {
+		return errors.New("sse keepalive must be > 0")
+	}
+	// Clients treat a stream as dead after sse.DefaultIdleTimeout without
+	// data, so keepalives must arrive sooner.
+	if c.SSEKeepalive >= sse.DefaultIdleTimeout {
+		return fmt.Errorf("sse keepalive must be below the clients' %s idle timeout", sse.DefaultIdleTimeout)
+	}
 	if strings.TrimSpace(c.OpenFPGALoaderBin) == "" {
 		return errors.New("openFPGALoader bin is required")
 	}
@@ -153,6 +402,12 @@ func (c Config) Validate() error {
 			return errors.New("discovery domain is required when discovery is enabled")
 		}
 	}
+	if c.RateLimit < 0 {
+		return errors.New("rate limit must be >= 0")
+	}
+	if c.RateLimit > 0 && c.RateLimitBurst < 1 {
+		return errors.New("rate limit burst must be >= 1")
+	}
 	for _, entry := range c.Allowlist {
 		if err := validateAllowEntry(entry); err != nil {
 			return err
@@ -163,6 +418,91 @@ func (c Config) Validate() error {
 			return err
 		}
 	}
+	if err := authz.Validate(c.Tokens, c.Token); err != nil {
+		return err
+	}
+	for _, t := range c.Tokens {
+		for _, scoped := range c.ScopedTokens {
+			if t.Token == scoped.Token {
+				return fmt.Errorf("token %q reuses a scoped token", t.Name)
+			}
+		}
+	}
+	if len(c.ScopedTokens) > 0 && strings.TrimSpace(c.Token) == "" {
+		return errors.New("scoped tokens require a full-access token")
+	}
+	for _, scoped := range c.ScopedTokens {
+		if strings.TrimSpace(scoped.Token) == "" {
+			return errors.New("scoped token cannot be empty")
+		}
+		if c.Token != "" && scoped.Token == c.Token {
+			return errors.New("scoped token must differ from the full-access token")
+		}
+		for _, tag := range scoped.Tags {
+			if err := validateTag(tag); err != nil {
+				return err
+			}
+		}
+	}
+	for board, tags := range c.BoardTags {
+		if err := validateBoardName(board); err != nil {
+			return err
+		}
+		for _, tag := range tags {
+			if err := validateTag(tag); err != nil {
+				return err
+			}
+		}
+	}
+	for serial, board := range c.DeviceBoards {
+		if serial == "" {
+			return errors.New("device board serial cannot be empty")
+		}
+		if err := validateBoardName(board); err != nil {
+			return err
+		}
+	}
+	if c.Kiosk && len(c.GoldenDesigns) == 0 {
+		return errors.New("kiosk mode requires at least one golden design")
+	}
+	if len(c.HILTests) > 0 && c.HILTimeout <= 0 {
+		return errors.New("hil timeout must be > 0")
+	}
+	seenHIL := map[string]bool{}
+	for _, test := range c.HILTests {
+		if err := validateBoardName(test.Board); err != nil {
+			return err
+		}
+		board := strings.ToLower(test.Board)
+		if seenHIL[board] {
+			return fmt.Errorf("board %q has more than one hil test", test.Board)
+		}
+		seenHIL[board] = true
+	}
+	if len(c.SerialPorts) > 0 && c.MonitorDuration <= 0 {
+		return errors.New("monitor duration must be > 0")
+	}
+	seenPorts := map[string]bool{}
+	for _, sp := range c.SerialPorts {
+		if err := validateBoardName(sp.Board); err != nil {
+			return err
+		}
+		board := strings.ToLower(sp.Board)
+		if seenPorts[board] {
+			return fmt.Errorf("board %q has more than one serial port", sp.Board)
+		}
+		seenPorts[board] = true
+	}
+	if c.MQTTURL != "" {
+		if _, err := mqtt.NewPublisher(c.MQTTURL, ""); err != nil {
+			return err
+		}
+		for name, prefix := range map[string]string{"mqtt topic prefix": c.MQTTTopicPrefix, "home assistant discovery prefix": c.HassDiscoveryPrefix} {
+			if prefix == "" || strings.ContainsAny(prefix, "+#") {
+				return fmt.Errorf("%s %q must be non-empty and free of + and # wildcards", name, prefix)
+			}
+		}
+	}
 	return nil
 }
 
@@ -207,8 +547,217 @@ func (c Config) BoardAllowed(board string) bool {
 	return false
 }
 
-func getEnv(key, fallback string) string {
-	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+// ParseScopedTokens parses "token=tag|tag" entries. The split is at the last
+// "=", so tokens may themselves contain "=".
+func ParseScopedTokens(entries []string) ([]ScopedToken, error) {
+	out := make([]ScopedToken, 0, len(entries))
+	for _, entry := range entries {
+		idx := strings.LastIndex(entry, "=")
+		if idx < 0 {
+			return nil, fmt.Errorf("scoped token entry must be token=tag|tag")
+		}
+		tags := parseTags(entry[idx+1:])
+		if len(tags) == 0 {
+			return nil, fmt.Errorf("scoped token entry has no tags")
+		}
+		out = append(out, ScopedToken{Token: strings.TrimSpace(entry[:idx]), Tags: tags})
+	}
+	return out, nil
+}
+
+// ParseBoardTags parses "board=tag|tag" entries.
+func ParseBoardTags(entries []string) (map[string][]string, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	out := make(map[string][]string, len(entries))
+	for _, entry := range entries {
+		board, rawTags, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("board tags entry %q must be board=tag|tag", entry)
+		}
+		board = strings.ToLower(strings.TrimSpace(board))
+		out[board] = append(out[board], parseTags(rawTags)...)
+	}
+	return out, nil
+}
+
+// ParseDeviceBoards parses "serial=board" entries.
+func ParseDeviceBoards(entries []string) (map[string]string, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		serial, board, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("device boards entry %q must be serial=board", entry)
+		}
+		out[strings.TrimSpace(serial)] = strings.ToLower(strings.TrimSpace(board))
+	}
+	return out, nil
+}
+
+// ScopedToken returns the scoped token matching token, if any.
+func (c Config) ScopedToken(token string) (ScopedToken, bool) {
+	token = strings.TrimSpace(token)
+	for _, scoped := range c.ScopedTokens {
+		if token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(scoped.Token)) == 1 {
+			return scoped, true
+		}
+	}
+	return ScopedToken{}, false
+}
+
+// TokenMayFlash reports whether scoped may flash board: the board's own name
+// or one of its BoardTags must be among the token's tags.
+func (c Config) TokenMayFlash(scoped ScopedToken, board string) bool {
+	board = strings.ToLower(strings.TrimSpace(board))
+	boardTags := append([]string{board}, c.BoardTags[board]...)
+	for _, want := range scoped.Tags {
+		for _, have := range boardTags {
+			if strings.EqualFold(want, have) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// BoardsTagged returns the boards in BoardTags carrying tag, sorted. A
+// board's own name counts as one of its tags.
+func (c Config) BoardsTagged(tag string) []string {
+	tag = strings.TrimSpace(tag)
+	var out []string
+	for board, tags := range c.BoardTags {
+		for _, have := range append([]string{board}, tags...) {
+			if strings.EqualFold(have, tag) {
+				out = append(out, board)
+				break
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// ParseGoldenDesigns parses "board:design" or bare "design" entries.
+func ParseGoldenDesigns(entries []string) ([]GoldenDesign, error) {
+	out := make([]GoldenDesign, 0, len(entries))
+	for _, entry := range entries {
+		var g GoldenDesign
+		if board, design, ok := strings.Cut(entry, ":"); ok && boardNamePattern.MatchString(strings.TrimSpace(board)) {
+			g = GoldenDesign{Board: strings.TrimSpace(board), Design: strings.TrimSpace(design)}
+		} else {
+			g = GoldenDesign{Design: strings.TrimSpace(entry)}
+		}
+		if g.Design == "" {
+			return nil, fmt.Errorf("golden design %q has an empty design name", entry)
+		}
+		out = append(out, g)
+	}
+	return out, nil
+}
+
+// ParseHILTests parses "board=/path/to/script" and
+// "board=serial:<port>[@<baud>]:/path/to/file.expect" entries.
+func ParseHILTests(entries []string) ([]HILTest, error) {
+	out := make([]HILTest, 0, len(entries))
+	for _, entry := range entries {
+		board, spec, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("hil test entry %q must be board=script or board=serial:port[@baud]:expect-file", entry)
+		}
+		test := HILTest{Board: strings.TrimSpace(board)}
+		spec = strings.TrimSpace(spec)
+		if rest, isSerial := strings.CutPrefix(spec, "serial:"); isSerial {
+			port, expectFile, ok := strings.Cut(rest, ":")
+			if !ok || strings.TrimSpace(expectFile) == "" {
+				return nil, fmt.Errorf("hil test entry %q has no expect file", entry)
+			}
+			test.SerialPort, test.Baud = strings.TrimSpace(port), defaultHILBaud
+			if p, rawBaud, ok := strings.Cut(test.SerialPort, "@"); ok {
+				baud, err := strconv.Atoi(rawBaud)
+				if err != nil || baud <= 0 {
+					return nil, fmt.Errorf("hil test entry %q has an invalid baud rate %q", entry, rawBaud)
+				}
+				test.SerialPort, test.Baud = p, baud
+			}
+			if test.SerialPort == "" {
+				return nil, fmt.Errorf("hil test entry %q has no serial port", entry)
+			}
+			test.ExpectFile = strings.TrimSpace(expectFile)
+		} else {
+			test.Script = spec
+		}
+		if test.Script == "" && test.ExpectFile == "" {
+			return nil, fmt.Errorf("hil test entry %q has no script", entry)
+		}
+		out = append(out, test)
+	}
+	return out, nil
+}
+
+// ParseSerialPorts parses board=port[@baud] entries; the baud defaults to
+// 115200.
+func ParseSerialPorts(entries []string) ([]SerialPort, error) {
+	out := make([]SerialPort, 0, len(entries))
+	for _, entry := range entries {
+		board, port, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("serial port entry %q must be board=port[@baud]", entry)
+		}
+		sp := SerialPort{Board: strings.TrimSpace(board), Port: strings.TrimSpace(port), Baud: defaultHILBaud}
+		if p, rawBaud, ok := strings.Cut(sp.Port, "@"); ok {
+			baud, err := strconv.Atoi(rawBaud)
+			if err != nil || baud <= 0 {
+				return nil, fmt.Errorf("serial port entry %q has an invalid baud rate %q", entry, rawBaud)
+			}
+			sp.Port, sp.Baud = p, baud
+		}
+		if sp.Port == "" {
+			return nil, fmt.Errorf("serial port entry %q has no port", entry)
+		}
+		out = append(out, sp)
+	}
+	return out, nil
+}
+
+// SerialPort returns the UART configured for board, if any.
+func (c Config) SerialPort(board string) (SerialPort, bool) {
+	for _, sp := range c.SerialPorts {
+		if strings.EqualFold(sp.Board, strings.TrimSpace(board)) {
+			return sp, true
+		}
+	}
+	return SerialPort{}, false
+}
+
+// HILTest returns the post-flash test configured for board, if any.
+func (c Config) HILTest(board string) (HILTest, bool) {
+	for _, test := range c.HILTests {
+		if strings.EqualFold(test.Board, strings.TrimSpace(board)) {
+			return test, true
+		}
+	}
+	return HILTest{}, false
+}
+
+// IsGolden reports whether the design on board is one of GoldenDesigns.
+func (c Config) IsGolden(board, design string) bool {
+	for _, g := range c.GoldenDesigns {
+		if g.Design != strings.TrimSpace(design) {
+			continue
+		}
+		if g.Board == "" || strings.EqualFold(g.Board, strings.TrimSpace(board)) {
+			return true
+		}
+	}
+	return false
+}
+
+func getEnv(getenv func(string) string, key, fallback string) string {
+	if v := strings.TrimSpace(getenv(key)); v != "" {
 		return v
 	}
 	return fallback
@@ -269,6 +818,23 @@ func validateAllowEntry(entry string) error {
 	return nil
 }
 
+func parseTags(v string) []string {
+	var out []string
+	for _, tag := range strings.Split(v, "|") {
+		if t := strings.TrimSpace(tag); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func validateTag(tag string) error {
+	if !boardNamePattern.MatchString(strings.TrimSpace(tag)) {
+		return fmt.Errorf("invalid board tag %q; expected pattern %s", tag, boardNamePattern.String())
+	}
+	return nil
+}
+
 func validateBoardName(board string) error {
 	if !boardNamePattern.MatchString(strings.TrimSpace(board)) {
 		return fmt.Errorf("invalid allowed board %q; expected pattern %s", board, boardNamePattern.String())
@@ -0,0 +1,115 @@
+package flasher
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/mblsha/spadeforge/internal/spadeloader/job"
+)
+
+// failurePatterns map lowercase openFPGALoader output fragments to failure
+// kinds. Order matters: libftdi reports permission problems as "unable to
+// open ftdi device" too, so the permission patterns are checked first.
+var failurePatterns = []struct {
+	kind     string
+	patterns []string
+}{
+	{job.FailurePermissionDenied, []string{
+		"permission denied",
+		"libusb_error_access",
+		"inappropriate permissions",
+		"usb_open() failed",
+		"access denied",
+	}},
+	{job.FailureDeviceNotFound, []string{
+		"device not found",
+		"no device found",
+		"no cable found",
+		"cable not found",
+		"unable to find device",
+		"jtag init failed: no device",
+		"found 0 device",
+	}},
+	{job.FailureWrongPart, []string{
+		"idcode mismatch",
+		"device mismatch",
+		"part mismatch",
+		"wrong device",
+		"not supported",
+		"unknown device",
+		"does not match",
+	}},
+	{job.FailureVerifyFailed, []string{
+		"verify failed",
+		"verification failed",
+		"crc error",
+		"crc check",
+		"done pin",
+		"fail to configure",
+		"program failed",
+	}},
+	{job.FailureCableError, []string{
+		"ftdi_write_data",
+		"ftdi_read_data",
+		"usb bulk",
+		"libusb_error_io",
+		"libusb_error_pipe",
+		"libusb_error_no_device",
+		"libusb_error_timeout",
+		"jtag init failed",
+		"tdo stuck",
+	}},
+}
+
+// ClassifyFailure maps a failed flash to a job failure kind and a one-line
+// summary, using the console output and the error returned by Flash.
+func ClassifyFailure(output []byte, err error) (kind string, summary string) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return job.FailureTimeout, "flash timed out"
+	}
+	var lines []string
+	for _, line := range strings.Split(string(output), "\n") {
+		// Skip the command echo so bitstream paths cannot match.
+		if !strings.HasPrefix(line, commandEchoPrefix) {
+			lines = append(lines, line)
+		}
+	}
+	for _, group := range failurePatterns {
+		for _, line := range lines {
+			lower := strings.ToLower(line)
+			for _, p := range group.patterns {
+				if strings.Contains(lower, p) {
+					return group.kind, strings.TrimSpace(line)
+				}
+			}
+		}
+	}
+	summary = lastErrorLine(lines)
+	if summary == "" && err != nil {
+		summary = strings.TrimSpace(err.Error())
+	}
+	if summary == "" {
+		summary = "flash failed"
+	}
+	return job.FailureInternal, summary
+}
+
+// lastErrorLine prefers the last line mentioning an error, falling back to
+// the last non-empty line.
+func lastErrorLine(lines []string) string {
+	last := ""
+	for i := len(lines) - 1; i >= 0; i-- {
+		t := strings.TrimSpace(lines[i])
+		if t == "" {
+			continue
+		}
+		if last == "" {
+			last = t
+		}
+		if strings.Contains(strings.ToLower(t), "error") {
+			return t
+		}
+	}
+	return last
+}
@@ -0,0 +1,37 @@
+package flasher
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mblsha/spadeforge/internal/spadeloader/job"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		output string
+		err    error
+		want   string
+	}{
+		{"permission", "running: openFPGALoader -b arty /x.bit\nJTAG init failed with: unable to open ftdi device: -4 (usb_open() failed)\n", errors.New("exit status 1"), job.FailurePermissionDenied},
+		{"not found", "JTAG init failed with: unable to open ftdi device: -3 (device not found)\n", errors.New("exit status 1"), job.FailureDeviceNotFound},
+		{"wrong part", "Error: IDCODE mismatch: 0x0362d093 != 0x13631093\n", errors.New("exit status 1"), job.FailureWrongPart},
+		{"verify", "Load SRAM: [====] 100.00%\nError: DONE pin not high, CRC error\n", errors.New("exit status 1"), job.FailureVerifyFailed},
+		{"cable", "Load SRAM: [==  ] 40.00%\nftdi_write_data: usb bulk write failed\n", errors.New("exit status 1"), job.FailureCableError},
+		{"timeout", "Load SRAM: [==  ] 40.00%\n", context.DeadlineExceeded, job.FailureTimeout},
+		{"internal", "running: openFPGALoader -b arty /designs/not supported/x.bit\nsegfault\n", errors.New("exit status 139"), job.FailureInternal},
+	}
+	for _, tt := range tests {
+		kind, summary := ClassifyFailure([]byte(tt.output), tt.err)
+		if kind != tt.want {
+			t.Fatalf("%s: kind = %q, want %q", tt.name, kind, tt.want)
+		}
+		if summary == "" {
+			t.Fatalf("%s: empty summary", tt.name)
+		}
+	}
+}
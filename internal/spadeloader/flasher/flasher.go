@@ -8,15 +8,29 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/spadeloader/job"
 )
 
-const defaultBin = "openFPGALoader"
+const (
+	defaultBin = "openFPGALoader"
+
+	// commandEchoPrefix starts the first console.log line, which echoes the
+	// openFPGALoader invocation.
+	commandEchoPrefix = "running: "
+
+	dryRunMessage = "dry run succeeded; board not programmed"
+)
 
 type ProgressUpdate struct {
-	Step        string
-	Message     string
+	Step    string
+	Message string
+	// Percent is the step's completion, when the tool reports it.
+	Percent     *float64
 	HeartbeatAt time.Time
 }
 
@@ -26,8 +40,20 @@ type FlashJob struct {
 	ID            string
 	Board         string
 	BitstreamPath string
-	ArtifactsDir  string
-	Progress      ProgressFunc
+	// WorkDir is the tool's working directory; files left there are kept
+	// under the artifacts' outputs/ dir.
+	WorkDir      string
+	ArtifactsDir string
+	// DryRun detects the board with openFPGALoader --detect instead of
+	// programming the bitstream.
+	DryRun bool
+	// Mode flash writes the SPI flash at FlashOffset instead of loading
+	// SRAM.
+	Mode        job.Mode
+	FlashOffset uint32
+	// Target selects the programmer when several boards are attached.
+	Target   job.Target
+	Progress ProgressFunc
 }
 
 type Result struct {
@@ -41,6 +67,10 @@ type Flasher interface {
 
 type OpenFPGALoaderFlasher struct {
 	Bin string
+	// Runner, when set, runs openFPGALoader instead of os/exec; use
+	// builder.RecordingRunner to capture sessions or builder.ReplayRunner
+	// to feed them back.
+	Runner builder.Runner
 }
 
 func NewOpenFPGALoaderFlasher(bin string) *OpenFPGALoaderFlasher {
@@ -61,23 +91,89 @@ func (f *OpenFPGALoaderFlasher) Flash(ctx context.Context, job FlashJob) (Result
 	}
 	defer logFile.Close()
 
-	if job.Progress != nil {
+	args := append(boardArgs(job), modeArgs(job)...)
+	args = append(args, job.BitstreamPath)
+	if job.DryRun {
+		args = append(boardArgs(job), "--detect")
+		_, _ = fmt.Fprintf(logFile, "spadeloader: dry run, %s will not be programmed\n", job.BitstreamPath)
+		if job.Progress != nil {
+			job.Progress(ProgressUpdate{Step: "detect", Message: "detecting board (dry run)", HeartbeatAt: time.Now().UTC()})
+		}
+	} else if job.Progress != nil {
 		job.Progress(ProgressUpdate{Step: "flash", Message: "running openFPGALoader", HeartbeatAt: time.Now().UTC()})
 	}
+	inv := newInvocation(append([]string{f.Bin}, args...), job.WorkDir)
+	if path, err := exec.LookPath(f.Bin); err == nil {
+		inv.Binary = path
+		inv.ToolVersion = toolVersion(ctx, path)
+	}
 
-	_, _ = fmt.Fprintf(logFile, "running: %s -b %s %s\n", f.Bin, job.Board, job.BitstreamPath)
+	result, err := f.run(ctx, job, args, logFile)
+	if err == nil && job.DryRun {
+		result.Message = dryRunMessage
+	}
 
-	cmd := exec.CommandContext(ctx, f.Bin, "-b", job.Board, job.BitstreamPath)
-	cmd.Stdout = io.MultiWriter(logFile)
-	cmd.Stderr = io.MultiWriter(logFile)
+	inv.finish(result, err)
+	outputs, outErr := collectOutputs(job.WorkDir, job.ArtifactsDir)
+	if outErr != nil {
+		_, _ = fmt.Fprintf(logFile, "spadeloader: collect outputs: %v\n", outErr)
+	}
+	inv.Outputs = outputs
+	if werr := writeInvocation(job.ArtifactsDir, inv); werr != nil {
+		_, _ = fmt.Fprintf(logFile, "spadeloader: write %s: %v\n", InvocationFileName, werr)
+	}
+	return result, err
+}
 
-	err = cmd.Run()
-	if err != nil {
-		exitCode := -1
-		var exitErr *exec.ExitError
-		if errors.As(err, &exitErr) {
-			exitCode = exitErr.ExitCode()
+// boardArgs selects the board and, when the job targets one, the
+// programmer among several attached.
+func boardArgs(j FlashJob) []string {
+	args := []string{"-b", j.Board}
+	if j.Target.Cable != "" {
+		args = append(args, "-c", j.Target.Cable)
+	}
+	if kind, value, ok := strings.Cut(j.Target.Device, ":"); ok {
+		switch kind {
+		case "serial":
+			args = append(args, "--ftdi-serial", value)
+		case "busdev":
+			args = append(args, "--busdev-num", value)
 		}
+	}
+	if j.Target.FTDIIndex != nil {
+		args = append(args, "--cable-index", strconv.Itoa(*j.Target.FTDIIndex))
+	}
+	return args
+}
+
+// modeArgs writes the SPI flash, at an offset when one is set, for flash
+// mode jobs; SRAM loads need no arguments.
+func modeArgs(j FlashJob) []string {
+	if j.Mode != job.ModeFlash {
+		return nil
+	}
+	args := []string{"-f"}
+	if j.FlashOffset != 0 {
+		args = append(args, "-o", fmt.Sprintf("0x%x", j.FlashOffset))
+	}
+	return args
+}
+
+func (f *OpenFPGALoaderFlasher) run(ctx context.Context, job FlashJob, args []string, logFile io.Writer) (Result, error) {
+	_, _ = fmt.Fprintf(logFile, commandEchoPrefix+"%s %s\n", f.Bin, strings.Join(args, " "))
+
+	if job.WorkDir != "" {
+		if err := os.MkdirAll(job.WorkDir, 0o755); err != nil {
+			return Result{Message: "failed to prepare work directory", ExitCode: -1}, err
+		}
+	}
+	step := "flash"
+	if job.DryRun {
+		step = "detect"
+	}
+	out := io.MultiWriter(logFile, newProgressWriter(step, job.Progress))
+	exitCode, err := f.exec(ctx, builder.CommandSpec{Name: f.Bin, Args: args, Dir: job.WorkDir}, out)
+	if err != nil {
 		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
 			if exitCode == -1 {
 				exitCode = 124
@@ -90,11 +186,32 @@ func (f *OpenFPGALoaderFlasher) Flash(ctx context.Context, job FlashJob) (Result
 	return Result{Message: "flash succeeded", ExitCode: 0}, nil
 }
 
+func (f *OpenFPGALoaderFlasher) exec(ctx context.Context, spec builder.CommandSpec, logFile io.Writer) (int, error) {
+	if f.Runner != nil {
+		return f.Runner.Run(ctx, spec, logFile, logFile)
+	}
+	cmd := exec.CommandContext(ctx, spec.Name, spec.Args...)
+	cmd.Dir = spec.Dir
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	err := cmd.Run()
+	if err == nil {
+		return 0, nil
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), err
+	}
+	return -1, err
+}
+
 type FakeFlasher struct {
 	Delay    time.Duration
 	Fail     bool
 	ExitCode int
 	Message  string
+	// Devices is what Scan reports.
+	Devices []job.Device
 }
 
 func (f *FakeFlasher) Flash(ctx context.Context, job FlashJob) (Result, error) {
@@ -108,11 +225,30 @@ func (f *FakeFlasher) Flash(ctx context.Context, job FlashJob) (Result, error) {
 	}
 	defer logFile.Close()
 
+	step, target := "flash", job.BitstreamPath
+	if job.DryRun {
+		step, target = "detect", "--detect"
+	}
 	if job.Progress != nil {
-		job.Progress(ProgressUpdate{Step: "flash", Message: "running fake flasher", HeartbeatAt: time.Now().UTC()})
+		job.Progress(ProgressUpdate{Step: step, Message: "running fake flasher", HeartbeatAt: time.Now().UTC()})
 	}
 
-	_, _ = fmt.Fprintf(logFile, "fake flashing board=%s bitstream=%s\n", job.Board, job.BitstreamPath)
+	args := append(boardArgs(job), target)
+	if !job.DryRun {
+		args = append(append(boardArgs(job), modeArgs(job)...), target)
+	}
+	inv := newInvocation(append([]string{"fake"}, args...), job.WorkDir)
+	inv.ToolVersion = "fake"
+	result, err := f.run(ctx, job, logFile)
+	inv.finish(result, err)
+	if werr := writeInvocation(job.ArtifactsDir, inv); werr != nil {
+		_, _ = fmt.Fprintf(logFile, "spadeloader: write %s: %v\n", InvocationFileName, werr)
+	}
+	return result, err
+}
+
+func (f *FakeFlasher) run(ctx context.Context, job FlashJob, logFile io.Writer) (Result, error) {
+	_, _ = fmt.Fprintf(logFile, "fake flashing board=%s bitstream=%s mode=%s dry_run=%t\n", job.Board, job.BitstreamPath, job.Mode, job.DryRun)
 
 	if f.Delay > 0 {
 		timer := time.NewTimer(f.Delay)
@@ -140,6 +276,9 @@ func (f *FakeFlasher) Flash(ctx context.Context, job FlashJob) (Result, error) {
 	message := f.Message
 	if strings.TrimSpace(message) == "" {
 		message = "flash succeeded"
+		if job.DryRun {
+			message = dryRunMessage
+		}
 	}
 	_, _ = fmt.Fprintln(logFile, message)
 	return Result{Message: message, ExitCode: 0}, nil
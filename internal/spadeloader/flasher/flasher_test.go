@@ -0,0 +1,227 @@
+package flasher
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/spadeloader/job"
+)
+
+func TestOpenFPGALoaderFlasher_CapturesInvocationAndOutputs(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the flash tool")
+	}
+
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "openFPGALoader")
+	script := "#!/bin/sh\n" +
+		"if [ \"$1\" = \"--Version\" ]; then echo 'openFPGALoader v0.12.1'; exit 0; fi\n" +
+		"echo 'Load SRAM: [====] 100.00%'\n" +
+		"echo readback > verify.bin\n"
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	artifactsDir := filepath.Join(dir, "artifacts")
+	f := NewOpenFPGALoaderFlasher(bin)
+	if _, err := f.Flash(context.Background(), FlashJob{
+		ID:            "j1",
+		Board:         "arty",
+		BitstreamPath: filepath.Join(dir, "design.bit"),
+		WorkDir:       filepath.Join(dir, "work"),
+		ArtifactsDir:  artifactsDir,
+	}); err != nil {
+		t.Fatalf("Flash() error: %v", err)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(artifactsDir, InvocationFileName))
+	if err != nil {
+		t.Fatalf("read invocation: %v", err)
+	}
+	var inv Invocation
+	if err := json.Unmarshal(raw, &inv); err != nil {
+		t.Fatal(err)
+	}
+	if len(inv.Command) != 4 || inv.Command[1] != "-b" || inv.Command[2] != "arty" {
+		t.Fatalf("unexpected command: %v", inv.Command)
+	}
+	if inv.ToolVersion != "openFPGALoader v0.12.1" {
+		t.Fatalf("ToolVersion = %q", inv.ToolVersion)
+	}
+	if _, ok := inv.Env["PATH"]; !ok {
+		t.Fatalf("expected PATH in env subset, got %v", inv.Env)
+	}
+	if len(inv.Outputs) != 1 || inv.Outputs[0] != "outputs/verify.bin" {
+		t.Fatalf("Outputs = %v", inv.Outputs)
+	}
+	if got, err := os.ReadFile(filepath.Join(artifactsDir, "outputs", "verify.bin")); err != nil || string(got) != "readback\n" {
+		t.Fatalf("copied output = %q, %v", got, err)
+	}
+}
+
+func TestOpenFPGALoaderFlasher_DryRunDetectsWithoutProgramming(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the flash tool")
+	}
+
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "openFPGALoader")
+	script := "#!/bin/sh\n" +
+		"if [ \"$1\" = \"--Version\" ]; then echo 'openFPGALoader v0.12.1'; exit 0; fi\n" +
+		"echo \"args: $*\"\n"
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	var steps []string
+	artifactsDir := filepath.Join(dir, "artifacts")
+	result, err := NewOpenFPGALoaderFlasher(bin).Flash(context.Background(), FlashJob{
+		ID:            "j1",
+		Board:         "arty",
+		BitstreamPath: filepath.Join(dir, "design.bit"),
+		WorkDir:       filepath.Join(dir, "work"),
+		ArtifactsDir:  artifactsDir,
+		DryRun:        true,
+		Progress:      func(u ProgressUpdate) { steps = append(steps, u.Step) },
+	})
+	if err != nil {
+		t.Fatalf("Flash() error: %v", err)
+	}
+	if result.Message != dryRunMessage {
+		t.Fatalf("Message = %q", result.Message)
+	}
+	if len(steps) != 1 || steps[0] != "detect" {
+		t.Fatalf("progress steps = %v, want [detect]", steps)
+	}
+	console, err := os.ReadFile(filepath.Join(artifactsDir, "console.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(console), "args: -b arty --detect") || strings.Contains(string(console), "args: -b arty "+filepath.Join(dir, "design.bit")) {
+		t.Fatalf("console log = %q", console)
+	}
+}
+
+func TestBoardArgs_TargetsProgrammer(t *testing.T) {
+	t.Parallel()
+
+	index := 2
+	for _, tc := range []struct {
+		target job.Target
+		want   string
+	}{
+		{job.Target{}, "-b arty"},
+		{job.Target{Device: "serial:210319B0"}, "-b arty --ftdi-serial 210319B0"},
+		{job.Target{Device: "busdev:1:4", Cable: "ft2232"}, "-b arty -c ft2232 --busdev-num 1:4"},
+		{job.Target{FTDIIndex: &index}, "-b arty --cable-index 2"},
+	} {
+		got := strings.Join(boardArgs(FlashJob{Board: "arty", Target: tc.target}), " ")
+		if got != tc.want {
+			t.Fatalf("boardArgs(%+v) = %q, want %q", tc.target, got, tc.want)
+		}
+	}
+}
+
+func TestModeArgs_WritesSPIFlash(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		job  FlashJob
+		want string
+	}{
+		{FlashJob{}, ""},
+		{FlashJob{Mode: job.ModeSRAM}, ""},
+		{FlashJob{Mode: job.ModeFlash}, "-f"},
+		{FlashJob{Mode: job.ModeFlash, FlashOffset: 0x100000}, "-f -o 0x100000"},
+	} {
+		if got := strings.Join(modeArgs(tc.job), " "); got != tc.want {
+			t.Fatalf("modeArgs(%q, %#x) = %q, want %q", tc.job.Mode, tc.job.FlashOffset, got, tc.want)
+		}
+	}
+}
+
+func TestOpenFPGALoaderFlasher_ReplaysRecordedSession(t *testing.T) {
+	t.Parallel()
+
+	session, err := builder.LoadSession(filepath.Join("testdata", "openfpgaloader_usb_permissions.session.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	f := NewOpenFPGALoaderFlasher("openFPGALoader")
+	f.Runner = &builder.ReplayRunner{Session: session}
+
+	result, flashErr := f.Flash(context.Background(), FlashJob{
+		ID:            "j1",
+		Board:         "arty",
+		BitstreamPath: filepath.Join(dir, "design.bit"),
+		WorkDir:       filepath.Join(dir, "work"),
+		ArtifactsDir:  filepath.Join(dir, "artifacts"),
+	})
+	if flashErr == nil || result.ExitCode != 1 {
+		t.Fatalf("Flash() = %+v, %v; want recorded exit 1", result, flashErr)
+	}
+	console, err := os.ReadFile(filepath.Join(dir, "artifacts", "console.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kind, _ := ClassifyFailure(console, flashErr); kind != job.FailurePermissionDenied {
+		t.Fatalf("kind = %q, want %q", kind, job.FailurePermissionDenied)
+	}
+}
+
+func TestOpenFPGALoaderFlasher_ReportsProgressPercent(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the flash tool")
+	}
+
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "openFPGALoader")
+	// openFPGALoader redraws its bar with \r and ends it with a newline.
+	script := "#!/bin/sh\n" +
+		"printf 'Load SRAM: [==>       ] 25.00%%\\rLoad SRAM: [==>       ] 25.40%%\\r'\n" +
+		"printf 'Load SRAM: [=========>] 100.00%%\\nDone\\n'\n"
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	var updates []ProgressUpdate
+	f := NewOpenFPGALoaderFlasher(bin)
+	if _, err := f.Flash(context.Background(), FlashJob{
+		ID:            "j1",
+		Board:         "arty",
+		BitstreamPath: filepath.Join(dir, "design.bit"),
+		ArtifactsDir:  filepath.Join(dir, "artifacts"),
+		Progress:      func(u ProgressUpdate) { updates = append(updates, u) },
+	}); err != nil {
+		t.Fatalf("Flash() error: %v", err)
+	}
+
+	var percents []float64
+	for _, u := range updates {
+		if u.Percent == nil {
+			continue
+		}
+		if u.Step != "flash" || u.Message != "Load SRAM" {
+			t.Fatalf("unexpected progress update: %+v", u)
+		}
+		percents = append(percents, *u.Percent)
+	}
+	// 25.40% is the same whole percent as 25.00% and is not reported again.
+	if len(percents) != 2 || percents[0] != 25 || percents[1] != 100 {
+		t.Fatalf("percents = %v", percents)
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, "artifacts", "console.log"))
+	if err != nil || !strings.Contains(string(raw), "100.00%") {
+		t.Fatalf("console.log should keep the raw output: %q err=%v", raw, err)
+	}
+}
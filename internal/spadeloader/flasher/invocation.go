@@ -0,0 +1,149 @@
+package flasher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// InvocationFileName records how the flash tool was run, next to
+	// console.log in the job's artifacts.
+	InvocationFileName = "invocation.json"
+	// OutputsDirName holds files the tool left in its working directory,
+	// e.g. readback or verify dumps.
+	OutputsDirName = "outputs"
+
+	versionProbeTimeout = 5 * time.Second
+)
+
+// invocationEnvKeys are the environment variables that change how
+// openFPGALoader finds its libraries and USB devices.
+var invocationEnvKeys = []string{
+	"PATH",
+	"LD_LIBRARY_PATH",
+	"DYLD_LIBRARY_PATH",
+	"LIBUSB_DEBUG",
+	"OPENFPGALOADER_SOJ_DIR",
+}
+
+// Invocation describes a single flash tool run.
+type Invocation struct {
+	Command     []string          `json:"command"`
+	Binary      string            `json:"binary,omitempty"`
+	WorkDir     string            `json:"work_dir,omitempty"`
+	Env         map[string]string `json:"env,omitempty"`
+	ToolVersion string            `json:"tool_version,omitempty"`
+	StartedAt   time.Time         `json:"started_at"`
+	FinishedAt  time.Time         `json:"finished_at"`
+	DurationMS  int64             `json:"duration_ms"`
+	ExitCode    int               `json:"exit_code"`
+	Error       string            `json:"error,omitempty"`
+	Outputs     []string          `json:"outputs,omitempty"`
+}
+
+func newInvocation(command []string, workDir string) Invocation {
+	env := map[string]string{}
+	for _, key := range invocationEnvKeys {
+		if v, ok := os.LookupEnv(key); ok {
+			env[key] = v
+		}
+	}
+	return Invocation{
+		Command:   command,
+		WorkDir:   workDir,
+		Env:       env,
+		StartedAt: time.Now().UTC(),
+	}
+}
+
+func (inv *Invocation) finish(result Result, err error) {
+	inv.FinishedAt = time.Now().UTC()
+	inv.DurationMS = inv.FinishedAt.Sub(inv.StartedAt).Milliseconds()
+	inv.ExitCode = result.ExitCode
+	if err != nil {
+		inv.Error = err.Error()
+	}
+}
+
+func writeInvocation(artifactsDir string, inv Invocation) error {
+	raw, err := json.MarshalIndent(inv, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(artifactsDir, InvocationFileName), raw, 0o644)
+}
+
+// toolVersion runs `<bin> --Version` and returns its first non-empty line.
+func toolVersion(ctx context.Context, bin string) string {
+	ctx, cancel := context.WithTimeout(ctx, versionProbeTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, bin, "--Version").CombinedOutput()
+	if err != nil && len(out) == 0 {
+		return ""
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if t := strings.TrimSpace(line); t != "" {
+			return t
+		}
+	}
+	return ""
+}
+
+// collectOutputs copies every regular file under workDir into
+// artifactsDir/outputs and returns their slash-separated relative paths.
+func collectOutputs(workDir, artifactsDir string) ([]string, error) {
+	if workDir == "" {
+		return nil, nil
+	}
+	var outputs []string
+	err := filepath.WalkDir(workDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(workDir, path)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(artifactsDir, OutputsDirName, rel)
+		if err := copyFile(path, dst); err != nil {
+			return fmt.Errorf("copy %s: %w", rel, err)
+		}
+		outputs = append(outputs, filepath.ToSlash(filepath.Join(OutputsDirName, rel)))
+		return nil
+	})
+	return outputs, err
+}
+
+func copyFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
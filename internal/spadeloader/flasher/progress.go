@@ -0,0 +1,70 @@
+package flasher
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// progressPattern matches openFPGALoader's progress bars, which it redraws
+// with \r: "Load SRAM: [=====>       ] 45.00%".
+var progressPattern = regexp.MustCompile(`^\s*([^\[]*?):?\s*\[[^\]]*\]\s*(\d{1,3}(?:\.\d+)?)%`)
+
+// progressWriter reports openFPGALoader's progress bars through report as
+// the output streams past, once per whole percent.
+type progressWriter struct {
+	step   string
+	report ProgressFunc
+
+	buf       []byte
+	lastLabel string
+	lastPct   int
+}
+
+func newProgressWriter(step string, report ProgressFunc) *progressWriter {
+	return &progressWriter{step: step, report: report, lastPct: -1}
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexAny(w.buf, "\r\n")
+		if i < 0 {
+			break
+		}
+		w.line(string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+	// A bar still being drawn has no terminator yet; parse it anyway so
+	// the last percent isn't held back until the next redraw.
+	if len(w.buf) > 0 {
+		w.line(string(w.buf))
+	}
+	return len(p), nil
+}
+
+func (w *progressWriter) line(line string) {
+	match := progressPattern.FindStringSubmatch(line)
+	if match == nil {
+		return
+	}
+	pct, err := strconv.ParseFloat(match[2], 64)
+	if err != nil || pct > 100 {
+		return
+	}
+	label := strings.TrimSpace(match[1])
+	if label == w.lastLabel && int(pct) == w.lastPct {
+		return
+	}
+	w.lastLabel, w.lastPct = label, int(pct)
+	if w.report == nil {
+		return
+	}
+	message := label
+	if message == "" {
+		message = "running openFPGALoader"
+	}
+	w.report(ProgressUpdate{Step: w.step, Message: message, Percent: &pct, HeartbeatAt: time.Now().UTC()})
+}
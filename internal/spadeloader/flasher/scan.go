@@ -0,0 +1,105 @@
+package flasher
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/spadeloader/job"
+)
+
+// Scanner lists the programmers attached to the host. Flashers that can't
+// see USB devices don't implement it.
+type Scanner interface {
+	Scan(ctx context.Context) ([]job.Device, error)
+}
+
+// scanColumns are the --scan-usb table headers; values are aligned under
+// them, and manufacturer and product may contain spaces.
+var scanColumns = []string{"Bus", "device", "vid:pid", "probe type", "manufacturer", "serial", "product"}
+
+// Scan runs openFPGALoader --scan-usb. Only Bus, Address and the USB
+// strings are filled in; the inventory adds the rest.
+func (f *OpenFPGALoaderFlasher) Scan(ctx context.Context) ([]job.Device, error) {
+	var out bytes.Buffer
+	exitCode, err := f.exec(ctx, builder.CommandSpec{Name: f.Bin, Args: []string{"--scan-usb"}}, &out)
+	if err != nil {
+		_, summary := ClassifyFailure(out.Bytes(), err)
+		return nil, fmt.Errorf("openFPGALoader --scan-usb exited %d: %s", exitCode, summary)
+	}
+	return ParseScanUSB(out.String())
+}
+
+// ParseScanUSB parses the output of openFPGALoader --scan-usb.
+func ParseScanUSB(output string) ([]job.Device, error) {
+	var (
+		devices []job.Device
+		offsets []int
+	)
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if offsets == nil {
+			offsets = scanHeaderOffsets(line)
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := make([]string, len(offsets))
+		for i, start := range offsets {
+			if start >= len(line) {
+				break
+			}
+			end := len(line)
+			if i+1 < len(offsets) && offsets[i+1] < end {
+				end = offsets[i+1]
+			}
+			fields[i] = strings.TrimSpace(line[start:end])
+		}
+		bus, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("parse --scan-usb bus in %q: %w", line, err)
+		}
+		addr, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("parse --scan-usb device in %q: %w", line, err)
+		}
+		devices = append(devices, job.Device{
+			Bus:          bus,
+			Address:      addr,
+			VIDPID:       fields[2],
+			ProbeType:    fields[3],
+			Manufacturer: fields[4],
+			Serial:       fields[5],
+			Product:      fields[6],
+		})
+	}
+	if offsets == nil {
+		return nil, fmt.Errorf("no device table in --scan-usb output")
+	}
+	return devices, nil
+}
+
+// scanHeaderOffsets returns the start of each column, or nil when line is
+// not the table header.
+func scanHeaderOffsets(line string) []int {
+	offsets := make([]int, 0, len(scanColumns))
+	pos := 0
+	for _, col := range scanColumns {
+		i := strings.Index(line[pos:], col)
+		if i < 0 {
+			return nil
+		}
+		offsets = append(offsets, pos+i)
+		pos += i + len(col)
+	}
+	return offsets
+}
+
+// Scan returns a copy of f.Devices.
+func (f *FakeFlasher) Scan(ctx context.Context) ([]job.Device, error) {
+	return append([]job.Device(nil), f.Devices...), nil
+}
@@ -0,0 +1,66 @@
+package flasher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+const scanUSBOutput = `found 2 USB device
+Bus device vid:pid       probe type      manufacturer serial               product
+001 009    0x0403:0x6010 FTDI2232        Digilent     210319A28CB6         Digilent USB Device
+003 004    0x0403:0x6014 FTDI232H        Alchitry                          Alchitry Au
+`
+
+func TestParseScanUSB(t *testing.T) {
+	t.Parallel()
+
+	devices, err := ParseScanUSB(scanUSBOutput)
+	if err != nil {
+		t.Fatalf("ParseScanUSB() error: %v", err)
+	}
+	if len(devices) != 2 {
+		t.Fatalf("got %d devices: %+v", len(devices), devices)
+	}
+	d := devices[0]
+	if d.Bus != 1 || d.Address != 9 || d.VIDPID != "0x0403:0x6010" || d.ProbeType != "FTDI2232" ||
+		d.Manufacturer != "Digilent" || d.Serial != "210319A28CB6" || d.Product != "Digilent USB Device" {
+		t.Fatalf("unexpected first device: %+v", d)
+	}
+	if got := d.Target().Device; got != "serial:210319A28CB6" {
+		t.Fatalf("Target().Device = %q", got)
+	}
+	// No serial: the USB location identifies the device instead.
+	if devices[1].Serial != "" || devices[1].Target().Device != "busdev:3:4" {
+		t.Fatalf("unexpected second device: %+v", devices[1])
+	}
+
+	if _, err := ParseScanUSB("error: unable to open libusb\n"); err == nil {
+		t.Fatal("expected error without a device table")
+	}
+	if devices, err := ParseScanUSB("found 0 USB device\nBus device vid:pid probe type manufacturer serial product\n"); err != nil || len(devices) != 0 {
+		t.Fatalf("empty table: devices=%v err=%v", devices, err)
+	}
+}
+
+func TestOpenFPGALoaderFlasher_Scan(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the flash tool")
+	}
+
+	bin := filepath.Join(t.TempDir(), "openFPGALoader")
+	script := "#!/bin/sh\n[ \"$1\" = \"--scan-usb\" ] || exit 2\ncat <<'EOT'\n" + scanUSBOutput + "EOT\n"
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	devices, err := NewOpenFPGALoaderFlasher(bin).Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan() error: %v", err)
+	}
+	if len(devices) != 2 || devices[1].Product != "Alchitry Au" {
+		t.Fatalf("unexpected devices: %+v", devices)
+	}
+}
@@ -0,0 +1,332 @@
+// Package hass exposes spadeloader boards to Home Assistant through MQTT
+// discovery: each board becomes a device with a state sensor and one
+// "flash" button per golden design.
+package hass
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/mqtt"
+	"github.com/mblsha/spadeforge/internal/spadeloader/config"
+	"github.com/mblsha/spadeforge/internal/spadeloader/job"
+)
+
+// PressPayload is what Home Assistant sends on a button's command topic.
+const PressPayload = "PRESS"
+
+// Queue is the part of the spadeloader queue the bridge drives.
+type Queue interface {
+	ListJobs(limit int) []job.Record
+	GoldenDesigns(isGolden func(board, design string) bool) []job.Record
+	Reflash(ctx context.Context, sourceJobID string) (*job.Record, error)
+	ObserveStates(fn func(job.Record))
+}
+
+// Bridge publishes board devices and their state, and flashes golden
+// designs when their button is pressed.
+type Bridge struct {
+	cfg     config.Config
+	queue   Queue
+	session *mqtt.Session
+	// publish is session.Publish, swapped in tests.
+	publish func(msgs ...mqtt.Message) error
+
+	// node prefixes discovery object and unique IDs so several
+	// spadeloader hosts can share a broker.
+	node    string
+	updates chan job.Record
+
+	mu sync.Mutex
+	// announced holds boards whose discovery config was published on the
+	// current connection; commands maps button command topics to designs.
+	announced map[string]bool
+	commands  map[string]config.GoldenDesign
+}
+
+// New prepares a bridge for the broker at cfg.MQTTURL and registers for
+// queue state changes; call it before the queue starts.
+func New(cfg config.Config, q Queue) (*Bridge, error) {
+	node := cfg.DiscoveryInstance
+	if node == "" {
+		node, _ = os.Hostname()
+	}
+	node = objectID(node)
+	pub, err := mqtt.NewPublisher(cfg.MQTTURL, "spadeloader-"+node)
+	if err != nil {
+		return nil, err
+	}
+	b := &Bridge{
+		cfg:       cfg,
+		queue:     q,
+		node:      node,
+		updates:   make(chan job.Record, 64),
+		announced: map[string]bool{},
+		commands:  map[string]config.GoldenDesign{},
+	}
+	b.session = mqtt.NewSession(pub, mqtt.SessionOptions{
+		Will:      &mqtt.Message{Topic: b.availabilityTopic(), Payload: []byte("offline"), Retain: true},
+		Subscribe: []string{b.topic("boards/+/golden/+/flash")},
+		OnConnect: func(*mqtt.Session) { b.announceAll() },
+		OnMessage: func(msg mqtt.Message) { b.handleCommand(context.Background(), msg) },
+		OnError:   func(err error) { log.Printf("home assistant bridge: %v", err) },
+	})
+	b.publish = b.session.Publish
+	q.ObserveStates(b.observe)
+	return b, nil
+}
+
+// Run keeps the broker session open and publishes state changes until ctx
+// is done.
+func (b *Bridge) Run(ctx context.Context) {
+	go b.session.Run(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case rec := <-b.updates:
+			b.publishBoard(strings.ToLower(rec.Board), &rec)
+		}
+	}
+}
+
+// observe runs under the queue lock, so it only hands the record over.
+func (b *Bridge) observe(rec job.Record) {
+	select {
+	case b.updates <- rec:
+	default:
+		log.Printf("home assistant bridge: update backlog full, dropping %s state for board %s", rec.State, rec.Board)
+	}
+}
+
+// announceAll publishes availability plus every known board's discovery
+// config and current state after each (re)connect.
+func (b *Bridge) announceAll() {
+	b.mu.Lock()
+	b.announced = map[string]bool{}
+	b.mu.Unlock()
+	if err := b.publish(mqtt.Message{Topic: b.availabilityTopic(), Payload: []byte("online"), Retain: true}); err != nil {
+		log.Printf("home assistant bridge: publish availability: %v", err)
+		return
+	}
+	latest := b.latestByBoard()
+	for _, board := range b.knownBoards(latest) {
+		var rec *job.Record
+		if r, ok := latest[board]; ok {
+			rec = &r
+		}
+		b.publishBoard(board, rec)
+	}
+}
+
+// publishBoard announces board on first sight and publishes its state,
+// taken from rec (the board's newest job) or idle when rec is nil.
+func (b *Bridge) publishBoard(board string, rec *job.Record) {
+	if board == "" || !b.cfg.BoardAllowed(board) {
+		return
+	}
+	b.mu.Lock()
+	var msgs []mqtt.Message
+	if !b.announced[board] {
+		msgs = b.discoveryMessagesLocked(board)
+		b.announced[board] = true
+	}
+	b.mu.Unlock()
+
+	state := boardState{State: "IDLE"}
+	if rec != nil {
+		state = boardState{
+			State:     string(rec.State),
+			Design:    rec.DesignName,
+			JobID:     rec.ID,
+			Message:   rec.Message,
+			Error:     rec.Error,
+			UpdatedAt: &rec.UpdatedAt,
+		}
+	}
+	payload, err := json.Marshal(state)
+	if err != nil {
+		log.Printf("home assistant bridge: encode state for board %s: %v", board, err)
+		return
+	}
+	msgs = append(msgs, mqtt.Message{Topic: b.stateTopic(board), Payload: payload, Retain: true})
+	// Between connections the next announceAll republishes everything.
+	if err := b.publish(msgs...); err != nil && !errors.Is(err, mqtt.ErrNotConnected) {
+		log.Printf("home assistant bridge: publish board %s: %v", board, err)
+	}
+}
+
+// boardState is the JSON on a board's state topic; the sensor shows State
+// and exposes the rest as attributes.
+type boardState struct {
+	State     string     `json:"state"`
+	Design    string     `json:"design,omitempty"`
+	JobID     string     `json:"job_id,omitempty"`
+	Message   string     `json:"message,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// discoveryConfig is one retained Home Assistant discovery message.
+type discoveryConfig struct {
+	topic  string
+	config map[string]any
+}
+
+func (b *Bridge) discoveryMessagesLocked(board string) []mqtt.Message {
+	id := b.node + "_" + objectID(board)
+	device := map[string]any{
+		"identifiers":  []string{id},
+		"name":         board,
+		"manufacturer": "spadeforge",
+		"model":        "spadeloader board",
+	}
+	configs := []discoveryConfig{{
+		topic: b.cfg.HassDiscoveryPrefix + "/sensor/" + id + "/state/config",
+		config: map[string]any{
+			"name":                  "State",
+			"unique_id":             id + "_state",
+			"state_topic":           b.stateTopic(board),
+			"value_template":        "{{ value_json.state }}",
+			"json_attributes_topic": b.stateTopic(board),
+			"availability_topic":    b.availabilityTopic(),
+			"icon":                  "mdi:chip",
+			"device":                device,
+		},
+	}}
+	for _, design := range b.goldenDesignsFor(board) {
+		command := b.topic("boards/" + topicLevel(board) + "/golden/" + topicLevel(design) + "/flash")
+		b.commands[command] = config.GoldenDesign{Board: board, Design: design}
+		configs = append(configs, discoveryConfig{
+			topic: b.cfg.HassDiscoveryPrefix + "/button/" + id + "/golden_" + objectID(design) + "/config",
+			config: map[string]any{
+				"name":               "Flash " + design,
+				"unique_id":          id + "_golden_" + objectID(design),
+				"command_topic":      command,
+				"payload_press":      PressPayload,
+				"availability_topic": b.availabilityTopic(),
+				"icon":               "mdi:flash",
+				"device":             device,
+			},
+		})
+	}
+
+	msgs := make([]mqtt.Message, 0, len(configs))
+	for _, c := range configs {
+		payload, err := json.Marshal(c.config)
+		if err != nil {
+			continue
+		}
+		msgs = append(msgs, mqtt.Message{Topic: c.topic, Payload: payload, Retain: true})
+	}
+	return msgs
+}
+
+// handleCommand flashes the newest successful copy of the golden design
+// whose button was pressed.
+func (b *Bridge) handleCommand(ctx context.Context, msg mqtt.Message) {
+	b.mu.Lock()
+	golden, ok := b.commands[msg.Topic]
+	b.mu.Unlock()
+	if !ok || string(msg.Payload) != PressPayload {
+		return
+	}
+	matches := b.queue.GoldenDesigns(func(board, design string) bool {
+		return strings.EqualFold(board, golden.Board) && design == golden.Design
+	})
+	if len(matches) == 0 {
+		log.Printf("home assistant bridge: no flashed copy of golden design %q for board %s to reflash", golden.Design, golden.Board)
+		return
+	}
+	go func(source job.Record) {
+		rec, err := b.queue.Reflash(ctx, source.ID)
+		if err != nil {
+			log.Printf("home assistant bridge: reflash %q on board %s: %v", golden.Design, golden.Board, err)
+			return
+		}
+		log.Printf("home assistant bridge: queued reflash %s of %q on board %s", rec.ID, golden.Design, golden.Board)
+	}(matches[0])
+}
+
+// goldenDesignsFor lists the golden design names offered on board.
+func (b *Bridge) goldenDesignsFor(board string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, g := range b.cfg.GoldenDesigns {
+		if (g.Board == "" || strings.EqualFold(g.Board, board)) && !seen[g.Design] {
+			seen[g.Design] = true
+			out = append(out, g.Design)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// latestByBoard returns each board's newest job, keyed by lowercase board.
+func (b *Bridge) latestByBoard() map[string]job.Record {
+	latest := map[string]job.Record{}
+	for _, rec := range b.queue.ListJobs(0) {
+		board := strings.ToLower(rec.Board)
+		if _, ok := latest[board]; !ok && board != "" {
+			latest[board] = rec
+		}
+	}
+	return latest
+}
+
+// knownBoards merges the allowed boards, boards named by golden designs and
+// boards with jobs, lowercased and sorted.
+func (b *Bridge) knownBoards(latest map[string]job.Record) []string {
+	seen := map[string]bool{}
+	for _, board := range b.cfg.AllowedBoards {
+		seen[strings.ToLower(board)] = true
+	}
+	for _, g := range b.cfg.GoldenDesigns {
+		if g.Board != "" {
+			seen[strings.ToLower(g.Board)] = true
+		}
+	}
+	for board := range latest {
+		seen[board] = true
+	}
+	out := make([]string, 0, len(seen))
+	for board := range seen {
+		out = append(out, board)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (b *Bridge) topic(suffix string) string { return b.cfg.MQTTTopicPrefix + "/" + suffix }
+
+func (b *Bridge) availabilityTopic() string { return b.topic("status") }
+
+func (b *Bridge) stateTopic(board string) string {
+	return b.topic(fmt.Sprintf("boards/%s/state", topicLevel(board)))
+}
+
+var nonObjectID = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// objectID maps a name onto the characters Home Assistant accepts in
+// discovery object IDs.
+func objectID(name string) string {
+	id := strings.Trim(nonObjectID.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if id == "" {
+		return "spadeloader"
+	}
+	return id
+}
+
+// topicLevel keeps a name to one topic level without wildcards.
+func topicLevel(name string) string {
+	return strings.NewReplacer("/", "_", "+", "_", "#", "_").Replace(name)
+}
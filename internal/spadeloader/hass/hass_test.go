@@ -0,0 +1,163 @@
+package hass
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/mqtt"
+	"github.com/mblsha/spadeforge/internal/spadeloader/config"
+	"github.com/mblsha/spadeforge/internal/spadeloader/job"
+)
+
+type fakeQueue struct {
+	jobs      []job.Record
+	reflashed chan string
+}
+
+func (q *fakeQueue) ListJobs(int) []job.Record { return q.jobs }
+
+func (q *fakeQueue) GoldenDesigns(isGolden func(board, design string) bool) []job.Record {
+	var out []job.Record
+	for _, rec := range q.jobs {
+		if rec.State == job.StateSucceeded && isGolden(rec.Board, rec.DesignName) {
+			out = append(out, rec)
+		}
+	}
+	return out
+}
+
+func (q *fakeQueue) Reflash(_ context.Context, id string) (*job.Record, error) {
+	q.reflashed <- id
+	return &job.Record{ID: "reflash-of-" + id}, nil
+}
+
+func (q *fakeQueue) ObserveStates(func(job.Record)) {}
+
+func newTestBridge(t *testing.T, q *fakeQueue) (*Bridge, *[]mqtt.Message) {
+	t.Helper()
+	cfg := config.Default()
+	cfg.MQTTURL = "mqtt://broker.lan"
+	cfg.DiscoveryInstance = "Bench Host"
+	cfg.AllowedBoards = []string{"arty", "Alchitry_AU"}
+	cfg.GoldenDesigns = []config.GoldenDesign{{Board: "arty", Design: "blinky"}, {Design: "uart echo"}}
+	b, err := New(cfg, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var published []mqtt.Message
+	b.publish = func(msgs ...mqtt.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		published = append(published, msgs...)
+		return nil
+	}
+	return b, &published
+}
+
+func byTopic(msgs []mqtt.Message) map[string]mqtt.Message {
+	out := map[string]mqtt.Message{}
+	for _, m := range msgs {
+		out[m.Topic] = m
+	}
+	return out
+}
+
+func TestBridge_AnnouncesBoardsWithStateAndGoldenButtons(t *testing.T) {
+	q := &fakeQueue{jobs: []job.Record{
+		{ID: "j2", Board: "arty", DesignName: "blinky", State: job.StateFailed, Error: "cable not found"},
+		{ID: "j1", Board: "arty", DesignName: "blinky", State: job.StateSucceeded},
+	}}
+	b, published := newTestBridge(t, q)
+	b.announceAll()
+	msgs := byTopic(*published)
+
+	if m := msgs["spadeloader/status"]; string(m.Payload) != "online" || !m.Retain {
+		t.Fatalf("availability = %+v", m)
+	}
+
+	var state boardState
+	if err := json.Unmarshal(msgs["spadeloader/boards/arty/state"].Payload, &state); err != nil {
+		t.Fatal(err)
+	}
+	if state.State != "FAILED" || state.JobID != "j2" || state.Error != "cable not found" {
+		t.Fatalf("arty state = %+v, want the newest job", state)
+	}
+	if err := json.Unmarshal(msgs["spadeloader/boards/alchitry_au/state"].Payload, &state); err != nil || state.State != "IDLE" {
+		t.Fatalf("alchitry_au state = %+v, %v; want IDLE", state, err)
+	}
+
+	var sensor map[string]any
+	if err := json.Unmarshal(msgs["homeassistant/sensor/bench_host_arty/state/config"].Payload, &sensor); err != nil {
+		t.Fatal(err)
+	}
+	if sensor["state_topic"] != "spadeloader/boards/arty/state" || sensor["availability_topic"] != "spadeloader/status" {
+		t.Fatalf("sensor config = %v", sensor)
+	}
+
+	var button map[string]any
+	if err := json.Unmarshal(msgs["homeassistant/button/bench_host_arty/golden_blinky/config"].Payload, &button); err != nil {
+		t.Fatal(err)
+	}
+	if button["command_topic"] != "spadeloader/boards/arty/golden/blinky/flash" || button["payload_press"] != PressPayload {
+		t.Fatalf("button config = %v", button)
+	}
+	if _, ok := msgs["homeassistant/button/bench_host_alchitry_au/golden_uart_echo/config"]; !ok {
+		t.Fatalf("expected the board-less golden design on every board")
+	}
+	if _, ok := msgs["homeassistant/button/bench_host_alchitry_au/golden_blinky/config"]; ok {
+		t.Fatalf("blinky is golden only on arty")
+	}
+}
+
+func TestBridge_ButtonPressReflashesNewestGoldenJob(t *testing.T) {
+	q := &fakeQueue{
+		jobs: []job.Record{
+			{ID: "j2", Board: "arty", DesignName: "blinky", State: job.StateFailed},
+			{ID: "j1", Board: "arty", DesignName: "blinky", State: job.StateSucceeded},
+		},
+		reflashed: make(chan string, 1),
+	}
+	b, _ := newTestBridge(t, q)
+	b.announceAll()
+
+	b.handleCommand(context.Background(), mqtt.Message{Topic: "spadeloader/boards/arty/golden/blinky/flash", Payload: []byte("ignored")})
+	b.handleCommand(context.Background(), mqtt.Message{Topic: "spadeloader/boards/arty/golden/blinky/flash", Payload: []byte(PressPayload)})
+	select {
+	case id := <-q.reflashed:
+		if id != "j1" {
+			t.Fatalf("reflashed %s, want the succeeded job j1", id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("button press did not reflash")
+	}
+	select {
+	case id := <-q.reflashed:
+		t.Fatalf("unexpected extra reflash of %s", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestBridge_StateUpdatesAnnounceNewBoardsOnce(t *testing.T) {
+	b, published := newTestBridge(t, &fakeQueue{})
+	b.cfg.AllowedBoards = nil
+
+	b.publishBoard("ulx3s", &job.Record{ID: "j1", Board: "ulx3s", State: job.StateRunning})
+	b.publishBoard("ulx3s", &job.Record{ID: "j1", Board: "ulx3s", State: job.StateSucceeded})
+
+	var configs, states int
+	for _, m := range *published {
+		switch m.Topic {
+		case "homeassistant/sensor/bench_host_ulx3s/state/config":
+			configs++
+		case "spadeloader/boards/ulx3s/state":
+			states++
+		}
+	}
+	if configs != 1 || states != 2 {
+		t.Fatalf("published %d sensor configs and %d states, want 1 and 2", configs, states)
+	}
+}
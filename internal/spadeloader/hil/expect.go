@@ -0,0 +1,162 @@
+package hil
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultExpectTimeout bounds each expect step unless the script sets its
+// own with a timeout line.
+const DefaultExpectTimeout = 10 * time.Second
+
+// Step is one line of an expect script.
+type Step struct {
+	Line int
+	// Exactly one of Send, Expect and Sleep is set; Timeout steps only
+	// change the wait for later expects.
+	Send    string
+	Expect  *regexp.Regexp
+	Sleep   time.Duration
+	Timeout time.Duration
+}
+
+// ParseExpect reads an expect script: one "send <text>", "expect <regexp>",
+// "sleep <duration>" or "timeout <duration>" per line, with "#" comments.
+// Send text takes Go escapes, so "send help\r\n" ends with CR LF.
+func ParseExpect(r io.Reader) ([]Step, error) {
+	var steps []Step
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		arg = strings.TrimSpace(arg)
+		step := Step{Line: n}
+		switch verb {
+		case "send":
+			text, err := strconv.Unquote(`"` + strings.ReplaceAll(arg, `"`, `\"`) + `"`)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: send %q: invalid escape", n, arg)
+			}
+			step.Send = text
+		case "expect":
+			re, err := regexp.Compile(arg)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			step.Expect = re
+		case "sleep", "timeout":
+			d, err := time.ParseDuration(arg)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("line %d: %s needs a positive duration, got %q", n, verb, arg)
+			}
+			if verb == "sleep" {
+				step.Sleep = d
+			} else {
+				step.Timeout = d
+			}
+		default:
+			return nil, fmt.Errorf("line %d: unknown command %q (want send, expect, sleep or timeout)", n, verb)
+		}
+		steps = append(steps, step)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(steps) == 0 {
+		return nil, errors.New("expect script has no steps")
+	}
+	return steps, nil
+}
+
+// Play runs steps against port, copying everything received to transcript
+// along with a "> " line per send and a "# " note per matched expect. It
+// returns nil once every step ran, or an error naming the step that failed.
+func Play(ctx context.Context, port io.ReadWriter, steps []Step, transcript io.Writer) error {
+	received := make(chan []byte, 16)
+	readErr := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+	// The reader stays blocked in Read until the caller closes port.
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, err := port.Read(buf)
+			if n > 0 {
+				chunk := append([]byte(nil), buf[:n]...)
+				select {
+				case received <- chunk:
+				case <-done:
+					return
+				}
+			}
+			if err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	var pending strings.Builder
+	timeout := DefaultExpectTimeout
+	for _, step := range steps {
+		switch {
+		case step.Timeout > 0:
+			timeout = step.Timeout
+		case step.Sleep > 0:
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(step.Sleep):
+			}
+		case step.Expect == nil:
+			_, _ = fmt.Fprintf(transcript, "\n> %q\n", step.Send)
+			if _, err := io.WriteString(port, step.Send); err != nil {
+				return fmt.Errorf("line %d: send: %w", step.Line, err)
+			}
+		default:
+			timer := time.NewTimer(timeout)
+			for !matchPending(&pending, step.Expect) {
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return fmt.Errorf("line %d: expect %q: %w", step.Line, step.Expect, ctx.Err())
+				case <-timer.C:
+					return fmt.Errorf("line %d: expect %q: no match within %s", step.Line, step.Expect, timeout)
+				case err := <-readErr:
+					timer.Stop()
+					return fmt.Errorf("line %d: expect %q: read: %w", step.Line, step.Expect, err)
+				case chunk := <-received:
+					_, _ = transcript.Write(chunk)
+					pending.Write(chunk)
+				}
+			}
+			timer.Stop()
+			_, _ = fmt.Fprintf(transcript, "\n# line %d: matched %q\n", step.Line, step.Expect)
+		}
+	}
+	return nil
+}
+
+// matchPending reports whether re matches the unconsumed output and, if so,
+// drops everything up to the end of the match.
+func matchPending(pending *strings.Builder, re *regexp.Regexp) bool {
+	text := pending.String()
+	loc := re.FindStringIndex(text)
+	if loc == nil {
+		return false
+	}
+	rest := text[loc[1]:]
+	pending.Reset()
+	pending.WriteString(rest)
+	return true
+}
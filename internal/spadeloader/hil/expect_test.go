@@ -0,0 +1,79 @@
+package hil
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseExpect(t *testing.T) {
+	steps, err := ParseExpect(strings.NewReader("# boot banner\ntimeout 2s\nexpect hello, (world|board)\nsend ping\\r\\n\nsleep 10ms\nexpect \"pong\"\n"))
+	if err != nil {
+		t.Fatalf("ParseExpect() error: %v", err)
+	}
+	if len(steps) != 5 {
+		t.Fatalf("len(steps) = %d, want 5", len(steps))
+	}
+	if steps[0].Timeout != 2*time.Second || steps[1].Expect.String() != "hello, (world|board)" || steps[2].Send != "ping\r\n" || steps[3].Sleep != 10*time.Millisecond {
+		t.Fatalf("unexpected steps: %+v", steps)
+	}
+	if steps[4].Expect.String() != `"pong"` || steps[4].Line != 6 {
+		t.Fatalf("quoted expect = %q at line %d", steps[4].Expect, steps[4].Line)
+	}
+
+	for _, bad := range []string{"", "# only comments\n", "expect (\n", "wait 1s\n", "sleep soon\n", "send \\q\n"} {
+		if _, err := ParseExpect(strings.NewReader(bad)); err == nil {
+			t.Fatalf("ParseExpect(%q) should fail", bad)
+		}
+	}
+}
+
+func TestPlay(t *testing.T) {
+	host, board := net.Pipe()
+	defer host.Close()
+	defer board.Close()
+	go func() {
+		_, _ = board.Write([]byte("boot...\r\nhello from arty\r\n"))
+		line, err := bufio.NewReader(board).ReadString('\n')
+		if err == nil && line == "ping\r\n" {
+			_, _ = board.Write([]byte("pong\r\n"))
+		}
+	}()
+
+	steps, err := ParseExpect(strings.NewReader("expect hello from \\w+\nsend ping\\r\\n\nexpect pong\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var transcript strings.Builder
+	if err := Play(context.Background(), host, steps, &transcript); err != nil {
+		t.Fatalf("Play() error: %v\n%s", err, transcript.String())
+	}
+	for _, want := range []string{"hello from arty", `> "ping\r\n"`, "pong", `# line 3: matched "pong"`} {
+		if !strings.Contains(transcript.String(), want) {
+			t.Fatalf("transcript missing %q:\n%s", want, transcript.String())
+		}
+	}
+}
+
+func TestPlayTimesOut(t *testing.T) {
+	host, board := net.Pipe()
+	defer host.Close()
+	defer board.Close()
+	go func() { _, _ = board.Write([]byte("panic: bad bitstream\n")) }()
+
+	steps, err := ParseExpect(strings.NewReader("timeout 50ms\nexpect hello\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var transcript strings.Builder
+	err = Play(context.Background(), host, steps, &transcript)
+	if err == nil || !strings.Contains(err.Error(), `line 2: expect "hello": no match within 50ms`) {
+		t.Fatalf("Play() error = %v", err)
+	}
+	if !strings.Contains(transcript.String(), "panic: bad bitstream") {
+		t.Fatalf("transcript should keep the board's output:\n%s", transcript.String())
+	}
+}
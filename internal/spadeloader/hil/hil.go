@@ -0,0 +1,101 @@
+// Package hil runs a board's hardware-in-the-loop test after a flash: a
+// script, or an expect sequence played against the board's UART.
+package hil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/spadeloader/config"
+	"github.com/mblsha/spadeforge/internal/spadeloader/serial"
+)
+
+// LogFileName is the test transcript written to the job's artifacts.
+const LogFileName = "hil.log"
+
+// Job describes the flash a test follows.
+type Job struct {
+	ID            string
+	Board         string
+	DesignName    string
+	BitstreamPath string
+	ArtifactsDir  string
+}
+
+// Run runs test for j and writes its output to LogFileName in
+// j.ArtifactsDir. A nil error means the test passed; otherwise the error is
+// the one-line verdict.
+func Run(ctx context.Context, test config.HILTest, j Job) error {
+	if err := os.MkdirAll(j.ArtifactsDir, 0o755); err != nil {
+		return err
+	}
+	logFile, err := os.Create(filepath.Join(j.ArtifactsDir, LogFileName))
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	if test.Script != "" {
+		err = runScript(ctx, test.Script, j, logFile)
+	} else {
+		err = runExpect(ctx, test, logFile)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(logFile, "\nspadeloader: test failed: %v\n", err)
+		return err
+	}
+	_, _ = fmt.Fprintln(logFile, "\nspadeloader: test passed")
+	return nil
+}
+
+// runScript runs script in the artifacts dir, so files it writes there are
+// kept with the job. Exit status 0 passes.
+func runScript(ctx context.Context, script string, j Job, out io.Writer) error {
+	_, _ = fmt.Fprintf(out, "running: %s\n", script)
+	cmd := exec.CommandContext(ctx, script)
+	cmd.Dir = j.ArtifactsDir
+	cmd.Env = append(os.Environ(),
+		"SPADELOADER_JOB_ID="+j.ID,
+		"SPADELOADER_BOARD="+j.Board,
+		"SPADELOADER_DESIGN="+j.DesignName,
+		"SPADELOADER_BITSTREAM="+j.BitstreamPath,
+		"SPADELOADER_ARTIFACTS_DIR="+j.ArtifactsDir,
+	)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	cmd.WaitDelay = 5 * time.Second
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(script), ctx.Err())
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return fmt.Errorf("%s exited with status %d", filepath.Base(script), exitErr.ExitCode())
+	}
+	return err
+}
+
+func runExpect(ctx context.Context, test config.HILTest, out io.Writer) error {
+	raw, err := os.ReadFile(test.ExpectFile)
+	if err != nil {
+		return err
+	}
+	steps, err := ParseExpect(strings.NewReader(string(raw)))
+	if err != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(test.ExpectFile), err)
+	}
+	_, _ = fmt.Fprintf(out, "opening %s at %d baud for %s\n", test.SerialPort, test.Baud, test.ExpectFile)
+	port, err := serial.Open(test.SerialPort, test.Baud)
+	if err != nil {
+		return err
+	}
+	defer port.Close()
+	return Play(ctx, port, steps, out)
+}
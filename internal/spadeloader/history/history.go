@@ -22,6 +22,9 @@ type Item struct {
 	SubmittedAt        time.Time `json:"submitted_at"`
 	FinishedAt         time.Time `json:"finished_at"`
 	State              job.State `json:"state"`
+	// Mode tells persistent designs, written to SPI flash, from SRAM loads.
+	Mode        job.Mode `json:"mode,omitempty"`
+	FlashOffset uint32   `json:"flash_offset,omitempty"`
 }
 
 type filePayload struct {
@@ -0,0 +1,40 @@
+package job
+
+// AllTaggedPrefix in a submission's board field flashes every board
+// carrying the tag that follows, e.g. "all-tagged:bench".
+const AllTaggedPrefix = "all-tagged:"
+
+// Batch is returned by batch submissions and GET /v1/batches/{id}: the jobs
+// flashing one upload onto several boards, in submission order. State is
+// QUEUED until a job starts, RUNNING until all have finished, then
+// SUCCEEDED only if every job did.
+type Batch struct {
+	ID     string        `json:"batch_id"`
+	State  State         `json:"state"`
+	Counts map[State]int `json:"counts"`
+	Jobs   []Record      `json:"jobs"`
+}
+
+// NewBatch summarizes the jobs of batch id.
+func NewBatch(id string, jobs []Record) Batch {
+	b := Batch{ID: id, Counts: map[State]int{}, Jobs: jobs}
+	for _, rec := range jobs {
+		b.Counts[rec.State]++
+	}
+	switch {
+	case b.Counts[StateQueued] == len(jobs):
+		b.State = StateQueued
+	case b.Counts[StateQueued]+b.Counts[StateRunning] > 0:
+		b.State = StateRunning
+	case b.Counts[StateFailed] > 0:
+		b.State = StateFailed
+	default:
+		b.State = StateSucceeded
+	}
+	return b
+}
+
+// Terminal reports whether every job of the batch has finished.
+func (b Batch) Terminal() bool {
+	return b.State == StateSucceeded || b.State == StateFailed
+}
@@ -0,0 +1,52 @@
+package job
+
+import (
+	"strconv"
+	"time"
+)
+
+// Device is a USB programmer seen by openFPGALoader --scan-usb. Devices
+// stay in the inventory after they are unplugged so clients can show when
+// a board was last attached.
+type Device struct {
+	Bus          int    `json:"bus"`
+	Address      int    `json:"address"`
+	VIDPID       string `json:"vid_pid,omitempty"`
+	ProbeType    string `json:"probe_type,omitempty"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	Serial       string `json:"serial,omitempty"`
+	Product      string `json:"product,omitempty"`
+	// Board is the openFPGALoader board name configured for Serial in
+	// SPADELOADER_DEVICE_BOARDS, empty when unknown.
+	Board     string    `json:"board,omitempty"`
+	Connected bool      `json:"connected"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// Key identifies d across scans: its serial when it has one, otherwise
+// its USB location.
+func (d Device) Key() string {
+	if d.Serial != "" {
+		return "serial:" + d.Serial
+	}
+	return d.BusDev()
+}
+
+// BusDev is d's USB location in Target.Device form.
+func (d Device) BusDev() string {
+	return "busdev:" + strconv.Itoa(d.Bus) + ":" + strconv.Itoa(d.Address)
+}
+
+// Target selects d for a flash. Serials survive replugging, so they are
+// preferred over the bus location.
+func (d Device) Target() Target {
+	t := Target{Device: d.Key()}
+	t.Normalize()
+	if t.Validate() != nil {
+		// Serials with characters openFPGALoader can't take unquoted fall
+		// back to the location.
+		t.Device = d.BusDev()
+	}
+	return t
+}
@@ -2,16 +2,36 @@ package job
 
 import "time"
 
+// EventDroppedEvents notices tell a subscriber that it fell behind and
+// missed events; its Seq is the last event delivered before the gap, so
+// clients resync by reconnecting with since=Seq.
+const EventDroppedEvents = "dropped_events"
+
+// EventSnapshot events replace the backlog when a subscriber's since falls
+// outside the retained window; they carry the job's current state at the
+// latest seq.
+const EventSnapshot = "snapshot"
+
 type Event struct {
 	Seq int64 `json:"seq"`
 
-	JobID string `json:"job_id"`
-	Type  string `json:"type"`
-	State State  `json:"state"`
+	JobID      string `json:"job_id"`
+	Board      string `json:"board,omitempty"`
+	DesignName string `json:"design_name,omitempty"`
+	Type       string `json:"type"`
+	State      State  `json:"state"`
 
 	Step    string `json:"step,omitempty"`
 	Message string `json:"message,omitempty"`
 	Error   string `json:"error,omitempty"`
+	// Progress is the step's completion in percent, when the tool reports it.
+	Progress *float64 `json:"progress,omitempty"`
+
+	FailureKind    string `json:"failure_kind,omitempty"`
+	FailureSummary string `json:"failure_summary,omitempty"`
+
+	// Dropped is the number of events skipped, set on dropped_events notices.
+	Dropped int64 `json:"dropped,omitempty"`
 
 	HeartbeatAt *time.Time `json:"heartbeat_at,omitempty"`
 	ExitCode    *int       `json:"exit_code,omitempty"`
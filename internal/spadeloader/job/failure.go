@@ -0,0 +1,30 @@
+package job
+
+// Failure kinds stored in Record.FailureKind, mirroring spadeforge's
+// build failure kinds.
+const (
+	FailureDeviceNotFound   = "device_not_found"
+	FailurePermissionDenied = "permission_denied"
+	FailureWrongPart        = "wrong_part"
+	FailureVerifyFailed     = "verify_failed"
+	FailureCableError       = "cable_error"
+	FailureTimeout          = "timeout"
+	FailureTestFailed       = "test_failed"
+	FailureInternal         = "internal"
+)
+
+var failureGuidance = map[string]string{
+	FailureDeviceNotFound:   "no board answered; check it is powered and plugged in, and that --board names the right cable/board",
+	FailurePermissionDenied: "the server cannot open the USB device; install openFPGALoader's udev rules and replug (see `spadeloader doctor`)",
+	FailureWrongPart:        "the FPGA on the cable is not the one the bitstream or --board expects; check the board name and the build's part",
+	FailureVerifyFailed:     "the device did not configure from the bitstream; rebuild it for this part and check power and the flash chip",
+	FailureCableError:       "USB/JTAG communication broke mid-flash; try another cable or port, avoid hubs, and close other JTAG tools",
+	FailureTimeout:          "openFPGALoader did not finish in time; raise SPADELOADER_WORKER_TIMEOUT or check for a hung probe",
+	FailureTestFailed:       "the board programmed but its hardware test did not pass; see hil.log in the job's artifacts",
+}
+
+// FailureGuidance returns a one-line next step for a failure kind, or "" for
+// unknown and internal failures.
+func FailureGuidance(kind string) string {
+	return failureGuidance[kind]
+}
@@ -22,6 +22,12 @@ type Record struct {
 	Message     string `json:"message,omitempty"`
 	Error       string `json:"error,omitempty"`
 	CurrentStep string `json:"current_step,omitempty"`
+	// Progress is CurrentStep's completion in percent, when openFPGALoader
+	// reports it.
+	Progress *float64 `json:"progress,omitempty"`
+
+	FailureKind    string `json:"failure_kind,omitempty"`
+	FailureSummary string `json:"failure_summary,omitempty"`
 
 	HeartbeatAt *time.Time `json:"heartbeat_at,omitempty"`
 
@@ -37,6 +43,37 @@ type Record struct {
 	BitstreamName      string `json:"bitstream_name"`
 	BitstreamSHA256    string `json:"bitstream_sha256"`
 	BitstreamSizeBytes int64  `json:"bitstream_size_bytes"`
+	// BitstreamFormat and BitstreamPart come from the upload check; the
+	// part is only known for Xilinx .bit files.
+	BitstreamFormat string `json:"bitstream_format,omitempty"`
+	BitstreamPart   string `json:"bitstream_part,omitempty"`
+	// DryRun jobs detect the board but never program it.
+	DryRun bool `json:"dry_run,omitempty"`
+	// Mode is sram or flash; FlashOffset is where in the SPI flash the
+	// bitstream goes.
+	Mode        Mode   `json:"mode,omitempty"`
+	FlashOffset uint32 `json:"flash_offset,omitempty"`
+	// Target picks the programmer on hosts with several boards attached.
+	Target
+	// TokenName is the named API token that asked for the flash, for
+	// auditing; empty for the shared token.
+	TokenName string `json:"token_name,omitempty"`
+	// BatchID groups the jobs flashing one upload onto several boards.
+	BatchID string `json:"batch_id,omitempty"`
+
+	// Test is the verdict of the board's post-flash hardware test, when it
+	// has one; a failed test fails the job.
+	Test *TestResult `json:"test,omitempty"`
+	// Monitor is the post-flash serial monitor, when one was asked for.
+	Monitor *Monitor `json:"monitor,omitempty"`
+}
+
+// TestResult records a hardware-in-the-loop test run. Log names its
+// transcript among the job's artifacts.
+type TestResult struct {
+	Passed  bool   `json:"passed"`
+	Summary string `json:"summary,omitempty"`
+	Log     string `json:"log"`
 }
 
 type NewRecordInput struct {
@@ -45,6 +82,13 @@ type NewRecordInput struct {
 	BitstreamName      string
 	BitstreamSHA256    string
 	BitstreamSizeBytes int64
+	BitstreamFormat    string
+	BitstreamPart      string
+	DryRun             bool
+	Mode               Mode
+	FlashOffset        uint32
+	Target             Target
+	Monitor            *Monitor
 }
 
 func New(id string, input NewRecordInput, now time.Time) *Record {
@@ -59,6 +103,13 @@ func New(id string, input NewRecordInput, now time.Time) *Record {
 		BitstreamName:      input.BitstreamName,
 		BitstreamSHA256:    input.BitstreamSHA256,
 		BitstreamSizeBytes: input.BitstreamSizeBytes,
+		BitstreamFormat:    input.BitstreamFormat,
+		BitstreamPart:      input.BitstreamPart,
+		DryRun:             input.DryRun,
+		Mode:               input.Mode,
+		FlashOffset:        input.FlashOffset,
+		Target:             input.Target,
+		Monitor:            input.Monitor,
 	}
 }
 
@@ -70,12 +121,16 @@ func (r *Record) Transition(next State, now time.Time, message string) error {
 	r.State = next
 	r.UpdatedAt = n
 	r.Message = message
+	r.Progress = nil
 	if next == StateRunning {
 		r.StartedAt = &n
 		r.FinishedAt = nil
 		r.ExitCode = nil
 		r.Error = ""
+		r.FailureKind = ""
+		r.FailureSummary = ""
 		r.HeartbeatAt = &n
+		r.Test = nil
 	}
 	if next == StateSucceeded || next == StateFailed {
 		r.FinishedAt = &n
@@ -0,0 +1,9 @@
+package job
+
+// KioskStatus is returned by GET /v1/kiosk: whether the server is locked
+// down and the newest successfully flashed record of each golden design,
+// sorted by board and design name.
+type KioskStatus struct {
+	Enabled bool     `json:"enabled"`
+	Designs []Record `json:"designs"`
+}
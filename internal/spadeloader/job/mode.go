@@ -0,0 +1,59 @@
+package job
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Mode is where a flash job writes the bitstream.
+type Mode string
+
+const (
+	// ModeSRAM loads the FPGA directly; the design is gone at power-off.
+	ModeSRAM Mode = "sram"
+	// ModeFlash writes the board's SPI configuration flash, so the design
+	// loads again at every power-up.
+	ModeFlash Mode = "flash"
+)
+
+// ParseMode reads a mode flag or form field; empty means ModeSRAM.
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(strings.ToLower(strings.TrimSpace(s))); m {
+	case "":
+		return ModeSRAM, nil
+	case ModeSRAM, ModeFlash:
+		return m, nil
+	default:
+		return "", fmt.Errorf("mode %q must be sram or flash", s)
+	}
+}
+
+// ParseFlashOffset reads a flash offset in decimal or 0x-prefixed hex;
+// empty means 0.
+func ParseFlashOffset(s string) (uint32, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(s, 0, 32)
+	if err != nil {
+		return 0, fmt.Errorf("flash offset %q must be a 32-bit number, e.g. 0x100000", s)
+	}
+	return uint32(n), nil
+}
+
+// ValidateFlashOffset rejects an offset outside flash mode, where
+// openFPGALoader would ignore it.
+func ValidateFlashOffset(mode Mode, offset uint32) error {
+	if offset != 0 && mode != ModeFlash {
+		return fmt.Errorf("flash offset requires mode %s", ModeFlash)
+	}
+	return nil
+}
+
+// Persistent reports whether the design survives a power cycle. Records
+// from before modes existed have no mode and were loaded to SRAM.
+func (m Mode) Persistent() bool {
+	return m == ModeFlash
+}
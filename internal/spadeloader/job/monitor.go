@@ -0,0 +1,25 @@
+package job
+
+// SerialLogName is the serial monitor's capture among a job's artifacts.
+const SerialLogName = "serial.log"
+
+// Monitor is a job's post-flash serial monitor: the board's UART is
+// captured to SerialLogName for a while after a successful flash.
+type Monitor struct {
+	Port string `json:"port"`
+	Baud int    `json:"baud"`
+	// Active is set while the port is being captured.
+	Active bool  `json:"active,omitempty"`
+	Bytes  int64 `json:"bytes,omitempty"`
+	// Error says why capture ended early or never started.
+	Error string `json:"error,omitempty"`
+	Log   string `json:"log"`
+}
+
+// SerialChunk is one message of the GET /v1/jobs/{id}/serial?follow=1
+// stream: captured text, or the end of capture with Done set.
+type SerialChunk struct {
+	Text  string `json:"text,omitempty"`
+	Done  bool   `json:"done,omitempty"`
+	Error string `json:"error,omitempty"`
+}
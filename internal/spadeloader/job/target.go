@@ -0,0 +1,97 @@
+package job
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Target picks one programmer when several boards are attached to the
+// host. Empty fields leave the choice to openFPGALoader, which takes the
+// first cable matching the board.
+type Target struct {
+	// Device is "serial:<FTDI serial>" or "busdev:<bus>:<device>".
+	Device string `json:"device,omitempty"`
+	// Cable overrides the board's default cable, e.g. "ft2232".
+	Cable string `json:"cable,omitempty"`
+	// FTDIIndex selects among identical cables by probe order.
+	FTDIIndex *int `json:"ftdi_index,omitempty"`
+}
+
+var (
+	cablePattern  = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,63}$`)
+	serialPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+)
+
+// NewTarget builds a validated Target from command-line flags, where a
+// negative ftdiIndex means none was given.
+func NewTarget(device, cable string, ftdiIndex int) (Target, error) {
+	t := Target{Device: device, Cable: cable}
+	if ftdiIndex >= 0 {
+		t.FTDIIndex = &ftdiIndex
+	}
+	t.Normalize()
+	return t, t.Validate()
+}
+
+// IsZero reports whether t leaves the choice to openFPGALoader.
+func (t Target) IsZero() bool {
+	return t.Device == "" && t.Cable == "" && t.FTDIIndex == nil
+}
+
+// Normalize trims whitespace and lower-cases the device kind.
+func (t *Target) Normalize() {
+	t.Device = strings.TrimSpace(t.Device)
+	if kind, value, ok := strings.Cut(t.Device, ":"); ok {
+		t.Device = strings.ToLower(strings.TrimSpace(kind)) + ":" + strings.TrimSpace(value)
+	}
+	t.Cable = strings.TrimSpace(t.Cable)
+}
+
+// Validate checks that t can be passed to openFPGALoader unquoted.
+func (t Target) Validate() error {
+	if t.Device != "" {
+		kind, value, _ := strings.Cut(t.Device, ":")
+		switch kind {
+		case "serial":
+			if !serialPattern.MatchString(value) {
+				return fmt.Errorf("device serial %q must be letters, digits, '.', '_' or '-'", value)
+			}
+		case "busdev":
+			bus, dev, ok := strings.Cut(value, ":")
+			if !ok || !isUint(bus) || !isUint(dev) {
+				return fmt.Errorf("device %q must be busdev:<bus>:<device>", t.Device)
+			}
+		default:
+			return fmt.Errorf("device %q must be serial:<serial> or busdev:<bus>:<device>", t.Device)
+		}
+	}
+	if t.Cable != "" && !cablePattern.MatchString(t.Cable) {
+		return fmt.Errorf("cable %q must be letters, digits, '_' or '-'", t.Cable)
+	}
+	if t.FTDIIndex != nil && *t.FTDIIndex < 0 {
+		return fmt.Errorf("ftdi index must not be negative")
+	}
+	return nil
+}
+
+// String describes t for logs, e.g. "serial:210319B0 cable=ft2232".
+func (t Target) String() string {
+	var parts []string
+	if t.Device != "" {
+		parts = append(parts, t.Device)
+	}
+	if t.Cable != "" {
+		parts = append(parts, "cable="+t.Cable)
+	}
+	if t.FTDIIndex != nil {
+		parts = append(parts, "ftdi_index="+strconv.Itoa(*t.FTDIIndex))
+	}
+	return strings.Join(parts, " ")
+}
+
+func isUint(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 16)
+	return err == nil
+}
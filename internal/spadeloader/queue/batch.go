@@ -0,0 +1,67 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/mblsha/spadeforge/internal/spadeloader/job"
+)
+
+// SubmitBatch flashes req's bitstream onto each of boards, one job per
+// board sharing a new batch ID; req.Board is ignored. Every board is checked
+// before any job is queued, but if storing a later job fails the earlier
+// ones stay queued and the error says which board was reached.
+func (m *Manager) SubmitBatch(ctx context.Context, req SubmitRequest, boards []string) (job.Batch, error) {
+	if req.Bitstream == nil {
+		return job.Batch{}, fmt.Errorf("bitstream reader is required")
+	}
+	if len(boards) == 0 {
+		return job.Batch{}, fmt.Errorf("a batch needs at least one board")
+	}
+	for _, board := range boards {
+		check := req
+		check.Board = board
+		if _, err := m.newMonitor(check); err != nil {
+			return job.Batch{}, err
+		}
+	}
+	data, err := io.ReadAll(req.Bitstream)
+	if err != nil {
+		return job.Batch{}, fmt.Errorf("read bitstream: %w", err)
+	}
+	batchID, err := newJobID()
+	if err != nil {
+		return job.Batch{}, fmt.Errorf("generate batch id: %w", err)
+	}
+
+	req.BatchID = batchID
+	for _, board := range boards {
+		req.Board = board
+		req.Bitstream = bytes.NewReader(data)
+		if _, err := m.Submit(ctx, req); err != nil {
+			return job.Batch{}, fmt.Errorf("batch %s: flash %s: %w", batchID, board, err)
+		}
+	}
+	b, _ := m.Batch(batchID)
+	return b, nil
+}
+
+// Batch returns the jobs of a batch that are still in the job table.
+func (m *Manager) Batch(id string) (job.Batch, bool) {
+	m.mu.RLock()
+	var recs []job.Record
+	for _, rec := range m.jobs {
+		if id != "" && rec.BatchID == id {
+			recs = append(recs, *rec)
+		}
+	}
+	m.mu.RUnlock()
+	if len(recs) == 0 {
+		return job.Batch{}, false
+	}
+	sort.Slice(recs, func(i, j int) bool { return recs[i].Board < recs[j].Board })
+	return job.NewBatch(id, recs), true
+}
@@ -0,0 +1,102 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/spadeloader/flasher"
+	"github.com/mblsha/spadeforge/internal/spadeloader/job"
+)
+
+// ErrNoScanner is returned by ListDevices when the flasher can't list USB
+// devices.
+var ErrNoScanner = errors.New("flasher cannot scan for devices")
+
+// deviceScanTTL keeps clients polling /v1/devices from running
+// openFPGALoader over and over.
+const deviceScanTTL = 2 * time.Second
+
+// deviceInventory remembers every programmer seen since startup.
+type deviceInventory struct {
+	mu       sync.Mutex
+	devices  map[string]job.Device
+	scanned  bool
+	lastScan time.Time
+}
+
+// ListDevices scans for attached programmers and returns them along with
+// those seen earlier but since unplugged, connected ones first. While a job
+// is flashing the last scan is returned instead, so the scan can't get in
+// the programmer's way.
+func (m *Manager) ListDevices(ctx context.Context) ([]job.Device, error) {
+	scanner, ok := m.flasher.(flasher.Scanner)
+	if !ok {
+		return nil, ErrNoScanner
+	}
+	inv := &m.inventory
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	now := time.Now().UTC()
+	if !inv.scanned || (now.Sub(inv.lastScan) >= deviceScanTTL && !m.flashing()) {
+		found, err := scanner.Scan(ctx)
+		if err != nil {
+			return nil, err
+		}
+		inv.merge(found, now, m.cfg.DeviceBoards)
+	}
+
+	out := make([]job.Device, 0, len(inv.devices))
+	for _, d := range inv.devices {
+		out = append(out, d)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Connected != out[j].Connected {
+			return out[i].Connected
+		}
+		if out[i].Board != out[j].Board {
+			return out[i].Board < out[j].Board
+		}
+		return out[i].Key() < out[j].Key()
+	})
+	return out, nil
+}
+
+func (inv *deviceInventory) merge(found []job.Device, now time.Time, boards map[string]string) {
+	if inv.devices == nil {
+		inv.devices = map[string]job.Device{}
+	}
+	for key, d := range inv.devices {
+		d.Connected = false
+		inv.devices[key] = d
+	}
+	for _, d := range found {
+		key := d.Key()
+		d.FirstSeen = now
+		if prev, ok := inv.devices[key]; ok {
+			d.FirstSeen = prev.FirstSeen
+		}
+		d.LastSeen = now
+		d.Connected = true
+		if d.Serial != "" {
+			d.Board = boards[d.Serial]
+		}
+		inv.devices[key] = d
+	}
+	inv.scanned = true
+	inv.lastScan = now
+}
+
+func (m *Manager) flashing() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, rec := range m.jobs {
+		if rec.State == job.StateRunning {
+			return true
+		}
+	}
+	return false
+}
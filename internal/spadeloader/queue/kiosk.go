@@ -0,0 +1,33 @@
+package queue
+
+import (
+	"sort"
+
+	"github.com/mblsha/spadeforge/internal/spadeloader/job"
+)
+
+// GoldenDesigns returns the newest succeeded job for each board and design
+// accepted by isGolden, sorted by board and design name.
+func (m *Manager) GoldenDesigns(isGolden func(board, design string) bool) []job.Record {
+	latest := map[string]job.Record{}
+	for _, rec := range m.ListJobs(0) {
+		if rec.State != job.StateSucceeded || !isGolden(rec.Board, rec.DesignName) {
+			continue
+		}
+		key := rec.Board + "\x00" + rec.DesignName
+		if _, ok := latest[key]; !ok {
+			latest[key] = rec
+		}
+	}
+	out := make([]job.Record, 0, len(latest))
+	for _, rec := range latest {
+		out = append(out, rec)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Board != out[j].Board {
+			return out[i].Board < out[j].Board
+		}
+		return out[i].DesignName < out[j].DesignName
+	})
+	return out
+}
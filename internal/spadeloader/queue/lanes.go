@@ -0,0 +1,74 @@
+package queue
+
+import (
+	"context"
+	"strings"
+
+	"github.com/mblsha/spadeforge/internal/spadeloader/job"
+)
+
+// pendingFlash is a queued job waiting for its lane to free up.
+type pendingFlash struct {
+	id   string
+	lane string
+}
+
+// laneKey names the programmer a job flashes through. Jobs targeting a
+// device share that device's lane; untargeted jobs share their board's,
+// since openFPGALoader takes the first cable matching the board.
+func laneKey(rec *job.Record) string {
+	if rec.Device != "" {
+		return rec.Device
+	}
+	return "board:" + strings.ToLower(rec.Board)
+}
+
+func (m *Manager) laneOf(jobID string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rec, ok := m.jobs[jobID]
+	if !ok {
+		return ""
+	}
+	return laneKey(rec)
+}
+
+// worker runs queued jobs in submission order, one at a time per lane and
+// up to MaxConcurrentFlashes across lanes, so flashes to different boards
+// overlap while flashes to the same board stay serialized.
+func (m *Manager) worker(ctx context.Context) {
+	limit := m.cfg.MaxConcurrentFlashes
+	if limit < 1 {
+		limit = 1
+	}
+	var pending []pendingFlash
+	busy := map[string]bool{}
+	done := make(chan string)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-m.queue:
+			pending = append(pending, pendingFlash{id: id, lane: m.laneOf(id)})
+		case lane := <-done:
+			delete(busy, lane)
+		}
+
+		for i := 0; i < len(pending) && len(busy) < limit; {
+			next := pending[i]
+			if busy[next.lane] {
+				i++
+				continue
+			}
+			pending = append(pending[:i], pending[i+1:]...)
+			busy[next.lane] = true
+			go func() {
+				m.process(ctx, next.id)
+				select {
+				case done <- next.lane:
+				case <-ctx.Done():
+				}
+			}()
+		}
+	}
+}
@@ -10,11 +10,14 @@ import (
 	"log"
 	"os"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/mblsha/spadeforge/internal/archive"
 	"github.com/mblsha/spadeforge/internal/spadeloader/config"
 	"github.com/mblsha/spadeforge/internal/spadeloader/flasher"
+	"github.com/mblsha/spadeforge/internal/spadeloader/hil"
 	"github.com/mblsha/spadeforge/internal/spadeloader/history"
 	"github.com/mblsha/spadeforge/internal/spadeloader/job"
 	"github.com/mblsha/spadeforge/internal/spadeloader/store"
@@ -25,6 +28,25 @@ type SubmitRequest struct {
 	DesignName    string
 	BitstreamName string
 	Bitstream     io.Reader
+	// Format and Part describe the checked bitstream, when known.
+	Format string
+	Part   string
+	// DryRun runs every step except programming the board.
+	DryRun bool
+	// Mode picks SRAM or SPI flash; empty is SRAM. FlashOffset only
+	// applies to flash.
+	Mode        job.Mode
+	FlashOffset uint32
+	// Target picks the programmer when several boards are attached.
+	Target job.Target
+	// Monitor captures the board's UART after a successful flash, at
+	// MonitorBaud when set or else the port's configured rate.
+	Monitor     bool
+	MonitorBaud int
+	// TokenName is the named API token that submitted the flash.
+	TokenName string
+	// BatchID is set by SubmitBatch.
+	BatchID string
 }
 
 var (
@@ -44,27 +66,54 @@ type Manager struct {
 
 	events          map[string][]job.Event
 	nextEventSeq    map[string]int64
-	subscribers     map[string]map[chan job.Event]struct{}
+	subscribers     map[string]map[chan job.Event]*eventSubscriber
 	maxEventsPerJob int
 	subscriberBuf   int
+	droppedEvents   int64
+
+	// stateObservers are called with every state transition; see
+	// ObserveStates.
+	stateObservers []func(job.Record)
+
+	// metrics backs the /metrics endpoint.
+	metrics *managerMetrics
+
+	// globalEvents and globalSubscribers back SubscribeAllEvents, numbered
+	// by globalSeq across all jobs.
+	globalEvents      []job.Event
+	globalSeq         int64
+	globalSubscribers map[chan job.Event]*eventSubscriber
+
+	// inventory backs ListDevices.
+	inventory deviceInventory
+
+	// monitors are the serial monitors waiting for their flash or
+	// capturing, by job ID; openSerial opens their ports.
+	monitors   map[string]*serialMonitor
+	openSerial func(port string, baud int) (io.ReadCloser, error)
 
 	once sync.Once
 }
 
 func New(cfg config.Config, st *store.Store, f flasher.Flasher, h *history.Store) *Manager {
-	return &Manager{
-		cfg:             cfg,
-		store:           st,
-		flasher:         f,
-		history:         h,
-		jobs:            map[string]*job.Record{},
-		queue:           make(chan string, 4096),
-		events:          map[string][]job.Event{},
-		nextEventSeq:    map[string]int64{},
-		subscribers:     map[string]map[chan job.Event]struct{}{},
-		maxEventsPerJob: 512,
-		subscriberBuf:   128,
-	}
+	m := &Manager{
+		cfg:               cfg,
+		store:             st,
+		flasher:           f,
+		history:           h,
+		jobs:              map[string]*job.Record{},
+		queue:             make(chan string, 4096),
+		events:            map[string][]job.Event{},
+		nextEventSeq:      map[string]int64{},
+		subscribers:       map[string]map[chan job.Event]*eventSubscriber{},
+		globalSubscribers: map[chan job.Event]*eventSubscriber{},
+		maxEventsPerJob:   512,
+		subscriberBuf:     128,
+		monitors:          map[string]*serialMonitor{},
+		openSerial:        openSerialPort,
+	}
+	m.metrics = newManagerMetrics(m)
+	return m
 }
 
 func (m *Manager) Start(ctx context.Context) error {
@@ -88,6 +137,21 @@ func (m *Manager) Submit(_ context.Context, req SubmitRequest) (*job.Record, err
 	if req.Bitstream == nil {
 		return nil, fmt.Errorf("bitstream reader is required")
 	}
+	req.Target.Normalize()
+	if err := req.Target.Validate(); err != nil {
+		return nil, err
+	}
+	mode, err := job.ParseMode(string(req.Mode))
+	if err != nil {
+		return nil, err
+	}
+	if err := job.ValidateFlashOffset(mode, req.FlashOffset); err != nil {
+		return nil, err
+	}
+	monitor, err := m.newMonitor(req)
+	if err != nil {
+		return nil, err
+	}
 	id, err := newJobID()
 	if err != nil {
 		return nil, fmt.Errorf("generate job id: %w", err)
@@ -100,6 +164,7 @@ func (m *Manager) Submit(_ context.Context, req SubmitRequest) (*job.Record, err
 	if err != nil {
 		return nil, err
 	}
+	m.metrics.uploadBytes.Add(float64(size))
 
 	rec := job.New(id, job.NewRecordInput{
 		Board:              req.Board,
@@ -107,16 +172,29 @@ func (m *Manager) Submit(_ context.Context, req SubmitRequest) (*job.Record, err
 		BitstreamName:      req.BitstreamName,
 		BitstreamSHA256:    sha,
 		BitstreamSizeBytes: size,
+		BitstreamFormat:    req.Format,
+		BitstreamPart:      req.Part,
+		DryRun:             req.DryRun,
+		Mode:               mode,
+		FlashOffset:        req.FlashOffset,
+		Target:             req.Target,
+		Monitor:            monitor,
 	}, time.Now())
+	rec.TokenName = req.TokenName
+	rec.BatchID = req.BatchID
 	if err := m.store.Save(rec); err != nil {
 		return nil, err
 	}
 
 	m.mu.Lock()
 	m.jobs[id] = rec
+	if monitor != nil {
+		m.monitors[id] = &serialMonitor{board: rec.Board}
+	}
 	m.emitEventLocked(rec, "queued")
 	copyRec := *rec
 	m.mu.Unlock()
+	m.metrics.submitted.Inc()
 
 	m.enqueue(id)
 	return &copyRec, nil
@@ -158,18 +236,56 @@ func (m *Manager) ReadConsoleLog(jobID string) ([]byte, error) {
 	return os.ReadFile(path)
 }
 
+// DownloadArtifacts writes a zip of the finished job's artifacts (console
+// log, invocation record and tool outputs) to w.
+func (m *Manager) DownloadArtifacts(jobID string, w io.Writer) error {
+	rec, ok := m.Get(jobID)
+	if !ok {
+		return os.ErrNotExist
+	}
+	if !rec.Terminal() {
+		return fmt.Errorf("job is not complete")
+	}
+	artifactsDir := m.store.ArtifactsJobDir(jobID)
+	if _, err := os.Stat(artifactsDir); err != nil {
+		return err
+	}
+	return archive.WriteZipFromDir(artifactsDir, w)
+}
+
+// OpenBitstream opens the bitstream the job was submitted with. It is an
+// *os.File unless the bitstream is encrypted at rest.
+func (m *Manager) OpenBitstream(jobID string) (io.ReadCloser, error) {
+	return m.store.OpenBitstream(jobID)
+}
+
 func (m *Manager) ListRecentDesigns(limit int) ([]history.Item, error) {
 	return m.history.List(limit)
 }
 
+// Retarget points a reflash at another board or programmer, e.g. after the
+// design moved to a second board of the same kind.
+type Retarget struct {
+	// Board replaces the source job's board when set.
+	Board string
+	// Target replaces the source job's programmer selection.
+	Target job.Target
+}
+
 func (m *Manager) Reflash(ctx context.Context, sourceJobID string) (*job.Record, error) {
+	return m.ReflashTo(ctx, sourceJobID, nil, "")
+}
+
+// ReflashTo reflashes the source job's bitstream like Reflash, but onto the
+// board and programmer in to; a nil to keeps the source job's. tokenName
+// is the named API token that asked for it.
+func (m *Manager) ReflashTo(ctx context.Context, sourceJobID string, to *Retarget, tokenName string) (*job.Record, error) {
 	sourceRec, ok := m.Get(sourceJobID)
 	if !ok {
 		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, sourceJobID)
 	}
 
-	srcPath := m.store.RequestBitstreamPath(sourceJobID)
-	file, err := os.Open(srcPath)
+	file, err := m.store.OpenBitstream(sourceJobID)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, fmt.Errorf("%w: %s", ErrBitstreamUnavailable, sourceJobID)
@@ -178,11 +294,33 @@ func (m *Manager) Reflash(ctx context.Context, sourceJobID string) (*job.Record,
 	}
 	defer file.Close()
 
+	board, target := sourceRec.Board, sourceRec.Target
+	if to != nil {
+		if to.Board != "" {
+			board = to.Board
+		}
+		target = to.Target
+	}
+	// The monitor is kept unless the design moved to another board, whose
+	// UART may not be configured.
+	var monitorBaud int
+	monitor := sourceRec.Monitor != nil && strings.EqualFold(board, sourceRec.Board)
+	if monitor {
+		monitorBaud = sourceRec.Monitor.Baud
+	}
 	return m.Submit(ctx, SubmitRequest{
-		Board:         sourceRec.Board,
+		Board:         board,
 		DesignName:    sourceRec.DesignName,
 		BitstreamName: sourceRec.BitstreamName,
 		Bitstream:     file,
+		Format:        sourceRec.BitstreamFormat,
+		Part:          sourceRec.BitstreamPart,
+		Mode:          sourceRec.Mode,
+		FlashOffset:   sourceRec.FlashOffset,
+		Target:        target,
+		Monitor:       monitor,
+		MonitorBaud:   monitorBaud,
+		TokenName:     tokenName,
 	})
 }
 
@@ -193,6 +331,14 @@ func (m *Manager) recoverJobs() error {
 	}
 	for _, rec := range recs {
 		m.jobs[rec.ID] = rec
+		if !rec.Terminal() && rec.Monitor != nil {
+			m.monitors[rec.ID] = &serialMonitor{board: rec.Board}
+		}
+		if monitorInterrupted(rec, time.Now().UTC()) {
+			if err := m.store.Save(rec); err != nil {
+				return err
+			}
+		}
 		switch rec.State {
 		case job.StateQueued:
 			m.enqueue(rec.ID)
@@ -202,6 +348,8 @@ func (m *Manager) recoverJobs() error {
 			rec.UpdatedAt = now
 			rec.Message = "requeued after restart"
 			rec.Error = ""
+			rec.FailureKind = ""
+			rec.FailureSummary = ""
 			rec.CurrentStep = ""
 			rec.StartedAt = nil
 			rec.FinishedAt = nil
@@ -216,17 +364,6 @@ func (m *Manager) recoverJobs() error {
 	return nil
 }
 
-func (m *Manager) worker(ctx context.Context) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case id := <-m.queue:
-			m.process(ctx, id)
-		}
-	}
-}
-
 func (m *Manager) process(parentCtx context.Context, id string) {
 	m.mu.Lock()
 	rec, ok := m.jobs[id]
@@ -243,22 +380,57 @@ func (m *Manager) process(parentCtx context.Context, id string) {
 		return
 	}
 	rec.CurrentStep = "flash"
+	m.stopMonitorsLocked(rec.Board)
 	board := rec.Board
 	designName := rec.DesignName
+	dryRun := rec.DryRun
+	mode := rec.Mode
+	flashOffset := rec.FlashOffset
+	target := rec.Target
 	_ = m.store.Save(rec)
 	m.emitEventLocked(rec, "running")
 	m.mu.Unlock()
-	log.Printf("[spadeloader job %s] started board=%q design=%q", id, board, designName)
+	log.Printf("[spadeloader job %s] started board=%q design=%q mode=%s offset=0x%x dry_run=%t target=%q", id, board, designName, mode, flashOffset, dryRun, target)
+
+	var result flasher.Result
+	bitstreamPath, flashErr := m.store.PlainBitstreamPath(id)
+	if flashErr == nil {
+		ctx, cancel := context.WithTimeout(parentCtx, m.cfg.WorkerTimeout)
+		flashStart := time.Now()
+		result, flashErr = m.flasher.Flash(ctx, flasher.FlashJob{
+			ID:            id,
+			Board:         board,
+			BitstreamPath: bitstreamPath,
+			WorkDir:       m.store.WorkJobDir(id),
+			ArtifactsDir:  m.store.ArtifactsJobDir(id),
+			DryRun:        dryRun,
+			Mode:          mode,
+			FlashOffset:   flashOffset,
+			Target:        target,
+			Progress:      m.progressUpdater(id),
+		})
+		cancel()
+		m.metrics.flashDuration.Observe(time.Since(flashStart).Seconds())
+	}
 
-	ctx, cancel := context.WithTimeout(parentCtx, m.cfg.WorkerTimeout)
-	result, flashErr := m.flasher.Flash(ctx, flasher.FlashJob{
-		ID:            id,
-		Board:         board,
-		BitstreamPath: m.store.RequestBitstreamPath(id),
-		ArtifactsDir:  m.store.ArtifactsJobDir(id),
-		Progress:      m.progressUpdater(id),
-	})
-	cancel()
+	var failureKind, failureSummary string
+	var testResult *job.TestResult
+	if flashErr != nil {
+		consoleRaw, _ := m.ReadConsoleLog(id)
+		failureKind, failureSummary = flasher.ClassifyFailure(consoleRaw, flashErr)
+	} else if test, ok := m.cfg.HILTest(board); ok && !dryRun {
+		testResult = m.runHILTest(parentCtx, id, designName, bitstreamPath, test)
+		if !testResult.Passed {
+			flashErr = errors.New(testResult.Summary)
+			result.Message = "flashed; hardware test failed"
+			failureKind, failureSummary = job.FailureTestFailed, testResult.Summary
+		} else {
+			result.Message = "flashed; hardware test passed"
+		}
+	}
+	if bitstreamPath != "" && bitstreamPath != m.store.RequestBitstreamPath(id) {
+		_ = os.Remove(bitstreamPath)
+	}
 
 	m.mu.Lock()
 	rec, ok = m.jobs[id]
@@ -267,6 +439,7 @@ func (m *Manager) process(parentCtx context.Context, id string) {
 		return
 	}
 	now := time.Now()
+	rec.Test = testResult
 	if flashErr != nil {
 		if markErr := rec.MarkFailed(now, result.Message, flashErr, result.ExitCode); markErr != nil {
 			rec.State = job.StateFailed
@@ -276,9 +449,11 @@ func (m *Manager) process(parentCtx context.Context, id string) {
 			rec.ExitCode = &result.ExitCode
 			rec.FinishedAt = &rec.UpdatedAt
 		}
+		rec.FailureKind = failureKind
+		rec.FailureSummary = failureSummary
 		rec.CurrentStep = "failed"
 		m.emitEventLocked(rec, "failed")
-		log.Printf("[spadeloader job %s] failed message=%q error=%v", id, result.Message, flashErr)
+		log.Printf("[spadeloader job %s] failed kind=%s summary=%q message=%q error=%v", id, failureKind, failureSummary, result.Message, flashErr)
 	} else {
 		if markErr := rec.MarkSucceeded(now, result.Message, result.ExitCode); markErr != nil {
 			rec.State = job.StateSucceeded
@@ -293,6 +468,7 @@ func (m *Manager) process(parentCtx context.Context, id string) {
 		log.Printf("[spadeloader job %s] succeeded message=%q", id, result.Message)
 	}
 	_ = m.store.Save(rec)
+	m.metrics.finished.Inc(string(rec.State))
 
 	historyItem := history.Item{
 		JobID:              rec.ID,
@@ -302,17 +478,42 @@ func (m *Manager) process(parentCtx context.Context, id string) {
 		BitstreamSizeBytes: rec.BitstreamSizeBytes,
 		SubmittedAt:        rec.CreatedAt,
 		State:              rec.State,
+		Mode:               rec.Mode,
+		FlashOffset:        rec.FlashOffset,
 	}
 	if rec.FinishedAt != nil {
 		historyItem.FinishedAt = rec.FinishedAt.UTC()
 	}
 	jobID := rec.ID
+	var monitor *job.Monitor
+	if rec.Monitor != nil {
+		mon := *rec.Monitor
+		monitor = &mon
+	}
+	succeeded := rec.State == job.StateSucceeded
 	preserveWorkDir := m.cfg.PreserveWorkDir
 	pruneIDs := m.pruneTerminalJobsLocked()
 	m.mu.Unlock()
 
-	if err := m.history.Append(historyItem); err != nil {
-		log.Printf("[spadeloader job %s] failed to append history: %v", jobID, err)
+	// Dry runs never reached the board, so they stay out of the recent
+	// designs list.
+	if !dryRun {
+		if err := m.history.Append(historyItem); err != nil {
+			log.Printf("[spadeloader job %s] failed to append history: %v", jobID, err)
+		}
+	}
+
+	if monitor != nil {
+		if succeeded {
+			m.startMonitor(parentCtx, jobID, *monitor)
+		} else {
+			m.mu.RLock()
+			sm := m.monitors[jobID]
+			m.mu.RUnlock()
+			if sm != nil {
+				m.finishMonitor(jobID, sm, 0, errors.New("flash failed; nothing to monitor"))
+			}
+		}
 	}
 
 	if !preserveWorkDir {
@@ -323,6 +524,29 @@ func (m *Manager) process(parentCtx context.Context, id string) {
 	}
 }
 
+// runHILTest runs the board's post-flash test, reporting it as the "test"
+// step, and returns its verdict.
+func (m *Manager) runHILTest(parentCtx context.Context, id, designName, bitstreamPath string, test config.HILTest) *job.TestResult {
+	m.progressUpdater(id)(flasher.ProgressUpdate{Step: "test", Message: "running hardware test", HeartbeatAt: time.Now().UTC()})
+	log.Printf("[spadeloader job %s] hardware test started board=%q", id, test.Board)
+
+	ctx, cancel := context.WithTimeout(parentCtx, m.cfg.HILTimeout)
+	defer cancel()
+	err := hil.Run(ctx, test, hil.Job{
+		ID:            id,
+		Board:         test.Board,
+		DesignName:    designName,
+		BitstreamPath: bitstreamPath,
+		ArtifactsDir:  m.store.ArtifactsJobDir(id),
+	})
+	if err != nil {
+		log.Printf("[spadeloader job %s] hardware test failed: %v", id, err)
+		return &job.TestResult{Summary: err.Error(), Log: hil.LogFileName}
+	}
+	log.Printf("[spadeloader job %s] hardware test passed", id)
+	return &job.TestResult{Passed: true, Log: hil.LogFileName}
+}
+
 func (m *Manager) progressUpdater(jobID string) flasher.ProgressFunc {
 	return func(update flasher.ProgressUpdate) {
 		m.mu.Lock()
@@ -339,8 +563,13 @@ func (m *Manager) progressUpdater(jobID string) flasher.ProgressFunc {
 		}
 		rec.UpdatedAt = now
 		rec.HeartbeatAt = &now
-		if update.Step != "" {
+		if update.Step != "" && update.Step != rec.CurrentStep {
 			rec.CurrentStep = update.Step
+			rec.Progress = nil
+		}
+		if update.Percent != nil {
+			p := *update.Percent
+			rec.Progress = &p
 		}
 		if update.Message != "" {
 			rec.Message = update.Message
@@ -371,7 +600,7 @@ func (m *Manager) SubscribeEvents(jobID string, since int64) ([]job.Event, <-cha
 		return nil, nil, nil, false
 	}
 
-	backlog := m.eventsSinceLocked(jobID, since)
+	backlog := m.eventsSinceLocked(rec, since)
 	if rec.Terminal() {
 		return backlog, nil, func() {}, true
 	}
@@ -382,9 +611,13 @@ func (m *Manager) SubscribeEvents(jobID string, since int64) ([]job.Event, <-cha
 	}
 	ch := make(chan job.Event, buf)
 	if m.subscribers[jobID] == nil {
-		m.subscribers[jobID] = map[chan job.Event]struct{}{}
+		m.subscribers[jobID] = map[chan job.Event]*eventSubscriber{}
 	}
-	m.subscribers[jobID][ch] = struct{}{}
+	sub := &eventSubscriber{ch: ch, lastSeq: since}
+	if len(backlog) > 0 {
+		sub.lastSeq = backlog[len(backlog)-1].Seq
+	}
+	m.subscribers[jobID][ch] = sub
 	cancel := func() {
 		m.mu.Lock()
 		defer m.mu.Unlock()
@@ -403,8 +636,17 @@ func (m *Manager) SubscribeEvents(jobID string, since int64) ([]job.Event, <-cha
 	return backlog, ch, cancel, true
 }
 
-func (m *Manager) eventsSinceLocked(jobID string, since int64) []job.Event {
-	src := m.events[jobID]
+// eventsSinceLocked returns the retained events after since. When since
+// falls outside the retained window (older events were compacted away, or
+// since is ahead of the job's sequence after a restart), the backlog is a
+// single snapshot event built from the current record at the latest seq, so
+// the subscriber resumes from a consistent state instead of a partial one.
+func (m *Manager) eventsSinceLocked(rec *job.Record, since int64) []job.Event {
+	src := m.events[rec.ID]
+	latest := m.nextEventSeq[rec.ID]
+	if since > latest || (len(src) > 0 && since < src[0].Seq-1) {
+		return []job.Event{recordEvent(rec, job.EventSnapshot, latest, time.Now().UTC())}
+	}
 	if len(src) == 0 {
 		return nil
 	}
@@ -418,64 +660,70 @@ func (m *Manager) eventsSinceLocked(jobID string, since int64) []job.Event {
 }
 
 func (m *Manager) emitEventLocked(rec *job.Record, eventType string) {
-	now := time.Now().UTC()
 	seq := m.nextEventSeq[rec.ID] + 1
 	m.nextEventSeq[rec.ID] = seq
 
-	var heartbeat *time.Time
-	if rec.HeartbeatAt != nil {
-		hb := rec.HeartbeatAt.UTC()
-		heartbeat = &hb
-	}
-	var exitCode *int
-	if rec.ExitCode != nil {
-		ec := *rec.ExitCode
-		exitCode = &ec
-	}
-
-	ev := job.Event{
-		Seq:         seq,
-		JobID:       rec.ID,
-		Type:        eventType,
-		State:       rec.State,
-		Step:        rec.CurrentStep,
-		Message:     rec.Message,
-		Error:       rec.Error,
-		HeartbeatAt: heartbeat,
-		ExitCode:    exitCode,
-		At:          now,
-	}
+	ev := recordEvent(rec, eventType, seq, time.Now().UTC())
 	list := append(m.events[rec.ID], ev)
 	if len(list) > m.maxEventsPerJob {
 		list = list[len(list)-m.maxEventsPerJob:]
 	}
 	m.events[rec.ID] = list
 
-	for ch := range m.subscribers[rec.ID] {
-		publishEvent(ch, ev)
+	for _, sub := range m.subscribers[rec.ID] {
+		m.droppedEvents += sub.publish(ev)
+	}
+	m.emitGlobalLocked(ev)
+	if eventType != "progress" {
+		for _, fn := range m.stateObservers {
+			fn(*rec)
+		}
 	}
 }
 
-func publishEvent(ch chan job.Event, ev job.Event) {
-	select {
-	case ch <- ev:
-		return
-	default:
-	}
+// ObserveStates registers fn to receive a copy of a job's record whenever
+// it changes state. fn runs with the manager lock held, so it must not block
+// or call back into the manager. Register observers before Start.
+func (m *Manager) ObserveStates(fn func(job.Record)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stateObservers = append(m.stateObservers, fn)
+}
 
-	// For non-terminal updates, dropping events is acceptable when a subscriber is slow.
-	if !ev.Terminal() {
-		return
+// recordEvent snapshots rec into an event with the given type and seq.
+func recordEvent(rec *job.Record, eventType string, seq int64, now time.Time) job.Event {
+	var heartbeat *time.Time
+	if rec.HeartbeatAt != nil {
+		hb := rec.HeartbeatAt.UTC()
+		heartbeat = &hb
 	}
-
-	// Guarantee terminal delivery: evict one queued event and retry.
-	select {
-	case <-ch:
-	default:
+	var exitCode *int
+	if rec.ExitCode != nil {
+		ec := *rec.ExitCode
+		exitCode = &ec
 	}
-	select {
-	case ch <- ev:
-	default:
+	var progress *float64
+	if rec.Progress != nil {
+		p := *rec.Progress
+		progress = &p
+	}
+
+	return job.Event{
+		Seq:            seq,
+		JobID:          rec.ID,
+		Board:          rec.Board,
+		DesignName:     rec.DesignName,
+		Type:           eventType,
+		State:          rec.State,
+		Step:           rec.CurrentStep,
+		Message:        rec.Message,
+		Error:          rec.Error,
+		Progress:       progress,
+		FailureKind:    rec.FailureKind,
+		FailureSummary: rec.FailureSummary,
+		HeartbeatAt:    heartbeat,
+		ExitCode:       exitCode,
+		At:             now,
 	}
 }
 
@@ -4,12 +4,15 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/mblsha/spadeforge/internal/atrest"
 	loaderconfig "github.com/mblsha/spadeforge/internal/spadeloader/config"
 	"github.com/mblsha/spadeforge/internal/spadeloader/flasher"
 	"github.com/mblsha/spadeforge/internal/spadeloader/history"
@@ -175,6 +178,46 @@ func TestManagerEventsAndTail(t *testing.T) {
 	}
 }
 
+func TestManagerClassifiesFailure(t *testing.T) {
+	t.Parallel()
+
+	cfg := loaderconfig.Default()
+	cfg.BaseDir = t.TempDir()
+	cfg.WorkerTimeout = 2 * time.Second
+
+	st := store.New(cfg)
+	hs := history.New(cfg.HistoryPath(), cfg.HistoryLimit)
+	f := &flasher.FakeFlasher{Fail: true, Message: "JTAG init failed with: unable to open ftdi device: -3 (device not found)"}
+	mgr := New(cfg, st, f, hs)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+
+	rec, err := mgr.Submit(context.Background(), SubmitRequest{
+		Board:         "alchitry_au",
+		DesignName:    "Missing",
+		BitstreamName: "design.bit",
+		Bitstream:     bytes.NewBufferString("bitstream"),
+	})
+	if err != nil {
+		t.Fatalf("Submit() error: %v", err)
+	}
+
+	finished := waitForTerminal(t, mgr, rec.ID, 3*time.Second)
+	if finished.State != job.StateFailed {
+		t.Fatalf("State = %s, want %s", finished.State, job.StateFailed)
+	}
+	if finished.FailureKind != job.FailureDeviceNotFound {
+		t.Fatalf("FailureKind = %q, want %q", finished.FailureKind, job.FailureDeviceNotFound)
+	}
+	if finished.FailureSummary != f.Message {
+		t.Fatalf("FailureSummary = %q, want %q", finished.FailureSummary, f.Message)
+	}
+}
+
 func TestManagerListJobsSortedDesc(t *testing.T) {
 	t.Parallel()
 
@@ -491,3 +534,394 @@ func waitForTerminal(t *testing.T, mgr *Manager, jobID string, timeout time.Dura
 	t.Fatalf("timeout waiting for terminal state for job %s", jobID)
 	return nil
 }
+
+func TestManagerObserveStatesSkipsProgress(t *testing.T) {
+	t.Parallel()
+
+	cfg := loaderconfig.Default()
+	cfg.BaseDir = t.TempDir()
+	cfg.WorkerTimeout = 2 * time.Second
+
+	st := store.New(cfg)
+	hs := history.New(cfg.HistoryPath(), cfg.HistoryLimit)
+	mgr := New(cfg, st, &flasher.FakeFlasher{}, hs)
+	states := make(chan job.State, 16)
+	mgr.ObserveStates(func(rec job.Record) {
+		if rec.Board != "alchitry_au" {
+			t.Errorf("observed board %q", rec.Board)
+		}
+		states <- rec.State
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	rec, err := mgr.Submit(context.Background(), SubmitRequest{
+		Board:      "alchitry_au",
+		DesignName: "Blink",
+		Bitstream:  bytes.NewBufferString("bitstream"),
+	})
+	if err != nil {
+		t.Fatalf("Submit() error: %v", err)
+	}
+	waitForTerminal(t, mgr, rec.ID, 3*time.Second)
+
+	want := []job.State{job.StateQueued, job.StateRunning, job.StateSucceeded}
+	for _, w := range want {
+		if got := <-states; got != w {
+			t.Fatalf("observed %s, want %s", got, w)
+		}
+	}
+	select {
+	case extra := <-states:
+		t.Fatalf("unexpected extra observation %s", extra)
+	default:
+	}
+}
+
+func TestManagerRunsHILTestAfterFlash(t *testing.T) {
+	t.Parallel()
+
+	scripts := t.TempDir()
+	pass := filepath.Join(scripts, "pass.sh")
+	fail := filepath.Join(scripts, "fail.sh")
+	if err := os.WriteFile(pass, []byte("#!/bin/sh\necho \"hello from $SPADELOADER_BOARD running $SPADELOADER_DESIGN\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(fail, []byte("#!/bin/sh\necho 'uart silent'\nexit 3\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := loaderconfig.Default()
+	cfg.BaseDir = t.TempDir()
+	cfg.WorkerTimeout = 2 * time.Second
+	cfg.HILTimeout = 5 * time.Second
+	cfg.HILTests = []loaderconfig.HILTest{{Board: "arty", Script: pass}, {Board: "basys3", Script: fail}}
+
+	st := store.New(cfg)
+	mgr := New(cfg, st, &flasher.FakeFlasher{}, history.New(cfg.HistoryPath(), cfg.HistoryLimit))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+
+	submit := func(board string) *job.Record {
+		rec, err := mgr.Submit(context.Background(), SubmitRequest{
+			Board:         board,
+			DesignName:    "Hello",
+			BitstreamName: "design.bit",
+			Bitstream:     bytes.NewBufferString("bitstream"),
+		})
+		if err != nil {
+			t.Fatalf("Submit() error: %v", err)
+		}
+		return waitForTerminal(t, mgr, rec.ID, 5*time.Second)
+	}
+
+	passed := submit("arty")
+	if passed.State != job.StateSucceeded || passed.Test == nil || !passed.Test.Passed {
+		t.Fatalf("passing test: state=%s test=%+v", passed.State, passed.Test)
+	}
+	logRaw, err := os.ReadFile(filepath.Join(st.ArtifactsJobDir(passed.ID), passed.Test.Log))
+	if err != nil {
+		t.Fatalf("read test log: %v", err)
+	}
+	if !strings.Contains(string(logRaw), "hello from arty running Hello") {
+		t.Fatalf("test log = %q", logRaw)
+	}
+
+	failed := submit("basys3")
+	if failed.State != job.StateFailed || failed.FailureKind != job.FailureTestFailed {
+		t.Fatalf("failing test: state=%s kind=%q", failed.State, failed.FailureKind)
+	}
+	if failed.Test == nil || failed.Test.Passed || failed.Test.Summary != "fail.sh exited with status 3" {
+		t.Fatalf("failing test result = %+v", failed.Test)
+	}
+
+	untested := submit("alchitry_au")
+	if untested.State != job.StateSucceeded || untested.Test != nil {
+		t.Fatalf("board without a test: state=%s test=%+v", untested.State, untested.Test)
+	}
+}
+
+func TestManagerEncryptsStoredBitstream(t *testing.T) {
+	t.Parallel()
+
+	cfg := loaderconfig.Default()
+	cfg.BaseDir = t.TempDir()
+	cfg.WorkerTimeout = 2 * time.Second
+
+	st := store.New(cfg)
+	key, err := atrest.NewKey(bytes.Repeat([]byte{3}, 32))
+	if err != nil {
+		t.Fatalf("NewKey() error: %v", err)
+	}
+	st.SetKey(key)
+	hs := history.New(cfg.HistoryPath(), cfg.HistoryLimit)
+	mgr := New(cfg, st, &flasher.FakeFlasher{}, hs)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+
+	rec, err := mgr.Submit(context.Background(), SubmitRequest{
+		Board:         "alchitry_au",
+		DesignName:    "Blink",
+		BitstreamName: "design.bit",
+		Bitstream:     bytes.NewBufferString("bitstream"),
+	})
+	if err != nil {
+		t.Fatalf("Submit() error: %v", err)
+	}
+	if finished := waitForTerminal(t, mgr, rec.ID, 3*time.Second); finished.State != job.StateSucceeded {
+		t.Fatalf("State = %s, want %s (error=%s)", finished.State, job.StateSucceeded, finished.Error)
+	}
+
+	sealed, err := atrest.IsSealed(st.RequestBitstreamPath(rec.ID))
+	if err != nil {
+		t.Fatalf("IsSealed() error: %v", err)
+	}
+	if !sealed {
+		t.Fatalf("expected stored bitstream to be encrypted")
+	}
+	if _, err := os.Stat(filepath.Join(st.WorkJobDir(rec.ID), "request.bit")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected decrypted work copy to be removed, stat err=%v", err)
+	}
+
+	rc, err := mgr.OpenBitstream(rec.ID)
+	if err != nil {
+		t.Fatalf("OpenBitstream() error: %v", err)
+	}
+	raw, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatalf("read bitstream: %v", err)
+	}
+	if string(raw) != "bitstream" {
+		t.Fatalf("OpenBitstream() = %q, want plaintext", raw)
+	}
+
+	reflashed, err := mgr.Reflash(context.Background(), rec.ID)
+	if err != nil {
+		t.Fatalf("Reflash() error: %v", err)
+	}
+	if finished := waitForTerminal(t, mgr, reflashed.ID, 3*time.Second); finished.State != job.StateSucceeded {
+		t.Fatalf("reflash State = %s, want %s (error=%s)", finished.State, job.StateSucceeded, finished.Error)
+	}
+}
+
+func TestManagerMetricsCountFlashes(t *testing.T) {
+	t.Parallel()
+
+	cfg := loaderconfig.Default()
+	cfg.BaseDir = t.TempDir()
+	cfg.WorkerTimeout = 2 * time.Second
+
+	st := store.New(cfg)
+	hs := history.New(cfg.HistoryPath(), cfg.HistoryLimit)
+	mgr := New(cfg, st, &flasher.FakeFlasher{}, hs)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+
+	rec, err := mgr.Submit(context.Background(), SubmitRequest{
+		Board:         "alchitry_au",
+		DesignName:    "Blink",
+		BitstreamName: "design.bit",
+		Bitstream:     bytes.NewBufferString("bitstream"),
+	})
+	if err != nil {
+		t.Fatalf("Submit() error: %v", err)
+	}
+	waitForTerminal(t, mgr, rec.ID, 3*time.Second)
+
+	var buf bytes.Buffer
+	if err := mgr.Metrics().WriteText(&buf); err != nil {
+		t.Fatalf("WriteText() error: %v", err)
+	}
+	for _, want := range []string{
+		"spadeloader_jobs_submitted_total 1\n",
+		`spadeloader_jobs_finished_total{state="SUCCEEDED"} 1` + "\n",
+		"spadeloader_upload_bytes_total 9\n",
+		"spadeloader_flash_duration_seconds_count 1\n",
+		"spadeloader_queue_depth 0\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("metrics missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+// gatedFlasher reports each flash on started and holds it until release
+// is closed.
+type gatedFlasher struct {
+	started chan string
+	release chan struct{}
+}
+
+func (f *gatedFlasher) Flash(ctx context.Context, fj flasher.FlashJob) (flasher.Result, error) {
+	f.started <- fj.Board + " " + fj.Target.Device
+	select {
+	case <-f.release:
+	case <-ctx.Done():
+		return flasher.Result{ExitCode: 124}, ctx.Err()
+	}
+	return (&flasher.FakeFlasher{}).Flash(ctx, fj)
+}
+
+func TestManagerFlashesBoardsConcurrentlyAndSerializesEachBoard(t *testing.T) {
+	t.Parallel()
+
+	cfg := loaderconfig.Default()
+	cfg.BaseDir = t.TempDir()
+	cfg.WorkerTimeout = 5 * time.Second
+
+	st := store.New(cfg)
+	hs := history.New(cfg.HistoryPath(), cfg.HistoryLimit)
+	fl := &gatedFlasher{started: make(chan string, 8), release: make(chan struct{})}
+	mgr := New(cfg, st, fl, hs)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+
+	var ids []string
+	for _, req := range []SubmitRequest{
+		{Board: "alchitry_au", Target: job.Target{Device: "serial:A"}},
+		{Board: "alchitry_au", Target: job.Target{Device: "serial:A"}},
+		{Board: "alchitry_au", Target: job.Target{Device: "serial:B"}},
+		{Board: "arty"},
+	} {
+		req.DesignName = "Blink"
+		req.BitstreamName = "design.bit"
+		req.Bitstream = bytes.NewBufferString("bitstream")
+		rec, err := mgr.Submit(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Submit() error: %v", err)
+		}
+		ids = append(ids, rec.ID)
+	}
+
+	running := map[string]bool{}
+	for range 3 {
+		select {
+		case lane := <-fl.started:
+			running[lane] = true
+		case <-time.After(3 * time.Second):
+			t.Fatalf("only %d flashes started concurrently: %v", len(running), running)
+		}
+	}
+	if !running["alchitry_au serial:A"] || !running["alchitry_au serial:B"] || !running["arty "] {
+		t.Fatalf("running = %v, want one flash per lane", running)
+	}
+	if rec, _ := mgr.Get(ids[1]); rec.State != job.StateQueued {
+		t.Fatalf("second flash to serial:A is %s while the first runs, want QUEUED", rec.State)
+	}
+
+	close(fl.release)
+	for _, id := range ids {
+		if rec := waitForTerminal(t, mgr, id, 3*time.Second); rec.State != job.StateSucceeded {
+			t.Fatalf("job %s state = %s", id, rec.State)
+		}
+	}
+}
+
+func TestManagerCapturesSerialAfterFlash(t *testing.T) {
+	t.Parallel()
+
+	cfg := loaderconfig.Default()
+	cfg.BaseDir = t.TempDir()
+	cfg.WorkerTimeout = 2 * time.Second
+	cfg.SerialPorts = []loaderconfig.SerialPort{{Board: "alchitry_au", Port: "/dev/ttyUSB1", Baud: 115200}}
+
+	st := store.New(cfg)
+	hs := history.New(cfg.HistoryPath(), cfg.HistoryLimit)
+	mgr := New(cfg, st, &flasher.FakeFlasher{}, hs)
+	uart, board := io.Pipe()
+	opened := make(chan string, 1)
+	mgr.openSerial = func(port string, baud int) (io.ReadCloser, error) {
+		opened <- fmt.Sprintf("%s@%d", port, baud)
+		return uart, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+
+	if _, err := mgr.Submit(context.Background(), SubmitRequest{
+		Board:         "arty",
+		DesignName:    "Blink",
+		BitstreamName: "design.bit",
+		Bitstream:     bytes.NewBufferString("bitstream"),
+		Monitor:       true,
+	}); !errors.Is(err, ErrNoSerialPort) {
+		t.Fatalf("Submit() for a board without a UART error = %v, want ErrNoSerialPort", err)
+	}
+
+	rec, err := mgr.Submit(context.Background(), SubmitRequest{
+		Board:         "alchitry_au",
+		DesignName:    "Blink",
+		BitstreamName: "design.bit",
+		Bitstream:     bytes.NewBufferString("bitstream"),
+		Monitor:       true,
+		MonitorBaud:   9600,
+	})
+	if err != nil {
+		t.Fatalf("Submit() error: %v", err)
+	}
+	_, chunks, stop, err := mgr.SubscribeSerial(rec.ID)
+	if err != nil {
+		t.Fatalf("SubscribeSerial() error: %v", err)
+	}
+	defer stop()
+
+	select {
+	case got := <-opened:
+		if got != "/dev/ttyUSB1@9600" {
+			t.Fatalf("opened %s, want /dev/ttyUSB1@9600", got)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("serial port was not opened after the flash")
+	}
+	if _, err := board.Write([]byte("hello\n")); err != nil {
+		t.Fatalf("write uart: %v", err)
+	}
+	select {
+	case chunk := <-chunks:
+		if chunk.Text != "hello\n" {
+			t.Fatalf("chunk = %+v, want hello", chunk)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no serial chunk")
+	}
+	_ = board.Close()
+	select {
+	case chunk := <-chunks:
+		if !chunk.Done || chunk.Error != "serial port closed" {
+			t.Fatalf("last chunk = %+v, want Done with serial port closed", chunk)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no Done chunk")
+	}
+
+	raw, err := mgr.ReadSerialLog(rec.ID)
+	if err != nil || string(raw) != "hello\n" {
+		t.Fatalf("ReadSerialLog() = %q, %v", raw, err)
+	}
+	got, _ := mgr.Get(rec.ID)
+	if got.Monitor == nil || got.Monitor.Active || got.Monitor.Bytes != 6 || got.Monitor.Log != job.SerialLogName {
+		t.Fatalf("Monitor = %+v, want 6 bytes captured and inactive", got.Monitor)
+	}
+}
@@ -0,0 +1,59 @@
+package queue
+
+import (
+	"github.com/mblsha/spadeforge/internal/metrics"
+	"github.com/mblsha/spadeforge/internal/spadeloader/job"
+)
+
+// flashDurationBuckets span a quick SRAM load to a slow SPI flash write.
+var flashDurationBuckets = []float64{1, 2, 5, 10, 20, 30, 60, 120, 300, 600}
+
+// managerMetrics are the series a Manager exports on /metrics.
+type managerMetrics struct {
+	registry      *metrics.Registry
+	submitted     *metrics.Counter
+	finished      *metrics.Counter
+	uploadBytes   *metrics.Counter
+	flashDuration *metrics.Histogram
+}
+
+func newManagerMetrics(m *Manager) *managerMetrics {
+	r := metrics.NewRegistry()
+	mm := &managerMetrics{
+		registry:      r,
+		submitted:     r.Counter("spadeloader_jobs_submitted_total", "Flash jobs accepted, including reflashes."),
+		finished:      r.Counter("spadeloader_jobs_finished_total", "Flash jobs that reached a terminal state, by state.", "state"),
+		uploadBytes:   r.Counter("spadeloader_upload_bytes_total", "Bitstream bytes stored by submits."),
+		flashDuration: r.Histogram("spadeloader_flash_duration_seconds", "Flasher run time per job, excluding hardware tests.", flashDurationBuckets),
+	}
+	r.GaugeFunc("spadeloader_queue_depth", "Flash jobs waiting for the flasher.", func() float64 {
+		return float64(m.countState(job.StateQueued))
+	})
+	r.GaugeFunc("spadeloader_jobs_running", "Flash jobs being flashed or tested.", func() float64 {
+		return float64(m.countState(job.StateRunning))
+	})
+	r.GaugeFunc("spadeloader_event_subscribers", "Open job event streams (SSE and WebSocket).", func() float64 {
+		return float64(m.EventStats().Subscribers)
+	})
+	r.CounterFunc("spadeloader_events_dropped_total", "Events dropped for slow subscribers.", func() float64 {
+		return float64(m.EventStats().DroppedEvents)
+	})
+	return mm
+}
+
+// Metrics returns the registry served on /metrics.
+func (m *Manager) Metrics() *metrics.Registry {
+	return m.metrics.registry
+}
+
+func (m *Manager) countState(state job.State) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	n := 0
+	for _, rec := range m.jobs {
+		if rec.State == state {
+			n++
+		}
+	}
+	return n
+}
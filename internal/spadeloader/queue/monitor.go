@@ -0,0 +1,288 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/spadeloader/job"
+	"github.com/mblsha/spadeforge/internal/spadeloader/serial"
+)
+
+var (
+	// ErrNoMonitor is returned by SubscribeSerial for jobs submitted
+	// without a serial monitor.
+	ErrNoMonitor = errors.New("job has no serial monitor")
+	// ErrNoSerialPort rejects a monitor for a board without a configured
+	// UART.
+	ErrNoSerialPort = errors.New("no serial port is configured for this board")
+)
+
+// serialSubscriberBuf is how many chunks a follower may lag behind before
+// it is disconnected; it can catch up from the capture file.
+const serialSubscriberBuf = 256
+
+// serialMonitor captures one job's UART after its flash and fans the output
+// out to followers. Writes to the capture file and subscriber changes share
+// mu, so a new follower's backlog and live chunks never overlap or gap.
+type serialMonitor struct {
+	board string
+
+	mu     sync.Mutex
+	file   *os.File
+	subs   map[chan job.SerialChunk]struct{}
+	done   bool
+	cancel context.CancelFunc
+}
+
+func openSerialPort(port string, baud int) (io.ReadCloser, error) {
+	return serial.Open(port, baud)
+}
+
+// newMonitor builds the record's monitor for req, or nil when none was
+// asked for.
+func (m *Manager) newMonitor(req SubmitRequest) (*job.Monitor, error) {
+	if !req.Monitor {
+		return nil, nil
+	}
+	if req.DryRun {
+		return nil, errors.New("a serial monitor needs a real flash, not a dry run")
+	}
+	sp, ok := m.cfg.SerialPort(req.Board)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoSerialPort, req.Board)
+	}
+	baud := sp.Baud
+	if req.MonitorBaud > 0 {
+		baud = req.MonitorBaud
+	}
+	return &job.Monitor{Port: sp.Port, Baud: baud, Log: job.SerialLogName}, nil
+}
+
+// startMonitor opens the job's serial port and captures it in the
+// background for up to MonitorDuration, until ctx ends or until another job
+// starts flashing the same board.
+func (m *Manager) startMonitor(ctx context.Context, jobID string, mon job.Monitor) {
+	m.mu.Lock()
+	sm := m.monitors[jobID]
+	m.mu.Unlock()
+	if sm == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.MonitorDuration)
+	port, err := m.openSerial(mon.Port, mon.Baud)
+	if err != nil {
+		cancel()
+		m.finishMonitor(jobID, sm, 0, err)
+		return
+	}
+	file, err := os.Create(filepath.Join(m.store.ArtifactsJobDir(jobID), job.SerialLogName))
+	if err != nil {
+		cancel()
+		_ = port.Close()
+		m.finishMonitor(jobID, sm, 0, err)
+		return
+	}
+	sm.mu.Lock()
+	sm.file, sm.cancel = file, cancel
+	sm.mu.Unlock()
+	m.updateMonitor(jobID, func(mon *job.Monitor) { mon.Active = true })
+	log.Printf("[spadeloader job %s] serial monitor started port=%s baud=%d for %s", jobID, mon.Port, mon.Baud, m.cfg.MonitorDuration)
+
+	go func() {
+		<-ctx.Done()
+		_ = port.Close()
+	}()
+	go func() {
+		var total int64
+		var readErr error
+		buf := make([]byte, 4096)
+		for {
+			n, err := port.Read(buf)
+			if n > 0 {
+				sm.write(buf[:n])
+				total += int64(n)
+			}
+			if err != nil {
+				readErr = err
+				break
+			}
+		}
+		if ctx.Err() != nil {
+			// Closed on purpose: the capture window ended or the board
+			// is being flashed again.
+			readErr = nil
+		} else if errors.Is(readErr, io.EOF) {
+			readErr = errors.New("serial port closed")
+		}
+		cancel()
+		m.finishMonitor(jobID, sm, total, readErr)
+	}()
+}
+
+// finishMonitor records how capture ended and ends every follower's
+// stream.
+func (m *Manager) finishMonitor(jobID string, sm *serialMonitor, total int64, err error) {
+	m.updateMonitor(jobID, func(mon *job.Monitor) {
+		mon.Active = false
+		mon.Bytes = total
+		if err != nil {
+			mon.Error = err.Error()
+		}
+	})
+	m.mu.Lock()
+	delete(m.monitors, jobID)
+	m.mu.Unlock()
+
+	end := job.SerialChunk{Done: true}
+	if err != nil {
+		end.Error = err.Error()
+		log.Printf("[spadeloader job %s] serial monitor ended after %d bytes: %v", jobID, total, err)
+	} else {
+		log.Printf("[spadeloader job %s] serial monitor ended after %d bytes", jobID, total)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.done = true
+	if sm.file != nil {
+		_ = sm.file.Close()
+	}
+	for ch := range sm.subs {
+		select {
+		case ch <- end:
+		default:
+		}
+		close(ch)
+	}
+	sm.subs = nil
+}
+
+func (m *Manager) updateMonitor(jobID string, fn func(*job.Monitor)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec, ok := m.jobs[jobID]
+	if !ok || rec.Monitor == nil {
+		return
+	}
+	fn(rec.Monitor)
+	_ = m.store.Save(rec)
+}
+
+// stopMonitorsLocked ends capture on board's serial port before another
+// job flashes that board.
+func (m *Manager) stopMonitorsLocked(board string) {
+	for _, sm := range m.monitors {
+		if !strings.EqualFold(sm.board, board) {
+			continue
+		}
+		sm.mu.Lock()
+		if sm.cancel != nil {
+			sm.cancel()
+		}
+		sm.mu.Unlock()
+	}
+}
+
+func (sm *serialMonitor) write(p []byte) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	_, _ = sm.file.Write(p)
+	chunk := job.SerialChunk{Text: string(p)}
+	for ch := range sm.subs {
+		select {
+		case ch <- chunk:
+		default:
+			// Too far behind; the follower can reconnect and read the
+			// capture file.
+			delete(sm.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// ReadSerialLog returns the job's serial capture so far.
+func (m *Manager) ReadSerialLog(jobID string) ([]byte, error) {
+	rec, ok := m.Get(jobID)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, jobID)
+	}
+	if rec.Monitor == nil {
+		return nil, ErrNoMonitor
+	}
+	raw, err := os.ReadFile(filepath.Join(m.store.ArtifactsJobDir(jobID), job.SerialLogName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return raw, err
+}
+
+// SubscribeSerial returns the job's serial capture so far and, while the
+// monitor is still waiting for the flash or capturing, a channel of further
+// output. The channel ends with a Done chunk, or is closed early for a
+// follower that fell behind. A nil channel means capture is over.
+func (m *Manager) SubscribeSerial(jobID string) ([]byte, <-chan job.SerialChunk, func(), error) {
+	m.mu.RLock()
+	rec, ok := m.jobs[jobID]
+	if !ok {
+		m.mu.RUnlock()
+		return nil, nil, nil, fmt.Errorf("%w: %s", ErrJobNotFound, jobID)
+	}
+	if rec.Monitor == nil {
+		m.mu.RUnlock()
+		return nil, nil, nil, ErrNoMonitor
+	}
+	sm := m.monitors[jobID]
+	m.mu.RUnlock()
+
+	path := filepath.Join(m.store.ArtifactsJobDir(jobID), job.SerialLogName)
+	if sm == nil {
+		raw, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, nil, nil, err
+		}
+		return raw, nil, func() {}, nil
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	raw, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, nil, err
+	}
+	if sm.done {
+		return raw, nil, func() {}, nil
+	}
+	ch := make(chan job.SerialChunk, serialSubscriberBuf)
+	if sm.subs == nil {
+		sm.subs = map[chan job.SerialChunk]struct{}{}
+	}
+	sm.subs[ch] = struct{}{}
+	cancel := func() {
+		sm.mu.Lock()
+		defer sm.mu.Unlock()
+		if _, ok := sm.subs[ch]; ok {
+			delete(sm.subs, ch)
+			close(ch)
+		}
+	}
+	return raw, ch, cancel, nil
+}
+
+// monitorInterrupted marks a monitor cut short by a restart.
+func monitorInterrupted(rec *job.Record, now time.Time) bool {
+	if rec.Monitor == nil || !rec.Monitor.Active {
+		return false
+	}
+	rec.Monitor.Active = false
+	rec.Monitor.Error = "interrupted by restart"
+	rec.UpdatedAt = now
+	return true
+}
@@ -0,0 +1,24 @@
+// Package serial opens a board's UART as a raw 8N1 byte stream.
+package serial
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrUnsupported is returned by Open on platforms without termios support.
+var ErrUnsupported = errors.New("serial ports are not supported on this platform")
+
+// Open opens port in raw 8N1 mode at baud.
+func Open(port string, baud int) (*os.File, error) {
+	f, err := openPort(port)
+	if err != nil {
+		return nil, err
+	}
+	if err := configure(f, baud); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("configure %s: %w", port, err)
+	}
+	return f, nil
+}
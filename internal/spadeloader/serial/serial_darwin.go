@@ -0,0 +1,38 @@
+package serial
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+func configure(f *os.File, baud int) error {
+	raw, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var ioctlErr error
+	err = raw.Control(func(fd uintptr) {
+		var t syscall.Termios
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TIOCGETA, uintptr(unsafe.Pointer(&t))); errno != 0 {
+			ioctlErr = errno
+			return
+		}
+		t.Iflag = 0
+		t.Oflag = 0
+		t.Lflag = 0
+		t.Cflag = syscall.CS8 | syscall.CREAD | syscall.CLOCAL
+		// Darwin takes the baud rate as a plain number.
+		t.Ispeed = uint64(baud)
+		t.Ospeed = uint64(baud)
+		t.Cc[syscall.VMIN] = 1
+		t.Cc[syscall.VTIME] = 0
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TIOCSETA, uintptr(unsafe.Pointer(&t))); errno != 0 {
+			ioctlErr = errno
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return ioctlErr
+}
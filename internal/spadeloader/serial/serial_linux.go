@@ -0,0 +1,59 @@
+package serial
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var linuxBauds = map[int]uint32{
+	1200:    syscall.B1200,
+	2400:    syscall.B2400,
+	4800:    syscall.B4800,
+	9600:    syscall.B9600,
+	19200:   syscall.B19200,
+	38400:   syscall.B38400,
+	57600:   syscall.B57600,
+	115200:  syscall.B115200,
+	230400:  syscall.B230400,
+	460800:  syscall.B460800,
+	921600:  syscall.B921600,
+	1000000: syscall.B1000000,
+	2000000: syscall.B2000000,
+	3000000: syscall.B3000000,
+}
+
+func configure(f *os.File, baud int) error {
+	speed, ok := linuxBauds[baud]
+	if !ok {
+		return fmt.Errorf("unsupported baud rate %d", baud)
+	}
+	raw, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var ioctlErr error
+	err = raw.Control(func(fd uintptr) {
+		var t syscall.Termios
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCGETS, uintptr(unsafe.Pointer(&t))); errno != 0 {
+			ioctlErr = errno
+			return
+		}
+		t.Iflag = 0
+		t.Oflag = 0
+		t.Lflag = 0
+		t.Cflag = syscall.CS8 | syscall.CREAD | syscall.CLOCAL | speed
+		t.Ispeed = speed
+		t.Ospeed = speed
+		t.Cc[syscall.VMIN] = 1
+		t.Cc[syscall.VTIME] = 0
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCSETS, uintptr(unsafe.Pointer(&t))); errno != 0 {
+			ioctlErr = errno
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return ioctlErr
+}
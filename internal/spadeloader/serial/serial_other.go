@@ -0,0 +1,13 @@
+//go:build !(linux || darwin)
+
+package serial
+
+import "os"
+
+func openPort(string) (*os.File, error) {
+	return nil, ErrUnsupported
+}
+
+func configure(*os.File, int) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,15 @@
+//go:build linux || darwin
+
+package serial
+
+import (
+	"os"
+	"syscall"
+)
+
+// openPort opens without becoming the controlling terminal and without
+// waiting for carrier detect; the runtime poller then serves reads, so
+// Close unblocks a pending Read.
+func openPort(port string) (*os.File, error) {
+	return os.OpenFile(port, os.O_RDWR|syscall.O_NOCTTY|syscall.O_NONBLOCK, 0)
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/mblsha/spadeforge/internal/authz"
+	"github.com/mblsha/spadeforge/internal/httpmw"
+	"github.com/mblsha/spadeforge/internal/spadeloader/config"
+)
+
+type scopeKey struct{}
+
+// boardTokenScopes are the scopes a board-scoped token has: flashing its
+// boards and reading, never admin routes.
+var boardTokenScopes = []authz.Scope{authz.ScopeFlash, authz.ScopeRead}
+
+// tokenAuth accepts the full-access token, a board-scoped token when scope
+// is flash or read, a verified client certificate, which has full access,
+// or a named token that allows scope. Requests made with a board-scoped
+// token carry it in their context so the flash handlers can check its
+// board tags. With no tokens configured, auth is disabled.
+func (a *API) tokenAuth(scope authz.Scope) httpmw.Middleware {
+	token := strings.TrimSpace(a.cfg.Token)
+	named := httpmw.ScopedTokenAuth(a.cfg.AuthHeader, "", a.cfg.Tokens, scope)
+	return func(next http.Handler) http.Handler {
+		if token == "" && len(a.cfg.ScopedTokens) == 0 && len(a.cfg.Tokens) == 0 {
+			return next
+		}
+		namedNext := named(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if httpmw.ClientCertVerified(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			got := strings.TrimSpace(r.Header.Get(a.cfg.AuthHeader))
+			if token != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if scoped, ok := a.cfg.ScopedToken(got); ok {
+				if !slices.Contains(boardTokenScopes, scope) {
+					writeJSON(w, http.StatusForbidden, map[string]string{"error": fmt.Sprintf("board-scoped token lacks the %s scope", scope)})
+					return
+				}
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), scopeKey{}, scoped)))
+				return
+			}
+			// Without named tokens, named would let the request through.
+			if len(a.cfg.Tokens) == 0 {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+				return
+			}
+			namedNext.ServeHTTP(w, r)
+		})
+	}
+}
+
+// checkBoardScope returns an error when the request's scoped token may not
+// flash board. Full-access requests always pass.
+func (a *API) checkBoardScope(r *http.Request, board string) error {
+	scoped, ok := r.Context().Value(scopeKey{}).(config.ScopedToken)
+	if !ok || a.cfg.TokenMayFlash(scoped, board) {
+		return nil
+	}
+	return fmt.Errorf("token may not flash board %q; allowed tags: %s", board, strings.Join(scoped.Tags, ", "))
+}
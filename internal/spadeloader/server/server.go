@@ -1,20 +1,30 @@
 package server
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net"
+	"io"
+	"mime/multipart"
 	"net/http"
+	"os"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/mblsha/spadeforge/internal/authz"
+	"github.com/mblsha/spadeforge/internal/buildinfo"
+	"github.com/mblsha/spadeforge/internal/httpmw"
+	"github.com/mblsha/spadeforge/internal/openapi"
+	"github.com/mblsha/spadeforge/internal/spadeloader/bitstream"
 	"github.com/mblsha/spadeforge/internal/spadeloader/config"
+	"github.com/mblsha/spadeforge/internal/spadeloader/history"
 	"github.com/mblsha/spadeforge/internal/spadeloader/job"
 	"github.com/mblsha/spadeforge/internal/spadeloader/queue"
+	"github.com/mblsha/spadeforge/internal/sse"
 )
 
 var boardPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
@@ -23,78 +33,164 @@ type API struct {
 	cfg     config.Config
 	manager *queue.Manager
 	mux     *http.ServeMux
+	limiter *httpmw.RateLimiter
+	spec    *openapi.Spec
 }
 
 func New(cfg config.Config, manager *queue.Manager) *API {
-	a := &API{cfg: cfg, manager: manager, mux: http.NewServeMux()}
+	a := &API{
+		cfg:     cfg,
+		manager: manager,
+		mux:     http.NewServeMux(),
+		limiter: httpmw.NewRateLimiter(cfg.RateLimit, cfg.RateLimitBurst),
+		spec:    openapi.New("spadeloader", buildinfo.Get().Version, cfg.AuthHeader),
+	}
 	a.routes()
 	return a
 }
 
 func (a *API) Handler() http.Handler {
-	return a.mux
+	var accessLog httpmw.Middleware
+	if a.cfg.AccessLog {
+		accessLog = httpmw.AccessLog(nil)
+	}
+	return httpmw.Chain(a.mux, httpmw.Recover(), accessLog, httpmw.Gzip())
 }
 
 func (a *API) routes() {
 	a.mux.HandleFunc("GET /healthz", a.handleHealthz)
-	a.mux.Handle("POST /v1/jobs", a.guard(http.HandlerFunc(a.handleSubmitJob)))
-	a.mux.Handle("GET /v1/jobs", a.guard(http.HandlerFunc(a.handleListJobs)))
-	a.mux.Handle("GET /v1/jobs/{id}", a.guard(http.HandlerFunc(a.handleGetJob)))
-	a.mux.Handle("POST /v1/jobs/{id}/reflash", a.guard(http.HandlerFunc(a.handleReflashJob)))
-	a.mux.Handle("GET /v1/jobs/{id}/log", a.guard(http.HandlerFunc(a.handleGetLog)))
-	a.mux.Handle("GET /v1/jobs/{id}/tail", a.guard(http.HandlerFunc(a.handleGetTail)))
-	a.mux.Handle("GET /v1/jobs/{id}/events", a.guard(http.HandlerFunc(a.handleGetEvents)))
-	a.mux.Handle("GET /v1/designs/recent", a.guard(http.HandlerFunc(a.handleGetRecentDesigns)))
-}
-
-func (a *API) guard(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if err := a.checkAllowlist(r); err != nil {
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
-			return
-		}
-		if err := a.checkToken(r); err != nil {
-			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
-			return
-		}
-		next.ServeHTTP(w, r)
+	a.spec.Add("GET /healthz", openapi.Op{Summary: "Liveness check", Response: map[string]string{}, Public: true})
+	a.mux.Handle("GET /openapi.json", a.spec.Handler())
+	a.spec.Add("GET /openapi.json", openapi.Op{Summary: "This OpenAPI document", ContentType: "application/json", Public: true})
+
+	submitted := struct {
+		JobID string    `json:"job_id"`
+		State job.State `json:"state"`
+	}{}
+	a.handle("GET /metrics", authz.ScopeRead, a.manager.Metrics().Handler(), openapi.Op{Summary: "Prometheus metrics", ContentType: "text/plain"})
+	a.handle("GET /v1/version", authz.ScopeRead, http.HandlerFunc(a.handleVersion), openapi.Op{Summary: "Server version", Response: buildinfo.Info{}})
+	a.handle("POST /v1/jobs", authz.ScopeFlash, http.HandlerFunc(a.handleSubmitJob), openapi.Op{
+		Summary: "Flash a bitstream; with boards or board=all-tagged:<tag>, one job per board, answered with a Batch",
+		Form:    []string{"board", "boards", "design_name", "dry_run", "mode", "flash_offset", "device", "cable", "ftdi_index", "monitor", "baud"},
+		Files:   []string{"bitstream"},
+		Status:  http.StatusAccepted, Response: submitted,
+	})
+	a.handle("GET /v1/batches/{id}", authz.ScopeRead, http.HandlerFunc(a.handleGetBatch), openapi.Op{Summary: "Jobs of a batch submission and their combined state", Response: job.Batch{}})
+	a.handle("GET /v1/jobs", authz.ScopeRead, http.HandlerFunc(a.handleListJobs), openapi.Op{
+		Summary: "Recent flash jobs, newest first",
+		Query:   []openapi.Param{{Name: "limit", Type: "integer"}},
+		Response: struct {
+			Items []job.Record `json:"items"`
+		}{},
+	})
+	a.handle("GET /v1/jobs/{id}", authz.ScopeRead, http.HandlerFunc(a.handleGetJob), openapi.Op{Summary: "Flash job state", Response: job.Record{}})
+	a.handle("POST /v1/jobs/{id}/reflash", authz.ScopeFlash, http.HandlerFunc(a.handleReflashJob), openapi.Op{
+		Summary: "Flash a job's bitstream again, optionally to another board",
+		Form:    []string{"board", "device", "cable", "ftdi_index"},
+		Status:  http.StatusAccepted, Response: submitted,
+	})
+	a.handle("GET /v1/jobs/{id}/artifacts", authz.ScopeRead, http.HandlerFunc(a.handleGetArtifacts), openapi.Op{Summary: "Flash job artifacts", ContentType: "application/zip"})
+	a.handle("GET /v1/jobs/{id}/bitstream", authz.ScopeRead, http.HandlerFunc(a.handleGetBitstream), openapi.Op{Summary: "The uploaded bitstream", ContentType: "application/octet-stream"})
+	a.handle("GET /v1/jobs/{id}/log", authz.ScopeRead, http.HandlerFunc(a.handleGetLog), openapi.Op{Summary: "Flash console log", ContentType: "text/plain"})
+	a.handle("GET /v1/jobs/{id}/tail", authz.ScopeRead, http.HandlerFunc(a.handleGetTail), openapi.Op{
+		Summary: "Last lines of the console log", Query: []openapi.Param{{Name: "lines", Type: "integer"}}, ContentType: "text/plain",
+	})
+	a.handle("GET /v1/jobs/{id}/serial", authz.ScopeRead, http.HandlerFunc(a.handleGetSerial), openapi.Op{
+		Summary:     "UART output captured after the flash; SSE of SerialChunk with follow=1",
+		Query:       []openapi.Param{{Name: "follow", Type: "boolean"}},
+		ContentType: "text/plain",
+	})
+	a.handle("GET /v1/events", authz.ScopeRead, http.HandlerFunc(a.handleGetAllEvents), openapi.Op{
+		Summary: "Events of every job", Query: []openapi.Param{{Name: "since", Type: "integer"}}, Events: job.Event{},
+	})
+	a.handle("GET /v1/jobs/{id}/events", authz.ScopeRead, http.HandlerFunc(a.handleGetEvents), openapi.Op{
+		Summary: "Flash job events", Query: []openapi.Param{{Name: "since", Type: "integer"}}, Events: job.Event{},
+	})
+	a.handle("GET /v1/devices", authz.ScopeRead, http.HandlerFunc(a.handleListDevices), openapi.Op{
+		Summary: "Attached programmers",
+		Response: struct {
+			Items []job.Device `json:"items"`
+		}{},
+	})
+	a.handle("GET /v1/designs/recent", authz.ScopeRead, http.HandlerFunc(a.handleGetRecentDesigns), openapi.Op{
+		Summary: "Recently flashed designs",
+		Query:   []openapi.Param{{Name: "limit", Type: "integer"}},
+		Response: struct {
+			Items []history.Item `json:"items"`
+		}{},
+	})
+	a.handle("GET /v1/kiosk", authz.ScopeRead, http.HandlerFunc(a.handleGetKiosk), openapi.Op{Summary: "Kiosk mode and golden designs", Response: job.KioskStatus{}})
+	a.handle("GET /v1/admin/metrics", authz.ScopeAdmin, http.HandlerFunc(a.handleMetrics), openapi.Op{
+		Summary: "Event subscriber counters",
+		Response: struct {
+			Events queue.EventStats `json:"events"`
+		}{},
 	})
 }
 
-func (a *API) checkToken(r *http.Request) error {
-	if strings.TrimSpace(a.cfg.Token) == "" {
-		return nil
-	}
-	if strings.TrimSpace(r.Header.Get(a.cfg.AuthHeader)) != a.cfg.Token {
-		return errors.New("invalid token")
-	}
-	return nil
+// handle registers a route guarded for tokens with scope and documents it
+// in the OpenAPI spec.
+func (a *API) handle(pattern string, scope authz.Scope, h http.Handler, op openapi.Op) {
+	a.mux.Handle(pattern, a.guard(scope, h))
+	a.spec.Add(pattern, op)
 }
 
-func (a *API) checkAllowlist(r *http.Request) error {
-	if !a.cfg.AllowlistEnabled() {
-		return nil
-	}
-	ip, err := remoteIP(r.RemoteAddr)
-	if err != nil {
-		return err
-	}
-	for _, allow := range a.cfg.Allowlist {
-		if allowEntryMatches(allow, ip) {
-			return nil
-		}
-	}
-	return fmt.Errorf("remote ip %s is not allowed", ip.String())
+// guard applies the allowlist, rate limit and token checks every /v1
+// route shares; named tokens also need scope.
+func (a *API) guard(scope authz.Scope, next http.Handler) http.Handler {
+	return httpmw.Chain(next,
+		httpmw.Allowlist(a.cfg.Allowlist),
+		httpmw.RateLimit(a.limiter),
+		a.tokenAuth(scope),
+	)
 }
 
 func (a *API) handleHealthz(w http.ResponseWriter, _ *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
+// handleVersion reports the version, commit and build date stamped in by
+// `spadeforge release`, and where the CLIs can fetch that release.
+func (a *API) handleVersion(w http.ResponseWriter, _ *http.Request) {
+	info := buildinfo.Get()
+	info.ReleaseURL = a.cfg.ReleaseURL
+	writeJSON(w, http.StatusOK, info)
+}
+
+// handleMetrics reports server counters as JSON.
+func (a *API) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"events": a.manager.EventStats()})
+}
+
+// handleGetKiosk lists the golden designs offered in kiosk mode.
+func (a *API) handleGetKiosk(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, job.KioskStatus{
+		Enabled: a.cfg.Kiosk,
+		Designs: a.manager.GoldenDesigns(a.cfg.IsGolden),
+	})
+}
+
+// handleListDevices lists the programmers attached to the host, and those
+// seen since startup that have been unplugged.
+func (a *API) handleListDevices(w http.ResponseWriter, r *http.Request) {
+	devices, err := a.manager.ListDevices(r.Context())
+	if errors.Is(err, queue.ErrNoScanner) {
+		writeJSON(w, http.StatusNotImplemented, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": devices})
+}
+
 func (a *API) handleSubmitJob(w http.ResponseWriter, r *http.Request) {
-	r.Body = http.MaxBytesReader(w, r.Body, a.cfg.MaxUploadBytes)
-	if err := r.ParseMultipartForm(32 << 20); err != nil {
-		writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": err.Error()})
+	if a.cfg.Kiosk {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "uploads are disabled in kiosk mode"})
+		return
+	}
+	if !httpmw.ParseUpload(w, r, "bitstream", a.cfg.MaxBitstreamBytes) {
 		return
 	}
 	if r.MultipartForm != nil {
@@ -106,18 +202,61 @@ func (a *API) handleSubmitJob(w http.ResponseWriter, r *http.Request) {
 	board := strings.TrimSpace(r.FormValue("board"))
 	designName := strings.TrimSpace(r.FormValue("design_name"))
 
-	if err := validateBoard(board); err != nil {
+	boards, err := a.batchBoards(r)
+	if err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
-	if !a.cfg.BoardAllowed(board) {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "board is not allowed by server policy"})
-		return
+	batch := boards != nil
+	if !batch {
+		boards = []string{board}
+	}
+	for _, board := range boards {
+		if err := validateBoard(board); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if !a.cfg.BoardAllowed(board) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("board %q is not allowed by server policy", board)})
+			return
+		}
+		if err := a.checkBoardScope(r, board); err != nil {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+			return
+		}
 	}
 	if err := validateDesignName(designName); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
+	dryRun := false
+	if raw := strings.TrimSpace(r.FormValue("dry_run")); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "dry_run must be a boolean"})
+			return
+		}
+		dryRun = v
+	}
+	target, err := parseTarget(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if batch && (target.Device != "" || target.FTDIIndex != nil) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "device and ftdi_index pick a single programmer and cannot be used with several boards"})
+		return
+	}
+	mode, flashOffset, err := parseMode(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	monitor, baud, err := parseMonitor(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
 
 	file, header, err := r.FormFile("bitstream")
 	if err != nil {
@@ -132,12 +271,40 @@ func (a *API) handleSubmitJob(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rec, err := a.manager.Submit(r.Context(), queue.SubmitRequest{
+	var info bitstream.Info
+	if a.cfg.CheckBitstreams {
+		info, err = checkBitstream(file, header.Size, boards...)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+
+	req := queue.SubmitRequest{
 		Board:         board,
 		DesignName:    designName,
 		BitstreamName: filepath.Base(bitstreamName),
 		Bitstream:     file,
-	})
+		Format:        info.Format,
+		Part:          info.Part,
+		DryRun:        dryRun,
+		Mode:          mode,
+		FlashOffset:   flashOffset,
+		Target:        target,
+		Monitor:       monitor,
+		MonitorBaud:   baud,
+		TokenName:     authz.Name(r.Context()),
+	}
+	if batch {
+		b, err := a.manager.SubmitBatch(r.Context(), req, boards)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusAccepted, b)
+		return
+	}
+	rec, err := a.manager.Submit(r.Context(), req)
 	if err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
@@ -149,6 +316,101 @@ func (a *API) handleSubmitJob(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// batchBoards returns the boards of a batch submission, from the boards
+// form field (comma-separated or repeated) or from board=all-tagged:<tag>.
+// It returns nil for a single-board submission.
+func (a *API) batchBoards(r *http.Request) ([]string, error) {
+	board := strings.TrimSpace(r.FormValue("board"))
+	var boards []string
+	for _, v := range r.Form["boards"] {
+		for _, b := range strings.Split(v, ",") {
+			if b = strings.TrimSpace(b); b != "" {
+				boards = append(boards, b)
+			}
+		}
+	}
+	if tag, ok := strings.CutPrefix(board, job.AllTaggedPrefix); ok {
+		if len(boards) > 0 {
+			return nil, errors.New("use either boards or board=" + job.AllTaggedPrefix + "<tag>, not both")
+		}
+		boards = a.cfg.BoardsTagged(tag)
+		if len(boards) == 0 {
+			return nil, fmt.Errorf("no boards are tagged %q", tag)
+		}
+		return boards, nil
+	}
+	if len(boards) == 0 {
+		return nil, nil
+	}
+	if board != "" {
+		return nil, errors.New("use either board or boards, not both")
+	}
+	seen := map[string]bool{}
+	for _, b := range boards {
+		key := strings.ToLower(b)
+		if seen[key] {
+			return nil, fmt.Errorf("board %q is listed twice", b)
+		}
+		seen[key] = true
+	}
+	return boards, nil
+}
+
+// parseTarget reads the optional device, cable and ftdi_index form fields.
+func parseTarget(r *http.Request) (job.Target, error) {
+	t := job.Target{
+		Device: r.FormValue("device"),
+		Cable:  r.FormValue("cable"),
+	}
+	if raw := strings.TrimSpace(r.FormValue("ftdi_index")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return job.Target{}, fmt.Errorf("ftdi_index must be an integer")
+		}
+		t.FTDIIndex = &n
+	}
+	t.Normalize()
+	return t, t.Validate()
+}
+
+// parseMode reads the optional mode (sram or flash) and flash_offset form
+// fields.
+func parseMode(r *http.Request) (job.Mode, uint32, error) {
+	mode, err := job.ParseMode(r.FormValue("mode"))
+	if err != nil {
+		return "", 0, err
+	}
+	offset, err := job.ParseFlashOffset(r.FormValue("flash_offset"))
+	if err != nil {
+		return "", 0, err
+	}
+	return mode, offset, job.ValidateFlashOffset(mode, offset)
+}
+
+// parseMonitor reads the optional monitor and baud form fields.
+func parseMonitor(r *http.Request) (bool, int, error) {
+	monitor := false
+	if raw := strings.TrimSpace(r.FormValue("monitor")); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return false, 0, errors.New("monitor must be a boolean")
+		}
+		monitor = v
+	}
+	baud := 0
+	if raw := strings.TrimSpace(r.FormValue("baud")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			return false, 0, errors.New("baud must be a positive integer")
+		}
+		if !monitor {
+			return false, 0, errors.New("baud needs monitor")
+		}
+		baud = n
+	}
+	return monitor, baud, nil
+}
+
 func (a *API) handleGetJob(w http.ResponseWriter, r *http.Request) {
 	jobID := r.PathValue("id")
 	rec, ok := a.manager.Get(jobID)
@@ -159,6 +421,15 @@ func (a *API) handleGetJob(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, rec)
 }
 
+func (a *API) handleGetBatch(w http.ResponseWriter, r *http.Request) {
+	b, ok := a.manager.Batch(r.PathValue("id"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "batch not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, b)
+}
+
 func (a *API) handleListJobs(w http.ResponseWriter, r *http.Request) {
 	limit := a.cfg.HistoryLimit
 	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
@@ -182,12 +453,41 @@ func (a *API) handleReflashJob(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusNotFound, map[string]string{"error": "job not found"})
 		return
 	}
-	if !a.cfg.BoardAllowed(sourceRec.Board) {
+	to, err := parseRetarget(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	board := sourceRec.Board
+	if to != nil {
+		if a.cfg.Kiosk {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "retargeting is disabled in kiosk mode"})
+			return
+		}
+		if to.Board != "" && to.Board != board {
+			board = to.Board
+			if a.cfg.CheckBitstreams && sourceRec.BitstreamFormat != "" {
+				if err := bitstream.CheckBoard(board, bitstream.Info{Format: sourceRec.BitstreamFormat}); err != nil {
+					writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+					return
+				}
+			}
+		}
+	}
+	if !a.cfg.BoardAllowed(board) {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "board is not allowed by server policy"})
 		return
 	}
+	if err := a.checkBoardScope(r, board); err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+	if a.cfg.Kiosk && !a.cfg.IsGolden(sourceRec.Board, sourceRec.DesignName) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "only golden designs can be flashed in kiosk mode"})
+		return
+	}
 
-	rec, err := a.manager.Reflash(r.Context(), sourceJobID)
+	rec, err := a.manager.ReflashTo(r.Context(), sourceJobID, to, authz.Name(r.Context()))
 	if err != nil {
 		switch {
 		case errors.Is(err, queue.ErrJobNotFound), errors.Is(err, queue.ErrBitstreamUnavailable):
@@ -205,6 +505,102 @@ func (a *API) handleReflashJob(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// parseRetarget reads the optional board, device, cable and ftdi_index
+// fields of a reflash. Without any of them the reflash keeps the source
+// job's board and programmer; with any, they replace it.
+func parseRetarget(r *http.Request) (*queue.Retarget, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, fmt.Errorf("invalid reflash form: %w", err)
+	}
+	set := false
+	for _, field := range []string{"board", "device", "cable", "ftdi_index"} {
+		if strings.TrimSpace(r.FormValue(field)) != "" {
+			set = true
+		}
+	}
+	if !set {
+		return nil, nil
+	}
+	board := strings.TrimSpace(r.FormValue("board"))
+	if board != "" {
+		if err := validateBoard(board); err != nil {
+			return nil, err
+		}
+	}
+	target, err := parseTarget(r)
+	if err != nil {
+		return nil, err
+	}
+	return &queue.Retarget{Board: board, Target: target}, nil
+}
+
+func (a *API) handleGetArtifacts(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	if _, ok := a.manager.Get(jobID); !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "job not found"})
+		return
+	}
+
+	var payload bytes.Buffer
+	if err := a.manager.DownloadArtifacts(jobID, &payload); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, os.ErrNotExist) {
+			status = http.StatusNotFound
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", jobID+"-artifacts.zip"))
+	http.ServeContent(w, r, jobID+"-artifacts.zip", time.Time{}, bytes.NewReader(payload.Bytes()))
+}
+
+// handleGetBitstream returns the bitstream a job was submitted with. Scoped
+// tokens only get bitstreams for boards they may flash.
+func (a *API) handleGetBitstream(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	rec, ok := a.manager.Get(jobID)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "job not found"})
+		return
+	}
+	if err := a.checkBoardScope(r, rec.Board); err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+	f, err := a.manager.OpenBitstream(jobID)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "bitstream is no longer available"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	defer f.Close()
+
+	name := rec.BitstreamName
+	if name == "" {
+		name = jobID + ".bit"
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	file, ok := f.(*os.File)
+	if !ok {
+		// Bitstreams encrypted at rest are decrypted as they stream, so
+		// they cannot serve ranges.
+		_, _ = io.Copy(w, f)
+		return
+	}
+	fi, err := file.Stat()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	http.ServeContent(w, r, name, fi.ModTime(), file)
+}
+
 func (a *API) handleGetLog(w http.ResponseWriter, r *http.Request) {
 	jobID := r.PathValue("id")
 	if _, ok := a.manager.Get(jobID); !ok {
@@ -246,16 +642,123 @@ func (a *API) handleGetTail(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write(raw)
 }
 
-func (a *API) handleGetEvents(w http.ResponseWriter, r *http.Request) {
+// handleGetSerial returns what the job's serial monitor has captured. With
+// follow=1 it streams the capture as SSE chunks until the monitor ends.
+func (a *API) handleGetSerial(w http.ResponseWriter, r *http.Request) {
 	jobID := r.PathValue("id")
-	since := int64(0)
-	if rawSince := strings.TrimSpace(r.URL.Query().Get("since")); rawSince != "" {
-		n, err := strconv.ParseInt(rawSince, 10, 64)
+	follow := false
+	if raw := strings.TrimSpace(r.URL.Query().Get("follow")); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "follow must be a boolean"})
+			return
+		}
+		follow = v
+	}
+	if !follow {
+		raw, err := a.manager.ReadSerialLog(jobID)
 		if err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid since query value"})
+			writeSerialError(w, err)
 			return
 		}
-		since = n
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(raw)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "streaming unsupported"})
+		return
+	}
+	backlog, ch, cancel, err := a.manager.SubscribeSerial(jobID)
+	if err != nil {
+		writeSerialError(w, err)
+		return
+	}
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", sse.DefaultRetry.Milliseconds()); err != nil {
+		return
+	}
+	if len(backlog) > 0 {
+		if err := writeSerialChunk(w, job.SerialChunk{Text: string(backlog)}); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+	if ch == nil {
+		end := job.SerialChunk{Done: true}
+		if rec, ok := a.manager.Get(jobID); ok && rec.Monitor != nil {
+			end.Error = rec.Monitor.Error
+		}
+		_ = writeSerialChunk(w, end)
+		flusher.Flush()
+		return
+	}
+
+	keepalive := time.NewTicker(a.sseKeepalive())
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			if _, err := w.Write([]byte(": keepalive\n\n")); err != nil {
+				return
+			}
+			flusher.Flush()
+		case chunk, ok := <-ch:
+			if !ok {
+				// Fell behind; the client reconnects and reads the capture
+				// file again.
+				return
+			}
+			if err := writeSerialChunk(w, chunk); err != nil {
+				return
+			}
+			flusher.Flush()
+			if chunk.Done {
+				return
+			}
+		}
+	}
+}
+
+func writeSerialError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, queue.ErrJobNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "job not found"})
+	case errors.Is(err, queue.ErrNoMonitor):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	default:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+}
+
+func writeSerialChunk(w http.ResponseWriter, chunk job.SerialChunk) error {
+	raw, err := json.Marshal(chunk)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: serial\ndata: %s\n\n", raw)
+	return err
+}
+
+func (a *API) handleGetEvents(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	since, err := parseSince(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
 	}
 
 	backlog, ch, cancel, ok := a.manager.SubscribeEvents(jobID, since)
@@ -277,6 +780,11 @@ func (a *API) handleGetEvents(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("X-Accel-Buffering", "no")
 	w.WriteHeader(http.StatusOK)
 
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", sse.DefaultRetry.Milliseconds()); err != nil {
+		return
+	}
+	flusher.Flush()
+
 	for _, ev := range backlog {
 		if err := writeSSEEvent(w, ev); err != nil {
 			return
@@ -287,7 +795,7 @@ func (a *API) handleGetEvents(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	keepalive := time.NewTicker(15 * time.Second)
+	keepalive := time.NewTicker(a.sseKeepalive())
 	defer keepalive.Stop()
 
 	for {
@@ -331,6 +839,63 @@ func (a *API) handleGetEvents(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleGetAllEvents streams every job's events, numbered by a server-wide
+// seq so clients such as the TUI resume across jobs with since.
+func (a *API) handleGetAllEvents(w http.ResponseWriter, r *http.Request) {
+	since, err := parseSince(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "streaming unsupported"})
+		return
+	}
+
+	backlog, ch, cancel := a.manager.SubscribeAllEvents(since)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", sse.DefaultRetry.Milliseconds()); err != nil {
+		return
+	}
+	for _, ev := range backlog {
+		if err := writeSSEEvent(w, ev); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	keepalive := time.NewTicker(a.sseKeepalive())
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			if _, err := w.Write([]byte(": keepalive\n\n")); err != nil {
+				return
+			}
+			flusher.Flush()
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := writeSSEEvent(w, ev); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
 func (a *API) handleGetRecentDesigns(w http.ResponseWriter, r *http.Request) {
 	limit := 20
 	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
@@ -353,6 +918,25 @@ func (a *API) handleGetRecentDesigns(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]any{"items": items})
 }
 
+func parseSince(r *http.Request) (int64, error) {
+	rawSince := strings.TrimSpace(r.URL.Query().Get("since"))
+	if rawSince == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(rawSince, 10, 64)
+	if err != nil {
+		return 0, errors.New("invalid since query value")
+	}
+	return n, nil
+}
+
+func (a *API) sseKeepalive() time.Duration {
+	if a.cfg.SSEKeepalive > 0 {
+		return a.cfg.SSEKeepalive
+	}
+	return 15 * time.Second
+}
+
 func writeSSEEvent(w http.ResponseWriter, ev job.Event) error {
 	raw, err := json.Marshal(ev)
 	if err != nil {
@@ -389,35 +973,26 @@ func validateBitstreamName(name string) error {
 	return nil
 }
 
-func writeJSON(w http.ResponseWriter, status int, v any) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	_ = json.NewEncoder(w).Encode(v)
-}
-
-func remoteIP(remoteAddr string) (net.IP, error) {
-	host, _, err := net.SplitHostPort(remoteAddr)
+// checkBitstream validates the uploaded file for board and rewinds it for
+// the queue to copy.
+func checkBitstream(file multipart.File, size int64, boards ...string) (bitstream.Info, error) {
+	info, err := bitstream.Inspect(file, size)
 	if err != nil {
-		return nil, fmt.Errorf("parse remote addr: %w", err)
+		return bitstream.Info{}, err
 	}
-	ip := net.ParseIP(host)
-	if ip == nil {
-		return nil, fmt.Errorf("invalid remote ip: %s", host)
-	}
-	return ip, nil
-}
-
-func allowEntryMatches(entry string, ip net.IP) bool {
-	if strings.Contains(entry, "/") {
-		_, cidr, err := net.ParseCIDR(entry)
-		if err != nil {
-			return false
+	for _, board := range boards {
+		if err := bitstream.CheckBoard(board, info); err != nil {
+			return bitstream.Info{}, err
 		}
-		return cidr.Contains(ip)
 	}
-	allowed := net.ParseIP(entry)
-	if allowed == nil {
-		return false
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return bitstream.Info{}, fmt.Errorf("rewind bitstream: %w", err)
 	}
-	return allowed.Equal(ip)
+	return info, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
 }
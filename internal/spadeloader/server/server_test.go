@@ -1,6 +1,7 @@
 package server
 
 import (
+	"archive/zip"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -8,11 +9,14 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/mblsha/spadeforge/internal/authz"
+	"github.com/mblsha/spadeforge/internal/spadeloader/client"
 	loaderconfig "github.com/mblsha/spadeforge/internal/spadeloader/config"
 	"github.com/mblsha/spadeforge/internal/spadeloader/flasher"
 	"github.com/mblsha/spadeforge/internal/spadeloader/history"
@@ -41,7 +45,7 @@ func TestSubmitAndRecentDesigns(t *testing.T) {
 	ts := httptest.NewServer(api.Handler())
 	defer ts.Close()
 
-	status, body := submitJob(t, ts.URL, "alchitry_au", "Blink", "design.bit", []byte("bitstream"), "", "")
+	status, body := submitJob(t, ts.URL, "alchitry_au", "Blink", "design.bit", testBitstream(), "", "")
 	if status != http.StatusAccepted {
 		t.Fatalf("submit status = %d, body=%s", status, body)
 	}
@@ -109,6 +113,30 @@ func TestSubmitAndRecentDesigns(t *testing.T) {
 		t.Fatalf("expected non-empty tail body")
 	}
 
+	artifactsResp, err := http.Get(ts.URL + "/v1/jobs/" + jobID + "/artifacts")
+	if err != nil {
+		t.Fatalf("GET artifacts error: %v", err)
+	}
+	defer artifactsResp.Body.Close()
+	if artifactsResp.StatusCode != http.StatusOK {
+		t.Fatalf("artifacts status = %d", artifactsResp.StatusCode)
+	}
+	artifactsRaw, err := io.ReadAll(artifactsResp.Body)
+	if err != nil {
+		t.Fatalf("read artifacts body: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(artifactsRaw), int64(len(artifactsRaw)))
+	if err != nil {
+		t.Fatalf("open artifacts zip: %v", err)
+	}
+	names := map[string]bool{}
+	for _, f := range zr.File {
+		names[f.Name] = true
+	}
+	if !names["console.log"] || !names[flasher.InvocationFileName] {
+		t.Fatalf("artifacts zip missing console.log or %s: %v", flasher.InvocationFileName, names)
+	}
+
 	eventsResp, err := http.Get(ts.URL + "/v1/jobs/" + jobID + "/events")
 	if err != nil {
 		t.Fatalf("GET events error: %v", err)
@@ -149,12 +177,12 @@ func TestSubmitValidation(t *testing.T) {
 	ts := httptest.NewServer(api.Handler())
 	defer ts.Close()
 
-	status, _ := submitJob(t, ts.URL, "bad board !", "Blink", "design.bit", []byte("bitstream"), "", "")
+	status, _ := submitJob(t, ts.URL, "bad board !", "Blink", "design.bit", testBitstream(), "", "")
 	if status != http.StatusBadRequest {
 		t.Fatalf("status = %d, want %d", status, http.StatusBadRequest)
 	}
 
-	status, _ = submitJob(t, ts.URL, "alchitry_au", "Blink", "design.txt", []byte("bitstream"), "", "")
+	status, _ = submitJob(t, ts.URL, "alchitry_au", "Blink", "design.txt", testBitstream(), "", "")
 	if status != http.StatusBadRequest {
 		t.Fatalf("status = %d, want %d", status, http.StatusBadRequest)
 	}
@@ -180,12 +208,12 @@ func TestTokenGuard(t *testing.T) {
 	ts := httptest.NewServer(api.Handler())
 	defer ts.Close()
 
-	status, _ := submitJob(t, ts.URL, "alchitry_au", "Blink", "design.bit", []byte("bitstream"), "", "")
+	status, _ := submitJob(t, ts.URL, "alchitry_au", "Blink", "design.bit", testBitstream(), "", "")
 	if status != http.StatusUnauthorized {
 		t.Fatalf("status = %d, want %d", status, http.StatusUnauthorized)
 	}
 
-	status, body := submitJob(t, ts.URL, "alchitry_au", "Blink", "design.bit", []byte("bitstream"), cfg.AuthHeader, "secret")
+	status, body := submitJob(t, ts.URL, "alchitry_au", "Blink", "design.bit", testBitstream(), cfg.AuthHeader, "secret")
 	if status != http.StatusAccepted {
 		t.Fatalf("status = %d, want %d", status, http.StatusAccepted)
 	}
@@ -198,6 +226,311 @@ func TestTokenGuard(t *testing.T) {
 	}
 }
 
+func TestOpenAPIServedWithoutToken(t *testing.T) {
+	t.Parallel()
+
+	cfg := loaderconfig.Default()
+	cfg.BaseDir = t.TempDir()
+	cfg.Token = "secret"
+
+	st := store.New(cfg)
+	hs := history.New(cfg.HistoryPath(), cfg.HistoryLimit)
+	mgr := queue.New(cfg, st, &flasher.FakeFlasher{}, hs)
+
+	api := New(cfg, mgr)
+	ts := httptest.NewServer(api.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/openapi.json")
+	if err != nil {
+		t.Fatalf("GET openapi.json error: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var doc struct {
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas         map[string]json.RawMessage `json:"schemas"`
+			SecuritySchemes map[string]struct {
+				Name string `json:"name"`
+			} `json:"securitySchemes"`
+		} `json:"components"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		t.Fatalf("decode openapi.json: %v", err)
+	}
+	if _, ok := doc.Paths["/v1/jobs"]["post"]; !ok {
+		t.Fatalf("POST /v1/jobs is not documented")
+	}
+	if _, ok := doc.Paths["/v1/jobs/{id}/reflash"]["post"]; !ok {
+		t.Fatalf("POST /v1/jobs/{id}/reflash is not documented")
+	}
+	for _, name := range []string{"Record", "Event", "Device", "Item", "KioskStatus"} {
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Fatalf("schema %s is missing", name)
+		}
+	}
+	if got := doc.Components.SecuritySchemes["token"].Name; got != cfg.AuthHeader {
+		t.Fatalf("security header = %q, want %q", got, cfg.AuthHeader)
+	}
+}
+
+func TestScopedTokensOnlyFlashTaggedBoards(t *testing.T) {
+	t.Parallel()
+
+	cfg := loaderconfig.Default()
+	cfg.BaseDir = t.TempDir()
+	cfg.WorkerTimeout = 2 * time.Second
+	cfg.Token = "teacher"
+	cfg.ScopedTokens = []loaderconfig.ScopedToken{{Token: "student-3", Tags: []string{"bench-3"}}}
+	cfg.BoardTags = map[string][]string{"alchitry_au": {"bench-3"}}
+
+	st := store.New(cfg)
+	hs := history.New(cfg.HistoryPath(), cfg.HistoryLimit)
+	mgr := queue.New(cfg, st, &flasher.FakeFlasher{}, hs)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+
+	ts := httptest.NewServer(New(cfg, mgr).Handler())
+	defer ts.Close()
+
+	status, body := submitJob(t, ts.URL, "alchitry_au", "Blink", "design.bit", testBitstream(), cfg.AuthHeader, "student-3")
+	if status != http.StatusAccepted {
+		t.Fatalf("tagged board status = %d, body=%s", status, body)
+	}
+	var submitResp map[string]string
+	if err := json.Unmarshal([]byte(body), &submitResp); err != nil {
+		t.Fatalf("decode submit response: %v", err)
+	}
+	_ = waitForTerminalHTTP(t, ts.URL, submitResp["job_id"], cfg.AuthHeader, "student-3")
+
+	status, body = submitJob(t, ts.URL, "arty", "Blink", "design.bit", testBitstream(), cfg.AuthHeader, "student-3")
+	if status != http.StatusForbidden || !strings.Contains(body, "bench-3") {
+		t.Fatalf("untagged board status = %d, body=%s", status, body)
+	}
+	status, body = submitJob(t, ts.URL, "arty", "Blink", "design.bit", testBitstream(), cfg.AuthHeader, "teacher")
+	if status != http.StatusAccepted {
+		t.Fatalf("full-access token status = %d, body=%s", status, body)
+	}
+	if err := json.Unmarshal([]byte(body), &submitResp); err != nil {
+		t.Fatalf("decode submit response: %v", err)
+	}
+	artyJobID := submitResp["job_id"]
+	_ = waitForTerminalHTTP(t, ts.URL, artyJobID, cfg.AuthHeader, "teacher")
+
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/v1/jobs/"+artyJobID+"/reflash", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(cfg.AuthHeader, "student-3")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST reflash error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("scoped reflash of another bench status = %d, want 403", resp.StatusCode)
+	}
+
+	for token, want := range map[string]int{"student-3": http.StatusForbidden, "teacher": http.StatusOK} {
+		req, err := http.NewRequest(http.MethodGet, ts.URL+"/v1/admin/metrics", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(cfg.AuthHeader, token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET admin metrics error: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("admin metrics with %s status = %d, want %d", token, resp.StatusCode, want)
+		}
+	}
+
+	if status, _ := submitJob(t, ts.URL, "alchitry_au", "Blink", "design.bit", testBitstream(), cfg.AuthHeader, "guess"); status != http.StatusUnauthorized {
+		t.Fatalf("unknown token status = %d, want 401", status)
+	}
+}
+
+func TestNamedTokensNeedFlashScope(t *testing.T) {
+	t.Parallel()
+
+	cfg := loaderconfig.Default()
+	cfg.BaseDir = t.TempDir()
+	cfg.WorkerTimeout = 2 * time.Second
+	cfg.Token = "secret"
+	cfg.Tokens = []authz.Token{
+		{Name: "ci", Token: "ci-secret", Scopes: []authz.Scope{authz.ScopeRead, authz.ScopeFlash}},
+		{Name: "dash", Token: "dash-secret", Scopes: []authz.Scope{authz.ScopeRead}},
+	}
+
+	st := store.New(cfg)
+	hs := history.New(cfg.HistoryPath(), cfg.HistoryLimit)
+	mgr := queue.New(cfg, st, &flasher.FakeFlasher{}, hs)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+
+	ts := httptest.NewServer(New(cfg, mgr).Handler())
+	defer ts.Close()
+
+	status, body := submitJob(t, ts.URL, "alchitry_au", "Blink", "design.bit", testBitstream(), cfg.AuthHeader, "ci-secret")
+	if status != http.StatusAccepted {
+		t.Fatalf("flash token status = %d, body=%s", status, body)
+	}
+	var submitResp map[string]string
+	if err := json.Unmarshal([]byte(body), &submitResp); err != nil {
+		t.Fatalf("decode submit response: %v", err)
+	}
+	rec := waitForTerminalHTTP(t, ts.URL, submitResp["job_id"], cfg.AuthHeader, "dash-secret")
+	if rec.TokenName != "ci" {
+		t.Fatalf("token_name = %q, want ci", rec.TokenName)
+	}
+
+	if status, body := submitJob(t, ts.URL, "alchitry_au", "Blink", "design.bit", testBitstream(), cfg.AuthHeader, "dash-secret"); status != http.StatusForbidden {
+		t.Fatalf("read token status = %d, want 403, body=%s", status, body)
+	}
+	if status, _ := submitJob(t, ts.URL, "alchitry_au", "Blink", "design.bit", testBitstream(), cfg.AuthHeader, "guess"); status != http.StatusUnauthorized {
+		t.Fatalf("unknown token status = %d, want 401", status)
+	}
+}
+
+func TestBatchFlashesEveryBoard(t *testing.T) {
+	t.Parallel()
+
+	cfg := loaderconfig.Default()
+	cfg.BaseDir = t.TempDir()
+	cfg.WorkerTimeout = 2 * time.Second
+	cfg.BoardTags = map[string][]string{"alchitry_au": {"bench"}, "arty": {"bench"}, "basys3": {"spare"}}
+
+	st := store.New(cfg)
+	hs := history.New(cfg.HistoryPath(), cfg.HistoryLimit)
+	mgr := queue.New(cfg, st, &flasher.FakeFlasher{}, hs)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+
+	ts := httptest.NewServer(New(cfg, mgr).Handler())
+	defer ts.Close()
+
+	bitPath := filepath.Join(t.TempDir(), "design.bit")
+	if err := os.WriteFile(bitPath, testBitstream(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := &client.HTTPClient{BaseURL: ts.URL}
+	submitted, err := c.SubmitFlashBatch(ctx, client.SubmitRequest{Board: "all-tagged:bench", DesignName: "Blink", BitstreamPath: bitPath}, nil)
+	if err != nil {
+		t.Fatalf("SubmitFlashBatch() error: %v", err)
+	}
+	if len(submitted.Jobs) != 2 || submitted.Jobs[0].Board != "alchitry_au" || submitted.Jobs[1].Board != "arty" {
+		t.Fatalf("batch jobs = %+v", submitted.Jobs)
+	}
+	b, err := c.WaitForBatch(ctx, submitted.ID, 10*time.Millisecond, nil)
+	if err != nil {
+		t.Fatalf("WaitForBatch() error: %v", err)
+	}
+	if b.State != job.StateSucceeded || b.Counts[job.StateSucceeded] != 2 {
+		t.Fatalf("batch = %+v", b)
+	}
+	for _, rec := range b.Jobs {
+		if rec.BatchID != b.ID || rec.BitstreamSHA256 != b.Jobs[0].BitstreamSHA256 {
+			t.Fatalf("job %s: batch_id=%q sha=%q", rec.ID, rec.BatchID, rec.BitstreamSHA256)
+		}
+	}
+
+	for name, fields := range map[string]map[string]string{
+		"listed twice":  {"boards": "arty, ARTY", "design_name": "Blink"},
+		"no such tag":   {"board": "all-tagged:lab", "design_name": "Blink"},
+		"one device":    {"boards": "arty,basys3", "design_name": "Blink", "device": "serial:FT1"},
+		"board and set": {"board": "arty", "boards": "basys3", "design_name": "Blink"},
+	} {
+		if status, body := submitJobFields(t, ts.URL, fields, "design.bit", testBitstream(), "", ""); status != http.StatusBadRequest {
+			t.Fatalf("%s: status = %d, want 400, body=%s", name, status, body)
+		}
+	}
+
+	resp, err := http.Get(ts.URL + "/v1/batches/nope")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("unknown batch status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestBitstreamDownloadHonoursBoardScope(t *testing.T) {
+	t.Parallel()
+
+	cfg := loaderconfig.Default()
+	cfg.BaseDir = t.TempDir()
+	cfg.WorkerTimeout = 2 * time.Second
+	cfg.Token = "teacher"
+	cfg.ScopedTokens = []loaderconfig.ScopedToken{{Token: "student-3", Tags: []string{"bench-3"}}}
+	cfg.BoardTags = map[string][]string{"alchitry_au": {"bench-3"}}
+
+	st := store.New(cfg)
+	hs := history.New(cfg.HistoryPath(), cfg.HistoryLimit)
+	mgr := queue.New(cfg, st, &flasher.FakeFlasher{}, hs)
+	ts := httptest.NewServer(New(cfg, mgr).Handler())
+	defer ts.Close()
+
+	download := func(jobID, token string) (int, []byte) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, ts.URL+"/v1/jobs/"+jobID+"/bitstream", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(cfg.AuthHeader, token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		raw, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, raw
+	}
+	jobFor := func(board string) string {
+		t.Helper()
+		status, body := submitJob(t, ts.URL, board, "Blink", "blink.bit", testBitstream(), cfg.AuthHeader, "teacher")
+		if status != http.StatusAccepted {
+			t.Fatalf("submit %s status = %d, body=%s", board, status, body)
+		}
+		var resp map[string]string
+		if err := json.Unmarshal([]byte(body), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp["job_id"]
+	}
+
+	auJob, artyJob := jobFor("alchitry_au"), jobFor("arty")
+	if status, raw := download(auJob, "student-3"); status != http.StatusOK || !bytes.Equal(raw, testBitstream()) {
+		t.Fatalf("own bench download status = %d, %d bytes", status, len(raw))
+	}
+	if status, _ := download(artyJob, "student-3"); status != http.StatusForbidden {
+		t.Fatalf("other bench download status = %d, want 403", status)
+	}
+	if status, _ := download(artyJob, "teacher"); status != http.StatusOK {
+		t.Fatalf("full-access download status = %d, want 200", status)
+	}
+	if status, _ := download("missing", "teacher"); status != http.StatusNotFound {
+		t.Fatalf("unknown job status = %d, want 404", status)
+	}
+}
+
 func TestBoardAllowlistGuard(t *testing.T) {
 	t.Parallel()
 
@@ -218,12 +551,12 @@ func TestBoardAllowlistGuard(t *testing.T) {
 	ts := httptest.NewServer(api.Handler())
 	defer ts.Close()
 
-	status, _ := submitJob(t, ts.URL, "other_board", "Blink", "design.bit", []byte("bitstream"), "", "")
+	status, _ := submitJob(t, ts.URL, "other_board", "Blink", "design.bit", testBitstream(), "", "")
 	if status != http.StatusBadRequest {
 		t.Fatalf("status = %d, want %d", status, http.StatusBadRequest)
 	}
 
-	status, body := submitJob(t, ts.URL, "alchitry_au", "Blink", "design.bit", []byte("bitstream"), "", "")
+	status, body := submitJob(t, ts.URL, "alchitry_au", "Blink", "design.bit", testBitstream(), "", "")
 	if status != http.StatusAccepted {
 		t.Fatalf("status = %d, want %d", status, http.StatusAccepted)
 	}
@@ -256,7 +589,7 @@ func TestListJobsAndReflash(t *testing.T) {
 	ts := httptest.NewServer(api.Handler())
 	defer ts.Close()
 
-	status, body := submitJob(t, ts.URL, "alchitry_au", "Blink", "design.bit", []byte("bitstream"), "", "")
+	status, body := submitJob(t, ts.URL, "alchitry_au", "Blink", "design.bit", testBitstream(), "", "")
 	if status != http.StatusAccepted {
 		t.Fatalf("submit status = %d, body=%s", status, body)
 	}
@@ -338,16 +671,434 @@ func TestListJobsAndReflash(t *testing.T) {
 	}
 }
 
+func TestKioskModeOffersOnlyGoldenDesigns(t *testing.T) {
+	t.Parallel()
+
+	cfg := loaderconfig.Default()
+	cfg.BaseDir = t.TempDir()
+	cfg.WorkerTimeout = 2 * time.Second
+
+	st := store.New(cfg)
+	hs := history.New(cfg.HistoryPath(), cfg.HistoryLimit)
+	mgr := queue.New(cfg, st, &flasher.FakeFlasher{}, hs)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+
+	// Seed the library through an unlocked server sharing the manager.
+	open := httptest.NewServer(New(cfg, mgr).Handler())
+	defer open.Close()
+	jobIDs := map[string]string{}
+	for _, design := range []string{"Blink", "Scratch"} {
+		status, body := submitJob(t, open.URL, "bench-3", design, "design.bit", append(testBitstream(), design...), "", "")
+		if status != http.StatusAccepted {
+			t.Fatalf("submit status = %d, body=%s", status, body)
+		}
+		var resp map[string]string
+		if err := json.Unmarshal([]byte(body), &resp); err != nil {
+			t.Fatalf("decode submit response: %v", err)
+		}
+		jobIDs[design] = resp["job_id"]
+		_ = waitForTerminalHTTP(t, open.URL, resp["job_id"], "", "")
+	}
+
+	kioskCfg := cfg
+	kioskCfg.Kiosk = true
+	kioskCfg.GoldenDesigns = []loaderconfig.GoldenDesign{{Board: "bench-3", Design: "Blink"}}
+	ts := httptest.NewServer(New(kioskCfg, mgr).Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/v1/kiosk")
+	if err != nil {
+		t.Fatalf("GET kiosk error: %v", err)
+	}
+	defer resp.Body.Close()
+	var kiosk job.KioskStatus
+	if err := json.NewDecoder(resp.Body).Decode(&kiosk); err != nil {
+		t.Fatalf("decode kiosk payload: %v", err)
+	}
+	if !kiosk.Enabled || len(kiosk.Designs) != 1 || kiosk.Designs[0].ID != jobIDs["Blink"] {
+		t.Fatalf("unexpected kiosk payload: %+v", kiosk)
+	}
+
+	if status, body := submitJob(t, ts.URL, "bench-3", "Upload", "design.bit", []byte("x"), "", ""); status != http.StatusForbidden {
+		t.Fatalf("kiosk submit status = %d, body=%s", status, body)
+	}
+	scratch, err := http.Post(ts.URL+"/v1/jobs/"+jobIDs["Scratch"]+"/reflash", "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST reflash error: %v", err)
+	}
+	scratch.Body.Close()
+	if scratch.StatusCode != http.StatusForbidden {
+		t.Fatalf("non-golden reflash status = %d, want 403", scratch.StatusCode)
+	}
+	golden, err := http.Post(ts.URL+"/v1/jobs/"+jobIDs["Blink"]+"/reflash", "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST reflash error: %v", err)
+	}
+	golden.Body.Close()
+	if golden.StatusCode != http.StatusAccepted {
+		t.Fatalf("golden reflash status = %d, want 202", golden.StatusCode)
+	}
+}
+
+func TestSubmit_RejectsInvalidBitstreams(t *testing.T) {
+	t.Parallel()
+
+	cfg := loaderconfig.Default()
+	cfg.BaseDir = t.TempDir()
+
+	st := store.New(cfg)
+	hs := history.New(cfg.HistoryPath(), cfg.HistoryLimit)
+	mgr := queue.New(cfg, st, &flasher.FakeFlasher{}, hs)
+	ts := httptest.NewServer(New(cfg, mgr).Handler())
+	defer ts.Close()
+
+	for name, tc := range map[string]struct {
+		board string
+		data  []byte
+		want  string
+	}{
+		"text":         {"alchitry_au", []byte("see https://example.com/design.bit\n"), "looks like text"},
+		"wrong vendor": {"ulx3s", testBitstream(), "cannot program ulx3s"},
+	} {
+		status, body := submitJob(t, ts.URL, tc.board, "Blink", "design.bit", tc.data, "", "")
+		if status != http.StatusBadRequest || !strings.Contains(body, tc.want) {
+			t.Fatalf("%s: status = %d body=%s, want 400 mentioning %q", name, status, body, tc.want)
+		}
+	}
+	if jobs := mgr.ListJobs(10); len(jobs) != 0 {
+		t.Fatalf("expected rejected uploads not to be queued, got %d jobs", len(jobs))
+	}
+
+	status, body := submitJob(t, ts.URL, "alchitry_au", "Blink", "design.bit", testBitstream(), "", "")
+	if status != http.StatusAccepted {
+		t.Fatalf("valid bitstream status = %d, body=%s", status, body)
+	}
+	var resp map[string]string
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if rec, ok := mgr.Get(resp["job_id"]); !ok || rec.BitstreamFormat != "xilinx" {
+		t.Fatalf("expected job to record the bitstream format, got %+v", rec)
+	}
+}
+
+func TestSubmit_OversizedBitstreamReportsLimit(t *testing.T) {
+	t.Parallel()
+
+	cfg := loaderconfig.Default()
+	cfg.BaseDir = t.TempDir()
+	cfg.MaxBitstreamBytes = 4 << 10
+
+	st := store.New(cfg)
+	hs := history.New(cfg.HistoryPath(), cfg.HistoryLimit)
+	mgr := queue.New(cfg, st, &flasher.FakeFlasher{}, hs)
+	ts := httptest.NewServer(New(cfg, mgr).Handler())
+	defer ts.Close()
+
+	status, body := submitJob(t, ts.URL, "alchitry_au", "Blink", "design.bit", bytes.Repeat([]byte{0xff}, 8<<10), "", "")
+	if status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("submit status = %d, want 413; body=%s", status, body)
+	}
+	var payload struct {
+		Error      string `json:"error"`
+		Upload     string `json:"upload"`
+		LimitBytes int64  `json:"limit_bytes"`
+	}
+	if err := json.Unmarshal([]byte(body), &payload); err != nil {
+		t.Fatalf("decode 413 body: %v", err)
+	}
+	if payload.Upload != "bitstream" || payload.LimitBytes != cfg.MaxBitstreamBytes || !strings.Contains(payload.Error, "4096 byte limit") {
+		t.Fatalf("unexpected 413 body: %+v", payload)
+	}
+}
+
+func TestSubmit_DryRunSkipsProgramming(t *testing.T) {
+	t.Parallel()
+
+	cfg := loaderconfig.Default()
+	cfg.BaseDir = t.TempDir()
+	cfg.WorkerTimeout = 2 * time.Second
+
+	st := store.New(cfg)
+	hs := history.New(cfg.HistoryPath(), cfg.HistoryLimit)
+	mgr := queue.New(cfg, st, &flasher.FakeFlasher{}, hs)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	ts := httptest.NewServer(New(cfg, mgr).Handler())
+	defer ts.Close()
+
+	status, body := submitJobFields(t, ts.URL, map[string]string{"board": "alchitry_au", "design_name": "Blink", "dry_run": "maybe"}, "design.bit", testBitstream(), "", "")
+	if status != http.StatusBadRequest || !strings.Contains(body, "dry_run") {
+		t.Fatalf("invalid dry_run status = %d body=%s, want 400", status, body)
+	}
+
+	status, body = submitJobFields(t, ts.URL, map[string]string{"board": "alchitry_au", "design_name": "Blink", "dry_run": "1"}, "design.bit", testBitstream(), "", "")
+	if status != http.StatusAccepted {
+		t.Fatalf("submit status = %d, body=%s", status, body)
+	}
+	var resp map[string]string
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+	final := waitForTerminalHTTP(t, ts.URL, resp["job_id"], "", "")
+	if final.State != job.StateSucceeded || !final.DryRun || !strings.Contains(final.Message, "dry run") {
+		t.Fatalf("unexpected dry run record: %+v", final)
+	}
+
+	consoleRaw, err := mgr.ReadConsoleLog(final.ID)
+	if err != nil || !strings.Contains(string(consoleRaw), "dry_run=true") {
+		t.Fatalf("console log = %q, %v", consoleRaw, err)
+	}
+	if recent, err := mgr.ListRecentDesigns(5); err != nil || len(recent) != 0 {
+		t.Fatalf("expected dry runs to stay out of recent designs, got %v, %v", recent, err)
+	}
+}
+
+func TestSubmit_MonitorNeedsConfiguredSerialPort(t *testing.T) {
+	t.Parallel()
+
+	cfg := loaderconfig.Default()
+	cfg.BaseDir = t.TempDir()
+	cfg.WorkerTimeout = 2 * time.Second
+	cfg.SerialPorts = []loaderconfig.SerialPort{{Board: "arty", Port: "/dev/ttyUSB1", Baud: 115200}}
+
+	st := store.New(cfg)
+	hs := history.New(cfg.HistoryPath(), cfg.HistoryLimit)
+	mgr := queue.New(cfg, st, &flasher.FakeFlasher{}, hs)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	ts := httptest.NewServer(New(cfg, mgr).Handler())
+	defer ts.Close()
+
+	status, body := submitJobFields(t, ts.URL, map[string]string{"board": "alchitry_au", "design_name": "Blink", "monitor": "1"}, "design.bit", testBitstream(), "", "")
+	if status != http.StatusBadRequest || !strings.Contains(body, "no serial port") {
+		t.Fatalf("monitor without a port status = %d body=%s, want 400", status, body)
+	}
+	status, body = submitJobFields(t, ts.URL, map[string]string{"board": "alchitry_au", "design_name": "Blink", "baud": "9600"}, "design.bit", testBitstream(), "", "")
+	if status != http.StatusBadRequest || !strings.Contains(body, "baud") {
+		t.Fatalf("baud without monitor status = %d body=%s, want 400", status, body)
+	}
+
+	status, body = submitJobFields(t, ts.URL, map[string]string{"board": "alchitry_au", "design_name": "Blink"}, "design.bit", testBitstream(), "", "")
+	if status != http.StatusAccepted {
+		t.Fatalf("submit status = %d, body=%s", status, body)
+	}
+	var resp map[string]string
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+	serialResp, err := http.Get(ts.URL + "/v1/jobs/" + resp["job_id"] + "/serial")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serialResp.Body.Close()
+	if serialResp.StatusCode != http.StatusNotFound {
+		t.Fatalf("serial of a job without a monitor status = %d, want 404", serialResp.StatusCode)
+	}
+}
+
+func TestSubmit_TargetsDeviceAndReflashKeepsIt(t *testing.T) {
+	t.Parallel()
+
+	cfg := loaderconfig.Default()
+	cfg.BaseDir = t.TempDir()
+	cfg.WorkerTimeout = 2 * time.Second
+
+	st := store.New(cfg)
+	hs := history.New(cfg.HistoryPath(), cfg.HistoryLimit)
+	mgr := queue.New(cfg, st, &flasher.FakeFlasher{}, hs)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	ts := httptest.NewServer(New(cfg, mgr).Handler())
+	defer ts.Close()
+
+	for _, fields := range []map[string]string{
+		{"device": "usb:1"},
+		{"device": "serial:bad serial"},
+		{"device": "busdev:1"},
+		{"cable": "ft2232; rm"},
+		{"ftdi_index": "first"},
+		{"ftdi_index": "-1"},
+	} {
+		fields["board"], fields["design_name"] = "alchitry_au", "Blink"
+		status, body := submitJobFields(t, ts.URL, fields, "design.bit", testBitstream(), "", "")
+		if status != http.StatusBadRequest {
+			t.Fatalf("fields %v: status = %d body=%s, want 400", fields, status, body)
+		}
+	}
+
+	status, body := submitJobFields(t, ts.URL, map[string]string{
+		"board":       "alchitry_au",
+		"design_name": "Blink",
+		"device":      " Serial:210319B0 ",
+		"cable":       "ft2232",
+		"ftdi_index":  "1",
+	}, "design.bit", testBitstream(), "", "")
+	if status != http.StatusAccepted {
+		t.Fatalf("submit status = %d, body=%s", status, body)
+	}
+	var resp map[string]string
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+	final := waitForTerminalHTTP(t, ts.URL, resp["job_id"], "", "")
+	if final.Device != "serial:210319B0" || final.Cable != "ft2232" || final.FTDIIndex == nil || *final.FTDIIndex != 1 {
+		t.Fatalf("unexpected target on record: %+v", final.Target)
+	}
+	raw, err := os.ReadFile(filepath.Join(st.ArtifactsJobDir(final.ID), flasher.InvocationFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(raw), `"--ftdi-serial",`) || !strings.Contains(string(raw), `"--cable-index",`) {
+		t.Fatalf("invocation does not target the device: %s", raw)
+	}
+
+	again, err := mgr.Reflash(context.Background(), final.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Device != final.Device || again.Cable != final.Cable {
+		t.Fatalf("reflash dropped the target: %+v", again.Target)
+	}
+
+	c := &client.HTTPClient{BaseURL: ts.URL}
+	for _, to := range []*client.Retarget{
+		{Target: job.Target{Device: "usb:2"}},
+		{Board: "bad board !"},
+	} {
+		if _, err := c.ReflashJobTo(context.Background(), final.ID, to); err == nil || !strings.Contains(err.Error(), "status=400") {
+			t.Fatalf("reflash to %+v: err = %v, want status=400", to, err)
+		}
+	}
+	movedID, err := c.ReflashJobTo(context.Background(), final.ID, &client.Retarget{Board: "alchitry_au_plus", Target: job.Target{Device: "serial:FT5YJ1BZ"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved := waitForTerminalHTTP(t, ts.URL, movedID, "", "")
+	if moved.Board != "alchitry_au_plus" || moved.Device != "serial:FT5YJ1BZ" || moved.Cable != "" || moved.FTDIIndex != nil {
+		t.Fatalf("retargeted reflash = board %q target %+v", moved.Board, moved.Target)
+	}
+}
+
+func TestSubmit_FlashModeWritesSPIFlashAndHistory(t *testing.T) {
+	t.Parallel()
+
+	cfg := loaderconfig.Default()
+	cfg.BaseDir = t.TempDir()
+	cfg.WorkerTimeout = 2 * time.Second
+
+	st := store.New(cfg)
+	hs := history.New(cfg.HistoryPath(), cfg.HistoryLimit)
+	mgr := queue.New(cfg, st, &flasher.FakeFlasher{}, hs)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	ts := httptest.NewServer(New(cfg, mgr).Handler())
+	defer ts.Close()
+
+	for _, fields := range []map[string]string{
+		{"mode": "eeprom"},
+		{"mode": "flash", "flash_offset": "1MiB"},
+		{"mode": "flash", "flash_offset": "0x100000000"},
+		{"flash_offset": "0x100000"},
+	} {
+		fields["board"], fields["design_name"] = "alchitry_au", "Blink"
+		status, body := submitJobFields(t, ts.URL, fields, "design.bit", testBitstream(), "", "")
+		if status != http.StatusBadRequest {
+			t.Fatalf("fields %v: status = %d body=%s, want 400", fields, status, body)
+		}
+	}
+
+	status, body := submitJobFields(t, ts.URL, map[string]string{
+		"board":        "alchitry_au",
+		"design_name":  "Blink",
+		"mode":         "Flash",
+		"flash_offset": "0x100000",
+	}, "design.bit", testBitstream(), "", "")
+	if status != http.StatusAccepted {
+		t.Fatalf("submit status = %d, body=%s", status, body)
+	}
+	var resp map[string]string
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+	final := waitForTerminalHTTP(t, ts.URL, resp["job_id"], "", "")
+	if final.Mode != job.ModeFlash || final.FlashOffset != 0x100000 {
+		t.Fatalf("record mode = %q offset = %#x", final.Mode, final.FlashOffset)
+	}
+	raw, err := os.ReadFile(filepath.Join(st.ArtifactsJobDir(final.ID), flasher.InvocationFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(raw), `"-f",`) || !strings.Contains(string(raw), `"0x100000",`) {
+		t.Fatalf("invocation does not write flash: %s", raw)
+	}
+	// History is appended just after the record turns terminal.
+	var items []history.Item
+	for deadline := time.Now().Add(2 * time.Second); len(items) == 0 && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if items, err = hs.List(0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(items) != 1 || !items[0].Mode.Persistent() || items[0].FlashOffset != 0x100000 {
+		t.Fatalf("history = %+v, want one persistent design", items)
+	}
+
+	again, err := mgr.Reflash(context.Background(), final.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Mode != job.ModeFlash || again.FlashOffset != final.FlashOffset {
+		t.Fatalf("reflash dropped the mode: %q %#x", again.Mode, again.FlashOffset)
+	}
+
+	status, body = submitJob(t, ts.URL, "alchitry_au", "Blink", "design.bit", testBitstream(), "", "")
+	if status != http.StatusAccepted {
+		t.Fatalf("submit status = %d, body=%s", status, body)
+	}
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if rec := waitForTerminalHTTP(t, ts.URL, resp["job_id"], "", ""); rec.Mode != job.ModeSRAM {
+		t.Fatalf("default mode = %q, want sram", rec.Mode)
+	}
+}
+
+// testBitstream is raw Xilinx configuration data: padding followed by the
+// sync word, enough to pass the upload check.
+func testBitstream() []byte {
+	return append(bytes.Repeat([]byte{0xff}, 16), 0xaa, 0x99, 0x55, 0x66, 0x20, 0x00, 0x00, 0x00)
+}
+
 func submitJob(t *testing.T, baseURL, board, designName, filename string, bitstream []byte, authHeader, token string) (int, string) {
 	t.Helper()
+	return submitJobFields(t, baseURL, map[string]string{"board": board, "design_name": designName}, filename, bitstream, authHeader, token)
+}
+
+func submitJobFields(t *testing.T, baseURL string, fields map[string]string, filename string, bitstream []byte, authHeader, token string) (int, string) {
+	t.Helper()
 
 	var body bytes.Buffer
 	mw := multipart.NewWriter(&body)
-	if err := mw.WriteField("board", board); err != nil {
-		t.Fatalf("WriteField(board) error: %v", err)
-	}
-	if err := mw.WriteField("design_name", designName); err != nil {
-		t.Fatalf("WriteField(design_name) error: %v", err)
+	for name, value := range fields {
+		if err := mw.WriteField(name, value); err != nil {
+			t.Fatalf("WriteField(%s) error: %v", name, err)
+		}
 	}
 	fw, err := mw.CreateFormFile("bitstream", filepath.Base(filename))
 	if err != nil {
@@ -416,3 +1167,127 @@ func waitForTerminalHTTP(t *testing.T, baseURL, jobID, authHeader, token string)
 	t.Fatalf("timeout waiting for terminal job state")
 	return nil
 }
+
+func TestListDevicesKeepsUnpluggedDevices(t *testing.T) {
+	t.Parallel()
+
+	cfg := loaderconfig.Default()
+	cfg.BaseDir = t.TempDir()
+	cfg.DeviceBoards = map[string]string{"FT5YJ1BZ": "alchitry_au"}
+
+	st := store.New(cfg)
+	hs := history.New(cfg.HistoryPath(), cfg.HistoryLimit)
+	fake := &flasher.FakeFlasher{Devices: []job.Device{
+		{Bus: 1, Address: 9, Serial: "210319A28CB6", Product: "Digilent USB Device"},
+		{Bus: 3, Address: 4, Serial: "FT5YJ1BZ", Product: "Alchitry Au"},
+	}}
+	mgr := queue.New(cfg, st, fake, hs)
+	api := New(cfg, mgr)
+	ts := httptest.NewServer(api.Handler())
+	defer ts.Close()
+
+	listDevices := func() []job.Device {
+		t.Helper()
+		resp, err := http.Get(ts.URL + "/v1/devices")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status = %d", resp.StatusCode)
+		}
+		var payload struct {
+			Items []job.Device `json:"items"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+			t.Fatal(err)
+		}
+		return payload.Items
+	}
+
+	first := listDevices()
+	if len(first) != 2 || first[0].Board != "" || first[1].Board != "alchitry_au" {
+		t.Fatalf("unexpected devices: %+v", first)
+	}
+	if !first[1].Connected || first[1].LastSeen.IsZero() {
+		t.Fatalf("expected connected device with last-seen time: %+v", first[1])
+	}
+
+	// Scans are cached briefly, so unplugging shows up once the cache
+	// expires.
+	fake.Devices = fake.Devices[1:]
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		devices := listDevices()
+		if len(devices) == 2 && !devices[1].Connected {
+			if devices[1].Serial != "210319A28CB6" || !devices[0].Connected {
+				t.Fatalf("unexpected devices after unplug: %+v", devices)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("unplugged device still connected: %+v", devices)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+func TestGlobalEventsStreamEveryJob(t *testing.T) {
+	t.Parallel()
+
+	cfg := loaderconfig.Default()
+	cfg.BaseDir = t.TempDir()
+	cfg.WorkerTimeout = 2 * time.Second
+
+	st := store.New(cfg)
+	hs := history.New(cfg.HistoryPath(), cfg.HistoryLimit)
+	mgr := queue.New(cfg, st, &flasher.FakeFlasher{Delay: 50 * time.Millisecond}, hs)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	ts := httptest.NewServer(New(cfg, mgr).Handler())
+	defer ts.Close()
+
+	connected := make(chan struct{}, 1)
+	events := make(chan job.Event, 64)
+	streamCtx, stopStream := context.WithCancel(ctx)
+	defer stopStream()
+	go func() {
+		c := &client.HTTPClient{BaseURL: ts.URL}
+		_ = c.StreamAllEvents(streamCtx, 0, func() { connected <- struct{}{} }, func(ev *job.Event) { events <- *ev })
+	}()
+	select {
+	case <-connected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream did not connect")
+	}
+
+	for _, board := range []string{"alchitry_au", "arty"} {
+		if status, body := submitJob(t, ts.URL, board, "Blink", "design.bit", testBitstream(), "", ""); status != http.StatusAccepted {
+			t.Fatalf("submit status = %d, body=%s", status, body)
+		}
+	}
+
+	var lastSeq int64
+	succeeded := map[string]bool{}
+	timeout := time.After(10 * time.Second)
+	for len(succeeded) < 2 {
+		select {
+		case ev := <-events:
+			if ev.Seq <= lastSeq {
+				t.Fatalf("seq %d after %d; global seq must increase across jobs", ev.Seq, lastSeq)
+			}
+			lastSeq = ev.Seq
+			if ev.Board == "" || ev.DesignName != "Blink" {
+				t.Fatalf("event missing job labels: %+v", ev)
+			}
+			if ev.Type == "succeeded" {
+				succeeded[ev.Board] = true
+			}
+		case <-timeout:
+			t.Fatalf("timed out; succeeded=%v", succeeded)
+		}
+	}
+}
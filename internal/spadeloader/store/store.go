@@ -1,8 +1,6 @@
 package store
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,12 +9,15 @@ import (
 	"sort"
 	"sync"
 
+	"github.com/mblsha/spadeforge/internal/atrest"
+	"github.com/mblsha/spadeforge/internal/checksum"
 	"github.com/mblsha/spadeforge/internal/spadeloader/config"
 	"github.com/mblsha/spadeforge/internal/spadeloader/job"
 )
 
 type Store struct {
 	cfg config.Config
+	key *atrest.Key
 	mu  sync.Mutex
 }
 
@@ -24,6 +25,12 @@ func New(cfg config.Config) *Store {
 	return &Store{cfg: cfg}
 }
 
+// SetKey turns on encryption at rest of stored bitstreams. A nil key turns
+// it off for new files.
+func (s *Store) SetKey(k *atrest.Key) {
+	s.key = k
+}
+
 func (s *Store) EnsureDirs() error {
 	dirs := []string{s.cfg.BaseDir, s.cfg.JobsDir(), s.cfg.WorkDir(), s.cfg.ArtifactsDir(), s.cfg.HistoryDir()}
 	for _, dir := range dirs {
@@ -61,12 +68,54 @@ func (s *Store) WriteBitstream(jobID string, r io.Reader) (sha string, size int6
 		}
 	}()
 
-	h := sha256.New()
-	n, err := io.Copy(io.MultiWriter(f, h), r)
+	w, err := s.key.NewWriter(f)
 	if err != nil {
 		return "", 0, fmt.Errorf("write bitstream file: %w", err)
 	}
-	return hex.EncodeToString(h.Sum(nil)), n, nil
+	sum, n, err := checksum.Copy(w, r)
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("write bitstream file: %w", err)
+	}
+	return sum, n, nil
+}
+
+// OpenBitstream opens the job's bitstream for reading its plaintext. It is
+// an *os.File unless the bitstream is encrypted at rest.
+func (s *Store) OpenBitstream(jobID string) (io.ReadCloser, error) {
+	return s.key.OpenFile(s.RequestBitstreamPath(jobID))
+}
+
+// PlainBitstreamPath returns a path the flash tool can read the job's
+// bitstream from: the stored file, or a decrypted copy in the job's work
+// dir when it is encrypted at rest. The copy is the caller's to remove.
+func (s *Store) PlainBitstreamPath(jobID string) (string, error) {
+	path := s.RequestBitstreamPath(jobID)
+	sealed, err := atrest.IsSealed(path)
+	if err != nil || !sealed {
+		return path, err
+	}
+	src, err := s.key.OpenFile(path)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	plain := filepath.Join(s.WorkJobDir(jobID), "request.bit")
+	dst, err := os.OpenFile(plain, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(dst, src)
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(plain)
+		return "", fmt.Errorf("decrypt bitstream: %w", err)
+	}
+	return plain, nil
 }
 
 func (s *Store) Save(record *job.Record) error {
@@ -4,11 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"os"
 	"sort"
 	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/mblsha/spadeforge/internal/spadeloader/client"
 	"github.com/mblsha/spadeforge/internal/spadeloader/job"
 )
@@ -26,6 +29,17 @@ type Options struct {
 	RefreshInterval      time.Duration
 	ReflashTimeout       time.Duration
 	AdvertisePrimaryAddr string
+	// Kiosk shows only the server's golden designs with a single flash
+	// action, for demo booths and teaching labs.
+	Kiosk bool
+	// NoColor renders plain text without colours or bold.
+	NoColor bool
+	// Plain skips the full-screen view: the list and timeline are printed
+	// line by line to Output and commands are read a line at a time from
+	// Input, which default to stdout and stdin.
+	Plain  bool
+	Input  io.Reader
+	Output io.Writer
 }
 
 func Run(ctx context.Context, opts Options) error {
@@ -33,7 +47,20 @@ func Run(ctx context.Context, opts Options) error {
 	if err != nil {
 		return err
 	}
-	p := tea.NewProgram(model, tea.WithContext(ctx), tea.WithAltScreen())
+	model.ctx = ctx
+	var p *tea.Program
+	if opts.Plain {
+		in, out := opts.Input, opts.Output
+		if in == nil {
+			in = os.Stdin
+		}
+		if out == nil {
+			out = os.Stdout
+		}
+		p = tea.NewProgram(newPlainModel(model, in, out), tea.WithContext(ctx), tea.WithoutRenderer(), tea.WithInput(nil))
+	} else {
+		p = tea.NewProgram(model, tea.WithContext(ctx), tea.WithAltScreen())
+	}
 	_, err = p.Run()
 	if err == nil {
 		return nil
@@ -52,6 +79,13 @@ type refreshTickMsg struct{}
 type jobsLoadedMsg struct {
 	items []job.Record
 	err   error
+	// flashing is the kiosk's in-flight flash job, if any.
+	flashing *job.Record
+}
+
+type devicesLoadedMsg struct {
+	items []job.Device
+	err   error
 }
 
 type reflashResultMsg struct {
@@ -61,17 +95,33 @@ type reflashResultMsg struct {
 
 type model struct {
 	client *client.HTTPClient
+	// ctx ends the event stream when the TUI exits; stream hands its
+	// messages to Update.
+	ctx    context.Context
+	stream chan tea.Msg
 
 	limit                int
 	refreshInterval      time.Duration
 	reflashTimeout       time.Duration
 	advertisePrimaryAddr string
+	kiosk                bool
+	styles               styles
 
 	items []job.Record
 
+	// devices are the attached programmers; devicesErr is set when the
+	// server can't list them.
+	devices          []job.Device
+	devicesErr       string
+	devicesLoaded    bool
+	connectedDevices map[string]bool
+	// retarget is the open device picker for a reflash, if any.
+	retarget *retargetPopup
+
 	selectedIdx int
 	selectedID  string
 	pendingID   string
+	flashingID  string
 
 	width  int
 	height int
@@ -81,8 +131,16 @@ type model struct {
 	status     string
 	lastErr    string
 
-	eventLines    []string
-	lastJobStates map[string]job.State
+	// timeline is fed by GET /v1/events. While paused, scroll counts the
+	// lines hidden below the window and unseen the lines added since;
+	// timelineAdded counts every line ever appended.
+	timeline      []timelineLine
+	timelineAdded int
+	paused        bool
+	scroll        int
+	unseen        int
+	streamLive    bool
+	streamErr     string
 }
 
 func newModel(opts Options) (model, error) {
@@ -101,20 +159,28 @@ func newModel(opts Options) (model, error) {
 	if reflashTimeout <= 0 {
 		reflashTimeout = defaultReflashTimeout
 	}
+	st := styles{}
+	if !opts.NoColor {
+		st = newStyles(lipgloss.DefaultRenderer())
+	}
 	return model{
 		client:               opts.Client,
+		styles:               st,
 		limit:                limit,
 		refreshInterval:      refresh,
 		reflashTimeout:       reflashTimeout,
 		advertisePrimaryAddr: strings.TrimSpace(opts.AdvertisePrimaryAddr),
+		kiosk:                opts.Kiosk,
 		loading:              true,
 		status:               "loading bitstreams...",
-		lastJobStates:        map[string]job.State{},
+		ctx:                  context.Background(),
+		stream:               make(chan tea.Msg, 64),
+		connectedDevices:     map[string]bool{},
 	}, nil
 }
 
 func (m model) Init() tea.Cmd {
-	return tea.Batch(m.fetchJobsCmd(), m.tickCmd())
+	return tea.Batch(m.fetchJobsCmd(), m.fetchDevicesCmd(), m.tickCmd(), m.followEventsCmd(), m.waitForStreamCmd())
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -125,7 +191,27 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 	case refreshTickMsg:
 		m.loading = true
-		return m, tea.Batch(m.fetchJobsCmd(), m.tickCmd())
+		return m, tea.Batch(m.fetchJobsCmd(), m.fetchDevicesCmd(), m.tickCmd())
+	case timelineEventMsg:
+		m.streamLive, m.streamErr = true, ""
+		m.applyEvent(typed.event)
+		return m, m.waitForStreamCmd()
+	case streamStatusMsg:
+		if typed.connected {
+			m.streamLive, m.streamErr = true, ""
+		} else {
+			m.streamLive, m.streamErr = false, typed.err.Error()
+		}
+		return m, m.waitForStreamCmd()
+	case devicesLoadedMsg:
+		if typed.err != nil {
+			m.devicesErr = typed.err.Error()
+			return m, nil
+		}
+		m.devicesErr = ""
+		m.observeDevices(typed.items)
+		m.devices = typed.items
+		return m, nil
 	case jobsLoadedMsg:
 		m.loading = false
 		if typed.err != nil {
@@ -135,7 +221,11 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 		m.lastErr = ""
-		m.observeJobEvents(typed.items)
+		if m.kiosk {
+			m.applyKioskDesigns(typed.items)
+			m.observeFlashing(typed.flashing)
+			return m, nil
+		}
 		m.applyJobs(typed.items)
 		if m.reflashing {
 			m.status = "submitting reflash..."
@@ -152,26 +242,60 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 		m.lastErr = ""
+		if m.kiosk {
+			m.flashingID = typed.newJobID
+			m.addEvent("flash queued: " + shortID(typed.newJobID))
+			m.loading = true
+			return m, m.fetchJobsCmd()
+		}
 		m.pendingID = typed.newJobID
 		m.status = fmt.Sprintf("reflash submitted: %s", typed.newJobID)
 		m.addEvent("reflash submitted: " + shortID(typed.newJobID))
 		m.loading = true
 		return m, m.fetchJobsCmd()
 	case tea.KeyMsg:
+		if m.retarget != nil {
+			return m.updateRetarget(typed.String())
+		}
 		switch typed.String() {
-		case "ctrl+c", "q":
+		case "ctrl+c":
 			return m, tea.Quit
+		case "q":
+			if !m.kiosk {
+				return m, tea.Quit
+			}
+			return m, nil
 		case "k", "up":
 			m.moveSelection(-1)
 			return m, nil
 		case "j", "down":
 			m.moveSelection(1)
 			return m, nil
+		case "p":
+			if !m.kiosk {
+				m.togglePause()
+			}
+			return m, nil
+		case "pgup":
+			if !m.kiosk {
+				m.scrollTimeline(1)
+			}
+			return m, nil
+		case "pgdown":
+			if !m.kiosk {
+				m.scrollTimeline(-1)
+			}
+			return m, nil
 		case "r":
 			m.loading = true
-			return m, m.fetchJobsCmd()
+			return m, tea.Batch(m.fetchJobsCmd(), m.fetchDevicesCmd())
+		case "t":
+			if !m.kiosk && !m.reflashing {
+				m.openRetarget()
+			}
+			return m, nil
 		case "enter":
-			if m.reflashing {
+			if m.reflashing || m.flashingID != "" {
 				return m, nil
 			}
 			selected, ok := m.selected()
@@ -182,24 +306,34 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.status = fmt.Sprintf("reflashing %s | %s ...", selected.Board, selected.DesignName)
 			m.lastErr = ""
 			m.addEvent(fmt.Sprintf("reflash requested for %s | %s", selected.Board, selected.DesignName))
-			return m, m.reflashCmd(selected.ID)
+			return m, m.reflashCmd(selected.ID, nil)
 		}
 	}
 	return m, nil
 }
 
 func (m model) View() string {
+	if m.kiosk {
+		return m.kioskView()
+	}
 	var b strings.Builder
-	b.WriteString(trimToWidth("Spadeloader TUI - Bitstreams (newest first)", m.width))
+	b.WriteString(trimToWidth(m.styles.render(m.styles.title, "Spadeloader TUI - Bitstreams (newest first)"), m.width))
 	b.WriteByte('\n')
 	if strings.TrimSpace(m.advertisePrimaryAddr) != "" {
 		b.WriteString(trimToWidth("Zeroconf primary: "+m.advertisePrimaryAddr, m.width))
 		b.WriteByte('\n')
 	}
-	b.WriteString(trimToWidth("Keys: j/k or arrows move  enter reflash  r refresh  q quit", m.width))
+	b.WriteString(trimToWidth(m.styles.render(m.styles.help, "Keys: j/k or arrows move  enter reflash  t reflash to device  r refresh  p pause timeline  pgup/pgdn scroll  q quit"), m.width))
 	b.WriteByte('\n')
-	b.WriteString(m.statusLine())
+	b.WriteString(trimToWidth(m.statusLine(), m.width))
 	b.WriteString("\n")
+	b.WriteString(trimToWidth(m.devicesLine(), m.width))
+	b.WriteString("\n")
+	if m.retarget != nil {
+		m.writeRetargetSection(&b)
+		m.writeEventSection(&b)
+		return b.String()
+	}
 	if len(m.items) == 0 {
 		if m.loading {
 			b.WriteString("\nLoading...\n")
@@ -215,18 +349,24 @@ func (m model) View() string {
 		rec := m.items[i]
 		prefix := "  "
 		if i == m.selectedIdx {
-			prefix = "> "
+			prefix = m.styles.render(m.styles.selected, "> ")
 		}
 		created := rec.CreatedAt.Local().Format("2006-01-02 15:04:05")
 		line := fmt.Sprintf(
-			"%s%s  %-12s  %-24s  %-10s  %s",
+			"%s%s  %s  %s  %s  %s",
 			prefix,
 			created,
-			rec.Board,
-			rec.DesignName,
-			rec.State,
+			padToWidth(rec.Board, 12),
+			padToWidth(rec.DesignName, 24),
+			m.styles.state(rec.State, 10),
 			shortID(rec.ID),
 		)
+		if rec.Mode.Persistent() {
+			line += "  [flash]"
+		}
+		if rec.State == job.StateFailed && rec.FailureKind != "" {
+			line += "  " + rec.FailureKind
+		}
 		b.WriteString(trimToWidth(line, m.width))
 		b.WriteByte('\n')
 	}
@@ -307,7 +447,7 @@ type rowWindow struct {
 }
 
 func (m model) visibleRows() rowWindow {
-	maxRows := m.height - 6 - m.eventRowsLimit()
+	maxRows := m.height - 7 - m.eventRowsLimit()
 	if maxRows <= 0 {
 		maxRows = 1
 	}
@@ -331,7 +471,7 @@ func (m model) statusLine() string {
 		parts = append(parts, m.status)
 	}
 	if strings.TrimSpace(m.lastErr) != "" {
-		parts = append(parts, "error: "+m.lastErr)
+		parts = append(parts, m.styles.render(m.styles.err, "error: "+m.lastErr))
 	}
 	if len(parts) == 0 {
 		return ""
@@ -339,55 +479,12 @@ func (m model) statusLine() string {
 	return strings.Join(parts, " | ")
 }
 
-func (m *model) addEvent(message string) {
-	trimmed := strings.TrimSpace(message)
-	if trimmed == "" {
-		return
-	}
-	line := fmt.Sprintf("%s  %s", time.Now().Local().Format("15:04:05"), trimmed)
-	m.eventLines = append(m.eventLines, line)
-	if len(m.eventLines) > maxEventLines {
-		m.eventLines = m.eventLines[len(m.eventLines)-maxEventLines:]
-	}
-}
-
-func (m *model) observeJobEvents(items []job.Record) {
-	if m.lastJobStates == nil {
-		m.lastJobStates = map[string]job.State{}
-	}
-	if len(m.lastJobStates) == 0 {
-		next := make(map[string]job.State, len(items))
-		for _, rec := range items {
-			next[rec.ID] = rec.State
-		}
-		m.lastJobStates = next
-		if len(items) > 0 {
-			m.addEvent(fmt.Sprintf("loaded %d jobs from server", len(items)))
-		}
-		return
-	}
-
-	next := make(map[string]job.State, len(items))
-	for _, rec := range items {
-		next[rec.ID] = rec.State
-		prevState, seen := m.lastJobStates[rec.ID]
-		if !seen {
-			m.addEvent(fmt.Sprintf("new job %s %s | %s", shortID(rec.ID), rec.Board, rec.DesignName))
-			continue
-		}
-		if prevState != rec.State {
-			m.addEvent(fmt.Sprintf("job %s %s -> %s", shortID(rec.ID), prevState, rec.State))
-		}
-	}
-	m.lastJobStates = next
-}
-
 func (m model) eventRowsLimit() int {
 	if m.height <= 0 {
 		return maxEventLines
 	}
 	minListRows := 5
-	available := m.height - 6 - minListRows
+	available := m.height - 7 - minListRows
 	if available < 0 {
 		available = 0
 	}
@@ -397,51 +494,43 @@ func (m model) eventRowsLimit() int {
 	return available
 }
 
-func (m model) writeEventSection(b *strings.Builder) {
-	b.WriteString(trimToWidth(strings.Repeat("-", 120), m.width))
-	b.WriteByte('\n')
-	b.WriteString(trimToWidth("Events (latest 25)", m.width))
-	b.WriteByte('\n')
-
-	rows := m.eventRowsLimit()
-	if rows <= 0 {
-		return
-	}
-	if len(m.eventLines) == 0 {
-		b.WriteString(trimToWidth("(no events yet)", m.width))
-		b.WriteByte('\n')
-		return
-	}
-	start := len(m.eventLines) - rows
-	if start < 0 {
-		start = 0
-	}
-	for i := start; i < len(m.eventLines); i++ {
-		b.WriteString(trimToWidth(m.eventLines[i], m.width))
-		b.WriteByte('\n')
-	}
-}
-
 func (m model) fetchJobsCmd() tea.Cmd {
 	return func() tea.Msg {
 		ctx, cancel := context.WithTimeout(context.Background(), m.refreshInterval)
 		defer cancel()
+		if m.kiosk {
+			return m.fetchKiosk(ctx)
+		}
 		items, err := m.client.ListJobs(ctx, m.limit)
 		return jobsLoadedMsg{items: items, err: err}
 	}
 }
 
+// fetchDevicesCmd lists the attached programmers; the kiosk has no use
+// for them.
+func (m model) fetchDevicesCmd() tea.Cmd {
+	if m.kiosk {
+		return nil
+	}
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), m.refreshInterval)
+		defer cancel()
+		items, err := m.client.ListDevices(ctx)
+		return devicesLoadedMsg{items: items, err: err}
+	}
+}
+
 func (m model) tickCmd() tea.Cmd {
 	return tea.Tick(m.refreshInterval, func(_ time.Time) tea.Msg {
 		return refreshTickMsg{}
 	})
 }
 
-func (m model) reflashCmd(sourceJobID string) tea.Cmd {
+func (m model) reflashCmd(sourceJobID string, to *client.Retarget) tea.Cmd {
 	return func() tea.Msg {
 		ctx, cancel := context.WithTimeout(context.Background(), m.reflashTimeout)
 		defer cancel()
-		newID, err := m.client.ReflashJob(ctx, sourceJobID)
+		newID, err := m.client.ReflashJobTo(ctx, sourceJobID, to)
 		return reflashResultMsg{newJobID: newID, err: err}
 	}
 }
@@ -471,18 +560,10 @@ func bitstreamKey(rec job.Record) string {
 	b.WriteString(sha)
 	b.WriteByte('|')
 	b.WriteString(name)
-	return b.String()
-}
-
-func trimToWidth(in string, width int) string {
-	if width <= 0 {
-		return in
-	}
-	if len(in) <= width {
-		return in
+	// A design written to flash keeps its own row, so either kind can be
+	// reflashed from the list.
+	if rec.Mode.Persistent() {
+		b.WriteString("|flash")
 	}
-	if width <= 3 {
-		return in[:width]
-	}
-	return in[:width-3] + "..."
+	return b.String()
 }
@@ -1,10 +1,13 @@
 package tui
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
 	"time"
 
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/mblsha/spadeforge/internal/spadeloader/client"
 	"github.com/mblsha/spadeforge/internal/spadeloader/job"
 )
@@ -145,41 +148,135 @@ func TestAddEventTrimsToMaxLines(t *testing.T) {
 		t.Fatalf("newModel() error: %v", err)
 	}
 
-	for i := 0; i < maxEventLines+5; i++ {
+	for i := 0; i < maxTimelineLines+5; i++ {
 		m.addEvent("event")
 	}
-	if len(m.eventLines) != maxEventLines {
-		t.Fatalf("len(eventLines) = %d, want %d", len(m.eventLines), maxEventLines)
+	if len(m.timeline) != maxTimelineLines {
+		t.Fatalf("len(timeline) = %d, want %d", len(m.timeline), maxTimelineLines)
 	}
 }
 
-func TestObserveJobEventsDetectsStateChange(t *testing.T) {
+func TestApplyEventRedrawsProgressInPlace(t *testing.T) {
 	t.Parallel()
 
-	m, err := newModel(Options{Client: &client.HTTPClient{}})
+	m, err := newModel(Options{Client: &client.HTTPClient{}, NoColor: true})
 	if err != nil {
 		t.Fatalf("newModel() error: %v", err)
 	}
 	now := time.Now().UTC()
+	pct := func(v float64) *float64 { return &v }
+	base := job.Event{JobID: "j1abcdef99", Board: "alchitry_au", DesignName: "Blink", At: now}
 
-	m.observeJobEvents([]job.Record{
-		{ID: "j1", State: job.StateQueued, CreatedAt: now, Board: "alchitry_au", DesignName: "Blink"},
-	})
-	baseEvents := len(m.eventLines)
-	if baseEvents == 0 {
-		t.Fatalf("expected baseline events after initial observe")
+	for _, ev := range []job.Event{
+		{Type: "running", State: job.StateRunning, Step: "flash", Message: "flashing"},
+		{Type: "progress", State: job.StateRunning, Step: "flash", Message: "Load SRAM", Progress: pct(10)},
+		{Type: "progress", State: job.StateRunning, Step: "flash", Message: "Load SRAM", Progress: pct(45)},
+		{Type: "succeeded", State: job.StateSucceeded, Step: "done", Message: "flash succeeded"},
+	} {
+		ev.JobID, ev.Board, ev.DesignName, ev.At = base.JobID, base.Board, base.DesignName, base.At
+		updated, _ := m.Update(timelineEventMsg{event: ev})
+		m = updated.(model)
+	}
+
+	if len(m.timeline) != 3 {
+		t.Fatalf("expected progress to redraw one row, got:\n%s", timelineText(m))
 	}
+	if got := m.timeline[1].text; !strings.Contains(got, "alchitry_au | Blink  flash  45%  Load SRAM") {
+		t.Fatalf("progress row = %q", got)
+	}
+	if got := m.timeline[2].text; !strings.Contains(got, "-> SUCCEEDED  flash succeeded") {
+		t.Fatalf("terminal row = %q", got)
+	}
+	if !m.streamLive || !strings.Contains(m.View(), "Timeline (live)") {
+		t.Fatalf("expected live timeline header, got:\n%s", m.View())
+	}
+}
 
-	m.observeJobEvents([]job.Record{
-		{ID: "j1", State: job.StateRunning, CreatedAt: now, Board: "alchitry_au", DesignName: "Blink"},
+func TestApplyEventShowsFailureGuidance(t *testing.T) {
+	t.Parallel()
+
+	m, err := newModel(Options{Client: &client.HTTPClient{}})
+	if err != nil {
+		t.Fatalf("newModel() error: %v", err)
+	}
+	m.applyEvent(job.Event{
+		JobID: "j1", Type: "failed", State: job.StateFailed, At: time.Now(),
+		FailureKind: job.FailurePermissionDenied, FailureSummary: "unable to open ftdi device",
 	})
-	if len(m.eventLines) <= baseEvents {
-		t.Fatalf("expected event count to grow on state change")
+
+	joined := timelineText(m)
+	if !strings.Contains(joined, "permission_denied: unable to open ftdi device") {
+		t.Fatalf("timeline missing failure kind, got:\n%s", joined)
+	}
+	if !strings.Contains(joined, "hint: "+job.FailureGuidance(job.FailurePermissionDenied)) {
+		t.Fatalf("timeline missing guidance, got:\n%s", joined)
+	}
+}
+
+func TestPausedTimelineHoldsWindow(t *testing.T) {
+	t.Parallel()
+
+	m, err := newModel(Options{Client: &client.HTTPClient{}, NoColor: true})
+	if err != nil {
+		t.Fatalf("newModel() error: %v", err)
+	}
+	m.width, m.height = 100, 20
+	for i := 0; i < 30; i++ {
+		m.addEvent(fmt.Sprintf("old event %02d", i))
+	}
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	m = updated.(model)
+	m.addEvent("fresh event")
+	view := m.View()
+	if strings.Contains(view, "fresh event") || !strings.Contains(view, "old event 29") {
+		t.Fatalf("paused timeline moved:\n%s", view)
+	}
+	if !strings.Contains(view, "paused, 1 new") {
+		t.Fatalf("expected paused header, got:\n%s", view)
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyPgUp})
+	m = updated.(model)
+	if strings.Contains(m.View(), "old event 29") {
+		t.Fatalf("pgup did not scroll back:\n%s", m.View())
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	m = updated.(model)
+	if m.paused || !strings.Contains(m.View(), "fresh event") {
+		t.Fatalf("resume did not jump to the newest events:\n%s", m.View())
 	}
-	last := m.eventLines[len(m.eventLines)-1]
-	if !strings.Contains(last, "QUEUED -> RUNNING") {
-		t.Fatalf("last event %q missing state transition", last)
+}
+
+func TestStreamStatusShowsReconnect(t *testing.T) {
+	t.Parallel()
+
+	m, err := newModel(Options{Client: &client.HTTPClient{}, NoColor: true})
+	if err != nil {
+		t.Fatalf("newModel() error: %v", err)
+	}
+	if !strings.Contains(m.View(), "Timeline (connecting...)") {
+		t.Fatalf("expected connecting header, got:\n%s", m.View())
+	}
+	updated, _ := m.Update(streamStatusMsg{err: errors.New("connection refused")})
+	m = updated.(model)
+	if !strings.Contains(m.View(), "Timeline (reconnecting: connection refused)") {
+		t.Fatalf("expected reconnecting header, got:\n%s", m.View())
+	}
+	updated, _ = m.Update(streamStatusMsg{connected: true})
+	m = updated.(model)
+	if !strings.Contains(m.View(), "Timeline (live)") {
+		t.Fatalf("expected live header, got:\n%s", m.View())
+	}
+}
+
+func timelineText(m model) string {
+	lines := make([]string, len(m.timeline))
+	for i, line := range m.timeline {
+		lines[i] = line.text
 	}
+	return strings.Join(lines, "\n")
 }
 
 func TestViewShowsZeroconfPrimaryInHeader(t *testing.T) {
@@ -198,3 +295,179 @@ func TestViewShowsZeroconfPrimaryInHeader(t *testing.T) {
 		t.Fatalf("view missing zeroconf primary header, got:\n%s", view)
 	}
 }
+
+func TestKioskKeepsSelectionAcrossReflashAndIgnoresQuit(t *testing.T) {
+	t.Parallel()
+
+	m, err := newModel(Options{Client: &client.HTTPClient{}, Kiosk: true})
+	if err != nil {
+		t.Fatalf("newModel() error: %v", err)
+	}
+	m.applyKioskDesigns([]job.Record{
+		{ID: "a1", Board: "bench-1", DesignName: "Blink"},
+		{ID: "b1", Board: "bench-2", DesignName: "UART"},
+	})
+	m.moveSelection(1)
+
+	// A successful flash replaces the golden entry's job ID.
+	m.applyKioskDesigns([]job.Record{
+		{ID: "a1", Board: "bench-1", DesignName: "Blink"},
+		{ID: "b2", Board: "bench-2", DesignName: "UART"},
+	})
+	if m.selectedIdx != 1 || m.selectedID != "b2" {
+		t.Fatalf("selection = %d/%q, want 1/b2", m.selectedIdx, m.selectedID)
+	}
+
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")}); cmd != nil {
+		t.Fatalf("expected q to be ignored in kiosk mode")
+	}
+	view := m.View()
+	if !strings.Contains(view, "Golden designs") || strings.Contains(view, "q quit") {
+		t.Fatalf("unexpected kiosk view:\n%s", view)
+	}
+}
+
+func TestObserveDevicesLogsPlugAndUnplug(t *testing.T) {
+	t.Parallel()
+
+	m, err := newModel(Options{Client: &client.HTTPClient{}})
+	if err != nil {
+		t.Fatalf("newModel() error: %v", err)
+	}
+	au := job.Device{Bus: 3, Address: 4, Serial: "FT5YJ1BZ", Board: "alchitry_au", Connected: true}
+	other := job.Device{Bus: 1, Address: 2, Connected: true}
+
+	updated, _ := m.Update(devicesLoadedMsg{items: []job.Device{au}})
+	m = updated.(model)
+	if len(m.timeline) != 0 {
+		t.Fatalf("first scan should not log events, got %v", timelineText(m))
+	}
+	if !strings.Contains(m.View(), "Devices: alchitry_au serial:FT5YJ1BZ") {
+		t.Fatalf("expected devices line, got:\n%s", m.View())
+	}
+
+	au.Connected = false
+	updated, _ = m.Update(devicesLoadedMsg{items: []job.Device{other, au}})
+	m = updated.(model)
+	events := timelineText(m)
+	if !strings.Contains(events, "device connected: busdev:1:2") || !strings.Contains(events, "device disconnected: alchitry_au (serial:FT5YJ1BZ)") {
+		t.Fatalf("unexpected events: %v", events)
+	}
+	if !strings.Contains(m.View(), "Devices: ? busdev:1:2") {
+		t.Fatalf("expected unknown board marker, got:\n%s", m.View())
+	}
+}
+
+func TestRetargetPopupReflashesToPickedDevice(t *testing.T) {
+	t.Parallel()
+
+	m, err := newModel(Options{Client: &client.HTTPClient{}})
+	if err != nil {
+		t.Fatalf("newModel() error: %v", err)
+	}
+	m.height = 30
+	m.applyJobs([]job.Record{{
+		ID: "a1", Board: "alchitry_au", DesignName: "Blink", CreatedAt: time.Now(),
+		Target: job.Target{Device: "serial:OLD", Cable: "ft2232"},
+	}})
+	m.devices = []job.Device{
+		{Bus: 1, Address: 2, Serial: "OLD", Board: "alchitry_au", Connected: true},
+		{Bus: 1, Address: 3, Serial: "GONE", Board: "alchitry_au", Connected: false},
+		{Bus: 1, Address: 4, Serial: "NEW", Board: "alchitry_au_plus", Product: "Alchitry Au+", Connected: true},
+	}
+
+	press := func(key string) tea.Cmd {
+		t.Helper()
+		msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+		if key == "enter" {
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		}
+		updated, cmd := m.Update(msg)
+		m = updated.(model)
+		return cmd
+	}
+
+	press("t")
+	if m.retarget == nil || m.retarget.idx != 0 || len(m.retarget.devices) != 2 {
+		t.Fatalf("popup = %+v, want the two connected devices starting on the current one", m.retarget)
+	}
+	view := m.View()
+	if !strings.Contains(view, "Reflash alchitry_au | Blink to:") || !strings.Contains(view, "serial:OLD") || !strings.Contains(view, "(current)") || strings.Contains(view, "serial:GONE") {
+		t.Fatalf("unexpected popup view:\n%s", view)
+	}
+
+	press("j")
+	if cmd := press("enter"); cmd == nil {
+		t.Fatalf("expected a reflash command")
+	}
+	if m.retarget != nil || !m.reflashing || !strings.Contains(m.status, "to serial:NEW") {
+		t.Fatalf("popup %v reflashing %t status %q", m.retarget, m.reflashing, m.status)
+	}
+
+	to := retargetTo(m.items[0], m.devices[2])
+	if to.Board != "alchitry_au_plus" || to.Target.Device != "serial:NEW" || to.Target.Cable != "" {
+		t.Fatalf("retarget to another board = %+v", to)
+	}
+	to = retargetTo(m.items[0], job.Device{Bus: 2, Address: 5, Connected: true})
+	if to.Board != "" || to.Target.Device != "busdev:2:5" || to.Target.Cable != "ft2232" {
+		t.Fatalf("retarget to an unknown board = %+v", to)
+	}
+}
+
+func TestPlainModePrintsLinesAndTakesNumberedCommands(t *testing.T) {
+	t.Parallel()
+
+	m, err := newModel(Options{Client: &client.HTTPClient{}})
+	if err != nil {
+		t.Fatalf("newModel() error: %v", err)
+	}
+	var out strings.Builder
+	p := newPlainModel(m, nil, &out)
+	update := func(msg tea.Msg) tea.Cmd {
+		t.Helper()
+		updated, cmd := p.Update(msg)
+		p = updated.(plainModel)
+		return cmd
+	}
+
+	now := time.Now()
+	update(jobsLoadedMsg{items: []job.Record{
+		{ID: "a1", Board: "alchitry_au", DesignName: "Blink", State: job.StateSucceeded, CreatedAt: now},
+		{ID: "b2", Board: "arty", DesignName: "Uart", State: job.StateFailed, CreatedAt: now.Add(-time.Minute), Mode: job.ModeFlash},
+	}})
+	update(timelineEventMsg{event: job.Event{JobID: "a1", Board: "alchitry_au", DesignName: "Blink", Type: "progress", Step: "flash", Progress: ptrFloat(10), At: now}})
+	update(timelineEventMsg{event: job.Event{JobID: "a1", Board: "alchitry_au", DesignName: "Blink", Type: "progress", Step: "flash", Progress: ptrFloat(60), At: now}})
+	first := out.String()
+	for _, want := range []string{"Bitstreams (newest first):", "1. alchitry_au | Blink, succeeded", "2. arty | Uart, failed", "written to flash", "status: loaded 2 bitstream entries", "Timeline (live)", "flash  10%"} {
+		if !strings.Contains(first, want) {
+			t.Fatalf("missing %q in plain output:\n%s", want, first)
+		}
+	}
+	if strings.Contains(first, "60%") || strings.Contains(first, "\x1b") {
+		t.Fatalf("plain output redrew a line or carried escapes:\n%s", first)
+	}
+
+	// Unchanged entries are not listed again.
+	update(jobsLoadedMsg{items: p.items})
+	if strings.Count(out.String(), "Bitstreams") != 1 {
+		t.Fatalf("list printed again without changes:\n%s", out.String())
+	}
+
+	if cmd := update(plainLineMsg("2")); cmd == nil {
+		t.Fatalf("expected a reflash command")
+	}
+	if !p.reflashing || p.selectedID != "b2" || !strings.Contains(out.String(), "reflash requested for arty | Uart") {
+		t.Fatalf("reflashing %t selected %q:\n%s", p.reflashing, p.selectedID, out.String())
+	}
+	update(plainLineMsg("9"))
+	if !strings.Contains(out.String(), `unknown command "9"`) {
+		t.Fatalf("expected unknown command note:\n%s", out.String())
+	}
+	if p.View() != "" {
+		t.Fatalf("plain mode must not render a view")
+	}
+}
+
+func ptrFloat(v float64) *float64 {
+	return &v
+}
@@ -0,0 +1,65 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/mblsha/spadeforge/internal/spadeloader/job"
+)
+
+// devicesLine summarises the connected programmers, e.g.
+// "Devices: alchitry_au serial:210319B0  ? busdev:1:7".
+func (m model) devicesLine() string {
+	switch {
+	case m.devicesErr != "":
+		return "Devices: unavailable (" + m.devicesErr + ")"
+	case !m.devicesLoaded:
+		return "Devices: scanning..."
+	}
+	var parts []string
+	for _, d := range m.devices {
+		if !d.Connected {
+			continue
+		}
+		board := d.Board
+		if board == "" {
+			board = "?"
+		}
+		parts = append(parts, board+" "+d.Target().Device)
+	}
+	if len(parts) == 0 {
+		return "Devices: none connected"
+	}
+	return "Devices: " + strings.Join(parts, "  ")
+}
+
+// observeDevices logs programmers plugged in or unplugged since the last
+// scan; the first scan only sets the baseline.
+func (m *model) observeDevices(items []job.Device) {
+	next := make(map[string]bool, len(items))
+	for _, d := range items {
+		if !d.Connected {
+			continue
+		}
+		key := d.Key()
+		next[key] = true
+		if m.devicesLoaded && !m.connectedDevices[key] {
+			m.addEvent("device connected: " + deviceLabel(d))
+		}
+	}
+	if m.devicesLoaded {
+		for _, d := range items {
+			if m.connectedDevices[d.Key()] && !next[d.Key()] {
+				m.addEvent("device disconnected: " + deviceLabel(d))
+			}
+		}
+	}
+	m.connectedDevices = next
+	m.devicesLoaded = true
+}
+
+func deviceLabel(d job.Device) string {
+	if d.Board == "" {
+		return d.Target().Device
+	}
+	return d.Board + " (" + d.Target().Device + ")"
+}
@@ -0,0 +1,112 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mblsha/spadeforge/internal/spadeloader/job"
+)
+
+// fetchKiosk loads the golden designs and, while a flash is in flight, its
+// job record.
+func (m model) fetchKiosk(ctx context.Context) jobsLoadedMsg {
+	status, err := m.client.GetKiosk(ctx)
+	if err != nil {
+		return jobsLoadedMsg{err: err}
+	}
+	msg := jobsLoadedMsg{items: status.Designs}
+	if m.flashingID != "" {
+		rec, err := m.client.GetJob(ctx, m.flashingID)
+		if err != nil {
+			return jobsLoadedMsg{err: err}
+		}
+		msg.flashing = rec
+	}
+	return msg
+}
+
+// applyKioskDesigns keeps the server's board/design order and follows the
+// selection by board and design, since each successful flash replaces the
+// golden entry's job ID.
+func (m *model) applyKioskDesigns(items []job.Record) {
+	prevKey := ""
+	if selected, ok := m.selected(); ok {
+		prevKey = goldenKey(selected)
+	}
+	m.items = append([]job.Record(nil), items...)
+	m.selectedIdx = 0
+	m.selectedID = ""
+	for i := range m.items {
+		if goldenKey(m.items[i]) == prevKey {
+			m.selectedIdx = i
+			break
+		}
+	}
+	if len(m.items) > 0 {
+		m.selectedID = m.items[m.selectedIdx].ID
+	}
+	if m.flashingID == "" && !m.reflashing {
+		m.status = fmt.Sprintf("%d golden designs", len(m.items))
+	}
+}
+
+// observeFlashing reports the in-flight flash and clears it once it ends.
+func (m *model) observeFlashing(rec *job.Record) {
+	if rec == nil {
+		return
+	}
+	label := fmt.Sprintf("%s | %s", rec.Board, rec.DesignName)
+	switch rec.State {
+	case job.StateSucceeded:
+		m.status = "flashed " + label
+		m.addEvent("flashed " + label)
+		m.flashingID = ""
+	case job.StateFailed:
+		m.status = "flash failed: " + label
+		m.addEvent(fmt.Sprintf("flash failed %s: %s", label, rec.FailureSummary))
+		if hint := job.FailureGuidance(rec.FailureKind); hint != "" {
+			m.addEvent("hint: " + hint)
+		}
+		m.flashingID = ""
+	default:
+		m.status = fmt.Sprintf("flashing %s (%s)...", label, strings.ToLower(string(rec.State)))
+	}
+}
+
+func (m model) kioskView() string {
+	var b strings.Builder
+	b.WriteString(trimToWidth(m.styles.render(m.styles.title, "Spadeloader Kiosk - Golden designs"), m.width))
+	b.WriteByte('\n')
+	b.WriteString(trimToWidth(m.styles.render(m.styles.help, "Keys: j/k or arrows move  enter flash"), m.width))
+	b.WriteByte('\n')
+	b.WriteString(trimToWidth(m.statusLine(), m.width))
+	b.WriteString("\n")
+	if len(m.items) == 0 {
+		if m.loading {
+			b.WriteString("\nLoading...\n")
+		} else {
+			b.WriteString("\nNo golden designs available.\n")
+		}
+		m.writeEventSection(&b)
+		return b.String()
+	}
+
+	rows := m.visibleRows()
+	for i := rows.start; i < rows.end; i++ {
+		rec := m.items[i]
+		prefix := "  "
+		if i == m.selectedIdx {
+			prefix = m.styles.render(m.styles.selected, "> ")
+		}
+		line := fmt.Sprintf("%s%s  %s  %s", prefix, padToWidth(rec.Board, 12), padToWidth(rec.DesignName, 24), rec.BitstreamName)
+		b.WriteString(trimToWidth(line, m.width))
+		b.WriteByte('\n')
+	}
+	m.writeEventSection(&b)
+	return b.String()
+}
+
+func goldenKey(rec job.Record) string {
+	return rec.Board + "|" + rec.DesignName
+}
@@ -0,0 +1,199 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// plainLineMsg is one line typed in plain mode; plainInputClosedMsg ends
+// the input, after which the timeline is still followed.
+type plainLineMsg string
+
+type plainInputClosedMsg struct{}
+
+// plainModel runs the TUI for --plain: nothing is redrawn. It prints the
+// design list when it changes and every new timeline line, and reads one
+// command per input line, for screen readers, dumb terminals and CI logs.
+type plainModel struct {
+	model
+	out   io.Writer
+	input *bufio.Scanner
+
+	// What has been printed so far.
+	printedList   string
+	printedStatus string
+	printedStream string
+	printedLines  int
+}
+
+func newPlainModel(m model, in io.Reader, out io.Writer) plainModel {
+	// Plain output never carries colour codes.
+	m.styles = styles{}
+	p := plainModel{model: m, out: out}
+	if in != nil {
+		p.input = bufio.NewScanner(in)
+	}
+	return p
+}
+
+func (p plainModel) Init() tea.Cmd {
+	p.printHelp()
+	return tea.Batch(p.model.Init(), p.readLineCmd())
+}
+
+func (p plainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	switch typed := msg.(type) {
+	case plainLineMsg:
+		cmd = tea.Batch(p.command(strings.TrimSpace(string(typed))), p.readLineCmd())
+	case plainInputClosedMsg:
+	default:
+		var updated tea.Model
+		updated, cmd = p.model.Update(msg)
+		p.model = updated.(model)
+	}
+	p.flush()
+	return p, cmd
+}
+
+func (p plainModel) View() string {
+	return ""
+}
+
+// command runs one input line: a list number flashes that entry, l lists,
+// r refreshes and q quits.
+func (p *plainModel) command(line string) tea.Cmd {
+	switch line {
+	case "":
+		return nil
+	case "q", "quit":
+		if p.kiosk {
+			return nil
+		}
+		return tea.Quit
+	case "l", "list":
+		p.printedList = ""
+		return nil
+	case "r", "refresh":
+		return p.press("r")
+	case "h", "help", "?":
+		p.printHelp()
+		return nil
+	}
+	n, err := strconv.Atoi(line)
+	if err != nil || n < 1 || n > len(p.items) {
+		fmt.Fprintf(p.out, "unknown command %q; type h for help\n", line)
+		return nil
+	}
+	p.selectedIdx = n - 1
+	p.selectedID = p.items[n-1].ID
+	return p.press("enter")
+}
+
+// press feeds a key to the wrapped model.
+func (p *plainModel) press(key string) tea.Cmd {
+	msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+	if key == "enter" {
+		msg = tea.KeyMsg{Type: tea.KeyEnter}
+	}
+	updated, cmd := p.model.Update(msg)
+	p.model = updated.(model)
+	return cmd
+}
+
+func (p plainModel) printHelp() {
+	action := "reflash"
+	if p.kiosk {
+		action = "flash"
+	}
+	fmt.Fprintf(p.out, "Commands: <number> %s that entry, l list, r refresh", action)
+	if !p.kiosk {
+		fmt.Fprint(p.out, ", q quit")
+	}
+	fmt.Fprintln(p.out)
+}
+
+// flush prints what changed since the last message: the list when its
+// entries changed, the status line, the event stream's state and timeline
+// lines added since. A progress line redrawn in place is only printed when
+// it first appears.
+func (p *plainModel) flush() {
+	if key := p.itemsKey(); key != p.printedList && !p.loadingFirst() {
+		p.printedList = key
+		p.printList()
+	}
+	if status := p.statusLine(); status != "" && status != p.printedStatus {
+		p.printedStatus = status
+		fmt.Fprintln(p.out, "status: "+status)
+	}
+	if header := p.timelineHeader(); !p.kiosk && header != p.printedStream {
+		p.printedStream = header
+		fmt.Fprintln(p.out, header)
+	}
+	added := p.timelineAdded - p.printedLines
+	if added > len(p.timeline) {
+		added = len(p.timeline)
+	}
+	for _, line := range p.timeline[len(p.timeline)-added:] {
+		fmt.Fprintln(p.out, line.text)
+	}
+	p.printedLines = p.timelineAdded
+}
+
+// loadingFirst holds the list back until the first fetch answers.
+func (p plainModel) loadingFirst() bool {
+	return p.loading && len(p.items) == 0 && p.printedList == ""
+}
+
+func (p plainModel) itemsKey() string {
+	var b strings.Builder
+	b.WriteString("list")
+	for _, rec := range p.items {
+		b.WriteString("|" + rec.ID)
+	}
+	return b.String()
+}
+
+func (p plainModel) printList() {
+	if len(p.items) == 0 {
+		if p.kiosk {
+			fmt.Fprintln(p.out, "No golden designs available.")
+		} else {
+			fmt.Fprintln(p.out, "No bitstreams yet.")
+		}
+		return
+	}
+	if p.kiosk {
+		fmt.Fprintln(p.out, "Golden designs:")
+	} else {
+		fmt.Fprintln(p.out, "Bitstreams (newest first):")
+	}
+	for i, rec := range p.items {
+		line := fmt.Sprintf("%d. %s | %s", i+1, rec.Board, rec.DesignName)
+		if !p.kiosk {
+			line += fmt.Sprintf(", %s, %s, %s", strings.ToLower(string(rec.State)), rec.CreatedAt.Local().Format("2006-01-02 15:04:05"), shortID(rec.ID))
+			if rec.Mode.Persistent() {
+				line += ", written to flash"
+			}
+		}
+		fmt.Fprintln(p.out, line)
+	}
+}
+
+// readLineCmd waits for the next input line.
+func (p plainModel) readLineCmd() tea.Cmd {
+	if p.input == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		if !p.input.Scan() {
+			return plainInputClosedMsg{}
+		}
+		return plainLineMsg(p.input.Text())
+	}
+}
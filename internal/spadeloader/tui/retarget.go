@@ -0,0 +1,120 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mblsha/spadeforge/internal/spadeloader/client"
+	"github.com/mblsha/spadeforge/internal/spadeloader/job"
+)
+
+// retargetPopup picks the connected programmer a reflash goes to, for when
+// the design has moved to another board.
+type retargetPopup struct {
+	source  job.Record
+	devices []job.Device
+	idx     int
+}
+
+// openRetarget opens the popup for the selected design, starting on the
+// programmer it was last flashed through or else a board of the same kind.
+func (m *model) openRetarget() {
+	selected, ok := m.selected()
+	if !ok {
+		return
+	}
+	var devices []job.Device
+	for _, d := range m.devices {
+		if d.Connected {
+			devices = append(devices, d)
+		}
+	}
+	if len(devices) == 0 {
+		m.status = "no connected devices to target"
+		return
+	}
+	popup := &retargetPopup{source: selected, devices: devices, idx: -1}
+	for i, d := range devices {
+		if selected.Device != "" && d.Target().Device == selected.Device {
+			popup.idx = i
+			break
+		}
+		if popup.idx < 0 && d.Board == selected.Board {
+			popup.idx = i
+		}
+	}
+	if popup.idx < 0 {
+		popup.idx = 0
+	}
+	m.retarget = popup
+}
+
+// updateRetarget handles keys while the popup is open: j/k move, enter
+// reflashes onto the highlighted device and esc or q closes it.
+func (m model) updateRetarget(key string) (tea.Model, tea.Cmd) {
+	p := m.retarget
+	switch key {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc", "q", "t":
+		m.retarget = nil
+	case "k", "up":
+		if p.idx > 0 {
+			p.idx--
+		}
+	case "j", "down":
+		if p.idx < len(p.devices)-1 {
+			p.idx++
+		}
+	case "enter":
+		m.retarget = nil
+		if m.reflashing {
+			return m, nil
+		}
+		d := p.devices[p.idx]
+		to := retargetTo(p.source, d)
+		board := defaultText(to.Board, p.source.Board)
+		m.reflashing = true
+		m.lastErr = ""
+		m.status = fmt.Sprintf("reflashing %s | %s to %s ...", board, p.source.DesignName, to.Target.Device)
+		m.addEvent(fmt.Sprintf("reflash requested for %s | %s on %s", p.source.Board, p.source.DesignName, deviceLabel(d)))
+		return m, m.reflashCmd(p.source.ID, to)
+	}
+	return m, nil
+}
+
+// retargetTo reflashes onto d. A device on a known board of another kind
+// changes the board too, and drops the source's cable override, which
+// belonged to the old board.
+func retargetTo(source job.Record, d job.Device) *client.Retarget {
+	to := &client.Retarget{Target: job.Target{Device: d.Target().Device}}
+	if d.Board != "" && d.Board != source.Board {
+		to.Board = d.Board
+		return to
+	}
+	to.Target.Cable = source.Cable
+	return to
+}
+
+func (m model) writeRetargetSection(b *strings.Builder) {
+	p := m.retarget
+	title := fmt.Sprintf("Reflash %s | %s to:", p.source.Board, p.source.DesignName)
+	b.WriteString(trimToWidth(m.styles.render(m.styles.title, title), m.width))
+	b.WriteByte('\n')
+	for i, d := range p.devices {
+		prefix := "  "
+		if i == p.idx {
+			prefix = m.styles.render(m.styles.selected, "> ")
+		}
+		board := defaultText(d.Board, "?")
+		line := fmt.Sprintf("%s%s  %s  %s", prefix, padToWidth(board, 12), padToWidth(d.Target().Device, 24), d.Product)
+		if p.source.Device != "" && d.Target().Device == p.source.Device {
+			line += "  (current)"
+		}
+		b.WriteString(trimToWidth(line, m.width))
+		b.WriteByte('\n')
+	}
+	b.WriteString(trimToWidth(m.styles.render(m.styles.help, "j/k move  enter reflash here  esc cancel"), m.width))
+	b.WriteByte('\n')
+}
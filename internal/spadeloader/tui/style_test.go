@@ -0,0 +1,99 @@
+package tui
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/mblsha/spadeforge/internal/spadeloader/client"
+	"github.com/mblsha/spadeforge/internal/spadeloader/job"
+	"github.com/muesli/termenv"
+)
+
+func TestTrimToWidthCountsCells(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in    string
+		width int
+		want  string
+	}{
+		{in: "blinky", width: 10, want: "blinky"},
+		{in: "blinky_fast", width: 8, want: "blink..."},
+		{in: "点滅テスト回路", width: 9, want: "点滅テ..."},
+		{in: "Zähler-Entwurf", width: 8, want: "Zähle..."},
+		{in: "🚦 traffic", width: 5, want: "🚦..."},
+		{in: "abcdef", width: 2, want: "ab"},
+	}
+	for _, tc := range tests {
+		got := trimToWidth(tc.in, tc.width)
+		if got != tc.want {
+			t.Errorf("trimToWidth(%q, %d) = %q, want %q", tc.in, tc.width, got, tc.want)
+		}
+		if w := ansi.StringWidth(got); w > tc.width {
+			t.Errorf("trimToWidth(%q, %d) is %d cells wide", tc.in, tc.width, w)
+		}
+	}
+}
+
+func TestViewAlignsWideDesignNames(t *testing.T) {
+	t.Parallel()
+
+	m, err := newModel(Options{Client: &client.HTTPClient{}, NoColor: true})
+	if err != nil {
+		t.Fatalf("newModel() error: %v", err)
+	}
+	now := time.Now()
+	m.applyJobs([]job.Record{
+		{ID: "a", Board: "arty", DesignName: "点滅", State: job.StateSucceeded, CreatedAt: now, BitstreamSHA256: "a"},
+		{ID: "b", Board: "arty", DesignName: "blink", State: job.StateFailed, CreatedAt: now.Add(-time.Minute), BitstreamSHA256: "b"},
+	})
+	m.width, m.height = 80, 40
+
+	var cols []int
+	for _, line := range strings.Split(m.View(), "\n") {
+		if i := strings.Index(line, "SUCCEEDED"); i >= 0 {
+			cols = append(cols, ansi.StringWidth(line[:i]))
+		}
+		if i := strings.Index(line, "FAILED"); i >= 0 {
+			cols = append(cols, ansi.StringWidth(line[:i]))
+		}
+		if w := ansi.StringWidth(line); w > m.width {
+			t.Fatalf("line is %d cells wide: %q", w, line)
+		}
+	}
+	if len(cols) != 2 || cols[0] != cols[1] {
+		t.Fatalf("state columns not aligned: %v\n%s", cols, m.View())
+	}
+}
+
+func TestNoColorRendersPlainText(t *testing.T) {
+	t.Parallel()
+
+	records := []job.Record{{ID: "a", Board: "arty", DesignName: "blink", State: job.StateFailed, CreatedAt: time.Now(), BitstreamSHA256: "a"}}
+
+	plain, err := newModel(Options{Client: &client.HTTPClient{}, NoColor: true})
+	if err != nil {
+		t.Fatalf("newModel() error: %v", err)
+	}
+	plain.applyJobs(records)
+	plain.lastErr = "boom"
+	if strings.Contains(plain.View(), "\x1b[") {
+		t.Fatalf("expected no escape codes with NoColor:\n%q", plain.View())
+	}
+
+	r := lipgloss.NewRenderer(io.Discard)
+	r.SetColorProfile(termenv.ANSI)
+	colored := plain
+	colored.styles = newStyles(r)
+	view := colored.View()
+	if !strings.Contains(view, "\x1b[") {
+		t.Fatalf("expected escape codes with colour:\n%q", view)
+	}
+	if ansi.Strip(view) != plain.View() {
+		t.Fatalf("colour changed the text:\n%q\nvs\n%q", ansi.Strip(view), plain.View())
+	}
+}
@@ -0,0 +1,246 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mblsha/spadeforge/internal/spadeloader/job"
+)
+
+const (
+	// maxTimelineLines is the scrollback kept for the paused timeline.
+	maxTimelineLines = 500
+	// streamRetryDelay spaces reconnects while the server is unreachable.
+	streamRetryDelay = 2 * time.Second
+)
+
+// timelineEventMsg carries one event from GET /v1/events.
+type timelineEventMsg struct {
+	event job.Event
+}
+
+// streamStatusMsg reports the event stream connecting, or failing with err
+// before a reconnect.
+type streamStatusMsg struct {
+	connected bool
+	err       error
+}
+
+// timelineLine is one timeline row. Progress rows carry a key so the next
+// progress event for the same job and step redraws them in place.
+type timelineLine struct {
+	key  string
+	text string
+}
+
+// followEventsCmd follows the server's event stream until the TUI exits,
+// reconnecting from the last event after errors. Messages reach Update
+// through m.stream and waitForStreamCmd.
+func (m model) followEventsCmd() tea.Cmd {
+	if m.kiosk {
+		return nil
+	}
+	return func() tea.Msg {
+		var since int64
+		for {
+			err := m.client.StreamAllEvents(m.ctx, since,
+				func() { m.send(streamStatusMsg{connected: true}) },
+				func(ev *job.Event) {
+					since = ev.Seq
+					m.send(timelineEventMsg{event: *ev})
+				})
+			if m.ctx.Err() != nil {
+				return nil
+			}
+			if err == nil {
+				err = fmt.Errorf("stream closed")
+			}
+			m.send(streamStatusMsg{err: err})
+			select {
+			case <-m.ctx.Done():
+				return nil
+			case <-time.After(streamRetryDelay):
+			}
+		}
+	}
+}
+
+func (m model) send(msg tea.Msg) {
+	select {
+	case m.stream <- msg:
+	case <-m.ctx.Done():
+	}
+}
+
+func (m model) waitForStreamCmd() tea.Cmd {
+	if m.kiosk {
+		return nil
+	}
+	return func() tea.Msg {
+		select {
+		case msg := <-m.stream:
+			return msg
+		case <-m.ctx.Done():
+			return nil
+		}
+	}
+}
+
+// applyEvent adds ev to the timeline: progress redraws the job's current
+// progress row, everything else appends.
+func (m *model) applyEvent(ev job.Event) {
+	label := shortID(ev.JobID)
+	if ev.Board != "" || ev.DesignName != "" {
+		label += " " + ev.Board + " | " + ev.DesignName
+	}
+	at := ev.At.Local().Format("15:04:05")
+	state := m.styles.state(ev.State, 0)
+
+	switch ev.Type {
+	case "progress":
+		text := fmt.Sprintf("%s  %s  %s", at, label, defaultText(ev.Step, "progress"))
+		if ev.Progress != nil {
+			text += fmt.Sprintf(" %3.0f%%", *ev.Progress)
+		}
+		if ev.Message != "" {
+			text += "  " + ev.Message
+		}
+		m.addTimelineLine(ev.JobID+"|"+ev.Step, text)
+	case job.EventSnapshot:
+		text := fmt.Sprintf("%s  %s  is %s", at, label, state)
+		if ev.Step != "" {
+			text += "  " + ev.Step
+		}
+		m.addTimelineLine("", text)
+	default:
+		text := fmt.Sprintf("%s  %s  -> %s", at, label, state)
+		if ev.Message != "" {
+			text += "  " + ev.Message
+		}
+		m.addTimelineLine("", text)
+	}
+
+	if ev.State == job.StateFailed && ev.FailureKind != "" {
+		m.addEvent(fmt.Sprintf("job %s %s: %s", shortID(ev.JobID), ev.FailureKind, ev.FailureSummary))
+		if hint := job.FailureGuidance(ev.FailureKind); hint != "" {
+			m.addEvent("hint: " + hint)
+		}
+	}
+}
+
+// addEvent appends a local note, such as a reflash request, to the
+// timeline.
+func (m *model) addEvent(message string) {
+	trimmed := strings.TrimSpace(message)
+	if trimmed == "" {
+		return
+	}
+	m.addTimelineLine("", fmt.Sprintf("%s  %s", time.Now().Local().Format("15:04:05"), trimmed))
+}
+
+func (m *model) addTimelineLine(key, text string) {
+	if n := len(m.timeline); key != "" && n > 0 && m.timeline[n-1].key == key {
+		m.timeline[n-1].text = text
+		return
+	}
+	m.timeline = append(m.timeline, timelineLine{key: key, text: text})
+	m.timelineAdded++
+	if len(m.timeline) > maxTimelineLines {
+		m.timeline = m.timeline[len(m.timeline)-maxTimelineLines:]
+	}
+	if m.paused {
+		// Keep the paused window on the same lines.
+		m.scroll++
+		m.unseen++
+		m.clampScroll()
+	}
+}
+
+// togglePause freezes the timeline for reading, or resumes following it.
+func (m *model) togglePause() {
+	if m.paused {
+		m.paused, m.scroll, m.unseen = false, 0, 0
+		return
+	}
+	m.paused = true
+}
+
+// scrollTimeline moves the timeline window by pages; scrolling up pauses
+// it and scrolling back to the newest line resumes.
+func (m *model) scrollTimeline(pages int) {
+	rows := m.eventRowsLimit()
+	if rows <= 0 {
+		return
+	}
+	m.scroll += pages * rows
+	m.paused = true
+	m.clampScroll()
+	if m.scroll == 0 && pages < 0 {
+		m.paused, m.unseen = false, 0
+	}
+}
+
+func (m *model) clampScroll() {
+	maxScroll := len(m.timeline) - m.eventRowsLimit()
+	if maxScroll < 0 {
+		maxScroll = 0
+	}
+	if m.scroll > maxScroll {
+		m.scroll = maxScroll
+	}
+	if m.scroll < 0 {
+		m.scroll = 0
+	}
+}
+
+func (m model) timelineHeader() string {
+	switch {
+	case m.paused:
+		return fmt.Sprintf("Timeline (paused, %d new; p resumes)", m.unseen)
+	case m.streamErr != "":
+		return "Timeline (reconnecting: " + m.streamErr + ")"
+	case !m.streamLive:
+		return "Timeline (connecting...)"
+	default:
+		return "Timeline (live)"
+	}
+}
+
+func (m model) writeEventSection(b *strings.Builder) {
+	b.WriteString(trimToWidth(strings.Repeat("-", 120), m.width))
+	b.WriteByte('\n')
+	header := m.timelineHeader()
+	if m.kiosk {
+		header = "Events"
+	}
+	b.WriteString(trimToWidth(m.styles.render(m.styles.title, header), m.width))
+	b.WriteByte('\n')
+
+	rows := m.eventRowsLimit()
+	if rows <= 0 {
+		return
+	}
+	if len(m.timeline) == 0 {
+		b.WriteString(trimToWidth("(no events yet)", m.width))
+		b.WriteByte('\n')
+		return
+	}
+	end := len(m.timeline) - m.scroll
+	start := end - rows
+	if start < 0 {
+		start = 0
+	}
+	for i := start; i < end; i++ {
+		b.WriteString(trimToWidth(m.timeline[i].text, m.width))
+		b.WriteByte('\n')
+	}
+}
+
+func defaultText(v, fallback string) string {
+	if v == "" {
+		return fallback
+	}
+	return v
+}
@@ -0,0 +1,55 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MaxReconnects caps consecutive reconnects after stalls that delivered no
+// events.
+const MaxReconnects = 5
+
+// ErrResync ends a stream after a dropped_events notice so Follow
+// reconnects at once and the server replays the missed events.
+var ErrResync = errors.New("events dropped; resync")
+
+// Follow runs once until the stream ends, reconnecting from the last
+// received event after stalls (ErrStalled) and resyncs (ErrResync). seq
+// returns an event's sequence number; retryDelay is the wait before each
+// reconnect after a stall. It gives up after MaxReconnects stalls in a row
+// that delivered no events.
+func Follow[E any](ctx context.Context, since int64, seq func(E) int64, onEvent func(E), retryDelay func() time.Duration, once func(since int64, emit func(E)) error) error {
+	stalls := 0
+	for {
+		received := false
+		err := once(since, func(ev E) {
+			received = true
+			if s := seq(ev); s > since {
+				since = s
+			}
+			if onEvent != nil {
+				onEvent(ev)
+			}
+		})
+		if errors.Is(err, ErrResync) {
+			continue
+		}
+		if !errors.Is(err, ErrStalled) {
+			return err
+		}
+		if received {
+			stalls = 0
+		}
+		stalls++
+		if stalls > MaxReconnects {
+			return fmt.Errorf("stream events: %w", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(retryDelay()):
+		}
+	}
+}
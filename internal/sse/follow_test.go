@@ -0,0 +1,44 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFollow_ResumesFromLastEventAndGivesUpAfterStalls(t *testing.T) {
+	var sinces []int64
+	var got []int
+	calls := 0
+	err := Follow(context.Background(), 0, func(n int) int64 { return int64(n) }, func(n int) {
+		got = append(got, n)
+	}, func() time.Duration { return 0 }, func(since int64, emit func(int)) error {
+		calls++
+		sinces = append(sinces, since)
+		switch calls {
+		case 1:
+			emit(1)
+			emit(2)
+			return ErrStalled
+		case 2:
+			emit(3)
+			return ErrResync
+		}
+		return ErrStalled
+	})
+	if !errors.Is(err, ErrStalled) {
+		t.Fatalf("expected ErrStalled, got %v", err)
+	}
+	if len(got) != 3 || got[2] != 3 {
+		t.Fatalf("unexpected events %v", got)
+	}
+	if sinces[0] != 0 || sinces[1] != 2 || sinces[2] != 3 {
+		t.Fatalf("unexpected resume points %v", sinces)
+	}
+	// The stall that delivered events counts as the first of MaxReconnects+1
+	// in a row; the resync in between does not count.
+	if want := 2 + MaxReconnects; calls != want {
+		t.Fatalf("calls = %d, want %d", calls, want)
+	}
+}
@@ -0,0 +1,102 @@
+// Package sse decodes text/event-stream bodies for the spadeforge and
+// spadeloader clients.
+package sse
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultIdleTimeout is how long a client waits without receiving any
+	// bytes (events or keepalive comments) before treating the connection as
+	// dead. It is a few multiples of the default server keepalive interval.
+	DefaultIdleTimeout = 45 * time.Second
+	// DefaultRetry is the reconnect delay used until the server sends a
+	// retry hint.
+	DefaultRetry = 2 * time.Second
+)
+
+// ErrStalled is returned by Decode when no line arrived within the idle
+// timeout. Callers should reconnect, resuming from the last seen event.
+var ErrStalled = errors.New("event stream stalled: no data within idle timeout")
+
+// Decoder reads server-sent events. Retry holds the most recent reconnect
+// hint sent by the server and persists across Decode calls.
+type Decoder struct {
+	IdleTimeout time.Duration
+	Retry       time.Duration
+}
+
+// Decode reads body until EOF, calling fn with the data payload of every
+// dispatched event. Multi-line payloads are joined with newlines. If
+// IdleTimeout is positive and the body goes silent for that long, body is
+// closed and ErrStalled is returned.
+func (d *Decoder) Decode(body io.ReadCloser, fn func(data string) error) error {
+	var stalled atomic.Bool
+	var timer *time.Timer
+	if d.IdleTimeout > 0 {
+		timer = time.AfterFunc(d.IdleTimeout, func() {
+			stalled.Store(true)
+			_ = body.Close()
+		})
+		defer timer.Stop()
+	}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	dataLines := make([]string, 0, 4)
+	dispatch := func() error {
+		if len(dataLines) == 0 {
+			return nil
+		}
+		payload := strings.Join(dataLines, "\n")
+		dataLines = dataLines[:0]
+		return fn(payload)
+	}
+
+	for scanner.Scan() {
+		if timer != nil {
+			timer.Reset(d.IdleTimeout)
+		}
+		line := scanner.Text()
+		if line == "" {
+			if err := dispatch(); err != nil {
+				return err
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		if strings.HasPrefix(line, "data:") {
+			dataLines = append(dataLines, strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+			continue
+		}
+		if strings.HasPrefix(line, "retry:") {
+			if ms, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "retry:"))); err == nil && ms >= 0 {
+				d.Retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+	if stalled.Load() {
+		return ErrStalled
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return dispatch()
+}
+
+// RetryDelay returns the server's reconnect hint, or DefaultRetry.
+func (d *Decoder) RetryDelay() time.Duration {
+	if d.Retry > 0 {
+		return d.Retry
+	}
+	return DefaultRetry
+}
@@ -0,0 +1,59 @@
+package sse
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecode_DispatchesDataAndRetryHint(t *testing.T) {
+	body := io.NopCloser(strings.NewReader("retry: 1500\n\n: keepalive\n\nid: 1\nevent: queued\ndata: {\"a\":1}\n\ndata: line1\ndata: line2\n\ndata: tail"))
+	d := &Decoder{}
+	var got []string
+	if err := d.Decode(body, func(data string) error {
+		got = append(got, data)
+		return nil
+	}); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	want := []string{"{\"a\":1}", "line1\nline2", "tail"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("unexpected payloads: %q", got)
+	}
+	if d.RetryDelay() != 1500*time.Millisecond {
+		t.Fatalf("unexpected retry: %s", d.RetryDelay())
+	}
+}
+
+func TestDecode_StalledConnectionReturnsErrStalled(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	go func() {
+		_, _ = pw.Write([]byte("data: first\n\n"))
+	}()
+
+	d := &Decoder{IdleTimeout: 50 * time.Millisecond}
+	var got []string
+	start := time.Now()
+	err := d.Decode(pr, func(data string) error {
+		got = append(got, data)
+		return nil
+	})
+	if !errors.Is(err, ErrStalled) {
+		t.Fatalf("expected ErrStalled, got %v", err)
+	}
+	if len(got) != 1 || got[0] != "first" {
+		t.Fatalf("unexpected payloads: %q", got)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Fatalf("stall detection took too long")
+	}
+}
+
+func TestDecoder_RetryDelayDefault(t *testing.T) {
+	if got := (&Decoder{}).RetryDelay(); got != DefaultRetry {
+		t.Fatalf("unexpected default retry: %s", got)
+	}
+}
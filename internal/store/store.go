@@ -1,20 +1,26 @@
 package store
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
 
+	"github.com/mblsha/spadeforge/internal/atrest"
 	"github.com/mblsha/spadeforge/internal/config"
 	"github.com/mblsha/spadeforge/internal/job"
 )
 
 type Store struct {
 	cfg config.Config
+	key *atrest.Key
 	mu  sync.Mutex
 }
 
@@ -22,6 +28,12 @@ func New(cfg config.Config) *Store {
 	return &Store{cfg: cfg}
 }
 
+// SetKey turns on encryption at rest of request zips and bitstreams. A nil
+// key turns it off for new files.
+func (s *Store) SetKey(k *atrest.Key) {
+	s.key = k
+}
+
 func (s *Store) EnsureDirs() error {
 	dirs := []string{s.cfg.BaseDir, s.cfg.JobsDir(), s.cfg.WorkDir(), s.cfg.ArtifactsDir()}
 	for _, dir := range dirs {
@@ -54,9 +66,79 @@ func (s *Store) WriteRequestZip(jobID string, r io.Reader) error {
 		return fmt.Errorf("open request zip: %w", err)
 	}
 	defer f.Close()
-	if _, err := io.Copy(f, r); err != nil {
+	w, err := s.key.NewWriter(f)
+	if err != nil {
+		return fmt.Errorf("write request zip: %w", err)
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		return fmt.Errorf("write request zip: %w", err)
+	}
+	if err := w.Close(); err != nil {
 		return fmt.Errorf("write request zip: %w", err)
 	}
+	return f.Close()
+}
+
+// Open opens a stored file for reading its plaintext, decrypting it when it
+// is encrypted at rest.
+func (s *Store) Open(path string) (io.ReadCloser, error) {
+	return s.key.OpenFile(path)
+}
+
+// WithPlainFile calls fn with a path holding the plaintext of path. An
+// encrypted file is decrypted to a private temporary file under the jobs
+// root, away from the artifacts that are served, and removed once fn
+// returns.
+func (s *Store) WithPlainFile(path string, fn func(plainPath string) error) error {
+	sealed, err := atrest.IsSealed(path)
+	if err != nil {
+		return err
+	}
+	if !sealed {
+		return fn(path)
+	}
+	src, err := s.key.OpenFile(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	tmp, err := os.CreateTemp(s.cfg.JobsDir(), ".plain-*-"+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, src)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("decrypt %s: %w", path, err)
+	}
+	return fn(tmp.Name())
+}
+
+// SealBitstreams encrypts the .bit and .bin files at the top of the job's
+// artifacts when a key is set.
+func (s *Store) SealBitstreams(jobID string) error {
+	if !s.key.Enabled() {
+		return nil
+	}
+	entries, err := os.ReadDir(s.ArtifactsJobDir(jobID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if !e.Type().IsRegular() || (ext != ".bit" && ext != ".bin") {
+			continue
+		}
+		if err := s.key.SealFile(filepath.Join(s.ArtifactsJobDir(jobID), e.Name())); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -113,10 +195,114 @@ func (s *Store) LoadAll() ([]*job.Record, error) {
 	return records, nil
 }
 
+// AppendEvent adds ev to the end of its job's event log.
+func (s *Store) AppendEvent(ev job.Event) error {
+	raw, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+	raw = append(raw, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.EventsPath(ev.JobID), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open event log: %w", err)
+	}
+	if _, err := f.Write(raw); err != nil {
+		f.Close()
+		return fmt.Errorf("write event log: %w", err)
+	}
+	return f.Close()
+}
+
+// LoadEvents returns the last keep events of a job's log, rewriting the log
+// to hold only those when it has grown longer. A missing log is empty, and
+// a line torn by a crash mid-append is skipped.
+func (s *Store) LoadEvents(jobID string, keep int) ([]job.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.EventsPath(jobID)
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("open event log: %w", err)
+	}
+	defer f.Close()
+
+	var events []job.Event
+	lines := 0
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			lines++
+			var ev job.Event
+			if json.Unmarshal(line, &ev) == nil {
+				events = append(events, ev)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read event log: %w", err)
+		}
+	}
+	if keep > 0 && len(events) > keep {
+		events = events[len(events)-keep:]
+	}
+	if lines > len(events) {
+		if err := writeEventLog(path, events); err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+// writeEventLog replaces the log at path with events.
+func writeEventLog(path string, events []job.Event) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, ev := range events {
+		if err := enc.Encode(ev); err != nil {
+			return fmt.Errorf("marshal event: %w", err)
+		}
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("compact event log: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("compact event log: %w", err)
+	}
+	return nil
+}
+
 func (s *Store) RemoveWorkDir(jobID string) error {
 	return os.RemoveAll(s.WorkJobDir(jobID))
 }
 
+// RemoveJob deletes every directory of a job, which may live on separate
+// volumes for work and artifacts.
+func (s *Store) RemoveJob(jobID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	for _, p := range []string{s.WorkJobDir(jobID), s.ArtifactsJobDir(jobID), s.JobDir(jobID)} {
+		if err := os.RemoveAll(p); err != nil {
+			errs = append(errs, fmt.Errorf("remove %q: %w", p, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func (s *Store) JobDir(jobID string) string {
 	return filepath.Join(s.cfg.JobsDir(), jobID)
 }
@@ -125,6 +311,11 @@ func (s *Store) StatePath(jobID string) string {
 	return filepath.Join(s.JobDir(jobID), "state.json")
 }
 
+// EventsPath is the job's append-only event log, one JSON event per line.
+func (s *Store) EventsPath(jobID string) string {
+	return filepath.Join(s.JobDir(jobID), "events.jsonl")
+}
+
 func (s *Store) RequestZipPath(jobID string) string {
 	return filepath.Join(s.JobDir(jobID), "request.zip")
 }
@@ -140,3 +331,43 @@ func (s *Store) SourceDir(jobID string) string {
 func (s *Store) ArtifactsJobDir(jobID string) string {
 	return filepath.Join(s.cfg.ArtifactsDir(), jobID)
 }
+
+// Usage is the space stored jobs take under each root.
+type Usage struct {
+	JobsBytes      int64 `json:"jobs_bytes"`
+	WorkBytes      int64 `json:"work_bytes"`
+	ArtifactsBytes int64 `json:"artifacts_bytes"`
+}
+
+// Total sums the roots.
+func (u Usage) Total() int64 {
+	return u.JobsBytes + u.WorkBytes + u.ArtifactsBytes
+}
+
+// JobUsage measures one job's state and request zip, work dir and
+// artifacts.
+func (s *Store) JobUsage(jobID string) Usage {
+	return Usage{
+		JobsBytes:      DirBytes(s.JobDir(jobID)),
+		WorkBytes:      DirBytes(s.WorkJobDir(jobID)),
+		ArtifactsBytes: DirBytes(s.ArtifactsJobDir(jobID)),
+	}
+}
+
+// DirBytes sums the sizes of the regular files under dir, skipping any it
+// cannot read; a missing dir is empty.
+func DirBytes(dir string) int64 {
+	var total int64
+	_ = filepath.WalkDir(dir, func(_ string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
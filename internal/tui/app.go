@@ -0,0 +1,591 @@
+// Package tui is the spadeforge build server dashboard: the build queue,
+// each job's step and heartbeat, and the selected job's console tail.
+package tui
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mblsha/spadeforge/internal/client"
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+const (
+	defaultLimit           = 100
+	defaultRefreshInterval = 1500 * time.Millisecond
+	defaultTailLines       = 200
+	// actionTimeout bounds a cancel, resubmit or artifacts download.
+	actionTimeout = 5 * time.Minute
+	// headerRows are the title, help, status and summary lines above the
+	// job list; consoleChrome the separator and console title below it.
+	headerRows    = 4
+	consoleChrome = 2
+)
+
+type Options struct {
+	Client          *client.HTTPClient
+	Limit           int
+	RefreshInterval time.Duration
+	// TailLines is how much of the selected job's console is fetched.
+	TailLines int
+	// DownloadDir receives artifact zips downloaded with d; empty is the
+	// working directory.
+	DownloadDir string
+	// NoColor renders plain text without colours or bold.
+	NoColor bool
+}
+
+func Run(ctx context.Context, opts Options) error {
+	model, err := newModel(opts)
+	if err != nil {
+		return err
+	}
+	p := tea.NewProgram(model, tea.WithContext(ctx), tea.WithAltScreen())
+	_, err = p.Run()
+	if err == nil || errors.Is(err, tea.ErrInterrupted) {
+		return nil
+	}
+	if ctx.Err() != nil && (errors.Is(err, context.Canceled) || errors.Is(err, tea.ErrProgramKilled)) {
+		return nil
+	}
+	return err
+}
+
+type refreshTickMsg struct{}
+
+type jobsLoadedMsg struct {
+	items []job.Record
+	total int
+	err   error
+}
+
+// tailLoadedMsg carries the console tail of jobID, which is dropped if the
+// selection moved on meanwhile.
+type tailLoadedMsg struct {
+	jobID string
+	text  string
+	err   error
+}
+
+// actionResultMsg reports a cancel, resubmit or download; selectID, when
+// set, is selected once the list shows it.
+type actionResultMsg struct {
+	status   string
+	selectID string
+	err      error
+}
+
+type model struct {
+	client *client.HTTPClient
+
+	limit           int
+	refreshInterval time.Duration
+	tailLines       int
+	downloadDir     string
+	styles          styles
+
+	items []job.Record
+	total int
+
+	selectedIdx int
+	selectedID  string
+	pendingID   string
+	// confirmCancel is the job a first c asked to cancel; a second c on
+	// the same job cancels it.
+	confirmCancel string
+
+	tailID  string
+	tail    []string
+	tailErr string
+
+	width  int
+	height int
+
+	loading bool
+	busy    bool
+	status  string
+	lastErr string
+	now     func() time.Time
+}
+
+func newModel(opts Options) (model, error) {
+	if opts.Client == nil {
+		return model{}, fmt.Errorf("tui client is required")
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	refresh := opts.RefreshInterval
+	if refresh <= 0 {
+		refresh = defaultRefreshInterval
+	}
+	tailLines := opts.TailLines
+	if tailLines <= 0 {
+		tailLines = defaultTailLines
+	}
+	st := styles{}
+	if !opts.NoColor {
+		st = newStyles(lipgloss.DefaultRenderer())
+	}
+	return model{
+		client:          opts.Client,
+		limit:           limit,
+		refreshInterval: refresh,
+		tailLines:       tailLines,
+		downloadDir:     opts.DownloadDir,
+		styles:          st,
+		loading:         true,
+		status:          "loading jobs...",
+		now:             time.Now,
+	}, nil
+}
+
+func (m model) Init() tea.Cmd {
+	return tea.Batch(m.fetchJobsCmd(), m.tickCmd())
+}
+
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch typed := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = typed.Width
+		m.height = typed.Height
+		return m, nil
+	case refreshTickMsg:
+		m.loading = true
+		return m, tea.Batch(m.fetchJobsCmd(), m.fetchTailCmd(), m.tickCmd())
+	case jobsLoadedMsg:
+		m.loading = false
+		if typed.err != nil {
+			m.lastErr = typed.err.Error()
+			m.status = "refresh failed"
+			return m, nil
+		}
+		m.lastErr = ""
+		previous := m.selectedID
+		m.total = typed.total
+		m.applyJobs(typed.items)
+		if !m.busy {
+			m.status = fmt.Sprintf("loaded %d of %d jobs", len(m.items), m.total)
+		}
+		if m.selectedID != previous {
+			return m, m.fetchTailCmd()
+		}
+		return m, nil
+	case tailLoadedMsg:
+		if typed.jobID != m.selectedID {
+			return m, nil
+		}
+		m.tailID = typed.jobID
+		if typed.err != nil {
+			m.tailErr = typed.err.Error()
+			return m, nil
+		}
+		m.tailErr = ""
+		m.tail = splitTail(typed.text)
+		return m, nil
+	case actionResultMsg:
+		m.busy = false
+		if typed.err != nil {
+			m.lastErr = typed.err.Error()
+			m.status = "action failed"
+			return m, nil
+		}
+		m.lastErr = ""
+		m.status = typed.status
+		if typed.selectID != "" {
+			m.pendingID = typed.selectID
+		}
+		m.loading = true
+		return m, m.fetchJobsCmd()
+	case tea.KeyMsg:
+		key := typed.String()
+		if key != "c" {
+			m.confirmCancel = ""
+		}
+		switch key {
+		case "ctrl+c", "q":
+			return m, tea.Quit
+		case "k", "up":
+			return m, m.moveSelection(-1)
+		case "j", "down":
+			return m, m.moveSelection(1)
+		case "r":
+			m.loading = true
+			return m, tea.Batch(m.fetchJobsCmd(), m.fetchTailCmd())
+		case "c":
+			return m.cancelSelected()
+		case "s":
+			return m.startAction("resubmitting", m.resubmitCmd)
+		case "d":
+			return m.startAction("downloading artifacts of", m.downloadCmd)
+		}
+	}
+	return m, nil
+}
+
+// cancelSelected asks for confirmation on the first c and cancels on the
+// second.
+func (m model) cancelSelected() (tea.Model, tea.Cmd) {
+	selected, ok := m.selected()
+	if !ok || m.busy {
+		return m, nil
+	}
+	if selected.State.Terminal() {
+		m.status = fmt.Sprintf("%s already %s", shortID(selected.ID), strings.ToLower(string(selected.State)))
+		return m, nil
+	}
+	if m.confirmCancel != selected.ID {
+		m.confirmCancel = selected.ID
+		m.status = fmt.Sprintf("press c again to cancel %s", shortID(selected.ID))
+		return m, nil
+	}
+	m.confirmCancel = ""
+	return m.startAction("canceling", m.cancelCmd)
+}
+
+func (m model) startAction(verb string, cmd func(job.Record) tea.Cmd) (tea.Model, tea.Cmd) {
+	selected, ok := m.selected()
+	if !ok || m.busy {
+		return m, nil
+	}
+	m.busy = true
+	m.lastErr = ""
+	m.status = fmt.Sprintf("%s %s ...", verb, shortID(selected.ID))
+	return m, cmd(selected)
+}
+
+func (m model) View() string {
+	var b strings.Builder
+	m.writeLine(&b, m.styles.render(m.styles.title, "Spadeforge TUI - Build queue (newest first)"))
+	m.writeLine(&b, m.styles.render(m.styles.help, "Keys: j/k or arrows move  c cancel  s resubmit  d download artifacts  r refresh  q quit"))
+	m.writeLine(&b, m.statusLine())
+	m.writeLine(&b, m.summaryLine())
+
+	if len(m.items) == 0 {
+		if m.loading {
+			b.WriteString("\nLoading...\n")
+		} else {
+			b.WriteString("\nNo jobs yet.\n")
+		}
+		return b.String()
+	}
+	start, end := m.visibleRows()
+	for i := start; i < end; i++ {
+		m.writeLine(&b, m.jobLine(i))
+	}
+	m.writeConsole(&b)
+	return b.String()
+}
+
+func (m model) writeLine(b *strings.Builder, line string) {
+	b.WriteString(trimToWidth(line, m.width))
+	b.WriteByte('\n')
+}
+
+func (m model) jobLine(i int) string {
+	rec := m.items[i]
+	prefix := "  "
+	if i == m.selectedIdx {
+		prefix = m.styles.render(m.styles.selected, "> ")
+	}
+	line := fmt.Sprintf("%s%s  %s  %s  %s  %s  %s",
+		prefix,
+		rec.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+		padToWidth(defaultText(rec.Manifest.Project, "-"), 20),
+		m.styles.state(rec.State, 9),
+		padToWidth(defaultText(rec.CurrentStep, "-"), 12),
+		padToWidth(m.heartbeat(rec), 10),
+		shortID(rec.ID),
+	)
+	if rec.State == job.StateFailed && rec.FailureKind != "" {
+		line += "  " + rec.FailureKind
+	}
+	if rec.Attempt > 1 {
+		line += fmt.Sprintf("  attempt %d", rec.Attempt)
+	}
+	return line
+}
+
+// heartbeat is the age of a running job's last heartbeat, so a wedged
+// build stands out.
+func (m model) heartbeat(rec job.Record) string {
+	if rec.State != job.StateRunning || rec.HeartbeatAt == nil {
+		return ""
+	}
+	age := m.now().Sub(*rec.HeartbeatAt).Round(time.Second)
+	if age < 0 {
+		age = 0
+	}
+	return "hb " + age.String()
+}
+
+func (m model) writeConsole(b *strings.Builder) {
+	rows := m.consoleRows()
+	if rows <= 0 {
+		return
+	}
+	m.writeLine(b, strings.Repeat("-", 120))
+	selected, ok := m.selected()
+	if !ok {
+		return
+	}
+	title := fmt.Sprintf("Console: %s %s (%s)", shortID(selected.ID), defaultText(selected.Manifest.Project, ""), strings.ToLower(string(selected.State)))
+	m.writeLine(b, m.styles.render(m.styles.title, title))
+	switch {
+	case m.tailErr != "" && m.tailID == selected.ID:
+		m.writeLine(b, m.styles.render(m.styles.err, "console unavailable: "+m.tailErr))
+	case m.tailID != selected.ID:
+		m.writeLine(b, "(loading console...)")
+	case len(m.tail) == 0:
+		m.writeLine(b, "(no console output yet)")
+	default:
+		lines := m.tail
+		if len(lines) > rows {
+			lines = lines[len(lines)-rows:]
+		}
+		for _, line := range lines {
+			m.writeLine(b, line)
+		}
+	}
+}
+
+// consoleRows splits the screen between the job list and the console: the
+// console gets half of it once the list has room for five jobs.
+func (m model) consoleRows() int {
+	if m.height <= 0 {
+		return 20
+	}
+	rows := (m.height - headerRows) / 2
+	if free := m.height - headerRows - consoleChrome - 5; rows > free {
+		rows = free
+	}
+	if rows < 0 {
+		return 0
+	}
+	return rows
+}
+
+func (m model) visibleRows() (int, int) {
+	maxRows := len(m.items)
+	if m.height > 0 {
+		maxRows = m.height - headerRows - m.consoleRows()
+		if m.consoleRows() > 0 {
+			maxRows -= consoleChrome
+		}
+		if maxRows < 1 {
+			maxRows = 1
+		}
+	}
+	if maxRows > len(m.items) {
+		maxRows = len(m.items)
+	}
+	start := 0
+	if m.selectedIdx >= maxRows {
+		start = m.selectedIdx - maxRows + 1
+	}
+	return start, start + maxRows
+}
+
+func (m model) statusLine() string {
+	parts := make([]string, 0, 2)
+	if strings.TrimSpace(m.status) != "" {
+		parts = append(parts, m.status)
+	}
+	if strings.TrimSpace(m.lastErr) != "" {
+		parts = append(parts, m.styles.render(m.styles.err, "error: "+m.lastErr))
+	}
+	return strings.Join(parts, " | ")
+}
+
+// summaryLine counts the listed jobs that are waiting or building.
+func (m model) summaryLine() string {
+	var queued, running int
+	for _, rec := range m.items {
+		switch rec.State {
+		case job.StateQueued:
+			queued++
+		case job.StateRunning:
+			running++
+		}
+	}
+	return fmt.Sprintf("Queue: %d running, %d queued", running, queued)
+}
+
+// applyJobs shows items, newest first as the server lists them, keeping
+// the selection on the same job.
+func (m *model) applyJobs(items []job.Record) {
+	m.items = items
+	if len(m.items) == 0 {
+		m.selectedIdx, m.selectedID, m.pendingID = 0, "", ""
+		return
+	}
+	// A resubmitted job is selected once it is listed; until then the
+	// selection stays put.
+	for _, targetID := range []string{m.pendingID, m.selectedID} {
+		if targetID == "" {
+			continue
+		}
+		for i := range m.items {
+			if m.items[i].ID == targetID {
+				m.selectedIdx, m.selectedID = i, targetID
+				if targetID == m.pendingID {
+					m.pendingID = ""
+				}
+				return
+			}
+		}
+	}
+	if m.selectedIdx >= len(m.items) {
+		m.selectedIdx = len(m.items) - 1
+	}
+	m.selectedID = m.items[m.selectedIdx].ID
+}
+
+// moveSelection moves the cursor and fetches the newly selected console.
+func (m *model) moveSelection(delta int) tea.Cmd {
+	if len(m.items) == 0 {
+		return nil
+	}
+	next := m.selectedIdx + delta
+	if next < 0 {
+		next = 0
+	}
+	if next >= len(m.items) {
+		next = len(m.items) - 1
+	}
+	if next == m.selectedIdx {
+		return nil
+	}
+	m.selectedIdx = next
+	m.selectedID = m.items[next].ID
+	m.pendingID = ""
+	return m.fetchTailCmd()
+}
+
+func (m model) selected() (job.Record, bool) {
+	if len(m.items) == 0 || m.selectedIdx < 0 || m.selectedIdx >= len(m.items) {
+		return job.Record{}, false
+	}
+	return m.items[m.selectedIdx], true
+}
+
+func (m model) fetchJobsCmd() tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), m.refreshInterval)
+		defer cancel()
+		list, err := m.client.ListJobs(ctx, client.ListJobsOptions{Limit: m.limit})
+		if err != nil {
+			return jobsLoadedMsg{err: err}
+		}
+		return jobsLoadedMsg{items: list.Items, total: list.Total}
+	}
+}
+
+// fetchTailCmd loads the selected job's console. Finished jobs whose tail
+// is already shown are not fetched again.
+func (m model) fetchTailCmd() tea.Cmd {
+	selected, ok := m.selected()
+	if !ok {
+		return nil
+	}
+	if selected.State.Terminal() && m.tailID == selected.ID && m.tailErr == "" {
+		return nil
+	}
+	jobID, lines := selected.ID, m.tailLines
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), m.refreshInterval)
+		defer cancel()
+		text, err := m.client.GetLogTail(ctx, jobID, lines)
+		return tailLoadedMsg{jobID: jobID, text: text, err: err}
+	}
+}
+
+func (m model) tickCmd() tea.Cmd {
+	return tea.Tick(m.refreshInterval, func(_ time.Time) tea.Msg {
+		return refreshTickMsg{}
+	})
+}
+
+func (m model) cancelCmd(rec job.Record) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), actionTimeout)
+		defer cancel()
+		if _, err := m.client.CancelJob(ctx, rec.ID); err != nil {
+			return actionResultMsg{err: err}
+		}
+		return actionResultMsg{status: "cancel requested for " + shortID(rec.ID)}
+	}
+}
+
+// resubmitCmd queues the job's original bundle again as a new job.
+func (m model) resubmitCmd(rec job.Record) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), actionTimeout)
+		defer cancel()
+		var bundle bytes.Buffer
+		if err := m.client.DownloadBundle(ctx, rec.ID, &bundle); err != nil {
+			return actionResultMsg{err: err}
+		}
+		resp, err := m.client.Submit(ctx, bundle.Bytes())
+		if err != nil {
+			return actionResultMsg{err: err}
+		}
+		return actionResultMsg{
+			status:   fmt.Sprintf("resubmitted %s as %s", shortID(rec.ID), shortID(resp.JobID)),
+			selectID: resp.JobID,
+		}
+	}
+}
+
+// downloadCmd saves the job's artifacts zip to the download directory.
+func (m model) downloadCmd(rec job.Record) tea.Cmd {
+	dest := filepath.Join(m.downloadDir, rec.ID+"-artifacts.zip")
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), actionTimeout)
+		defer cancel()
+		f, err := os.Create(dest)
+		if err != nil {
+			return actionResultMsg{err: err}
+		}
+		err = m.client.DownloadArtifacts(ctx, rec.ID, f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			_ = os.Remove(dest)
+			return actionResultMsg{err: err}
+		}
+		return actionResultMsg{status: "artifacts saved to " + dest}
+	}
+}
+
+func splitTail(text string) []string {
+	text = strings.TrimRight(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+func shortID(id string) string {
+	if len(id) <= 8 {
+		return id
+	}
+	return id[:8]
+}
+
+func defaultText(v, fallback string) string {
+	if v == "" {
+		return fallback
+	}
+	return v
+}
@@ -0,0 +1,99 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mblsha/spadeforge/internal/client"
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+func testModel(t *testing.T) model {
+	t.Helper()
+	m, err := newModel(Options{Client: &client.HTTPClient{}, NoColor: true})
+	if err != nil {
+		t.Fatalf("newModel() error: %v", err)
+	}
+	return m
+}
+
+func press(t *testing.T, m model, key string) (model, tea.Cmd) {
+	t.Helper()
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+	return updated.(model), cmd
+}
+
+func TestApplyJobsKeepsSelectionAndPicksResubmittedJob(t *testing.T) {
+	t.Parallel()
+
+	m := testModel(t)
+	m.applyJobs([]job.Record{{ID: "b"}, {ID: "a"}})
+	m.moveSelection(1)
+	if m.selectedID != "a" {
+		t.Fatalf("selectedID = %q, want a", m.selectedID)
+	}
+
+	m.pendingID = "c"
+	m.applyJobs([]job.Record{{ID: "b"}, {ID: "a"}})
+	if m.selectedID != "a" || m.pendingID != "c" {
+		t.Fatalf("before the new job is listed: selected %q pending %q", m.selectedID, m.pendingID)
+	}
+	m.applyJobs([]job.Record{{ID: "c"}, {ID: "b"}, {ID: "a"}})
+	if m.selectedID != "c" || m.selectedIdx != 0 || m.pendingID != "" {
+		t.Fatalf("after: selected %q at %d pending %q, want c at 0", m.selectedID, m.selectedIdx, m.pendingID)
+	}
+}
+
+func TestCancelNeedsSecondPress(t *testing.T) {
+	t.Parallel()
+
+	m := testModel(t)
+	m.applyJobs([]job.Record{{ID: "running-job", State: job.StateRunning}, {ID: "done-job", State: job.StateSucceeded}})
+
+	m, cmd := press(t, m, "c")
+	if cmd != nil || !strings.Contains(m.status, "press c again") {
+		t.Fatalf("first c: cmd=%v status=%q, want a confirmation prompt", cmd != nil, m.status)
+	}
+	m, cmd = press(t, m, "c")
+	if cmd == nil || !m.busy {
+		t.Fatalf("second c should cancel the job")
+	}
+
+	m.busy = false
+	m, _ = press(t, m, "j")
+	m, cmd = press(t, m, "c")
+	if cmd != nil || !strings.Contains(m.status, "already succeeded") {
+		t.Fatalf("c on a finished job: cmd=%v status=%q", cmd != nil, m.status)
+	}
+}
+
+func TestViewShowsStepHeartbeatAndSelectedConsole(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	beat := now.Add(-7 * time.Second)
+	m := testModel(t)
+	m.now = func() time.Time { return now }
+	m.loading = false
+	m.applyJobs([]job.Record{
+		{ID: "build-1234567", State: job.StateRunning, CurrentStep: "synth", HeartbeatAt: &beat},
+		{ID: "queued-job", State: job.StateQueued},
+	})
+
+	updated, _ := m.Update(tailLoadedMsg{jobID: "queued-job", text: "other job\n"})
+	m = updated.(model)
+	updated, _ = m.Update(tailLoadedMsg{jobID: "build-1234567", text: "INFO: synth_design\nINFO: done\n"})
+	m = updated.(model)
+
+	view := m.View()
+	for _, want := range []string{"synth", "hb 7s", "Queue: 1 running, 1 queued", "Console: build-12", "INFO: done"} {
+		if !strings.Contains(view, want) {
+			t.Fatalf("view missing %q:\n%s", want, view)
+		}
+	}
+	if strings.Contains(view, "other job") {
+		t.Fatalf("view shows the console of a job that is not selected:\n%s", view)
+	}
+}
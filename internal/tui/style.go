@@ -0,0 +1,70 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+// styles colours the dashboard. The zero value renders plain text, which is
+// what --no-color and NO_COLOR select.
+type styles struct {
+	enabled  bool
+	title    lipgloss.Style
+	help     lipgloss.Style
+	selected lipgloss.Style
+	err      lipgloss.Style
+	states   map[job.State]lipgloss.Style
+}
+
+func newStyles(r *lipgloss.Renderer) styles {
+	return styles{
+		enabled:  true,
+		title:    r.NewStyle().Bold(true),
+		help:     r.NewStyle().Faint(true),
+		selected: r.NewStyle().Bold(true).Foreground(lipgloss.Color("12")),
+		err:      r.NewStyle().Foreground(lipgloss.Color("9")),
+		states: map[job.State]lipgloss.Style{
+			job.StateQueued:    r.NewStyle().Foreground(lipgloss.Color("11")),
+			job.StateRunning:   r.NewStyle().Foreground(lipgloss.Color("14")),
+			job.StateSucceeded: r.NewStyle().Foreground(lipgloss.Color("10")),
+			job.StateFailed:    r.NewStyle().Foreground(lipgloss.Color("9")).Bold(true),
+			job.StateCanceled:  r.NewStyle().Faint(true),
+		},
+	}
+}
+
+func (s styles) render(style lipgloss.Style, text string) string {
+	if !s.enabled || text == "" {
+		return text
+	}
+	return style.Render(text)
+}
+
+// state pads the state to width cells before colouring it, so colour codes
+// don't upset column alignment.
+func (s styles) state(state job.State, width int) string {
+	return s.render(s.states[state], padToWidth(string(state), width))
+}
+
+// trimToWidth cuts in to at most width terminal cells without splitting
+// graphemes or ANSI escapes.
+func trimToWidth(in string, width int) string {
+	if width <= 0 || ansi.StringWidth(in) <= width {
+		return in
+	}
+	if width <= 3 {
+		return ansi.Truncate(in, width, "")
+	}
+	return ansi.Truncate(in, width, "...")
+}
+
+// padToWidth pads in with spaces to width cells. Longer text is left as is.
+func padToWidth(in string, width int) string {
+	if w := ansi.StringWidth(in); w < width {
+		return in + strings.Repeat(" ", width-w)
+	}
+	return in
+}
@@ -0,0 +1,312 @@
+// Package upload keeps resumable bundle uploads. A client declares the
+// bundle's size and SHA-256, sends it in chunks at increasing offsets,
+// possibly over several connections, and finalizes it once every byte has
+// arrived and the digest matches.
+package upload
+
+import (
+	crand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/checksum"
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+var (
+	ErrNotFound = errors.New("upload not found")
+	// ErrOffsetMismatch means a chunk does not start where the stored
+	// bytes end; the client should ask for the offset and resume there.
+	ErrOffsetMismatch = errors.New("chunk offset does not match upload offset")
+	// ErrBusy means another chunk for the same upload is being written.
+	ErrBusy       = errors.New("another chunk of this upload is in progress")
+	ErrTooLarge   = errors.New("chunk runs past the declared upload size")
+	ErrIncomplete = errors.New("upload is incomplete")
+	// ErrChecksum means the finished upload does not match its declared
+	// SHA-256. The upload is discarded.
+	ErrChecksum = errors.New("upload sha256 mismatch")
+)
+
+var (
+	idPattern     = regexp.MustCompile(`^[0-9a-f]{32}$`)
+	sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+)
+
+// Store keeps uploads as <id>.json metadata plus <id>.part data in one
+// directory. The data file's length is the upload offset, so bytes that
+// arrived before a connection dropped are kept.
+type Store struct {
+	dir     string
+	maxSize int64
+	ttl     time.Duration
+	now     func() time.Time
+
+	mu   sync.Mutex
+	busy map[string]bool
+}
+
+// New returns a store in dir for uploads of at most maxSize bytes that are
+// removed after ttl without a new chunk.
+func New(dir string, maxSize int64, ttl time.Duration) *Store {
+	return &Store{dir: dir, maxSize: maxSize, ttl: ttl, now: time.Now, busy: map[string]bool{}}
+}
+
+// Create starts an upload.
+func (s *Store) Create(req job.UploadRequest) (*job.Upload, error) {
+	req.SHA256 = strings.ToLower(strings.TrimSpace(req.SHA256))
+	if !sha256Pattern.MatchString(req.SHA256) {
+		return nil, errors.New("sha256 must be 64 hex characters")
+	}
+	if req.Size <= 0 {
+		return nil, errors.New("size must be > 0")
+	}
+	if req.Size > s.maxSize {
+		return nil, fmt.Errorf("size %d exceeds the %d byte upload limit", req.Size, s.maxSize)
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create uploads dir: %w", err)
+	}
+	var buf [16]byte
+	if _, err := crand.Read(buf[:]); err != nil {
+		return nil, fmt.Errorf("generate upload id: %w", err)
+	}
+	now := s.now().UTC()
+	up := &job.Upload{
+		ID:        hex.EncodeToString(buf[:]),
+		Size:      req.Size,
+		SHA256:    req.SHA256,
+		Submitter: req.Submitter,
+		NoCache:   req.NoCache,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.ttl),
+	}
+	raw, err := json.Marshal(up)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(s.dataPath(up.ID), nil, 0o644); err != nil {
+		return nil, fmt.Errorf("create upload: %w", err)
+	}
+	if err := os.WriteFile(s.metaPath(up.ID), raw, 0o644); err != nil {
+		_ = os.Remove(s.dataPath(up.ID))
+		return nil, fmt.Errorf("create upload: %w", err)
+	}
+	return up, nil
+}
+
+// Get returns the upload with its current offset.
+func (s *Store) Get(id string) (*job.Upload, error) {
+	if !idPattern.MatchString(id) {
+		return nil, ErrNotFound
+	}
+	raw, err := os.ReadFile(s.metaPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var up job.Upload
+	if err := json.Unmarshal(raw, &up); err != nil {
+		return nil, fmt.Errorf("decode upload %s: %w", id, err)
+	}
+	info, err := os.Stat(s.dataPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	up.Offset = info.Size()
+	up.ExpiresAt = info.ModTime().UTC().Add(s.ttl)
+	return &up, nil
+}
+
+// Append writes the chunk in r at offset, which must equal the upload's
+// current offset. Bytes written before r fails are kept, so the returned
+// upload reports the new offset even alongside an error.
+func (s *Store) Append(id string, offset int64, r io.Reader) (*job.Upload, error) {
+	if !s.acquire(id) {
+		return nil, ErrBusy
+	}
+	defer s.release(id)
+
+	up, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if offset != up.Offset {
+		return up, ErrOffsetMismatch
+	}
+	f, err := os.OpenFile(s.dataPath(id), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open upload: %w", err)
+	}
+	defer f.Close()
+	remaining := up.Size - up.Offset
+	n, copyErr := io.Copy(f, io.LimitReader(r, remaining+1))
+	if n > remaining {
+		// Only the declared size is kept; the client sent too much.
+		if err := f.Truncate(up.Size); err != nil {
+			return nil, fmt.Errorf("truncate upload: %w", err)
+		}
+		n, copyErr = remaining, ErrTooLarge
+	}
+	if err := f.Close(); err != nil && copyErr == nil {
+		copyErr = fmt.Errorf("write upload: %w", err)
+	}
+	now := s.now()
+	_ = os.Chtimes(s.dataPath(id), now, now)
+	up.Offset += n
+	up.ExpiresAt = now.UTC().Add(s.ttl)
+	return up, copyErr
+}
+
+// Open checks that the upload is complete and matches its SHA-256, and
+// returns its data. The upload stays held until the data is closed, which
+// removes it, so a concurrent Open, Append or Remove gets ErrBusy and the
+// upload is finalized at most once. A mismatching upload is removed.
+func (s *Store) Open(id string) (*job.Upload, io.ReadCloser, error) {
+	if !s.acquire(id) {
+		return nil, nil, ErrBusy
+	}
+	held := true
+	defer func() {
+		if held {
+			s.release(id)
+		}
+	}()
+
+	up, err := s.Get(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if up.Offset != up.Size {
+		return up, nil, ErrIncomplete
+	}
+	sum, err := checksum.File(s.dataPath(id))
+	if err != nil {
+		return nil, nil, err
+	}
+	if sum != up.SHA256 {
+		_ = s.remove(id)
+		return up, nil, fmt.Errorf("%w: got %s", ErrChecksum, sum)
+	}
+	f, err := os.Open(s.dataPath(id))
+	if err != nil {
+		return nil, nil, err
+	}
+	held = false
+	return up, &openUpload{File: f, store: s, id: id}, nil
+}
+
+// openUpload is the data Open returns; closing it removes the upload and
+// releases it.
+type openUpload struct {
+	*os.File
+	store *Store
+	id    string
+	once  sync.Once
+}
+
+func (u *openUpload) Close() error {
+	err := u.File.Close()
+	u.once.Do(func() {
+		err = errors.Join(err, u.store.remove(u.id))
+		u.store.release(u.id)
+	})
+	return err
+}
+
+// Remove deletes the upload. It returns ErrBusy while a chunk is being
+// written or the upload is open.
+func (s *Store) Remove(id string) error {
+	if !idPattern.MatchString(id) {
+		return ErrNotFound
+	}
+	if !s.acquire(id) {
+		return ErrBusy
+	}
+	defer s.release(id)
+	return s.remove(id)
+}
+
+// remove deletes an upload the caller holds.
+func (s *Store) remove(id string) error {
+	if _, err := os.Stat(s.metaPath(id)); errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	return errors.Join(removeIfExists(s.metaPath(id)), removeIfExists(s.dataPath(id)))
+}
+
+// Prune removes uploads whose last chunk is older than the store's ttl and
+// returns how many it removed.
+func (s *Store) Prune() (int, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	now := s.now()
+	removed := 0
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || !idPattern.MatchString(id) {
+			continue
+		}
+		up, err := s.Get(id)
+		if err == nil && now.Before(up.ExpiresAt) {
+			continue
+		}
+		if !s.acquire(id) {
+			continue
+		}
+		if err := s.remove(id); err == nil {
+			removed++
+		}
+		s.release(id)
+	}
+	return removed, nil
+}
+
+func (s *Store) acquire(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.busy[id] {
+		return false
+	}
+	s.busy[id] = true
+	return true
+}
+
+func (s *Store) release(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.busy, id)
+}
+
+func (s *Store) metaPath(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+func (s *Store) dataPath(id string) string {
+	return filepath.Join(s.dir, id+".part")
+}
+
+func removeIfExists(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package upload
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+func digest(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// failingReader yields data and then fails, like a request body whose
+// connection dropped.
+type failingReader struct{ data io.Reader }
+
+func (r failingReader) Read(p []byte) (int, error) {
+	n, err := r.data.Read(p)
+	if err == io.EOF {
+		return n, errors.New("connection reset")
+	}
+	return n, err
+}
+
+func TestAppendKeepsBytesBeforeFailureAndOpenVerifiesDigest(t *testing.T) {
+	t.Parallel()
+
+	s := New(t.TempDir(), 1<<20, time.Hour)
+	up, err := s.Create(job.UploadRequest{Size: 10, SHA256: digest("0123456789"), Submitter: "alice"})
+	if err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+
+	got, err := s.Append(up.ID, 0, failingReader{strings.NewReader("0123")})
+	if err == nil || got.Offset != 4 {
+		t.Fatalf("Append() = offset %v, err %v; want 4 and the read error", got, err)
+	}
+	if _, err := s.Append(up.ID, 0, strings.NewReader("0123")); !errors.Is(err, ErrOffsetMismatch) {
+		t.Fatalf("Append() at a stale offset error = %v, want ErrOffsetMismatch", err)
+	}
+	if _, _, err := s.Open(up.ID); !errors.Is(err, ErrIncomplete) {
+		t.Fatalf("Open() of a partial upload error = %v, want ErrIncomplete", err)
+	}
+	if got, err = s.Append(up.ID, 4, strings.NewReader("456789xyz")); !errors.Is(err, ErrTooLarge) || got.Offset != 10 {
+		t.Fatalf("Append() past the size = %v, %v; want offset 10 and ErrTooLarge", got, err)
+	}
+
+	got, data, err := s.Open(up.ID)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	raw, _ := io.ReadAll(data)
+	data.Close()
+	if string(raw) != "0123456789" || got.Submitter != "alice" {
+		t.Fatalf("Open() = %q by %q", raw, got.Submitter)
+	}
+}
+
+func TestOpenHoldsUploadUntilDataIsClosed(t *testing.T) {
+	t.Parallel()
+
+	s := New(t.TempDir(), 1<<20, time.Hour)
+	up, err := s.Create(job.UploadRequest{Size: 3, SHA256: digest("abc")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Append(up.ID, 0, strings.NewReader("abc")); err != nil {
+		t.Fatal(err)
+	}
+	_, data, err := s.Open(up.ID)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	if _, _, err := s.Open(up.ID); !errors.Is(err, ErrBusy) {
+		t.Fatalf("second Open() error = %v, want ErrBusy", err)
+	}
+	if err := s.Remove(up.ID); !errors.Is(err, ErrBusy) {
+		t.Fatalf("Remove() of an open upload error = %v, want ErrBusy", err)
+	}
+	if err := data.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if _, _, err := s.Open(up.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Open() after Close() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestOpenDiscardsDigestMismatch(t *testing.T) {
+	t.Parallel()
+
+	s := New(t.TempDir(), 1<<20, time.Hour)
+	up, err := s.Create(job.UploadRequest{Size: 3, SHA256: digest("abc")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Append(up.ID, 0, strings.NewReader("abd")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Open(up.ID); !errors.Is(err, ErrChecksum) {
+		t.Fatalf("Open() error = %v, want ErrChecksum", err)
+	}
+	if _, err := s.Get(up.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() after mismatch error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCreateRejectsOversizeAndPruneRemovesIdleUploads(t *testing.T) {
+	t.Parallel()
+
+	s := New(t.TempDir(), 100, time.Hour)
+	if _, err := s.Create(job.UploadRequest{Size: 101, SHA256: digest("")}); err == nil {
+		t.Fatalf("Create() accepted an upload over the limit")
+	}
+	up, err := s.Create(job.UploadRequest{Size: 5, SHA256: digest("hello")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.Prune(); err != nil || n != 0 {
+		t.Fatalf("Prune() of a fresh upload = %d, %v", n, err)
+	}
+	s.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if n, err := s.Prune(); err != nil || n != 1 {
+		t.Fatalf("Prune() of an idle upload = %d, %v; want 1", n, err)
+	}
+	if _, err := s.Get(up.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() after prune error = %v", err)
+	}
+}
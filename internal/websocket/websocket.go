@@ -0,0 +1,353 @@
+// Package websocket is a minimal RFC 6455 implementation for job event
+// streams: text messages, ping/pong and close, without extensions or
+// fragmented writes. Accept upgrades a server request; Dial goes through a
+// regular *http.Client, so TLS settings and proxies apply unchanged.
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	OpText   = 0x1
+	OpBinary = 0x2
+	OpClose  = 0x8
+	OpPing   = 0x9
+	OpPong   = 0xA
+
+	opContinuation = 0x0
+
+	// CloseNormal ends a finished stream.
+	CloseNormal = 1000
+
+	// maxMessageBytes bounds a single incoming message.
+	maxMessageBytes = 4 << 20
+
+	acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+)
+
+// ErrClosed is returned by ReadMessage after the peer sent a close frame.
+var ErrClosed = errors.New("websocket: connection closed")
+
+// ErrIdle is returned by ReadMessage when the idle timeout expired.
+var ErrIdle = errors.New("websocket: no frames within idle timeout")
+
+// Conn is one WebSocket connection. Writes are safe for concurrent use;
+// reads must come from one goroutine.
+type Conn struct {
+	rwc  io.ReadWriteCloser
+	r    *bufio.Reader
+	mask bool // clients mask every frame they send
+
+	wmu    sync.Mutex
+	closed bool
+
+	idle      time.Duration
+	idleTimer *time.Timer
+	stalled   atomic.Bool
+}
+
+// IsUpgrade reports whether r asks for a WebSocket upgrade.
+func IsUpgrade(r *http.Request) bool {
+	return headerHasToken(r.Header, "Connection", "upgrade") && headerHasToken(r.Header, "Upgrade", "websocket")
+}
+
+// Accept completes the server side of the handshake and takes over the
+// connection. On failure it has already answered with 400 or 500.
+func Accept(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	key := strings.TrimSpace(r.Header.Get("Sec-WebSocket-Key"))
+	if r.Method != http.MethodGet || !IsUpgrade(r) || key == "" {
+		http.Error(w, "websocket upgrade required", http.StatusBadRequest)
+		return nil, errors.New("websocket: not an upgrade request")
+	}
+	if v := r.Header.Get("Sec-WebSocket-Version"); v != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusBadRequest)
+		return nil, fmt.Errorf("websocket: unsupported version %q", v)
+	}
+	netConn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, "websocket upgrade unsupported", http.StatusInternalServerError)
+		return nil, fmt.Errorf("websocket: hijack: %w", err)
+	}
+	_ = netConn.SetDeadline(time.Time{})
+	resp := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+	if _, err := brw.WriteString(resp); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	if err := brw.Flush(); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	return &Conn{rwc: netConn, r: brw.Reader}, nil
+}
+
+// Dial opens a WebSocket to an http:// or https:// URL using client, which
+// must not be nil. header carries extra request headers such as auth. A
+// non-101 answer is returned as an error quoting the response body.
+func Dial(ctx context.Context, client *http.Client, rawURL string, header http.Header) (*Conn, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		return nil, &HandshakeError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(raw))}
+	}
+	rwc, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		resp.Body.Close()
+		return nil, errors.New("websocket: transport does not support protocol upgrades")
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		rwc.Close()
+		return nil, errors.New("websocket: server sent a bad Sec-WebSocket-Accept")
+	}
+	return &Conn{rwc: rwc, r: bufio.NewReader(rwc), mask: true}, nil
+}
+
+// HandshakeError is a Dial answered with something other than 101.
+type HandshakeError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HandshakeError) Error() string {
+	return fmt.Sprintf("websocket handshake failed: status=%d body=%s", e.StatusCode, e.Body)
+}
+
+// WriteText sends one text message.
+func (c *Conn) WriteText(data []byte) error { return c.writeFrame(OpText, data) }
+
+// Ping sends a ping control frame.
+func (c *Conn) Ping() error { return c.writeFrame(OpPing, nil) }
+
+// WriteClose starts the closing handshake; keep reading until ReadMessage
+// returns ErrClosed to receive the peer's answer, then call Close.
+func (c *Conn) WriteClose(code int, reason string) error {
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	payload = append(payload, reason...)
+	if len(payload) > 125 {
+		payload = payload[:125]
+	}
+	err := c.writeFrame(OpClose, payload)
+	if errors.Is(err, ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// Close sends a close frame with code and reason unless one was already
+// sent, then closes the connection without waiting for the peer's answer.
+func (c *Conn) Close(code int, reason string) error {
+	err := c.WriteClose(code, reason)
+	if c.idleTimer != nil {
+		c.idleTimer.Stop()
+	}
+	_ = c.rwc.Close()
+	return err
+}
+
+// ReadMessage returns the next text or binary message, answering pings
+// along the way. It returns ErrClosed once the peer closes the connection.
+func (c *Conn) ReadMessage() (opcode int, data []byte, err error) {
+	var msgOp int
+	var msg []byte
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch op {
+		case OpPing:
+			// A failed pong surfaces on the next read.
+			_ = c.writeFrame(OpPong, payload)
+			continue
+		case OpPong:
+			continue
+		case OpClose:
+			code := CloseNormal
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			_ = c.writeFrame(OpClose, binary.BigEndian.AppendUint16(nil, uint16(code)))
+			return 0, nil, ErrClosed
+		case OpText, OpBinary:
+			if msgOp != 0 {
+				return 0, nil, errors.New("websocket: new message inside a fragmented one")
+			}
+			msgOp = op
+		case opContinuation:
+			if msgOp == 0 {
+				return 0, nil, errors.New("websocket: continuation without a message")
+			}
+		default:
+			return 0, nil, fmt.Errorf("websocket: unknown opcode %d", op)
+		}
+		if len(msg)+len(payload) > maxMessageBytes {
+			return 0, nil, errors.New("websocket: message too large")
+		}
+		msg = append(msg, payload...)
+		if fin {
+			return msgOp, msg, nil
+		}
+	}
+}
+
+// SetIdleTimeout closes the connection when no frame, pings included,
+// arrives for d; the pending ReadMessage then returns ErrIdle. Call it
+// once, before reading.
+func (c *Conn) SetIdleTimeout(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	c.idle = d
+	c.idleTimer = time.AfterFunc(d, func() {
+		c.stalled.Store(true)
+		_ = c.rwc.Close()
+	})
+}
+
+func (c *Conn) writeFrame(op int, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	frame := []byte{0x80 | byte(op)}
+	maskBit := byte(0)
+	if c.mask {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	if c.mask {
+		var key [4]byte
+		if _, err := rand.Read(key[:]); err != nil {
+			return err
+		}
+		frame = append(frame, key[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		for i := range payload {
+			frame[start+i] ^= key[i%4]
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+	_, err := c.rwc.Write(frame)
+	if op == OpClose {
+		// Nothing may follow a close frame.
+		c.closed = true
+	}
+	return err
+}
+
+func (c *Conn) readFrame() (fin bool, op int, payload []byte, err error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.r, head[:]); err != nil {
+		if c.stalled.Load() {
+			return false, 0, nil, ErrIdle
+		}
+		return false, 0, nil, err
+	}
+	if c.idleTimer != nil {
+		c.idleTimer.Reset(c.idle)
+	}
+	fin = head[0]&0x80 != 0
+	op = int(head[0] & 0x0F)
+	masked := head[1]&0x80 != 0
+	n := uint64(head[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > maxMessageBytes {
+		return false, 0, nil, errors.New("websocket: frame too large")
+	}
+	var key [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.r, key[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= key[i%4]
+		}
+	}
+	return fin, op, payload, nil
+}
+
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, part := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
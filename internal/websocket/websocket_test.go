@@ -0,0 +1,111 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDialAndAccept_ExchangeMessagesAndClose(t *testing.T) {
+	serverDone := make(chan error, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Build-Token") != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		conn, err := Accept(w, r)
+		if err != nil {
+			serverDone <- err
+			return
+		}
+		if err := conn.Ping(); err != nil {
+			serverDone <- err
+			return
+		}
+		// Large enough for the 16-bit length form.
+		if err := conn.WriteText([]byte(strings.Repeat("x", 300))); err != nil {
+			serverDone <- err
+			return
+		}
+		op, msg, err := conn.ReadMessage()
+		if err != nil || op != OpText || string(msg) != "hello from client" {
+			serverDone <- errors.New("unexpected client message: " + string(msg))
+			return
+		}
+		serverDone <- conn.Close(CloseNormal, "done")
+	}))
+	defer ts.Close()
+
+	header := http.Header{}
+	header.Set("X-Build-Token", "secret")
+	conn, err := Dial(context.Background(), ts.Client(), ts.URL, header)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	op, msg, err := conn.ReadMessage()
+	if err != nil || op != OpText || len(msg) != 300 {
+		t.Fatalf("read = %d, %d bytes, %v", op, len(msg), err)
+	}
+	if err := conn.WriteText([]byte("hello from client")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, _, err := conn.ReadMessage(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected ErrClosed after server close, got %v", err)
+	}
+	if err := <-serverDone; err != nil {
+		t.Fatalf("server: %v", err)
+	}
+	if err := conn.Close(CloseNormal, ""); err != nil {
+		t.Fatalf("close after peer close: %v", err)
+	}
+}
+
+func TestDial_ReportsRejectedHandshake(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	_, err := Dial(context.Background(), ts.Client(), ts.URL, nil)
+	var hsErr *HandshakeError
+	if !errors.As(err, &hsErr) || hsErr.StatusCode != http.StatusUnauthorized || hsErr.Body != "unauthorized" {
+		t.Fatalf("expected a 401 handshake error, got %v", err)
+	}
+}
+
+func TestAccept_RejectsPlainRequests(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if _, err := Accept(rec, httptest.NewRequest(http.MethodGet, "/", nil)); err == nil {
+		t.Fatal("expected plain GET to be rejected")
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+}
+
+func TestConn_IdleTimeout(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Accept(w, r)
+		if err != nil {
+			return
+		}
+		<-release
+		_ = conn.Close(CloseNormal, "")
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	conn, err := Dial(context.Background(), ts.Client(), ts.URL, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	conn.SetIdleTimeout(50 * time.Millisecond)
+	if _, _, err := conn.ReadMessage(); !errors.Is(err, ErrIdle) {
+		t.Fatalf("expected ErrIdle, got %v", err)
+	}
+}
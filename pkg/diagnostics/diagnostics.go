@@ -0,0 +1,42 @@
+// Package diagnostics is the public hook for adding log parsers to a
+// spadeforge server. A parser registered here, from an init function in a
+// package linked into the server binary, is used for every job whose
+// builder reports the tool name (see builder.ToolReporter).
+//
+//	func init() {
+//		diagnostics.Register("gowin", func(line, source string) (diagnostics.Diagnostic, bool) {
+//			...
+//		})
+//	}
+package diagnostics
+
+import (
+	internal "github.com/mblsha/spadeforge/internal/diagnostics"
+	"github.com/mblsha/spadeforge/internal/job"
+)
+
+type (
+	// Diagnostic is one parsed log message, as stored in diagnostics.json.
+	Diagnostic = job.Diagnostic
+	Severity   = job.DiagnosticSeverity
+	// LineParser turns one log line into a diagnostic; source is the log
+	// file name and should be copied into the result.
+	LineParser = internal.LineParser
+)
+
+const (
+	SeverityError   = job.SeverityError
+	SeverityWarning = job.SeverityWarning
+	SeverityInfo    = job.SeverityInfo
+)
+
+// Register adds a parser under a case-insensitive tool name. It panics on
+// an empty name, a nil parser or a name that is already registered.
+func Register(tool string, p LineParser) {
+	internal.Register(tool, p)
+}
+
+// Tools lists the registered tool names, built-in ones included.
+func Tools() []string {
+	return internal.Tools()
+}
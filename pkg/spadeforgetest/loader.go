@@ -0,0 +1,147 @@
+package spadeforgetest
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	loaderclient "github.com/mblsha/spadeforge/internal/spadeloader/client"
+	loaderconfig "github.com/mblsha/spadeforge/internal/spadeloader/config"
+	"github.com/mblsha/spadeforge/internal/spadeloader/flasher"
+	"github.com/mblsha/spadeforge/internal/spadeloader/history"
+	loaderjob "github.com/mblsha/spadeforge/internal/spadeloader/job"
+	loaderqueue "github.com/mblsha/spadeforge/internal/spadeloader/queue"
+	loaderserver "github.com/mblsha/spadeforge/internal/spadeloader/server"
+	loaderstore "github.com/mblsha/spadeforge/internal/spadeloader/store"
+)
+
+type (
+	// LoaderConfig is the spadeloader configuration, for
+	// LoaderOptions.Configure.
+	LoaderConfig = loaderconfig.Config
+	// LoaderClient talks to a spadeloader server.
+	LoaderClient = loaderclient.HTTPClient
+	// LoaderRecord is a flash job.
+	LoaderRecord = loaderjob.Record
+	// LoaderState is a flash job's state.
+	LoaderState = loaderjob.State
+)
+
+const (
+	FlashQueued    = loaderjob.StateQueued
+	FlashRunning   = loaderjob.StateRunning
+	FlashSucceeded = loaderjob.StateSucceeded
+	FlashFailed    = loaderjob.StateFailed
+)
+
+// LoaderOptions configures NewLoader. The zero value runs an open loader
+// whose flashes all succeed.
+type LoaderOptions struct {
+	Token string
+	// FailFlashes makes every flash fail.
+	FailFlashes bool
+	// Configure adjusts the loader config before the server starts.
+	Configure func(*LoaderConfig)
+}
+
+// Loader is a running spadeloader server backed by a fake flasher.
+type Loader struct {
+	URL        string
+	Token      string
+	AuthHeader string
+	BaseDir    string
+}
+
+// NewLoader starts a spadeloader server on a random local port and stops it
+// when the test ends.
+func NewLoader(t testing.TB, opts LoaderOptions) *Loader {
+	t.Helper()
+
+	cfg := loaderconfig.Default()
+	cfg.BaseDir = t.TempDir()
+	cfg.Token = opts.Token
+	cfg.DiscoveryEnabled = false
+	cfg.WorkerTimeout = time.Minute
+	if opts.Configure != nil {
+		opts.Configure(&cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("spadeforgetest: invalid loader config: %v", err)
+	}
+
+	hs := history.New(cfg.HistoryPath(), cfg.HistoryLimit)
+	mgr := loaderqueue.New(cfg, loaderstore.New(cfg), &flasher.FakeFlasher{Fail: opts.FailFlashes}, hs)
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := mgr.Start(ctx); err != nil {
+		cancel()
+		t.Fatalf("spadeforgetest: start flash queue: %v", err)
+	}
+	ts := httptest.NewServer(loaderserver.New(cfg, mgr).Handler())
+	t.Cleanup(func() {
+		ts.Close()
+		cancel()
+	})
+	return &Loader{
+		URL:        ts.URL,
+		Token:      cfg.Token,
+		AuthHeader: cfg.AuthHeader,
+		BaseDir:    cfg.BaseDir,
+	}
+}
+
+// Client returns a client for the loader.
+func (l *Loader) Client() *LoaderClient {
+	return &loaderclient.HTTPClient{BaseURL: l.URL, Token: l.Token, AuthHeader: l.AuthHeader}
+}
+
+// Flash uploads bitstream for board and returns the new job's ID.
+func (l *Loader) Flash(t testing.TB, board, design string, bitstream []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "design.bit")
+	if err := os.WriteFile(path, bitstream, 0o644); err != nil {
+		t.Fatalf("spadeforgetest: %v", err)
+	}
+	id, err := l.Client().SubmitFlash(context.Background(), loaderclient.SubmitRequest{
+		Board:         board,
+		DesignName:    design,
+		BitstreamPath: path,
+	})
+	if err != nil {
+		t.Fatalf("spadeforgetest: submit flash: %v", err)
+	}
+	return id
+}
+
+// Wait returns the flash job once it finishes, failing the test after
+// DefaultTimeout.
+func (l *Loader) Wait(t testing.TB, jobID string) *LoaderRecord {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+	rec, err := l.Client().WaitForTerminalWithProgress(ctx, jobID, 20*time.Millisecond, nil)
+	if err != nil {
+		t.Fatalf("spadeforgetest: wait for flash %s: %v", jobID, err)
+	}
+	return rec
+}
+
+// RequireState waits for the flash job to finish and fails the test unless
+// it ended in want.
+func (l *Loader) RequireState(t testing.TB, jobID string, want LoaderState) *LoaderRecord {
+	t.Helper()
+	rec := l.Wait(t, jobID)
+	if rec.State != want {
+		t.Fatalf("spadeforgetest: flash %s is %s (%s), want %s", jobID, rec.State, firstNonEmpty(rec.Error, rec.Message), want)
+	}
+	return rec
+}
+
+// FakeBitstream returns a minimal bitstream with a Xilinx sync word, which
+// passes the loader's checks for 7-series boards such as alchitry_au.
+func FakeBitstream() []byte {
+	return append(bytes.Repeat([]byte{0xff}, 16), 0xaa, 0x99, 0x55, 0x66, 0x20, 0x00, 0x00, 0x00)
+}
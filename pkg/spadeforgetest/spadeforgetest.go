@@ -0,0 +1,233 @@
+// Package spadeforgetest runs a spadeforge build server with a fake builder,
+// and a spadeloader server with a fake flasher, in-process on random local
+// ports, so projects that drive the HTTP API can test against it without
+// Vivado or a board:
+//
+//	func TestBuildAndFlash(t *testing.T) {
+//		srv := spadeforgetest.NewServer(t, spadeforgetest.Options{})
+//		id := srv.SubmitProject(t, spadeforgetest.Project{
+//			Name:  "blinky",
+//			Top:   "top",
+//			Files: map[string]string{"top.sv": "module top; endmodule\n"},
+//		})
+//		srv.RequireState(t, id, spadeforgetest.StateSucceeded)
+//
+//		loader := spadeforgetest.NewLoader(t, spadeforgetest.LoaderOptions{})
+//		flash := loader.Flash(t, "alchitry_au", "blinky", spadeforgetest.FakeBitstream())
+//		loader.RequireState(t, flash, spadeforgetest.FlashSucceeded)
+//	}
+//
+// Servers stop and their state directories are removed when the test ends.
+package spadeforgetest
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mblsha/spadeforge/internal/builder"
+	"github.com/mblsha/spadeforge/internal/client"
+	"github.com/mblsha/spadeforge/internal/config"
+	"github.com/mblsha/spadeforge/internal/job"
+	"github.com/mblsha/spadeforge/internal/queue"
+	"github.com/mblsha/spadeforge/internal/server"
+	"github.com/mblsha/spadeforge/internal/store"
+)
+
+type (
+	// Config is the build server's configuration, for Options.Configure.
+	Config = config.Config
+	// Client talks to a build server; Server.Client returns one set up
+	// for it.
+	Client = client.HTTPClient
+	// BundleSpec describes a bundle for client.BuildBundle-style submits.
+	BundleSpec = client.BundleSpec
+	// Record is a build job as GET /v1/jobs/{id} returns it.
+	Record = job.Record
+	// State is a build job's state.
+	State = job.State
+)
+
+const (
+	StateQueued    = job.StateQueued
+	StateRunning   = job.StateRunning
+	StateSucceeded = job.StateSucceeded
+	StateFailed    = job.StateFailed
+	StateCanceled  = job.StateCanceled
+)
+
+// DefaultTimeout bounds how long RequireState waits for a job to finish.
+const DefaultTimeout = 10 * time.Second
+
+// defaultPart is the FPGA part a Project builds for when it names none.
+const defaultPart = "xc7a35tcsg324-1"
+
+// Options configures NewServer. The zero value runs an open server whose
+// builds all succeed.
+type Options struct {
+	// Token, when set, is required on every request; the Server's Client
+	// sends it.
+	Token string
+	// FailProjects makes builds of these projects fail with the given
+	// message.
+	FailProjects map[string]string
+	// Configure adjusts the server config before the server starts.
+	Configure func(*Config)
+}
+
+// Server is a running build server backed by a fake builder.
+type Server struct {
+	// URL is the server's base URL, e.g. http://127.0.0.1:41234.
+	URL        string
+	Token      string
+	AuthHeader string
+	// BaseDir holds the server's jobs, work dirs and artifacts.
+	BaseDir string
+}
+
+// NewServer starts a build server on a random local port and stops it when
+// the test ends.
+func NewServer(t testing.TB, opts Options) *Server {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.BaseDir = t.TempDir()
+	cfg.Token = opts.Token
+	cfg.DiscoveryEnabled = false
+	cfg.WorkerTimeout = time.Minute
+	if opts.Configure != nil {
+		opts.Configure(&cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("spadeforgetest: invalid server config: %v", err)
+	}
+
+	fb := &builder.FakeBuilder{FailProjects: map[string]error{}}
+	for project, message := range opts.FailProjects {
+		fb.FailProjects[project] = errors.New(message)
+	}
+	mgr := queue.New(cfg, store.New(cfg), fb)
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := mgr.Start(ctx); err != nil {
+		cancel()
+		t.Fatalf("spadeforgetest: start build queue: %v", err)
+	}
+	ts := httptest.NewServer(server.New(cfg, mgr).Handler())
+	t.Cleanup(func() {
+		ts.Close()
+		cancel()
+	})
+	return &Server{
+		URL:        ts.URL,
+		Token:      cfg.Token,
+		AuthHeader: cfg.AuthHeader,
+		BaseDir:    cfg.BaseDir,
+	}
+}
+
+// Client returns a client for the server.
+func (s *Server) Client() *Client {
+	return &client.HTTPClient{BaseURL: s.URL, Token: s.Token, AuthHeader: s.AuthHeader}
+}
+
+// Submit uploads a bundle zip and returns the new job's ID.
+func (s *Server) Submit(t testing.TB, bundle []byte) string {
+	t.Helper()
+	resp, err := s.Client().Submit(context.Background(), bundle)
+	if err != nil {
+		t.Fatalf("spadeforgetest: submit: %v", err)
+	}
+	return resp.JobID
+}
+
+// SubmitSpec builds a bundle from spec, whose files are read from disk,
+// and submits it.
+func (s *Server) SubmitSpec(t testing.TB, spec BundleSpec) string {
+	t.Helper()
+	bundle, err := client.BuildBundle(spec)
+	if err != nil {
+		t.Fatalf("spadeforgetest: build bundle: %v", err)
+	}
+	return s.Submit(t, bundle)
+}
+
+// Project is a design given by file contents. Files ending in .xdc, .pcf
+// or .lpf are constraints; the rest are sources.
+type Project struct {
+	Name string
+	Top  string
+	// Part defaults to an Artix-7 part.
+	Part  string
+	Files map[string]string
+}
+
+// SubmitProject writes p's files to a temporary directory and submits them
+// as a bundle.
+func (s *Server) SubmitProject(t testing.TB, p Project) string {
+	t.Helper()
+	dir := t.TempDir()
+	spec := BundleSpec{Project: p.Name, Top: p.Top, Part: p.Part}
+	if spec.Part == "" {
+		spec.Part = defaultPart
+	}
+	names := make([]string, 0, len(p.Files))
+	for name := range p.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("spadeforgetest: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(p.Files[name]), 0o644); err != nil {
+			t.Fatalf("spadeforgetest: %v", err)
+		}
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".xdc", ".pcf", ".lpf":
+			spec.Constraints = append(spec.Constraints, path)
+		default:
+			spec.Sources = append(spec.Sources, path)
+		}
+	}
+	return s.SubmitSpec(t, spec)
+}
+
+// Wait returns the job once it finishes, failing the test after
+// DefaultTimeout.
+func (s *Server) Wait(t testing.TB, jobID string) *Record {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+	rec, err := s.Client().WaitForTerminal(ctx, jobID, 20*time.Millisecond)
+	if err != nil {
+		t.Fatalf("spadeforgetest: wait for job %s: %v", jobID, err)
+	}
+	return rec
+}
+
+// RequireState waits for the job to finish and fails the test unless it
+// ended in want.
+func (s *Server) RequireState(t testing.TB, jobID string, want State) *Record {
+	t.Helper()
+	rec := s.Wait(t, jobID)
+	if rec.State != want {
+		t.Fatalf("spadeforgetest: job %s is %s (%s), want %s", jobID, rec.State, firstNonEmpty(rec.Error, rec.Message), want)
+	}
+	return rec
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,45 @@
+package spadeforgetest_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mblsha/spadeforge/pkg/spadeforgetest"
+)
+
+func TestServerBuildsAndFailsProjects(t *testing.T) {
+	t.Parallel()
+
+	srv := spadeforgetest.NewServer(t, spadeforgetest.Options{
+		Token:        "secret",
+		FailProjects: map[string]string{"broken": "synthesis failed"},
+	})
+	files := map[string]string{
+		"rtl/top.sv": "module top(input clk); endmodule\n",
+		"pins.xdc":   "set_property PACKAGE_PIN N14 [get_ports clk]\n",
+	}
+
+	ok := srv.SubmitProject(t, spadeforgetest.Project{Name: "blinky", Top: "top", Files: files})
+	rec := srv.RequireState(t, ok, spadeforgetest.StateSucceeded)
+	if rec.Manifest.Project != "blinky" {
+		t.Fatalf("project = %q, want blinky", rec.Manifest.Project)
+	}
+
+	bad := srv.SubmitProject(t, spadeforgetest.Project{Name: "broken", Top: "top", Files: files})
+	rec = srv.RequireState(t, bad, spadeforgetest.StateFailed)
+	if !strings.Contains(rec.Error+rec.Message, "synthesis failed") {
+		t.Fatalf("failed job error = %q message = %q", rec.Error, rec.Message)
+	}
+}
+
+func TestLoaderFlashesFakeBitstream(t *testing.T) {
+	t.Parallel()
+
+	loader := spadeforgetest.NewLoader(t, spadeforgetest.LoaderOptions{})
+	id := loader.Flash(t, "alchitry_au", "blinky", spadeforgetest.FakeBitstream())
+	loader.RequireState(t, id, spadeforgetest.FlashSucceeded)
+
+	failing := spadeforgetest.NewLoader(t, spadeforgetest.LoaderOptions{FailFlashes: true})
+	id = failing.Flash(t, "alchitry_au", "blinky", spadeforgetest.FakeBitstream())
+	failing.RequireState(t, id, spadeforgetest.FlashFailed)
+}